@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"parakeet/internal/server"
+)
+
+// runCompare implements `parakeet compare [flags] FILE`: the file is
+// transcribed in-process by the loaded model and by the -compare-models-dir
+// model (or the canary or shadow one), and the word-level diff and WER
+// between the two go to stdout, to decide between int8 and fp32 or v2 and
+// v3 on one's own audio. It accepts every server flag, like transcribe.
+func runCompare(args []string) int {
+	cfg := server.Config{}
+	var format string
+	opts := server.FileOptions{}
+
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	registerServerFlags(fs, &cfg)
+	fs.StringVar(&format, "format", "text", "Output format: text (the diff inline) or json")
+	fs.StringVar(&opts.Language, "language", "", "Language of the audio (default: en)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: parakeet compare -compare-models-dir DIR [flags] FILE")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	applyEnvDefaults(fs)
+	if err := applyProfile(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown -format %q (available: text, json)\n", format)
+		return 2
+	}
+	if cfg.CompareModelsDir == "" && cfg.CanaryModelsDir == "" && cfg.ShadowModelsDir == "" {
+		fmt.Fprintln(os.Stderr, "-compare-models-dir is required: the model to compare with the one in -models")
+		return 2
+	}
+
+	setupLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	cfg.AssemblyAI, cfg.JobsNATSURL = false, ""
+
+	srv, err := server.New(cfg)
+	if err != nil {
+		slog.Error("failed to initialize", "error", err)
+		return 1
+	}
+	defer srv.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cmp, err := srv.CompareFile(ctx, fs.Arg(0), opts)
+	if err != nil {
+		slog.Error("comparison failed", "file", fs.Arg(0), "error", err)
+		return 1
+	}
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(cmp)
+		return 0
+	}
+	writeComparison(os.Stdout, cmp)
+	return 0
+}
+
+// writeComparison prints cmp for a terminal: both models, the WER and the
+// transcript with A's words that B changed in [-...-] and B's in {+...+}.
+func writeComparison(w io.Writer, cmp *server.Comparison) {
+	fmt.Fprintf(w, "A: %s (%s) %.2fs\n", cmp.A.Model, cmp.A.Role, cmp.A.Seconds)
+	fmt.Fprintf(w, "B: %s (%s) %.2fs\n", cmp.B.Model, cmp.B.Role, cmp.B.Seconds)
+	fmt.Fprintf(w, "WER: %.2f%% (%d substitutions, %d deletions, %d insertions over %d words)\n\n",
+		cmp.WER*100, cmp.Substitutions, cmp.Deletions, cmp.Insertions, cmp.ReferenceWords)
+	parts := make([]string, 0, len(cmp.Diff))
+	for _, e := range cmp.Diff {
+		switch e.Op {
+		case "equal":
+			parts = append(parts, e.Reference)
+		case "substitute":
+			parts = append(parts, "[-"+e.Reference+"-] {+"+e.Hypothesis+"+}")
+		case "delete":
+			parts = append(parts, "[-"+e.Reference+"-]")
+		case "insert":
+			parts = append(parts, "{+"+e.Hypothesis+"+}")
+		}
+	}
+	fmt.Fprintln(w, strings.Join(parts, " "))
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"parakeet/internal/selftest"
+	"parakeet/internal/server"
+)
+
+// runEval implements `parakeet eval [flags] DIR`: every audio file of DIR
+// with a sibling .txt reference is transcribed in-process with the server
+// defaults and scored by WER, so a change to the preprocessing (FFT, mel
+// features, normalization) can be checked on real recordings. With -assert
+// it exits with 1 when a clip or the corpus is over its bound, or worse than
+// the -baseline transcripts of a reference implementation or an earlier
+// build.
+func runEval(args []string) int {
+	cfg := server.Config{}
+	opts := server.FileOptions{}
+	var (
+		assert       bool
+		maxWER       float64
+		maxCorpusWER float64
+		baselineDir  string
+		tolerance    float64
+		saveDir      string
+	)
+
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	registerServerFlags(fs, &cfg)
+	fs.BoolVar(&assert, "assert", false, "Exit with 1 when a bound below is exceeded")
+	fs.Float64Var(&maxWER, "max-wer", selftest.DefaultMaxWER, "Maximum word error rate of each clip")
+	fs.Float64Var(&maxCorpusWER, "max-corpus-wer", 0, "Maximum word error rate over the whole directory (0 = no bound)")
+	fs.StringVar(&baselineDir, "baseline", "", "Directory of baseline transcripts, one NAME.txt per clip, e.g. from NeMo or an earlier -save; no clip nor the corpus may score worse")
+	fs.Float64Var(&tolerance, "tolerance", 0, "WER a clip or the corpus may lose against -baseline")
+	fs.StringVar(&saveDir, "save", "", "Write the transcripts to this directory, one NAME.txt per clip, for a later -baseline")
+	fs.StringVar(&opts.Language, "language", "", "Language of the audio (default: en)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: parakeet eval [flags] DIR")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	applyEnvDefaults(fs)
+	if err := applyProfile(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := fs.Arg(0)
+	if saveDir != "" && sameDir(saveDir, dir) {
+		fmt.Fprintln(os.Stderr, "-save must not be the clips directory: it would overwrite the references")
+		return 2
+	}
+	cases, err := selftest.LoadClips(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(cases) == 0 {
+		fmt.Fprintf(os.Stderr, "no audio file with a .txt reference in %s\n", dir)
+		return 2
+	}
+	var baseline map[string]string
+	if baselineDir != "" {
+		if baseline, err = selftest.LoadTranscripts(baselineDir, cases); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	setupLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	cfg.AssemblyAI, cfg.JobsNATSURL = false, ""
+
+	srv, err := server.New(cfg)
+	if err != nil {
+		slog.Error("failed to initialize", "error", err)
+		return 1
+	}
+	defer srv.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report := selftest.RunFunc(ctx, cases, maxWER, func(ctx context.Context, c selftest.Case) (string, error) {
+		return srv.TranscribeText(ctx, c.Filename, c.Audio, opts)
+	})
+	var base *selftest.Report
+	if baseline != nil {
+		r := selftest.RunFunc(ctx, cases, maxWER, func(_ context.Context, c selftest.Case) (string, error) {
+			return baseline[c.Name], nil
+		})
+		base = &r
+	}
+	failures := writeEval(os.Stdout, report, base, maxCorpusWER, tolerance)
+
+	if saveDir != "" {
+		if err := selftest.SaveTranscripts(saveDir, report); err != nil {
+			slog.Error("failed to save the transcripts", "dir", saveDir, "error", err)
+			return 1
+		}
+	}
+	if assert && failures > 0 {
+		fmt.Fprintln(os.Stdout, "eval FAILED")
+		return 1
+	}
+	return 0
+}
+
+// writeEval prints the report, the baseline's corpus WER and the clips that
+// regressed against it, and a line per bound exceeded. It returns the number
+// of failures: clips over -max-wer or that failed, regressed clips and
+// corpus bounds exceeded.
+func writeEval(w io.Writer, report selftest.Report, base *selftest.Report, maxCorpusWER, tolerance float64) int {
+	report.Write(w)
+	failures := 0
+	for _, res := range report.Results {
+		if !res.Passed() {
+			failures++
+		}
+	}
+	if failures > 0 {
+		fmt.Fprintf(w, "%d of %d clips over -max-wer or failed\n", failures, len(report.Results))
+	}
+	wer, _ := report.CorpusWER()
+	if maxCorpusWER > 0 && wer > maxCorpusWER {
+		failures++
+		fmt.Fprintf(w, "corpus WER %.3f over -max-corpus-wer %.3f\n", wer, maxCorpusWER)
+	}
+	if base == nil {
+		return failures
+	}
+	baseWER, words := base.CorpusWER()
+	fmt.Fprintf(w, "baseline corpus WER: %.3f over %d reference words\n", baseWER, words)
+	for _, r := range report.Regressions(*base, tolerance) {
+		failures++
+		fmt.Fprintf(w, "REGRESSION  %-22s wer=%.3f baseline=%.3f\n", r.Name, r.WER, r.BaselineWER)
+	}
+	if wer > baseWER+tolerance {
+		failures++
+		fmt.Fprintf(w, "corpus WER %.3f worse than the baseline's %.3f\n", wer, baseWER)
+	}
+	return failures
+}
+
+// sameDir reports whether a and b name the same directory.
+func sameDir(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	bi, err := os.Stat(b)
+	return err == nil && os.SameFile(ai, bi)
+}
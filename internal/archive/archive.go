@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package archive reads the zip and tar archives, plain or gzipped, that
+// clients send instead of a single audio file, one file at a time so that
+// only the file being transcribed is held in memory.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Format is the kind of an archive.
+type Format string
+
+const (
+	Zip   Format = "zip"
+	Tar   Format = "tar"
+	TarGz Format = "tar.gz"
+)
+
+// Magic numbers: a zip local file header (or the end of the central
+// directory that an empty zip starts with), the gzip header and the ustar
+// mark of a tar header at offset 257.
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	emptyZipMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte{0x1f, 0x8b}
+	tarMagic      = []byte("ustar")
+)
+
+const tarMagicOffset = 257
+
+// Detect returns the Format of the size bytes of r by their magic number,
+// or "" when they are not an archive.
+func Detect(r io.ReaderAt, size int64) Format {
+	head := make([]byte, min(size, tarMagicOffset+int64(len(tarMagic))))
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, emptyZipMagic):
+		return Zip
+	case bytes.HasPrefix(head, gzipMagic):
+		return TarGz
+	case len(head) >= tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:], tarMagic):
+		return Tar
+	}
+	return ""
+}
+
+// Walk calls fn with each file of the archive in the size bytes of r, in
+// archive order: its path in the archive, its size and its content, which
+// can be read until fn returns. Directories, links and the metadata macOS
+// adds (__MACOSX/ and ._ files) are skipped. An error from fn stops the
+// walk and is returned.
+func Walk(r io.ReaderAt, size int64, fn func(name string, size int64, content io.Reader) error) error {
+	switch Detect(r, size) {
+	case Zip:
+		return walkZip(r, size, fn)
+	case Tar:
+		return walkTar(io.NewSectionReader(r, 0, size), fn)
+	case TarGz:
+		gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return fmt.Errorf("malformed gzip archive: %w", err)
+		}
+		defer gz.Close()
+		return walkTar(gz, fn)
+	}
+	return errors.New("not a zip or tar archive")
+}
+
+// ErrTooManyFiles is returned by Count for an archive of more than its
+// limit of files.
+var ErrTooManyFiles = errors.New("too many files in the archive")
+
+// Count returns how many files Walk would go through, stopping with
+// ErrTooManyFiles as soon as there are more than limit. A gzipped tar is
+// decompressed to find out.
+func Count(r io.ReaderAt, size int64, limit int) (int, error) {
+	n := 0
+	err := Walk(r, size, func(string, int64, io.Reader) error {
+		if n++; n > limit {
+			return ErrTooManyFiles
+		}
+		return nil
+	})
+	return n, err
+}
+
+func walkZip(r io.ReaderAt, size int64, fn func(string, int64, io.Reader) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("malformed zip archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || skipped(f.Name) {
+			continue
+		}
+		if err := walkZipFile(f, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkZipFile(f *zip.File, fn func(string, int64, io.Reader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	return fn(f.Name, int64(f.UncompressedSize64), rc)
+}
+
+func walkTar(r io.Reader, fn func(string, int64, io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("malformed tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || skipped(hdr.Name) {
+			continue
+		}
+		if err := fn(hdr.Name, hdr.Size, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// skipped reports whether name is macOS metadata rather than a file the
+// user put in the archive.
+func skipped(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._")
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func zipOf(files map[string]string, order []string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.Create("calls/")
+	for _, name := range order {
+		w, _ := zw.Create(name)
+		w.Write([]byte(files[name]))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func tarOf(files map[string]string, order []string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "calls/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for _, name := range order {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(files[name]))})
+		tw.Write([]byte(files[name]))
+	}
+	tw.Close()
+	return buf.Bytes()
+}
+
+func TestWalk(t *testing.T) {
+	files := map[string]string{"calls/1.wav": "one", "calls/2.mp3": "two", "__MACOSX/calls/._1.wav": "meta", "calls/._2.mp3": "meta"}
+	order := []string{"calls/1.wav", "__MACOSX/calls/._1.wav", "calls/._2.mp3", "calls/2.mp3"}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(tarOf(files, order))
+	zw.Close()
+
+	for _, tc := range []struct {
+		format Format
+		data   []byte
+	}{
+		{Zip, zipOf(files, order)},
+		{Tar, tarOf(files, order)},
+		{TarGz, gz.Bytes()},
+	} {
+		r := bytes.NewReader(tc.data)
+		if got := Detect(r, r.Size()); got != tc.format {
+			t.Errorf("%s: Detect = %q", tc.format, got)
+		}
+		var got []string
+		err := Walk(r, r.Size(), func(name string, size int64, content io.Reader) error {
+			data, _ := io.ReadAll(content)
+			if int64(len(data)) != size {
+				t.Errorf("%s: %s is %d bytes, size %d", tc.format, name, len(data), size)
+			}
+			got = append(got, name+"="+string(data))
+			return nil
+		})
+		if want := []string{"calls/1.wav=one", "calls/2.mp3=two"}; err != nil || !slices.Equal(got, want) {
+			t.Errorf("%s: Walk = %v, %v; want %v", tc.format, got, err, want)
+		}
+		if n, err := Count(r, r.Size(), 2); n != 2 || err != nil {
+			t.Errorf("%s: Count = %d, %v", tc.format, n, err)
+		}
+		if n, err := Count(r, r.Size(), 1); n != 2 || !errors.Is(err, ErrTooManyFiles) {
+			t.Errorf("%s: Count with limit 1 = %d, %v; want it to stop at the second file", tc.format, n, err)
+		}
+	}
+
+	wav := bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVEfmt "))
+	if got := Detect(wav, wav.Size()); got != "" {
+		t.Errorf("wav detected as %q", got)
+	}
+	if err := Walk(wav, wav.Size(), nil); err == nil {
+		t.Error("Walk accepted a wav file")
+	}
+}
@@ -20,8 +20,21 @@ func isWAV(data []byte) bool {
 	return string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE"
 }
 
-// parseWAV parses a WAV file and returns float32 samples normalized to [-1, 1]
-func parseWAV(data []byte) ([]float32, error) {
+// parseWAV parses a WAV file and returns float32 samples normalized to [-1, 1],
+// downmixed to mono and resampled to 16 kHz with the given quality when the
+// file uses another rate.
+func parseWAV(data []byte, quality ResampleQuality) ([]float32, error) {
+	planes, err := parseWAVChannels(data, quality, ChannelMix)
+	if err != nil {
+		return nil, err
+	}
+	return planes[0], nil
+}
+
+// parseWAVChannels is parseWAV with a channel mode: it returns one 16 kHz
+// plane per channel selected by mode (a single plane except for
+// ChannelPerChannel).
+func parseWAVChannels(data []byte, quality ResampleQuality, mode ChannelMode) ([][]float32, error) {
 	if len(data) < 44 {
 		return nil, fmt.Errorf("WAV file too small")
 	}
@@ -56,10 +69,31 @@ func parseWAV(data []byte) ([]float32, error) {
 			bitsPerSample = binary.LittleEndian.Uint16(data[offset+22 : offset+24])
 			_ = byteRate   // unused
 			_ = blockAlign // unused
+
+			// WAVE_FORMAT_EXTENSIBLE carries the real format tag in the first
+			// two bytes of its SubFormat GUID (after cbSize, valid bits and
+			// the channel mask). The container bitsPerSample still gives the
+			// sample stride; 24-in-32 audio is read as left-justified 32-bit.
+			if audioFormat == wavFormatExtensible {
+				if chunkSize < 40 || offset+8+40 > len(data) {
+					return nil, fmt.Errorf("extensible fmt chunk too small")
+				}
+				guid := data[offset+32 : offset+48]
+				if string(guid[2:]) != wavSubFormatGUIDTail {
+					return nil, fmt.Errorf("unsupported extensible sub-format GUID %x", guid)
+				}
+				audioFormat = binary.LittleEndian.Uint16(guid[0:2])
+			}
 		} else if chunkID == "data" {
+			if numChannels == 0 || bitsPerSample == 0 {
+				return nil, fmt.Errorf("data chunk without a preceding valid fmt chunk")
+			}
 			dataStart := offset + 8
 			dataEnd := dataStart + int(chunkSize)
-			if dataEnd > len(data) {
+			// Streaming encoders and browsers write the header before the
+			// length is known, leaving 0xFFFFFFFF (or 0) as the size. Treat
+			// either as "until end of file".
+			if chunkSize == wavUnknownSize || chunkSize == 0 || dataEnd > len(data) {
 				dataEnd = len(data)
 			}
 			audioData := data[dataStart:dataEnd]
@@ -75,7 +109,7 @@ func parseWAV(data []byte) ([]float32, error) {
 			}
 
 			// Convert to float32
-			samples, err := convertToFloat32(audioData, audioFormat, numChannels, bitsPerSample)
+			planes, err := convertToFloat32(audioData, audioFormat, numChannels, bitsPerSample, mode)
 			if err != nil {
 				return nil, err
 			}
@@ -86,14 +120,18 @@ func parseWAV(data []byte) ([]float32, error) {
 					slog.Debug("resampling",
 						"from", sampleRate,
 						"to", 16000,
-						"samplesIn", len(samples),
-						"samplesOut", int(float64(len(samples))*16000.0/float64(sampleRate)),
+						"channels", len(planes),
+						"samplesIn", len(planes[0]),
+						"samplesOut", int(float64(len(planes[0]))*16000.0/float64(sampleRate)),
+						"quality", quality,
 					)
 				}
-				samples = resample(samples, int(sampleRate), 16000)
+				for i := range planes {
+					planes[i] = resample(planes[i], int(sampleRate), 16000, quality)
+				}
 			}
 
-			return samples, nil
+			return planes, nil
 		}
 
 		offset += 8 + int(chunkSize)
@@ -105,14 +143,65 @@ func parseWAV(data []byte) ([]float32, error) {
 	return nil, fmt.Errorf("no data chunk found")
 }
 
-func convertToFloat32(data []byte, audioFormat, numChannels, bitsPerSample uint16) ([]float32, error) {
-	if audioFormat != 1 && audioFormat != 3 {
-		return nil, fmt.Errorf("unsupported audio format: %d (only PCM supported)", audioFormat)
+// WAV format tags understood by convertToFloat32.
+const (
+	wavFormatPCM       = 1
+	wavFormatIEEEFloat = 3
+	wavFormatALaw      = 6 // G.711 A-law
+	wavFormatMuLaw     = 7 // G.711 µ-law
+	// wavFormatExtensible defers the real format to a SubFormat GUID.
+	wavFormatExtensible = 0xFFFE
+)
+
+// wavSubFormatGUIDTail is the fixed part of every KSDATAFORMAT_SUBTYPE_* GUID
+// (xxxxxxxx-0000-0010-8000-00aa00389b71 in little-endian byte order); the
+// first two bytes are the plain format tag.
+const wavSubFormatGUIDTail = "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71"
+
+// wavUnknownSize is the chunk size streaming writers leave in the header when
+// the final length is not known up front.
+const wavUnknownSize = 0xFFFFFFFF
+
+// convertToFloat32 decodes interleaved samples and arranges them by channel
+// mode: ChannelMix averages every channel into one plane, ChannelLeft and
+// ChannelRight keep a single channel (a mono file serves both), and
+// ChannelPerChannel returns one plane per channel.
+func convertToFloat32(data []byte, audioFormat, numChannels, bitsPerSample uint16, mode ChannelMode) ([][]float32, error) {
+	switch audioFormat {
+	case wavFormatPCM, wavFormatIEEEFloat:
+	case wavFormatALaw, wavFormatMuLaw:
+		// Companded telephony audio: always one byte per sample.
+		if bitsPerSample != 8 {
+			return nil, fmt.Errorf("unsupported bits per sample for G.711: %d (want 8)", bitsPerSample)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported audio format: %d (supported: PCM, IEEE float, A-law, µ-law)", audioFormat)
 	}
 
 	bytesPerSample := int(bitsPerSample / 8)
+	if bytesPerSample == 0 {
+		return nil, fmt.Errorf("unsupported bits per sample: %d", bitsPerSample)
+	}
 	numSamples := len(data) / (bytesPerSample * int(numChannels))
-	samples := make([]float32, numSamples)
+
+	// Output plane p reads source channel srcChannel[p]; a nil entry marks
+	// the single mixed plane.
+	var srcChannel []int
+	switch mode {
+	case ChannelLeft:
+		srcChannel = []int{0}
+	case ChannelRight:
+		srcChannel = []int{min(1, int(numChannels)-1)}
+	case ChannelPerChannel:
+		for ch := 0; ch < int(numChannels); ch++ {
+			srcChannel = append(srcChannel, ch)
+		}
+	}
+	numPlanes := max(len(srcChannel), 1)
+	planes := make([][]float32, numPlanes)
+	for p := range planes {
+		planes[p] = make([]float32, numSamples)
+	}
 
 	for i := 0; i < numSamples; i++ {
 		var sum float64
@@ -123,15 +212,19 @@ func convertToFloat32(data []byte, audioFormat, numChannels, bitsPerSample uint1
 			}
 
 			var val float64
-			switch bitsPerSample {
-			case 8:
+			switch {
+			case audioFormat == wavFormatMuLaw:
+				val = float64(decodeMuLaw(data[offset])) / 32768.0
+			case audioFormat == wavFormatALaw:
+				val = float64(decodeALaw(data[offset])) / 32768.0
+			case bitsPerSample == 8:
 				// Unsigned 8-bit
 				val = float64(data[offset])/128.0 - 1.0
-			case 16:
+			case bitsPerSample == 16:
 				// Signed 16-bit little endian
 				sample := int16(binary.LittleEndian.Uint16(data[offset : offset+2]))
 				val = float64(sample) / 32768.0
-			case 24:
+			case bitsPerSample == 24:
 				// Signed 24-bit little endian
 				b := data[offset : offset+3]
 				sample := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16
@@ -139,8 +232,8 @@ func convertToFloat32(data []byte, audioFormat, numChannels, bitsPerSample uint1
 					sample |= ^0xffffff // Sign extend
 				}
 				val = float64(sample) / 8388608.0
-			case 32:
-				if audioFormat == 3 {
+			case bitsPerSample == 32:
+				if audioFormat == wavFormatIEEEFloat {
 					// Float 32-bit
 					bits := binary.LittleEndian.Uint32(data[offset : offset+4])
 					val = float64(math.Float32frombits(bits))
@@ -152,35 +245,52 @@ func convertToFloat32(data []byte, audioFormat, numChannels, bitsPerSample uint1
 			default:
 				return nil, fmt.Errorf("unsupported bits per sample: %d", bitsPerSample)
 			}
-			sum += val
+			if srcChannel == nil {
+				sum += val
+				continue
+			}
+			for p, src := range srcChannel {
+				if src == ch {
+					planes[p][i] = float32(val)
+				}
+			}
+		}
+		if srcChannel == nil {
+			// Average channels (convert stereo to mono)
+			planes[0][i] = float32(sum / float64(numChannels))
 		}
-		// Average channels (convert stereo to mono)
-		samples[i] = float32(sum / float64(numChannels))
 	}
 
-	return samples, nil
+	return planes, nil
 }
 
-// resample uses linear interpolation for simple resampling
-func resample(samples []float32, srcRate, dstRate int) []float32 {
-	if srcRate == dstRate {
-		return samples
+// decodeMuLaw expands one G.711 µ-law byte to a 16-bit linear sample
+// (ITU-T G.711, as in the reference Sun/CCITT implementation).
+func decodeMuLaw(b byte) int16 {
+	u := ^b
+	t := (int(u&0x0f) << 3) + 0x84
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(0x84 - t)
 	}
+	return int16(t - 0x84)
+}
 
-	ratio := float64(srcRate) / float64(dstRate)
-	newLen := int(float64(len(samples)) / ratio)
-	result := make([]float32, newLen)
-
-	for i := 0; i < newLen; i++ {
-		srcIdx := float64(i) * ratio
-		lo := int(srcIdx)
-		hi := lo + 1
-		if hi >= len(samples) {
-			hi = len(samples) - 1
-		}
-		frac := float32(srcIdx - float64(lo))
-		result[i] = samples[lo]*(1-frac) + samples[hi]*frac
+// decodeALaw expands one G.711 A-law byte to a 16-bit linear sample.
+func decodeALaw(b byte) int16 {
+	a := b ^ 0x55
+	t := int(a&0x0f) << 4
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
 	}
-
-	return result
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
 }
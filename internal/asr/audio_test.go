@@ -83,6 +83,137 @@ func TestLoadAudioAcceptsWAV(t *testing.T) {
 	}
 }
 
+// buildG711WAV wraps 8-bit companded samples in a WAV with the given format
+// tag (6 = A-law, 7 = µ-law), as written by telephony recorders.
+func buildG711WAV(t *testing.T, format uint16, sampleRate uint32, payload []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(4+26+8+len(payload)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(18))
+	_ = binary.Write(&buf, binary.LittleEndian, format)
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&buf, binary.LittleEndian, sampleRate)
+	_ = binary.Write(&buf, binary.LittleEndian, sampleRate)
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(8))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(0)) // cbSize
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(payload)))
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+// Reference points from the ITU-T G.711 tables.
+func TestDecodeG711(t *testing.T) {
+	mu := map[byte]int16{0xFF: 0, 0x7F: 0, 0x80: 32124, 0x00: -32124, 0x8F: 16764, 0xFE: 8}
+	for in, want := range mu {
+		if got := decodeMuLaw(in); got != want {
+			t.Errorf("decodeMuLaw(%#x) = %d, want %d", in, got, want)
+		}
+	}
+	alaw := map[byte]int16{0xD5: 8, 0x55: -8, 0xAA: 32256, 0x2A: -32256}
+	for in, want := range alaw {
+		if got := decodeALaw(in); got != want {
+			t.Errorf("decodeALaw(%#x) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+// 8 kHz G.711 WAVs must decode and come out at 16 kHz (twice the samples).
+func TestLoadAudioAcceptsG711(t *testing.T) {
+	tr := &Transcriber{}
+	payload := make([]byte, 800) // 100 ms
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	for _, format := range []uint16{6, 7} {
+		samples, err := tr.loadAudio(buildG711WAV(t, format, 8000, payload), "")
+		if err != nil {
+			t.Fatalf("format %d: unexpected error: %v", format, err)
+		}
+		if len(samples) != 1600 {
+			t.Fatalf("format %d: got %d samples, want 1600 at 16 kHz", format, len(samples))
+		}
+	}
+
+	// G.711 is 8 bits per sample by definition.
+	bad := buildG711WAV(t, 7, 8000, payload)
+	binary.LittleEndian.PutUint16(bad[34:36], 16)
+	if _, err := tr.loadAudio(bad, ""); err == nil {
+		t.Fatal("expected an error for 16-bit µ-law")
+	}
+}
+
+// buildExtensibleWAV writes 16-bit PCM samples with a WAVE_FORMAT_EXTENSIBLE
+// fmt chunk whose SubFormat GUID carries the given format tag. dataSize is
+// written verbatim so streaming headers (0xFFFFFFFF) can be simulated.
+func buildExtensibleWAV(t *testing.T, subFormat uint16, samples []int16, dataSize uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(0xFFFFFFFF))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(40))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(0xFFFE))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16000))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(32000))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(22)) // cbSize
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(16)) // valid bits
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(4))  // channel mask: front centre
+	_ = binary.Write(&buf, binary.LittleEndian, subFormat)
+	buf.WriteString(wavSubFormatGUIDTail)
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, dataSize)
+	for _, v := range samples {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+func TestParseWAV_Extensible(t *testing.T) {
+	in := []int16{0, 16384, -16384, 32767}
+	want := []float32{0, 0.5, -0.5, 32767.0 / 32768.0}
+
+	for _, tc := range []struct {
+		name     string
+		dataSize uint32
+	}{
+		{"sized data chunk", uint32(len(in) * 2)},
+		{"streaming 0xFFFFFFFF size", 0xFFFFFFFF},
+		{"streaming zero size", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseWAV(buildExtensibleWAV(t, 1, in, tc.dataSize), ResampleMedium)
+			if err != nil {
+				t.Fatalf("parseWAV: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d samples, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("sample %d = %v, want %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseWAV_ExtensibleRejectsUnknownGUID(t *testing.T) {
+	wav := buildExtensibleWAV(t, 1, []int16{1, 2}, 4)
+	wav[46] ^= 0xFF // corrupt the GUID tail
+	if _, err := parseWAV(wav, ResampleMedium); err == nil {
+		t.Fatal("expected an error for a non-standard SubFormat GUID")
+	}
+}
+
 func TestLoadAudioRejectsNonWAVWhenFFmpegDisabled(t *testing.T) {
 	tr := &Transcriber{ffmpeg: nil}
 
@@ -167,7 +298,7 @@ func TestFFmpegConverterConcurrentTempFiles(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_, err := conv.Convert(payload)
+			_, err := conv.Convert(payload, false)
 			if err != nil && !errors.Is(err, ErrUnsupportedAudio) {
 				t.Errorf("unexpected error class: %v", err)
 			}
@@ -0,0 +1,365 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// DefaultDecoderBatchWait is how long a decoder step waits for the other
+// running decodes when DecoderBatchConfig.Wait is zero.
+const DefaultDecoderBatchWait = time.Millisecond
+
+// DecoderBatchConfig runs the decoder steps of concurrent decodes together:
+// each decode still steps through its window one frame at a time, but the
+// steps pending at once go to the joint network as one ONNX call with a row
+// each, instead of a call per step on the decode's own session. Under load
+// that turns many small calls into a few wide ones. A Size of 0 or 1
+// disables it.
+//
+// With batching on, decoder workers no longer own a session, only the
+// tensors of a decode, so -workers can be raised to the decodes that
+// should share a batch at little memory cost.
+type DecoderBatchConfig struct {
+	// Size caps the steps of one ONNX call.
+	Size int
+	// Wait bounds how long a batch waits for the steps of running decodes
+	// that have not submitted theirs yet; it runs as soon as every running
+	// decode has. Zero means DefaultDecoderBatchWait.
+	Wait time.Duration
+}
+
+// decodeStep is one decoder step of a decode: its inputs and where its
+// outputs go, all shaped as a batch of one. A decoderWorker's step points
+// at its tensors.
+type decodeStep struct {
+	encFrame           []float32 // encoderDim
+	target             []int32   // one token
+	state1In, state2In []float32 // decoderNumLayers x decoderStateDim
+	output             []float32 // the joint network's logits
+	state1Out          []float32
+	state2Out          []float32
+	done               chan error // cap 1; receives the step's outcome
+}
+
+// stepBatcher runs the decodeSteps submitted while another batch runs, or
+// while it waits for the other running decodes, as one batch. infer runs
+// the model over the first n rows of the batch buffers; only the batcher
+// goroutine calls it.
+type stepBatcher struct {
+	size      int
+	wait      time.Duration
+	outputDim int
+	infer     func(n int) error
+	destroy   func() // releases what infer holds, after the goroutine ends
+
+	// Batch buffers, for size rows. The state buffers are laid out as the
+	// model's [layers, n, stateDim]: a batch of n uses their first
+	// layers*n*stateDim values.
+	encOut    []float32
+	targets   []int32
+	targetLen []int32 // all ones: each row is a single step
+	state1In  []float32
+	state2In  []float32
+	output    []float32
+	state1Out []float32
+	state2Out []float32
+
+	mu      sync.Mutex
+	active  int // decodes between begin and end
+	pending []*decodeStep
+	since   time.Time // when the oldest pending step came
+	runs    int64
+	steps   int64
+
+	wake    chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+// newStepBatcher starts a batcher over infer, or returns nil when cfg
+// leaves batching off.
+func newStepBatcher(cfg DecoderBatchConfig, outputDim int, infer func(n int) error) *stepBatcher {
+	if cfg.Size <= 1 {
+		return nil
+	}
+	if cfg.Wait <= 0 {
+		cfg.Wait = DefaultDecoderBatchWait
+	}
+	states := int(decoderNumLayers*decoderStateDim) * cfg.Size
+	b := &stepBatcher{
+		size:      cfg.Size,
+		wait:      cfg.Wait,
+		outputDim: outputDim,
+		infer:     infer,
+		encOut:    make([]float32, int(encoderDim)*cfg.Size),
+		targets:   make([]int32, cfg.Size),
+		targetLen: make([]int32, cfg.Size),
+		state1In:  make([]float32, states),
+		state2In:  make([]float32, states),
+		output:    make([]float32, outputDim*cfg.Size),
+		state1Out: make([]float32, states),
+		state2Out: make([]float32, states),
+		wake:      make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	for i := range b.targetLen {
+		b.targetLen[i] = 1
+	}
+	go b.loop()
+	return b
+}
+
+// newSessionBatcher creates a batcher running the decoder model on a
+// session of its own. The export must take a dynamic batch dimension.
+func newSessionBatcher(decoder onnxModel, cfg DecoderBatchConfig, outputDim int64, io map[string]ort.TensorElementDataType, sessOpts *ort.SessionOptions) (*stepBatcher, error) {
+	if cfg.Size <= 1 {
+		return nil, nil
+	}
+	inputs, _, err := decoder.inputOutputInfo(sessOpts)
+	if err != nil {
+		return nil, fmt.Errorf("inspect decoder model: %w", err)
+	}
+	for _, in := range inputs {
+		if in.Name == "encoder_outputs" && len(in.Dimensions) > 0 && in.Dimensions[0] > 0 {
+			return nil, fmt.Errorf("the decoder export has a fixed batch size of %d; batching needs one exported with a dynamic batch dimension", in.Dimensions[0])
+		}
+	}
+	session, err := decoder.newDynamicSession(
+		[]string{"encoder_outputs", "targets", "target_length", "input_states_1", "input_states_2"},
+		[]string{"outputs", "output_states_1", "output_states_2"},
+		sessOpts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create decoder batch session: %w", err)
+	}
+	var b *stepBatcher
+	batches := make([]*batchTensors, cfg.Size+1) // by n, created on first use
+	b = newStepBatcher(cfg, int(outputDim), func(n int) error {
+		if batches[n] == nil {
+			bt, err := b.newBatchTensors(n, io)
+			if err != nil {
+				return err
+			}
+			batches[n] = bt
+		}
+		return batches[n].run(session)
+	})
+	b.destroy = func() {
+		for _, bt := range batches {
+			if bt != nil {
+				bt.destroy()
+			}
+		}
+		session.Destroy()
+	}
+	return b, nil
+}
+
+// batchTensors are the session inputs and outputs of a batch of n steps,
+// over the first n rows of the batcher's buffers.
+type batchTensors struct {
+	encOut, state1In, state2In   *floatTensor
+	output, state1Out, state2Out *floatTensor
+	targets, targetLen           *ort.Tensor[int32]
+}
+
+func (b *stepBatcher) newBatchTensors(n int, io map[string]ort.TensorElementDataType) (*batchTensors, error) {
+	bt := &batchTensors{}
+	states := ort.NewShape(decoderNumLayers, int64(n), decoderStateDim)
+	stateLen := int(decoderNumLayers*decoderStateDim) * n
+	var err error
+	for _, f := range []struct {
+		dst   **floatTensor
+		name  string
+		shape ort.Shape
+		data  []float32
+	}{
+		{&bt.encOut, "encoder_outputs", ort.NewShape(int64(n), encoderDim, 1), b.encOut[:int(encoderDim)*n]},
+		{&bt.state1In, "input_states_1", states, b.state1In[:stateLen]},
+		{&bt.state2In, "input_states_2", states, b.state2In[:stateLen]},
+		{&bt.output, "outputs", ort.NewShape(int64(n), 1, 1, int64(b.outputDim)), b.output[:b.outputDim*n]},
+		{&bt.state1Out, "output_states_1", states, b.state1Out[:stateLen]},
+		{&bt.state2Out, "output_states_2", states, b.state2Out[:stateLen]},
+	} {
+		if *f.dst, err = newFloatTensor(io[f.name], f.shape, f.data); err != nil {
+			bt.destroy()
+			return nil, fmt.Errorf("create %s tensor for a batch of %d: %w", f.name, n, err)
+		}
+	}
+	if bt.targets, err = ort.NewTensor(ort.NewShape(int64(n), 1), b.targets[:n]); err != nil {
+		bt.destroy()
+		return nil, fmt.Errorf("create targets tensor for a batch of %d: %w", n, err)
+	}
+	if bt.targetLen, err = ort.NewTensor(ort.NewShape(int64(n)), b.targetLen[:n]); err != nil {
+		bt.destroy()
+		return nil, fmt.Errorf("create target_length tensor for a batch of %d: %w", n, err)
+	}
+	return bt, nil
+}
+
+// run runs session over the tensors' current contents.
+func (bt *batchTensors) run(session *ort.DynamicAdvancedSession) error {
+	bt.encOut.toModel()
+	bt.state1In.toModel()
+	bt.state2In.toModel()
+	err := session.Run(
+		[]ort.Value{bt.encOut.value(), bt.targets, bt.targetLen, bt.state1In.value(), bt.state2In.value()},
+		[]ort.Value{bt.output.value(), bt.state1Out.value(), bt.state2Out.value()},
+	)
+	if err != nil {
+		return err
+	}
+	bt.output.fromModel()
+	bt.state1Out.fromModel()
+	bt.state2Out.fromModel()
+	return nil
+}
+
+func (bt *batchTensors) destroy() {
+	for _, f := range []*floatTensor{bt.encOut, bt.state1In, bt.state2In, bt.output, bt.state1Out, bt.state2Out} {
+		if f != nil {
+			f.Destroy()
+		}
+	}
+	if bt.targets != nil {
+		bt.targets.Destroy()
+	}
+	if bt.targetLen != nil {
+		bt.targetLen.Destroy()
+	}
+}
+
+// begin registers a decode that will submit steps; end unregisters it. A
+// batch waits for the steps of registered decodes only.
+func (b *stepBatcher) begin() {
+	b.mu.Lock()
+	b.active++
+	b.mu.Unlock()
+}
+
+func (b *stepBatcher) end() {
+	b.mu.Lock()
+	b.active--
+	b.mu.Unlock()
+	b.signal() // the pending steps may be all there is now
+}
+
+// step runs s in the next batch and returns once its outputs are written.
+func (b *stepBatcher) step(s *decodeStep) error {
+	b.mu.Lock()
+	if len(b.pending) == 0 {
+		b.since = time.Now()
+	}
+	b.pending = append(b.pending, s)
+	b.mu.Unlock()
+	b.signal()
+	return <-s.done
+}
+
+func (b *stepBatcher) signal() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop runs the batches until close.
+func (b *stepBatcher) loop() {
+	defer close(b.stopped)
+	for {
+		b.mu.Lock()
+		batch, wait := b.next()
+		b.mu.Unlock()
+		if batch != nil {
+			b.run(batch)
+			continue
+		}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-b.wake:
+		case <-timeout:
+		case <-b.quit:
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// next takes the steps of the next batch off pending once it is due: it is
+// full, every running decode is in it, or its oldest step waited long
+// enough. Otherwise it returns how long until then, zero with no step
+// pending.
+func (b *stepBatcher) next() ([]*decodeStep, time.Duration) {
+	if len(b.pending) == 0 {
+		return nil, 0
+	}
+	if waited := time.Since(b.since); len(b.pending) < b.size && len(b.pending) < b.active && waited < b.wait {
+		return nil, b.wait - waited
+	}
+	n := min(len(b.pending), b.size)
+	batch := make([]*decodeStep, n)
+	copy(batch, b.pending)
+	b.pending = append(b.pending[:0], b.pending[n:]...)
+	b.runs++
+	b.steps += int64(n)
+	return batch, 0
+}
+
+// run gathers the steps into the batch buffers, runs them and scatters the
+// outputs back.
+func (b *stepBatcher) run(batch []*decodeStep) {
+	n := len(batch)
+	layer := int(decoderStateDim)
+	for i, s := range batch {
+		copy(b.encOut[i*int(encoderDim):(i+1)*int(encoderDim)], s.encFrame)
+		b.targets[i] = s.target[0]
+		for l := 0; l < int(decoderNumLayers); l++ {
+			row := (l*n + i) * layer
+			copy(b.state1In[row:row+layer], s.state1In[l*layer:(l+1)*layer])
+			copy(b.state2In[row:row+layer], s.state2In[l*layer:(l+1)*layer])
+		}
+	}
+	err := b.infer(n)
+	for i, s := range batch {
+		if err == nil {
+			copy(s.output, b.output[i*b.outputDim:(i+1)*b.outputDim])
+			for l := 0; l < int(decoderNumLayers); l++ {
+				row := (l*n + i) * layer
+				copy(s.state1Out[l*layer:(l+1)*layer], b.state1Out[row:row+layer])
+				copy(s.state2Out[l*layer:(l+1)*layer], b.state2Out[row:row+layer])
+			}
+		}
+		s.done <- err
+	}
+}
+
+// stats returns the batches run and the steps they held.
+func (b *stepBatcher) stats() (runs, steps int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.runs, b.steps
+}
+
+// close stops the batcher and releases its session. Close calls it once no
+// decode runs.
+func (b *stepBatcher) close() {
+	close(b.quit)
+	<-b.stopped
+	if b.destroy != nil {
+		b.destroy()
+	}
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testOutputDim = 4
+
+// newTestStep returns a step whose inputs are marked with target.
+func newTestStep(target int32) *decodeStep {
+	states := int(decoderNumLayers * decoderStateDim)
+	s := &decodeStep{
+		encFrame:  make([]float32, encoderDim),
+		target:    []int32{target},
+		state1In:  make([]float32, states),
+		state2In:  make([]float32, states),
+		output:    make([]float32, testOutputDim),
+		state1Out: make([]float32, states),
+		state2Out: make([]float32, states),
+		done:      make(chan error, 1),
+	}
+	s.encFrame[0] = float32(target)
+	for l := 0; l < int(decoderNumLayers); l++ {
+		s.state1In[l*int(decoderStateDim)] = float32(100*target) + float32(l)
+		s.state2In[l*int(decoderStateDim)] = float32(-100*target) - float32(l)
+	}
+	return s
+}
+
+// fakeInfer returns an infer that records the batch sizes and writes each
+// row's target and encoder frame to its logits and its states plus one to
+// its output states, as a joint network that mixed up rows would not.
+func fakeInfer(b **stepBatcher, sizes *[]int, mu *sync.Mutex) func(n int) error {
+	return func(n int) error {
+		mu.Lock()
+		*sizes = append(*sizes, n)
+		mu.Unlock()
+		bt := *b
+		for i := 0; i < n; i++ {
+			bt.output[i*testOutputDim] = float32(bt.targets[i])
+			bt.output[i*testOutputDim+1] = bt.encOut[i*int(encoderDim)]
+			for l := 0; l < int(decoderNumLayers); l++ {
+				row := (l*n + i) * int(decoderStateDim)
+				bt.state1Out[row] = bt.state1In[row] + 1
+				bt.state2Out[row] = bt.state2In[row] + 1
+			}
+		}
+		return nil
+	}
+}
+
+func checkStep(t *testing.T, s *decodeStep) {
+	t.Helper()
+	target := s.target[0]
+	if s.output[0] != float32(target) || s.output[1] != float32(target) {
+		t.Errorf("step %d: output = %v", target, s.output)
+	}
+	for l := 0; l < int(decoderNumLayers); l++ {
+		at := l * int(decoderStateDim)
+		if s.state1Out[at] != s.state1In[at]+1 || s.state2Out[at] != s.state2In[at]+1 {
+			t.Errorf("step %d layer %d: states out %g %g for %g %g", target, l, s.state1Out[at], s.state2Out[at], s.state1In[at], s.state2In[at])
+		}
+	}
+}
+
+func TestStepBatcherRunsConcurrentStepsTogether(t *testing.T) {
+	var (
+		b     *stepBatcher
+		sizes []int
+		mu    sync.Mutex
+	)
+	// A long wait: the batch must run because every decode is in it.
+	b = newStepBatcher(DecoderBatchConfig{Size: 8, Wait: time.Minute}, testOutputDim, fakeInfer(&b, &sizes, &mu))
+	defer b.close()
+
+	steps := []*decodeStep{newTestStep(1), newTestStep(2), newTestStep(3)}
+	for range steps {
+		b.begin()
+	}
+	var wg sync.WaitGroup
+	for _, s := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer b.end()
+			if err := b.step(s); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, s := range steps {
+		checkStep(t, s)
+	}
+	if len(sizes) != 1 || sizes[0] != 3 {
+		t.Errorf("batches = %v, want [3]", sizes)
+	}
+	if runs, n := b.stats(); runs != 1 || n != 3 {
+		t.Errorf("stats = %d runs, %d steps", runs, n)
+	}
+}
+
+func TestStepBatcherBounds(t *testing.T) {
+	var (
+		b     *stepBatcher
+		sizes []int
+		mu    sync.Mutex
+	)
+	b = newStepBatcher(DecoderBatchConfig{Size: 2, Wait: 20 * time.Millisecond}, testOutputDim, fakeInfer(&b, &sizes, &mu))
+	defer b.close()
+
+	// A decode that never submits holds a step back for Wait only.
+	b.begin()
+	b.begin()
+	start := time.Now()
+	s := newTestStep(7)
+	if err := b.step(s); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("step ran after %s, before the wait", waited)
+	}
+	checkStep(t, s)
+	b.end()
+	b.end()
+
+	// No batch is larger than Size.
+	sizes = nil
+	steps := make([]*decodeStep, 5)
+	for i := range steps {
+		steps[i] = newTestStep(int32(i + 1))
+		b.begin()
+	}
+	var wg sync.WaitGroup
+	for _, s := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer b.end()
+			if err := b.step(s); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	total := 0
+	for _, n := range sizes {
+		if n > 2 {
+			t.Errorf("batch of %d over the size of 2", n)
+		}
+		total += n
+	}
+	if total != 5 {
+		t.Errorf("batches = %v, want 5 steps", sizes)
+	}
+	for _, s := range steps {
+		checkStep(t, s)
+	}
+}
+
+func TestStepBatcherError(t *testing.T) {
+	failed := errors.New("run failed")
+	b := newStepBatcher(DecoderBatchConfig{Size: 4}, testOutputDim, func(int) error { return failed })
+	defer b.close()
+	b.begin()
+	defer b.end()
+	if err := b.step(newTestStep(1)); !errors.Is(err, failed) {
+		t.Errorf("step error = %v", err)
+	}
+	if newStepBatcher(DecoderBatchConfig{Size: 1}, testOutputDim, nil) != nil {
+		t.Error("a batch size of 1 did not leave batching off")
+	}
+}
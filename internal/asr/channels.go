@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChannelMode selects how multi-channel recordings are turned into model
+// input. Stereo call recordings usually carry one speaker per channel, which
+// downmixing destroys.
+type ChannelMode string
+
+const (
+	// ChannelMix averages all channels into mono (the default).
+	ChannelMix ChannelMode = "mix"
+	// ChannelLeft and ChannelRight transcribe a single channel. A mono file
+	// serves both.
+	ChannelLeft  ChannelMode = "left"
+	ChannelRight ChannelMode = "right"
+	// ChannelPerChannel transcribes every channel separately and merges the
+	// results in time order, labelling each segment with its channel.
+	ChannelPerChannel ChannelMode = "per_channel"
+)
+
+// ParseChannelMode normalizes a user-supplied channel mode. An empty value
+// defaults to mix; unknown values are rejected.
+func ParseChannelMode(s string) (ChannelMode, error) {
+	switch m := ChannelMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ChannelMix, nil
+	case ChannelMix, ChannelLeft, ChannelRight, ChannelPerChannel:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported channel mode %q (supported: mix, left, right, per_channel)", s)
+	}
+}
+
+// channelTurnGapSeconds splits one channel's transcript into separate
+// segments wherever its speaker is silent for at least this long, so the
+// merged per-channel transcript interleaves like a conversation instead of
+// printing one channel after the other.
+const channelTurnGapSeconds = 1.0
+
+// channelSegments groups one channel's tokens into segments at word
+// boundaries separated by a pause of channelTurnGapSeconds or more.
+func (t *Transcriber) channelSegments(tokens []decodedToken, channel int) []Segment {
+	frameSec := t.encoderFrameSeconds()
+	gapFrames := int64(channelTurnGapSeconds / frameSec)
+
+	var segments []Segment
+	start := 0
+	flush := func(end int) {
+		if text := t.tokensToText(tokens[start:end]); text != "" {
+			segments = append(segments, Segment{
+				Channel: channel,
+				Start:   float64(tokens[start].timestep) * frameSec,
+				End:     float64(tokens[end-1].timestep+1) * frameSec,
+				Text:    text,
+			})
+		}
+		start = end
+	}
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].timestep-tokens[i-1].timestep >= gapFrames && strings.HasPrefix(t.tokenText(tokens[i].id), " ") {
+			flush(i)
+		}
+	}
+	if start < len(tokens) {
+		flush(len(tokens))
+	}
+	return segments
+}
+
+// mergeChannelSegments orders segments from all channels by start time (ties
+// by channel) and renders the labelled transcript, one segment per line.
+func mergeChannelSegments(segments []Segment) ([]Segment, string) {
+	sort.SliceStable(segments, func(i, j int) bool {
+		if segments[i].Start != segments[j].Start {
+			return segments[i].Start < segments[j].Start
+		}
+		return segments[i].Channel < segments[j].Channel
+	})
+	lines := make([]string, len(segments))
+	for i, seg := range segments {
+		lines[i] = ChannelLabel(seg.Channel) + " " + seg.Text
+	}
+	return segments, strings.Join(lines, "\n")
+}
+
+// ChannelLabel is the speaker label used for a channel in merged per-channel
+// transcripts and subtitle cues.
+func ChannelLabel(channel int) string {
+	return fmt.Sprintf("[channel %d]", channel)
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildStereoWAV interleaves two 16-bit PCM channels at 16 kHz.
+func buildStereoWAV(t *testing.T, left, right []int16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	dataSize := uint32(len(left) * 4)
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16000))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(64000))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(4))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, dataSize)
+	for i := range left {
+		_ = binary.Write(&buf, binary.LittleEndian, left[i])
+		_ = binary.Write(&buf, binary.LittleEndian, right[i])
+	}
+	return buf.Bytes()
+}
+
+func TestParseChannelMode(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    ChannelMode
+		wantErr bool
+	}{
+		{"", ChannelMix, false},
+		{"mix", ChannelMix, false},
+		{" Left ", ChannelLeft, false},
+		{"right", ChannelRight, false},
+		{"per_channel", ChannelPerChannel, false},
+		{"both", "", true},
+	}
+	for _, tc := range cases {
+		got, err := ParseChannelMode(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseChannelMode(%q) = %q, %v; want %q, err=%v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestParseWAVChannels_Modes(t *testing.T) {
+	wav := buildStereoWAV(t, []int16{16384, 16384}, []int16{-16384, 0})
+
+	cases := []struct {
+		mode ChannelMode
+		want [][]float32
+	}{
+		{ChannelMix, [][]float32{{0, 0.25}}},
+		{ChannelLeft, [][]float32{{0.5, 0.5}}},
+		{ChannelRight, [][]float32{{-0.5, 0}}},
+		{ChannelPerChannel, [][]float32{{0.5, 0.5}, {-0.5, 0}}},
+	}
+	for _, tc := range cases {
+		got, err := parseWAVChannels(wav, ResampleMedium, tc.mode)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.mode, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: %d planes, want %d", tc.mode, len(got), len(tc.want))
+		}
+		for p := range tc.want {
+			for i := range tc.want[p] {
+				if got[p][i] != tc.want[p][i] {
+					t.Fatalf("%s: plane %d sample %d = %v, want %v", tc.mode, p, i, got[p][i], tc.want[p][i])
+				}
+			}
+		}
+	}
+}
+
+// A mono file has no right channel; every mode must still yield its signal.
+func TestParseWAVChannels_MonoServesEveryMode(t *testing.T) {
+	wav := buildMinimalWAV(t, 16000, 4)
+	for _, mode := range []ChannelMode{ChannelMix, ChannelLeft, ChannelRight, ChannelPerChannel} {
+		got, err := parseWAVChannels(wav, ResampleMedium, mode)
+		if err != nil || len(got) != 1 || len(got[0]) != 4 {
+			t.Fatalf("%s: got %d planes, err %v; want one 4-sample plane", mode, len(got), err)
+		}
+	}
+}
+
+func TestChannelSegments_SplitsAtPauses(t *testing.T) {
+	tr := &Transcriber{
+		config: Config{SubsamplingFactor: 8},
+		mel:    NewMelFilterbank(128, 16000, DefaultMelOptions()),
+		vocab:  map[int]string{1: " hello", 2: " there", 3: "s", 4: " again"},
+	}
+	// 0.08 s frames: the 2 s gap before "again" opens a new turn; the gap
+	// before the "s" continuation does not, since it is mid-word.
+	tokens := []decodedToken{{1, 0, 1}, {2, 3, 1}, {3, 20, 1}, {4, 45, 1}}
+
+	got := tr.channelSegments(tokens, 1)
+	if len(got) != 2 {
+		t.Fatalf("got %d segments, want 2: %+v", len(got), got)
+	}
+	if got[0].Text != "hello theres" || got[1].Text != "again" {
+		t.Fatalf("segment texts = %q, %q", got[0].Text, got[1].Text)
+	}
+	if got[1].Channel != 1 || got[1].Start != 45*0.08 || got[1].End != 46*0.08 {
+		t.Fatalf("second segment = %+v", got[1])
+	}
+}
+
+func TestMergeChannelSegments_InterleavesByStart(t *testing.T) {
+	segments := []Segment{
+		{Channel: 0, Start: 0, End: 1, Text: "hi"},
+		{Channel: 0, Start: 4, End: 5, Text: "fine thanks"},
+		{Channel: 1, Start: 2, End: 3, Text: "how are you"},
+	}
+	merged, text := mergeChannelSegments(segments)
+	if merged[1].Channel != 1 {
+		t.Fatalf("segments not ordered by start: %+v", merged)
+	}
+	want := "[channel 0] hi\n[channel 1] how are you\n[channel 0] fine thanks"
+	if text != want {
+		t.Fatalf("text = %q, want %q", text, want)
+	}
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// GainMode selects how the level of decoded audio is normalized before
+// feature extraction.
+type GainMode string
+
+const (
+	// GainNone leaves the level untouched (the default).
+	GainNone GainMode = "none"
+	// GainPeak scales the signal so its loudest sample sits at
+	// conditionPeakDBFS.
+	GainPeak GainMode = "peak"
+	// GainLoudness scales the RMS of the non-silent part of the signal to
+	// conditionLoudnessDBFS, limited so the peak never exceeds
+	// conditionPeakDBFS and the gain never exceeds conditionMaxGainDB.
+	GainLoudness GainMode = "loudness"
+)
+
+// ParseGainMode normalizes a user-supplied gain mode. An empty value means
+// none; unknown values are rejected.
+func ParseGainMode(s string) (GainMode, error) {
+	switch m := GainMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return GainNone, nil
+	case GainNone, GainPeak, GainLoudness:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported gain mode %q (supported: none, peak, loudness)", s)
+	}
+}
+
+// Conditioning is the optional clean-up chain applied to decoded 16 kHz
+// audio before features are extracted. The zero value does nothing. The
+// steps run in a fixed order: DC removal, silence trim, then gain, so the
+// trim threshold and the gain both see a centred signal and the gain is
+// measured on the audio that is actually transcribed.
+type Conditioning struct {
+	// RemoveDC subtracts the mean of each channel. Cheap sound cards and
+	// some telephony gateways add a constant offset that wastes headroom
+	// and skews the peak normalization.
+	RemoveDC bool
+
+	// Gain selects peak or loudness normalization; empty or none disables it.
+	Gain GainMode
+
+	// TrimSilence drops leading and trailing audio quieter than
+	// conditionSilenceDBFS, keeping conditionTrimPadSeconds on each side.
+	// Segment timestamps still refer to the untrimmed audio.
+	TrimSilence bool
+}
+
+const (
+	conditionPeakDBFS       = -1.0  // peak target, and the loudness limiter ceiling
+	conditionLoudnessDBFS   = -20.0 // RMS target of GainLoudness
+	conditionMaxGainDB      = 30.0  // never amplify by more than this
+	conditionSilenceDBFS    = -50.0 // frames below this RMS count as silence
+	conditionFrameSeconds   = 0.02  // analysis frame for trim and loudness
+	conditionTrimPadSeconds = 0.25  // audio kept around the trimmed speech
+)
+
+// enabled reports whether any step would touch the audio.
+func (c Conditioning) enabled() bool {
+	return c.RemoveDC || c.TrimSilence || (c.Gain != "" && c.Gain != GainNone)
+}
+
+// apply runs the chain over every plane in place and returns the (possibly
+// trimmed) planes together with the number of leading samples removed. All
+// planes are trimmed to the same range so per-channel timestamps stay
+// aligned.
+func (c Conditioning) apply(planes [][]float32) ([][]float32, int) {
+	if c.RemoveDC {
+		for _, p := range planes {
+			removeDC(p)
+		}
+	}
+
+	offset := 0
+	if c.TrimSilence {
+		start, end := speechRange(planes, featureSampleRate)
+		for i := range planes {
+			planes[i] = planes[i][start:end]
+		}
+		offset = start
+	}
+
+	for _, p := range planes {
+		switch c.Gain {
+		case GainPeak:
+			applyGain(p, peakGain(p))
+		case GainLoudness:
+			applyGain(p, loudnessGain(p, featureSampleRate))
+		}
+	}
+	return planes, offset
+}
+
+// removeDC subtracts the mean from x in place.
+func removeDC(x []float32) {
+	if len(x) == 0 {
+		return
+	}
+	var sum float64
+	for _, v := range x {
+		sum += float64(v)
+	}
+	mean := float32(sum / float64(len(x)))
+	for i := range x {
+		x[i] -= mean
+	}
+}
+
+func dbToAmplitude(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func peakAbs(x []float32) float64 {
+	var peak float64
+	for _, v := range x {
+		if a := math.Abs(float64(v)); a > peak {
+			peak = a
+		}
+	}
+	return peak
+}
+
+// peakGain is the factor that brings the loudest sample of x to
+// conditionPeakDBFS. Silent input (below conditionSilenceDBFS) gets unit
+// gain so background hiss is never blown up to full scale.
+func peakGain(x []float32) float64 {
+	peak := peakAbs(x)
+	if peak < dbToAmplitude(conditionSilenceDBFS) {
+		return 1
+	}
+	return math.Min(dbToAmplitude(conditionPeakDBFS)/peak, dbToAmplitude(conditionMaxGainDB))
+}
+
+// loudnessGain is the factor that brings the RMS of the non-silent frames of
+// x to conditionLoudnessDBFS, capped by the peak ceiling and the maximum
+// gain.
+func loudnessGain(x []float32, sampleRate int) float64 {
+	frame := int(conditionFrameSeconds * float64(sampleRate))
+	threshold := dbToAmplitude(conditionSilenceDBFS)
+	var sum float64
+	var n int
+	for start := 0; start < len(x); start += frame {
+		end := min(start+frame, len(x))
+		if frameRMS(x[start:end]) < threshold {
+			continue
+		}
+		for _, v := range x[start:end] {
+			sum += float64(v) * float64(v)
+		}
+		n += end - start
+	}
+	if n == 0 {
+		return 1
+	}
+	gain := dbToAmplitude(conditionLoudnessDBFS) / math.Sqrt(sum/float64(n))
+	if peak := peakAbs(x); peak > 0 {
+		gain = math.Min(gain, dbToAmplitude(conditionPeakDBFS)/peak)
+	}
+	return math.Min(gain, dbToAmplitude(conditionMaxGainDB))
+}
+
+func applyGain(x []float32, gain float64) {
+	if gain == 1 {
+		return
+	}
+	g := float32(gain)
+	for i := range x {
+		x[i] *= g
+	}
+}
+
+func frameRMS(x []float32) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range x {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(x)))
+}
+
+// speechRange returns the sample range [start, end) spanning every frame
+// that is above conditionSilenceDBFS on any plane, widened by
+// conditionTrimPadSeconds. When nothing is above the threshold the full
+// range is returned: an all-quiet upload is transcribed as-is rather than
+// reduced to nothing.
+func speechRange(planes [][]float32, sampleRate int) (int, int) {
+	n := len(planes[0])
+	frame := int(conditionFrameSeconds * float64(sampleRate))
+	threshold := dbToAmplitude(conditionSilenceDBFS)
+
+	first, last := -1, -1
+	for start := 0; start < n; start += frame {
+		end := min(start+frame, n)
+		for _, p := range planes {
+			if frameRMS(p[start:end]) >= threshold {
+				if first < 0 {
+					first = start
+				}
+				last = end
+				break
+			}
+		}
+	}
+	if first < 0 {
+		return 0, n
+	}
+	pad := int(conditionTrimPadSeconds * float64(sampleRate))
+	return max(first-pad, 0), min(last+pad, n)
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseGainMode(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    GainMode
+		wantErr bool
+	}{
+		{"", GainNone, false},
+		{"none", GainNone, false},
+		{" Peak ", GainPeak, false},
+		{"loudness", GainLoudness, false},
+		{"lufs", "", true},
+	}
+	for _, tc := range cases {
+		got, err := ParseGainMode(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseGainMode(%q) = %q, %v; want %q, err=%v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestConditioning_RemoveDC(t *testing.T) {
+	x := tone(440, 16000, 16000)
+	for i := range x {
+		x[i] = 0.1*x[i] + 0.3
+	}
+	planes, _ := Conditioning{RemoveDC: true}.apply([][]float32{x})
+
+	var sum float64
+	for _, v := range planes[0] {
+		sum += float64(v)
+	}
+	if mean := sum / float64(len(planes[0])); math.Abs(mean) > 1e-4 {
+		t.Fatalf("mean after DC removal = %v, want ~0", mean)
+	}
+}
+
+func TestConditioning_Gain(t *testing.T) {
+	quiet := func() []float32 {
+		x := tone(440, 16000, 16000)
+		for i := range x {
+			x[i] *= 0.1 // -20 dBFS peak
+		}
+		return x
+	}
+
+	peak, _ := Conditioning{Gain: GainPeak}.apply([][]float32{quiet()})
+	if got := 20 * math.Log10(peakAbs(peak[0])); math.Abs(got-conditionPeakDBFS) > 0.01 {
+		t.Fatalf("peak after peak normalization = %.2f dBFS, want %.2f", got, conditionPeakDBFS)
+	}
+
+	loud, _ := Conditioning{Gain: GainLoudness}.apply([][]float32{quiet()})
+	if got := 20 * math.Log10(frameRMS(loud[0])); math.Abs(got-conditionLoudnessDBFS) > 0.01 {
+		t.Fatalf("RMS after loudness normalization = %.2f dBFS, want %.2f", got, conditionLoudnessDBFS)
+	}
+
+	// Gain is capped: a -40 dBFS tone only comes up by conditionMaxGainDB.
+	faint := tone(440, 16000, 16000)
+	for i := range faint {
+		faint[i] *= 0.01
+	}
+	capped, _ := Conditioning{Gain: GainPeak}.apply([][]float32{faint})
+	if got := 20 * math.Log10(peakAbs(capped[0])); math.Abs(got-(-40+conditionMaxGainDB)) > 0.01 {
+		t.Fatalf("capped peak = %.2f dBFS, want %.2f", got, -40+conditionMaxGainDB)
+	}
+
+	// Digital silence must not be amplified.
+	silent := make([]float32, 16000)
+	silent[0] = 1e-6
+	out, _ := Conditioning{Gain: GainPeak}.apply([][]float32{silent})
+	if out[0][0] != 1e-6 {
+		t.Fatalf("silence was amplified to %v", out[0][0])
+	}
+}
+
+func TestConditioning_TrimSilence(t *testing.T) {
+	// 1 s silence, 1 s tone, 1 s silence on the left; the right channel is
+	// silent throughout and must be cut to the same range.
+	left := make([]float32, 3*16000)
+	copy(left[16000:], tone(440, 16000, 16000))
+	right := make([]float32, len(left))
+
+	planes, offset := Conditioning{TrimSilence: true}.apply([][]float32{left, right})
+
+	pad := int(conditionTrimPadSeconds * 16000)
+	if offset != 16000-pad {
+		t.Fatalf("offset = %d, want %d", offset, 16000-pad)
+	}
+	if want := 16000 + 2*pad; len(planes[0]) != want || len(planes[1]) != want {
+		t.Fatalf("trimmed lengths = %d, %d; want %d", len(planes[0]), len(planes[1]), want)
+	}
+
+	// All-silent input is kept whole.
+	quiet := make([]float32, 16000)
+	planes, offset = Conditioning{TrimSilence: true}.apply([][]float32{quiet})
+	if offset != 0 || len(planes[0]) != 16000 {
+		t.Fatalf("silent input trimmed to offset %d, %d samples", offset, len(planes[0]))
+	}
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfidenceFilter removes or masks the words decoded with a confidence
+// below Min: a command parser would rather see "turn on the [?]" than act
+// on a word the model guessed. The zero value is off.
+type ConfidenceFilter struct {
+	// Min is the Word.Confidence, 0..1, below which a word is filtered.
+	Min float64
+	// Placeholder replaces the text of a filtered word, which keeps its
+	// timing and confidence; empty drops the word. Either way its tokens
+	// are dropped, and a segment left without words goes with them.
+	Placeholder string
+}
+
+// Validate rejects a Min outside 0..1.
+func (f ConfidenceFilter) Validate() error {
+	if !(f.Min >= 0 && f.Min <= 1) {
+		return fmt.Errorf("min confidence %g is not between 0 and 1", f.Min)
+	}
+	return nil
+}
+
+func (f ConfidenceFilter) enabled() bool {
+	return f.Min > 0
+}
+
+// apply filters the words of res, dropping the tokens of the filtered ones,
+// and rebuilds the text of the segments they were in.
+func (f ConfidenceFilter) apply(res *Result) {
+	var filtered []Segment // the spans of the filtered words
+	words := res.Words[:0]
+	for _, w := range res.Words {
+		if w.Confidence >= f.Min {
+			words = append(words, w)
+			continue
+		}
+		filtered = append(filtered, Segment{Channel: w.Channel, Start: w.Start, End: w.End})
+		if f.Placeholder != "" {
+			w.Text = f.Placeholder
+			words = append(words, w)
+		}
+	}
+	res.Words = words
+	if len(filtered) == 0 {
+		return
+	}
+	res.Tokens = filterSpans(res.Tokens, filtered, func(t Token) (int, float64) { return t.Channel, t.Start })
+
+	segments := res.Segments[:0]
+	for _, seg := range res.Segments {
+		var texts []string
+		touched := false
+		for _, w := range res.Words {
+			if w.Channel == seg.Channel && w.Start >= seg.Start && w.Start < seg.End {
+				texts = append(texts, w.Text)
+			}
+		}
+		for _, span := range filtered {
+			if span.Channel == seg.Channel && span.Start >= seg.Start && span.Start < seg.End {
+				touched = true
+				break
+			}
+		}
+		if !touched {
+			segments = append(segments, seg)
+			continue
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		seg.Text = strings.Join(texts, " ")
+		segments = append(segments, seg)
+	}
+	res.Segments = segments
+	rebuildText(res)
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import "testing"
+
+func confidenceResult() *Result {
+	return &Result{
+		Text:     "Turn on the lamp. Thanks.",
+		Channels: 1,
+		Segments: []Segment{
+			{Start: 0, End: 2, Text: "Turn on the lamp."},
+			{Start: 3, End: 4, Text: "Thanks."},
+		},
+		Words: []Word{
+			{Start: 0, End: 0.3, Text: "Turn", Confidence: 0.95},
+			{Start: 0.3, End: 0.5, Text: "on", Confidence: 0.9},
+			{Start: 0.5, End: 0.7, Text: "the", Confidence: 0.9},
+			{Start: 0.7, End: 1.2, Text: "lamp.", Confidence: 0.3},
+			{Start: 3, End: 3.5, Text: "Thanks.", Confidence: 0.2},
+		},
+		Tokens: []Token{{Start: 0, Text: " Turn"}, {Start: 0.7, Text: " lamp"}, {Start: 1.1, Text: "."}, {Start: 3, Text: " Thanks"}},
+	}
+}
+
+func TestConfidenceFilter(t *testing.T) {
+	res := confidenceResult()
+	ConfidenceFilter{Min: 0.5, Placeholder: "[?]"}.apply(res)
+	if res.Text != "Turn on the [?] [?]" || len(res.Segments) != 2 || len(res.Words) != 5 || res.Words[3].Text != "[?]" || res.Words[3].End != 1.2 {
+		t.Errorf("masked: %q %+v %+v", res.Text, res.Segments, res.Words)
+	}
+	if len(res.Tokens) != 1 || res.Tokens[0].Text != " Turn" {
+		t.Errorf("tokens of masked words kept: %+v", res.Tokens)
+	}
+
+	res = confidenceResult()
+	ConfidenceFilter{Min: 0.5}.apply(res)
+	if res.Text != "Turn on the" || len(res.Segments) != 1 || len(res.Words) != 3 {
+		t.Errorf("dropped: %q %+v %+v", res.Text, res.Segments, res.Words)
+	}
+
+	res = confidenceResult()
+	ConfidenceFilter{Min: 0.1}.apply(res)
+	if res.Text != "Turn on the lamp. Thanks." || len(res.Tokens) != 4 {
+		t.Errorf("nothing under the threshold, yet changed: %+v", res)
+	}
+
+	if (ConfidenceFilter{Min: 1.5}).Validate() == nil {
+		t.Error("Min above 1 accepted")
+	}
+}
@@ -0,0 +1,297 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Bounds of the per-request decoding overrides. They keep a single request
+// from costing more than a few greedy decodes or from emitting runaway
+// token loops.
+const (
+	MaxTokensPerStepLimit = 20
+	MaxBlankPenalty       = 10.0
+	MaxBeamSize           = 8
+	MaxTemperature        = 1.0
+)
+
+// decodeSampleTopK is how many of the most likely tokens temperature sampling
+// chooses among; the long tail of the vocabulary is never sampled.
+const decodeSampleTopK = 5
+
+// DecodingOptions override how the TDT decoder picks tokens. The zero value
+// is the default greedy decoding.
+type DecodingOptions struct {
+	// MaxTokensPerStep caps the tokens emitted on one encoder frame before
+	// the decoder is forced forward. Zero keeps the Transcriber's default
+	// (Options.MaxTokensPerStep, else config.json, else 10).
+	MaxTokensPerStep int
+
+	// BlankPenalty is subtracted from the blank logit before a token is
+	// picked: positive values make the decoder emit more (fewer deletions),
+	// negative values less (fewer insertions).
+	BlankPenalty float64
+
+	// BeamSize above 1 replaces greedy decoding with a beam search over
+	// that many hypotheses, costing about as many decoder runs per step.
+	BeamSize int
+
+	// Temperature above 0 samples each token among the decodeSampleTopK most
+	// likely ones instead of taking the best. It cannot be combined with a
+	// beam search.
+	Temperature float64
+}
+
+// Validate checks the options against the package bounds.
+func (d DecodingOptions) Validate() error {
+	if d.MaxTokensPerStep < 0 || d.MaxTokensPerStep > MaxTokensPerStepLimit {
+		return fmt.Errorf("max_tokens_per_step must be between 1 and %d", MaxTokensPerStepLimit)
+	}
+	if math.IsNaN(d.BlankPenalty) || math.Abs(d.BlankPenalty) > MaxBlankPenalty {
+		return fmt.Errorf("blank_penalty must be between -%g and %g", MaxBlankPenalty, MaxBlankPenalty)
+	}
+	if d.BeamSize < 0 || d.BeamSize > MaxBeamSize {
+		return fmt.Errorf("beam_size must be between 1 and %d", MaxBeamSize)
+	}
+	if math.IsNaN(d.Temperature) || d.Temperature < 0 || d.Temperature > MaxTemperature {
+		return fmt.Errorf("temperature must be between 0 and %g", MaxTemperature)
+	}
+	if d.BeamSize > 1 && d.Temperature > 0 {
+		return fmt.Errorf("temperature sampling cannot be combined with beam_size above 1")
+	}
+	return nil
+}
+
+// beam reports whether the options ask for a beam search.
+func (d DecodingOptions) beam() bool {
+	return d.BeamSize > 1
+}
+
+// maxSymbols is the per-frame token cap for these options.
+func (d DecodingOptions) maxSymbols(def int) int {
+	if d.MaxTokensPerStep > 0 {
+		return d.MaxTokensPerStep
+	}
+	return def
+}
+
+// topK returns the indices of the k largest logits, best first.
+func topK(logits []float32, k int) []int {
+	k = min(k, len(logits))
+	top := make([]int, 0, k+1)
+	for i, v := range logits {
+		if len(top) == k && v <= logits[top[k-1]] {
+			continue
+		}
+		at, _ := slices.BinarySearchFunc(top, v, func(j int, v float32) int {
+			if logits[j] > v {
+				return -1
+			}
+			return 1
+		})
+		top = slices.Insert(top, at, i)
+		if len(top) > k {
+			top = top[:k]
+		}
+	}
+	return top
+}
+
+// sampleToken draws a token among the decodeSampleTopK most likely ones with
+// probabilities softmax(logit / temperature).
+func sampleToken(logits []float32, temperature float64) int {
+	top := topK(logits, decodeSampleTopK)
+	weights := make([]float64, len(top))
+	sum := 0.0
+	for i, id := range top {
+		weights[i] = math.Exp(float64(logits[id]-logits[top[0]]) / temperature)
+		sum += weights[i]
+	}
+	r := rand.Float64() * sum
+	for i, w := range weights {
+		if r < w {
+			return top[i]
+		}
+		r -= w
+	}
+	return top[len(top)-1]
+}
+
+// logSoftmax returns the log-probabilities of logits.
+func logSoftmax(logits []float32) []float64 {
+	maxLogit := logits[0]
+	for _, v := range logits {
+		maxLogit = max(maxLogit, v)
+	}
+	sum := 0.0
+	for _, v := range logits {
+		sum += math.Exp(float64(v - maxLogit))
+	}
+	norm := float64(maxLogit) + math.Log(sum)
+	out := make([]float64, len(logits))
+	for i, v := range logits {
+		out[i] = float64(v) - norm
+	}
+	return out
+}
+
+// beamHyp is one hypothesis of the beam search: its tokens, log-probability
+// and the decoder state it continues from.
+type beamHyp struct {
+	tokens         []decodedToken
+	score          float64
+	state1, state2 []float32
+	prev           int
+	timestep       int64
+	emitted        int // tokens emitted on the current frame
+}
+
+// key identifies hypotheses that reached the same place with the same
+// tokens, which the search merges.
+func (h *beamHyp) key() string {
+	var b strings.Builder
+	b.WriteString(strconv.FormatInt(h.timestep, 10))
+	for _, tok := range h.tokens {
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(tok.id))
+	}
+	return b.String()
+}
+
+// beamDecode decodes one window with a beam search on worker w and returns
+// the tokens of the best hypothesis with window-absolute timesteps
+// (frameOffset added). Each hypothesis is expanded with its BeamSize best
+// tokens, each advancing by the most likely duration; a hypothesis is
+// finished when it passes the last frame, and the search stops once the best
+// finished one scores above every open one (scores only decrease).
+func (t *Transcriber) beamDecode(ctx context.Context, w *decoderWorker, encoderOut []float32, encodedLen, frameOffset int64, dec DecodingOptions, progress func(frame int64)) ([]decodedToken, error) {
+	maxSym := dec.maxSymbols(t.maxTokensPerStep)
+	encOutData := w.encOut.GetData()
+	beam := []*beamHyp{{
+		state1: make([]float32, len(w.state1In.GetData())),
+		state2: make([]float32, len(w.state2In.GetData())),
+		prev:   t.blankIdx,
+	}}
+	var finished []*beamHyp
+	byScore := func(a, b *beamHyp) int { return cmp.Compare(b.score, a.score) }
+
+	for len(beam) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var next []*beamHyp
+		for _, h := range beam {
+			for d := int64(0); d < encoderDim; d++ {
+				if idx := d*encodedLen + h.timestep; idx < int64(len(encoderOut)) {
+					encOutData[d] = encoderOut[idx]
+				} else {
+					encOutData[d] = 0
+				}
+			}
+			w.targets.GetData()[0] = int32(h.prev)
+			copy(w.state1In.GetData(), h.state1)
+			copy(w.state2In.GetData(), h.state2)
+			if err := t.decoderStep(w); err != nil {
+				return nil, fmt.Errorf("decoder run failed: %w", err)
+			}
+
+			output := w.output.GetData()
+			vocabLogits := output[:t.vocabSize]
+			vocabLogits[t.blankIdx] -= float32(dec.BlankPenalty)
+			durationLogits := output[t.vocabSize:]
+			class := argmax(durationLogits)
+			step := t.durations[class]
+			stepLogProb := logSoftmax(durationLogits)[class]
+			logProbs := logSoftmax(vocabLogits)
+
+			var state1, state2 []float32 // shared by this expansion's token children
+			for _, token := range topK(vocabLogits, dec.BeamSize) {
+				n := &beamHyp{
+					tokens:   h.tokens,
+					score:    h.score + logProbs[token] + stepLogProb,
+					state1:   h.state1,
+					state2:   h.state2,
+					prev:     h.prev,
+					timestep: h.timestep,
+					emitted:  h.emitted,
+				}
+				if token != t.blankIdx {
+					if state1 == nil {
+						state1 = slices.Clone(w.state1Out.GetData())
+						state2 = slices.Clone(w.state2Out.GetData())
+					}
+					n.tokens = append(h.tokens[:len(h.tokens):len(h.tokens)], decodedToken{
+						id:       token,
+						timestep: frameOffset + h.timestep,
+						prob:     float32(math.Exp(logProbs[token])),
+					})
+					n.state1, n.state2, n.prev = state1, state2, token
+					n.emitted++
+				}
+				if step > 0 {
+					n.timestep += int64(step)
+					n.emitted = 0
+				} else if token == t.blankIdx || n.emitted >= maxSym {
+					n.timestep++
+					n.emitted = 0
+				}
+				if n.timestep >= encodedLen {
+					finished = append(finished, n)
+				} else {
+					next = append(next, n)
+				}
+			}
+		}
+
+		beam = mergeHyps(next)
+		slices.SortFunc(beam, byScore)
+		beam = beam[:min(len(beam), dec.BeamSize)]
+		slices.SortFunc(finished, byScore)
+		finished = finished[:min(len(finished), dec.BeamSize)]
+		if len(finished) > 0 && (len(beam) == 0 || finished[0].score >= beam[0].score) {
+			break
+		}
+		if progress != nil && len(beam) > 0 {
+			// Report the frame every open hypothesis has passed.
+			reached := beam[0].timestep
+			for _, h := range beam {
+				reached = min(reached, h.timestep)
+			}
+			progress(frameOffset + reached)
+		}
+	}
+	if progress != nil {
+		progress(frameOffset + encodedLen)
+	}
+	if len(finished) == 0 {
+		return nil, nil
+	}
+	return finished[0].tokens, nil
+}
+
+// mergeHyps keeps the best-scoring of hypotheses that share a key.
+func mergeHyps(hyps []*beamHyp) []*beamHyp {
+	best := make(map[string]*beamHyp, len(hyps))
+	out := hyps[:0]
+	for _, h := range hyps {
+		k := h.key()
+		if b, ok := best[k]; ok {
+			if h.score > b.score {
+				*b = *h
+			}
+			continue
+		}
+		best[k] = h
+		out = append(out, h)
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	logits := []float32{0.1, 3, -2, 3.5, 0.2, 1}
+	if got := topK(logits, 3); !slices.Equal(got, []int{3, 1, 5}) {
+		t.Fatalf("topK = %v", got)
+	}
+	if got := topK(logits, 10); len(got) != len(logits) || got[0] != 3 || got[len(got)-1] != 2 {
+		t.Fatalf("topK over the length = %v", got)
+	}
+}
+
+func TestSampleToken(t *testing.T) {
+	logits := make([]float32, 100)
+	for i := range logits {
+		logits[i] = float32(i % 10)
+	}
+	logits[42] = 20
+	// A low temperature is greedy in practice; a high one still never
+	// leaves the top tokens.
+	for range 100 {
+		if got := sampleToken(logits, 0.01); got != 42 {
+			t.Fatalf("cold sample = %d, want 42", got)
+		}
+	}
+	logits[42] = 9
+	top := topK(logits, decodeSampleTopK)
+	seen := map[int]bool{}
+	for range 2000 {
+		got := sampleToken(logits, 1)
+		if !slices.Contains(top, got) {
+			t.Fatalf("sampled %d outside the top %v", got, top)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("hot sampling only produced %v", seen)
+	}
+}
+
+func TestLogSoftmax(t *testing.T) {
+	lp := logSoftmax([]float32{1, 2, 3})
+	sum := 0.0
+	for _, v := range lp {
+		sum += math.Exp(v)
+	}
+	if math.Abs(sum-1) > 1e-9 || lp[2] <= lp[1] {
+		t.Fatalf("logSoftmax = %v (sum %v)", lp, sum)
+	}
+}
+
+func TestMergeHyps(t *testing.T) {
+	a := &beamHyp{tokens: []decodedToken{{id: 5}}, timestep: 3, score: -2}
+	b := &beamHyp{tokens: []decodedToken{{id: 5}}, timestep: 3, score: -1}
+	c := &beamHyp{tokens: []decodedToken{{id: 5}}, timestep: 4, score: -3}
+	got := mergeHyps([]*beamHyp{a, b, c})
+	if len(got) != 2 || got[0].score != -1 || got[1] != c {
+		t.Fatalf("merged = %+v", got)
+	}
+}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"errors"
+	"fmt"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ErrDenoiseUnavailable is returned when a request asks for noise
+// suppression but no denoise model was loaded. The HTTP layer maps it to 400.
+var ErrDenoiseUnavailable = errors.New("noise suppression requested but no denoise model is loaded")
+
+// DenoiseConfig points at the optional noise-suppression model. ModelPath
+// empty means denoise.onnx inside the models directory; a missing file only
+// disables the feature.
+type DenoiseConfig struct {
+	ModelPath string
+}
+
+// Denoise block layout. Waveform models are run on fixed blocks so memory
+// stays bounded on long uploads; consecutive blocks overlap and are
+// crossfaded so the block edges (where the model has no context) never
+// produce an audible or visible seam.
+const (
+	denoiseBlockSamples   = 10 * featureSampleRate // 10 s
+	denoiseOverlapSamples = featureSampleRate / 2  // 0.5 s
+)
+
+// denoiser wraps a shared waveform-to-waveform noise-suppression session.
+//
+// The model contract is the common one for exported waveform denoisers
+// (DeepFilterNet-style streaming exports, Demucs/DTLN-style wrappers): one
+// float32 input and one float32 output, both 16 kHz mono audio shaped [1, N]
+// or [1, 1, N] with N dynamic. Like the encoder, the session is shared by all
+// requests and runs outside the decoder worker pool.
+type denoiser struct {
+	session *ort.DynamicAdvancedSession
+	rank    int // 2 for [1, N], 3 for [1, 1, N]
+}
+
+// newDenoiser loads the denoise model and checks its contract; a model with
+// the wrong signature is an error. The caller warns and continues when the
+// file is missing.
+func newDenoiser(model onnxModel, sessOpts *ort.SessionOptions) (*denoiser, error) {
+	inputs, outputs, err := model.inputOutputInfo(sessOpts)
+	if err != nil {
+		return nil, fmt.Errorf("inspect denoise model: %w", err)
+	}
+	if len(inputs) != 1 || len(outputs) != 1 {
+		return nil, fmt.Errorf("denoise model must have exactly one input and one output, got %d and %d", len(inputs), len(outputs))
+	}
+	in, out := inputs[0], outputs[0]
+	if in.DataType != ort.TensorElementDataTypeFloat || out.DataType != ort.TensorElementDataTypeFloat {
+		return nil, fmt.Errorf("denoise model input and output must be float32")
+	}
+	rank := len(in.Dimensions)
+	if rank != 2 && rank != 3 {
+		return nil, fmt.Errorf("denoise model input must be [1, N] or [1, 1, N], got %v", in.Dimensions)
+	}
+
+	session, err := model.newDynamicSession([]string{in.Name}, []string{out.Name}, sessOpts)
+	if err != nil {
+		return nil, fmt.Errorf("create denoise session: %w", err)
+	}
+	return &denoiser{session: session, rank: rank}, nil
+}
+
+// destroy releases the underlying ONNX session.
+func (d *denoiser) destroy() {
+	if d == nil || d.session == nil {
+		return
+	}
+	d.session.Destroy()
+	d.session = nil
+}
+
+// CanDenoise reports whether a noise-suppression model is loaded, i.e.
+// whether TranscribeOptions.Denoise can be honoured.
+func (t *Transcriber) CanDenoise() bool {
+	return t.denoiser != nil
+}
+
+// process returns a denoised copy of samples.
+func (d *denoiser) process(samples []float32) ([]float32, error) {
+	return processBlocks(samples, denoiseBlockSamples, denoiseOverlapSamples, d.runBlock)
+}
+
+// runBlock runs one block through the model.
+func (d *denoiser) runBlock(block []float32) ([]float32, error) {
+	shape := ort.NewShape(1, int64(len(block)))
+	if d.rank == 3 {
+		shape = ort.NewShape(1, 1, int64(len(block)))
+	}
+	input, err := ort.NewTensor(shape, block)
+	if err != nil {
+		return nil, fmt.Errorf("create denoise input tensor: %w", err)
+	}
+	defer input.Destroy()
+
+	outputs := []ort.Value{nil}
+	if err := d.session.Run([]ort.Value{input}, outputs); err != nil {
+		return nil, fmt.Errorf("denoise inference: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	out, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("denoise model returned a non-float32 tensor")
+	}
+	data := out.GetData()
+	if len(data) < len(block) {
+		return nil, fmt.Errorf("denoise model returned %d samples for a %d-sample block", len(data), len(block))
+	}
+	// Some exports pad to their hop size; anything past the input is padding.
+	return append([]float32(nil), data[:len(block)]...), nil
+}
+
+// processBlocks runs run over samples in blocks of block samples that overlap
+// by overlap samples, and stitches the results with a linear crossfade over
+// each overlap. run must return exactly as many samples as it was given.
+func processBlocks(samples []float32, block, overlap int, run func([]float32) ([]float32, error)) ([]float32, error) {
+	out := make([]float32, len(samples))
+	hop := block - overlap
+	for start := 0; start < len(samples); start += hop {
+		end := min(start+block, len(samples))
+		y, err := run(samples[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(y) != end-start {
+			return nil, fmt.Errorf("denoise block returned %d samples, want %d", len(y), end-start)
+		}
+
+		fade := 0
+		if start > 0 {
+			fade = min(overlap, len(y))
+		}
+		for i := 0; i < fade; i++ {
+			w := (float32(i) + 0.5) / float32(fade)
+			out[start+i] = out[start+i]*(1-w) + y[i]*w
+		}
+		copy(out[start+fade:end], y[fade:])
+
+		if end == len(samples) {
+			break
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// An identity model must reproduce the input exactly across block seams: the
+// crossfade weights of the two overlapping blocks sum to one.
+func TestProcessBlocks_IdentityIsLossless(t *testing.T) {
+	in := tone(440, 16000, 5*16000+123)
+	calls := 0
+	out, err := processBlocks(in, 16000, 4000, func(b []float32) ([]float32, error) {
+		calls++
+		return append([]float32(nil), b...), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 7 {
+		t.Fatalf("ran %d blocks, want 7", calls)
+	}
+	for i := range in {
+		if math.Abs(float64(out[i]-in[i])) > 1e-6 {
+			t.Fatalf("sample %d = %v, want %v", i, out[i], in[i])
+		}
+	}
+}
+
+// Block outputs are blended linearly across the overlap.
+func TestProcessBlocks_Crossfade(t *testing.T) {
+	in := make([]float32, 30)
+	block := 0
+	out, err := processBlocks(in, 20, 10, func(b []float32) ([]float32, error) {
+		y := make([]float32, len(b))
+		for i := range y {
+			y[i] = float32(block)
+		}
+		block++
+		return y, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[9] != 0 || out[20] != 1 {
+		t.Fatalf("outside the overlap: out[9]=%v out[20]=%v", out[9], out[20])
+	}
+	for i := 10; i < 20; i++ {
+		if out[i] <= out[i-1] {
+			t.Fatalf("crossfade not increasing at %d: %v", i, out[10:20])
+		}
+	}
+}
+
+func TestProcessBlocks_PropagatesErrors(t *testing.T) {
+	boom := errors.New("boom")
+	_, err := processBlocks(make([]float32, 100), 50, 10, func([]float32) ([]float32, error) { return nil, boom })
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	_, err = processBlocks(make([]float32, 100), 50, 10, func(b []float32) ([]float32, error) { return b[:1], nil })
+	if err == nil {
+		t.Fatal("expected an error for a short block result")
+	}
+}
+
+func TestTranscribeWithOptions_DenoiseWithoutModel(t *testing.T) {
+	tr := &Transcriber{}
+	_, err := tr.TranscribeWithOptions(t.Context(), buildMinimalWAV(t, 16000, 100), TranscribeOptions{Denoise: true}, nil)
+	if !errors.Is(err, ErrDenoiseUnavailable) {
+		t.Fatalf("err = %v, want ErrDenoiseUnavailable", err)
+	}
+}
@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// byteToken reports whether piece is a SentencePiece byte-fallback token,
+// <0xHH>, and the byte it stands for. Such tokens spell out, one UTF-8 byte
+// each, the characters missing from the vocabulary.
+func byteToken(piece string) (byte, bool) {
+	if len(piece) != 6 || !strings.HasPrefix(piece, "<0x") || piece[5] != '>' {
+		return 0, false
+	}
+	b, err := strconv.ParseUint(piece[3:5], 16, 8)
+	if err != nil {
+		return 0, false
+	}
+	return byte(b), true
+}
+
+// joinPieces concatenates token texts into valid UTF-8: the raw bytes of
+// byte-fallback tokens join into the characters they spell, and any byte
+// sequence that does not make one becomes U+FFFD.
+func joinPieces(pieces ...string) string {
+	return strings.ToValidUTF8(strings.Join(pieces, ""), string(utf8.RuneError))
+}
+
+// partialRune returns the length of the incomplete UTF-8 sequence ending b,
+// or 0 when b ends on a character boundary.
+func partialRune(b []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if utf8.FullRune(b[len(b)-i:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// runeWriter streams token texts to emit without splitting a character
+// spelled by byte-fallback tokens across deltas: the bytes of an
+// incomplete character are held until the rest arrives.
+type runeWriter struct {
+	emit    func(delta string)
+	pending []byte
+}
+
+func (w *runeWriter) write(delta string) {
+	w.pending = append(w.pending, delta...)
+	n := len(w.pending) - partialRune(w.pending)
+	if n == 0 {
+		return
+	}
+	w.emit(joinPieces(string(w.pending[:n])))
+	w.pending = append(w.pending[:0], w.pending[n:]...)
+}
+
+// flush emits the bytes still held, once decoding is over.
+func (w *runeWriter) flush() {
+	if w == nil || len(w.pending) == 0 {
+		return
+	}
+	w.emit(joinPieces(string(w.pending)))
+	w.pending = nil
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// byteVocabTranscriber spells "日" (E6 97 A5) with byte-fallback tokens.
+func byteVocabTranscriber() *Transcriber {
+	tr := &Transcriber{
+		config: Config{SubsamplingFactor: 8},
+		mel:    NewMelFilterbank(128, 16000, DefaultMelOptions()),
+	}
+	tr.setVocab(map[int]string{0: "<unk>", 1: "▁a", 2: "▁", 3: "<0xE6>", 4: "<0x97>", 5: "<0xA5>", 6: "本", 7: "<0x41>"})
+	return tr
+}
+
+func TestByteToken(t *testing.T) {
+	for piece, want := range map[string]int{"<0x00>": 0, "<0xE6>": 0xE6, "<0xff>": 0xFF, "<0xG1>": -1, "<0x1>": -1, "<unk>": -1, "0xE6": -1} {
+		b, ok := byteToken(piece)
+		if ok != (want >= 0) || ok && int(b) != want {
+			t.Errorf("byteToken(%q) = %#x, %v", piece, b, ok)
+		}
+	}
+}
+
+func TestTokensToTextByteFallback(t *testing.T) {
+	tr := byteVocabTranscriber()
+	tokens := []decodedToken{{id: 1}, {id: 2}, {id: 3}, {id: 4}, {id: 5}, {id: 6}, {id: 7}}
+	if got := tr.tokensToText(tokens); got != "a 日本A" {
+		t.Errorf("text = %q, want %q", got, "a 日本A")
+	}
+	// A truncated sequence is replaced, not passed on as invalid UTF-8.
+	tokens = []decodedToken{{id: 1}, {id: 3}, {id: 4}, {id: 6}}
+	if got := tr.tokensToText(tokens); got != "a�本" {
+		t.Errorf("text = %q, want %q", got, "a�本")
+	}
+}
+
+func TestByteFallbackWordsAndTokens(t *testing.T) {
+	tr := byteVocabTranscriber()
+	tokens := []decodedToken{
+		{id: 2, timestep: 0, prob: 1},
+		{id: 3, timestep: 1, prob: 0.5},
+		{id: 4, timestep: 1, prob: 0.5},
+		{id: 5, timestep: 2, prob: 0.5},
+		{id: 6, timestep: 3, prob: 1},
+		{id: 1, timestep: 5, prob: 1},
+	}
+	words := tr.tokenWords(tokens, 0)
+	if len(words) != 2 || words[0].Text != "日本" || words[1].Text != "a" {
+		t.Errorf("words = %+v", words)
+	}
+
+	list := tr.tokenList(tokens, 0)
+	var texts []string
+	for _, tok := range list {
+		texts = append(texts, tok.Text)
+	}
+	if strings.Join(texts, "|") != " |日|本| a" {
+		t.Fatalf("tokens = %q", texts)
+	}
+	if list[1].Start != 0.08 || math.Abs(list[1].Logprob-3*math.Log(0.5)) > 1e-9 {
+		t.Errorf("byte-fallback token = %+v", list[1])
+	}
+}
+
+func TestRuneWriter(t *testing.T) {
+	var deltas []string
+	w := &runeWriter{emit: func(d string) { deltas = append(deltas, d) }}
+	for _, piece := range []string{" a", "\xe6", "\x97", "\xa5", "本", "\xe6"} {
+		w.write(piece)
+	}
+	w.flush()
+	if got := strings.Join(deltas, "|"); got != " a|日|本|�" {
+		t.Errorf("deltas = %q", deltas)
+	}
+}
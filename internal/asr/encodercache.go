@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+// EncoderCacheConfig keeps the encoder output of recent windows, so that a
+// follow-up request on the same audio (another response format, word
+// timestamps, a different decoding) only runs the decoder. Entries are
+// keyed by the SHA-256 of the window's features, which covers the audio,
+// its conditioning and denoising and the window bounds alike. A zero TTL
+// disables the cache.
+type EncoderCacheConfig struct {
+	TTL time.Duration
+	// MaxBytes caps the outputs kept; the least recently used go first.
+	// A 300 s window is 15 MB.
+	MaxBytes int64
+}
+
+type encoderCache struct {
+	ttl      time.Duration
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List // front is the most recently used
+	bytes   int64
+}
+
+type encoderEntry struct {
+	key     [sha256.Size]byte
+	out     []float32 // never written once cached; shared by its readers
+	len     int64
+	expires time.Time
+}
+
+func newEncoderCache(cfg EncoderCacheConfig) *encoderCache {
+	if cfg.TTL <= 0 || cfg.MaxBytes <= 0 {
+		return nil
+	}
+	return &encoderCache{
+		ttl:      cfg.TTL,
+		maxBytes: cfg.MaxBytes,
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// featuresKey hashes a window's features.
+func featuresKey(features [][]float32) [sha256.Size]byte {
+	h := sha256.New()
+	var buf []byte
+	for _, frame := range features {
+		buf = buf[:0]
+		for _, v := range frame {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+		}
+		h.Write(buf)
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// get returns the encoder output cached under key, refreshing its place.
+// The window it returns must not be written to; its release is a no-op.
+func (c *encoderCache) get(key [sha256.Size]byte) (*encodedWindow, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*encoderEntry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return &encodedWindow{out: e.out, len: e.len, release: func() {}}, true
+}
+
+// put keeps a copy of enc's output under key, evicting expired entries and
+// then the least recently used ones to stay within maxBytes.
+func (c *encoderCache) put(key [sha256.Size]byte, enc *encodedWindow) {
+	size := int64(len(enc.out)) * 4
+	if size > c.maxBytes {
+		return
+	}
+	e := &encoderEntry{key: key, out: append([]float32(nil), enc.out...), len: enc.len, expires: time.Now().Add(c.ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	now := time.Now()
+	for el := c.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if now.After(el.Value.(*encoderEntry).expires) || c.bytes+size > c.maxBytes {
+			c.remove(el)
+		}
+		el = prev
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.bytes += size
+}
+
+// remove drops an entry; c.mu must be held.
+func (c *encoderCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*encoderEntry)
+	delete(c.entries, e.key)
+	c.bytes -= int64(len(e.out)) * 4
+}
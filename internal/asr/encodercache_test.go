@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEncoderCache(t *testing.T) {
+	if newEncoderCache(EncoderCacheConfig{MaxBytes: 1 << 20}) != nil {
+		t.Error("a zero TTL built a cache")
+	}
+	c := newEncoderCache(EncoderCacheConfig{TTL: time.Minute, MaxBytes: 40})
+	window := func(v float32) *encodedWindow {
+		return &encodedWindow{out: []float32{v, v, v, v}, len: 1, release: func() {}}
+	}
+	a, b, d := featuresKey([][]float32{{1}}), featuresKey([][]float32{{2}}), featuresKey([][]float32{{3}})
+	if a == b {
+		t.Fatal("different features share a key")
+	}
+
+	enc := window(1)
+	c.put(a, enc)
+	enc.out[0] = 9 // the pooled buffer is reused once released
+	c.put(b, window(2))
+	if got, ok := c.get(a); !ok || !slices.Equal(got.out, []float32{1, 1, 1, 1}) {
+		t.Errorf("get(a) = %v, %v", got, ok)
+	}
+	// a was used last: the third entry evicts b.
+	c.put(d, window(3))
+	if _, ok := c.get(b); ok {
+		t.Error("least recently used entry kept past MaxBytes")
+	}
+	if _, ok := c.get(a); !ok {
+		t.Error("recently used entry evicted")
+	}
+	if c.bytes != 32 {
+		t.Errorf("bytes = %d, want 32", c.bytes)
+	}
+	c.put(b, &encodedWindow{out: make([]float32, 11)})
+	if _, ok := c.get(b); ok {
+		t.Error("entry larger than MaxBytes cached")
+	}
+
+	c.entries[a].Value.(*encoderEntry).expires = time.Now().Add(-time.Second)
+	if _, ok := c.get(a); ok {
+		t.Error("expired entry served")
+	}
+}
+
+func TestEncodeWindowCached(t *testing.T) {
+	tr := &Transcriber{encoderCache: newEncoderCache(EncoderCacheConfig{TTL: time.Minute, MaxBytes: 1 << 20})}
+	features := [][]float32{{0.5, 0.25}, {0.125, 1}}
+	tr.encoderCache.put(featuresKey(features), &encodedWindow{out: []float32{1, 2, 3}, len: 3})
+
+	// No encoder session: a hit must not run it.
+	enc, err := tr.encodeWindow(context.Background(), features)
+	if err != nil || enc.len != 3 || !slices.Equal(enc.out, []float32{1, 2, 3}) {
+		t.Fatalf("encodeWindow = %+v, %v", enc, err)
+	}
+	enc.release()
+}
@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+)
+
+// defaultTemperatureStep is how much the temperature rises on each retry
+// when Fallback.TemperatureStep is zero, giving Whisper's ladder 0, 0.2, ...
+// 1.0.
+const defaultTemperatureStep = 0.2
+
+// Fallback retries a window whose decoded text looks wrong with temperature
+// sampling at rising temperatures, the way Whisper does: a transcript that
+// compresses too well is stuck in a loop, and one with a low mean token
+// log-probability is a guess. The zero value never retries.
+type Fallback struct {
+	// CompressionRatio retries windows whose text compresses (zlib) by more
+	// than this ratio. Zero disables the check; Whisper uses 2.4.
+	CompressionRatio float64
+
+	// Logprob retries windows whose mean token log-probability is below
+	// this negative value. Zero disables the check; Whisper uses -1.
+	Logprob float64
+
+	// TemperatureStep is added to the temperature on each retry, up to
+	// MaxTemperature. Zero takes defaultTemperatureStep.
+	TemperatureStep float64
+}
+
+// Validate checks the thresholds and the step.
+func (f Fallback) Validate() error {
+	if math.IsNaN(f.CompressionRatio) || f.CompressionRatio < 0 {
+		return fmt.Errorf("compression ratio threshold must be 0 (off) or positive")
+	}
+	if math.IsNaN(f.Logprob) || f.Logprob > 0 {
+		return fmt.Errorf("logprob threshold must be 0 (off) or negative")
+	}
+	if math.IsNaN(f.TemperatureStep) || f.TemperatureStep < 0 || f.TemperatureStep > MaxTemperature {
+		return fmt.Errorf("temperature step must be between 0 and %g", MaxTemperature)
+	}
+	return nil
+}
+
+// enabled reports whether any check can trigger a retry.
+func (f Fallback) enabled() bool {
+	return f.CompressionRatio > 0 || f.Logprob < 0
+}
+
+// temperatures is the ladder of temperatures a window is decoded at, from
+// the request's own.
+func (f Fallback) temperatures(start float64) []float64 {
+	step := f.TemperatureStep
+	if step == 0 {
+		step = defaultTemperatureStep
+	}
+	temps := []float64{start}
+	for i := 1; ; i++ {
+		// Rounded so 0.2 steps read 0.6 rather than 0.6000000000000001
+		// and still reach 1.
+		temp := math.Round((start+float64(i)*step)*1e9) / 1e9
+		if temp > MaxTemperature {
+			return temps
+		}
+		temps = append(temps, temp)
+	}
+}
+
+// windowAttempt is one decode of a window and how it scored.
+type windowAttempt struct {
+	tokens      []decodedToken
+	blank       blankFrames
+	temperature float64
+	ratio       float64
+	logprob     float64
+}
+
+// better reports whether a is preferable to b when no attempt passes: text
+// that is not looping first, then the more confident one.
+func (a windowAttempt) better(b windowAttempt, f Fallback) bool {
+	aLoop := f.CompressionRatio > 0 && a.ratio > f.CompressionRatio
+	bLoop := f.CompressionRatio > 0 && b.ratio > f.CompressionRatio
+	if aLoop != bLoop {
+		return !aLoop
+	}
+	return a.logprob > b.logprob
+}
+
+// passes reports whether a clears every check f enables.
+func (a windowAttempt) passes(f Fallback) bool {
+	if f.CompressionRatio > 0 && a.ratio > f.CompressionRatio {
+		return false
+	}
+	return f.Logprob == 0 || a.logprob >= f.Logprob
+}
+
+// windowTemperature records that the owned frames [from, to) of a window
+// were decoded at temperature.
+type windowTemperature struct {
+	from, to    int64
+	temperature float64
+}
+
+// decodeWindow decodes one window with tdtDecode and, when fb is enabled,
+// decodes it again at each higher temperature of the ladder until the owned
+// text passes the checks, keeping the best attempt if none does. Every
+// attempt reuses the window's encoder output. Streamed text is held until
+// the window is settled, since an attempt may be thrown away, and the
+// no-speech evidence of discarded attempts is dropped with them.
+func (t *Transcriber) decodeWindow(ctx context.Context, enc *encodedWindow, dec DecodingOptions, fb Fallback, emitStart, emitEnd, frameOffset int64, holdFirst int, resolveSeam func(head []decodedToken) []decodedToken, emit func(delta string), progress func(frame int64)) ([]decodedToken, error) {
+	tk := ticketFrom(ctx)
+	if !fb.enabled() {
+		tokens, err := t.tdtDecode(ctx, enc.out, enc.len, dec, emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, emit, progress)
+		tk.noteTemperature(frameOffset+emitStart, frameOffset+emitEnd, dec.Temperature)
+		return tokens, err
+	}
+
+	before := slices.Clone(tk.blank)
+	var best windowAttempt
+	var err error
+	for i, temp := range fb.temperatures(dec.Temperature) {
+		attempt := dec
+		attempt.Temperature = temp
+		if i > 0 {
+			// Retries sample; they cannot run as a beam search.
+			attempt.BeamSize = 0
+		}
+		tk.blank = slices.Clone(before)
+		var tokens []decodedToken
+		tokens, err = t.tdtDecode(ctx, enc.out, enc.len, attempt, emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, nil, progress)
+		if err != nil {
+			// Out of time or cancelled: a settled earlier attempt beats a
+			// partial one.
+			if i == 0 {
+				best = windowAttempt{tokens: tokens, blank: tk.blank, temperature: temp}
+			}
+			break
+		}
+		a := windowAttempt{tokens: tokens, blank: tk.blank, temperature: temp}
+		a.ratio, a.logprob = t.windowQuality(tokens)
+		if i > 0 {
+			slog.DebugContext(ctx, "decoding fallback",
+				"frame", frameOffset+emitStart,
+				"temperature", temp,
+				"compressionRatio", a.ratio,
+				"avgLogprob", a.logprob,
+			)
+		}
+		if i == 0 || a.better(best, fb) {
+			best = a
+		}
+		if a.passes(fb) {
+			best = a
+			break
+		}
+	}
+
+	tk.blank = best.blank
+	tk.noteTemperature(frameOffset+emitStart, frameOffset+emitEnd, best.temperature)
+	if emit != nil {
+		for _, tok := range best.tokens {
+			if text := t.tokenText(tok.id); text != "" {
+				emit(text)
+			}
+		}
+	}
+	return best.tokens, err
+}
+
+// windowQuality is the compression ratio of the text of tokens and their
+// mean log-probability. No tokens score 0 and 0, which always pass.
+func (t *Transcriber) windowQuality(tokens []decodedToken) (ratio, logprob float64) {
+	if len(tokens) == 0 {
+		return 0, 0
+	}
+	for _, tok := range tokens {
+		logprob += math.Log(max(float64(tok.prob), 1e-10))
+	}
+	return CompressionRatio(t.tokensToText(tokens)), logprob / float64(len(tokens))
+}
+
+// CompressionRatio is the length of text over the length of its zlib
+// compression, as Whisper measures it: repetition loops compress far better
+// than speech. Empty text is 0.
+func CompressionRatio(text string) float64 {
+	if text == "" {
+		return 0
+	}
+	var b bytes.Buffer
+	// At lower levels Go stores inputs of a line or two uncompressed,
+	// unlike the C zlib Whisper measures with; the best level matches it.
+	z, _ := zlib.NewWriterLevel(&b, zlib.BestCompression)
+	z.Write([]byte(text))
+	z.Close()
+	return float64(len(text)) / float64(b.Len())
+}
+
+// noteTemperature records the temperature the owned frames [from, to) were
+// decoded at; greedy and beam decodes at 0 need no record.
+func (tk *decodeTicket) noteTemperature(from, to int64, temperature float64) {
+	if temperature > 0 {
+		tk.temperatures = append(tk.temperatures, windowTemperature{from: from, to: to, temperature: temperature})
+	}
+}
+
+// segmentTemperatures sets the Temperature of segments to the highest
+// temperature any window they overlap was decoded at; offset is the seconds
+// trimmed off the plane's start.
+func (t *Transcriber) segmentTemperatures(segments []Segment, temps []windowTemperature, offset float64) {
+	frameSec := t.encoderFrameSeconds()
+	for i, seg := range segments {
+		from := int64((seg.Start - offset) / frameSec)
+		to := int64((seg.End-offset)/frameSec + 0.5)
+		for _, w := range temps {
+			if w.from < to && from < w.to {
+				segments[i].Temperature = max(segments[i].Temperature, w.temperature)
+			}
+		}
+	}
+}
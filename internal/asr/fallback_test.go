@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFallbackValidate(t *testing.T) {
+	for _, ok := range []Fallback{{}, {CompressionRatio: 2.4, Logprob: -1}, {Logprob: -0.5, TemperatureStep: 1}} {
+		if err := ok.Validate(); err != nil {
+			t.Errorf("%+v: %v", ok, err)
+		}
+	}
+	for _, bad := range []Fallback{{CompressionRatio: -1}, {Logprob: 0.5}, {Logprob: math.NaN()}, {TemperatureStep: 1.5}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+	if (Fallback{TemperatureStep: 0.2}).enabled() {
+		t.Error("a step alone enabled the fallback")
+	}
+}
+
+func TestFallbackTemperatures(t *testing.T) {
+	for _, tc := range []struct {
+		f     Fallback
+		start float64
+		want  string
+	}{
+		{Fallback{}, 0, "[0 0.2 0.4 0.6 0.8 1]"},
+		{Fallback{TemperatureStep: 0.5}, 0, "[0 0.5 1]"},
+		{Fallback{TemperatureStep: 0.3}, 0.5, "[0.5 0.8]"},
+		{Fallback{}, 1, "[1]"},
+	} {
+		if got := fmt.Sprint(tc.f.temperatures(tc.start)); got != tc.want {
+			t.Errorf("%+v from %g = %s, want %s", tc.f, tc.start, got, tc.want)
+		}
+	}
+}
+
+func TestCompressionRatio(t *testing.T) {
+	speech := CompressionRatio("The quick brown fox jumps over the lazy dog near the riverbank.")
+	loop := CompressionRatio(strings.Repeat("thank you so much ", 12))
+	if !(speech < 2.4 && loop > 2.4) {
+		t.Errorf("speech %g, loop %g", speech, loop)
+	}
+	if CompressionRatio("") != 0 {
+		t.Error("empty text has a ratio")
+	}
+}
+
+func TestWindowAttempt(t *testing.T) {
+	f := Fallback{CompressionRatio: 2.4, Logprob: -1}
+	sure := windowAttempt{ratio: 1.2, logprob: -0.3}
+	unsure := windowAttempt{ratio: 1.2, logprob: -1.5}
+	looping := windowAttempt{ratio: 4, logprob: -0.1}
+	if !sure.passes(f) || unsure.passes(f) || looping.passes(f) {
+		t.Error("passes")
+	}
+	if !unsure.better(looping, f) || looping.better(unsure, f) || !sure.better(unsure, f) {
+		t.Error("better")
+	}
+	// Only the enabled check counts.
+	if !unsure.passes(Fallback{CompressionRatio: 2.4}) || !looping.passes(Fallback{Logprob: -1}) {
+		t.Error("disabled checks rejected an attempt")
+	}
+}
+
+func TestSegmentTemperatures(t *testing.T) {
+	tr := &Transcriber{
+		config: Config{SubsamplingFactor: 8},
+		mel:    NewMelFilterbank(128, 16000, DefaultMelOptions()),
+	}
+	tk := &decodeTicket{}
+	tk.noteTemperature(0, 100, 0) // greedy: nothing recorded
+	tk.noteTemperature(100, 200, 0.4)
+	tk.noteTemperature(200, 300, 0.2)
+	if len(tk.temperatures) != 2 {
+		t.Fatalf("temperatures = %+v", tk.temperatures)
+	}
+	// Frames are 80 ms: frame 100 is second 8; one second is trimmed.
+	segments := []Segment{{Start: 1, End: 8}, {Start: 9, End: 10}, {Start: 16, End: 18}, {Start: 25, End: 26}}
+	tr.segmentTemperatures(segments, tk.temperatures, 1)
+	for i, want := range []float64{0, 0.4, 0.4, 0} {
+		if segments[i].Temperature != want {
+			t.Errorf("segment %d temperature = %g, want %g", i, segments[i].Temperature, want)
+		}
+	}
+}
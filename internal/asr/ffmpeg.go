@@ -89,13 +89,15 @@ func newFFmpegConverter(cfg FFmpegConfig) *ffmpegConverter {
 	}
 }
 
-// Convert transcodes arbitrary audio bytes into 16 kHz mono PCM WAV bytes
-// by shelling out to ffmpeg. It returns the raw WAV payload so the caller
-// can feed it into parseWAV and reuse the existing decode path.
+// Convert transcodes arbitrary audio bytes into 16 kHz PCM WAV bytes by
+// shelling out to ffmpeg. The output is downmixed to mono unless
+// keepChannels is set, in which case the source channel layout is kept for
+// per-channel transcription. It returns the raw WAV payload so the caller can
+// feed it into parseWAV and reuse the existing decode path.
 //
 // The function is safe for concurrent use: it allocates unique temporary
 // files for each invocation and cleans them up on return.
-func (c *ffmpegConverter) Convert(data []byte) ([]byte, error) {
+func (c *ffmpegConverter) Convert(data []byte, keepChannels bool) ([]byte, error) {
 	if c == nil {
 		return nil, ErrUnsupportedAudio
 	}
@@ -132,20 +134,29 @@ func (c *ffmpegConverter) Convert(data []byte) ([]byte, error) {
 	// -nostdin: never read from stdin (defensive, avoids hangs).
 	// -y: overwrite output without prompting.
 	// -hide_banner -loglevel error: keep stderr focused on real errors.
-	// -ac 1 -ar 16000 -acodec pcm_s16le: match the pipeline expectation.
+	// -ac 1 -ar 16000 -acodec pcm_s16le: match the pipeline expectation
+	// (-ac 1 is dropped when the caller needs the individual channels).
 	// -f wav: force WAV container regardless of output filename.
-	cmd := exec.CommandContext(ctx, c.binaryPath,
+	// -vn -sn -dn: video containers (mp4, mkv, webm) only contribute their
+	// default audio track; video, subtitle and data streams are skipped.
+	args := []string{
 		"-nostdin",
 		"-hide_banner",
 		"-loglevel", "error",
 		"-y",
 		"-i", inputPath,
-		"-ac", "1",
+		"-vn", "-sn", "-dn",
+	}
+	if !keepChannels {
+		args = append(args, "-ac", "1")
+	}
+	args = append(args,
 		"-ar", "16000",
 		"-acodec", "pcm_s16le",
 		"-f", "wav",
 		outputPath,
 	)
+	cmd := exec.CommandContext(ctx, c.binaryPath, args...)
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
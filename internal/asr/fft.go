@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"math/bits"
+)
+
+// rfftPlan computes the one-sided spectrum of a real signal of fixed,
+// power-of-two length n. Everything that depends only on n (bit-reversal
+// permutation, twiddle factors) is computed once at construction, so a
+// transform is pure butterflies with no trig calls and no allocation.
+//
+// It uses the standard real-input trick: the n real samples are packed as
+// n/2 complex values z[k] = x[2k] + i*x[2k+1], transformed with a half-size
+// complex FFT, and then split into the n/2+1 non-redundant bins. This halves
+// the work compared with a complex FFT that discards the mirrored bins.
+type rfftPlan struct {
+	n       int
+	half    int
+	rev     []int        // bit-reversal permutation for the half-size FFT
+	twiddle []complex128 // exp(-2πi·j/half) for j < half/2
+	post    []complex128 // exp(-2πi·k/n) for k <= half/2, used by the split step
+
+	// twiddle32 and post32 are the same tables for transform32.
+	twiddle32 []complex64
+	post32    []complex64
+}
+
+// newRFFTPlan builds a plan for real signals of length n, which must be a
+// power of two and at least 4.
+func newRFFTPlan(n int) *rfftPlan {
+	if n < 4 || n&(n-1) != 0 {
+		panic("rfft length must be a power of two >= 4")
+	}
+	half := n / 2
+	p := &rfftPlan{
+		n:       n,
+		half:    half,
+		rev:     make([]int, half),
+		twiddle: make([]complex128, half/2),
+		post:    make([]complex128, half/2+1),
+	}
+
+	logHalf := bits.TrailingZeros(uint(half))
+	for i := range p.rev {
+		p.rev[i] = int(bits.Reverse(uint(i)) >> (bits.UintSize - logHalf))
+	}
+	for j := range p.twiddle {
+		s, c := math.Sincos(-2 * math.Pi * float64(j) / float64(half))
+		p.twiddle[j] = complex(c, s)
+	}
+	for k := range p.post {
+		s, c := math.Sincos(-2 * math.Pi * float64(k) / float64(n))
+		p.post[k] = complex(c, s)
+	}
+	p.twiddle32 = make([]complex64, len(p.twiddle))
+	for j, w := range p.twiddle {
+		p.twiddle32[j] = complex64(w)
+	}
+	p.post32 = make([]complex64, len(p.post))
+	for k, w := range p.post {
+		p.post32[k] = complex64(w)
+	}
+	return p
+}
+
+// bins returns the length of the one-sided spectrum, n/2+1.
+func (p *rfftPlan) bins() int {
+	return p.half + 1
+}
+
+// transform writes the one-sided spectrum of signal into out. signal must have
+// exactly n samples and out exactly n/2+1 entries; out doubles as the work
+// buffer, so the call does not allocate.
+func (p *rfftPlan) transform(signal []float64, out []complex128) {
+	half := p.half
+
+	// Pack even/odd samples as complex values, in bit-reversed order.
+	for i := 0; i < half; i++ {
+		out[p.rev[i]] = complex(signal[2*i], signal[2*i+1])
+	}
+
+	// Iterative radix-2 Cooley-Tukey on out[:half]. The twiddle table is
+	// strided so each stage reads its factors directly.
+	for size := 2; size <= half; size *= 2 {
+		step := half / size
+		hs := size / 2
+		for i := 0; i < half; i += size {
+			for j := 0; j < hs; j++ {
+				t := p.twiddle[j*step] * out[i+j+hs]
+				out[i+j+hs] = out[i+j] - t
+				out[i+j] += t
+			}
+		}
+	}
+
+	// Split Z into the spectrum of the real signal:
+	//   X[k] = (Z[k] + conj(Z[half-k]))/2 - i·w^k·(Z[k] - conj(Z[half-k]))/2
+	// with w = exp(-2πi/n). Bins k and half-k depend on the same pair, so
+	// they are produced together and the split runs in place.
+	z0 := out[0]
+	out[0] = complex(real(z0)+imag(z0), 0)
+	out[half] = complex(real(z0)-imag(z0), 0)
+	for k := 1; k <= half/2; k++ {
+		a, b := out[k], out[half-k]
+		out[k] = splitBin(a, b, p.post[k])
+		// w^(half-k) = -conj(w^k)
+		out[half-k] = splitBin(b, a, -conjugate(p.post[k]))
+	}
+}
+
+// splitBin computes one output bin of the real-FFT split step from Z[k] (a),
+// Z[half-k] (b) and the twiddle w^k.
+func splitBin(a, b, w complex128) complex128 {
+	bc := conjugate(b)
+	even := (a + bc) * 0.5
+	odd := (a - bc) * complex(0, -0.5)
+	return even + w*odd
+}
+
+func conjugate(c complex128) complex128 {
+	return complex(real(c), -imag(c))
+}
+
+// transform32 is transform in single precision.
+func (p *rfftPlan) transform32(signal []float32, out []complex64) {
+	half := p.half
+	for i := 0; i < half; i++ {
+		out[p.rev[i]] = complex(signal[2*i], signal[2*i+1])
+	}
+	for size := 2; size <= half; size *= 2 {
+		step := half / size
+		hs := size / 2
+		for i := 0; i < half; i += size {
+			for j := 0; j < hs; j++ {
+				t := p.twiddle32[j*step] * out[i+j+hs]
+				out[i+j+hs] = out[i+j] - t
+				out[i+j] += t
+			}
+		}
+	}
+	z0 := out[0]
+	out[0] = complex(real(z0)+imag(z0), 0)
+	out[half] = complex(real(z0)-imag(z0), 0)
+	for k := 1; k <= half/2; k++ {
+		a, b := out[k], out[half-k]
+		out[k] = splitBin32(a, b, p.post32[k])
+		out[half-k] = splitBin32(b, a, -conjugate32(p.post32[k]))
+	}
+}
+
+func splitBin32(a, b, w complex64) complex64 {
+	bc := conjugate32(b)
+	even := (a + bc) * 0.5
+	odd := (a - bc) * complex(0, -0.5)
+	return even + w*odd
+}
+
+func conjugate32(c complex64) complex64 {
+	return complex(real(c), -imag(c))
+}
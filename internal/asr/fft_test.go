@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// naiveDFT is the O(n²) textbook definition, used as the reference.
+func naiveDFT(signal []float64) []complex128 {
+	n := len(signal)
+	out := make([]complex128, n/2+1)
+	for k := range out {
+		var sum complex128
+		for t, x := range signal {
+			sum += complex(x, 0) * cmplx.Exp(complex(0, -2*math.Pi*float64(k*t)/float64(n)))
+		}
+		out[k] = sum
+	}
+	return out
+}
+
+// The real FFT must match the direct DFT on every one-sided bin, for the
+// production size and for the smallest sizes where the split step's k and
+// half-k indices coincide.
+func TestRFFT_MatchesNaiveDFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{4, 8, 16, 512} {
+		signal := make([]float64, n)
+		for i := range signal {
+			signal[i] = rng.Float64()*2 - 1
+		}
+
+		plan := newRFFTPlan(n)
+		got := make([]complex128, plan.bins())
+		plan.transform(signal, got)
+
+		want := naiveDFT(signal)
+		for k := range want {
+			if cmplx.Abs(got[k]-want[k]) > 1e-9*float64(n) {
+				t.Fatalf("n=%d bin %d: got %v, want %v", n, k, got[k], want[k])
+			}
+		}
+	}
+}
+
+// A pure tone must put its energy in exactly its bin.
+func TestRFFT_ToneLandsInItsBin(t *testing.T) {
+	const n, bin = 512, 37
+	signal := make([]float64, n)
+	for i := range signal {
+		signal[i] = math.Cos(2 * math.Pi * bin * float64(i) / n)
+	}
+
+	plan := newRFFTPlan(n)
+	out := make([]complex128, plan.bins())
+	plan.transform(signal, out)
+
+	for k, c := range out {
+		mag := cmplx.Abs(c)
+		if k == bin {
+			if math.Abs(mag-n/2) > 1e-6 {
+				t.Fatalf("tone bin magnitude = %v, want %v", mag, n/2)
+			}
+		} else if mag > 1e-6 {
+			t.Fatalf("leakage into bin %d: %v", k, mag)
+		}
+	}
+}
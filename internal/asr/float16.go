@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// fp16 exports may take and return float16 tensors. Go has no float16 type,
+// so those tensors are raw bytes for ONNX Runtime and everything else keeps
+// working on float32: values are converted at the session boundary only.
+
+// float32ToFloat16 converts f to IEEE 754 half precision, rounding to nearest
+// even. Values beyond the half range become infinity; NaN stays NaN.
+func float32ToFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int32(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+	e := exp - 127 + 15
+	switch {
+	case e >= 0x1f:
+		return sign | 0x7c00
+	case e <= 0:
+		// Subnormal in half precision, or below half of its smallest value.
+		// Rounding up may carry into the smallest normal, which is correct.
+		if e < -10 {
+			return sign
+		}
+		return sign | uint16(roundShift(mant|0x800000, uint32(14-e)))
+	}
+	// A mantissa carry bumps the exponent, up to infinity, which is correct.
+	return sign | uint16(uint32(e)<<10+roundShift(mant, 13))
+}
+
+// roundShift is m >> s rounded to nearest even.
+func roundShift(m, s uint32) uint32 {
+	half := uint32(1) << (s - 1)
+	r := m >> s
+	rem := m & (1<<s - 1)
+	if rem > half || (rem == half && r&1 == 1) {
+		r++
+	}
+	return r
+}
+
+// float16ToFloat32 converts IEEE 754 half precision h to float32, which
+// holds every half value exactly.
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// Subnormal: normalize into a float32 exponent.
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// encodeFloat16s writes src into dst as native-endian halves, the layout
+// ONNX Runtime reads. dst holds 2 bytes per value.
+func encodeFloat16s(dst []byte, src []float32) {
+	for i, f := range src {
+		binary.NativeEndian.PutUint16(dst[2*i:], float32ToFloat16(f))
+	}
+}
+
+// decodeFloat16s reads the native-endian halves of src into dst.
+func decodeFloat16s(dst []float32, src []byte) {
+	for i := range dst {
+		dst[i] = float16ToFloat32(binary.NativeEndian.Uint16(src[2*i:]))
+	}
+}
+
+// floatTensor is a float model input or output whose element type is set by
+// the export. Callers always read and write data as float32; for a float16
+// tensor, toModel and fromModel convert it around each session run.
+type floatTensor struct {
+	data []float32
+	f32  *ort.Tensor[float32]
+	f16  *ort.CustomDataTensor
+}
+
+// newFloatTensor creates a tensor of element type dt over data, which must
+// hold shape's element count.
+func newFloatTensor(dt ort.TensorElementDataType, shape ort.Shape, data []float32) (*floatTensor, error) {
+	switch dt {
+	case ort.TensorElementDataTypeFloat:
+		t, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, err
+		}
+		return &floatTensor{data: data, f32: t}, nil
+	case ort.TensorElementDataTypeFloat16:
+		t, err := ort.NewCustomDataTensor(shape, make([]byte, 2*len(data)), ort.TensorElementDataTypeFloat16)
+		if err != nil {
+			return nil, err
+		}
+		return &floatTensor{data: data, f16: t}, nil
+	default:
+		return nil, fmt.Errorf("unsupported tensor element type %v", dt)
+	}
+}
+
+// newEmptyFloatTensor creates a zeroed tensor of element type dt.
+func newEmptyFloatTensor(dt ort.TensorElementDataType, shape ort.Shape) (*floatTensor, error) {
+	return newFloatTensor(dt, shape, make([]float32, shape.FlattenedSize()))
+}
+
+// GetData returns the float32 values, named like ort.Tensor's accessor.
+func (t *floatTensor) GetData() []float32 {
+	return t.data
+}
+
+// value is the tensor handed to the session.
+func (t *floatTensor) value() ort.Value {
+	if t.f16 != nil {
+		return t.f16
+	}
+	return t.f32
+}
+
+// toModel copies data into a float16 tensor before a run that reads it.
+func (t *floatTensor) toModel() {
+	if t.f16 != nil {
+		encodeFloat16s(t.f16.GetData(), t.data)
+	}
+}
+
+// fromModel copies a float16 tensor into data after a run that wrote it.
+func (t *floatTensor) fromModel() {
+	if t.f16 != nil {
+		decodeFloat16s(t.data, t.f16.GetData())
+	}
+}
+
+// Destroy releases the ONNX Runtime tensor.
+func (t *floatTensor) Destroy() {
+	if t.f16 != nil {
+		t.f16.Destroy()
+	}
+	if t.f32 != nil {
+		t.f32.Destroy()
+	}
+}
+
+// floatIOTypes reads the element types of a model's named inputs and
+// outputs, each float32 or float16. Only fp16 exports are inspected, since
+// inspecting loads the model a second time; the others always exchange
+// float32, as does an fp16 export converted with its IO types kept.
+func floatIOTypes(model onnxModel, p Precision, opts *ort.SessionOptions, names ...string) (map[string]ort.TensorElementDataType, error) {
+	types := make(map[string]ort.TensorElementDataType, len(names))
+	if p != PrecisionFP16 {
+		for _, name := range names {
+			types[name] = ort.TensorElementDataTypeFloat
+		}
+		return types, nil
+	}
+	inputs, outputs, err := model.inputOutputInfo(opts)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]ort.TensorElementDataType, len(inputs)+len(outputs))
+	for _, info := range append(inputs, outputs...) {
+		all[info.Name] = info.DataType
+	}
+	for _, name := range names {
+		dt, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("model has no input or output %q", name)
+		}
+		if dt != ort.TensorElementDataTypeFloat && dt != ort.TensorElementDataTypeFloat16 {
+			return nil, fmt.Errorf("%s must be float32 or float16, got %v", name, dt)
+		}
+		types[name] = dt
+	}
+	return types, nil
+}
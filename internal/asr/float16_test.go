@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat32ToFloat16(t *testing.T) {
+	for _, tc := range []struct {
+		in   float32
+		want uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff},                           // largest half
+		{65520, 0x7c00},                           // rounds past it to +Inf
+		{1e10, 0x7c00},                            // overflow
+		{float32(math.Inf(-1)), 0xfc00},           // -Inf
+		{6.103515625e-05, 0x0400},                 // smallest normal
+		{5.9604645e-08, 0x0001},                   // smallest subnormal
+		{2.9802322e-08, 0x0000},                   // half of it ties to even zero
+		{1 + 1.0/2048, 0x3c00},                    // tie rounds to even
+		{1 + 3.0/2048, 0x3c02},                    // tie rounds to even, up
+		{1 + 1.0/2048 + 1.0/65536, 0x3c01},        // above the tie rounds up
+		{6.1035156e-05 - 5.9604645e-08/4, 0x0400}, // subnormal carries into normal
+	} {
+		if got := float32ToFloat16(tc.in); got != tc.want {
+			t.Errorf("float32ToFloat16(%g) = %#04x, want %#04x", tc.in, got, tc.want)
+		}
+	}
+	if got := float32ToFloat16(float32(math.NaN())); got&0x7c00 != 0x7c00 || got&0x3ff == 0 {
+		t.Errorf("float32ToFloat16(NaN) = %#04x, want a NaN", got)
+	}
+}
+
+func TestFloat16RoundTrip(t *testing.T) {
+	// Every finite half survives float32 and back unchanged.
+	for h := 0; h <= 0xffff; h++ {
+		if h&0x7c00 == 0x7c00 && h&0x3ff != 0 {
+			if f := float16ToFloat32(uint16(h)); !math.IsNaN(float64(f)) {
+				t.Fatalf("float16ToFloat32(%#04x) = %g, want NaN", h, f)
+			}
+			continue
+		}
+		if got := float32ToFloat16(float16ToFloat32(uint16(h))); got != uint16(h) {
+			t.Fatalf("round trip of %#04x = %#04x", h, got)
+		}
+	}
+}
+
+func TestFloat16Slices(t *testing.T) {
+	src := []float32{0, 1, -0.25, 3.140625}
+	buf := make([]byte, 2*len(src))
+	encodeFloat16s(buf, src)
+	got := make([]float32, len(src))
+	decodeFloat16s(got, buf)
+	for i := range src {
+		if got[i] != src[i] {
+			t.Errorf("value %d = %g, want %g", i, got[i], src[i])
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import "parakeet/internal/itn"
+
+// applyFormatting writes the numbers, dates, times and units of the
+// transcript and its segments the way f asks. Words and tokens keep the
+// recognised text, which their timing belongs to. The rules are English:
+// other requested languages are left alone.
+func applyFormatting(res *Result, f itn.Options, language string) {
+	if !f.Enabled() || !itn.Applies(language) {
+		return
+	}
+	for k := range res.Segments {
+		res.Segments[k].Text = f.Apply(res.Segments[k].Text)
+	}
+	if res.Channels > 1 {
+		rebuildText(res) // keeps the channel labels out of the rules' way
+		return
+	}
+	res.Text = f.Apply(res.Text)
+}
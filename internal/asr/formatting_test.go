@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"testing"
+
+	"parakeet/internal/itn"
+)
+
+func TestApplyFormatting(t *testing.T) {
+	digits := itn.Options{Numbers: itn.Digits, Units: itn.Abbreviate}
+	res := &Result{
+		Text:     "[channel 0] twenty five kilometers\n[channel 1] two",
+		Channels: 2,
+		Segments: []Segment{
+			{Channel: 0, Start: 0, End: 1, Text: "twenty five kilometers"},
+			{Channel: 1, Start: 1, End: 2, Text: "two"},
+		},
+		Words: []Word{{Text: "twenty"}, {Text: "five"}, {Text: "kilometers"}},
+	}
+	applyFormatting(res, digits, "en")
+	if res.Segments[0].Text != "25 km" || res.Words[0].Text != "twenty" {
+		t.Errorf("per channel: %+v %+v", res.Segments, res.Words)
+	}
+	if want := "[channel 0] 25 km\n[channel 1] two"; res.Text != want {
+		t.Errorf("Text = %q, want %q", res.Text, want)
+	}
+
+	res = &Result{Text: "twenty five kilometers", Channels: 1, Segments: []Segment{{Text: "twenty five kilometers"}}}
+	applyFormatting(res, digits, "es")
+	if res.Text != "twenty five kilometers" {
+		t.Errorf("formatted a Spanish request: %q", res.Text)
+	}
+	applyFormatting(res, digits, "")
+	if res.Text != "25 km" || res.Segments[0].Text != "25 km" {
+		t.Errorf("single channel: %q %+v", res.Text, res.Segments)
+	}
+}
@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// GuardMode selects what the hallucination guard does with transcript it
+// finds improbable.
+type GuardMode string
+
+const (
+	// GuardOff runs no checks (the default).
+	GuardOff GuardMode = "off"
+	// GuardFlag marks suspect segments with Segment.Suspect and changes
+	// nothing else.
+	GuardFlag GuardMode = "flag"
+	// GuardBlank also removes suspect segments and collapses repetition
+	// loops, keeping one copy.
+	GuardBlank GuardMode = "blank"
+)
+
+// ParseGuardMode normalizes a user-supplied guard mode. An empty value
+// means off; unknown values are rejected.
+func ParseGuardMode(s string) (GuardMode, error) {
+	switch m := GuardMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return GuardOff, nil
+	case GuardOff, GuardFlag, GuardBlank:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported hallucination guard %q (supported: off, flag, blank)", s)
+	}
+}
+
+// Reasons the guard gives in Segment.Suspect.
+const (
+	SuspectUnvoiced   = "unvoiced"   // too few voiced frames under the text
+	SuspectTokenRate  = "token_rate" // more tokens per second than speech has
+	SuspectRepetition = "repetition" // the same words looping
+)
+
+// HallucinationGuard checks each segment for text the model produced from
+// music or noise: too little voiced audio under it, more tokens per second
+// than anyone speaks, or a phrase repeated over and over. Zero limits take
+// the guard defaults.
+type HallucinationGuard struct {
+	Mode GuardMode
+
+	// MinVoicedRatio is the fraction of a segment's 20 ms frames that must
+	// be voiced (above the silence floor, with a zero-crossing rate low
+	// enough for voice rather than hiss).
+	MinVoicedRatio float64
+
+	// MaxTokensPerSecond caps the token rate of a segment, measured over
+	// at least a second.
+	MaxTokensPerSecond float64
+
+	// MaxRepeats is how many times in a row a run of up to four words may
+	// repeat before it counts as a loop.
+	MaxRepeats int
+}
+
+// Guard defaults. Fast speech stays below 8 tokens per second and 20% of a
+// segment's frames are voiced even with long pauses.
+const (
+	guardMinVoicedRatio     = 0.2
+	guardMaxTokensPerSecond = 15
+	guardMaxRepeats         = 4
+	guardMaxNGram           = 4    // longest repeated phrase looked for, in words
+	guardVoicedZCR          = 0.25 // zero crossings per sample below which a frame can be voiced
+)
+
+func (g HallucinationGuard) enabled() bool {
+	return g.Mode == GuardFlag || g.Mode == GuardBlank
+}
+
+func (g HallucinationGuard) withDefaults() HallucinationGuard {
+	if g.MinVoicedRatio == 0 {
+		g.MinVoicedRatio = guardMinVoicedRatio
+	}
+	if g.MaxTokensPerSecond == 0 {
+		g.MaxTokensPerSecond = guardMaxTokensPerSecond
+	}
+	if g.MaxRepeats == 0 {
+		g.MaxRepeats = guardMaxRepeats
+	}
+	return g
+}
+
+// check runs the guard over the segments of one channel, decoded from plane
+// with offset seconds trimmed off its start (nil skips the voicing check),
+// and returns the segments, words and tokens that remain.
+func (g HallucinationGuard) check(segments []Segment, words []Word, tokens []Token, plane []float32, offset float64) ([]Segment, []Word, []Token) {
+	g = g.withDefaults()
+	var dropped []Segment // whole segments, or single collapsed words
+	kept := segments[:0]
+	for _, seg := range segments {
+		var segWords []Word
+		for _, w := range words {
+			if w.Channel == seg.Channel && w.Start >= seg.Start && w.Start < seg.End {
+				segWords = append(segWords, w)
+			}
+		}
+		if len(segWords) == 0 {
+			kept = append(kept, seg)
+			continue
+		}
+
+		seg.Suspect = nil
+		improbable := false
+		if plane != nil {
+			from := min(max(int((seg.Start-offset)*featureSampleRate), 0), len(plane))
+			to := min(max(int((seg.End-offset)*featureSampleRate), from), len(plane))
+			if voicedRatio(plane[from:to]) < g.MinVoicedRatio {
+				seg.Suspect = append(seg.Suspect, SuspectUnvoiced)
+				improbable = true
+			}
+		}
+		n := 0
+		for _, tok := range tokens {
+			if tok.Channel == seg.Channel && tok.Start >= seg.Start && tok.Start < seg.End {
+				n++
+			}
+		}
+		if float64(n)/max(seg.End-seg.Start, 1) > g.MaxTokensPerSecond {
+			seg.Suspect = append(seg.Suspect, SuspectTokenRate)
+			improbable = true
+		}
+		keep, looped := collapseRepeats(segWords, g.MaxRepeats)
+		if looped {
+			seg.Suspect = append(seg.Suspect, SuspectRepetition)
+		}
+
+		if g.Mode != GuardBlank {
+			kept = append(kept, seg)
+			continue
+		}
+		if improbable {
+			dropped = append(dropped, seg)
+			continue
+		}
+		if looped {
+			var texts []string
+			for i, w := range segWords {
+				if keep[i] {
+					texts = append(texts, w.Text)
+				} else {
+					dropped = append(dropped, Segment{Channel: w.Channel, Start: w.Start, End: w.End})
+				}
+			}
+			seg.Text = strings.Join(texts, " ")
+		}
+		kept = append(kept, seg)
+	}
+	if len(dropped) == 0 {
+		return kept, words, tokens
+	}
+	return kept, filterSpans(words, dropped, func(w Word) (int, float64) { return w.Channel, w.Start }),
+		filterSpans(tokens, dropped, func(t Token) (int, float64) { return t.Channel, t.Start })
+}
+
+// filterSpans returns the items that do not start inside any of spans, in
+// place.
+func filterSpans[T any](items []T, spans []Segment, at func(T) (int, float64)) []T {
+	out := items[:0]
+	for _, it := range items {
+		channel, start := at(it)
+		inside := false
+		for _, s := range spans {
+			if s.Channel == channel && start >= s.Start && start < s.End {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// voicedRatio is the fraction of 20 ms frames of x that sound like voice:
+// above the silence floor and with fewer zero crossings than broadband
+// noise.
+func voicedRatio(x []float32) float64 {
+	frame := int(conditionFrameSeconds * featureSampleRate)
+	floor := dbToAmplitude(conditionSilenceDBFS)
+	var frames, voiced int
+	for start := 0; start+frame <= len(x); start += frame {
+		f := x[start : start+frame]
+		frames++
+		if frameRMS(f) >= floor && zeroCrossingRate(f) < guardVoicedZCR {
+			voiced++
+		}
+	}
+	if frames == 0 {
+		return 1
+	}
+	return float64(voiced) / float64(frames)
+}
+
+func zeroCrossingRate(x []float32) float64 {
+	n := 0
+	for i := 1; i < len(x); i++ {
+		if (x[i] >= 0) != (x[i-1] >= 0) {
+			n++
+		}
+	}
+	return float64(n) / float64(len(x))
+}
+
+// collapseRepeats finds runs of one to guardMaxNGram words repeated more
+// than maxRepeats times in a row and marks every copy but the first for
+// removal. It returns which words to keep and whether it found a loop.
+func collapseRepeats(words []Word, maxRepeats int) ([]bool, bool) {
+	keys := make([]string, len(words))
+	for i, w := range words {
+		keys[i] = strings.ToLower(strings.TrimFunc(w.Text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}))
+	}
+	same := func(a, b int, n int) bool {
+		for k := 0; k < n; k++ {
+			if keys[a+k] != keys[b+k] {
+				return false
+			}
+		}
+		return true
+	}
+
+	keep := make([]bool, len(words))
+	looped := false
+	for i := 0; i < len(words); {
+		reps, n := 1, 1
+		for n = 1; n <= guardMaxNGram && i+2*n <= len(words); n++ {
+			reps = 1
+			for i+(reps+1)*n <= len(words) && same(i, i+reps*n, n) {
+				reps++
+			}
+			if reps > maxRepeats {
+				break
+			}
+		}
+		if reps > maxRepeats {
+			for k := i; k < i+n; k++ {
+				keep[k] = true
+			}
+			i += reps * n
+			looped = true
+			continue
+		}
+		keep[i] = true
+		i++
+	}
+	return keep, looped
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseGuardMode(t *testing.T) {
+	for in, want := range map[string]GuardMode{"": GuardOff, "off": GuardOff, " Flag ": GuardFlag, "blank": GuardBlank} {
+		if got, err := ParseGuardMode(in); err != nil || got != want {
+			t.Errorf("ParseGuardMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseGuardMode("drop"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestCollapseRepeats(t *testing.T) {
+	words := func(text string) []Word {
+		var ws []Word
+		for _, f := range strings.Fields(text) {
+			ws = append(ws, Word{Text: f})
+		}
+		return ws
+	}
+	kept := func(ws []Word, keep []bool) string {
+		var out []string
+		for i, w := range ws {
+			if keep[i] {
+				out = append(out, w.Text)
+			}
+		}
+		return strings.Join(out, " ")
+	}
+	for _, tc := range []struct {
+		in, want string
+		looped   bool
+	}{
+		{"no no no", "no no no", false},
+		{"Thank you. Thank you. thank you, Thank you. Thank you. Bye", "Thank you. Bye", true},
+		{"la la la la la la", "la", true},
+		{"so I said go go go go go now", "so I said go now", true},
+	} {
+		ws := words(tc.in)
+		keep, looped := collapseRepeats(ws, 4)
+		if got := kept(ws, keep); got != tc.want || looped != tc.looped {
+			t.Errorf("collapseRepeats(%q) = %q, %t", tc.in, got, looped)
+		}
+	}
+}
+
+func TestVoicedRatio(t *testing.T) {
+	n := featureSampleRate
+	tone := make([]float32, n) // 200 Hz: voiced
+	hiss := make([]float32, n) // alternating samples: a zero crossing each
+	for i := range tone {
+		tone[i] = float32(0.1 * math.Sin(2*math.Pi*200*float64(i)/featureSampleRate))
+		hiss[i] = 0.1
+		if i%2 == 1 {
+			hiss[i] = -0.1
+		}
+	}
+	if r := voicedRatio(tone); r != 1 {
+		t.Errorf("tone ratio = %g", r)
+	}
+	if r := voicedRatio(hiss); r != 0 {
+		t.Errorf("hiss ratio = %g", r)
+	}
+	if r := voicedRatio(make([]float32, n)); r != 0 {
+		t.Errorf("silence ratio = %g", r)
+	}
+}
+
+func TestHallucinationGuard(t *testing.T) {
+	// One second of speech-like tone, then a second of silence under a
+	// made-up "Thank you.", then a repetition loop over the tone again.
+	plane := make([]float32, 3*featureSampleRate)
+	for i := range plane {
+		if i < featureSampleRate || i >= 2*featureSampleRate {
+			plane[i] = float32(0.1 * math.Sin(2*math.Pi*200*float64(i)/featureSampleRate))
+		}
+	}
+	segments := []Segment{
+		{Start: 0, End: 1, Text: "Hello there."},
+		{Start: 1, End: 2, Text: "Thank you."},
+		{Start: 2, End: 3, Text: "go go go go go go"},
+	}
+	var words []Word
+	var tokens []Token
+	for _, seg := range segments {
+		fields := strings.Fields(seg.Text)
+		step := (seg.End - seg.Start) / float64(len(fields))
+		for i, f := range fields {
+			at := seg.Start + float64(i)*step
+			words = append(words, Word{Start: at, End: at + step, Text: f})
+			tokens = append(tokens, Token{Start: at, Text: " " + f})
+		}
+	}
+
+	flagged, _, _ := HallucinationGuard{Mode: GuardFlag}.check(append([]Segment(nil), segments...), append([]Word(nil), words...), tokens, plane, 0)
+	if len(flagged) != 3 || flagged[0].Suspect != nil ||
+		strings.Join(flagged[1].Suspect, ",") != SuspectUnvoiced || strings.Join(flagged[2].Suspect, ",") != SuspectRepetition {
+		t.Fatalf("flagged = %+v", flagged)
+	}
+
+	res := &Result{Channels: 1}
+	res.Segments, res.Words, res.Tokens = HallucinationGuard{Mode: GuardBlank}.check(segments, words, tokens, plane, 0)
+	rebuildText(res)
+	if res.Text != "Hello there. go" || len(res.Words) != 3 || len(res.Tokens) != 3 {
+		t.Errorf("blanked = %q, %d words, %d tokens", res.Text, len(res.Words), len(res.Tokens))
+	}
+
+	// A burst of tokens no one can speak.
+	fast := []Token{}
+	for i := 0; i < 40; i++ {
+		fast = append(fast, Token{Start: float64(i) * 0.025})
+	}
+	got, _, _ := HallucinationGuard{Mode: GuardFlag}.check([]Segment{{Start: 0, End: 1, Text: "x"}}, []Word{{Start: 0, End: 1, Text: "x"}}, fast, nil, 0)
+	if strings.Join(got[0].Suspect, ",") != SuspectTokenRate {
+		t.Errorf("fast segment = %+v", got[0])
+	}
+}
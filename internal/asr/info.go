@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+// RuntimeInfo describes what a Transcriber actually loaded, for version and
+// health reporting. Model files are listed in load order; optional models
+// (VAD, denoise) only appear when they were found.
+type RuntimeInfo struct {
+	ModelType          string
+	Provider           Provider
+	ONNXRuntimeVersion string
+	ModelFiles         []string
+	// Precision is the export loaded for each model.
+	Precision PrecisionConfig
+	// Languages are the ISO-639-1 codes the model transcribes.
+	Languages []string
+	// Name identifies the model: config.json "name", else DefaultModelName.
+	Name string
+	// Parameters is config.json "parameters", else estimated from the size
+	// of the encoder and decoder weights at their precision.
+	Parameters int64
+	// Modified is the newest modification time of the model files; zero
+	// when none could be read.
+	Modified time.Time
+}
+
+// DefaultModelName is the name of a model whose config.json has none.
+const DefaultModelName = "parakeet-tdt-0.6b"
+
+// Info returns the runtime details recorded when the Transcriber was built.
+func (t *Transcriber) Info() RuntimeInfo {
+	return RuntimeInfo{
+		ModelType:          t.config.ModelType,
+		Provider:           t.provider,
+		ONNXRuntimeVersion: t.runtimeVersion,
+		ModelFiles:         append([]string(nil), t.modelFiles...),
+		Precision:          t.precision,
+		Languages:          t.Languages(),
+		Name:               t.name(),
+		Parameters:         t.parameters,
+		Modified:           t.modified,
+	}
+}
+
+func (t *Transcriber) name() string {
+	if t.config.Name == "" {
+		return DefaultModelName
+	}
+	return t.config.Name
+}
+
+// describeModel records the newest modification time of the model files,
+// the size of the weights and, unless config.json gives it, the parameter count estimated from the
+// size of the weights at their precision. The estimate ignores the few
+// tensors quantized exports keep in full precision, so it is rounded to
+// millions.
+func (t *Transcriber) describeModel(models modelStore, files []string, weights map[string]Precision) {
+	note := func(name string) int64 {
+		st, err := models.stat(name)
+		if err != nil {
+			return 0
+		}
+		if st.ModTime().After(t.modified) {
+			t.modified = st.ModTime()
+		}
+		return st.Size()
+	}
+	for _, name := range files {
+		note(name)
+	}
+	var estimate float64
+	for name, p := range weights {
+		size := note(name)
+		t.weightBytes += size
+		estimate += float64(size) / bytesPerWeight(p)
+	}
+	t.parameters = t.config.Parameters
+	if t.parameters == 0 {
+		t.parameters = int64(math.Round(estimate/1e6)) * 1e6
+	}
+}
+
+// bytesPerWeight is the storage of one weight at precision p.
+func bytesPerWeight(p Precision) float64 {
+	switch p {
+	case PrecisionInt8:
+		return 1
+	case PrecisionFP16:
+		return 2
+	}
+	return 4
+}
+
+// OpenModelFile opens one of the files listed in Info, from the models
+// directory or from Options.Models.
+func (t *Transcriber) OpenModelFile(name string) (io.ReadCloser, error) {
+	return t.models.open(name)
+}
+
+// PoolStatus is a snapshot of the decoder worker pool.
+type PoolStatus struct {
+	Workers int // pool size (-workers)
+	Busy    int // workers currently decoding
+	Waiting int // decodes queued for a free worker
+	// WaitingByPriority splits Waiting by scheduling class.
+	WaitingByPriority map[Priority]int
+	// BatchRuns and BatchSteps count the ONNX calls of decoder batching
+	// and the decoder steps they ran; zero when it is off.
+	BatchRuns  int64
+	BatchSteps int64
+}
+
+// PoolStatus returns the current decoder pool occupancy.
+func (t *Transcriber) PoolStatus() PoolStatus {
+	if t.decoderPool == nil {
+		return PoolStatus{}
+	}
+	st := t.decoderPool.status()
+	if t.batcher != nil {
+		st.BatchRuns, st.BatchSteps = t.batcher.stats()
+	}
+	return st
+}
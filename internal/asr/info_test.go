@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestDescribeModel(t *testing.T) {
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newest := old.Add(24 * time.Hour)
+	models := modelStore{fsys: fstest.MapFS{
+		"config.json":                   {Data: []byte("{}"), ModTime: old},
+		"vocab.txt":                     {Data: []byte("<blk> 0\n"), ModTime: newest},
+		"encoder-model.int8.onnx":       {Data: []byte(strings.Repeat("w", 600e3)), ModTime: old},
+		"decoder_joint-model.fp16.onnx": {Data: []byte(strings.Repeat("w", 2*400e3)), ModTime: old},
+	}}
+	weights := map[string]Precision{
+		"encoder-model.int8.onnx":       PrecisionInt8,
+		"encoder-model.int8.onnx.data":  PrecisionInt8, // absent: ignored
+		"decoder_joint-model.fp16.onnx": PrecisionFP16,
+	}
+
+	tr := &Transcriber{}
+	tr.describeModel(models, []string{"config.json", "vocab.txt"}, weights)
+	info := tr.Info()
+	if info.Name != DefaultModelName || !info.Modified.Equal(newest) || info.Parameters != 1e6 {
+		t.Errorf("info = %q %v %d", info.Name, info.Modified, info.Parameters)
+	}
+
+	// config.json wins over the estimate.
+	tr = &Transcriber{config: Config{Name: "parakeet-tdt-0.6b-v3", Parameters: 627e6}}
+	tr.describeModel(models, nil, weights)
+	if info := tr.Info(); info.Name != "parakeet-tdt-0.6b-v3" || info.Parameters != 627e6 {
+		t.Errorf("info = %q %d", info.Name, info.Parameters)
+	}
+}
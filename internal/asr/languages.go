@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// defaultLanguages are the ISO-639-1 codes parakeet-tdt-0.6b-v3 transcribes,
+// assumed when config.json does not list the model's own.
+var defaultLanguages = []string{
+	"bg", "cs", "da", "de", "el", "en", "es", "et", "fi", "fr", "hr", "hu", "it",
+	"lt", "lv", "mt", "nl", "pl", "pt", "ro", "ru", "sk", "sl", "sv", "uk",
+}
+
+// normalizeLanguages lowercases, sorts and checks the "languages" of
+// config.json; an empty list takes defaultLanguages.
+func normalizeLanguages(langs []string) ([]string, error) {
+	if len(langs) == 0 {
+		return slices.Clone(defaultLanguages), nil
+	}
+	out := make([]string, 0, len(langs))
+	for _, l := range langs {
+		code := strings.ToLower(strings.TrimSpace(l))
+		if len(code) != 2 || strings.Trim(code, "abcdefghijklmnopqrstuvwxyz") != "" {
+			return nil, fmt.Errorf("invalid config: language %q is not an ISO-639-1 code", l)
+		}
+		out = append(out, code)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// Languages returns the ISO-639-1 codes the loaded model transcribes, sorted.
+func (t *Transcriber) Languages() []string {
+	return slices.Clone(t.languages())
+}
+
+// SupportsLanguage reports whether the loaded model transcribes tag. Only
+// the primary subtag counts, so "en-US" and AssemblyAI's "en_us" are "en".
+func (t *Transcriber) SupportsLanguage(tag string) bool {
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return slices.Contains(t.languages(), strings.ToLower(primary))
+}
+
+// languages is the model's list, set by NewTranscriber.
+func (t *Transcriber) languages() []string {
+	if len(t.config.Languages) == 0 {
+		return defaultLanguages
+	}
+	return t.config.Languages
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeLanguages(t *testing.T) {
+	got, err := normalizeLanguages(nil)
+	if err != nil || len(got) != len(defaultLanguages) {
+		t.Errorf("defaults = %v, %v", got, err)
+	}
+	got, err = normalizeLanguages([]string{"FR", " en", "fr"})
+	if err != nil || fmt.Sprint(got) != "[en fr]" {
+		t.Errorf("normalized = %v, %v", got, err)
+	}
+	for _, bad := range []string{"eng", "e1", ""} {
+		if _, err := normalizeLanguages([]string{bad}); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestSupportsLanguage(t *testing.T) {
+	v3 := &Transcriber{}
+	for tag, want := range map[string]bool{"en": true, "EN-us": true, "pt_br": true, "uk": true, "ja": false, "": false} {
+		if got := v3.SupportsLanguage(tag); got != want {
+			t.Errorf("default model: %q = %t", tag, got)
+		}
+	}
+	english := &Transcriber{config: Config{Languages: []string{"en"}}}
+	if !english.SupportsLanguage("en-GB") || english.SupportsLanguage("de") {
+		t.Error("English-only model")
+	}
+	if langs := english.Languages(); fmt.Sprint(langs) != "[en]" {
+		t.Errorf("Languages() = %v", langs)
+	}
+}
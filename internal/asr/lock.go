@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// modelsLockFile lists the expected SHA-256 of the model files, one
+// "<hex>  <file>" line each as written by sha256sum (make models-lock).
+const modelsLockFile = "models.lock"
+
+// VerifyMode is what NewTranscriber does when a model file does not match
+// models.lock.
+type VerifyMode string
+
+const (
+	// VerifyError refuses to load mismatched or missing files; the default.
+	VerifyError VerifyMode = "error"
+	// VerifyWarn logs the mismatch and loads the files anyway.
+	VerifyWarn VerifyMode = "warn"
+	// VerifyOff skips the check, and the hashing time it costs at startup.
+	VerifyOff VerifyMode = "off"
+)
+
+// ParseVerifyMode normalizes a user-supplied verify mode. An empty value
+// defaults to error; unknown values are rejected at startup.
+func ParseVerifyMode(s string) (VerifyMode, error) {
+	switch m := VerifyMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return VerifyError, nil
+	case VerifyError, VerifyWarn, VerifyOff:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported verify mode %q (supported: error, warn, off)", s)
+	}
+}
+
+// lockEntry is one line of models.lock.
+type lockEntry struct {
+	name   string
+	sha256 string
+}
+
+// parseModelsLock reads models.lock. Blank lines and # comments are
+// skipped; sha256sum's binary marker (*file) is accepted.
+func parseModelsLock(data []byte) ([]lockEntry, error) {
+	var entries []lockEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if raw, err := hex.DecodeString(sum); !ok || err != nil || len(raw) != sha256.Size || !fs.ValidPath(name) {
+			return nil, fmt.Errorf("%s line %d: want \"<sha256>  <file>\", got %q", modelsLockFile, n, line)
+		}
+		entries = append(entries, lockEntry{name: name, sha256: strings.ToLower(sum)})
+	}
+	return entries, scanner.Err()
+}
+
+// verifyModels checks the files listed in the store's models.lock, if it has
+// one. A listed file that is missing or differs is an error in VerifyError
+// mode and a warning in VerifyWarn mode.
+func verifyModels(models modelStore, mode VerifyMode) error {
+	if mode == VerifyOff {
+		return nil
+	}
+	data, err := models.readFile(modelsLockFile)
+	if os.IsNotExist(err) {
+		slog.Debug("no models.lock, model files not verified")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", modelsLockFile, err)
+	}
+	entries, err := parseModelsLock(data)
+	if err != nil {
+		return err
+	}
+
+	var bad []string
+	for _, e := range entries {
+		sum, err := sha256Of(models, e.name)
+		switch {
+		case err != nil:
+			bad = append(bad, fmt.Sprintf("%s: %v", e.name, err))
+		case sum != e.sha256:
+			bad = append(bad, fmt.Sprintf("%s: sha256 %s, want %s", e.name, sum, e.sha256))
+		}
+	}
+	if len(bad) == 0 {
+		slog.Info("model files verified", "lock", models.path(modelsLockFile), "files", len(entries))
+		return nil
+	}
+	if mode == VerifyWarn {
+		for _, b := range bad {
+			slog.Warn("model file does not match models.lock", "problem", b)
+		}
+		return nil
+	}
+	return fmt.Errorf("model files do not match %s (re-download them, or set -verify-models=warn): %s",
+		models.path(modelsLockFile), strings.Join(bad, "; "))
+}
+
+func sha256Of(models modelStore, name string) (string, error) {
+	f, err := models.open(models.path(name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// sha256("abc") and sha256("")
+const (
+	sumABC   = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	sumEmpty = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+func TestParseVerifyMode(t *testing.T) {
+	for in, want := range map[string]VerifyMode{"": VerifyError, "error": VerifyError, " WARN ": VerifyWarn, "off": VerifyOff} {
+		if got, err := ParseVerifyMode(in); err != nil || got != want {
+			t.Errorf("ParseVerifyMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseVerifyMode("strict"); err == nil {
+		t.Error("want error for an unknown mode")
+	}
+}
+
+func TestParseModelsLock(t *testing.T) {
+	entries, err := parseModelsLock([]byte("# models\n" + sumABC + "  vocab.txt\n\n" + strings.ToUpper(sumEmpty) + " *config.json\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0] != (lockEntry{"vocab.txt", sumABC}) || entries[1] != (lockEntry{"config.json", sumEmpty}) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for _, bad := range []string{"abc  vocab.txt", sumABC, sumABC + "  ../vocab.txt"} {
+		if _, err := parseModelsLock([]byte(bad)); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestVerifyModels(t *testing.T) {
+	files := fstest.MapFS{
+		"vocab.txt":   {Data: []byte("abc")},
+		"config.json": {Data: []byte("")},
+	}
+	store := func(lock string) modelStore {
+		fsys := fstest.MapFS{}
+		for k, v := range files {
+			fsys[k] = v
+		}
+		if lock != "" {
+			fsys[modelsLockFile] = &fstest.MapFile{Data: []byte(lock)}
+		}
+		return modelStore{fsys: fsys}
+	}
+
+	good := sumABC + "  vocab.txt\n" + sumEmpty + "  config.json\n"
+	if err := verifyModels(store(good), VerifyError); err != nil {
+		t.Fatalf("matching files: %v", err)
+	}
+	if err := verifyModels(store(""), VerifyError); err != nil {
+		t.Fatalf("no lock: %v", err)
+	}
+
+	mismatch := sumEmpty + "  vocab.txt\n" + sumABC + "  encoder-model.int8.onnx\n"
+	err := verifyModels(store(mismatch), VerifyError)
+	if err == nil || !strings.Contains(err.Error(), "vocab.txt: sha256 "+sumABC) || !strings.Contains(err.Error(), "encoder-model.int8.onnx") {
+		t.Fatalf("mismatch: %v", err)
+	}
+	if err := verifyModels(store(mismatch), VerifyWarn); err != nil {
+		t.Fatalf("warn mode: %v", err)
+	}
+	if err := verifyModels(store("not a lock"), VerifyOff); err != nil {
+		t.Fatalf("off mode: %v", err)
+	}
+}
@@ -4,11 +4,124 @@
 package asr
 
 import (
+	"fmt"
 	"log/slog"
 	"math"
-	"math/cmplx"
+	"math/rand"
+	"runtime"
+	"sync"
 )
 
+// melMinFramesPerWorker is the smallest frame range worth a goroutine in
+// Extract. 1000 frames is 10 s of audio; shorter clips are extracted serially.
+const melMinFramesPerWorker = 1000
+
+// Log zero guard modes, named as in NeMo's preprocessor config.
+const (
+	LogZeroGuardAdd   = "add"   // log(x + guard)
+	LogZeroGuardClamp = "clamp" // log(max(x, guard))
+)
+
+// Feature normalization modes, named as in NeMo's preprocessor config.
+const (
+	NormalizePerFeature  = "per_feature"  // mean/std per mel bin over time
+	NormalizeAllFeatures = "all_features" // one mean/std over the whole matrix
+	NormalizeNone        = "none"
+)
+
+// nemoNormalizeEpsilon is added to the standard deviation by NeMo's
+// normalize_batch before dividing, so silent bins do not blow up.
+const nemoNormalizeEpsilon = 1e-5
+
+// MelOptions are the STFT geometry and the waveform/log-mel preprocessing
+// details that must match how the model was trained. DefaultMelOptions
+// returns NeMo's values for parakeet models.
+type MelOptions struct {
+	// NFFT is the FFT size (a power of two); WinLength and HopLength are the
+	// analysis window and frame step, all in samples. The Hann window is
+	// zero-padded up to NFFT.
+	NFFT      int
+	WinLength int
+	HopLength int
+
+	// FMin and FMax bound the mel filterbank in Hz. FMax 0 means Nyquist.
+	FMin float64
+	FMax float64
+
+	// Preemphasis is the first-order high-pass coefficient applied to the
+	// waveform (x[t] - k*x[t-1]). Zero disables it.
+	Preemphasis float64
+
+	// Dither is the standard deviation of Gaussian noise added to the
+	// waveform. NeMo only dithers while training, so inference defaults to
+	// zero; when set, the noise is seeded per call to keep output deterministic.
+	Dither float64
+
+	// LogZeroGuard is LogZeroGuardAdd or LogZeroGuardClamp, applied with
+	// LogZeroGuardValue before taking the log of each mel energy.
+	LogZeroGuard      string
+	LogZeroGuardValue float64
+
+	// Normalize is NormalizePerFeature, NormalizeAllFeatures or NormalizeNone.
+	Normalize string
+}
+
+// DefaultMelOptions returns NeMo's preprocessing defaults for parakeet models.
+func DefaultMelOptions() MelOptions {
+	return MelOptions{
+		NFFT:              512,
+		WinLength:         400, // 25ms at 16kHz
+		HopLength:         160, // 10ms at 16kHz
+		Preemphasis:       0.97,
+		Dither:            0,
+		LogZeroGuard:      LogZeroGuardAdd,
+		LogZeroGuardValue: math.Exp2(-24),
+		Normalize:         NormalizePerFeature,
+	}
+}
+
+// validate rejects option values the extractor does not implement, given
+// the sample rate the features are computed at.
+func (o MelOptions) validate(sampleRate int) error {
+	if o.NFFT < 4 || o.NFFT&(o.NFFT-1) != 0 {
+		return fmt.Errorf("n_fft must be a power of two >= 4, got %d", o.NFFT)
+	}
+	if o.WinLength < 2 || o.WinLength > o.NFFT {
+		return fmt.Errorf("window length must be in [2, n_fft=%d] samples, got %d", o.NFFT, o.WinLength)
+	}
+	if o.HopLength < 1 {
+		return fmt.Errorf("hop length must be >= 1 sample, got %d", o.HopLength)
+	}
+	nyquist := float64(sampleRate) / 2
+	fmax := o.FMax
+	if fmax == 0 {
+		fmax = nyquist
+	}
+	if o.FMin < 0 || fmax > nyquist || o.FMin >= fmax {
+		return fmt.Errorf("mel band must satisfy 0 <= lowfreq < highfreq <= %v Hz, got %v..%v", nyquist, o.FMin, fmax)
+	}
+	if o.Preemphasis < 0 || o.Preemphasis >= 1 {
+		return fmt.Errorf("preemph must be in [0, 1), got %v", o.Preemphasis)
+	}
+	if o.Dither < 0 {
+		return fmt.Errorf("dither must be >= 0, got %v", o.Dither)
+	}
+	switch o.LogZeroGuard {
+	case LogZeroGuardAdd, LogZeroGuardClamp:
+	default:
+		return fmt.Errorf("unknown log_zero_guard_type %q (want %q or %q)", o.LogZeroGuard, LogZeroGuardAdd, LogZeroGuardClamp)
+	}
+	if o.LogZeroGuardValue <= 0 {
+		return fmt.Errorf("log_zero_guard_value must be > 0, got %v", o.LogZeroGuardValue)
+	}
+	switch o.Normalize {
+	case NormalizePerFeature, NormalizeAllFeatures, NormalizeNone:
+	default:
+		return fmt.Errorf("unknown normalize mode %q", o.Normalize)
+	}
+	return nil
+}
+
 // MelFilterbank computes mel-scale filterbank features
 type MelFilterbank struct {
 	nMels      int
@@ -16,22 +129,33 @@ type MelFilterbank struct {
 	nFFT       int
 	hopLength  int
 	winLength  int
-	filterbank [][]float64
+	filters    []melFilter
 	hannWindow []float64
+	rfft       *rfftPlan
+	opts       MelOptions
+
+	// float32 selects extractFrames32, with these single-precision copies
+	// of the window and filters.
+	float32      bool
+	hannWindow32 []float32
+	filters32    [][]float32
 }
 
-// NewMelFilterbank creates a new mel filterbank extractor
-// Using NeMo default parameters for 128 mel features
-func NewMelFilterbank(nMels, sampleRate int) *MelFilterbank {
+// NewMelFilterbank creates a new mel filterbank extractor with the STFT
+// geometry and preprocessing in opts (see DefaultMelOptions for NeMo's
+// parakeet values). opts must have passed validation.
+func NewMelFilterbank(nMels, sampleRate int, opts MelOptions) *MelFilterbank {
 	m := &MelFilterbank{
 		nMels:      nMels,
 		sampleRate: sampleRate,
-		nFFT:       512,
-		hopLength:  160, // 10ms at 16kHz
-		winLength:  400, // 25ms at 16kHz
+		nFFT:       opts.NFFT,
+		hopLength:  opts.HopLength,
+		winLength:  opts.WinLength,
+		opts:       opts,
 	}
-	m.filterbank = m.createMelFilterbank()
+	m.filters = sparseFilters(m.createMelFilterbank())
 	m.hannWindow = m.createHannWindow()
+	m.rfft = newRFFTPlan(m.nFFT)
 	return m
 }
 
@@ -53,8 +177,12 @@ func (m *MelFilterbank) melToHz(mel float64) float64 {
 
 func (m *MelFilterbank) createMelFilterbank() [][]float64 {
 	numBins := m.nFFT/2 + 1
-	melMin := m.hzToMel(0)
-	melMax := m.hzToMel(float64(m.sampleRate) / 2)
+	fmax := m.opts.FMax
+	if fmax == 0 {
+		fmax = float64(m.sampleRate) / 2
+	}
+	melMin := m.hzToMel(m.opts.FMin)
+	melMax := m.hzToMel(fmax)
 
 	// Create mel points
 	melPoints := make([]float64, m.nMels+2)
@@ -85,6 +213,67 @@ func (m *MelFilterbank) createMelFilterbank() [][]float64 {
 	return filterbank
 }
 
+// melFilter is one triangular mel filter stored as its nonzero band: weights
+// apply to FFT bins start..start+len(weights)-1. A filter only spans the bins
+// between its neighbours' centres, so skipping the zeros elsewhere removes
+// most of the filterbank multiply.
+type melFilter struct {
+	start   int
+	weights []float64
+}
+
+// sparseFilters trims each dense filter row to its nonzero band. A filter
+// with no nonzero weight (possible at low frequencies, where adjacent mel
+// points round to the same bin) gets an empty band and contributes zero energy,
+// exactly as the dense row did.
+func sparseFilters(dense [][]float64) []melFilter {
+	filters := make([]melFilter, len(dense))
+	for i, row := range dense {
+		lo, hi := 0, len(row)
+		for lo < hi && row[lo] == 0 {
+			lo++
+		}
+		for hi > lo && row[hi-1] == 0 {
+			hi--
+		}
+		filters[i] = melFilter{start: lo, weights: append([]float64(nil), row[lo:hi]...)}
+	}
+	return filters
+}
+
+// apply returns the filter's energy for a power spectrum.
+func (f melFilter) apply(power []float64) float64 {
+	var energy float64
+	for j, w := range f.weights {
+		energy += power[f.start+j] * w
+	}
+	return energy
+}
+
+// SetFloat32 makes Extract compute the window, FFT, power spectrum and
+// filterbank in float32 instead of float64: half the memory traffic per
+// frame, for hardware where that is the bottleneck (Raspberry Pi class
+// boards), at the cost of normalized features about 1e-4 away from the
+// float64 ones. Normalization stays in float64.
+// It must be called before the filterbank is used concurrently.
+func (m *MelFilterbank) SetFloat32(on bool) {
+	m.float32 = on
+	if !on || m.hannWindow32 != nil {
+		return
+	}
+	m.hannWindow32 = make([]float32, len(m.hannWindow))
+	for i, w := range m.hannWindow {
+		m.hannWindow32[i] = float32(w)
+	}
+	m.filters32 = make([][]float32, len(m.filters))
+	for i, f := range m.filters {
+		m.filters32[i] = make([]float32, len(f.weights))
+		for j, w := range f.weights {
+			m.filters32[i][j] = float32(w)
+		}
+	}
+}
+
 // FramesPerSecond returns how many mel frames one second of audio yields, set
 // by the hop length and sample rate. It ties frame counts to wall-clock time
 // so chunk sizes can be configured in seconds.
@@ -110,53 +299,155 @@ func (m *MelFilterbank) Extract(samples []float32) [][]float32 {
 	}
 
 	features := make([][]float32, numFrames)
+	samples = m.prepareWaveform(samples)
+
+	// Frames are independent, so split them into contiguous ranges across up
+	// to GOMAXPROCS goroutines. Short clips stay on the calling goroutine:
+	// below melMinFramesPerWorker frames per range the goroutine overhead
+	// outweighs the work.
+	workers := runtime.GOMAXPROCS(0)
+	if maxWorkers := numFrames / melMinFramesPerWorker; workers > maxWorkers {
+		workers = maxWorkers
+	}
+	extract := m.extractFrames
+	if m.float32 {
+		extract = m.extractFrames32
+	}
+	if workers <= 1 {
+		extract(samples, features, 0, numFrames)
+	} else {
+		per := (numFrames + workers - 1) / workers
+		var wg sync.WaitGroup
+		for from := 0; from < numFrames; from += per {
+			to := min(from+per, numFrames)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				extract(samples, features, from, to)
+			}()
+		}
+		wg.Wait()
+	}
+
+	switch m.opts.Normalize {
+	case NormalizePerFeature:
+		m.normalize(features)
+	case NormalizeAllFeatures:
+		normalizeAll(features)
+	}
+
+	return features
+}
+
+// prepareWaveform applies dither and preemphasis. Both need the whole
+// waveform (preemphasis reads the previous sample across frame boundaries),
+// so they run once before framing, on a copy: the caller's samples are also
+// used for VAD and must stay untouched.
+func (m *MelFilterbank) prepareWaveform(samples []float32) []float32 {
+	if m.opts.Dither == 0 && m.opts.Preemphasis == 0 {
+		return samples
+	}
+	out := make([]float32, len(samples))
+	copy(out, samples)
+	if m.opts.Dither > 0 {
+		rng := rand.New(rand.NewSource(int64(len(samples))))
+		for i := range out {
+			out[i] += float32(m.opts.Dither * rng.NormFloat64())
+		}
+	}
+	if k := float32(m.opts.Preemphasis); k != 0 {
+		for i := len(out) - 1; i > 0; i-- {
+			out[i] -= k * out[i-1]
+		}
+	}
+	return out
+}
+
+// logMel applies the configured zero guard and takes the natural log.
+func (m *MelFilterbank) logMel(energy float64) float32 {
+	if m.opts.LogZeroGuard == LogZeroGuardClamp {
+		return float32(math.Log(max(energy, m.opts.LogZeroGuardValue)))
+	}
+	return float32(math.Log(energy + m.opts.LogZeroGuardValue))
+}
 
-	for frame := 0; frame < numFrames; frame++ {
+// extractFrames fills features[from:to] with log-mel frames. Each call owns
+// its scratch buffers, so concurrent calls on disjoint ranges are safe; the
+// filterbank, window and FFT plan are read-only after construction.
+func (m *MelFilterbank) extractFrames(samples []float32, features [][]float32, from, to int) {
+	numBins := m.rfft.bins()
+	windowed := make([]float64, m.nFFT)
+	spectrum := make([]complex128, numBins)
+	power := make([]float64, numBins)
+
+	for frame := from; frame < to; frame++ {
 		start := frame * m.hopLength
 		end := start + m.winLength
 		if end > len(samples) {
 			end = len(samples)
 		}
 
-		// Extract frame and apply pre-computed Hann window
-		windowed := make([]float64, m.nFFT)
+		// Extract frame and apply pre-computed Hann window (the tail stays
+		// zero-padded up to nFFT)
+		clear(windowed)
 		for i := 0; i < end-start && i < m.winLength; i++ {
 			windowed[i] = float64(samples[start+i]) * m.hannWindow[i]
 		}
 
-		// FFT
-		spectrum := m.fft(windowed)
+		// One-sided real FFT
+		m.rfft.transform(windowed, spectrum)
 
 		// Power spectrum
-		numBins := m.nFFT/2 + 1
-		power := make([]float64, numBins)
 		for i := 0; i < numBins; i++ {
 			power[i] = real(spectrum[i])*real(spectrum[i]) + imag(spectrum[i])*imag(spectrum[i])
 		}
 
-		// Apply mel filterbank
+		// Apply mel filterbank over each filter's nonzero band only
 		melEnergies := make([]float32, m.nMels)
-		for i := 0; i < m.nMels; i++ {
-			var energy float64
-			for j := 0; j < numBins; j++ {
-				energy += power[j] * m.filterbank[i][j]
-			}
-			// Log mel energy
-			if energy < 1e-10 {
-				energy = 1e-10
-			}
-			melEnergies[i] = float32(math.Log(energy))
+		for i, filter := range m.filters {
+			melEnergies[i] = m.logMel(filter.apply(power))
 		}
 
 		features[frame] = melEnergies
 	}
+}
 
-	// Normalize (optional but helpful)
-	m.normalize(features)
+// extractFrames32 is extractFrames in single precision, up to the log.
+func (m *MelFilterbank) extractFrames32(samples []float32, features [][]float32, from, to int) {
+	numBins := m.rfft.bins()
+	windowed := make([]float32, m.nFFT)
+	spectrum := make([]complex64, numBins)
+	power := make([]float32, numBins)
 
-	return features
+	for frame := from; frame < to; frame++ {
+		start := frame * m.hopLength
+		end := min(start+m.winLength, len(samples))
+
+		clear(windowed)
+		for i := 0; i < end-start && i < m.winLength; i++ {
+			windowed[i] = samples[start+i] * m.hannWindow32[i]
+		}
+		m.rfft.transform32(windowed, spectrum)
+		for i := 0; i < numBins; i++ {
+			power[i] = real(spectrum[i])*real(spectrum[i]) + imag(spectrum[i])*imag(spectrum[i])
+		}
+
+		melEnergies := make([]float32, m.nMels)
+		for i, weights := range m.filters32 {
+			var energy float32
+			band := power[m.filters[i].start:]
+			for j, w := range weights {
+				energy += band[j] * w
+			}
+			melEnergies[i] = m.logMel(float64(energy))
+		}
+		features[frame] = melEnergies
+	}
 }
 
+// normalize applies NeMo's per_feature normalization: each mel bin is
+// centred on its mean over time and divided by its unbiased (n-1) standard
+// deviation plus nemoNormalizeEpsilon.
 func (m *MelFilterbank) normalize(features [][]float32) {
 	if len(features) == 0 {
 		return
@@ -178,10 +469,7 @@ func (m *MelFilterbank) normalize(features [][]float32) {
 			diff := float64(frame[i]) - means[i]
 			sumSq += diff * diff
 		}
-		stds[i] = math.Sqrt(sumSq / float64(len(features)))
-		if stds[i] < 1e-10 {
-			stds[i] = 1e-10
-		}
+		stds[i] = unbiasedStd(sumSq, len(features)) + nemoNormalizeEpsilon
 	}
 
 	// Apply normalization
@@ -192,56 +480,39 @@ func (m *MelFilterbank) normalize(features [][]float32) {
 	}
 }
 
-// fft performs a simple radix-2 FFT
-func (m *MelFilterbank) fft(signal []float64) []complex128 {
-	n := len(signal)
-	if n == 0 {
-		return nil
-	}
-
-	// Pad to power of 2 if needed
-	paddedLen := 1
-	for paddedLen < n {
-		paddedLen *= 2
+// normalizeAll applies NeMo's all_features normalization: one mean and one
+// standard deviation over every value of the utterance.
+func normalizeAll(features [][]float32) {
+	var sum, sumSq float64
+	var n int
+	for _, frame := range features {
+		for _, v := range frame {
+			sum += float64(v)
+			n++
+		}
 	}
-
-	x := make([]complex128, paddedLen)
-	for i := 0; i < n; i++ {
-		x[i] = complex(signal[i], 0)
+	if n == 0 {
+		return
 	}
-
-	// Bit reversal
-	bits := int(math.Log2(float64(paddedLen)))
-	for i := 0; i < paddedLen; i++ {
-		rev := reverseBits(i, bits)
-		if i < rev {
-			x[i], x[rev] = x[rev], x[i]
+	mean := sum / float64(n)
+	for _, frame := range features {
+		for _, v := range frame {
+			diff := float64(v) - mean
+			sumSq += diff * diff
 		}
 	}
-
-	// Cooley-Tukey FFT
-	for size := 2; size <= paddedLen; size *= 2 {
-		half := size / 2
-		step := -math.Pi / float64(half)
-		for i := 0; i < paddedLen; i += size {
-			for j := 0; j < half; j++ {
-				angle := step * float64(j)
-				w := cmplx.Exp(complex(0, angle))
-				t := w * x[i+j+half]
-				x[i+j+half] = x[i+j] - t
-				x[i+j] = x[i+j] + t
-			}
+	std := unbiasedStd(sumSq, n) + nemoNormalizeEpsilon
+	for _, frame := range features {
+		for i, v := range frame {
+			frame[i] = float32((float64(v) - mean) / std)
 		}
 	}
-
-	return x
 }
 
-func reverseBits(n, bits int) int {
-	result := 0
-	for i := 0; i < bits; i++ {
-		result = (result << 1) | (n & 1)
-		n >>= 1
+// unbiasedStd returns sqrt(sumSq/(n-1)), or 0 for a single sample.
+func unbiasedStd(sumSq float64, n int) float64 {
+	if n < 2 {
+		return 0
 	}
-	return result
+	return math.Sqrt(sumSq / float64(n-1))
 }
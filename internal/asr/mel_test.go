@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// Parallel extraction must be bit-identical to the serial path: frames are
+// independent and each range writes only its own slots.
+func TestExtract_ParallelMatchesSerial(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	// 35 s of noisy tone: several melMinFramesPerWorker ranges plus a ragged tail.
+	samples := make([]float32, 35*16000+123)
+	for i := range samples {
+		samples[i] = float32(0.3*math.Sin(2*math.Pi*440*float64(i)/16000) + 0.05*(rng.Float64()*2-1))
+	}
+
+	m := NewMelFilterbank(128, 16000, DefaultMelOptions())
+
+	prev := runtime.GOMAXPROCS(1)
+	serial := m.Extract(samples)
+	runtime.GOMAXPROCS(4)
+	parallel := m.Extract(samples)
+	runtime.GOMAXPROCS(prev)
+
+	if len(serial) == 0 {
+		t.Fatal("no frames extracted")
+	}
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Fatal("parallel mel features differ from serial extraction")
+	}
+}
+
+// Single-precision features track the float64 ones closely enough for the
+// encoder: normalized values within a thousandth.
+func TestExtract_Float32MatchesFloat64(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	samples := make([]float32, 5*16000)
+	for i := range samples {
+		samples[i] = float32(0.3*math.Sin(2*math.Pi*440*float64(i)/16000) + 0.05*(rng.Float64()*2-1))
+	}
+	m := NewMelFilterbank(128, 16000, DefaultMelOptions())
+	want := m.Extract(samples)
+	m.SetFloat32(true)
+	got := m.Extract(samples)
+
+	if len(got) != len(want) {
+		t.Fatalf("%d frames, want %d", len(got), len(want))
+	}
+	var worst float64
+	for f := range want {
+		for i := range want[f] {
+			worst = max(worst, math.Abs(float64(got[f][i]-want[f][i])))
+		}
+	}
+	if worst > 1e-3 {
+		t.Errorf("float32 features differ by up to %g", worst)
+	}
+}
+
+// Audio far shorter than one window yields no frames rather than panicking.
+func TestExtract_TooShort(t *testing.T) {
+	m := NewMelFilterbank(128, 16000, DefaultMelOptions())
+	if got := m.Extract(make([]float32, 100)); got != nil {
+		t.Fatalf("Extract(100 samples) = %d frames, want nil", len(got))
+	}
+}
+
+// The sparse filter bands must give the same energies as multiplying the
+// dense filterbank across every bin.
+func TestSparseFilters_MatchDense(t *testing.T) {
+	m := NewMelFilterbank(128, 16000, DefaultMelOptions())
+	dense := m.createMelFilterbank()
+	filters := sparseFilters(dense)
+
+	rng := rand.New(rand.NewSource(3))
+	power := make([]float64, m.rfft.bins())
+	for i := range power {
+		power[i] = rng.Float64()
+	}
+
+	var denseCells, sparseCells int
+	for i, row := range dense {
+		var want float64
+		for j, w := range row {
+			want += power[j] * w
+		}
+		if got := filters[i].apply(power); math.Abs(got-want) > 1e-12 {
+			t.Fatalf("filter %d: sparse energy %v, dense %v", i, got, want)
+		}
+		denseCells += len(row)
+		sparseCells += len(filters[i].weights)
+	}
+	if sparseCells*5 > denseCells {
+		t.Fatalf("sparse filterbank keeps %d of %d weights; expected a narrow band per filter", sparseCells, denseCells)
+	}
+}
+
+// An absent preprocessor block must resolve to NeMo's parakeet defaults, and
+// explicit zeros must be honoured rather than treated as "unset".
+func TestPreprocessorConfig_Defaults(t *testing.T) {
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"features_size":128}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cfg.Preprocessor.melOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != DefaultMelOptions() {
+		t.Fatalf("defaults = %+v, want %+v", got, DefaultMelOptions())
+	}
+
+	raw := `{"preprocessor":{"preemph":0,"normalize":"all_features","log_zero_guard_type":"clamp","log_zero_guard_value":1e-10}}`
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err = cfg.Preprocessor.melOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultMelOptions()
+	want.Preemphasis = 0
+	want.LogZeroGuard = LogZeroGuardClamp
+	want.LogZeroGuardValue = 1e-10
+	want.Normalize = NormalizeAllFeatures
+	if got != want {
+		t.Fatalf("overrides = %+v, want %+v", got, want)
+	}
+}
+
+func TestPreprocessorConfig_RejectsUnknownModes(t *testing.T) {
+	for _, raw := range []string{
+		`{"normalize":"per_utterance"}`,
+		`{"log_zero_guard_type":"floor"}`,
+		`{"preemph":1.5}`,
+		`{"dither":-1}`,
+		`{"sample_rate":8000}`,
+		`{"n_fft":400}`,
+		`{"window_size":0.05}`,
+		`{"window_stride":0}`,
+		`{"highfreq":9000}`,
+		`{"lowfreq":4000,"highfreq":3000}`,
+	} {
+		var p PreprocessorConfig
+		if err := json.Unmarshal([]byte(raw), &p); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.melOptions(); err == nil {
+			t.Errorf("melOptions(%s) accepted an invalid value", raw)
+		}
+	}
+}
+
+// NeMo expresses the window and hop in seconds; they become samples at the
+// 16 kHz feature rate, and the band edges shape the filterbank.
+func TestPreprocessorConfig_STFTGeometry(t *testing.T) {
+	var p PreprocessorConfig
+	raw := `{"sample_rate":16000,"window_size":0.032,"window_stride":0.02,"n_fft":1024,"lowfreq":100,"highfreq":7600}`
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := p.melOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.WinLength != 512 || opts.HopLength != 320 || opts.NFFT != 1024 || opts.FMin != 100 || opts.FMax != 7600 {
+		t.Fatalf("geometry = %+v", opts)
+	}
+
+	m := NewMelFilterbank(80, 16000, opts)
+	if m.FramesPerSecond() != 50 || m.HopLength() != 320 {
+		t.Fatalf("FramesPerSecond=%d HopLength=%d, want 50 and 320", m.FramesPerSecond(), m.HopLength())
+	}
+	// With 1024 bins of 15.6 Hz, no filter may reach past 7600 Hz.
+	last := m.filters[len(m.filters)-1]
+	if top := last.start + len(last.weights) - 1; float64(top)*16000/1024 > 7600+16000.0/1024 {
+		t.Fatalf("top filter reaches bin %d (%.0f Hz), beyond highfreq", top, float64(top)*16000/1024)
+	}
+	feats := m.Extract(make([]float32, 16000))
+	if len(feats) == 0 || len(feats[0]) != 80 {
+		t.Fatalf("Extract produced %d frames of %d features", len(feats), len(feats[0]))
+	}
+}
+
+// Preemphasis is y[t] = x[t] - k*x[t-1] on a copy; the caller's waveform is
+// left alone because VAD reads it afterwards.
+func TestPrepareWaveform_Preemphasis(t *testing.T) {
+	opts := DefaultMelOptions()
+	opts.Preemphasis = 0.5
+	m := NewMelFilterbank(128, 16000, opts)
+	in := []float32{1, 2, 4, 8}
+	got := m.prepareWaveform(in)
+
+	want := []float32{1, 1.5, 3, 6}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("preemphasis = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(in, []float32{1, 2, 4, 8}) {
+		t.Fatalf("input mutated: %v", in)
+	}
+}
+
+// per_feature normalization leaves every mel bin with zero mean and unit
+// unbiased standard deviation (up to NeMo's epsilon). Low bins whose filter
+// covers no FFT bin are constant and are skipped.
+func TestExtract_PerFeatureNormalization(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	samples := make([]float32, 3*16000)
+	for i := range samples {
+		samples[i] = float32(rng.Float64()*2 - 1)
+	}
+	feats := NewMelFilterbank(128, 16000, DefaultMelOptions()).Extract(samples)
+
+	n := float64(len(feats))
+	for _, bin := range []int{40, 64, 127} {
+		var sum, sumSq float64
+		for _, f := range feats {
+			sum += float64(f[bin])
+		}
+		mean := sum / n
+		for _, f := range feats {
+			sumSq += (float64(f[bin]) - mean) * (float64(f[bin]) - mean)
+		}
+		std := math.Sqrt(sumSq / (n - 1))
+		if math.Abs(mean) > 1e-4 || math.Abs(std-1) > 1e-3 {
+			t.Fatalf("bin %d: mean %v std %v, want 0 and 1", bin, mean, std)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// ErrOverMemoryBudget is returned for audio whose transcription needs more
+// memory than the whole MemoryBudget, so it could never run.
+var ErrOverMemoryBudget = errors.New("audio needs more memory than the memory budget")
+
+// MemoryBudget bounds the memory used by the Transcribers sharing it. Each
+// one holds the size of its model weights and encoder cache for as long as
+// it is open, and each transcription reserves an estimate of what its
+// audio, features and encoder window take before computing any of them; a
+// transcription that does not fit waits for others to finish, by priority
+// and then first come first served, and one that would not fit in an idle
+// budget fails at once with ErrOverMemoryBudget instead of running the
+// process out of memory halfway through.
+type MemoryBudget struct {
+	total int64
+
+	mu      sync.Mutex
+	weights int64 // held by open Transcribers
+	used    int64 // reserved by running transcriptions
+	waiters [3][]*memoryWaiter
+}
+
+type memoryWaiter struct {
+	bytes int64
+	ready chan struct{}
+}
+
+// NewMemoryBudget returns a budget of bytes.
+func NewMemoryBudget(bytes int64) *MemoryBudget {
+	return &MemoryBudget{total: bytes}
+}
+
+// MemoryStatus is a snapshot of a MemoryBudget.
+type MemoryStatus struct {
+	Total    int64 // bytes
+	Weights  int64 // held by the loaded models and their encoder caches
+	Reserved int64 // held by running transcriptions
+	Waiting  int   // transcriptions waiting for memory
+}
+
+// Status reports how the budget is used.
+func (b *MemoryBudget) Status() MemoryStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := MemoryStatus{Total: b.total, Weights: b.weights, Reserved: b.used}
+	for _, queue := range b.waiters {
+		st.Waiting += len(queue)
+	}
+	return st
+}
+
+// free is what transcriptions may reserve between them; b.mu must be held.
+func (b *MemoryBudget) free() int64 {
+	return b.total - b.weights
+}
+
+// holdWeights takes a model's weights, and its encoder cache, out of the
+// budget, failing when they do not fit next to the models already loaded.
+func (b *MemoryBudget) holdWeights(bytes int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.weights+bytes > b.total {
+		return fmt.Errorf("memory budget of %d MB cannot hold the loaded models (%d MB of weights and encoder cache)", b.total>>20, (b.weights+bytes)>>20)
+	}
+	b.weights += bytes
+	return nil
+}
+
+// releaseWeights gives back what holdWeights took.
+func (b *MemoryBudget) releaseWeights(bytes int64) {
+	b.mu.Lock()
+	b.weights -= bytes
+	b.wake()
+	b.mu.Unlock()
+}
+
+// reserve takes bytes for a transcription of priority prio, waiting until
+// ctx is done for running ones to give enough back. The returned function
+// gives them back.
+func (b *MemoryBudget) reserve(ctx context.Context, prio Priority, bytes int64) (func(), error) {
+	rank := prio.rank()
+	b.mu.Lock()
+	if bytes > b.free() {
+		free := b.free()
+		b.mu.Unlock()
+		return nil, fmt.Errorf("%w (needs %d MB, budget for audio %d MB)", ErrOverMemoryBudget, bytes>>20, free>>20)
+	}
+	release := func() {
+		b.mu.Lock()
+		b.used -= bytes
+		b.wake()
+		b.mu.Unlock()
+	}
+	if b.queued() == 0 && b.used+bytes <= b.free() {
+		b.used += bytes
+		b.mu.Unlock()
+		return release, nil
+	}
+	w := &memoryWaiter{bytes: bytes, ready: make(chan struct{})}
+	b.waiters[rank] = append(b.waiters[rank], w)
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		queue := b.waiters[rank]
+		for i, q := range queue {
+			if q == w {
+				b.waiters[rank] = append(queue[:i:i], queue[i+1:]...)
+				// The waiters behind it may fit now.
+				b.wake()
+				return nil, ctx.Err()
+			}
+		}
+		// wake granted it meanwhile: give it back.
+		b.used -= bytes
+		b.wake()
+		return nil, ctx.Err()
+	}
+}
+
+func (b *MemoryBudget) queued() int {
+	n := 0
+	for _, queue := range b.waiters {
+		n += len(queue)
+	}
+	return n
+}
+
+// wake grants the waiters that fit, highest class first and in order
+// within a class. It stops at the first that does not fit, so a large
+// transcription is not starved by smaller ones behind it. b.mu must be held.
+func (b *MemoryBudget) wake() {
+	for rank, queue := range b.waiters {
+		for len(queue) > 0 {
+			w := queue[0]
+			if b.used+w.bytes > b.free() {
+				b.waiters[rank] = queue
+				return
+			}
+			b.used += w.bytes
+			close(w.ready)
+			queue = queue[1:]
+		}
+		b.waiters[rank] = queue
+	}
+}
+
+// Memory estimates. They are deliberately rough: what matters is that a
+// two-hour file or a window too large for the machine is caught before
+// its features are computed, not an exact count.
+const (
+	// sampleBytes is a 16 kHz float32 sample as decoded, plus the copy
+	// made by denoise or conditioning.
+	sampleBytes = 2 * 4
+	// featureFrameOverhead is the slice header of each mel frame.
+	featureFrameOverhead = 24
+)
+
+// encoderBytes estimates the activations of a FastConformer encoder run
+// over frames encoder frames: the self-attention scores of one layer
+// (8 heads of frames x frames float32) and some sixteen live 1024-wide
+// hidden buffers per frame. A 300 s window (3750 frames) comes to ~700 MB.
+func encoderBytes(frames int64) int64 {
+	return 32*frames*frames + 64<<10*frames
+}
+
+// windowBytes estimates the memory of encoding a window of melFrames.
+func (t *Transcriber) windowBytes(melFrames int64) int64 {
+	return encoderBytes(melToEncoderFrame(melFrames, int64(t.config.SubsamplingFactor)))
+}
+
+// memoryEstimate estimates the memory transcribing planes takes: every
+// plane's samples and features, which are held until the end, and the
+// largest encoder window, as planes and windows are encoded one at a time.
+func (t *Transcriber) memoryEstimate(planes [][]float32) int64 {
+	hop := int64(t.mel.HopLength())
+	featureBytes := int64(t.config.FeaturesSize)*4 + featureFrameOverhead
+	var bytes, longest int64
+	for _, plane := range planes {
+		samples := int64(len(plane))
+		frames := samples / hop
+		bytes += samples*sampleBytes + frames*featureBytes
+		longest = max(longest, frames)
+	}
+	window := longest
+	if t.longAudio {
+		window = min(window, t.chunkFrames)
+	}
+	// Audio too long for a single pass fails with ErrAudioTooLong instead.
+	window = min(window, modelMaxEncoderFrames*int64(t.config.SubsamplingFactor))
+	return bytes + t.windowBytes(window)
+}
+
+// fitChunkToBudget shrinks the long-audio window, when it has to, so that
+// each of workers concurrent transcriptions can encode one with at most
+// half of its share of the budget, leaving the rest for the audio. It
+// fails when not even a window just over the overlap fits.
+func (t *Transcriber) fitChunkToBudget(budget *MemoryBudget, workers int) error {
+	if !t.longAudio {
+		return nil
+	}
+	budget.mu.Lock()
+	share := budget.free() / int64(workers) / 2
+	budget.mu.Unlock()
+	fps := int64(t.mel.FramesPerSecond())
+	configured := t.chunkFrames
+	for t.windowBytes(t.chunkFrames) > share {
+		t.chunkFrames -= fps
+		if t.chunkFrames <= t.overlapFrames {
+			return fmt.Errorf("memory budget too small for %d workers: a %d s window needs %d MB per worker", workers, (t.overlapFrames/fps)+1, t.windowBytes(t.overlapFrames+fps)>>20)
+		}
+	}
+	if t.chunkFrames < configured {
+		slog.Warn("chunk window shrunk to fit the memory budget",
+			"chunkSeconds", t.chunkFrames/fps,
+			"configuredSeconds", configured/fps,
+			"workers", workers)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitMemoryQueued waits until n transcriptions wait for memory.
+func waitMemoryQueued(t *testing.T, b *MemoryBudget, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Status().Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("waiting = %d, want %d", b.Status().Waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMemoryBudgetReserve(t *testing.T) {
+	b := NewMemoryBudget(100)
+	if err := b.holdWeights(40); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.holdWeights(70); err == nil {
+		t.Error("weights over the budget were held")
+	}
+	if _, err := b.reserve(context.Background(), PriorityNormal, 61); !errors.Is(err, ErrOverMemoryBudget) {
+		t.Errorf("reserve past the budget: %v, want ErrOverMemoryBudget", err)
+	}
+
+	release, err := b.reserve(context.Background(), PriorityNormal, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan Priority, 2)
+	for i, p := range []Priority{PriorityBatch, PriorityInteractive} {
+		go func() {
+			if r, err := b.reserve(context.Background(), p, 40); err == nil {
+				got <- p
+				r()
+			}
+		}()
+		waitMemoryQueued(t, b, i+1)
+	}
+
+	// A small reservation queues behind the waiters rather than jump them.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := b.reserve(ctx, PriorityBatch, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("small reservation behind waiters: %v", err)
+	}
+
+	release()
+	if first := <-got; first != PriorityInteractive {
+		t.Errorf("first granted %q, want interactive", first)
+	}
+	<-got
+	waitMemoryQueued(t, b, 0)
+	if st := b.Status(); st.Reserved != 0 || st.Weights != 40 {
+		t.Errorf("status after release = %+v", st)
+	}
+	b.releaseWeights(40)
+	if _, err := b.reserve(context.Background(), PriorityNormal, 61); err != nil {
+		t.Errorf("reserve after the weights were released: %v", err)
+	}
+}
+
+func TestMemoryEstimate(t *testing.T) {
+	tr := &Transcriber{
+		config: Config{SubsamplingFactor: 8, FeaturesSize: 128},
+		mel:    NewMelFilterbank(128, 16000, DefaultMelOptions()),
+	}
+	fps := int64(tr.mel.FramesPerSecond())
+	tr.chunkFrames, tr.overlapFrames = 300*fps, 15*fps
+
+	hour := [][]float32{make([]float32, 16000*3600)}
+	single := tr.memoryEstimate(hour)
+	tr.longAudio = true
+	chunked := tr.memoryEstimate(hour)
+	if chunked >= single {
+		t.Errorf("chunked estimate %d MB not below the single pass %d MB", chunked>>20, single>>20)
+	}
+	// An hour of samples and features is ~650 MB, the 300 s window ~700 MB.
+	if chunked < 1<<30 || chunked > 2<<30 {
+		t.Errorf("estimate for an hour = %d MB", chunked>>20)
+	}
+
+	// Two workers in 1 GiB leave 256 MiB for each window: 300 s needs ~700.
+	if err := tr.fitChunkToBudget(NewMemoryBudget(1<<30), 2); err != nil {
+		t.Fatal(err)
+	}
+	if tr.chunkFrames >= 300*fps || tr.windowBytes(tr.chunkFrames) > 256<<20 {
+		t.Errorf("chunk not shrunk to fit: %d s, %d MB", tr.chunkFrames/fps, tr.windowBytes(tr.chunkFrames)>>20)
+	}
+	if err := tr.fitChunkToBudget(NewMemoryBudget(16<<20), 4); err == nil {
+		t.Error("a budget too small for any window was accepted")
+	}
+}
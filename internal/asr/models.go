@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// modelStore is where NewTranscriber finds the model files: a directory, or
+// an fs.FS (Options.Models: a bundled archive, go:embed) whose ONNX files are
+// handed to ONNX Runtime from memory.
+type modelStore struct {
+	dir  string
+	fsys fs.FS
+}
+
+// path is how a model file is named in logs and Info: its path on disk, or
+// its name in the fs.FS.
+func (s modelStore) path(name string) string {
+	if s.fsys != nil {
+		return name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+func (s modelStore) exists(name string) bool {
+	if s.fsys != nil {
+		_, err := fs.Stat(s.fsys, name)
+		return err == nil
+	}
+	_, err := os.Stat(s.path(name))
+	return err == nil
+}
+
+func (s modelStore) readFile(name string) ([]byte, error) {
+	if s.fsys != nil {
+		return fs.ReadFile(s.fsys, name)
+	}
+	return os.ReadFile(s.path(name))
+}
+
+// stat describes a model file.
+func (s modelStore) stat(name string) (fs.FileInfo, error) {
+	if s.fsys != nil {
+		return fs.Stat(s.fsys, name)
+	}
+	return os.Stat(s.path(name))
+}
+
+// open opens a model file by the name path returned.
+func (s modelStore) open(name string) (io.ReadCloser, error) {
+	if s.fsys != nil {
+		return s.fsys.Open(name)
+	}
+	return os.Open(name)
+}
+
+// model returns the ONNX model name; os.ErrNotExist when it is missing.
+func (s modelStore) model(name string) (onnxModel, error) {
+	if s.fsys == nil {
+		return diskModel(s.path(name))
+	}
+	data, err := fs.ReadFile(s.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return onnxModel{}, os.ErrNotExist
+	}
+	if err != nil {
+		return onnxModel{}, err
+	}
+	return onnxModel{path: name, data: data}, nil
+}
+
+// onnxModel is what ORT sessions are created from: a file on disk, or the
+// bytes of one read from an fs.FS. ORT copies the bytes into each session.
+type onnxModel struct {
+	path string
+	data []byte // nil: load from path
+}
+
+// diskModel returns the model at path; os.ErrNotExist when it is missing.
+func diskModel(path string) (onnxModel, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return onnxModel{}, os.ErrNotExist
+	}
+	return onnxModel{path: path}, nil
+}
+
+func (m onnxModel) newAdvancedSession(inputNames, outputNames []string, inputs, outputs []ort.Value, opts *ort.SessionOptions) (*ort.AdvancedSession, error) {
+	if m.data != nil {
+		return ort.NewAdvancedSessionWithONNXData(m.data, inputNames, outputNames, inputs, outputs, opts)
+	}
+	return ort.NewAdvancedSession(m.path, inputNames, outputNames, inputs, outputs, opts)
+}
+
+func (m onnxModel) newDynamicSession(inputNames, outputNames []string, opts *ort.SessionOptions) (*ort.DynamicAdvancedSession, error) {
+	if m.data != nil {
+		return ort.NewDynamicAdvancedSessionWithONNXData(m.data, inputNames, outputNames, opts)
+	}
+	return ort.NewDynamicAdvancedSession(m.path, inputNames, outputNames, opts)
+}
+
+func (m onnxModel) inputOutputInfo(opts *ort.SessionOptions) ([]ort.InputOutputInfo, []ort.InputOutputInfo, error) {
+	if m.data != nil {
+		return ort.GetInputOutputInfoWithONNXData(m.data)
+	}
+	return ort.GetInputOutputInfoWithOptions(m.path, opts)
+}
+
+// ModelArchive is a bundle of model files opened by OpenModelArchive, for
+// Options.Models. Keep it open while the Transcriber runs: /version reads
+// the files again to checksum them.
+type ModelArchive interface {
+	fs.FS
+	io.Closer
+}
+
+// OpenModelArchive opens a .zip or .tar of the models directory. The files
+// may sit at the archive root or in one top-level directory. Archives are
+// not compressed as a whole (.tar.gz): model weights barely compress, and an
+// uncompressed archive is read in place instead of into memory.
+func OpenModelArchive(name string) (ModelArchive, error) {
+	var (
+		archive ModelArchive
+		names   []string
+		err     error
+	)
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".zip":
+		var zr *zip.ReadCloser
+		if zr, err = zip.OpenReader(name); err == nil {
+			archive = zr
+			for _, f := range zr.File {
+				names = append(names, f.Name)
+			}
+		}
+	case ".tar":
+		var tr *tarFS
+		if tr, err = openTarFS(name); err == nil {
+			archive = tr
+			for n := range tr.files {
+				names = append(names, n)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported model archive %q: use .zip or .tar", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open model archive: %w", err)
+	}
+
+	root := ""
+	found := false
+	for _, n := range names {
+		if path.Base(n) == "config.json" && strings.Count(n, "/") <= 1 {
+			root, found = path.Dir(n), true
+			if root == "." {
+				break
+			}
+		}
+	}
+	if !found {
+		archive.Close()
+		return nil, fmt.Errorf("model archive %q has no config.json at its root or in a top-level directory", name)
+	}
+	if root == "." {
+		return archive, nil
+	}
+	sub, err := fs.Sub(archive, root)
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+	return subArchive{FS: sub, Closer: archive}, nil
+}
+
+type subArchive struct {
+	fs.FS
+	io.Closer
+}
+
+// tarFS serves the regular files of an uncompressed tar from their offsets
+// in it, without loading them.
+type tarFS struct {
+	f     *os.File
+	files map[string]tarEntry
+}
+
+type tarEntry struct {
+	offset, size int64
+	modTime      time.Time
+}
+
+func openTarFS(name string) (*tarFS, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	t := &tarFS{f: f, files: make(map[string]tarEntry)}
+	cr := &countingReader{r: f}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		n := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		t.files[n] = tarEntry{offset: cr.n, size: hdr.Size, modTime: hdr.ModTime}
+	}
+	return t, nil
+}
+
+// countingReader tracks the offset in the tar file. It is an io.Seeker so
+// tar.Reader skips entry data instead of reading it.
+type countingReader struct {
+	r io.ReadSeeker
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Seek(offset int64, whence int) (int64, error) {
+	n, err := c.r.Seek(offset, whence)
+	if err == nil {
+		c.n = n
+	}
+	return n, err
+}
+
+func (t *tarFS) Open(name string) (fs.File, error) {
+	e, ok := t.files[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &tarFile{SectionReader: io.NewSectionReader(t.f, e.offset, e.size), name: path.Base(name), entry: e}, nil
+}
+
+func (t *tarFS) Close() error {
+	return t.f.Close()
+}
+
+type tarFile struct {
+	*io.SectionReader
+	name  string
+	entry tarEntry
+}
+
+func (f *tarFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *tarFile) Close() error               { return nil }
+
+func (f *tarFile) Name() string       { return f.name }
+func (f *tarFile) Size() int64        { return f.entry.size }
+func (f *tarFile) Mode() fs.FileMode  { return 0o444 }
+func (f *tarFile) ModTime() time.Time { return f.entry.modTime }
+func (f *tarFile) IsDir() bool        { return false }
+func (f *tarFile) Sys() any           { return nil }
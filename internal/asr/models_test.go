@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+var archiveFiles = map[string]string{
+	"config.json":             `{"model_type":"nemo-conformer-tdt"}`,
+	"vocab.txt":               "<blk> 0\n",
+	"encoder-model.int8.onnx": "encoder bytes",
+}
+
+func writeZip(t *testing.T, path, prefix string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range archiveFiles {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: prefix + name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, body)
+	}
+	zw.Close()
+	f.Close()
+}
+
+func writeTar(t *testing.T, path, prefix string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if prefix != "" {
+		tw.WriteHeader(&tar.Header{Name: prefix, Typeflag: tar.TypeDir, Mode: 0o755})
+	}
+	for name, body := range archiveFiles {
+		tw.WriteHeader(&tar.Header{Name: prefix + name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(body))})
+		io.WriteString(tw, body)
+	}
+	tw.Close()
+	f.Close()
+}
+
+func TestOpenModelArchive(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name   string
+		write  func(*testing.T, string, string)
+		prefix string
+	}{
+		{"models.zip", writeZip, ""},
+		{"nested.zip", writeZip, "parakeet-tdt/"},
+		{"models.tar", writeTar, ""},
+		{"nested.tar", writeTar, "./parakeet-tdt/"},
+	} {
+		path := filepath.Join(dir, tc.name)
+		tc.write(t, path, tc.prefix)
+		archive, err := OpenModelArchive(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for name, body := range archiveFiles {
+			got, err := fs.ReadFile(archive, name)
+			if err != nil || string(got) != body {
+				t.Errorf("%s: %s = %q, %v", tc.name, name, got, err)
+			}
+		}
+		if _, err := fs.Stat(archive, "silero_vad.onnx"); !os.IsNotExist(err) {
+			t.Errorf("%s: missing file: %v", tc.name, err)
+		}
+		archive.Close()
+	}
+
+	if _, err := OpenModelArchive(filepath.Join(dir, "models.tar.gz")); err == nil {
+		t.Error("want error for a .tar.gz")
+	}
+	empty := filepath.Join(dir, "empty.zip")
+	zf, _ := os.Create(empty)
+	zip.NewWriter(zf).Close()
+	zf.Close()
+	if _, err := OpenModelArchive(empty); err == nil {
+		t.Error("want error for an archive without config.json")
+	}
+}
+
+func TestModelStore(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0o644)
+	os.WriteFile(filepath.Join(dir, "encoder-model.onnx"), []byte("disk"), 0o644)
+	mem := fstest.MapFS{
+		"config.json":        {Data: []byte("{}")},
+		"encoder-model.onnx": {Data: []byte("memory")},
+	}
+
+	disk := modelStore{dir: dir}
+	m, err := disk.model("encoder-model.onnx")
+	if err != nil || m.data != nil || m.path != filepath.Join(dir, "encoder-model.onnx") {
+		t.Fatalf("disk model = %+v, %v", m, err)
+	}
+	inMemory := modelStore{dir: dir, fsys: mem}
+	m, err = inMemory.model("encoder-model.onnx")
+	if err != nil || string(m.data) != "memory" || m.path != "encoder-model.onnx" {
+		t.Fatalf("fs model = %+v, %v", m, err)
+	}
+
+	for _, s := range []modelStore{disk, inMemory} {
+		if !s.exists("config.json") || s.exists("denoise.onnx") {
+			t.Errorf("exists wrong for %+v", s)
+		}
+		if _, err := s.model("denoise.onnx"); !os.IsNotExist(err) {
+			t.Errorf("missing model: %v", err)
+		}
+		f, err := s.open(s.path("config.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(f); string(b) != "{}" {
+			t.Errorf("open read %q", b)
+		}
+		f.Close()
+	}
+}
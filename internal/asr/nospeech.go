@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import "strings"
+
+// noSpeechWindowFrames is the stretch of encoder frames (480 ms) whose
+// speech evidence a segment's no-speech probability is judged on: a segment
+// with half a second of clear speech is speech, however long its pauses.
+const noSpeechWindowFrames = 6
+
+// unsetBlank marks frames the greedy decoder never scored.
+const unsetBlank = 2
+
+// blankFrames is the blank probability of each encoder frame of the
+// waveform being decoded, indexed by absolute frame: the lowest the greedy
+// decoder saw at that frame, spread over the frames a predicted duration
+// skipped. High values mean the model heard nothing to transcribe.
+type blankFrames []float32
+
+// note records blank probability p for the span frames from frame on.
+func (b *blankFrames) note(frame, span int64, p float32) {
+	for end := frame + max(span, 1); int64(len(*b)) < end; {
+		*b = append(*b, unsetBlank)
+	}
+	for f := frame; f < frame+max(span, 1); f++ {
+		(*b)[f] = min((*b)[f], p)
+	}
+}
+
+// noSpeechProb is the no-speech probability of frames [from, to): the mean
+// blank probability over its noSpeechWindowFrames-long stretch with the
+// most speech in it. Frames that were never scored (beam search, or beyond
+// a truncated decode) are left out; with none scored it is 0, as unknown.
+func (b blankFrames) noSpeechProb(from, to int) float64 {
+	var scored []float64
+	for f := max(from, 0); f < min(to, len(b)); f++ {
+		if b[f] != unsetBlank {
+			scored = append(scored, float64(b[f]))
+		}
+	}
+	if len(scored) == 0 {
+		return 0
+	}
+	n := min(noSpeechWindowFrames, len(scored))
+	var sum float64
+	for _, p := range scored[:n] {
+		sum += p
+	}
+	best := sum
+	for i := n; i < len(scored); i++ {
+		sum += scored[i] - scored[i-n]
+		best = min(best, sum)
+	}
+	return best / float64(n)
+}
+
+// scoreSegments sets the NoSpeechProb of segments from the blank
+// probabilities of the plane they were decoded from; offset is the seconds
+// trimmed off the plane's start.
+func (t *Transcriber) scoreSegments(segments []Segment, blank blankFrames, offset float64) {
+	frameSec := t.encoderFrameSeconds()
+	for i, seg := range segments {
+		from := int((seg.Start - offset) / frameSec)
+		to := int((seg.End-offset)/frameSec + 0.5)
+		segments[i].NoSpeechProb = blank.noSpeechProb(from, to)
+	}
+}
+
+// dropNoSpeech removes the segments whose NoSpeechProb is above threshold,
+// with their words and tokens, and rebuilds the text from the rest. It is
+// how hallucinated text on silent or noise-only audio is suppressed.
+func dropNoSpeech(res *Result, threshold float64) {
+	kept := res.Segments[:0]
+	var dropped []Segment
+	for _, seg := range res.Segments {
+		if seg.NoSpeechProb > threshold {
+			dropped = append(dropped, seg)
+			continue
+		}
+		kept = append(kept, seg)
+	}
+	if len(dropped) == 0 {
+		return
+	}
+	res.Segments = kept
+	res.Words = filterSpans(res.Words, dropped, func(w Word) (int, float64) { return w.Channel, w.Start })
+	res.Tokens = filterSpans(res.Tokens, dropped, func(t Token) (int, float64) { return t.Channel, t.Start })
+	rebuildText(res)
+}
+
+// rebuildText sets the text of res from its segments after some were
+// removed or edited: one labelled line per segment for per-channel results,
+// the segments joined by spaces otherwise.
+func rebuildText(res *Result) {
+	if res.Channels > 1 {
+		res.Segments, res.Text = mergeChannelSegments(res.Segments)
+		return
+	}
+	texts := make([]string, 0, len(res.Segments))
+	for _, seg := range res.Segments {
+		if seg.Text != "" {
+			texts = append(texts, seg.Text)
+		}
+	}
+	res.Text = strings.Join(texts, " ")
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBlankFrames(t *testing.T) {
+	var b blankFrames
+	b.note(2, 3, 0.9) // a blank step skipping frames 2-4
+	b.note(3, 0, 0.1) // a token at frame 3
+	want := blankFrames{unsetBlank, unsetBlank, 0.9, 0.1, 0.9}
+	if len(b) != len(want) {
+		t.Fatalf("frames = %v", b)
+	}
+	for i := range want {
+		if b[i] != want[i] {
+			t.Fatalf("frames = %v, want %v", b, want)
+		}
+	}
+}
+
+func TestNoSpeechProb(t *testing.T) {
+	// Silence, then six frames of speech, then silence.
+	var b blankFrames
+	b.note(0, 20, 0.95)
+	b.note(8, 6, 0.05)
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-6 }
+	if p := b.noSpeechProb(0, 20); !near(p, 0.05) {
+		t.Errorf("segment with speech = %g, want 0.05", p)
+	}
+	if p := b.noSpeechProb(0, 8); !near(p, 0.95) {
+		t.Errorf("silent segment = %g, want 0.95", p)
+	}
+	// Shorter than a window: the mean of what there is.
+	if p := b.noSpeechProb(6, 9); !near(p, (0.95+0.95+0.05)/3) {
+		t.Errorf("short segment = %g", p)
+	}
+	if p := b.noSpeechProb(30, 40); p != 0 {
+		t.Errorf("unscored segment = %g, want 0", p)
+	}
+}
+
+func TestDropNoSpeech(t *testing.T) {
+	res := &Result{
+		Text:     "Thank you. Hello there.",
+		Channels: 1,
+		Segments: []Segment{
+			{Start: 0, End: 1, Text: "Thank you.", NoSpeechProb: 0.9},
+			{Start: 2, End: 3, Text: "Hello there.", NoSpeechProb: 0.1},
+		},
+		Words:  []Word{{Start: 0.1, End: 0.5, Text: "Thank"}, {Start: 0.5, End: 0.9, Text: "you."}, {Start: 2, End: 2.4, Text: "Hello"}, {Start: 2.5, End: 3, Text: "there."}},
+		Tokens: []Token{{Start: 0.1, Text: " Thank"}, {Start: 2, Text: " Hello"}},
+	}
+	dropNoSpeech(res, 0.6)
+	if res.Text != "Hello there." || len(res.Segments) != 1 || len(res.Words) != 2 || res.Words[0].Text != "Hello" || len(res.Tokens) != 1 {
+		t.Errorf("after drop: %+v", res)
+	}
+
+	res = &Result{
+		Channels: 2,
+		Segments: []Segment{
+			{Channel: 0, Start: 0, End: 1, Text: "Yes.", NoSpeechProb: 0.2},
+			{Channel: 1, Start: 0.5, End: 1, Text: "Hmm.", NoSpeechProb: 0.8},
+		},
+	}
+	dropNoSpeech(res, 0.6)
+	if res.Text != "[channel 0] Yes." || len(res.Segments) != 1 {
+		t.Errorf("per-channel after drop: %+v", res)
+	}
+}
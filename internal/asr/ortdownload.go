@@ -0,0 +1,323 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ONNXRuntimeVersion is the ONNX Runtime release the bindings are built for
+// (onnxruntime_go v1.30.1 declares ORT_API_VERSION 25) and the one
+// downloaded by RuntimeConfig.Download.
+const ONNXRuntimeVersion = "1.25.1"
+
+// defaultORTDownloadURL serves the release archives as v<version>/<archive>.
+const defaultORTDownloadURL = "https://github.com/microsoft/onnxruntime/releases/download"
+
+// ortDownloadTimeout bounds one release download; the GPU archives are a few
+// hundred MB.
+const ortDownloadTimeout = 15 * time.Minute
+
+// RuntimeConfig makes NewTranscriber download ONNX Runtime when no library
+// is installed (findONNXRuntime found nothing). Off by default.
+type RuntimeConfig struct {
+	Download bool
+	// CacheDir keeps downloaded releases; empty means onnxruntime under
+	// the user cache directory (e.g. ~/.cache/parakeet/onnxruntime).
+	CacheDir string
+	// BaseURL is a mirror of the GitHub release downloads; empty means
+	// GitHub. It must be https unless AllowHTTP is set.
+	BaseURL string
+	// AllowHTTP accepts a plain-http BaseURL, for a mirror on a trusted
+	// network. The archive digest is checked either way.
+	AllowHTTP bool
+	// SHA256 is the expected digest of the release archive, for archives
+	// ortArchiveSHA256 does not pin; it takes precedence over the pin.
+	SHA256 string
+}
+
+// ortArchiveSHA256 pins the SHA-256 of each ONNXRuntimeVersion release
+// archive by file name. The archive is checked before anything in it is
+// unpacked, let alone loaded, and one without a digest here or in
+// RuntimeConfig.SHA256 is refused. Bump it with ONNXRuntimeVersion;
+// `make onnxruntime-sha256` prints the lines.
+var ortArchiveSHA256 = map[string]string{}
+
+// Validate rejects a BaseURL that is not https (http with AllowHTTP) and a
+// SHA256 that is not 64 hex digits.
+func (c RuntimeConfig) Validate() error {
+	if c.BaseURL != "" {
+		u, err := url.Parse(c.BaseURL)
+		switch {
+		case err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http"):
+			return fmt.Errorf("ONNX Runtime download URL %q is not an https URL", c.BaseURL)
+		case u.Scheme == "http" && !c.AllowHTTP:
+			return fmt.Errorf("ONNX Runtime download URL %q is not https (see -onnxruntime-allow-http)", c.BaseURL)
+		}
+	}
+	if c.SHA256 != "" {
+		if b, err := hex.DecodeString(c.SHA256); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("ONNX Runtime SHA-256 %q is not 64 hex digits", c.SHA256)
+		}
+	}
+	return nil
+}
+
+// ortPackage returns the release archive for goos/goarch, the GPU (CUDA)
+// build when gpu is set.
+func ortPackage(goos, goarch string, gpu bool) (string, error) {
+	platforms := map[string]string{
+		"linux/amd64":   "linux-x64",
+		"linux/arm64":   "linux-aarch64",
+		"darwin/amd64":  "osx-x86_64",
+		"darwin/arm64":  "osx-arm64",
+		"windows/amd64": "win-x64",
+		"windows/arm64": "win-arm64",
+	}
+	platform, ok := platforms[goos+"/"+goarch]
+	if !ok {
+		return "", fmt.Errorf("no ONNX Runtime release for %s/%s; install it and set ONNXRUNTIME_LIB", goos, goarch)
+	}
+	if gpu {
+		if platform != "linux-x64" && platform != "win-x64" {
+			return "", fmt.Errorf("no ONNX Runtime GPU release for %s/%s", goos, goarch)
+		}
+		platform += "-gpu"
+	}
+	ext := ".tgz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return "onnxruntime-" + platform + "-" + ONNXRuntimeVersion + ext, nil
+}
+
+// isORTLibrary reports whether a file name in a release's lib/ directory is
+// the main library for goos, not a provider plugin or a symlink alias.
+func isORTLibrary(goos, name string) bool {
+	switch goos {
+	case "windows":
+		return name == "onnxruntime.dll"
+	case "darwin":
+		return strings.HasPrefix(name, "libonnxruntime.") && strings.HasSuffix(name, ".dylib")
+	default:
+		return strings.HasPrefix(name, "libonnxruntime.so")
+	}
+}
+
+// downloadONNXRuntime returns the ONNX Runtime library from the cache,
+// downloading and unpacking the release's lib/ directory into it first when
+// it is not there yet.
+func downloadONNXRuntime(cfg RuntimeConfig, goos, goarch string, gpu bool) (string, error) {
+	pkg, err := ortPackage(goos, goarch, gpu)
+	if err != nil {
+		return "", err
+	}
+	cacheDir := cfg.CacheDir
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("no cache directory for the ONNX Runtime download: %w", err)
+		}
+		cacheDir = filepath.Join(userCache, "parakeet", "onnxruntime")
+	}
+	dir := filepath.Join(cacheDir, strings.TrimSuffix(strings.TrimSuffix(pkg, ".tgz"), ".zip"))
+	if lib, ok := findORTLibrary(goos, dir); ok {
+		return lib, nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return "", err
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultORTDownloadURL
+	}
+	want := cfg.SHA256
+	if want == "" {
+		want = ortArchiveSHA256[pkg]
+	}
+	if want == "" {
+		return "", fmt.Errorf("no pinned SHA-256 for %s; set -onnxruntime-sha256, or install ONNX Runtime and set ONNXRUNTIME_LIB", pkg)
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/v" + ONNXRuntimeVersion + "/" + pkg
+	slog.Info("downloading ONNX Runtime", "url", url, "dir", dir)
+
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", fmt.Errorf("create ONNX Runtime cache: %w", err)
+	}
+	archive, err := os.CreateTemp(cacheDir, pkg+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	if err := fetchTo(archive, url, want); err != nil {
+		return "", fmt.Errorf("download ONNX Runtime: %w", err)
+	}
+
+	// Unpack next to the final directory and rename, so a crash never
+	// leaves a half-written release that later starts would trust.
+	tmp, err := os.MkdirTemp(cacheDir, ".unpack-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if strings.HasSuffix(pkg, ".zip") {
+		err = unpackORTZip(archive, tmp)
+	} else {
+		err = unpackORTTar(archive, tmp)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unpack %s: %w", pkg, err)
+	}
+	if _, ok := findORTLibrary(goos, tmp); !ok {
+		return "", fmt.Errorf("%s has no ONNX Runtime library in lib/", pkg)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		// Another process may have unpacked the same release meanwhile.
+		if _, ok := findORTLibrary(goos, dir); !ok {
+			return "", err
+		}
+	}
+	lib, _ := findORTLibrary(goos, dir)
+	slog.Info("ONNX Runtime downloaded", "library", lib)
+	return lib, nil
+}
+
+// findORTLibrary returns the main library in an unpacked release directory.
+func findORTLibrary(goos, dir string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, e := range entries {
+		if e.Type().IsRegular() && isORTLibrary(goos, e.Name()) {
+			return filepath.Join(dir, e.Name()), true
+		}
+	}
+	return "", false
+}
+
+// fetchTo downloads url into f and checks it against the hex SHA-256 want,
+// leaving f at its start for unpacking.
+func fetchTo(f *os.File, url, want string) error {
+	client := &http.Client{Timeout: ortDownloadTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+		return fmt.Errorf("%s has SHA-256 %s, want %s", url, got, want)
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// libFile returns the base name of an archive entry inside a lib/
+// directory, "" for anything else.
+func libFile(name string) string {
+	name = path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.Base(path.Dir(name)) != "lib" {
+		return ""
+	}
+	return path.Base(name)
+}
+
+func unpackORTTar(f *os.File, dir string) error {
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := libFile(hdr.Name)
+		if name == "" {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if err := writeFile(filepath.Join(dir, name), tr, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// libonnxruntime.so -> libonnxruntime.so.1 -> ...: keep the
+			// aliases for libraries that are linked against them.
+			if strings.Contains(hdr.Linkname, "/") {
+				continue
+			}
+			if err := os.Symlink(hdr.Linkname, filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unpackORTZip(f *os.File, dir string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		name := libFile(zf.Name)
+		if name == "" || zf.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(filepath.Join(dir, name), rc, 0o755)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(name string, r io.Reader, mode os.FileMode) error {
+	out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
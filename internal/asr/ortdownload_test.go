@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestORTPackage(t *testing.T) {
+	for _, tc := range []struct {
+		goos, goarch string
+		gpu          bool
+		want         string
+	}{
+		{"linux", "amd64", false, "onnxruntime-linux-x64-" + ONNXRuntimeVersion + ".tgz"},
+		{"linux", "amd64", true, "onnxruntime-linux-x64-gpu-" + ONNXRuntimeVersion + ".tgz"},
+		{"linux", "arm64", false, "onnxruntime-linux-aarch64-" + ONNXRuntimeVersion + ".tgz"},
+		{"darwin", "arm64", false, "onnxruntime-osx-arm64-" + ONNXRuntimeVersion + ".tgz"},
+		{"windows", "amd64", false, "onnxruntime-win-x64-" + ONNXRuntimeVersion + ".zip"},
+	} {
+		if got, err := ortPackage(tc.goos, tc.goarch, tc.gpu); err != nil || got != tc.want {
+			t.Errorf("ortPackage(%s, %s, %v) = %q, %v", tc.goos, tc.goarch, tc.gpu, got, err)
+		}
+	}
+	for _, bad := range [][2]string{{"linux", "riscv64"}, {"darwin", "arm64"}} {
+		if _, err := ortPackage(bad[0], bad[1], bad[0] == "darwin"); err == nil {
+			t.Errorf("%v: want error", bad)
+		}
+	}
+}
+
+func linuxRelease(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	root := "onnxruntime-linux-x64-" + ONNXRuntimeVersion + "/"
+	for name, body := range map[string]string{
+		root + "lib/libonnxruntime.so." + ONNXRuntimeVersion: "ELF",
+		root + "include/onnxruntime_c_api.h":                 "header",
+	} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(body))})
+		tw.Write([]byte(body))
+	}
+	tw.WriteHeader(&tar.Header{Name: root + "lib/libonnxruntime.so", Typeflag: tar.TypeSymlink, Linkname: "libonnxruntime.so." + ONNXRuntimeVersion})
+	tw.Close()
+	zw.Close()
+	return buf.Bytes()
+}
+
+func windowsRelease(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"lib/onnxruntime.dll", "lib/onnxruntime_providers_shared.dll", "include/onnxruntime_c_api.h"} {
+		w, _ := zw.Create("onnxruntime-win-x64-" + ONNXRuntimeVersion + "/" + name)
+		w.Write([]byte("MZ"))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDownloadONNXRuntime(t *testing.T) {
+	releases := map[string][]byte{
+		"/v" + ONNXRuntimeVersion + "/onnxruntime-linux-x64-" + ONNXRuntimeVersion + ".tgz":     linuxRelease(t),
+		"/v" + ONNXRuntimeVersion + "/onnxruntime-win-x64-" + ONNXRuntimeVersion + ".zip":       windowsRelease(t),
+		"/v" + ONNXRuntimeVersion + "/onnxruntime-linux-aarch64-" + ONNXRuntimeVersion + ".tgz": []byte("tampered"),
+	}
+	pinned := ortArchiveSHA256
+	ortArchiveSHA256 = map[string]string{}
+	defer func() { ortArchiveSHA256 = pinned }()
+	for name, body := range releases {
+		sum := sha256.Sum256(body)
+		ortArchiveSHA256[filepath.Base(name)] = hex.EncodeToString(sum[:])
+	}
+	// The tampered archive is served in place of the pinned release.
+	ortArchiveSHA256["onnxruntime-linux-aarch64-"+ONNXRuntimeVersion+".tgz"] = ortArchiveSHA256["onnxruntime-linux-x64-"+ONNXRuntimeVersion+".tgz"]
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, ok := releases[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+	cfg := RuntimeConfig{Download: true, CacheDir: t.TempDir(), BaseURL: srv.URL, AllowHTTP: true}
+
+	lib, err := downloadONNXRuntime(cfg, "linux", "amd64", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(lib) != "libonnxruntime.so."+ONNXRuntimeVersion {
+		t.Fatalf("library = %s", lib)
+	}
+	if target, err := os.Readlink(filepath.Join(filepath.Dir(lib), "libonnxruntime.so")); err != nil || target != filepath.Base(lib) {
+		t.Errorf("symlink = %q, %v", target, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(lib), "onnxruntime_c_api.h")); !os.IsNotExist(err) {
+		t.Errorf("headers unpacked: %v", err)
+	}
+
+	// Cached: no second download.
+	if again, err := downloadONNXRuntime(cfg, "linux", "amd64", false); err != nil || again != lib || hits != 1 {
+		t.Fatalf("cached lookup = %q, %v after %d downloads", again, err, hits)
+	}
+
+	dll, err := downloadONNXRuntime(cfg, "windows", "amd64", false)
+	if err != nil || filepath.Base(dll) != "onnxruntime.dll" {
+		t.Fatalf("windows library = %q, %v", dll, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dll), "onnxruntime_providers_shared.dll")); err != nil {
+		t.Errorf("provider library not unpacked: %v", err)
+	}
+
+	// A digest mismatch fails before anything is unpacked.
+	if _, err := downloadONNXRuntime(cfg, "linux", "arm64", false); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Errorf("tampered archive: %v", err)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(cfg.CacheDir, "onnxruntime-linux-aarch64-*")); len(entries) != 0 {
+		t.Errorf("tampered archive left %v", entries)
+	}
+	// An archive without a pin is refused unless its digest is given.
+	if _, err := downloadONNXRuntime(cfg, "darwin", "arm64", false); err == nil || !strings.Contains(err.Error(), "no pinned SHA-256") {
+		t.Errorf("unpinned archive: %v", err)
+	}
+	withSum := cfg
+	withSum.SHA256 = strings.Repeat("0", 64)
+	if _, err := downloadONNXRuntime(withSum, "darwin", "arm64", false); err == nil || strings.Contains(err.Error(), "no pinned SHA-256") {
+		t.Errorf("missing release with a digest: %v", err)
+	}
+	// Plain http needs the opt-in.
+	cfg.AllowHTTP = false
+	if _, err := downloadONNXRuntime(cfg, "linux", "arm64", false); err == nil || !strings.Contains(err.Error(), "not https") {
+		t.Errorf("http mirror without -onnxruntime-allow-http: %v", err)
+	}
+}
+
+func TestRuntimeConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		cfg RuntimeConfig
+		ok  bool
+	}{
+		{RuntimeConfig{}, true},
+		{RuntimeConfig{BaseURL: "https://mirror.internal/ort"}, true},
+		{RuntimeConfig{BaseURL: "http://mirror.internal/ort"}, false},
+		{RuntimeConfig{BaseURL: "http://mirror.internal/ort", AllowHTTP: true}, true},
+		{RuntimeConfig{BaseURL: "ftp://mirror.internal/ort", AllowHTTP: true}, false},
+		{RuntimeConfig{BaseURL: "mirror.internal/ort"}, false},
+		{RuntimeConfig{SHA256: strings.Repeat("ab", 32)}, true},
+		{RuntimeConfig{SHA256: "abc"}, false},
+	} {
+		if err := tc.cfg.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%+v: %v", tc.cfg, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ortLibraryNames are the ONNX Runtime shared library file names per OS.
+var ortLibraryNames = map[string]string{
+	"linux":   "libonnxruntime.so",
+	"darwin":  "libonnxruntime.dylib",
+	"windows": "onnxruntime.dll",
+}
+
+// ortLibraryCandidates returns where to look for the ONNX Runtime shared
+// library on goos, in order: next to the executable (exeDir, skipped when
+// empty), the working directory, then the usual install locations.
+func ortLibraryCandidates(goos, exeDir string) []string {
+	name, ok := ortLibraryNames[goos]
+	if !ok {
+		name = ortLibraryNames["linux"]
+	}
+	var paths []string
+	if exeDir != "" {
+		paths = append(paths, filepath.Join(exeDir, name))
+	}
+	switch goos {
+	case "darwin":
+		paths = append(paths,
+			"./"+name,
+			"/opt/homebrew/lib/"+name, // Homebrew on Apple silicon
+			"/opt/homebrew/opt/onnxruntime/lib/"+name,
+			"/usr/local/lib/"+name, // Homebrew on Intel, manual installs
+			"/usr/local/opt/onnxruntime/lib/"+name,
+			"/opt/onnxruntime/lib/"+name,
+			"/Library/Frameworks/onnxruntime.framework/onnxruntime",
+		)
+	case "windows":
+		// System32 is left out on purpose: Windows ships an older
+		// onnxruntime.dll there for Windows ML.
+		paths = append(paths, `.\`+name)
+		for _, env := range []string{"ProgramFiles", "LOCALAPPDATA"} {
+			if dir := os.Getenv(env); dir != "" {
+				paths = append(paths, filepath.Join(dir, "onnxruntime", "lib", name))
+			}
+		}
+	default:
+		paths = append(paths,
+			"/usr/lib/"+name,
+			"/usr/lib/x86_64-linux-gnu/"+name,
+			"/usr/lib/aarch64-linux-gnu/"+name,
+			"/usr/local/lib/"+name,
+			"/opt/onnxruntime/lib/"+name,
+			"./"+name,
+			name+"."+ONNXRuntimeVersion,
+		)
+	}
+	return paths
+}
+
+// findONNXRuntime returns the ONNX Runtime library to load: ONNXRUNTIME_LIB
+// when set, else the first existing ortLibraryCandidates entry. The error
+// lists every path tried.
+func findONNXRuntime() (string, error) {
+	if p := os.Getenv("ONNXRUNTIME_LIB"); p != "" {
+		if _, err := os.Stat(p); err != nil {
+			return "", fmt.Errorf("ONNXRUNTIME_LIB=%s: %w", p, err)
+		}
+		return p, nil
+	}
+	exeDir := ""
+	if exe, err := os.Executable(); err == nil {
+		exeDir = filepath.Dir(exe)
+	}
+	candidates := ortLibraryCandidates(runtime.GOOS, exeDir)
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("ONNX Runtime library not found; searched %s. Set the ONNXRUNTIME_LIB env var to the library path or install ONNX Runtime",
+		strings.Join(candidates, ", "))
+}
+
+// The ONNX Runtime environment is process-wide, but a model reload keeps two
+// Transcribers alive while the new one warms up. ortRefs counts the
+// Transcribers using it: the first initializes it, the last destroys it.
+var (
+	ortMu   sync.Mutex
+	ortRefs int
+)
+
+// acquireONNXRuntime initializes the environment from libPath unless another
+// Transcriber already did, in which case libPath is ignored.
+func acquireONNXRuntime(libPath string) error {
+	ortMu.Lock()
+	defer ortMu.Unlock()
+	if ortRefs == 0 {
+		ort.SetSharedLibraryPath(libPath)
+		if err := ort.InitializeEnvironment(); err != nil {
+			return err
+		}
+	}
+	ortRefs++
+	return nil
+}
+
+func releaseONNXRuntime() {
+	ortMu.Lock()
+	defer ortMu.Unlock()
+	if ortRefs--; ortRefs == 0 {
+		ort.DestroyEnvironment()
+	}
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestORTLibraryCandidates(t *testing.T) {
+	for goos, want := range map[string]string{
+		"linux":   "/usr/local/lib/libonnxruntime.so",
+		"darwin":  "/opt/homebrew/lib/libonnxruntime.dylib",
+		"windows": `.\onnxruntime.dll`,
+		"freebsd": "/usr/local/lib/libonnxruntime.so",
+	} {
+		paths := ortLibraryCandidates(goos, "/app")
+		if !strings.HasPrefix(paths[0], filepath.Join("/app", "")) {
+			t.Errorf("%s: first candidate %q, want the executable's directory", goos, paths[0])
+		}
+		found := false
+		for _, p := range paths {
+			found = found || p == want
+		}
+		if !found {
+			t.Errorf("%s: %q not in %v", goos, want, paths)
+		}
+	}
+	if paths := ortLibraryCandidates("linux", ""); strings.HasPrefix(paths[0], "/app") {
+		t.Errorf("empty exeDir added: %v", paths)
+	}
+}
+
+func TestFindONNXRuntime(t *testing.T) {
+	lib := filepath.Join(t.TempDir(), "libonnxruntime.so")
+	os.WriteFile(lib, nil, 0o644)
+	t.Setenv("ONNXRUNTIME_LIB", lib)
+	if got, err := findONNXRuntime(); err != nil || got != lib {
+		t.Fatalf("findONNXRuntime() = %q, %v", got, err)
+	}
+
+	t.Setenv("ONNXRUNTIME_LIB", lib+".missing")
+	if _, err := findONNXRuntime(); err == nil || !strings.Contains(err.Error(), "ONNXRUNTIME_LIB") {
+		t.Fatalf("missing ONNXRUNTIME_LIB: %v", err)
+	}
+}
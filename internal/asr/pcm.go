@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PCMEncoding is the sample encoding of headerless audio, as sent by live
+// streaming clients that cannot wrap every packet in a container.
+type PCMEncoding string
+
+const (
+	PCMLinear16 PCMEncoding = "linear16" // signed 16-bit little endian
+	PCMLinear32 PCMEncoding = "linear32" // signed 32-bit little endian
+	PCMFloat32  PCMEncoding = "float32"  // IEEE 754 32-bit little endian
+	PCMMuLaw    PCMEncoding = "mulaw"    // G.711 µ-law
+	PCMALaw     PCMEncoding = "alaw"     // G.711 A-law
+)
+
+// PCMFormat describes headerless interleaved audio.
+type PCMFormat struct {
+	Encoding   PCMEncoding
+	SampleRate int
+	Channels   int
+}
+
+// ParsePCMFormat validates a PCM description. channels defaults to 1 when
+// zero.
+func ParsePCMFormat(encoding string, sampleRate, channels int) (PCMFormat, error) {
+	f := PCMFormat{
+		Encoding:   PCMEncoding(strings.ToLower(strings.TrimSpace(encoding))),
+		SampleRate: sampleRate,
+		Channels:   channels,
+	}
+	if f.Channels == 0 {
+		f.Channels = 1
+	}
+	if _, _, err := f.wavFormat(); err != nil {
+		return PCMFormat{}, err
+	}
+	if f.SampleRate < 1000 || f.SampleRate > 384000 {
+		return PCMFormat{}, fmt.Errorf("unsupported sample rate %d", sampleRate)
+	}
+	if f.Channels < 1 || f.Channels > 32 {
+		return PCMFormat{}, fmt.Errorf("unsupported channel count %d", channels)
+	}
+	return f, nil
+}
+
+// wavFormat maps the encoding to its WAV format tag and bit depth.
+func (f PCMFormat) wavFormat() (tag, bits uint16, err error) {
+	switch f.Encoding {
+	case PCMLinear16:
+		return wavFormatPCM, 16, nil
+	case PCMLinear32:
+		return wavFormatPCM, 32, nil
+	case PCMFloat32:
+		return wavFormatIEEEFloat, 32, nil
+	case PCMMuLaw:
+		return wavFormatMuLaw, 8, nil
+	case PCMALaw:
+		return wavFormatALaw, 8, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported encoding %q (supported: linear16, linear32, float32, mulaw, alaw)", f.Encoding)
+	}
+}
+
+// FrameBytes is the size of one sample across all channels.
+func (f PCMFormat) FrameBytes() int {
+	_, bits, _ := f.wavFormat()
+	return int(bits) / 8 * f.Channels
+}
+
+// Seconds is the duration of n bytes of audio in this format.
+func (f PCMFormat) Seconds(n int) float64 {
+	if fb := f.FrameBytes(); fb > 0 && f.SampleRate > 0 {
+		return float64(n/fb) / float64(f.SampleRate)
+	}
+	return 0
+}
+
+// WAV wraps raw samples in a WAV container, so headerless audio goes
+// through the same decoding (G.711 expansion, downmix, resampling) as an
+// upload. A trailing partial frame is dropped.
+func (f PCMFormat) WAV(data []byte) []byte {
+	tag, bits, _ := f.wavFormat()
+	fb := f.FrameBytes()
+	if fb > 0 {
+		data = data[:len(data)/fb*fb]
+	}
+	var buf bytes.Buffer
+	buf.Grow(44 + len(data))
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(data)))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, tag)
+	binary.Write(&buf, binary.LittleEndian, uint16(f.Channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(f.SampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(f.SampleRate*fb))
+	binary.Write(&buf, binary.LittleEndian, uint16(fb))
+	binary.Write(&buf, binary.LittleEndian, bits)
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// LevelDBFS is the RMS level of the audio in dBFS, channels mixed; silence
+// (or no audio) reports -Inf. Live sessions use it to find pauses.
+func (f PCMFormat) LevelDBFS(data []byte) float64 {
+	tag, bits, err := f.wavFormat()
+	if err != nil {
+		return math.Inf(-1)
+	}
+	planes, err := convertToFloat32(data, tag, uint16(f.Channels), bits, ChannelMix)
+	if err != nil || len(planes[0]) == 0 {
+		return math.Inf(-1)
+	}
+	rms := frameRMS(planes[0])
+	if rms == 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(rms)
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestParsePCMFormat(t *testing.T) {
+	f, err := ParsePCMFormat(" Linear16 ", 8000, 0)
+	if err != nil || f.Encoding != PCMLinear16 || f.Channels != 1 {
+		t.Fatalf("ParsePCMFormat = %+v, %v", f, err)
+	}
+	for _, tc := range []struct {
+		enc   string
+		rate  int
+		chans int
+	}{
+		{"opus", 16000, 1},
+		{"linear16", 0, 1},
+		{"mulaw", 8000, 64},
+	} {
+		if _, err := ParsePCMFormat(tc.enc, tc.rate, tc.chans); err == nil {
+			t.Errorf("ParsePCMFormat(%q, %d, %d) succeeded, want error", tc.enc, tc.rate, tc.chans)
+		}
+	}
+}
+
+func TestPCMFormat_WAVRoundTrip(t *testing.T) {
+	f := PCMFormat{Encoding: PCMLinear16, SampleRate: 16000, Channels: 2}
+	// Two stereo frames plus a dangling byte that must be dropped.
+	raw := make([]byte, 9)
+	binary.LittleEndian.PutUint16(raw[0:], uint16(16384))
+	binary.LittleEndian.PutUint16(raw[2:], uint16(16384))
+
+	samples, err := parseWAV(f.WAV(raw), ResampleMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[0] != 0.5 || samples[1] != 0 {
+		t.Fatalf("samples = %v, want [0.5 0]", samples)
+	}
+	if got := f.Seconds(len(raw)); got != 2.0/16000 {
+		t.Errorf("Seconds = %v", got)
+	}
+}
+
+func TestPCMFormat_LevelDBFS(t *testing.T) {
+	f := PCMFormat{Encoding: PCMLinear16, SampleRate: 16000, Channels: 1}
+	raw := make([]byte, 3200)
+	if got := f.LevelDBFS(raw); !math.IsInf(got, -1) {
+		t.Errorf("silence level = %v, want -Inf", got)
+	}
+	for i := 0; i < len(raw); i += 2 {
+		binary.LittleEndian.PutUint16(raw[i:], uint16(int16(3277))) // ~0.1 full scale, DC
+	}
+	if got := f.LevelDBFS(raw); math.Abs(got+20) > 0.1 {
+		t.Errorf("level = %v dBFS, want about -20", got)
+	}
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"time"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// encodedWindow is one window's encoder output. The decoder reads out
+// directly from the output tensor's buffer, so it stays alive until release.
+type encodedWindow struct {
+	out     []float32
+	len     int64 // encoded frames
+	release func()
+}
+
+// encodeWindow runs the shared encoder session over one window's features,
+// or takes its output from the encoder cache.
+func (t *Transcriber) encodeWindow(ctx context.Context, features [][]float32) (*encodedWindow, error) {
+	start := time.Now()
+	var key [sha256.Size]byte
+	if t.encoderCache != nil {
+		key = featuresKey(features)
+		if enc, ok := t.encoderCache.get(key); ok {
+			ticketFrom(ctx).timings.Encoder += time.Since(start)
+			return enc, nil
+		}
+	}
+	batchSize := int64(1)
+	numFeatures := int64(t.config.FeaturesSize)
+	numFrames := int64(len(features))
+
+	// Flatten features: [frames, features] → [1, features, frames]. The flat
+	// buffers are pooled (see pool.go); they are returned only after the
+	// tensors borrowing them are destroyed, since defers run in reverse order.
+	inputData := getFloat32s(int(numFeatures * numFrames))
+	defer putFloat32s(inputData)
+	for f := int64(0); f < numFrames; f++ {
+		for m := int64(0); m < numFeatures && m < int64(len(features[f])); m++ {
+			inputData[m*numFrames+f] = features[f][m]
+		}
+	}
+
+	inputTensor, err := newFloatTensor(t.encoderIO["audio_signal"], ort.NewShape(batchSize, numFeatures, numFrames), inputData)
+	if err != nil {
+		return nil, fmt.Errorf("create input tensor: %w", err)
+	}
+	defer inputTensor.Destroy()
+	inputTensor.toModel()
+
+	lengthTensor, err := ort.NewTensor(ort.NewShape(batchSize), []int64{numFrames})
+	if err != nil {
+		return nil, fmt.Errorf("create length tensor: %w", err)
+	}
+	defer lengthTensor.Destroy()
+
+	encodedLen := (numFrames-1)/int64(t.config.SubsamplingFactor) + 1
+
+	// The output tensors outlive this call: they are handed to the decoder
+	// and destroyed by release.
+	outputData := getFloat32s(int(encoderDim * encodedLen))
+	outputTensor, err := newFloatTensor(t.encoderIO["outputs"], ort.NewShape(batchSize, encoderDim, encodedLen), outputData)
+	if err != nil {
+		putFloat32s(outputData)
+		return nil, fmt.Errorf("create output tensor: %w", err)
+	}
+	outLenTensor, err := ort.NewEmptyTensor[int64](ort.NewShape(batchSize))
+	if err != nil {
+		outputTensor.Destroy()
+		putFloat32s(outputData)
+		return nil, fmt.Errorf("create output length tensor: %w", err)
+	}
+	release := func() {
+		outLenTensor.Destroy()
+		outputTensor.Destroy()
+		putFloat32s(outputData)
+	}
+
+	// Reuse the shared encoder session. Shapes vary per request, so tensors are
+	// supplied to Run each time; the session itself is built once at startup.
+	if err := t.encoder.Run(
+		[]ort.Value{inputTensor.value(), lengthTensor},
+		[]ort.Value{outputTensor.value(), outLenTensor},
+	); err != nil {
+		release()
+		return nil, fmt.Errorf("encoder run failed: %w", err)
+	}
+	outputTensor.fromModel()
+
+	enc := &encodedWindow{out: outputTensor.GetData(), len: outLenTensor.GetData()[0], release: release}
+	if t.encoderCache != nil {
+		t.encoderCache.put(key, enc)
+	}
+	ticketFrom(ctx).timings.Encoder += time.Since(start)
+
+	if DebugMode {
+		slog.DebugContext(ctx, "encoder output", "floats", len(enc.out), "encodedLen", enc.len)
+	}
+	return enc, nil
+}
+
+// windowEncoder encodes a plan's windows in order on its own goroutine, one
+// window ahead of the decoder. The encoder session and the decoder workers
+// are separate, so a long recording takes about as long as the slower of
+// the two stages rather than their sum.
+type windowEncoder struct {
+	results chan encodeResult // unbuffered: at most one window waits
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+type encodeResult struct {
+	enc      *encodedWindow
+	err      error
+	panicked any
+}
+
+// encodeAhead starts running encode over the windows of plan. The caller
+// takes them with next, in plan order, and must call stop.
+func encodeAhead(ctx context.Context, plan []chunkWindow, encode func(context.Context, chunkWindow) (*encodedWindow, error)) *windowEncoder {
+	ctx, cancel := context.WithCancel(ctx)
+	e := &windowEncoder{results: make(chan encodeResult), ctx: ctx, cancel: cancel}
+	go func() {
+		defer func() {
+			// A panic is raised again on the decoding goroutine, where the
+			// server's recovery sees it; here it would end the process.
+			if p := recover(); p != nil {
+				e.results <- encodeResult{panicked: p}
+			}
+			close(e.results)
+		}()
+		for _, win := range plan {
+			// The encoder cannot be interrupted: check before each window.
+			if err := ctx.Err(); err != nil {
+				return
+			}
+			enc, err := encode(ctx, win)
+			select {
+			case e.results <- encodeResult{enc: enc, err: err}:
+			case <-ctx.Done():
+				if enc != nil {
+					enc.release()
+				}
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return e
+}
+
+// next returns the next encoded window, which the caller releases once it
+// is decoded. It fails with the encoder's error, or the context's if the
+// transcription ended first.
+func (e *windowEncoder) next() (*encodedWindow, error) {
+	r, ok := <-e.results
+	if !ok {
+		return nil, e.ctx.Err()
+	}
+	if r.panicked != nil {
+		panic(r.panicked)
+	}
+	return r.enc, r.err
+}
+
+// stop cancels the encoding and waits for the goroutine to exit, releasing
+// windows nobody took. The caller holds the Transcriber's lifecycle, so
+// returning before an encoder run ends could let Close destroy the session
+// under it.
+func (e *windowEncoder) stop() {
+	e.cancel()
+	for r := range e.results {
+		if r.enc != nil {
+			r.enc.release()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeEncoder counts encoded windows and the ones not yet released.
+type fakeEncoder struct {
+	encoded, live atomic.Int32
+	fail          int64 // window start that fails, or -1
+}
+
+func (f *fakeEncoder) encode(ctx context.Context, win chunkWindow) (*encodedWindow, error) {
+	if win.start == f.fail {
+		return nil, errors.New("encoder failed")
+	}
+	f.encoded.Add(1)
+	f.live.Add(1)
+	return &encodedWindow{len: win.start, release: func() { f.live.Add(-1) }}, nil
+}
+
+func testPlan(n int) []chunkWindow {
+	plan := make([]chunkWindow, n)
+	for i := range plan {
+		plan[i] = chunkWindow{start: int64(i)}
+	}
+	return plan
+}
+
+func TestEncodeAheadOrder(t *testing.T) {
+	f := &fakeEncoder{fail: -1}
+	e := encodeAhead(context.Background(), testPlan(4), f.encode)
+	defer e.stop()
+	for i := range 4 {
+		enc, err := e.next()
+		if err != nil || enc.len != int64(i) {
+			t.Fatalf("window %d: %+v, %v", i, enc, err)
+		}
+		// While window i decodes, only window i+1 may be encoded.
+		time.Sleep(10 * time.Millisecond)
+		if got, max := f.encoded.Load(), int32(min(i+2, 4)); got > max {
+			t.Fatalf("encoded %d windows while decoding window %d", got, i)
+		}
+		enc.release()
+	}
+}
+
+func TestEncodeAheadStop(t *testing.T) {
+	f := &fakeEncoder{fail: -1}
+	e := encodeAhead(context.Background(), testPlan(8), f.encode)
+	enc, _ := e.next()
+	enc.release()
+	e.stop()
+	if n := f.live.Load(); n != 0 {
+		t.Errorf("%d encoded windows not released", n)
+	}
+	if n := f.encoded.Load(); n > 3 {
+		t.Errorf("encoded %d windows after stop", n)
+	}
+}
+
+func TestEncodeAheadErrors(t *testing.T) {
+	f := &fakeEncoder{fail: 1}
+	e := encodeAhead(context.Background(), testPlan(4), f.encode)
+	if enc, err := e.next(); err != nil {
+		t.Fatal(err)
+	} else {
+		enc.release()
+	}
+	if _, err := e.next(); err == nil {
+		t.Fatal("encoder error not returned")
+	}
+	e.stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e = encodeAhead(ctx, testPlan(4), (&fakeEncoder{fail: -1}).encode)
+	if _, err := e.next(); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled context: %v", err)
+	}
+	e.stop()
+
+	e = encodeAhead(context.Background(), testPlan(2), func(context.Context, chunkWindow) (*encodedWindow, error) {
+		panic("boom")
+	})
+	defer e.stop()
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the encoder's panic", p)
+		}
+	}()
+	e.next()
+	t.Error("next did not panic")
+}
@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import "sync"
+
+// float32Pool recycles the large flat buffers that back per-window encoder
+// tensors. A five-minute window is ~30k mel frames x 128 features in and
+// ~3.75k x 1024 out, i.e. tens of MB per request; allocating them fresh every
+// time dominates GC pressure under load. The buffers are plain Go memory
+// (ort.NewTensor only borrows the slice), so sync.Pool is safe here: a buffer
+// dropped by the GC is simply reallocated next time.
+var float32Pool sync.Pool
+
+// getFloat32s returns a zeroed slice of length n, reusing a pooled buffer
+// when one with enough capacity is available.
+func getFloat32s(n int) []float32 {
+	if p, ok := float32Pool.Get().(*[]float32); ok && cap(*p) >= n {
+		buf := (*p)[:n]
+		clear(buf)
+		return buf
+	}
+	return make([]float32, n)
+}
+
+// putFloat32s hands a buffer back for reuse. The caller must not touch it (or
+// any tensor built on it) afterwards.
+func putFloat32s(buf []float32) {
+	if cap(buf) == 0 {
+		return
+	}
+	float32Pool.Put(&buf)
+}
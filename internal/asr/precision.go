@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Precision is the numeric format of a model export, which decides the file
+// it is loaded from.
+type Precision string
+
+const (
+	// PrecisionAuto loads the int8 export when present, else fp32; the
+	// default.
+	PrecisionAuto Precision = "auto"
+	PrecisionInt8 Precision = "int8"
+	PrecisionFP16 Precision = "fp16"
+	PrecisionFP32 Precision = "fp32"
+)
+
+// ParsePrecision normalizes a user-supplied precision. An empty value
+// defaults to auto; unknown values are rejected at startup.
+func ParsePrecision(s string) (Precision, error) {
+	switch p := Precision(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return PrecisionAuto, nil
+	case PrecisionAuto, PrecisionInt8, PrecisionFP16, PrecisionFP32:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unsupported precision %q (supported: auto, int8, fp16, fp32)", s)
+	}
+}
+
+// PrecisionConfig selects the precision of each model. Empty values are
+// PrecisionAuto.
+type PrecisionConfig struct {
+	Encoder Precision
+	Decoder Precision
+}
+
+// modelFileName is the file of model base (encoder-model, decoder_joint-model)
+// exported at precision p. fp32 is the plain export.
+func modelFileName(base string, p Precision) string {
+	if p == PrecisionFP32 {
+		return base + ".onnx"
+	}
+	return base + "." + string(p) + ".onnx"
+}
+
+// resolveModelFile picks the file of model base for precision want, and the
+// precision it holds. auto falls back from int8 to fp32; an explicit
+// precision whose file is missing is an error rather than a silent swap.
+// what names the model in errors.
+func resolveModelFile(models modelStore, what, base string, want Precision) (string, Precision, error) {
+	if want != "" && want != PrecisionAuto {
+		name := modelFileName(base, want)
+		if !models.exists(name) {
+			return "", "", fmt.Errorf("%s precision %s requested but %s does not exist", what, want, models.path(name))
+		}
+		return name, want, nil
+	}
+	for _, p := range []Precision{PrecisionInt8, PrecisionFP32} {
+		if name := modelFileName(base, p); models.exists(name) {
+			return name, p, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s model not found. Download from https://huggingface.co/istupakov/parakeet-tdt-0.6b-v3-onnx", what)
+}
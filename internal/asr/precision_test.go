@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParsePrecision(t *testing.T) {
+	for in, want := range map[string]Precision{"": PrecisionAuto, " FP16 ": PrecisionFP16, "int8": PrecisionInt8, "fp32": PrecisionFP32} {
+		if got, err := ParsePrecision(in); err != nil || got != want {
+			t.Errorf("ParsePrecision(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePrecision("bf16"); err == nil {
+		t.Error("ParsePrecision(bf16) succeeded")
+	}
+}
+
+func TestResolveModelFile(t *testing.T) {
+	models := modelStore{fsys: fstest.MapFS{
+		"encoder-model.onnx":            {},
+		"encoder-model.fp16.onnx":       {},
+		"decoder_joint-model.int8.onnx": {},
+		"decoder_joint-model.onnx":      {},
+	}}
+	for _, tc := range []struct {
+		base string
+		want Precision
+		file string
+		got  Precision
+	}{
+		{"encoder-model", PrecisionAuto, "encoder-model.onnx", PrecisionFP32},
+		{"encoder-model", PrecisionFP16, "encoder-model.fp16.onnx", PrecisionFP16},
+		{"decoder_joint-model", "", "decoder_joint-model.int8.onnx", PrecisionInt8},
+		{"decoder_joint-model", PrecisionFP32, "decoder_joint-model.onnx", PrecisionFP32},
+	} {
+		file, got, err := resolveModelFile(models, "model", tc.base, tc.want)
+		if err != nil || file != tc.file || got != tc.got {
+			t.Errorf("%s %q = %s, %s, %v; want %s, %s", tc.base, tc.want, file, got, err, tc.file, tc.got)
+		}
+	}
+
+	// An explicit precision never falls back to another file.
+	if _, _, err := resolveModelFile(models, "encoder", "encoder-model", PrecisionInt8); err == nil || !strings.Contains(err.Error(), "encoder-model.int8.onnx") {
+		t.Errorf("missing int8 encoder: %v", err)
+	}
+	if _, _, err := resolveModelFile(models, "vad", "vad-model", PrecisionAuto); err == nil {
+		t.Error("missing model resolved")
+	}
+}
@@ -60,7 +60,7 @@ func TestParseProviderErrorNamesSupported(t *testing.T) {
 // a GPU, so it is exercised manually, not in CI (see spec acceptance criteria).
 func TestBuildSessionOptionsCPU(t *testing.T) {
 	for _, p := range []Provider{ProviderCPU, Provider("")} {
-		opts, err := buildSessionOptions(GPUConfig{Provider: p})
+		opts, err := buildSessionOptions(GPUConfig{Provider: p}, ThreadConfig{})
 		if err != nil {
 			t.Fatalf("buildSessionOptions(%q) error: %v", p, err)
 		}
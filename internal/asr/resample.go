@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ResampleQuality selects the resampler used for WAV input whose rate is not
+// 16 kHz. Inputs converted by ffmpeg are resampled by ffmpeg itself.
+type ResampleQuality string
+
+const (
+	// ResampleLinear is plain linear interpolation: cheapest, but it aliases
+	// everything above the target Nyquist back into the speech band.
+	ResampleLinear ResampleQuality = "linear"
+	// ResampleMedium is a Kaiser-windowed sinc with ~60 dB stopband; the
+	// default, and plenty for speech recognition.
+	ResampleMedium ResampleQuality = "medium"
+	// ResampleHigh is a longer Kaiser-windowed sinc with ~90 dB stopband and a
+	// narrower transition band, at roughly twice the cost of medium.
+	ResampleHigh ResampleQuality = "high"
+)
+
+// ParseResampleQuality normalizes a user-supplied quality string. An empty
+// value defaults to medium; unknown values are rejected at startup.
+func ParseResampleQuality(s string) (ResampleQuality, error) {
+	switch q := ResampleQuality(strings.ToLower(strings.TrimSpace(s))); q {
+	case "":
+		return ResampleMedium, nil
+	case ResampleLinear, ResampleMedium, ResampleHigh:
+		return q, nil
+	default:
+		return "", fmt.Errorf("unsupported resample quality %q (supported: linear, medium, high)", s)
+	}
+}
+
+// sincParams are the filter design knobs of one quality level: zero crossings
+// on each side of the kernel centre, passband edge as a fraction of the lower
+// Nyquist, and the Kaiser window beta (stopband attenuation).
+type sincParams struct {
+	zeros   int
+	rolloff float64
+	beta    float64
+}
+
+func (q ResampleQuality) sincParams() sincParams {
+	if q == ResampleHigh {
+		return sincParams{zeros: 32, rolloff: 0.945, beta: 8.6}
+	}
+	return sincParams{zeros: 16, rolloff: 0.91, beta: 6.0}
+}
+
+// maxPolyphases caps the precomputed phase table. Every common audio rate
+// pair converted to 16 kHz needs at most 640 phases (11.025 kHz); exotic
+// ratios beyond the cap evaluate the kernel per output sample instead.
+const maxPolyphases = 4096
+
+// resample converts samples from srcRate to dstRate with the given quality.
+func resample(samples []float32, srcRate, dstRate int, quality ResampleQuality) []float32 {
+	if srcRate == dstRate || len(samples) == 0 {
+		return samples
+	}
+	if quality == ResampleLinear {
+		return resampleLinear(samples, srcRate, dstRate)
+	}
+	return resampleSinc(samples, srcRate, dstRate, quality.sincParams())
+}
+
+// resampleLinear uses linear interpolation for simple resampling
+func resampleLinear(samples []float32, srcRate, dstRate int) []float32 {
+	ratio := float64(srcRate) / float64(dstRate)
+	newLen := int(float64(len(samples)) / ratio)
+	result := make([]float32, newLen)
+
+	for i := 0; i < newLen; i++ {
+		srcIdx := float64(i) * ratio
+		lo := int(srcIdx)
+		hi := lo + 1
+		if hi >= len(samples) {
+			hi = len(samples) - 1
+		}
+		frac := float32(srcIdx - float64(lo))
+		result[i] = samples[lo]*(1-frac) + samples[hi]*frac
+	}
+
+	return result
+}
+
+// resampleSinc is a band-limited resampler. Output sample i sits at input
+// position t = i*src/dst and is the input convolved with a Kaiser-windowed
+// sinc low-pass whose cutoff is rolloff times the LOWER of the two Nyquist
+// rates, so downsampling removes content the target rate cannot represent
+// instead of aliasing it.
+//
+// For a rational ratio up/down (reduced by the GCD) the fractional part of t
+// only takes `up` distinct values, so the kernel is precomputed once per
+// phase (polyphase) and each output is a plain dot product. Samples beyond
+// either edge are treated as zero.
+func resampleSinc(samples []float32, srcRate, dstRate int, p sincParams) []float32 {
+	g := gcd(srcRate, dstRate)
+	up, down := dstRate/g, srcRate/g
+
+	// Cutoff in cycles per input sample x2 (1.0 = input Nyquist).
+	cutoff := p.rolloff * math.Min(1, float64(dstRate)/float64(srcRate))
+	halfWidth := float64(p.zeros) / cutoff // kernel half-length in input samples
+	taps := 2 * int(math.Ceil(halfWidth))
+	i0Beta := besselI0(p.beta)
+
+	kernel := func(d float64) float64 {
+		x := d / halfWidth
+		if x <= -1 || x >= 1 {
+			return 0
+		}
+		return cutoff * sinc(cutoff*d) * besselI0(p.beta*math.Sqrt(1-x*x)) / i0Beta
+	}
+
+	// Tap j of an output at position t = base + frac reads input base-taps/2+1+j,
+	// i.e. offset d = frac - (j - taps/2 + 1) from the output position.
+	weightsFor := func(frac float64, w []float64) {
+		for j := range w {
+			w[j] = kernel(frac - float64(j-taps/2+1))
+		}
+	}
+
+	var table [][]float64
+	if up <= maxPolyphases {
+		table = make([][]float64, up)
+		for ph := range table {
+			table[ph] = make([]float64, taps)
+			weightsFor(float64(ph)/float64(up), table[ph])
+		}
+	}
+
+	outLen := int(int64(len(samples)) * int64(up) / int64(down))
+	out := make([]float32, outLen)
+	scratch := make([]float64, taps)
+	for i := range out {
+		pos := int64(i) * int64(down) // output position in units of 1/up input samples
+		base := int(pos / int64(up))
+		ph := int(pos % int64(up))
+
+		w := scratch
+		if table != nil {
+			w = table[ph]
+		} else {
+			weightsFor(float64(ph)/float64(up), w)
+		}
+
+		start := base - taps/2 + 1
+		var acc float64
+		for j, wj := range w {
+			k := start + j
+			if k < 0 || k >= len(samples) {
+				continue
+			}
+			acc += float64(samples[k]) * wj
+		}
+		out[i] = float32(acc)
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind,
+// by its power series; it converges quickly for the beta values used here.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	q := x * x / 4
+	for k := 1; k < 64; k++ {
+		term *= q / float64(k*k)
+		sum += term
+		if term < sum*1e-16 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
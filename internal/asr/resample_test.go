@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"testing"
+)
+
+func tone(freq float64, rate, n int) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / float64(rate)))
+	}
+	return out
+}
+
+// rms over the middle of the signal, away from the zero-padded edges.
+func midRMS(x []float32) float64 {
+	lo, hi := len(x)/4, 3*len(x)/4
+	var sum float64
+	for _, v := range x[lo:hi] {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(hi-lo))
+}
+
+func TestParseResampleQuality(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    ResampleQuality
+		wantErr bool
+	}{
+		{"", ResampleMedium, false},
+		{"linear", ResampleLinear, false},
+		{" HIGH ", ResampleHigh, false},
+		{"medium", ResampleMedium, false},
+		{"soxr", "", true},
+	}
+	for _, tc := range cases {
+		got, err := ParseResampleQuality(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseResampleQuality(%q) = %q, %v; want %q, err=%v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+// A 1 kHz tone is well inside the passband for every common source rate and
+// must come through at unit gain with the expected output length.
+func TestResample_PassbandTone(t *testing.T) {
+	for _, src := range []int{8000, 11025, 22050, 44100, 48000} {
+		for _, q := range []ResampleQuality{ResampleMedium, ResampleHigh} {
+			in := tone(1000, src, src) // one second
+			out := resample(in, src, 16000, q)
+			if len(out) != 16000 {
+				t.Fatalf("%d Hz %s: %d samples out, want 16000", src, q, len(out))
+			}
+			if got := midRMS(out); math.Abs(got-math.Sqrt(0.5)) > 0.01 {
+				t.Fatalf("%d Hz %s: passband RMS %.4f, want %.4f", src, q, got, math.Sqrt(0.5))
+			}
+		}
+	}
+}
+
+// A 12 kHz tone in 44.1 kHz audio is above the 8 kHz target Nyquist. Linear
+// interpolation folds it back to 4 kHz at near full level; the sinc resampler
+// must suppress it.
+func TestResample_RejectsAliases(t *testing.T) {
+	in := tone(12000, 44100, 44100)
+
+	if got := midRMS(resample(in, 44100, 16000, ResampleLinear)); got < 0.1 {
+		t.Fatalf("linear alias RMS %.4f; expected the known aliasing (test is not exercising the stopband)", got)
+	}
+	limits := map[ResampleQuality]float64{ResampleMedium: 1e-3, ResampleHigh: 1e-4}
+	for q, limit := range limits {
+		if got := midRMS(resample(in, 44100, 16000, q)); got > limit {
+			t.Fatalf("%s: alias RMS %.2e, want < %.0e", q, got, limit)
+		}
+	}
+}
+
+// Ratios too large for the phase table take the per-sample path and must
+// agree with it.
+func TestResample_UntabledRatio(t *testing.T) {
+	const src = 16001 // 16000/16001 is irreducible: 16000 phases
+	in := tone(440, src, src)
+	out := resample(in, src, 16000, ResampleMedium)
+	if len(out) != 16000 {
+		t.Fatalf("got %d samples, want 16000", len(out))
+	}
+	if got := midRMS(out); math.Abs(got-math.Sqrt(0.5)) > 0.01 {
+		t.Fatalf("RMS %.4f, want %.4f", got, math.Sqrt(0.5))
+	}
+}
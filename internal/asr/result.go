@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"time"
+
+	"parakeet/internal/itn"
+)
+
+// TranscribeOptions are the per-request knobs of TranscribeWithOptions.
+// The zero value transcribes a downmixed mono signal, exactly like Transcribe.
+type TranscribeOptions struct {
+	// Format is the client-declared audio format (usually the file
+	// extension). It is informational; detection is by content.
+	Format string
+
+	// Language is the requested ISO-639-1 language. Parakeet detects the
+	// language itself, so this is currently informational.
+	Language string
+
+	// Channels selects mix (default), left, right or per_channel.
+	Channels ChannelMode
+
+	// Conditioning is the optional DC removal / silence trim / gain chain
+	// applied to the decoded audio before feature extraction.
+	Conditioning Conditioning
+
+	// Denoise runs the decoded audio through the noise-suppression model
+	// before conditioning. It fails with ErrDenoiseUnavailable when no model
+	// is loaded.
+	Denoise bool
+
+	// Decoding overrides the greedy TDT decoding (token cap, blank penalty,
+	// beam search, sampling). The zero value is the default.
+	Decoding DecodingOptions
+
+	// Fallback re-decodes windows whose text looks looped or unsure at
+	// rising sampling temperatures. The zero value never retries.
+	Fallback Fallback
+
+	// Progress, when set, is called as decoding advances with the seconds of
+	// audio processed so far and the total to process (after trimming, and
+	// summed over channels for per-channel transcription). It is called from
+	// the goroutine running the transcription and never goes backwards.
+	Progress func(processed, total float64) `json:"-"`
+
+	// Segmentation splits the transcript into segments at pauses and
+	// sentence ends. The zero value keeps the default segments.
+	Segmentation Segmentation
+
+	// NoSpeechThreshold, when positive, drops the segments whose
+	// NoSpeechProb is above it, with their words, from the result: the
+	// text the model made up on silence or noise. Text already streamed
+	// through emit is not taken back.
+	NoSpeechThreshold float64
+
+	// Guard checks the transcript for text made up from music or noise and
+	// flags or removes it. The zero value is off.
+	Guard HallucinationGuard
+
+	// Confidence drops or masks the words decoded with low confidence.
+	// Like NoSpeechThreshold, it does not take back streamed text.
+	Confidence ConfidenceFilter
+
+	// Formatting writes numbers, dates, times and units as digits or
+	// words in the text of the result and its segments. The zero value
+	// leaves them as recognised.
+	Formatting itn.Options
+
+	// Priority is the scheduling class for the decoder workers; empty is
+	// PriorityNormal.
+	Priority Priority
+
+	// MaxProcessing, when positive, caps how long the transcription may
+	// run. Once it is over, decoding stops and the transcript decoded so far
+	// is returned with Truncated set, instead of an error.
+	MaxProcessing time.Duration
+}
+
+// Result is a transcript with the timing detail the plain-text API drops.
+type Result struct {
+	// Text is the full transcript. For per-channel transcription it is one
+	// line per segment, prefixed with the segment's ChannelLabel.
+	Text string
+
+	// Model is the name of the model that decoded it (RuntimeInfo.Name).
+	Model string
+
+	// Duration is the length of the decoded audio in seconds.
+	Duration float64
+
+	// Channels is how many channels were transcribed separately: 1 unless
+	// the request used ChannelPerChannel on a multi-channel recording.
+	Channels int
+
+	// Segments are contiguous stretches of transcript in time order. Without
+	// per-channel transcription or Segmentation there is a single segment
+	// spanning the audio.
+	Segments []Segment
+
+	// Words are the transcript's words with their timing and confidence, in
+	// time order (across channels for per-channel transcription).
+	Words []Word
+
+	// Tokens are the decoded tokens behind Words, in the same order, with
+	// their log-probabilities.
+	Tokens []Token
+
+	// Truncated reports that decoding stopped at TranscribeOptions.
+	// MaxProcessing: the transcript covers only the start of the audio.
+	Truncated bool
+
+	// Timings is where the transcription spent its time. It describes the
+	// decode that produced the result, so it is not kept in the disk cache.
+	Timings Timings `json:"-"`
+}
+
+// Shifted returns a copy of r with its segment, word and token times moved
+// by offset seconds: the timeline of a longer recording the audio was cut
+// from. r itself is left as it is, so a shared or cached result can be
+// shifted for one request.
+func (r *Result) Shifted(offset float64) *Result {
+	if offset == 0 {
+		return r
+	}
+	out := *r
+	out.Segments = make([]Segment, len(r.Segments))
+	for i, seg := range r.Segments {
+		seg.Start += offset
+		seg.End += offset
+		out.Segments[i] = seg
+	}
+	out.Words = shiftWords(append([]Word(nil), r.Words...), offset)
+	out.Tokens = shiftTokens(append([]Token(nil), r.Tokens...), offset)
+	return &out
+}
+
+// AvgLogprob is the mean log-probability of the tokens seg covers: those on
+// its channel starting in [seg.Start, seg.End). ok is false when there are
+// none, as for a blanked segment or a result without tokens.
+func (r *Result) AvgLogprob(seg Segment) (avg float64, ok bool) {
+	var sum float64
+	n := 0
+	for _, tok := range r.Tokens {
+		if tok.Channel == seg.Channel && tok.Start >= seg.Start && tok.Start < seg.End {
+			sum += tok.Logprob
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// Timings splits a transcription's time by pipeline stage. Encoder and
+// Decoder are summed over windows and channels.
+type Timings struct {
+	DecodeAudio time.Duration // container decoding, resampling, denoise and conditioning
+	Features    time.Duration // mel features and chunk planning
+	Encoder     time.Duration
+	Decoder     time.Duration // excludes the wait for a decoder worker
+	Postprocess time.Duration // tokens to text, words and segments
+}
+
+// Segment is one stretch of transcript from one channel.
+type Segment struct {
+	Channel int
+	Start   float64 // seconds
+	End     float64 // seconds
+	Text    string
+
+	// NoSpeechProb is how likely the segment holds no speech, 0..1: the
+	// decoder's mean blank probability over the segment's half second with
+	// the most speech. Beam search does not measure it and leaves 0.
+	NoSpeechProb float64
+
+	// Temperature is the highest sampling temperature the segment was
+	// decoded at: the request's own, or higher where TranscribeOptions.
+	// Fallback retried a window. 0 is greedy or beam decoding.
+	Temperature float64
+
+	// Suspect lists why TranscribeOptions.Guard found the segment
+	// improbable (SuspectUnvoiced, SuspectTokenRate, SuspectRepetition).
+	Suspect []string
+}
+
+// Word is one word of the transcript. Text keeps the punctuation the model
+// attached to it ("world,"). Start and End are in seconds and have the
+// resolution of one encoder frame (80 ms).
+type Word struct {
+	Channel    int
+	Start      float64
+	End        float64
+	Text       string
+	Confidence float64 // mean probability of the word's tokens, 0..1
+}
+
+// Token is one decoded SentencePiece token. Text keeps the leading space
+// that marks a word start (" hello"); special tokens are left out.
+type Token struct {
+	Channel int
+	Start   float64 // seconds
+	Text    string
+	Logprob float64 // natural log of the token's softmax probability
+}
+
+// encoderFrameSeconds is the audio duration of one encoder output frame
+// (mel hop times the subsampling factor), the resolution of token timesteps.
+func (t *Transcriber) encoderFrameSeconds() float64 {
+	return float64(t.mel.HopLength()*t.config.SubsamplingFactor) / featureSampleRate
+}
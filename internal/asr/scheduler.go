@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Priority is the scheduling class of a transcription. When every decoder
+// worker is busy, a freed worker goes to the oldest waiting decode of the
+// highest class. Long audio takes a worker per window, so a batch job yields
+// to interactive requests between windows.
+type Priority string
+
+const (
+	// PriorityInteractive is for a user waiting on the answer, such as a
+	// voice assistant or live captions.
+	PriorityInteractive Priority = "interactive"
+	// PriorityNormal is the default.
+	PriorityNormal Priority = "normal"
+	// PriorityBatch is for work nobody waits on, such as async jobs.
+	PriorityBatch Priority = "batch"
+)
+
+// priorities lists the classes from highest to lowest.
+var priorities = []Priority{PriorityInteractive, PriorityNormal, PriorityBatch}
+
+// ParsePriority normalizes a user-supplied priority. An empty value defaults
+// to normal.
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return PriorityNormal, nil
+	case PriorityInteractive, PriorityNormal, PriorityBatch:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unsupported priority %q (supported: interactive, normal, batch)", s)
+	}
+}
+
+// rank is the index of p in priorities; unknown values rank as normal.
+func (p Priority) rank() int {
+	switch p {
+	case PriorityInteractive:
+		return 0
+	case PriorityBatch:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ErrQueueFull is returned when a transcription would wait for a decoder
+// worker behind more decodes of its priority than QueueLimits allows.
+var ErrQueueFull = errors.New("too many transcriptions waiting for a decoder worker")
+
+// QueueLimits caps the decodes waiting for a worker per priority. Only a
+// transcription's first window is refused; once it has decoded one, its
+// later windows always queue. Zero means unlimited.
+type QueueLimits struct {
+	Interactive int
+	Normal      int
+	Batch       int
+}
+
+func (l QueueLimits) of(rank int) int {
+	return [...]int{l.Interactive, l.Normal, l.Batch}[rank]
+}
+
+// decodeTicket carries a transcription's priority to each window's worker
+// acquisition, and collects its stage timings and the blank probabilities
+// of the waveform being decoded. Windows and channels decode one after
+// another, so it needs no lock: the encoder goroutine of a pipelined
+// waveform (pipeline.go) only touches timings.Encoder, and
+// transcribeWaveform waits for it before returning.
+type decodeTicket struct {
+	priority Priority
+	started  bool // a window got a worker; later ones skip QueueLimits
+	timings  Timings
+	blank    blankFrames // reset by TranscribeWithOptions for each plane
+
+	temperatures []windowTemperature // likewise; sampled windows only
+}
+
+type ticketKey struct{}
+
+// ticketFrom returns the transcription's ticket; a normal-priority one for
+// decodes that did not come through TranscribeWithOptions.
+func ticketFrom(ctx context.Context) *decodeTicket {
+	if tk, ok := ctx.Value(ticketKey{}).(*decodeTicket); ok {
+		return tk
+	}
+	return &decodeTicket{priority: PriorityNormal}
+}
+
+// workerPool hands out the decoder workers by priority, first come first
+// served within a class.
+type workerPool struct {
+	limits QueueLimits
+	size   int
+
+	mu      sync.Mutex
+	idle    []*decoderWorker
+	waiters [3][]chan *decoderWorker
+}
+
+func newWorkerPool(limits QueueLimits) *workerPool {
+	return &workerPool{limits: limits}
+}
+
+// add puts a new worker in the pool.
+func (p *workerPool) add(w *decoderWorker) {
+	p.mu.Lock()
+	p.size++
+	p.mu.Unlock()
+	p.release(w)
+}
+
+// acquire returns an idle worker, waiting for one until ctx is done. first
+// marks the first window of a transcription, the only one QueueLimits
+// refuses.
+func (p *workerPool) acquire(ctx context.Context, prio Priority, first bool) (*decoderWorker, error) {
+	rank := prio.rank()
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		w := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return w, nil
+	}
+	if limit := p.limits.of(rank); first && limit > 0 && len(p.waiters[rank]) >= limit {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w (priority %s)", ErrQueueFull, priorities[rank])
+	}
+	ch := make(chan *decoderWorker, 1)
+	p.waiters[rank] = append(p.waiters[rank], ch)
+	p.mu.Unlock()
+
+	select {
+	case w := <-ch:
+		return w, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		queue := p.waiters[rank]
+		for i, c := range queue {
+			if c == ch {
+				p.waiters[rank] = append(queue[:i:i], queue[i+1:]...)
+				p.mu.Unlock()
+				return nil, ctx.Err()
+			}
+		}
+		p.mu.Unlock()
+		// release handed a worker over meanwhile: pass it on.
+		p.release(<-ch)
+		return nil, ctx.Err()
+	}
+}
+
+// release gives w to the first waiter of the highest class, or back to the
+// idle workers.
+func (p *workerPool) release(w *decoderWorker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for rank, queue := range p.waiters {
+		if len(queue) > 0 {
+			p.waiters[rank] = queue[1:]
+			queue[0] <- w
+			return
+		}
+	}
+	p.idle = append(p.idle, w)
+}
+
+// status reports the pool occupancy for PoolStatus.
+func (p *workerPool) status() PoolStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := PoolStatus{
+		Workers:           p.size,
+		Busy:              p.size - len(p.idle),
+		WaitingByPriority: make(map[Priority]int, len(priorities)),
+	}
+	for rank, queue := range p.waiters {
+		st.Waiting += len(queue)
+		st.WaitingByPriority[priorities[rank]] = len(queue)
+	}
+	return st
+}
+
+// destroy releases the idle workers. Close calls it once no decode runs, so
+// every worker is idle.
+func (p *workerPool) destroy() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, w := range p.idle {
+		w.destroy()
+	}
+	p.idle = nil
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	for in, want := range map[string]Priority{"": PriorityNormal, " Batch ": PriorityBatch, "interactive": PriorityInteractive} {
+		if got, err := ParsePriority(in); err != nil || got != want {
+			t.Errorf("ParsePriority(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("ParsePriority(urgent) succeeded")
+	}
+}
+
+// waitQueued waits until n decodes are queued in the pool.
+func waitQueued(t *testing.T, p *workerPool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.status().Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", p.status().Waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkerPoolPriorityOrder(t *testing.T) {
+	p := newWorkerPool(QueueLimits{})
+	w := &decoderWorker{}
+	p.add(w)
+	held, err := p.acquire(context.Background(), PriorityNormal, true)
+	if err != nil || held != w {
+		t.Fatalf("acquire idle worker = %p, %v", held, err)
+	}
+
+	order := make(chan Priority, 4)
+	queue := func(prio Priority) {
+		go func() {
+			got, err := p.acquire(context.Background(), prio, true)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- prio
+			p.release(got)
+		}()
+	}
+	queue(PriorityBatch)
+	waitQueued(t, p, 1)
+	queue(PriorityNormal)
+	waitQueued(t, p, 2)
+	queue(PriorityInteractive)
+	waitQueued(t, p, 3)
+	queue(PriorityInteractive)
+	waitQueued(t, p, 4)
+
+	st := p.status()
+	if st.Busy != 1 || st.WaitingByPriority[PriorityInteractive] != 2 || st.WaitingByPriority[PriorityBatch] != 1 {
+		t.Errorf("status = %+v", st)
+	}
+
+	p.release(held)
+	want := []Priority{PriorityInteractive, PriorityInteractive, PriorityNormal, PriorityBatch}
+	for i, prio := range want {
+		select {
+		case got := <-order:
+			if got != prio {
+				t.Fatalf("decode %d ran %s, want %s", i, got, prio)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("worker not handed on")
+		}
+	}
+	if st := p.status(); st.Busy != 0 || st.Waiting != 0 {
+		t.Errorf("status after all ran = %+v", st)
+	}
+}
+
+func TestWorkerPoolQueueLimit(t *testing.T) {
+	p := newWorkerPool(QueueLimits{Batch: 1})
+	p.add(&decoderWorker{})
+	held, _ := p.acquire(context.Background(), PriorityBatch, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.acquire(ctx, PriorityBatch, true)
+		done <- err
+	}()
+	waitQueued(t, p, 1)
+
+	if _, err := p.acquire(context.Background(), PriorityBatch, true); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("second batch waiter: %v, want ErrQueueFull", err)
+	}
+	// Other classes and later windows of running transcriptions still queue.
+	for _, c := range []struct {
+		prio  Priority
+		first bool
+	}{{PriorityNormal, true}, {PriorityBatch, false}} {
+		short, stop := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		if _, err := p.acquire(short, c.prio, c.first); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s first=%t: %v, want a deadline", c.prio, c.first, err)
+		}
+		stop()
+	}
+
+	// A cancelled waiter leaves the queue.
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled waiter: %v", err)
+	}
+	if st := p.status(); st.Waiting != 0 {
+		t.Errorf("waiting after cancel = %d", st.Waiting)
+	}
+	p.release(held)
+	if st := p.status(); st.Busy != 0 {
+		t.Errorf("busy after release = %d", st.Busy)
+	}
+}
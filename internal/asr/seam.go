@@ -32,6 +32,7 @@ const (
 type decodedToken struct {
 	id       int
 	timestep int64
+	prob     float32 // softmax probability of id at its decode step
 }
 
 // dedupSeam decides which of window i+1's leading tokens (head) survive when
@@ -109,7 +109,7 @@ func TestSeamInspection(t *testing.T) {
 			resolveSeam = func(head []decodedToken) []decodedToken { return dedupSeam(tail, head) }
 		}
 
-		wt, err := tr.runInference(ctx, features[win.start:win.end], emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, nil)
+		wt, err := tr.runInference(ctx, features[win.start:win.end], DecodingOptions{}, emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, nil, nil)
 		if err != nil {
 			t.Fatalf("window %d inference: %v", i, err)
 		}
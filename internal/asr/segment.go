@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Segmentation splits a transcript into segments at pauses and sentence
+// ends, for verbose_json segments and subtitle cues that follow the speech.
+// The zero value keeps one segment per transcript (one per turn for
+// per-channel transcription).
+type Segmentation struct {
+	// Pause splits at silences between words of at least this many seconds.
+	// Zero disables pause splitting.
+	Pause float64
+
+	// Sentences splits after words that end a sentence (., ?, !, … and
+	// their CJK forms) when the next word starts like a new sentence.
+	Sentences bool
+
+	// MaxDuration splits segments longer than this many seconds, at the
+	// last sentence end that keeps the first part within it or else at the
+	// widest pause. Zero leaves segment length alone.
+	MaxDuration float64
+}
+
+// segmentQuietDB is how far below the speech level a pause between words
+// must be to count as silence. Gaps in the decoder's output can also be
+// music, noise or speech it did not transcribe, which should not end a
+// segment.
+const segmentQuietDB = 20.0
+
+// enabled reports whether s changes the default segments.
+func (s Segmentation) enabled() bool {
+	return s.Pause > 0 || s.Sentences || s.MaxDuration > 0
+}
+
+// split groups the words of one channel into segments. plane is the
+// channel's waveform the words were decoded from and offset the seconds
+// trimmed off its start, so pauses can be checked against its frame energy;
+// a nil plane takes every gap as silent. Any gap of turnGap seconds or more
+// splits regardless of the settings (zero for none).
+func (s Segmentation) split(words []Word, plane []float32, offset, turnGap float64) []Segment {
+	if len(words) == 0 {
+		return nil
+	}
+	e := newGapEnergy(words, plane, offset)
+
+	var segments []Segment
+	var run []Word
+	flush := func() {
+		for _, part := range s.limit(run) {
+			segments = append(segments, wordSegment(part))
+		}
+		run = nil
+	}
+	for i, w := range words {
+		if i > 0 {
+			prev := words[i-1]
+			gap := w.Start - prev.End
+			switch {
+			case turnGap > 0 && gap >= turnGap:
+				flush()
+			case s.Pause > 0 && gap >= s.Pause && e.quiet(prev.End, w.Start):
+				flush()
+			case s.Sentences && sentenceBreak(prev.Text, w.Text):
+				flush()
+			}
+		}
+		run = append(run, w)
+	}
+	flush()
+	return segments
+}
+
+// limit splits a run of words into parts of at most MaxDuration seconds.
+// A single word longer than that is a part of its own.
+func (s Segmentation) limit(run []Word) [][]Word {
+	var parts [][]Word
+	for s.MaxDuration > 0 && len(run) > 1 && run[len(run)-1].End-run[0].Start > s.MaxDuration {
+		// Candidate breaks are before word k; the first part must fit.
+		sentence, widest := 0, 0
+		widestGap := -1.0
+		for k := 1; k < len(run) && run[k-1].End-run[0].Start <= s.MaxDuration; k++ {
+			if sentenceBreak(run[k-1].Text, run[k].Text) {
+				sentence = k
+			}
+			if gap := run[k].Start - run[k-1].End; gap >= widestGap {
+				widest, widestGap = k, gap
+			}
+		}
+		k := sentence
+		if k == 0 {
+			k = max(widest, 1)
+		}
+		parts = append(parts, run[:k])
+		run = run[k:]
+	}
+	return append(parts, run)
+}
+
+// wordSegment is the segment spanning words, with their text joined.
+func wordSegment(words []Word) Segment {
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.Text
+	}
+	return Segment{
+		Channel: words[0].Channel,
+		Start:   words[0].Start,
+		End:     words[len(words)-1].End,
+		Text:    strings.Join(texts, " "),
+	}
+}
+
+// sentenceBreak reports whether a sentence ends between the words prev and
+// next: prev ends in sentence-final punctuation, possibly inside closing
+// quotes or brackets, and next does not start in lower case. "e.g. this"
+// stays together, but a title before a name ("Mr. Smith") is split.
+func sentenceBreak(prev, next string) bool {
+	prev = strings.TrimRight(prev, "\"'’”»)]")
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	switch last {
+	case '.', '?', '!', '…', '。', '？', '！':
+	default:
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(strings.TrimLeft(next, "\"'‘“«(["))
+	return !unicode.IsLower(first)
+}
+
+// gapEnergy measures pauses between words against the level of the speech
+// around them.
+type gapEnergy struct {
+	plane     []float32
+	offset    float64
+	threshold float64 // RMS below which a pause is silent
+}
+
+func newGapEnergy(words []Word, plane []float32, offset float64) gapEnergy {
+	e := gapEnergy{plane: plane, offset: offset}
+	if plane == nil {
+		return e
+	}
+	var sum float64
+	var n int
+	for _, w := range words {
+		for _, v := range e.samples(w.Start, w.End) {
+			sum += float64(v) * float64(v)
+			n++
+		}
+	}
+	if n > 0 {
+		e.threshold = math.Sqrt(sum/float64(n)) * dbToAmplitude(-segmentQuietDB)
+	}
+	return e
+}
+
+// samples is the stretch of the plane between two transcript times.
+func (e gapEnergy) samples(start, end float64) []float32 {
+	from := min(max(int((start-e.offset)*featureSampleRate), 0), len(e.plane))
+	to := min(max(int((end-e.offset)*featureSampleRate), from), len(e.plane))
+	return e.plane[from:to]
+}
+
+// quiet reports whether the pause from start to end is silence.
+func (e gapEnergy) quiet(start, end float64) bool {
+	if e.plane == nil {
+		return true
+	}
+	return frameRMS(e.samples(start, end)) <= e.threshold
+}
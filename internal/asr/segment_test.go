@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// segmentList renders segments as "start-end text" joined by |.
+func segmentList(segments []Segment) string {
+	var out []string
+	for _, s := range segments {
+		out = append(out, fmt.Sprintf("%g-%g %s", s.Start, s.End, s.Text))
+	}
+	return strings.Join(out, "|")
+}
+
+// timedWords spaces words half a second apart, each 0.4 s long, with extra
+// pause seconds before the words at the given indexes.
+func timedWords(text string, pauses map[int]float64) []Word {
+	var words []Word
+	at := 0.0
+	for i, f := range strings.Fields(text) {
+		at += pauses[i]
+		words = append(words, Word{Start: at, End: at + 0.4, Text: f})
+		at += 0.5
+	}
+	return words
+}
+
+func TestSegmentation_Split(t *testing.T) {
+	words := timedWords("Hello there. How are you? fine, thanks", map[int]float64{2: 1, 5: 0.5})
+
+	for _, tc := range []struct {
+		name string
+		seg  Segmentation
+		want string
+	}{
+		{"pause", Segmentation{Pause: 0.6}, "0-0.9 Hello there.|2-3.4 How are you?|4-4.9 fine, thanks"},
+		{"sentences", Segmentation{Sentences: true}, "0-0.9 Hello there.|2-4.9 How are you? fine, thanks"},
+		{"max duration", Segmentation{MaxDuration: 2}, "0-0.9 Hello there.|2-3.4 How are you?|4-4.9 fine, thanks"},
+		{"max duration without breaks", Segmentation{MaxDuration: 0.95}, "0-0.9 Hello there.|2-2.9 How are|3-3.4 you?|4-4.9 fine, thanks"},
+	} {
+		if got := segmentList(tc.seg.split(words, nil, 0, 0)); got != tc.want {
+			t.Errorf("%s:\n got %s\nwant %s", tc.name, got, tc.want)
+		}
+	}
+
+	// The turn gap splits even with nothing else enabled.
+	if got := segmentList(Segmentation{Sentences: true}.split(words, nil, 0, 1)); !strings.HasPrefix(got, "0-0.9 Hello there.|2-") {
+		t.Errorf("turn gap: %s", got)
+	}
+	if segs := (Segmentation{Pause: 1}).split(nil, nil, 0, 0); segs != nil {
+		t.Errorf("no words gave %v", segs)
+	}
+}
+
+func TestSegmentation_PauseEnergy(t *testing.T) {
+	// Speech at 0.1 amplitude; the gap after "one" is silent, the gap
+	// after "two" carries music as loud as the speech.
+	words := []Word{{Start: 0, End: 1, Text: "one"}, {Start: 2, End: 3, Text: "two"}, {Start: 4, End: 5, Text: "three"}}
+	plane := make([]float32, 5*featureSampleRate)
+	for i := range plane {
+		sec := float64(i) / featureSampleRate
+		if sec < 1 || sec >= 2 {
+			plane[i] = 0.1
+			if i%2 == 1 {
+				plane[i] = -0.1
+			}
+		}
+	}
+	got := segmentList(Segmentation{Pause: 0.5}.split(words, plane, 0, 0))
+	if want := "0-1 one|2-5 two three"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Word times refer to the untrimmed audio: with 1 s trimmed, the plane
+	// starts at second 1.
+	shifted := []Word{{Start: 1, End: 2, Text: "one"}, {Start: 3, End: 4, Text: "two"}}
+	if got := segmentList(Segmentation{Pause: 0.5}.split(shifted, plane, 1, 0)); got != "1-2 one|3-4 two" {
+		t.Errorf("with offset: %s", got)
+	}
+}
+
+func TestSentenceBreak(t *testing.T) {
+	for _, tc := range []struct {
+		prev, next string
+		want       bool
+	}{
+		{"there.", "How", true},
+		{"you?\"", "Fine", true},
+		{"e.g.", "this", false},
+		{"done!", "\"Yes", true},
+		{"終わり。", "次", true},
+		{"comma,", "And", false},
+		{"3.5", "Percent", false},
+	} {
+		if got := sentenceBreak(tc.prev, tc.next); got != tc.want {
+			t.Errorf("sentenceBreak(%q, %q) = %t", tc.prev, tc.next, got)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ErrVADUnavailable is returned by DetectSpeech when no Silero VAD model was
+// loaded. The HTTP layer maps it to 503.
+var ErrVADUnavailable = errors.New("speech detection requires silero_vad.onnx, which is not loaded")
+
+// VADOptions tune DetectSpeech. Start from DefaultVADOptions: every field is
+// used as given, zero included.
+type VADOptions struct {
+	Format     string  // file extension hint for ffmpeg, as in TranscribeOptions
+	Threshold  float64 // speech probability that starts speech
+	MinSpeech  float64 // seconds; shorter speech is dropped
+	MinSilence float64 // seconds of silence that end speech
+	SpeechPad  float64 // seconds added to both sides of each span
+}
+
+// DefaultVADOptions are the defaults of Silero's reference
+// get_speech_timestamps.
+var DefaultVADOptions = VADOptions{Threshold: 0.5, MinSpeech: 0.25, MinSilence: 0.1, SpeechPad: 0.03}
+
+// SpeechSpan is one stretch of detected speech, in seconds.
+type SpeechSpan struct {
+	Start float64
+	End   float64
+}
+
+// VADResult is the outcome of DetectSpeech: the audio length and its speech
+// spans in order. Everything between spans is non-speech.
+type VADResult struct {
+	Duration float64
+	Speech   []SpeechSpan
+}
+
+// CanDetectSpeech reports whether a Silero VAD model is loaded, i.e. whether
+// DetectSpeech can run.
+func (t *Transcriber) CanDetectSpeech() bool {
+	return t.vad != nil
+}
+
+// DetectSpeech runs the Silero VAD over the whole of audio, mixed to mono
+// 16 kHz, and returns its speech spans. Like the boundary oracle it runs
+// outside the decoder worker pool.
+func (t *Transcriber) DetectSpeech(ctx context.Context, audio []byte, opts VADOptions) (*VADResult, error) {
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	defer t.leave()
+	if t.vad == nil {
+		return nil, ErrVADUnavailable
+	}
+	samples, err := t.loadAudio(audio, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	tensors, err := t.vad.acquireTensors()
+	if err != nil {
+		return nil, err
+	}
+	defer t.vad.releaseTensors(tensors)
+
+	var st vadState
+	probs := make([]float32, 0, len(samples)/vadWindowSamples+1)
+	for i := 0; i < len(samples); i += vadWindowSamples {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		window := samples[i:min(i+vadWindowSamples, len(samples))]
+		if len(window) < vadWindowSamples {
+			// Zero-pad the tail so its last milliseconds are classified too.
+			window = append(make([]float32, 0, vadWindowSamples), window...)
+			window = window[:vadWindowSamples]
+		}
+		prob, err := t.vad.infer(&st, tensors, window)
+		if err != nil {
+			return nil, err
+		}
+		probs = append(probs, prob)
+	}
+
+	duration := float64(len(samples)) / featureSampleRate
+	return &VADResult{Duration: duration, Speech: speechSpans(probs, duration, opts)}, nil
+}
+
+// speechSpans turns per-window speech probabilities into speech spans,
+// following Silero's get_speech_timestamps: speech starts at a window at or
+// above the threshold and ends once the probability has stayed below
+// threshold-0.15 for MinSilence; spans shorter than MinSpeech are dropped and
+// the rest padded by SpeechPad, without overlapping or leaving [0, duration].
+func speechSpans(probs []float32, duration float64, opts VADOptions) []SpeechSpan {
+	threshold, minSpeech, minSilence, pad := opts.Threshold, opts.MinSpeech, opts.MinSilence, opts.SpeechPad
+	negThreshold := math.Max(threshold-0.15, 0.01)
+	const window = float64(vadWindowSamples) / featureSampleRate
+
+	var spans []SpeechSpan
+	triggered := false
+	var start, silenceStart float64
+	silent := false
+	closeSpan := func(end float64) {
+		if end-start >= minSpeech {
+			spans = append(spans, SpeechSpan{Start: start, End: end})
+		}
+		triggered, silent = false, false
+	}
+	for i, p := range probs {
+		at := float64(i) * window
+		switch {
+		case float64(p) >= threshold:
+			silent = false
+			if !triggered {
+				triggered, start = true, at
+			}
+		case triggered && float64(p) < negThreshold:
+			if !silent {
+				silent, silenceStart = true, at
+			}
+			if at+window-silenceStart >= minSilence {
+				closeSpan(silenceStart)
+			}
+		}
+	}
+	if triggered {
+		closeSpan(duration)
+	}
+
+	for i := range spans {
+		spans[i].Start = math.Max(spans[i].Start-pad, 0)
+		spans[i].End = math.Min(spans[i].End+pad, duration)
+		if i > 0 && spans[i].Start < spans[i-1].End {
+			// Split the gap the two pads would share.
+			mid := (spans[i-1].End - pad + spans[i].Start + pad) / 2
+			spans[i-1].End, spans[i].Start = mid, mid
+		}
+	}
+	return spans
+}
+
+// SpeechStream is the Silero VAD state of one live audio stream, which
+// Transcriber.StreamSpeech advances packet by packet. Not safe for
+// concurrent use.
+type SpeechStream struct {
+	format    PCMFormat
+	threshold float64
+	state     vadState
+	pending   []float32 // 16 kHz samples short of a VAD window
+	speaking  bool
+}
+
+// NewSpeechStream starts the VAD state of a stream of headerless audio in
+// format. threshold is the speech probability that starts speech, as in
+// VADOptions.
+func NewSpeechStream(format PCMFormat, threshold float64) *SpeechStream {
+	return &SpeechStream{format: format, threshold: threshold}
+}
+
+// StreamSpeech runs the Silero VAD over the next packet of st's stream and
+// reports whether it holds speech. As in DetectSpeech, speech starts at a
+// window at or above the threshold and lasts until one below
+// threshold-0.15. A packet too short to complete a window keeps the state
+// of the last one.
+func (t *Transcriber) StreamSpeech(st *SpeechStream, packet []byte) (bool, error) {
+	if err := t.enter(); err != nil {
+		return false, err
+	}
+	defer t.leave()
+	if t.vad == nil {
+		return false, ErrVADUnavailable
+	}
+	tag, bits, err := st.format.wavFormat()
+	if err != nil {
+		return false, err
+	}
+	planes, err := convertToFloat32(packet, tag, uint16(st.format.Channels), bits, ChannelMix)
+	if err != nil {
+		return false, err
+	}
+	st.pending = append(st.pending, resample(planes[0], st.format.SampleRate, featureSampleRate, ResampleLinear)...)
+	if len(st.pending) < vadWindowSamples {
+		return st.speaking, nil
+	}
+
+	tensors, err := t.vad.acquireTensors()
+	if err != nil {
+		return false, err
+	}
+	defer t.vad.releaseTensors(tensors)
+
+	speech := st.speaking
+	negThreshold := math.Max(st.threshold-0.15, 0.01)
+	n := 0
+	for ; n+vadWindowSamples <= len(st.pending); n += vadWindowSamples {
+		prob, err := t.vad.infer(&st.state, tensors, st.pending[n:n+vadWindowSamples])
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case float64(prob) >= st.threshold:
+			st.speaking = true
+		case float64(prob) < negThreshold:
+			st.speaking = false
+		}
+		speech = speech || st.speaking
+	}
+	st.pending = append(st.pending[:0], st.pending[n:]...)
+	return speech, nil
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// windows builds a probability track from runs of (probability, windows).
+func windows(runs ...float32) []float32 {
+	var probs []float32
+	for i := 0; i < len(runs); i += 2 {
+		for range int(runs[i+1]) {
+			probs = append(probs, runs[i])
+		}
+	}
+	return probs
+}
+
+func TestSpeechSpans(t *testing.T) {
+	const w = 0.032
+	opts := DefaultVADOptions
+	opts.SpeechPad = 0
+	approx := func(got, want float64) bool { return math.Abs(got-want) < 1e-6 }
+
+	// 10 silent windows, 20 speech, 10 silent: one span over the speech.
+	probs := windows(0.1, 10, 0.9, 20, 0.1, 10)
+	spans := speechSpans(probs, 40*w, opts)
+	if len(spans) != 1 || !approx(spans[0].Start, 10*w) || !approx(spans[0].End, 30*w) {
+		t.Fatalf("spans = %+v", spans)
+	}
+
+	// A dip shorter than MinSilence does not split the span.
+	probs = windows(0.9, 10, 0.1, 2, 0.9, 10)
+	if spans := speechSpans(probs, 22*w, opts); len(spans) != 1 {
+		t.Fatalf("short pause split speech: %+v", spans)
+	}
+	// A long one does.
+	probs = windows(0.9, 10, 0.1, 10, 0.9, 10)
+	if spans := speechSpans(probs, 30*w, opts); len(spans) != 2 {
+		t.Fatalf("long pause kept speech together: %+v", spans)
+	}
+
+	// Values between the two thresholds keep speech going.
+	probs = windows(0.9, 10, 0.4, 20, 0.1, 10)
+	spans = speechSpans(probs, 40*w, opts)
+	if len(spans) != 1 || !approx(spans[0].End, 30*w) {
+		t.Fatalf("hysteresis: %+v", spans)
+	}
+
+	// Blips shorter than MinSpeech are dropped; speech running to the end
+	// closes at the audio duration.
+	probs = windows(0.9, 3, 0.1, 10, 0.9, 10)
+	spans = speechSpans(probs, 23*w+0.01, opts)
+	if len(spans) != 1 || !approx(spans[0].Start, 13*w) || !approx(spans[0].End, 23*w+0.01) {
+		t.Fatalf("spans = %+v", spans)
+	}
+
+	// Padding is clamped to the audio and never makes spans overlap.
+	probs = windows(0.9, 10, 0.1, 4, 0.9, 10)
+	spans = speechSpans(probs, 24*w, VADOptions{Threshold: 0.5, MinSpeech: 0.25, MinSilence: 0.1, SpeechPad: 0.5})
+	if len(spans) != 2 || spans[0].Start != 0 || spans[1].End != 24*w || spans[0].End != spans[1].Start {
+		t.Fatalf("padded spans = %+v", spans)
+	}
+}
+
+func TestDetectSpeech_WithoutModel(t *testing.T) {
+	tr := &Transcriber{}
+	_, err := tr.DetectSpeech(t.Context(), buildMinimalWAV(t, 16000, 100), DefaultVADOptions)
+	if !errors.Is(err, ErrVADUnavailable) {
+		t.Fatalf("err = %v, want ErrVADUnavailable", err)
+	}
+}
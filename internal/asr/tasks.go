@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Tasks a model can declare in the "tasks" of config.json.
+const (
+	// TaskTranscribe writes the speech in the language it was spoken.
+	TaskTranscribe = "transcribe"
+	// TaskTranslate writes the speech in English whatever was spoken, as
+	// Whisper's translate task does: a speech translation export.
+	TaskTranslate = "translate"
+)
+
+// normalizeTasks lowercases, sorts and checks the "tasks" of config.json;
+// an empty list means transcription only, what every parakeet export does.
+func normalizeTasks(tasks []string) ([]string, error) {
+	if len(tasks) == 0 {
+		return []string{TaskTranscribe}, nil
+	}
+	out := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		task = strings.ToLower(strings.TrimSpace(task))
+		if task != TaskTranscribe && task != TaskTranslate {
+			return nil, fmt.Errorf("invalid config: unknown task %q (want %s or %s)", task, TaskTranscribe, TaskTranslate)
+		}
+		out = append(out, task)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// Translates reports whether the loaded model declares the translate task,
+// i.e. whether its transcripts are English translations.
+func (t *Transcriber) Translates() bool {
+	return slices.Contains(t.config.Tasks, TaskTranslate)
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeTasks(t *testing.T) {
+	got, err := normalizeTasks(nil)
+	if err != nil || fmt.Sprint(got) != "[transcribe]" {
+		t.Errorf("defaults = %v, %v", got, err)
+	}
+	got, err = normalizeTasks([]string{"Translate", " transcribe", "translate"})
+	if err != nil || fmt.Sprint(got) != "[transcribe translate]" {
+		t.Errorf("normalized = %v, %v", got, err)
+	}
+	if _, err := normalizeTasks([]string{"diarize"}); err == nil {
+		t.Error("unknown task accepted")
+	}
+}
+
+func TestTranslates(t *testing.T) {
+	if (&Transcriber{}).Translates() {
+		t.Error("a model without tasks translates")
+	}
+	if !(&Transcriber{config: Config{Tasks: []string{TaskTranslate}}}).Translates() {
+		t.Error("a translate model does not translate")
+	}
+}
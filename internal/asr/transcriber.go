@@ -4,16 +4,22 @@
 package asr
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	ort "github.com/yalue/onnxruntime_go"
 )
@@ -26,30 +32,159 @@ var whitespaceRegex = regexp.MustCompile(`\s{2,}`)
 
 // Fixed model dimensions for Parakeet TDT 0.6B
 const (
-	encoderDim         int64 = 1024
-	decoderStateDim    int64 = 640
-	decoderNumLayers   int64 = 2
-	numDurationClasses int64 = 5
+	encoderDim       int64 = 1024
+	decoderStateDim  int64 = 640
+	decoderNumLayers int64 = 2
 )
 
+// defaultDurationClasses is how many TDT duration classes a decoder whose
+// export leaves its output size dynamic is taken to have: parakeet's
+// durations 0 to 4.
+const defaultDurationClasses = 5
+
+// defaultMaxTokensPerStep caps the tokens one encoder frame may emit when
+// neither Options nor config.json set it.
+const defaultMaxTokensPerStep = 10
+
 type Config struct {
-	ModelType         string `json:"model_type"`
-	FeaturesSize      int    `json:"features_size"`
-	SubsamplingFactor int    `json:"subsampling_factor"`
+	ModelType         string             `json:"model_type"`
+	FeaturesSize      int                `json:"features_size"`
+	SubsamplingFactor int                `json:"subsampling_factor"`
+	Preprocessor      PreprocessorConfig `json:"preprocessor"`
+
+	// Languages lists the ISO-639-1 codes the model transcribes. Empty
+	// means the parakeet-tdt-0.6b-v3 set (defaultLanguages).
+	Languages []string `json:"languages"`
+
+	// Tasks lists what the model's output is: TaskTranscribe,
+	// TaskTranslate or both. Empty means TaskTranscribe only.
+	Tasks []string `json:"tasks"`
+
+	// Name identifies the model in /v1/models; empty means
+	// DefaultModelName. Parameters is its parameter count; zero means an
+	// estimate from the size of the weights.
+	Name       string `json:"name"`
+	Parameters int64  `json:"parameters"`
+
+	// Durations are the frames each TDT duration class advances, in the
+	// order of the joint network's duration logits (NeMo's
+	// model.durations). Empty means 0, 1, 2, ... for as many classes as
+	// the decoder outputs.
+	Durations []int `json:"durations"`
+
+	// MaxTokensPerStep caps the tokens emitted on one encoder frame
+	// (NeMo's max_symbols); zero means defaultMaxTokensPerStep.
+	// Options.MaxTokensPerStep overrides it.
+	MaxTokensPerStep int `json:"max_tokens_per_step"`
+
+	// SpecialTokens sets the text of special <...> tokens, which are
+	// otherwise dropped from the transcript: "" drops one explicitly, the
+	// token itself keeps it (a "<laughs>" tag), anything else replaces it.
+	SpecialTokens map[string]string `json:"special_tokens"`
+}
+
+// featureSampleRate is the rate every input is resampled to before feature
+// extraction (audio.go, ffmpeg.go) and the rate Silero VAD runs at.
+const featureSampleRate = 16000
+
+// PreprocessorConfig is the optional "preprocessor" object of config.json.
+// Keys follow NeMo's preprocessor config so values can be copied from a
+// model's .nemo archive; anything omitted keeps NeMo's parakeet default
+// (see DefaultMelOptions). Pointers distinguish "unset" from an explicit 0.
+// Window size and stride are in seconds, as in NeMo.
+type PreprocessorConfig struct {
+	SampleRate   *int     `json:"sample_rate"`
+	WindowSize   *float64 `json:"window_size"`
+	WindowStride *float64 `json:"window_stride"`
+	NFFT         *int     `json:"n_fft"`
+	LowFreq      *float64 `json:"lowfreq"`
+	HighFreq     *float64 `json:"highfreq"`
+
+	Preemphasis       *float64 `json:"preemph"`
+	Dither            *float64 `json:"dither"`
+	LogZeroGuardType  string   `json:"log_zero_guard_type"`
+	LogZeroGuardValue *float64 `json:"log_zero_guard_value"`
+	Normalize         string   `json:"normalize"`
+}
+
+// melOptions overlays the configured values on DefaultMelOptions and
+// validates the result. The audio pipeline resamples everything to
+// featureSampleRate, so a model expecting another rate is rejected rather
+// than silently fed mismatched features.
+func (p PreprocessorConfig) melOptions() (MelOptions, error) {
+	opts := DefaultMelOptions()
+	if p.SampleRate != nil && *p.SampleRate != featureSampleRate {
+		return MelOptions{}, fmt.Errorf("invalid preprocessor config: sample_rate %d is not supported (audio is resampled to %d Hz)", *p.SampleRate, featureSampleRate)
+	}
+	if p.WindowSize != nil {
+		opts.WinLength = int(math.Round(*p.WindowSize * featureSampleRate))
+	}
+	if p.WindowStride != nil {
+		opts.HopLength = int(math.Round(*p.WindowStride * featureSampleRate))
+	}
+	if p.NFFT != nil {
+		opts.NFFT = *p.NFFT
+	}
+	if p.LowFreq != nil {
+		opts.FMin = *p.LowFreq
+	}
+	if p.HighFreq != nil {
+		opts.FMax = *p.HighFreq
+	}
+	if p.Preemphasis != nil {
+		opts.Preemphasis = *p.Preemphasis
+	}
+	if p.Dither != nil {
+		opts.Dither = *p.Dither
+	}
+	if p.LogZeroGuardType != "" {
+		opts.LogZeroGuard = p.LogZeroGuardType
+	}
+	if p.LogZeroGuardValue != nil {
+		opts.LogZeroGuardValue = *p.LogZeroGuardValue
+	}
+	if p.Normalize != "" {
+		opts.Normalize = p.Normalize
+	}
+	if err := opts.validate(featureSampleRate); err != nil {
+		return MelOptions{}, fmt.Errorf("invalid preprocessor config: %w", err)
+	}
+	return opts, nil
 }
 
 // decoderWorker holds a pre-initialized decoder session with reusable tensors.
 // Each worker is owned by at most one goroutine at a time via the pool channel.
+// With decoder batching it has no session: step hands its tensors to the
+// stepBatcher instead.
 type decoderWorker struct {
 	session   *ort.AdvancedSession
-	encOut    *ort.Tensor[float32]
+	encOut    *floatTensor
 	targets   *ort.Tensor[int32]
 	targetLen *ort.Tensor[int32]
-	state1In  *ort.Tensor[float32]
-	state2In  *ort.Tensor[float32]
-	output    *ort.Tensor[float32]
-	state1Out *ort.Tensor[float32]
-	state2Out *ort.Tensor[float32]
+	state1In  *floatTensor
+	state2In  *floatTensor
+	output    *floatTensor
+	state1Out *floatTensor
+	state2Out *floatTensor
+	step      decodeStep
+}
+
+// decoderFloatIO names the decoder's float inputs and outputs, which an fp16
+// export may exchange as float16.
+var decoderFloatIO = []string{"encoder_outputs", "input_states_1", "input_states_2", "outputs", "output_states_1", "output_states_2"}
+
+// run runs one decoder step over the tensors' current contents.
+func (w *decoderWorker) run() error {
+	w.encOut.toModel()
+	w.state1In.toModel()
+	w.state2In.toModel()
+	if err := w.session.Run(); err != nil {
+		return err
+	}
+	w.output.fromModel()
+	w.state1Out.fromModel()
+	w.state2Out.fromModel()
+	return nil
 }
 
 func (w *decoderWorker) destroy() {
@@ -82,13 +217,15 @@ func (w *decoderWorker) destroy() {
 	}
 }
 
-func newDecoderWorker(decoderPath string, vocabSize int, sessOpts *ort.SessionOptions) (*decoderWorker, error) {
+// newDecoderWorker creates a worker whose float tensors have the element
+// types in io, keyed by decoderFloatIO names. outputDim is the size of the
+// joint network's output: the vocabulary with the blank, then the duration
+// classes. A batched worker gets no session of its own.
+func newDecoderWorker(decoder onnxModel, outputDim int64, io map[string]ort.TensorElementDataType, sessOpts *ort.SessionOptions, batched bool) (*decoderWorker, error) {
 	w := &decoderWorker{}
 	var err error
 
-	outputDim := int64(vocabSize) + numDurationClasses
-
-	w.encOut, err = ort.NewEmptyTensor[float32](ort.NewShape(1, encoderDim, 1))
+	w.encOut, err = newEmptyFloatTensor(io["encoder_outputs"], ort.NewShape(1, encoderDim, 1))
 	if err != nil {
 		w.destroy()
 		return nil, fmt.Errorf("create encOut tensor: %w", err)
@@ -106,42 +243,55 @@ func newDecoderWorker(decoderPath string, vocabSize int, sessOpts *ort.SessionOp
 		return nil, fmt.Errorf("create targetLen tensor: %w", err)
 	}
 
-	w.state1In, err = ort.NewEmptyTensor[float32](ort.NewShape(decoderNumLayers, 1, decoderStateDim))
+	w.state1In, err = newEmptyFloatTensor(io["input_states_1"], ort.NewShape(decoderNumLayers, 1, decoderStateDim))
 	if err != nil {
 		w.destroy()
 		return nil, fmt.Errorf("create state1In tensor: %w", err)
 	}
 
-	w.state2In, err = ort.NewEmptyTensor[float32](ort.NewShape(decoderNumLayers, 1, decoderStateDim))
+	w.state2In, err = newEmptyFloatTensor(io["input_states_2"], ort.NewShape(decoderNumLayers, 1, decoderStateDim))
 	if err != nil {
 		w.destroy()
 		return nil, fmt.Errorf("create state2In tensor: %w", err)
 	}
 
-	w.output, err = ort.NewEmptyTensor[float32](ort.NewShape(1, 1, 1, outputDim))
+	w.output, err = newEmptyFloatTensor(io["outputs"], ort.NewShape(1, 1, 1, outputDim))
 	if err != nil {
 		w.destroy()
 		return nil, fmt.Errorf("create output tensor: %w", err)
 	}
 
-	w.state1Out, err = ort.NewEmptyTensor[float32](ort.NewShape(decoderNumLayers, 1, decoderStateDim))
+	w.state1Out, err = newEmptyFloatTensor(io["output_states_1"], ort.NewShape(decoderNumLayers, 1, decoderStateDim))
 	if err != nil {
 		w.destroy()
 		return nil, fmt.Errorf("create state1Out tensor: %w", err)
 	}
 
-	w.state2Out, err = ort.NewEmptyTensor[float32](ort.NewShape(decoderNumLayers, 1, decoderStateDim))
+	w.state2Out, err = newEmptyFloatTensor(io["output_states_2"], ort.NewShape(decoderNumLayers, 1, decoderStateDim))
 	if err != nil {
 		w.destroy()
 		return nil, fmt.Errorf("create state2Out tensor: %w", err)
 	}
 
-	w.session, err = ort.NewAdvancedSession(
-		decoderPath,
+	w.step = decodeStep{
+		encFrame:  w.encOut.GetData(),
+		target:    w.targets.GetData(),
+		state1In:  w.state1In.GetData(),
+		state2In:  w.state2In.GetData(),
+		output:    w.output.GetData(),
+		state1Out: w.state1Out.GetData(),
+		state2Out: w.state2Out.GetData(),
+		done:      make(chan error, 1),
+	}
+	if batched {
+		return w, nil
+	}
+
+	w.session, err = decoder.newAdvancedSession(
 		[]string{"encoder_outputs", "targets", "target_length", "input_states_1", "input_states_2"},
 		[]string{"outputs", "output_states_1", "output_states_2"},
-		[]ort.ArbitraryTensor{w.encOut, w.targets, w.targetLen, w.state1In, w.state2In},
-		[]ort.ArbitraryTensor{w.output, w.state1Out, w.state2Out},
+		[]ort.ArbitraryTensor{w.encOut.value(), w.targets, w.targetLen, w.state1In.value(), w.state2In.value()},
+		[]ort.ArbitraryTensor{w.output.value(), w.state1Out.value(), w.state2Out.value()},
 		sessOpts,
 	)
 	if err != nil {
@@ -152,6 +302,54 @@ func newDecoderWorker(decoderPath string, vocabSize int, sessOpts *ort.SessionOp
 	return w, nil
 }
 
+// decoderLogits reads the size of the joint network's "outputs" from the
+// decoder model: the vocabulary with the blank, then the TDT duration
+// classes. It is 0 when the export leaves that dimension dynamic.
+func decoderLogits(decoder onnxModel, opts *ort.SessionOptions) (int64, error) {
+	_, outputs, err := decoder.inputOutputInfo(opts)
+	if err != nil {
+		return 0, err
+	}
+	for _, out := range outputs {
+		if out.Name != "outputs" {
+			continue
+		}
+		if len(out.Dimensions) == 0 {
+			return 0, nil
+		}
+		return max(out.Dimensions[len(out.Dimensions)-1], 0), nil
+	}
+	return 0, fmt.Errorf("model has no output %q", "outputs")
+}
+
+// resolveDurations returns the frames each duration class advances. The
+// classes are what the decoder outputs past the vocabulary (logits, when
+// known) and config.json durations, which must agree; failing both,
+// defaultDurationClasses.
+func resolveDurations(logits int64, vocabSize int, configured []int) ([]int, error) {
+	classes := 0
+	if logits > 0 {
+		classes = int(logits) - vocabSize
+		if classes < 1 {
+			return nil, fmt.Errorf("decoder outputs %d logits, no more than the %d tokens of the vocabulary: it does not belong to this model", logits, vocabSize)
+		}
+	}
+	if len(configured) > 0 {
+		if classes > 0 && len(configured) != classes {
+			return nil, fmt.Errorf("invalid config: %d durations, but the decoder outputs %d duration classes", len(configured), classes)
+		}
+		return configured, nil
+	}
+	if classes == 0 {
+		classes = defaultDurationClasses
+	}
+	durations := make([]int, classes)
+	for i := range durations {
+		durations[i] = i
+	}
+	return durations, nil
+}
+
 // Provider selects the ONNX Runtime execution provider used for inference.
 type Provider string
 
@@ -180,11 +378,23 @@ type GPUConfig struct {
 	DeviceID int
 }
 
+// ThreadConfig sizes the ONNX Runtime thread pools of every session.
+// IntraOp is the threads one operator runs on; zero lets ONNX Runtime pick
+// one per core, which oversubscribes the CPU when several workers decode at
+// once. NoSpin makes idle pool threads sleep instead of busy-waiting for the
+// next operator, trading a little latency for CPU time and power.
+type ThreadConfig struct {
+	IntraOp int
+	NoSpin  bool
+}
+
 type Transcriber struct {
 	config             Config
 	vocab              map[int]string
+	specialTokens      map[int]string // config.json special_tokens, by id
 	vocabSize          int
 	blankIdx           int
+	durations          []int
 	maxTokensPerStep   int
 	chunkFrames        int64
 	overlapFrames      int64
@@ -193,9 +403,40 @@ type Transcriber struct {
 	disableMelChunking bool
 	mel                *MelFilterbank
 	encoder            *ort.DynamicAdvancedSession
+	encoderIO          map[string]ort.TensorElementDataType // audio_signal, outputs
+	encoderCache       *encoderCache                        // nil when off
 	vad                *sileroVAD
-	decoderPool        chan *decoderWorker
+	denoiser           *denoiser
+	decoderPool        *workerPool
+	batcher            *stepBatcher // nil when decoder batching is off
 	ffmpeg             *ffmpegConverter
+	resampleQuality    ResampleQuality
+
+	// lifecycle is held shared by every call using the ORT sessions and
+	// exclusively by Close, so sessions are never destroyed under a running
+	// call. closed makes calls after Close fail with ErrClosed.
+	lifecycle sync.RWMutex
+	closed    bool
+
+	// ortAcquired is set once the Transcriber holds a reference on the
+	// ONNX Runtime environment, released by Close.
+	ortAcquired bool
+
+	// Reported by Info.
+	provider       Provider
+	runtimeVersion string
+	modelFiles     []string
+	models         modelStore
+	precision      PrecisionConfig // as loaded, never auto
+	parameters     int64
+	modified       time.Time
+
+	// budget, when set, holds budgetHeld (the weights and the encoder
+	// cache) while the Transcriber is open and is reserved from by each
+	// transcription.
+	budget      *MemoryBudget
+	weightBytes int64
+	budgetHeld  int64
 }
 
 // Options groups optional knobs passed to NewTranscriber. Zero values keep
@@ -206,6 +447,58 @@ type Options struct {
 	GPU      GPUConfig
 	Chunk    ChunkConfig
 	Boundary BoundaryConfig
+
+	// Resample selects the resampler for non-16 kHz WAV input. Empty means
+	// ResampleMedium.
+	Resample ResampleQuality
+
+	// Denoise locates the optional noise-suppression model used by requests
+	// that set TranscribeOptions.Denoise.
+	Denoise DenoiseConfig
+
+	// Models, when set, holds the model files in place of the models
+	// directory: an OpenModelArchive bundle, or an embed.FS in a custom
+	// binary. ONNX files are passed to ONNX Runtime from memory, so an
+	// encoder with external data (.onnx.data) cannot be loaded this way.
+	Models fs.FS
+
+	// Runtime downloads ONNX Runtime when it is not installed.
+	Runtime RuntimeConfig
+
+	// Verify is what happens when the files listed in models.lock, in the
+	// models directory or Models, do not match it. Empty means VerifyError.
+	Verify VerifyMode
+
+	// QueueLimits caps the decodes waiting for a worker per priority.
+	QueueLimits QueueLimits
+
+	// Precision selects the encoder and decoder exports to load.
+	Precision PrecisionConfig
+
+	// Threads sizes the ONNX Runtime thread pools.
+	Threads ThreadConfig
+
+	// MelFloat32 computes the features in float32 instead of float64; see
+	// MelFilterbank.SetFloat32.
+	MelFloat32 bool
+
+	// EncoderCache keeps recent encoder outputs for reuse; off when its TTL
+	// is zero.
+	EncoderCache EncoderCacheConfig
+
+	// MemoryBudget, when set, bounds the memory of transcriptions; see
+	// MemoryBudget. Several Transcribers may share one. Long-audio windows
+	// are shrunk for Workers of them to fit.
+	MemoryBudget *MemoryBudget
+
+	// MaxTokensPerStep is the default cap on tokens emitted on one encoder
+	// frame, overriding config.json; zero keeps the model's. Requests
+	// override it with DecodingOptions.MaxTokensPerStep.
+	MaxTokensPerStep int
+
+	// DecoderBatch runs the decoder steps of concurrent decodes in shared
+	// ONNX calls.
+	DecoderBatch DecoderBatchConfig
 }
 
 // ChunkConfig sets the sliding-window sizes that keep long audio within the
@@ -229,14 +522,16 @@ type BoundaryConfig struct {
 }
 
 // buildSessionOptions returns the ONNX Runtime session options for the
-// configured execution provider. It returns (nil, nil) for the CPU provider so
-// sessions are created with default CPU behavior, identical to the pre-GPU code
-// path. For a GPU provider it returns a configured *ort.SessionOptions that the
-// caller owns and must Destroy after all sessions are created (ORT copies the
-// options into each session at creation time, so the object is safe to free
-// once sessions exist). A future execution provider is added here.
-func buildSessionOptions(gpu GPUConfig) (*ort.SessionOptions, error) {
-	if gpu.Provider == ProviderCPU || gpu.Provider == "" {
+// configured execution provider and threads. It returns (nil, nil) for the
+// CPU provider with default threads so sessions are created with default CPU
+// behavior, identical to the pre-GPU code path. Otherwise it returns a
+// configured *ort.SessionOptions that the caller owns and must Destroy after
+// all sessions are created (ORT copies the options into each session at
+// creation time, so the object is safe to free once sessions exist). A
+// future execution provider is added here.
+func buildSessionOptions(gpu GPUConfig, threads ThreadConfig) (*ort.SessionOptions, error) {
+	cpu := gpu.Provider == ProviderCPU || gpu.Provider == ""
+	if cpu && threads == (ThreadConfig{}) {
 		return nil, nil
 	}
 
@@ -244,6 +539,13 @@ func buildSessionOptions(gpu GPUConfig) (*ort.SessionOptions, error) {
 	if err != nil {
 		return nil, fmt.Errorf("create session options: %w", err)
 	}
+	if err := setThreads(opts, threads); err != nil {
+		opts.Destroy()
+		return nil, err
+	}
+	if cpu {
+		return opts, nil
+	}
 
 	switch gpu.Provider {
 	case ProviderCUDA:
@@ -279,20 +581,42 @@ func buildSessionOptions(gpu GPUConfig) (*ort.SessionOptions, error) {
 	return opts, nil
 }
 
-// NewTranscriber loads models and initializes the decoder worker pool.
-// When opts.FFmpeg.Enabled is true and the ffmpeg binary is resolvable,
+// setThreads applies threads to opts. The spinning switches are ONNX
+// Runtime session config keys.
+func setThreads(opts *ort.SessionOptions, threads ThreadConfig) error {
+	if threads.IntraOp > 0 {
+		if err := opts.SetIntraOpNumThreads(threads.IntraOp); err != nil {
+			return fmt.Errorf("set intra-op threads: %w", err)
+		}
+	}
+	if threads.NoSpin {
+		for _, key := range []string{"session.intra_op.allow_spinning", "session.inter_op.allow_spinning"} {
+			if err := opts.AddSessionConfigEntry(key, "0"); err != nil {
+				return fmt.Errorf("set %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewTranscriber loads models from modelsDir, or from opts.Models when set,
+// and initializes the decoder worker pool. When opts.FFmpeg.Enabled is true and the ffmpeg binary is resolvable,
 // non-WAV inputs will be transcoded on the fly. Otherwise, only WAV is
 // accepted and non-WAV inputs return ErrUnsupportedAudio.
 func NewTranscriber(modelsDir string, workers int, opts Options) (*Transcriber, error) {
 	t := &Transcriber{
-		maxTokensPerStep: 10,
-		blankIdx:         8192,
-		ffmpeg:           newFFmpegConverter(opts.FFmpeg),
+		ffmpeg:          newFFmpegConverter(opts.FFmpeg),
+		resampleQuality: opts.Resample,
 	}
+	if t.resampleQuality == "" {
+		t.resampleQuality = ResampleMedium
+	}
+	t.models = modelStore{dir: modelsDir, fsys: opts.Models}
+	models := t.models
 
 	// Load config
-	configPath := filepath.Join(modelsDir, "config.json")
-	configData, err := os.ReadFile(configPath)
+	configPath := models.path("config.json")
+	configData, err := models.readFile("config.json")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
@@ -306,15 +630,45 @@ func NewTranscriber(modelsDir string, workers int, opts Options) (*Transcriber,
 	if t.config.SubsamplingFactor == 0 {
 		t.config.SubsamplingFactor = 8
 	}
+	if t.config.Languages, err = normalizeLanguages(t.config.Languages); err != nil {
+		return nil, err
+	}
+	if t.config.Tasks, err = normalizeTasks(t.config.Tasks); err != nil {
+		return nil, err
+	}
+	if t.config.Parameters < 0 {
+		return nil, fmt.Errorf("invalid config: parameters must not be negative")
+	}
+	for _, d := range t.config.Durations {
+		if d < 0 {
+			return nil, fmt.Errorf("invalid config: durations must not be negative")
+		}
+	}
+	for token := range t.config.SpecialTokens {
+		if !isSpecialToken(token) {
+			return nil, fmt.Errorf("invalid config: special_tokens key %q is not a <...> token", token)
+		}
+	}
+	t.maxTokensPerStep = cmp.Or(opts.MaxTokensPerStep, t.config.MaxTokensPerStep, defaultMaxTokensPerStep)
+	if t.maxTokensPerStep < 1 || t.maxTokensPerStep > MaxTokensPerStepLimit {
+		return nil, fmt.Errorf("invalid max tokens per step %d (between 1 and %d)", t.maxTokensPerStep, MaxTokensPerStepLimit)
+	}
 
 	// Load vocab
-	vocabPath := filepath.Join(modelsDir, "vocab.txt")
-	if err := t.loadVocab(vocabPath); err != nil {
+	vocabFile, tokens, err := readVocab(models)
+	if err != nil {
 		return nil, fmt.Errorf("failed to load vocab: %w", err)
 	}
+	vocabPath := models.path(vocabFile)
+	t.setVocab(tokens)
 
 	// Initialize mel filterbank
-	t.mel = NewMelFilterbank(t.config.FeaturesSize, 16000)
+	melOpts, err := t.config.Preprocessor.melOptions()
+	if err != nil {
+		return nil, err
+	}
+	t.mel = NewMelFilterbank(t.config.FeaturesSize, featureSampleRate, melOpts)
+	t.mel.SetFloat32(opts.MelFloat32)
 
 	// Resolve chunk sizes (seconds to mel frames) and reject anything that
 	// would overrun the model's frame limit.
@@ -338,55 +692,83 @@ func NewTranscriber(modelsDir string, workers int, opts Options) (*Transcriber,
 		}
 	}
 
+	// Check the model files against models.lock before ORT reads them, so a
+	// truncated download fails with a clear error instead of a parse error.
+	verify := opts.Verify
+	if verify == "" {
+		verify = VerifyError
+	}
+	if err := verifyModels(models, verify); err != nil {
+		return nil, err
+	}
+
 	// Initialize ONNX Runtime
-	libPath := os.Getenv("ONNXRUNTIME_LIB")
-	if libPath == "" {
-		commonPaths := []string{
-			"/usr/lib/libonnxruntime.so",
-			"/usr/lib/x86_64-linux-gnu/libonnxruntime.so",
-			"/usr/local/lib/libonnxruntime.so",
-			"/opt/onnxruntime/lib/libonnxruntime.so",
-			"./libonnxruntime.so",
-			"libonnxruntime.so.1.25.1",
-		}
-		for _, p := range commonPaths {
-			if _, err := os.Stat(p); err == nil {
-				libPath = p
-				break
-			}
-		}
+	libPath, err := findONNXRuntime()
+	if err != nil && opts.Runtime.Download {
+		libPath, err = downloadONNXRuntime(opts.Runtime, runtime.GOOS, runtime.GOARCH, opts.GPU.Provider == ProviderCUDA)
 	}
-	if libPath == "" {
-		return nil, fmt.Errorf("ONNX Runtime library not found. Set ONNXRUNTIME_LIB env var or install libonnxruntime")
+	if err != nil {
+		return nil, err
 	}
 
-	ort.SetSharedLibraryPath(libPath)
-	if err := ort.InitializeEnvironment(); err != nil {
+	if err := acquireONNXRuntime(libPath); err != nil {
 		return nil, fmt.Errorf("failed to initialize ONNX Runtime: %w", err)
 	}
-
-	// Resolve encoder path
-	encoderPath := filepath.Join(modelsDir, "encoder-model.int8.onnx")
-	if _, err := os.Stat(encoderPath); os.IsNotExist(err) {
-		encoderPath = filepath.Join(modelsDir, "encoder-model.onnx")
-		if _, err := os.Stat(encoderPath); os.IsNotExist(err) {
-			return nil, fmt.Errorf("encoder model not found. Download from https://huggingface.co/istupakov/parakeet-tdt-0.6b-v3-onnx")
+	t.ortAcquired = true
+	loaded := false
+	defer func() {
+		if !loaded {
+			t.Close()
 		}
+	}()
+	t.runtimeVersion = ort.GetVersion()
+	t.provider = provider(opts.GPU)
+
+	// Resolve the model files for the configured precisions.
+	encoderFile, encoderPrecision, err := resolveModelFile(models, "encoder", "encoder-model", opts.Precision.Encoder)
+	if err != nil {
+		return nil, err
+	}
+	encoderPath := models.path(encoderFile)
+	decoderFile, decoderPrecision, err := resolveModelFile(models, "decoder", "decoder_joint-model", opts.Precision.Decoder)
+	if err != nil {
+		return nil, err
 	}
+	decoderPath := models.path(decoderFile)
+	t.precision = PrecisionConfig{Encoder: encoderPrecision, Decoder: decoderPrecision}
 
-	// Resolve decoder path
-	decoderPath := filepath.Join(modelsDir, "decoder_joint-model.int8.onnx")
-	if _, err := os.Stat(decoderPath); os.IsNotExist(err) {
-		decoderPath = filepath.Join(modelsDir, "decoder_joint-model.onnx")
-		if _, err := os.Stat(decoderPath); os.IsNotExist(err) {
-			return nil, fmt.Errorf("decoder model not found. Download from https://huggingface.co/istupakov/parakeet-tdt-0.6b-v3-onnx")
+	t.modelFiles = []string{
+		configPath,
+		vocabPath,
+		encoderPath,
+	}
+	if models.exists(encoderFile + ".data") {
+		if opts.Models != nil {
+			return nil, fmt.Errorf("%s has external data (%s.data), which cannot be loaded from memory; bundle the int8 encoder or use a models directory", encoderFile, encoderFile)
 		}
+		t.modelFiles = append(t.modelFiles, encoderPath+".data")
 	}
+	t.modelFiles = append(t.modelFiles, decoderPath)
+	t.describeModel(models, []string{"config.json", vocabFile}, map[string]Precision{
+		encoderFile:           encoderPrecision,
+		encoderFile + ".data": encoderPrecision,
+		decoderFile:           decoderPrecision,
+	})
 
-	// Build execution-provider session options. nil for CPU (default behavior);
-	// a configured object for GPU that we own and destroy once every session
-	// below has been created (ORT copies options into each session).
-	sessOpts, err := buildSessionOptions(opts.GPU)
+	encoderModel, err := models.model(encoderFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encoder model: %w", err)
+	}
+	decoderModel, err := models.model(decoderFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decoder model: %w", err)
+	}
+
+	// Build execution-provider session options. nil for CPU with default
+	// threads (default behavior); otherwise a configured object that we own
+	// and destroy once every session below has been created (ORT copies
+	// options into each session).
+	sessOpts, err := buildSessionOptions(opts.GPU, opts.Threads)
 	if err != nil {
 		return nil, fmt.Errorf("failed to configure execution provider: %w", err)
 	}
@@ -394,13 +776,30 @@ func NewTranscriber(modelsDir string, workers int, opts Options) (*Transcriber,
 		defer sessOpts.Destroy()
 	}
 
+	// An fp16 export may exchange float16 tensors; they are converted from
+	// and to float32 at the session boundary (see float16.go).
+	t.encoderIO, err = floatIOTypes(encoderModel, encoderPrecision, sessOpts, "audio_signal", "outputs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect encoder model: %w", err)
+	}
+	decoderIO, err := floatIOTypes(decoderModel, decoderPrecision, sessOpts, decoderFloatIO...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect decoder model: %w", err)
+	}
+	logits, err := decoderLogits(decoderModel, sessOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect decoder model: %w", err)
+	}
+	if t.durations, err = resolveDurations(logits, t.vocabSize, t.config.Durations); err != nil {
+		return nil, err
+	}
+
 	// Encoder runs as a single long-lived dynamic session reused across requests.
 	// Input/output shapes vary with audio length, so we pass freshly shaped
 	// tensors to each Run rather than rebuilding the session. ORT Run is
 	// thread-safe on a shared session and every request supplies its own
 	// tensors, so this is safe under the concurrent decoder worker model.
-	t.encoder, err = ort.NewDynamicAdvancedSession(
-		encoderPath,
+	t.encoder, err = encoderModel.newDynamicSession(
 		[]string{"audio_signal", "length"},
 		[]string{"outputs", "encoded_lengths"},
 		sessOpts,
@@ -409,19 +808,39 @@ func NewTranscriber(modelsDir string, workers int, opts Options) (*Transcriber,
 		return nil, fmt.Errorf("failed to create encoder session: %w", err)
 	}
 
+	// With decoder batching, one session runs the steps of every worker.
+	outputDim := int64(t.vocabSize + len(t.durations))
+	if t.batcher, err = newSessionBatcher(decoderModel, opts.DecoderBatch, outputDim, decoderIO, sessOpts); err != nil {
+		return nil, fmt.Errorf("failed to set up decoder batching: %w", err)
+	}
+
 	// Create decoder worker pool — each worker owns a persistent session and
 	// pre-allocated tensors. Workers are acquired per request and returned after.
 	if workers < 1 {
 		workers = 1
 	}
-	t.decoderPool = make(chan *decoderWorker, workers)
+	t.decoderPool = newWorkerPool(opts.QueueLimits)
 	for i := 0; i < workers; i++ {
-		w, err := newDecoderWorker(decoderPath, t.vocabSize, sessOpts)
+		w, err := newDecoderWorker(decoderModel, outputDim, decoderIO, sessOpts, t.batcher != nil)
 		if err != nil {
-			t.Close()
 			return nil, fmt.Errorf("failed to create decoder worker %d: %w", i, err)
 		}
-		t.decoderPool <- w
+		t.decoderPool.add(w)
+	}
+
+	t.encoderCache = newEncoderCache(opts.EncoderCache)
+	if opts.MemoryBudget != nil {
+		held := t.weightBytes
+		if t.encoderCache != nil {
+			held += t.encoderCache.maxBytes
+		}
+		if err := opts.MemoryBudget.holdWeights(held); err != nil {
+			return nil, err
+		}
+		t.budget, t.budgetHeld = opts.MemoryBudget, held
+		if err := t.fitChunkToBudget(t.budget, workers); err != nil {
+			return nil, err
+		}
 	}
 
 	// Load the Silero VAD model for chunk-boundary selection. It is only useful
@@ -431,31 +850,71 @@ func NewTranscriber(modelsDir string, workers int, opts Options) (*Transcriber,
 	// corrupt model surfaces loudly at startup.
 	if t.longAudio && !t.disableVADChunking {
 		vadPath := opts.Boundary.VADModelPath
+		var vadModel onnxModel
 		if vadPath == "" {
-			vadPath = filepath.Join(modelsDir, "silero_vad.onnx")
+			vadPath = models.path("silero_vad.onnx")
+			vadModel, err = models.model("silero_vad.onnx")
+		} else {
+			vadModel, err = diskModel(vadPath)
+		}
+		var vad *sileroVAD
+		if err == nil {
+			vad, err = newSileroVAD(vadModel, sessOpts)
 		}
-		vad, err := newSileroVAD(vadPath, sessOpts)
 		switch {
 		case err == nil:
 			t.vad = vad
+			t.modelFiles = append(t.modelFiles, vadPath)
 		case os.IsNotExist(err):
 			slog.Warn("VAD model not found, chunk boundaries fall back to mel energy",
 				"path", vadPath)
 		default:
-			t.Close()
 			return nil, fmt.Errorf("failed to load Silero VAD model: %w", err)
 		}
 	}
 
+	// The denoise model is optional and opt-in per request; a missing file
+	// only means denoise requests are refused with ErrDenoiseUnavailable.
+	denoisePath := opts.Denoise.ModelPath
+	var denoiseModel onnxModel
+	if denoisePath == "" {
+		denoisePath = models.path("denoise.onnx")
+		denoiseModel, err = models.model("denoise.onnx")
+	} else {
+		denoiseModel, err = diskModel(denoisePath)
+	}
+	var dn *denoiser
+	if err == nil {
+		dn, err = newDenoiser(denoiseModel, sessOpts)
+	}
+	switch {
+	case err == nil:
+		t.denoiser = dn
+		t.modelFiles = append(t.modelFiles, denoisePath)
+	case os.IsNotExist(err):
+		if opts.Denoise.ModelPath != "" {
+			slog.Warn("denoise model not found, denoise requests will be rejected", "path", denoisePath)
+		}
+	default:
+		return nil, fmt.Errorf("failed to load denoise model: %w", err)
+	}
+
 	slog.Info("transcriber initialized",
 		"workers", workers,
 		"provider", string(provider(opts.GPU)),
 		"encoder", filepath.Base(encoderPath),
 		"decoder", filepath.Base(decoderPath),
+		"encoderPrecision", encoderPrecision,
+		"decoderPrecision", decoderPrecision,
 		"vocabSize", t.vocabSize,
+		"blank", t.blankIdx,
+		"durations", t.durations,
+		"decoderBatch", opts.DecoderBatch.Size,
 		"vad", t.vad != nil,
+		"denoise", t.denoiser != nil,
 	)
 
+	loaded = true
 	return t, nil
 }
 
@@ -467,63 +926,112 @@ func provider(gpu GPUConfig) Provider {
 	return gpu.Provider
 }
 
-func (t *Transcriber) loadVocab(path string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	t.vocab = make(map[int]string)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		token := parts[0]
-		id, err := strconv.Atoi(parts[1])
-		if err != nil {
-			continue
+// setVocab takes the tokens of the vocabulary file (see vocabFormats),
+// with word-boundary marks (U+2581) translated to spaces and byte-fallback
+// tokens (<0x00> to <0xFF>) to the raw byte they stand for. The blank is the
+// <blk> token; a vocabulary without one gets it after its last token, where
+// NeMo puts it. vocabSize counts the blank.
+func (t *Transcriber) setVocab(tokens map[int]string) {
+	t.vocab = make(map[int]string, len(tokens))
+	t.blankIdx = -1
+	maxID := -1
+	for id, token := range tokens {
+		if b, ok := byteToken(token); ok {
+			token = string([]byte{b})
+		} else {
+			token = strings.ReplaceAll(token, "▁", " ")
 		}
-		token = strings.ReplaceAll(token, "▁", " ")
 		t.vocab[id] = token
 		if token == "<blk>" {
 			t.blankIdx = id
 		}
+		maxID = max(maxID, id)
+	}
+	t.specialTokens = make(map[int]string)
+	for id, token := range tokens {
+		if text, ok := t.config.SpecialTokens[token]; ok {
+			t.specialTokens[id] = text
+		}
+	}
+	if len(t.specialTokens) < len(t.config.SpecialTokens) {
+		slog.Warn("some special_tokens of config.json are not in the vocabulary", "configured", len(t.config.SpecialTokens), "found", len(t.specialTokens))
 	}
-	t.vocabSize = len(t.vocab)
+	if t.blankIdx < 0 {
+		t.blankIdx = maxID + 1
+	}
+	t.vocabSize = max(maxID, t.blankIdx) + 1
 
 	if DebugMode {
 		slog.Debug("vocab loaded", "tokens", t.vocabSize, "blankIdx", t.blankIdx)
 	}
+}
+
+// errMaxProcessing is the cause of a transcription's context ending at
+// TranscribeOptions.MaxProcessing.
+var errMaxProcessing = errors.New("max processing time exceeded")
+
+// outOfTime reports whether err stopped a transcription because it ran past
+// TranscribeOptions.MaxProcessing, rather than because the caller gave up.
+func outOfTime(ctx context.Context, err error) bool {
+	return err != nil && errors.Is(context.Cause(ctx), errMaxProcessing)
+}
+
+// ErrClosed is returned by calls made after Close.
+var ErrClosed = errors.New("transcriber is closed")
+
+// enter registers a call that uses the ORT sessions; leave ends it.
+func (t *Transcriber) enter() error {
+	t.lifecycle.RLock()
+	if t.closed {
+		t.lifecycle.RUnlock()
+		return ErrClosed
+	}
+	return nil
+}
 
-	return scanner.Err()
+func (t *Transcriber) leave() {
+	t.lifecycle.RUnlock()
 }
 
-// Close releases the encoder session, all pool workers, and the ONNX Runtime
-// environment. Safe to call after requests have run.
+// Close waits for running calls to finish, then releases the encoder
+// session, all pool workers, and the ONNX Runtime environment. Later calls
+// fail with ErrClosed; closing twice is a no-op.
 func (t *Transcriber) Close() {
+	t.lifecycle.Lock()
+	defer t.lifecycle.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
 	if t.encoder != nil {
 		t.encoder.Destroy()
-		t.encoder = nil
 	}
 	if t.vad != nil {
 		t.vad.destroy()
-		t.vad = nil
+	}
+	if t.denoiser != nil {
+		t.denoiser.destroy()
+	}
+	if t.batcher != nil {
+		t.batcher.close()
 	}
 	if t.decoderPool != nil {
-		close(t.decoderPool)
-		for w := range t.decoderPool {
-			w.destroy()
-		}
+		t.decoderPool.destroy()
+	}
+	if t.budget != nil {
+		t.budget.releaseWeights(t.budgetHeld)
+	}
+	if t.ortAcquired {
+		releaseONNXRuntime()
 	}
-	ort.DestroyEnvironment()
 }
 
 func (t *Transcriber) Transcribe(ctx context.Context, audioData []byte, format, language string) (string, error) {
-	return t.transcribe(ctx, audioData, format, language, nil)
+	res, err := t.TranscribeWithOptions(ctx, audioData, TranscribeOptions{Format: format, Language: language}, nil)
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
 }
 
 // TranscribeStream behaves like Transcribe but invokes emit with each new
@@ -535,42 +1043,236 @@ func (t *Transcriber) Transcribe(ctx context.Context, audioData []byte, format,
 // concatenation by surrounding/duplicate spaces only.
 // emit is always called from the same goroutine that called TranscribeStream.
 func (t *Transcriber) TranscribeStream(ctx context.Context, audioData []byte, format, language string, emit func(delta string)) (string, error) {
-	return t.transcribe(ctx, audioData, format, language, emit)
+	res, err := t.TranscribeWithOptions(ctx, audioData, TranscribeOptions{Format: format, Language: language}, emit)
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
 }
 
-// transcribe is the shared implementation. When emit is non-nil, decoded text
-// is streamed delta by delta as tokens are produced.
-func (t *Transcriber) transcribe(ctx context.Context, audioData []byte, format, language string, emit func(delta string)) (string, error) {
+// TranscribeWithOptions is the full entry point behind Transcribe and
+// TranscribeStream: it applies the per-request options and returns the
+// transcript with its segments. When emit is non-nil, decoded text is
+// streamed delta by delta as in TranscribeStream; per-channel transcription
+// merges channels after decoding and therefore cannot be streamed.
+func (t *Transcriber) TranscribeWithOptions(ctx context.Context, audioData []byte, opts TranscribeOptions, emit func(delta string)) (*Result, error) {
 	// Let's check context immediately
 	select {
 	case <-ctx.Done():
-		return "", ctx.Err()
+		return nil, ctx.Err()
 	default:
 	}
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	defer t.leave()
+	tk := &decodeTicket{priority: opts.Priority}
+	ctx = context.WithValue(ctx, ticketKey{}, tk)
+	if opts.MaxProcessing > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, opts.MaxProcessing, errMaxProcessing)
+		defer cancel()
+	}
+
+	mode := opts.Channels
+	if mode == "" {
+		mode = ChannelMix
+	}
+	if mode == ChannelPerChannel && emit != nil {
+		return nil, fmt.Errorf("per_channel transcription cannot be streamed")
+	}
+	// Byte-fallback tokens stream one byte at a time; deltas holds them
+	// back until they make whole characters.
+	var deltas *runeWriter
+	if emit != nil {
+		deltas = &runeWriter{emit: emit}
+		emit = deltas.write
+	}
+	if opts.Denoise && t.denoiser == nil {
+		return nil, ErrDenoiseUnavailable
+	}
 
-	waveform, err := t.loadAudio(audioData, format)
+	stageStart := time.Now()
+	planes, err := t.loadAudioChannels(audioData, opts.Format, mode)
 	if err != nil {
-		return "", fmt.Errorf("failed to load audio: %w", err)
+		return nil, fmt.Errorf("failed to load audio: %w", err)
+	}
+	if t.budget != nil {
+		release, err := t.budget.reserve(ctx, opts.Priority, t.memoryEstimate(planes))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	res := &Result{
+		Model:    t.name(),
+		Duration: float64(len(planes[0])) / featureSampleRate,
+		Channels: len(planes),
+	}
+
+	// Noise suppression runs first, on the audio as decoded, so the
+	// conditioning chain's silence and loudness measurements see the cleaned
+	// signal rather than the fan or vacuum cleaner behind it.
+	if opts.Denoise {
+		for i, plane := range planes {
+			if planes[i], err = t.denoiser.process(plane); err != nil {
+				return nil, fmt.Errorf("denoise failed: %w", err)
+			}
+		}
 	}
 
+	// Duration is measured before conditioning so timestamps keep referring
+	// to the uploaded audio even when silence is trimmed off its edges.
+	offset := 0.0
+	if opts.Conditioning.enabled() {
+		var trimmed int
+		planes, trimmed = opts.Conditioning.apply(planes)
+		offset = float64(trimmed) / featureSampleRate
+	}
+	tk.timings.DecodeAudio = time.Since(stageStart)
+
+	// Progress is reported across all planes: per-channel transcription
+	// decodes them one after another, so each covers an equal share.
+	planeProgress := func(ch int) func(done float64) {
+		if opts.Progress == nil {
+			return nil
+		}
+		seconds := float64(len(planes[ch])) / featureSampleRate
+		total := seconds * float64(len(planes))
+		return func(done float64) {
+			opts.Progress((float64(ch)+done)*seconds, total)
+		}
+	}
+
+	if len(planes) == 1 {
+		tk.blank, tk.temperatures = nil, nil
+		tokens, err := t.transcribeWaveform(ctx, planes[0], opts.Decoding, opts.Fallback, emit, planeProgress(0))
+		deltas.flush()
+		if res.Truncated = outOfTime(ctx, err); err != nil && !res.Truncated {
+			return nil, err
+		}
+		stageStart = time.Now()
+		res.Text = t.tokensToText(tokens)
+		res.Words = shiftWords(t.tokenWords(tokens, 0), offset)
+		res.Tokens = shiftTokens(t.tokenList(tokens, 0), offset)
+		end := res.Duration
+		if res.Truncated {
+			// The segment ends where the transcript does, not the audio.
+			end = 0
+			if n := len(res.Words); n > 0 {
+				end = res.Words[n-1].End
+			}
+		}
+		res.Segments = []Segment{{Start: 0, End: end, Text: res.Text}}
+		if opts.Segmentation.enabled() && len(res.Words) > 0 {
+			res.Segments = opts.Segmentation.split(res.Words, planes[0], offset, 0)
+		}
+		t.scoreSegments(res.Segments, tk.blank, offset)
+		t.segmentTemperatures(res.Segments, tk.temperatures, offset)
+		if opts.Guard.enabled() {
+			res.Segments, res.Words, res.Tokens = opts.Guard.check(res.Segments, res.Words, res.Tokens, planes[0], offset)
+			if opts.Guard.Mode == GuardBlank {
+				rebuildText(res)
+			}
+		}
+		if opts.NoSpeechThreshold > 0 {
+			dropNoSpeech(res, opts.NoSpeechThreshold)
+		}
+		if opts.Confidence.enabled() {
+			opts.Confidence.apply(res)
+		}
+		applyFormatting(res, opts.Formatting, opts.Language)
+		tk.timings.Postprocess = time.Since(stageStart)
+		res.Timings = tk.timings
+		return res, nil
+	}
+
+	// One speaker per channel: decode each channel on its own, split it into
+	// turns at pauses, then interleave the turns by start time.
+	var segments []Segment
+	for ch, plane := range planes {
+		tk.blank, tk.temperatures = nil, nil
+		tokens, err := t.transcribeWaveform(ctx, plane, opts.Decoding, opts.Fallback, nil, planeProgress(ch))
+		if res.Truncated = outOfTime(ctx, err); err != nil && !res.Truncated {
+			return nil, fmt.Errorf("channel %d: %w", ch, err)
+		}
+		stageStart = time.Now()
+		words := shiftWords(t.tokenWords(tokens, ch), offset)
+		var turns []Segment
+		if opts.Segmentation.enabled() {
+			// Turns still end at pauses of channelTurnGapSeconds.
+			turns = opts.Segmentation.split(words, plane, offset, channelTurnGapSeconds)
+		} else {
+			for _, seg := range t.channelSegments(tokens, ch) {
+				seg.Start += offset
+				seg.End += offset
+				turns = append(turns, seg)
+			}
+		}
+		t.scoreSegments(turns, tk.blank, offset)
+		t.segmentTemperatures(turns, tk.temperatures, offset)
+		chTokens := shiftTokens(t.tokenList(tokens, ch), offset)
+		if opts.Guard.enabled() {
+			turns, words, chTokens = opts.Guard.check(turns, words, chTokens, plane, offset)
+		}
+		segments = append(segments, turns...)
+		res.Words = append(res.Words, words...)
+		res.Tokens = append(res.Tokens, chTokens...)
+		tk.timings.Postprocess += time.Since(stageStart)
+		if res.Truncated {
+			// Later channels are left out.
+			break
+		}
+	}
+	stageStart = time.Now()
+	res.Segments, res.Text = mergeChannelSegments(segments)
+	sortWords(res.Words)
+	sortTokens(res.Tokens)
+	if opts.NoSpeechThreshold > 0 {
+		dropNoSpeech(res, opts.NoSpeechThreshold)
+	}
+	if opts.Confidence.enabled() {
+		opts.Confidence.apply(res)
+	}
+	applyFormatting(res, opts.Formatting, opts.Language)
+	tk.timings.Postprocess += time.Since(stageStart)
+	res.Timings = tk.timings
+	return res, nil
+}
+
+// transcribeWaveform runs one 16 kHz mono waveform through features, chunk
+// planning and decoding, and returns the owned tokens in order. When emit is
+// non-nil, decoded text is streamed delta by delta as tokens are produced.
+// When progress is non-nil it receives the fraction of the waveform decoded
+// so far, never decreasing, ending with 1. dec picks the decoding strategy
+// and fb when to retry a window at a higher temperature.
+// If decoding fails or ctx ends, the tokens decoded until then are returned
+// with the error.
+func (t *Transcriber) transcribeWaveform(ctx context.Context, waveform []float32, dec DecodingOptions, fb Fallback, emit func(delta string), progress func(done float64)) ([]decodedToken, error) {
+
 	if DebugMode {
-		slog.Debug("waveform loaded", "samples", len(waveform), "seconds", float64(len(waveform))/16000.0)
+		slog.DebugContext(ctx, "waveform loaded", "samples", len(waveform), "seconds", float64(len(waveform))/16000.0)
 	}
 
 	if len(waveform) < 1600 {
 		if DebugMode {
-			slog.Debug("audio too short, skipping", "samples", len(waveform))
+			slog.DebugContext(ctx, "audio too short, skipping", "samples", len(waveform))
+		}
+		if progress != nil {
+			progress(1)
 		}
-		return "", nil
+		return nil, nil
 	}
 
+	featuresStart := time.Now()
 	features := t.mel.Extract(waveform)
 	if len(features) == 0 {
-		return "", fmt.Errorf("no features extracted")
+		return nil, fmt.Errorf("no features extracted")
 	}
 
 	if DebugMode {
-		slog.Debug("mel features extracted", "frames", len(features), "featuresPerFrame", len(features[0]))
+		slog.DebugContext(ctx, "mel features extracted", "frames", len(features), "featuresPerFrame", len(features[0]))
 	}
 
 	subsampling := int64(t.config.SubsamplingFactor)
@@ -579,15 +1281,32 @@ func (t *Transcriber) transcribe(ctx context.Context, audioData []byte, format,
 	// the oracle is unused (single window or ErrAudioTooLong).
 	oracle := t.newBoundaryOracle(features, waveform)
 	plan, err := planForAudioWithBoundaries(int64(len(features)), t.chunkFrames, t.overlapFrames, subsampling, t.longAudio, oracle)
+	ticketFrom(ctx).timings.Features += time.Since(featuresStart)
 	if err != nil {
-		slog.Warn("audio exceeds the single-pass model limit; enable --long-audio to transcribe long files in overlapping chunks",
+		slog.WarnContext(ctx, "audio exceeds the single-pass model limit; enable --long-audio to transcribe long files in overlapping chunks",
 			"seconds", float64(len(features))/float64(t.mel.FramesPerSecond()),
 			"limitSeconds", float64(modelMaxEncoderFrames*subsampling)/float64(t.mel.FramesPerSecond()))
-		return "", err
+		return nil, err
 	}
 
 	if DebugMode {
-		slog.Debug("chunk plan", "windows", len(plan), "melFrames", len(features), "longAudio", t.longAudio)
+		slog.DebugContext(ctx, "chunk plan", "windows", len(plan), "melFrames", len(features), "longAudio", t.longAudio)
+	}
+
+	// frameProgress turns the absolute encoder frame reached by the decoder
+	// into a fraction of the waveform. Adjacent windows overlap, so the frame
+	// steps back at every seam; only advances are reported.
+	var frameProgress func(frame int64)
+	if progress != nil {
+		totalFrames := melToEncoderFrame(int64(len(features)), subsampling)
+		reached := int64(0)
+		frameProgress = func(frame int64) {
+			if frame <= reached || totalFrames <= 0 {
+				return
+			}
+			reached = min(frame, totalFrames)
+			progress(float64(reached) / float64(totalFrames))
+		}
 	}
 
 	// Decode window by window. Adjacent windows share an overlap, so window i+1's
@@ -595,9 +1314,18 @@ func (t *Transcriber) transcribe(ctx context.Context, audioData []byte, format,
 	// are emitted, dropping seam duplicates and letting the earlier (warmed-up)
 	// window win text collisions. Held tokens are released in order
 	// before the rest of the window streams, so streaming order is preserved.
+	// The encoder runs one window ahead on its own goroutine, so window i+1
+	// is encoded while window i decodes.
+	encoded := encodeAhead(ctx, plan, func(ctx context.Context, win chunkWindow) (*encodedWindow, error) {
+		return t.encodeWindow(ctx, features[win.start:win.end])
+	})
+	defer encoded.stop()
 	var tokens []decodedToken
 	var prevTail []decodedToken
 	for i, win := range plan {
+		if err := ctx.Err(); err != nil {
+			return tokens, err
+		}
 		// Emit bounds are the window's owned region expressed in the window's
 		// local encoder frames, so tdtDecode drops the overlap it does not own.
 		emitStart := melToEncoderFrame(win.emitStart-win.start, subsampling)
@@ -616,19 +1344,27 @@ func (t *Transcriber) transcribe(ctx context.Context, audioData []byte, format,
 			}
 		}
 
-		windowTokens, err := t.runInference(ctx, features[win.start:win.end], emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, emit)
+		enc, err := encoded.next()
+		if err != nil {
+			return tokens, fmt.Errorf("inference failed: %w", err)
+		}
+		windowTokens, err := t.decodeWindow(ctx, enc, dec, fb, emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, emit, frameProgress)
+		enc.release()
 		if err != nil {
-			return "", fmt.Errorf("inference failed: %w", err)
+			return append(tokens, windowTokens...), fmt.Errorf("inference failed: %w", err)
 		}
 		tokens = append(tokens, windowTokens...)
 		prevTail = windowTokens
 	}
 
 	if DebugMode {
-		slog.Debug("tokens decoded", "count", len(tokens))
+		slog.DebugContext(ctx, "tokens decoded", "count", len(tokens))
+	}
+	if progress != nil {
+		progress(1)
 	}
 
-	return t.tokensToText(tokens), nil
+	return tokens, nil
 }
 
 // newBoundaryOracle builds the per-request chunk-boundary cascade over this
@@ -665,8 +1401,18 @@ func (t *Transcriber) newBoundaryOracle(features [][]float32, waveform []float32
 // The `format` parameter is kept for logging and future heuristics, but it
 // is intentionally not used to pick the decoder.
 func (t *Transcriber) loadAudio(data []byte, format string) ([]float32, error) {
+	planes, err := t.loadAudioChannels(data, format, ChannelMix)
+	if err != nil {
+		return nil, err
+	}
+	return planes[0], nil
+}
+
+// loadAudioChannels is loadAudio with a channel mode. It returns one 16 kHz
+// plane per selected channel; only ChannelPerChannel can return more than one.
+func (t *Transcriber) loadAudioChannels(data []byte, format string, mode ChannelMode) ([][]float32, error) {
 	if isWAV(data) {
-		return parseWAV(data)
+		return parseWAVChannels(data, t.resampleQuality, mode)
 	}
 
 	if t.ffmpeg == nil {
@@ -680,71 +1426,23 @@ func (t *Transcriber) loadAudio(data []byte, format string) ([]float32, error) {
 		)
 	}
 
-	wavData, err := t.ffmpeg.Convert(data)
+	// Only downmix in ffmpeg when the caller wants the mix anyway; the other
+	// modes need the original channel layout.
+	wavData, err := t.ffmpeg.Convert(data, mode != ChannelMix)
 	if err != nil {
 		return nil, err
 	}
-	return parseWAV(wavData)
+	return parseWAVChannels(wavData, t.resampleQuality, mode)
 }
 
-func (t *Transcriber) runInference(ctx context.Context, features [][]float32, emitStart, emitEnd, frameOffset int64, holdFirst int, resolveSeam func(head []decodedToken) []decodedToken, emit func(delta string)) ([]decodedToken, error) {
-	batchSize := int64(1)
-	numFeatures := int64(t.config.FeaturesSize)
-	numFrames := int64(len(features))
-
-	// Flatten features: [frames, features] → [1, features, frames]
-	inputData := make([]float32, numFeatures*numFrames)
-	for f := int64(0); f < numFrames; f++ {
-		for m := int64(0); m < numFeatures && m < int64(len(features[f])); m++ {
-			inputData[m*numFrames+f] = features[f][m]
-		}
-	}
-
-	inputTensor, err := ort.NewTensor(ort.NewShape(batchSize, numFeatures, numFrames), inputData)
+// runInference encodes one window and decodes it, without pipelining.
+func (t *Transcriber) runInference(ctx context.Context, features [][]float32, dec DecodingOptions, emitStart, emitEnd, frameOffset int64, holdFirst int, resolveSeam func(head []decodedToken) []decodedToken, emit func(delta string), progress func(frame int64)) ([]decodedToken, error) {
+	enc, err := t.encodeWindow(ctx, features)
 	if err != nil {
-		return nil, fmt.Errorf("create input tensor: %w", err)
-	}
-	defer inputTensor.Destroy()
-
-	lengthTensor, err := ort.NewTensor(ort.NewShape(batchSize), []int64{numFrames})
-	if err != nil {
-		return nil, fmt.Errorf("create length tensor: %w", err)
-	}
-	defer lengthTensor.Destroy()
-
-	encodedLen := (numFrames-1)/int64(t.config.SubsamplingFactor) + 1
-
-	outputTensor, err := ort.NewEmptyTensor[float32](ort.NewShape(batchSize, encoderDim, encodedLen))
-	if err != nil {
-		return nil, fmt.Errorf("create output tensor: %w", err)
-	}
-	defer outputTensor.Destroy()
-
-	outLenTensor, err := ort.NewEmptyTensor[int64](ort.NewShape(batchSize))
-	if err != nil {
-		return nil, fmt.Errorf("create output length tensor: %w", err)
-	}
-	defer outLenTensor.Destroy()
-
-	// Reuse the shared encoder session. Shapes vary per request, so tensors are
-	// supplied to Run each time; the session itself is built once at startup.
-	if err := t.encoder.Run(
-		[]ort.Value{inputTensor, lengthTensor},
-		[]ort.Value{outputTensor, outLenTensor},
-	); err != nil {
-		return nil, fmt.Errorf("encoder run failed: %w", err)
-	}
-
-	encoderOut := outputTensor.GetData()
-	actualEncodedLen := outLenTensor.GetData()[0]
-
-	if DebugMode {
-		slog.Debug("encoder output", "floats", len(encoderOut), "encodedLen", actualEncodedLen)
+		return nil, err
 	}
-
-	// Decoder tensors (encoderOut) must remain alive during tdtDecode.
-	// The defers above fire after tdtDecode returns, so this is safe.
-	return t.tdtDecode(ctx, encoderOut, actualEncodedLen, emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, emit)
+	defer enc.release()
+	return t.tdtDecode(ctx, enc.out, enc.len, dec, emitStart, emitEnd, frameOffset, holdFirst, resolveSeam, emit, progress)
 }
 
 // tdtDecode greedily decodes the encoder output for one window. It decodes the
@@ -759,24 +1457,35 @@ func (t *Transcriber) runInference(ctx context.Context, features [][]float32, em
 // emitted; the survivors are streamed in order, then the rest of the window
 // streams as it is decoded. This keeps streaming order correct while buffering
 // only a handful of tokens per seam.
-func (t *Transcriber) tdtDecode(ctx context.Context, encoderOut []float32, encodedLen, emitStart, emitEnd, frameOffset int64, holdFirst int, resolveSeam func(head []decodedToken) []decodedToken, emit func(delta string)) ([]decodedToken, error) {
-	// Acquire a pre-initialized worker. Honor cancellation so a client that
-	// disconnects while all workers are busy does not leak a goroutine.
-	var w *decoderWorker
-	select {
-	case w = <-t.decoderPool:
-	case <-ctx.Done():
-		return nil, ctx.Err()
+//
+// When progress is non-nil it is called with the absolute encoder frame each
+// time the decoder advances.
+//
+// dec adjusts the greedy choice (blank penalty, per-frame token cap,
+// temperature sampling) or switches to beamDecode. A beam search only knows
+// its tokens once the window is decoded, so they are streamed then.
+func (t *Transcriber) tdtDecode(ctx context.Context, encoderOut []float32, encodedLen int64, dec DecodingOptions, emitStart, emitEnd, frameOffset int64, holdFirst int, resolveSeam func(head []decodedToken) []decodedToken, emit func(delta string), progress func(frame int64)) ([]decodedToken, error) {
+	// Acquire a pre-initialized worker by priority. Honor cancellation so a
+	// client that disconnects while all workers are busy does not leak a
+	// goroutine.
+	tk := ticketFrom(ctx)
+	w, err := t.decoderPool.acquire(ctx, tk.priority, !tk.started)
+	if err != nil {
+		return nil, err
 	}
-	// Return the worker to the pool when done. Guard against a panic from
-	// sending on a closed pool during shutdown so we never crash the process.
-	defer func() {
-		defer func() { _ = recover() }()
-		t.decoderPool <- w
-	}()
+	tk.started = true
+	// Return the worker to the pool when done. Close waits for this call
+	// (lifecycle), so the pool is still there.
+	defer t.decoderPool.release(w)
+	if t.batcher != nil {
+		t.batcher.begin()
+		defer t.batcher.end()
+	}
+	decodeStart := time.Now()
+	defer func() { tk.timings.Decoder += time.Since(decodeStart) }()
 
 	if DebugMode {
-		slog.Debug("TDT decode started", "encoderOutLen", len(encoderOut), "encodedLen", encodedLen)
+		slog.DebugContext(ctx, "TDT decode started", "encoderOutLen", len(encoderOut), "encodedLen", encodedLen)
 	}
 
 	// Reset LSTM states to zero for this request
@@ -819,7 +1528,37 @@ func (t *Transcriber) tdtDecode(ctx context.Context, encoderOut []float32, encod
 		head = nil
 		resolved = true
 	}
+	// collect keeps a token this window owns, holding it for the seam
+	// deduper until holdFirst are buffered, then streaming again.
+	collect := func(dt decodedToken) {
+		if resolved {
+			result = append(result, dt)
+			emitText(dt.id)
+			return
+		}
+		head = append(head, dt)
+		if len(head) >= holdFirst {
+			flushHead()
+		}
+	}
 
+	if dec.beam() {
+		tokens, err := t.beamDecode(ctx, w, encoderOut, encodedLen, frameOffset, dec, progress)
+		if err != nil {
+			return nil, err
+		}
+		for _, dt := range tokens {
+			if local := dt.timestep - frameOffset; local >= emitStart && local < emitEnd {
+				collect(dt)
+			}
+		}
+		if !resolved {
+			flushHead()
+		}
+		return result, nil
+	}
+
+	maxSymbols := dec.maxSymbols(t.maxTokensPerStep)
 	encOutData := w.encOut.GetData()
 
 	for timestep < encodedLen {
@@ -836,19 +1575,31 @@ func (t *Transcriber) tdtDecode(ctx context.Context, encoderOut []float32, encod
 		// Update target token (written directly into tensor backing data)
 		w.targets.GetData()[0] = int32(prevToken)
 
-		if err := w.session.Run(); err != nil {
+		if err := t.decoderStep(w); err != nil {
 			return nil, fmt.Errorf("decoder run failed: %w", err)
 		}
 
 		output := w.output.GetData()
 		vocabLogits := output[:t.vocabSize]
 		durationLogits := output[t.vocabSize:]
+		// The model's own blank probability, before any penalty, is the
+		// no-speech evidence of this frame.
+		blankProb := softmaxProb(vocabLogits, t.blankIdx)
+		if dec.BlankPenalty != 0 {
+			vocabLogits[t.blankIdx] -= float32(dec.BlankPenalty)
+		}
 
 		token := argmax(vocabLogits)
-		step := argmax(durationLogits)
+		if dec.Temperature > 0 {
+			token = sampleToken(vocabLogits, dec.Temperature)
+		}
+		step := t.durations[argmax(durationLogits)]
+		if timestep >= emitStart && timestep < emitEnd {
+			tk.blank.note(frameOffset+timestep, min(int64(step), emitEnd-timestep), blankProb)
+		}
 
 		if DebugMode && timestep < 5 {
-			slog.Debug("decode step",
+			slog.DebugContext(ctx, "decode step",
 				"timestep", timestep,
 				"token", token,
 				"blank", t.blankIdx,
@@ -866,18 +1617,7 @@ func (t *Transcriber) tdtDecode(ctx context.Context, encoderOut []float32, encod
 			// Collect and stream only tokens this window owns; the rest belong
 			// to an adjacent window's overlap and would duplicate speech.
 			if timestep >= emitStart && timestep < emitEnd {
-				dt := decodedToken{id: token, timestep: frameOffset + timestep}
-				if resolved {
-					result = append(result, dt)
-					emitText(dt.id)
-				} else {
-					// Hold the window's leading tokens for the seam deduper. Once
-					// holdFirst are buffered, resolve and start streaming again.
-					head = append(head, dt)
-					if len(head) >= holdFirst {
-						flushHead()
-					}
-				}
+				collect(decodedToken{id: token, timestep: frameOffset + timestep, prob: softmaxProb(vocabLogits, token)})
 			}
 		}
 
@@ -892,12 +1632,18 @@ func (t *Transcriber) tdtDecode(ctx context.Context, encoderOut []float32, encod
 		default:
 		}
 
+		advanced := true
 		if step > 0 {
 			timestep += int64(step)
 			emittedTokens = 0
-		} else if token == t.blankIdx || emittedTokens >= t.maxTokensPerStep {
+		} else if token == t.blankIdx || emittedTokens >= maxSymbols {
 			timestep++
 			emittedTokens = 0
+		} else {
+			advanced = false
+		}
+		if advanced && progress != nil {
+			progress(frameOffset + min(timestep, encodedLen))
 		}
 	}
 
@@ -910,6 +1656,15 @@ func (t *Transcriber) tdtDecode(ctx context.Context, encoderOut []float32, encod
 	return result, nil
 }
 
+// decoderStep runs one decoder step over w's tensors: on w's session, or
+// with the steps of other decodes when decoder batching is on.
+func (t *Transcriber) decoderStep(w *decoderWorker) error {
+	if t.batcher != nil {
+		return t.batcher.step(&w.step)
+	}
+	return w.run()
+}
+
 func argmax(data []float32) int {
 	if len(data) == 0 {
 		return 0
@@ -926,20 +1681,28 @@ func argmax(data []float32) int {
 }
 
 // tokenText returns the printable text for a token id, or "" for unknown tokens
-// and special <...> markers. vocab values already have word-boundary marks
-// (U+2581) translated to spaces at load time (see loadVocab), so the text is
-// returned as-is.
+// and special <...> markers, unless config.json's special_tokens gives
+// their text. vocab values already have word-boundary marks
+// (U+2581) translated to spaces at load time (see setVocab), so the text is
+// returned as-is. A byte-fallback token returns its single raw byte, which
+// is only valid UTF-8 joined with the bytes around it: see joinPieces.
 func (t *Transcriber) tokenText(id int) string {
-	text, ok := t.vocab[id]
-	if !ok {
-		return ""
+	if text, ok := t.specialTokens[id]; ok {
+		return text
 	}
-	if strings.HasPrefix(text, "<") && strings.HasSuffix(text, ">") {
+	text, ok := t.vocab[id]
+	if !ok || isSpecialToken(text) {
 		return ""
 	}
 	return text
 }
 
+// isSpecialToken reports whether a vocabulary token is a <...> marker such
+// as <unk> or <blk>.
+func isSpecialToken(token string) bool {
+	return strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">")
+}
+
 func (t *Transcriber) tokensToText(tokens []decodedToken) string {
 	var parts []string
 	for _, tok := range tokens {
@@ -947,7 +1710,7 @@ func (t *Transcriber) tokensToText(tokens []decodedToken) string {
 			parts = append(parts, text)
 		}
 	}
-	text := strings.Join(parts, "")
+	text := joinPieces(parts...)
 	text = strings.TrimSpace(whitespaceRegex.ReplaceAllString(text, " "))
 	return text
 }
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTranscriberCloseWaitsForCalls(t *testing.T) {
+	tr := &Transcriber{decoderPool: newWorkerPool(QueueLimits{})}
+	if err := tr.enter(); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		tr.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a call was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tr.leave()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after the call ended")
+	}
+
+	tr.Close() // a second Close is a no-op
+	if _, err := tr.TranscribeWithOptions(context.Background(), nil, TranscribeOptions{}, nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("TranscribeWithOptions after Close: %v, want ErrClosed", err)
+	}
+	if _, err := tr.DetectSpeech(context.Background(), nil, VADOptions{}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("DetectSpeech after Close: %v, want ErrClosed", err)
+	}
+}
+
+func TestOutOfTime(t *testing.T) {
+	ctx, cancel := context.WithTimeoutCause(context.Background(), time.Millisecond, errMaxProcessing)
+	defer cancel()
+	<-ctx.Done()
+	if !outOfTime(ctx, ctx.Err()) {
+		t.Error("MaxProcessing deadline not reported")
+	}
+	if outOfTime(ctx, nil) {
+		t.Error("reported without an error")
+	}
+
+	// The caller giving up first is an error, not a truncation.
+	parent, stop := context.WithCancel(context.Background())
+	ctx, cancel = context.WithTimeoutCause(parent, time.Hour, errMaxProcessing)
+	defer cancel()
+	stop()
+	if outOfTime(ctx, ctx.Err()) {
+		t.Error("caller cancellation reported as MaxProcessing")
+	}
+}
+
+func TestSetVocabBlank(t *testing.T) {
+	tr := &Transcriber{}
+	tr.setVocab(map[int]string{0: "<unk>", 1: "▁the", 2: "<blk>"})
+	if tr.blankIdx != 2 || tr.vocabSize != 3 || tr.vocab[1] != " the" {
+		t.Errorf("blank %d, size %d, vocab %q", tr.blankIdx, tr.vocabSize, tr.vocab)
+	}
+
+	// Without <blk> the blank follows the last token, as in NeMo.
+	tr = &Transcriber{}
+	tr.setVocab(map[int]string{0: "<unk>", 1: "▁the", 2: "s"})
+	if tr.blankIdx != 3 || tr.vocabSize != 4 {
+		t.Errorf("blank %d, size %d", tr.blankIdx, tr.vocabSize)
+	}
+}
+
+func TestSpecialTokens(t *testing.T) {
+	tr := &Transcriber{config: Config{SpecialTokens: map[string]string{"<laughs>": "<laughs>", "<noise>": " [noise]", "<unk>": ""}}}
+	tr.setVocab(map[int]string{0: "<unk>", 1: "▁ha", 2: "<laughs>", 3: "<noise>", 4: "<pad>"})
+	for id, want := range map[int]string{0: "", 1: " ha", 2: "<laughs>", 3: " [noise]", 4: ""} {
+		if got := tr.tokenText(id); got != want {
+			t.Errorf("tokenText(%d) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestResolveDurations(t *testing.T) {
+	for _, tc := range []struct {
+		logits     int64
+		configured []int
+		want       []int
+		wantErr    bool
+	}{
+		{logits: 8198, want: []int{0, 1, 2, 3, 4}},
+		{logits: 8196, want: []int{0, 1, 2}},
+		{logits: 0, want: []int{0, 1, 2, 3, 4}},
+		{logits: 0, configured: []int{0, 2, 4}, want: []int{0, 2, 4}},
+		{logits: 8196, configured: []int{0, 2, 4}, want: []int{0, 2, 4}},
+		{logits: 8196, configured: []int{0, 1}, wantErr: true},
+		{logits: 8193, wantErr: true},
+	} {
+		got, err := resolveDurations(tc.logits, 8193, tc.configured)
+		if (err != nil) != tc.wantErr || !slices.Equal(got, tc.want) {
+			t.Errorf("resolveDurations(%d, %v) = %v, %v", tc.logits, tc.configured, got, err)
+		}
+	}
+}
@@ -6,7 +6,6 @@ package asr
 import (
 	"fmt"
 	"log/slog"
-	"os"
 
 	ort "github.com/yalue/onnxruntime_go"
 )
@@ -56,26 +55,84 @@ func (s *vadState) reset() {
 	}
 }
 
+// vadTensorPoolSize caps how many idle tensor sets the VAD keeps for reuse.
+// Concurrency here is bounded by in-flight requests rather than -workers, so
+// the pool only needs to cover the usual number of overlapping boundary
+// searches; extra sets are created on demand and destroyed when returned.
+const vadTensorPoolSize = 8
+
 // sileroVAD wraps the shared Silero VAD ONNX session. Like the encoder,
 // it is a single long-lived DynamicAdvancedSession reused across requests and
 // runs OUTSIDE the decoder worker pool, so its concurrency is bounded by the
 // number of in-flight HTTP requests rather than by -workers.
 type sileroVAD struct {
 	session *ort.DynamicAdvancedSession
-	srData  []int64
+	tensors chan *vadTensors
+}
+
+// vadTensors is one reusable set of Silero input/output tensors. Every shape
+// is fixed (one 576-sample window, the [2, 1, 128] state, the scalar rate), so
+// a set allocated once serves every window of an overlap region instead of
+// creating and destroying five tensors per 32 ms of audio.
+type vadTensors struct {
+	input    *ort.Tensor[float32]
+	state    *ort.Tensor[float32]
+	sr       *ort.Tensor[int64]
+	output   *ort.Tensor[float32]
+	stateOut *ort.Tensor[float32]
 }
 
-// newSileroVAD loads the Silero VAD model from path and creates the shared
-// session. A missing file is NOT fatal: the caller logs a warning once and the
-// boundary stack degrades to mel energy. Any other error (corrupt model, ORT
-// failure) is returned so it surfaces loudly at startup.
-func newSileroVAD(path string, sessOpts *ort.SessionOptions) (*sileroVAD, error) {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, os.ErrNotExist
+func newVADTensors() (*vadTensors, error) {
+	t := &vadTensors{}
+	var err error
+	if t.input, err = ort.NewEmptyTensor[float32](ort.NewShape(1, vadContextSamples+vadWindowSamples)); err != nil {
+		t.destroy()
+		return nil, fmt.Errorf("create VAD input tensor: %w", err)
+	}
+	if t.state, err = ort.NewEmptyTensor[float32](ort.NewShape(2, 1, 128)); err != nil {
+		t.destroy()
+		return nil, fmt.Errorf("create VAD state tensor: %w", err)
+	}
+	if t.sr, err = ort.NewTensor(ort.NewShape(1), []int64{vadSampleRate}); err != nil {
+		t.destroy()
+		return nil, fmt.Errorf("create VAD sr tensor: %w", err)
+	}
+	if t.output, err = ort.NewEmptyTensor[float32](ort.NewShape(1, 1)); err != nil {
+		t.destroy()
+		return nil, fmt.Errorf("create VAD output tensor: %w", err)
 	}
+	if t.stateOut, err = ort.NewEmptyTensor[float32](ort.NewShape(2, 1, 128)); err != nil {
+		t.destroy()
+		return nil, fmt.Errorf("create VAD state output tensor: %w", err)
+	}
+	return t, nil
+}
 
-	session, err := ort.NewDynamicAdvancedSession(
-		path,
+// destroy releases every tensor in the set, tolerating a partially built one.
+func (t *vadTensors) destroy() {
+	if t.input != nil {
+		t.input.Destroy()
+	}
+	if t.state != nil {
+		t.state.Destroy()
+	}
+	if t.sr != nil {
+		t.sr.Destroy()
+	}
+	if t.output != nil {
+		t.output.Destroy()
+	}
+	if t.stateOut != nil {
+		t.stateOut.Destroy()
+	}
+}
+
+// newSileroVAD creates the shared session of the Silero VAD model. A missing
+// file is NOT fatal: the caller logs a warning once and the boundary stack
+// degrades to mel energy. Any other error (corrupt model, ORT failure) is
+// returned so it surfaces loudly at startup.
+func newSileroVAD(model onnxModel, sessOpts *ort.SessionOptions) (*sileroVAD, error) {
+	session, err := model.newDynamicSession(
 		[]string{"input", "state", "sr"},
 		[]string{"output", "stateN"},
 		sessOpts,
@@ -86,78 +143,78 @@ func newSileroVAD(path string, sessOpts *ort.SessionOptions) (*sileroVAD, error)
 
 	return &sileroVAD{
 		session: session,
-		srData:  []int64{vadSampleRate},
+		tensors: make(chan *vadTensors, vadTensorPoolSize),
 	}, nil
 }
 
-// destroy releases the underlying ONNX session.
+// destroy releases the underlying ONNX session and every pooled tensor set.
 func (v *sileroVAD) destroy() {
-	if v != nil && v.session != nil {
+	if v == nil {
+		return
+	}
+	for drained := false; v.tensors != nil && !drained; {
+		select {
+		case t := <-v.tensors:
+			t.destroy()
+		default:
+			drained = true
+		}
+	}
+	if v.session != nil {
 		v.session.Destroy()
 		v.session = nil
 	}
 }
 
+// acquireTensors takes an idle tensor set from the pool or builds a new one.
+func (v *sileroVAD) acquireTensors() (*vadTensors, error) {
+	select {
+	case t := <-v.tensors:
+		return t, nil
+	default:
+		return newVADTensors()
+	}
+}
+
+// releaseTensors returns a set to the pool, destroying it when the pool is full.
+func (v *sileroVAD) releaseTensors(t *vadTensors) {
+	select {
+	case v.tensors <- t:
+	default:
+		t.destroy()
+	}
+}
+
 // infer runs one window through the model and returns the speech probability in
 // [0, 1]. It prepends st.ctx as left context, updates st.ctx with the trailing
 // samples of window, and advances the recurrent state in st.state. window must
-// be exactly vadWindowSamples long.
-func (v *sileroVAD) infer(st *vadState, window []float32) (float32, error) {
+// be exactly vadWindowSamples long. tensors is the caller's acquired set; its
+// backing data is overwritten in place, so nothing is allocated per window.
+func (v *sileroVAD) infer(st *vadState, tensors *vadTensors, window []float32) (float32, error) {
 	if len(window) != vadWindowSamples {
 		return 0, fmt.Errorf("silero window must be %d samples, got %d", vadWindowSamples, len(window))
 	}
 
 	// Assemble context + window into the model input.
-	input := make([]float32, vadContextSamples+vadWindowSamples)
+	input := tensors.input.GetData()
 	copy(input, st.ctx[:])
 	copy(input[vadContextSamples:], window)
 	// Save the trailing samples as context for the next window.
 	copy(st.ctx[:], window[vadWindowSamples-vadContextSamples:])
 
-	inputTensor, err := ort.NewTensor(ort.NewShape(1, int64(len(input))), input)
-	if err != nil {
-		return 0, fmt.Errorf("create VAD input tensor: %w", err)
-	}
-	defer inputTensor.Destroy()
-
-	// The state tensor is written in place across calls, so give the model a
-	// copy of the current state and read the fresh state from the output.
-	stateData := make([]float32, vadStateElements)
-	copy(stateData, st.state[:])
-	stateTensor, err := ort.NewTensor(ort.NewShape(2, 1, 128), stateData)
-	if err != nil {
-		return 0, fmt.Errorf("create VAD state tensor: %w", err)
-	}
-	defer stateTensor.Destroy()
-
-	srTensor, err := ort.NewTensor(ort.NewShape(1), v.srData)
-	if err != nil {
-		return 0, fmt.Errorf("create VAD sr tensor: %w", err)
-	}
-	defer srTensor.Destroy()
-
-	outputTensor, err := ort.NewEmptyTensor[float32](ort.NewShape(1, 1))
-	if err != nil {
-		return 0, fmt.Errorf("create VAD output tensor: %w", err)
-	}
-	defer outputTensor.Destroy()
-
-	stateOutTensor, err := ort.NewEmptyTensor[float32](ort.NewShape(2, 1, 128))
-	if err != nil {
-		return 0, fmt.Errorf("create VAD state output tensor: %w", err)
-	}
-	defer stateOutTensor.Destroy()
+	// Feed the current state and read the fresh state from the output tensor.
+	copy(tensors.state.GetData(), st.state[:])
 
 	if err := v.session.Run(
-		[]ort.Value{inputTensor, stateTensor, srTensor},
-		[]ort.Value{outputTensor, stateOutTensor},
+		[]ort.Value{tensors.input, tensors.state, tensors.sr},
+		[]ort.Value{tensors.output, tensors.stateOut},
 	); err != nil {
 		return 0, fmt.Errorf("VAD run failed: %w", err)
 	}
 
-	copy(st.state[:], stateOutTensor.GetData())
+	copy(st.state[:], tensors.stateOut.GetData())
 
-	prob := outputTensor.GetData()[0]
+	prob := tensors.output.GetData()[0]
 	return prob, nil
 }
 
@@ -175,10 +232,17 @@ func (v *sileroVAD) speechProbabilities(st *vadState, samples []float32) []float
 		return nil
 	}
 
+	tensors, err := v.acquireTensors()
+	if err != nil {
+		slog.Warn("VAD tensors unavailable, skipping overlap", "error", err)
+		return nil
+	}
+	defer v.releaseTensors(tensors)
+
 	probs := make([]float32, 0, numWindows)
 	for i := 0; i < numWindows; i++ {
 		window := samples[i*vadWindowSamples : (i+1)*vadWindowSamples]
-		prob, err := v.infer(st, window)
+		prob, err := v.infer(st, tensors, window)
 		if err != nil {
 			slog.Warn("VAD inference failed mid-overlap, using partial probabilities",
 				"window", i, "error", err)
@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// vocabFormats are the vocabulary files NewTranscriber looks for, in
+// order: the sherpa-onnx style vocab.txt, the SentencePiece model NeMo
+// trains, and a Hugging Face tokenizer.json. Each parser returns the token
+// text by id, word-boundary marks (U+2581) kept.
+var vocabFormats = []struct {
+	file  string
+	parse func([]byte) (map[int]string, error)
+}{
+	{"vocab.txt", parseVocabTxt},
+	{"tokenizer.model", parseSentencePieceModel},
+	{"tokenizer.json", parseTokenizerJSON},
+}
+
+// readVocab loads the first vocabulary file of vocabFormats in models and
+// returns its name and tokens.
+func readVocab(models modelStore) (string, map[int]string, error) {
+	for _, f := range vocabFormats {
+		if !models.exists(f.file) {
+			continue
+		}
+		data, err := models.readFile(f.file)
+		if err != nil {
+			return "", nil, err
+		}
+		tokens, err := f.parse(data)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", f.file, err)
+		}
+		if len(tokens) == 0 {
+			return "", nil, fmt.Errorf("%s: no tokens", f.file)
+		}
+		return f.file, tokens, nil
+	}
+	names := make([]string, len(vocabFormats))
+	for i, f := range vocabFormats {
+		names[i] = f.file
+	}
+	return "", nil, fmt.Errorf("no vocabulary found (looked for %s)", strings.Join(names, ", "))
+}
+
+// parseVocabTxt reads "token id" lines. Malformed lines are skipped.
+func parseVocabTxt(data []byte) (map[int]string, error) {
+	tokens := make(map[int]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		tokens[id] = parts[0]
+	}
+	return tokens, scanner.Err()
+}
+
+// errProtobuf reports a SentencePiece model that is not a valid protobuf.
+var errProtobuf = errors.New("not a SentencePiece model: malformed protobuf")
+
+// parseSentencePieceModel reads the pieces of a SentencePiece ModelProto:
+// field 1 repeats SentencePiece messages whose field 1 is the piece text,
+// numbered in order. The trainer and normalizer specs are skipped.
+func parseSentencePieceModel(data []byte) (map[int]string, error) {
+	tokens := make(map[int]string)
+	err := protoFields(data, func(field int, value []byte) error {
+		if field != 1 {
+			return nil
+		}
+		var piece string
+		err := protoFields(value, func(field int, value []byte) error {
+			if field == 1 {
+				piece = string(value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		tokens[len(tokens)] = piece
+		return nil
+	})
+	return tokens, err
+}
+
+// protoFields calls fn with the number and bytes of each length-delimited
+// field of a protobuf message and skips the other wire types.
+func protoFields(data []byte, fn func(field int, value []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errProtobuf
+		}
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0: // varint
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return errProtobuf
+			}
+			data = data[n:]
+		case 1: // fixed64
+			if len(data) < 8 {
+				return errProtobuf
+			}
+			data = data[8:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return errProtobuf
+			}
+			if err := fn(field, data[n:n+int(size)]); err != nil {
+				return err
+			}
+			data = data[n+int(size):]
+		case 5: // fixed32
+			if len(data) < 4 {
+				return errProtobuf
+			}
+			data = data[4:]
+		default:
+			return errProtobuf
+		}
+	}
+	return nil
+}
+
+// parseTokenizerJSON reads the vocabulary of a Hugging Face tokenizer.json:
+// a Unigram model's [piece, score] list, numbered in order, or a BPE
+// model's piece-to-id map, plus the added_tokens.
+func parseTokenizerJSON(data []byte) (map[int]string, error) {
+	var tj struct {
+		Model struct {
+			Type  string          `json:"type"`
+			Vocab json.RawMessage `json:"vocab"`
+		} `json:"model"`
+		AddedTokens []struct {
+			ID      int    `json:"id"`
+			Content string `json:"content"`
+		} `json:"added_tokens"`
+	}
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return nil, err
+	}
+	tokens := make(map[int]string)
+	switch tj.Model.Type {
+	case "Unigram":
+		var pieces [][2]any
+		if err := json.Unmarshal(tj.Model.Vocab, &pieces); err != nil {
+			return nil, fmt.Errorf("unigram vocab: %w", err)
+		}
+		for id, p := range pieces {
+			piece, ok := p[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("unigram vocab: entry %d has no piece", id)
+			}
+			tokens[id] = piece
+		}
+	case "BPE":
+		var ids map[string]int
+		if err := json.Unmarshal(tj.Model.Vocab, &ids); err != nil {
+			return nil, fmt.Errorf("BPE vocab: %w", err)
+		}
+		for piece, id := range ids {
+			tokens[id] = piece
+		}
+	default:
+		return nil, fmt.Errorf("unsupported tokenizer model %q (supported: Unigram, BPE)", tj.Model.Type)
+	}
+	for _, t := range tj.AddedTokens {
+		tokens[t.ID] = t.Content
+	}
+	return tokens, nil
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"encoding/binary"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// protoBytes encodes a length-delimited protobuf field.
+func protoBytes(field int, value []byte) []byte {
+	b := binary.AppendUvarint(nil, uint64(field<<3|2))
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// sentencePieceModel encodes a ModelProto with pieces, each with a score
+// (fixed32) and type (varint) as real models have, and a trainer spec.
+func sentencePieceModel(pieces ...string) []byte {
+	var model []byte
+	for _, p := range pieces {
+		piece := protoBytes(1, []byte(p))
+		piece = append(piece, 2<<3|5, 0, 0, 0x80, 0xbf) // score -1.0
+		piece = append(piece, 3<<3|0, 1)                // type NORMAL
+		model = append(model, protoBytes(1, piece)...)
+	}
+	return append(model, protoBytes(2, protoBytes(1, []byte("train.txt")))...)
+}
+
+func TestParseSentencePieceModel(t *testing.T) {
+	got, err := parseSentencePieceModel(sentencePieceModel("<unk>", "▁the", "s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]string{0: "<unk>", 1: "▁the", 2: "s"}; !maps.Equal(got, want) {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+	if _, err := parseSentencePieceModel([]byte{0x0a, 0xff}); err == nil {
+		t.Error("truncated protobuf: want error")
+	}
+}
+
+func TestParseTokenizerJSON(t *testing.T) {
+	unigram := `{"added_tokens": [{"id": 3, "content": "<pad>"}],
+		"model": {"type": "Unigram", "vocab": [["<unk>", 0], ["▁the", -2.5], ["s", -3.1]]}}`
+	got, err := parseTokenizerJSON([]byte(unigram))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]string{0: "<unk>", 1: "▁the", 2: "s", 3: "<pad>"}; !maps.Equal(got, want) {
+		t.Errorf("unigram tokens = %q, want %q", got, want)
+	}
+
+	bpe := `{"model": {"type": "BPE", "vocab": {"<unk>": 0, "▁the": 1, "s": 2}}}`
+	if got, err = parseTokenizerJSON([]byte(bpe)); err != nil || len(got) != 3 || got[1] != "▁the" {
+		t.Errorf("BPE tokens = %q, %v", got, err)
+	}
+
+	if _, err := parseTokenizerJSON([]byte(`{"model": {"type": "WordPiece", "vocab": {}}}`)); err == nil {
+		t.Error("WordPiece: want error")
+	}
+}
+
+func TestReadVocab(t *testing.T) {
+	dir := t.TempDir()
+	models := modelStore{dir: dir}
+	if _, _, err := readVocab(models); err == nil {
+		t.Fatal("no vocabulary: want error")
+	}
+
+	os.WriteFile(filepath.Join(dir, "tokenizer.model"), sentencePieceModel("<unk>", "▁a"), 0o644)
+	file, tokens, err := readVocab(models)
+	if err != nil || file != "tokenizer.model" || tokens[1] != "▁a" {
+		t.Fatalf("readVocab = %s, %q, %v", file, tokens, err)
+	}
+
+	// vocab.txt comes first.
+	os.WriteFile(filepath.Join(dir, "vocab.txt"), []byte("<unk> 0\n▁b 1\n<blk> 2\n"), 0o644)
+	if file, tokens, err = readVocab(models); err != nil || file != "vocab.txt" || tokens[1] != "▁b" || len(tokens) != 3 {
+		t.Fatalf("readVocab = %s, %q, %v", file, tokens, err)
+	}
+}
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// tokenWords groups decoded tokens into words. A token whose text starts
+// with a space (a SentencePiece word boundary) opens a new word; any other
+// token, punctuation included, continues the current one. A word spans from
+// its first token's frame to the end of its last token's frame.
+func (t *Transcriber) tokenWords(tokens []decodedToken, channel int) []Word {
+	frameSec := t.encoderFrameSeconds()
+
+	var words []Word
+	var text strings.Builder
+	var probSum float64
+	var n int
+	flush := func() {
+		if w := strings.TrimSpace(joinPieces(text.String())); w != "" {
+			words[len(words)-1].Text = w
+			words[len(words)-1].Confidence = probSum / float64(n)
+		} else if len(words) > 0 {
+			words = words[:len(words)-1]
+		}
+		text.Reset()
+		probSum, n = 0, 0
+	}
+	for _, tok := range tokens {
+		piece := t.tokenText(tok.id)
+		if piece == "" {
+			continue
+		}
+		if len(words) == 0 || strings.HasPrefix(piece, " ") {
+			if len(words) > 0 {
+				flush()
+			}
+			words = append(words, Word{Channel: channel, Start: float64(tok.timestep) * frameSec})
+		}
+		text.WriteString(piece)
+		probSum += float64(tok.prob)
+		n++
+		words[len(words)-1].End = float64(tok.timestep+1) * frameSec
+	}
+	if len(words) > 0 {
+		flush()
+	}
+	return words
+}
+
+// minLogprob stands in for the log of a probability that underflowed to
+// zero in float32, so logprobs stay finite.
+var minLogprob = math.Log(math.SmallestNonzeroFloat32)
+
+// tokenList returns the printable decoded tokens with their start times and
+// log-probabilities. The byte-fallback tokens spelling one character are
+// listed as a single token, starting with the first, whose log-probability
+// is their sum.
+func (t *Transcriber) tokenList(tokens []decodedToken, channel int) []Token {
+	frameSec := t.encoderFrameSeconds()
+	var out []Token
+	var pending []byte
+	var cur Token
+	flush := func() {
+		cur.Text = joinPieces(string(pending))
+		out = append(out, cur)
+		pending = pending[:0]
+	}
+	for _, tok := range tokens {
+		piece := t.tokenText(tok.id)
+		if piece == "" {
+			continue
+		}
+		logprob := minLogprob
+		if tok.prob > 0 {
+			logprob = math.Log(float64(tok.prob))
+		}
+		if len(pending) == 0 {
+			cur = Token{Channel: channel, Start: float64(tok.timestep) * frameSec}
+		}
+		pending = append(pending, piece...)
+		cur.Logprob += logprob
+		if partialRune(pending) == 0 {
+			flush()
+		}
+	}
+	if len(pending) > 0 {
+		flush()
+	}
+	return out
+}
+
+// shiftTokens moves token start times by offset seconds, in place.
+func shiftTokens(tokens []Token, offset float64) []Token {
+	if offset == 0 {
+		return tokens
+	}
+	for i := range tokens {
+		tokens[i].Start += offset
+	}
+	return tokens
+}
+
+// shiftWords moves word timings by offset seconds, in place.
+func shiftWords(words []Word, offset float64) []Word {
+	if offset == 0 {
+		return words
+	}
+	for i := range words {
+		words[i].Start += offset
+		words[i].End += offset
+	}
+	return words
+}
+
+// sortWords orders words from several channels by start time (ties by
+// channel), keeping each channel's own order.
+func sortWords(words []Word) {
+	sort.SliceStable(words, func(i, j int) bool {
+		if words[i].Start != words[j].Start {
+			return words[i].Start < words[j].Start
+		}
+		return words[i].Channel < words[j].Channel
+	})
+}
+
+// sortTokens orders tokens from several channels like sortWords.
+func sortTokens(tokens []Token) {
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if tokens[i].Start != tokens[j].Start {
+			return tokens[i].Start < tokens[j].Start
+		}
+		return tokens[i].Channel < tokens[j].Channel
+	})
+}
+
+// softmaxProb is the softmax probability of logits[idx], computed stably
+// against the maximum logit.
+func softmaxProb(logits []float32, idx int) float32 {
+	maxLogit := logits[idx]
+	for _, v := range logits {
+		if v > maxLogit {
+			maxLogit = v
+		}
+	}
+	var sum float64
+	for _, v := range logits {
+		sum += math.Exp(float64(v - maxLogit))
+	}
+	return float32(math.Exp(float64(logits[idx]-maxLogit)) / sum)
+}
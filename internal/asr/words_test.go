@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package asr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTokenWords(t *testing.T) {
+	tr := &Transcriber{
+		config: Config{SubsamplingFactor: 8},
+		mel:    NewMelFilterbank(128, 16000, DefaultMelOptions()),
+		vocab:  map[int]string{1: " hel", 2: "lo", 3: ",", 4: " world", 5: "<unk>", 6: " "},
+	}
+	tokens := []decodedToken{
+		{id: 6, timestep: 0, prob: 0.5},
+		{id: 1, timestep: 2, prob: 0.9},
+		{id: 2, timestep: 3, prob: 0.7},
+		{id: 3, timestep: 4, prob: 0.8},
+		{id: 5, timestep: 5, prob: 0.1},
+		{id: 4, timestep: 10, prob: 0.6},
+	}
+	got := tr.tokenWords(tokens, 1)
+	if len(got) != 2 {
+		t.Fatalf("got %d words, want 2: %+v", len(got), got)
+	}
+	if got[0].Text != "hello," || got[0].Start != 2*0.08 || got[0].End != 5*0.08 || got[0].Channel != 1 {
+		t.Errorf("first word = %+v", got[0])
+	}
+	if math.Abs(got[0].Confidence-0.8) > 1e-6 {
+		t.Errorf("first word confidence = %v, want 0.8 (mean of its tokens)", got[0].Confidence)
+	}
+	if got[1].Text != "world" || got[1].Start != 10*0.08 {
+		t.Errorf("second word = %+v", got[1])
+	}
+}
+
+func TestTokenList(t *testing.T) {
+	tr := &Transcriber{
+		config: Config{SubsamplingFactor: 8},
+		mel:    NewMelFilterbank(128, 16000, DefaultMelOptions()),
+		vocab:  map[int]string{1: " hel", 2: "lo", 5: "<unk>"},
+	}
+	got := tr.tokenList([]decodedToken{
+		{id: 1, timestep: 2, prob: 0.5},
+		{id: 5, timestep: 3, prob: 0.1},
+		{id: 2, timestep: 3, prob: 0},
+	}, 1)
+	if len(got) != 2 {
+		t.Fatalf("got %d tokens, want 2: %+v", len(got), got)
+	}
+	if got[0].Text != " hel" || got[0].Start != 2*0.08 || got[0].Channel != 1 || math.Abs(got[0].Logprob-math.Log(0.5)) > 1e-9 {
+		t.Errorf("first token = %+v", got[0])
+	}
+	// A probability that underflowed still has a finite logprob.
+	if math.IsInf(got[1].Logprob, 0) || got[1].Logprob >= got[0].Logprob {
+		t.Errorf("second token logprob = %v", got[1].Logprob)
+	}
+}
+
+func TestSortWords_InterleavesChannels(t *testing.T) {
+	words := []Word{{Channel: 0, Start: 0}, {Channel: 0, Start: 2}, {Channel: 1, Start: 1}, {Channel: 1, Start: 2}}
+	sortWords(words)
+	for i, want := range []int{0, 1, 0, 1} {
+		if words[i].Channel != want {
+			t.Fatalf("order = %+v", words)
+		}
+	}
+}
+
+func TestSoftmaxProb(t *testing.T) {
+	if p := softmaxProb([]float32{0, 0}, 1); math.Abs(float64(p)-0.5) > 1e-6 {
+		t.Errorf("uniform = %v, want 0.5", p)
+	}
+	// Large logits must not overflow.
+	if p := softmaxProb([]float32{1000, 0}, 0); math.Abs(float64(p)-1) > 1e-6 {
+		t.Errorf("dominant = %v, want 1", p)
+	}
+}
@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package itn
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// clock is a time of day read from a transcript.
+type clock struct {
+	hour, minute int
+	minutes      bool   // the minutes were said or written
+	meridiem     string // "am", "pm" or empty
+	fields       int
+}
+
+var clockRe = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?([ap]\.?m\.?)?$`)
+
+// meridiemAt reads "am", "p.m" and the like at fs[i].
+func meridiemAt(fs []field, i int) string {
+	if !follows(fs, i) {
+		return ""
+	}
+	switch strings.ReplaceAll(fs[i].lower(), ".", "") {
+	case "am":
+		return "am"
+	case "pm":
+		return "pm"
+	}
+	return ""
+}
+
+// follows reports whether fs[i] continues the phrase before it: no frozen
+// field and no punctuation in between.
+func follows(fs []field, i int) bool {
+	return i > 0 && i < len(fs) && !fs[i].frozen && fs[i].pre == "" && fs[i-1].post == ""
+}
+
+func isOclock(f field) bool {
+	return f.lower() == "o'clock" || f.lower() == "o’clock"
+}
+
+// readClock reads the time at fs[i]: "3:30 PM", "7am", "three thirty p.m.",
+// "seven o'clock". Spoken times need a meridiem or o'clock, or to follow
+// "at" with their minutes said.
+func readClock(fs []field, i int) (clock, bool) {
+	if fs[i].frozen {
+		return clock{}, false
+	}
+	if m := clockRe.FindStringSubmatch(fs[i].lower()); m != nil {
+		c := clock{fields: 1, minutes: m[2] != ""}
+		c.hour, _ = strconv.Atoi(m[1])
+		c.minute, _ = strconv.Atoi(m[2])
+		c.meridiem = strings.ReplaceAll(m[3], ".", "")
+		if c.meridiem == "" {
+			if c.meridiem = meridiemAt(fs, i+1); c.meridiem != "" {
+				c.fields++
+			}
+		}
+		if c.hour > 23 || c.minute > 59 || c.meridiem != "" && (c.hour == 0 || c.hour > 12) || !c.minutes && c.meridiem == "" {
+			return clock{}, false
+		}
+		return c, true
+	}
+
+	w, ok := spokenValues[fs[i].lower()]
+	if !ok || w.ordinal || w.value < 1 || w.value > 12 {
+		return clock{}, false
+	}
+	c := clock{hour: int(w.value), fields: 1}
+	if follows(fs, i+1) && fs[i+1].lower() == "oh" {
+		if m, ok := spokenValues[safeLower(fs, i+2)]; ok && follows(fs, i+2) && m.kind == kindUnit && !m.ordinal {
+			c.minute, c.minutes, c.fields = int(m.value), true, 3
+		}
+	} else if follows(fs, i+1) {
+		if m, ok := readSpoken(fs, i+1); ok && !m.ordinal && !m.year && !m.negative && m.decimals == "" && m.value >= 10 && m.value < 60 {
+			c.minute, c.minutes = int(m.value), true
+			c.fields += m.fields
+		}
+	}
+	oclock := false
+	if j := i + c.fields; follows(fs, j) {
+		if c.meridiem = meridiemAt(fs, j); c.meridiem != "" {
+			c.fields++
+		} else if !c.minutes && isOclock(fs[j]) {
+			oclock, c.minutes = true, true
+			c.fields++
+		}
+	}
+	afterAt := i > 0 && fs[i-1].lower() == "at" && fs[i-1].post == ""
+	if c.meridiem == "" && !oclock && !(c.minutes && afterAt) {
+		return clock{}, false
+	}
+	return c, true
+}
+
+func safeLower(fs []field, i int) string {
+	if i >= len(fs) {
+		return ""
+	}
+	return fs[i].lower()
+}
+
+// digits writes the time as "3:30 PM", "7 AM" or "19:00".
+func (c clock) digits() []field {
+	var s string
+	if c.minutes {
+		s = fmt.Sprintf("%d:%02d", c.hour, c.minute)
+	} else {
+		s = strconv.Itoa(c.hour)
+	}
+	if c.meridiem == "" {
+		return []field{{core: s}}
+	}
+	return []field{{core: s}, {core: strings.ToUpper(c.meridiem)}}
+}
+
+// words writes the time as "three thirty p.m.", "seven a.m." or "seven
+// o'clock".
+func (c clock) words() []field {
+	s := cardinalWords(int64(c.hour))
+	switch {
+	case c.minute > 0 && c.minute < 10:
+		s += " oh " + unitWords[c.minute]
+	case c.minute > 0:
+		s += " " + cardinalWords(int64(c.minute))
+	case c.meridiem == "":
+		s += " o'clock"
+	}
+	out := words(s)
+	if c.meridiem != "" {
+		out = append(out, field{core: c.meridiem[:1] + ".m."})
+	}
+	return out
+}
+
+func formatTimes(fs []field, style Style) []field {
+	for i := 0; i < len(fs); i++ {
+		c, ok := readClock(fs, i)
+		if !ok {
+			continue
+		}
+		out := c.digits()
+		if style == Words {
+			out = c.words()
+		}
+		post := fs[i+c.fields-1].post
+		fs = replace(fs, i, c.fields, out...)
+		i += len(out) - 1
+		// "p.m." ends a sentence with its own dot; keep one, and only there.
+		last := &fs[i]
+		if c.meridiem != "" && strings.HasPrefix(post, ".") {
+			last.post = strings.TrimPrefix(last.post, ".")
+			if style == Digits && (i+1 == len(fs) || startsUpper(fs[i+1])) {
+				last.post = "." + last.post
+			}
+		}
+	}
+	return fs
+}
+
+func startsUpper(f field) bool {
+	for _, r := range f.pre + f.core {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+var months = []string{"january", "february", "march", "april", "may", "june",
+	"july", "august", "september", "october", "november", "december"}
+
+func isMonth(f field) bool {
+	return startsUpper(f) && slices.Contains(months, f.lower())
+}
+
+// readDay reads a day of the month, 1 to 31, at fs[i]: "5", "5th", "fifth"
+// or "twenty-first".
+func readDay(fs []field, i int) (number, bool) {
+	if i >= len(fs) || fs[i].frozen {
+		return number{}, false
+	}
+	n, ok := readDigits(fs, i)
+	if !ok {
+		n, ok = readSpoken(fs, i)
+	}
+	if !ok || n.year || n.negative || n.decimals != "" || n.value < 1 || n.value > 31 {
+		return number{}, false
+	}
+	return n, true
+}
+
+// readYear reads the year after a date's day: "2024" or "twenty
+// twenty-four", with or without a comma in between.
+func readYear(fs []field, i int) (number, bool) {
+	if i >= len(fs) || fs[i].frozen || fs[i].pre != "" || fs[i-1].post != "" && fs[i-1].post != "," {
+		return number{}, false
+	}
+	n, ok := readDigits(fs, i)
+	if !ok {
+		n, ok = readSpokenNumber(fs, i, true)
+	}
+	if !ok || n.value < 1000 || n.value > 2999 || n.ordinal || n.negative || n.decimals != "" {
+		return number{}, false
+	}
+	n.year = true
+	return n, true
+}
+
+// formatDates rewrites the day and year of "January fifth, twenty
+// twenty-four" and the day of "the fifth of January": digits give
+// "January 5, 2024" and "the 5th of January", words the reverse. Months
+// are only told from other words when capitalized ("you may").
+func formatDates(fs []field, style Style) []field {
+	write := func(n number, ordinal bool) []field {
+		n.ordinal = ordinal
+		if style == Words {
+			n.ordinal = !n.year
+			return words(n.words())
+		}
+		return []field{{core: n.digits()}}
+	}
+	for i := 0; i < len(fs); i++ {
+		if fs[i].frozen {
+			continue
+		}
+		if isMonth(fs[i]) {
+			day, ok := readDay(fs, i+1)
+			if !ok || !follows(fs, i+1) {
+				continue
+			}
+			year, hasYear := readYear(fs, i+1+day.fields)
+			fs[i].frozen = true
+			out := write(day, false)
+			fs = replace(fs, i+1, day.fields, out...)
+			i += len(out)
+			if hasYear {
+				if style == Digits && !strings.HasSuffix(fs[i].post, ",") {
+					fs[i].post += ","
+				}
+				out = write(year, false)
+				fs = replace(fs, i+1, year.fields, out...)
+				i += len(out)
+			}
+			continue
+		}
+		day, ok := readDay(fs, i)
+		if !ok || !day.ordinal || !follows(fs, i+day.fields) || fs[i+day.fields].lower() != "of" || !follows(fs, i+day.fields+1) || !isMonth(fs[i+day.fields+1]) {
+			continue
+		}
+		out := write(day, true)
+		fs = replace(fs, i, day.fields, out...)
+		i += len(out) - 1
+	}
+	return fs
+}
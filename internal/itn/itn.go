@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package itn formats the numbers, dates, times and units of an English
+// transcript either way: inverse text normalization ("twenty five
+// kilometers" to "25 km") and its reverse, for speech synthesis or
+// command parsers that match words. It rewrites plain text; anything it
+// does not recognise is left as it was.
+package itn
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Style is how a kind of entity is written. Empty leaves it as recognised.
+type Style string
+
+const (
+	Digits Style = "digits"
+	Words  Style = "words"
+)
+
+// UnitStyle is how units after a number are written. Empty leaves them as
+// recognised.
+type UnitStyle string
+
+const (
+	// Abbreviate writes unit symbols: "5 km", "20%".
+	Abbreviate UnitStyle = "abbreviate"
+	// Expand writes unit names: "5 kilometers", "20 percent".
+	Expand UnitStyle = "expand"
+)
+
+// Options selects what Apply rewrites. The zero value changes nothing.
+type Options struct {
+	Numbers Style     `json:"numbers,omitempty"`
+	Dates   Style     `json:"dates,omitempty"`
+	Times   Style     `json:"times,omitempty"`
+	Units   UnitStyle `json:"units,omitempty"`
+}
+
+// Enabled reports whether o rewrites anything.
+func (o Options) Enabled() bool {
+	return o != Options{}
+}
+
+// Parse reads a comma-separated list of kind=style settings, such as
+// "numbers=digits,units=abbreviate". Kinds are numbers, dates and times
+// (digits or words) and units (abbreviate or expand); a kind left out is
+// not rewritten. An empty spec is the zero Options.
+func Parse(spec string) (Options, error) {
+	var o Options
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kind, value, ok := strings.Cut(part, "=")
+		kind, value = strings.ToLower(strings.TrimSpace(kind)), strings.ToLower(strings.TrimSpace(value))
+		if !ok {
+			return o, fmt.Errorf("formatting %q is not kind=style", part)
+		}
+		var style *Style
+		switch kind {
+		case "numbers":
+			style = &o.Numbers
+		case "dates":
+			style = &o.Dates
+		case "times":
+			style = &o.Times
+		case "units":
+			if u := UnitStyle(value); u == Abbreviate || u == Expand {
+				o.Units = u
+				continue
+			}
+			return o, fmt.Errorf("unsupported units formatting %q (supported: abbreviate, expand)", value)
+		default:
+			return o, fmt.Errorf("unsupported formatting kind %q (supported: numbers, dates, times, units)", kind)
+		}
+		if s := Style(value); s == Digits || s == Words {
+			*style = s
+			continue
+		}
+		return o, fmt.Errorf("unsupported %s formatting %q (supported: digits, words)", kind, value)
+	}
+	return o, nil
+}
+
+// Merge returns o with the kinds set in over replaced.
+func (o Options) Merge(over Options) Options {
+	if over.Numbers != "" {
+		o.Numbers = over.Numbers
+	}
+	if over.Dates != "" {
+		o.Dates = over.Dates
+	}
+	if over.Times != "" {
+		o.Times = over.Times
+	}
+	if over.Units != "" {
+		o.Units = over.Units
+	}
+	return o
+}
+
+// String is the spec Parse reads back, kinds in a fixed order.
+func (o Options) String() string {
+	var parts []string
+	for _, kv := range [][2]string{
+		{"numbers", string(o.Numbers)}, {"dates", string(o.Dates)},
+		{"times", string(o.Times)}, {"units", string(o.Units)},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Applies reports whether transcripts in language are rewritten: the rules
+// are English, so other languages are left as recognised. An empty
+// language (detected by the model) is treated as English.
+func Applies(language string) bool {
+	language = strings.ToLower(language)
+	return language == "" || language == "en" || strings.HasPrefix(language, "en-")
+}
+
+// Apply rewrites text: times first, then dates, then units, then the
+// numbers left. Whitespace is collapsed to single spaces.
+func (o Options) Apply(text string) string {
+	if !o.Enabled() {
+		return text
+	}
+	fs := splitFused(split(text))
+	if o.Times != "" {
+		fs = formatTimes(fs, o.Times)
+	}
+	if o.Dates != "" {
+		fs = formatDates(fs, o.Dates)
+	}
+	if o.Units != "" {
+		fs = formatUnits(fs, o.Units)
+	}
+	if o.Numbers != "" {
+		fs = formatNumbers(fs, o.Numbers)
+	}
+	return join(fs)
+}
+
+// field is one whitespace-separated word, split into its leading and
+// trailing punctuation and the core the rules match.
+type field struct {
+	pre, core, post string
+	glue            bool // joined to the previous field without a space
+	frozen          bool // written by a rule; later ones leave it
+}
+
+const (
+	leadingPunct  = "\"'([“‘¿¡"
+	trailingPunct = ".,!?;:)]\"'”’…"
+)
+
+func split(text string) []field {
+	words := strings.Fields(text)
+	fs := make([]field, 0, len(words))
+	for _, w := range words {
+		core := strings.TrimLeft(w, leadingPunct)
+		pre := w[:len(w)-len(core)]
+		trimmed := strings.TrimRight(core, trailingPunct)
+		post := core[len(trimmed):]
+		if trimmed == "" {
+			fs = append(fs, field{core: w})
+			continue
+		}
+		fs = append(fs, field{pre: pre, core: trimmed, post: post})
+	}
+	return fs
+}
+
+func join(fs []field) string {
+	var b strings.Builder
+	for i, f := range fs {
+		if i > 0 && !f.glue {
+			b.WriteByte(' ')
+		}
+		b.WriteString(f.pre)
+		b.WriteString(f.core)
+		b.WriteString(f.post)
+	}
+	return b.String()
+}
+
+// lower is the field's core in lower case.
+func (f field) lower() string {
+	return strings.ToLower(f.core)
+}
+
+// replace puts the fields of out in place of fs[i:i+n], with the first
+// one's leading and the last one's trailing punctuation, frozen.
+func replace(fs []field, i, n int, out ...field) []field {
+	if len(out) == 0 {
+		return slices.Delete(fs, i, i+n)
+	}
+	out[0].pre = fs[i].pre + out[0].pre
+	out[0].glue = fs[i].glue
+	out[len(out)-1].post += fs[i+n-1].post
+	for k := range out {
+		out[k].frozen = true
+	}
+	return slices.Replace(fs, i, i+n, out...)
+}
+
+// words builds unfrozen fields from space-separated text.
+func words(text string) []field {
+	var out []field
+	for _, w := range strings.Fields(text) {
+		out = append(out, field{core: w})
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package itn
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	digits := Options{Numbers: Digits, Dates: Digits, Times: Digits, Units: Abbreviate}
+	spelled := Options{Numbers: Words, Dates: Words, Times: Words, Units: Expand}
+	tests := []struct {
+		opts     Options
+		in, want string
+	}{
+		{digits, "I ran twenty five kilometers in two hours.", "I ran 25 km in two hours."},
+		{digits, "one of them costs three hundred and five dollars", "one of them costs 305 dollars"},
+		{digits, "five kilometers", "5 km"},
+		{digits, "a thousand people, forty-two percent of them", "1000 people, 42% of them"},
+		{digits, "twelve thousand five hundred", "12,500"},
+		{digits, "pi is three point one four", "pi is 3.14"},
+		{digits, "it was minus five degrees Celsius", "it was -5°C"},
+		{digits, "born in nineteen ninety nine, moved in nineteen oh five", "born in 1999, moved in 1905"},
+		{digits, "the twenty first time", "the 21st time"},
+		{digits, "We meet at three thirty p.m. tomorrow.", "We meet at 3:30 PM tomorrow."},
+		{digits, "Wake me at seven a.m.", "Wake me at 7 AM."},
+		{digits, "It is seven o'clock.", "It is 7:00."},
+		{digits, "see you at ten fifteen", "see you at 10:15"},
+		{digits, "On January fifth twenty twenty four we left.", "On January 5, 2024 we left."},
+		{digits, "the twenty-first of March", "the 21st of March"},
+		{digits, "you may one day", "you may one day"},
+		{digits, "since nineteen eighty four", "since 1984"},
+		{digits, "the year twenty twenty", "the year 2020"},
+		{digits, "March nineteen sixty nine", "March 1969"},
+		// Clock times and counts are not years.
+		{Options{Numbers: Digits}, "ten thirty", "10 30"},
+		{Options{Numbers: Digits}, "see you ten fifteen", "see you 10 15"},
+		{digits, "twelve thirty apples", "12 30 apples"},
+		{digits, "at twelve thirty", "at 12:30"},
+		{digits, "in ten fifteen", "in 10 15"},
+		{digits, "in nineteen twenty meters", "in 19 20 m"},
+
+		{spelled, "I ran 25 km in 2 hours.", "I ran twenty-five kilometers in two hours."},
+		{spelled, "It grew 42% to 1,250,000.", "It grew forty-two percent to one million two hundred fifty thousand."},
+		{spelled, "1 kg and 3.5 L", "one kilogram and three point five liters"},
+		{spelled, "in 1999 and 2005", "in nineteen ninety-nine and two thousand five"},
+		{spelled, "1500 meters", "one thousand five hundred meters"},
+		{spelled, "1984 apples", "one thousand nine hundred eighty-four apples"},
+		{spelled, "since 1984", "since nineteen eighty-four"},
+		{spelled, "zip 02134", "zip zero two one three four"},
+		{spelled, "agent 007.", "agent zero zero seven."},
+		{spelled, "0.5 and 0", "zero point five and zero"},
+		{spelled, "the 21st", "the twenty-first"},
+		{spelled, "At 3:05 PM.", "At three oh five p.m."},
+		{spelled, "7am and 19:00", "seven a.m. and nineteen o'clock"},
+		{spelled, "On January 5, 2024.", "On January fifth, twenty twenty-four."},
+
+		{Options{Numbers: Digits}, "twenty five kilometers in two hours", "25 kilometers in two hours"},
+		{Options{Units: Expand}, "1 km, 2 km", "1 kilometer, 2 kilometers"},
+		{Options{Units: Abbreviate}, "twenty five kilometers", "twenty five km"},
+		{Options{Units: Expand}, "5km", "5 kilometers"},
+		{Options{Numbers: Words}, "5%", "five percent"},
+		{Options{}, "twenty five", "twenty five"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Apply(tt.in); got != tt.want {
+			t.Errorf("%v.Apply(%q) = %q, want %q", tt.opts, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	o, err := Parse(" numbers=digits, units=Abbreviate ,times=words")
+	if err != nil || o != (Options{Numbers: Digits, Times: Words, Units: Abbreviate}) {
+		t.Fatalf("Parse = %+v, %v", o, err)
+	}
+	if o.String() != "numbers=digits,times=words,units=abbreviate" {
+		t.Errorf("String() = %q", o.String())
+	}
+	if got := o.Merge(Options{Numbers: Words}); got.Numbers != Words || got.Units != Abbreviate {
+		t.Errorf("Merge = %+v", got)
+	}
+	for _, bad := range []string{"numbers", "numbers=roman", "units=digits", "currency=digits"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) accepted", bad)
+		}
+	}
+	if o, err := Parse(""); err != nil || o.Enabled() {
+		t.Errorf("Parse(\"\") = %+v, %v", o, err)
+	}
+}
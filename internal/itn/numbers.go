@@ -0,0 +1,472 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package itn
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	unitWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	tenWords = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	unitNths = []string{"zeroth", "first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth",
+		"tenth", "eleventh", "twelfth", "thirteenth", "fourteenth", "fifteenth", "sixteenth", "seventeenth", "eighteenth", "nineteenth"}
+	tenNths = []string{"", "", "twentieth", "thirtieth", "fortieth", "fiftieth", "sixtieth", "seventieth", "eightieth", "ninetieth"}
+	scales  = []struct {
+		word  string
+		value int64
+	}{{"trillion", 1e12}, {"billion", 1e9}, {"million", 1e6}, {"thousand", 1e3}}
+
+	// spokenValues maps every number word to its value and kind, ordinals
+	// included.
+	spokenValues = map[string]spokenWord{}
+)
+
+type wordKind int
+
+const (
+	kindUnit    wordKind = iota // one to nine
+	kindZero                    // zero
+	kindTeen                    // ten to nineteen
+	kindTen                     // twenty, thirty...
+	kindHundred                 // hundred
+	kindScale                   // thousand, million...
+)
+
+type spokenWord struct {
+	value   int64
+	kind    wordKind
+	ordinal bool
+}
+
+func init() {
+	for v, w := range unitWords {
+		kind := kindUnit
+		switch {
+		case v == 0:
+			kind = kindZero
+		case v >= 10:
+			kind = kindTeen
+		}
+		spokenValues[w] = spokenWord{value: int64(v), kind: kind}
+		spokenValues[unitNths[v]] = spokenWord{value: int64(v), kind: kind, ordinal: true}
+	}
+	for v := 2; v < 10; v++ {
+		spokenValues[tenWords[v]] = spokenWord{value: int64(v * 10), kind: kindTen}
+		spokenValues[tenNths[v]] = spokenWord{value: int64(v * 10), kind: kindTen, ordinal: true}
+	}
+	spokenValues["hundred"] = spokenWord{value: 100, kind: kindHundred}
+	spokenValues["hundredth"] = spokenWord{value: 100, kind: kindHundred, ordinal: true}
+	for _, s := range scales {
+		spokenValues[s.word] = spokenWord{value: s.value, kind: kindScale}
+		spokenValues[s.word+"th"] = spokenWord{value: s.value, kind: kindScale, ordinal: true}
+	}
+}
+
+// number is a number read from the fields of a transcript, spoken or in
+// digits.
+type number struct {
+	value    int64
+	decimals string // the digits after the point
+	negative bool
+	ordinal  bool
+	year     bool   // read as a year: "nineteen ninety nine"
+	padded   string // digits written with a leading zero: "007", "02134"
+	plain    bool   // a single word below ten: "five"
+	fields   int    // fields it spans
+	spoken   bool
+}
+
+// spokenParts splits a field's core into number words, hyphenated ones
+// included, or returns nil if any part is not one.
+func spokenParts(f field) []string {
+	parts := strings.Split(f.lower(), "-")
+	for _, p := range parts {
+		if _, ok := spokenValues[p]; !ok {
+			return nil
+		}
+	}
+	return parts
+}
+
+// yearCues are the words after which a number is read as a year, besides
+// a month: "in nineteen ninety nine", "since 1984".
+var yearCues = []string{"in", "since", "year", "of", "circa"}
+
+// yearContext reports whether the number at fs[i] follows a month or a year
+// cue. Elsewhere "twelve thirty" is a time or a count, not 1230.
+func yearContext(fs []field, i int) bool {
+	if i == 0 || fs[i-1].post != "" || fs[i].pre != "" {
+		return false
+	}
+	return isMonth(fs[i-1]) || slices.Contains(yearCues, fs[i-1].lower())
+}
+
+// readSpoken reads the spoken number starting at fs[i]. It stops at a
+// frozen field, at leading punctuation past the first field and after
+// trailing punctuation. Two numbers are paired into a year only in a year
+// context; see readSpokenNumber.
+func readSpoken(fs []field, i int) (number, bool) {
+	return readSpokenNumber(fs, i, yearContext(fs, i))
+}
+
+// readSpokenNumber is readSpoken; years pairs "nineteen ninety nine" and
+// "nineteen oh five" into one number, from 1100 up, unless a unit follows.
+func readSpokenNumber(fs []field, i int, years bool) (number, bool) {
+	n := number{spoken: true}
+	j := i
+	usable := func(j int) bool {
+		return j < len(fs) && !fs[j].frozen && (j == i || fs[j].pre == "" && fs[j-1].post == "")
+	}
+	if usable(j) && usable(j+1) && (fs[j].lower() == "minus" || fs[j].lower() == "negative") && spokenParts(fs[j+1]) != nil {
+		n.negative = true
+		j++
+	}
+
+	var total, current, lastScale int64
+	last := -1 // wordKind of the last word, -1 before the first
+	words := 0
+	hundreds := false // current includes a hundred
+read:
+	for usable(j) {
+		core := fs[j].lower()
+		if core == "and" && (last == int(kindHundred) || last == int(kindScale)) && usable(j+1) {
+			if p := spokenParts(fs[j+1]); p != nil && spokenValues[p[0]].kind != kindHundred && spokenValues[p[0]].kind != kindScale {
+				j++
+				continue
+			}
+			break
+		}
+		if core == "a" && words == 0 && usable(j+1) {
+			if p := spokenParts(fs[j+1]); len(p) == 1 && (p[0] == "hundred" || p[0] == "thousand" || p[0] == "million") {
+				current, last, words = 1, int(kindUnit), 1
+				j++
+				continue
+			}
+		}
+		parts := spokenParts(fs[j])
+		if parts == nil {
+			break
+		}
+		for k, p := range parts {
+			w := spokenValues[p]
+			ok := false
+			switch w.kind {
+			case kindZero:
+				ok = last == -1
+			case kindUnit:
+				ok = last == -1 || last == int(kindTen) || last == int(kindHundred) || last == int(kindScale)
+			case kindTeen, kindTen:
+				ok = last == -1 || last == int(kindHundred) || last == int(kindScale)
+			case kindHundred:
+				ok = !hundreds && current > 0 && current < 100 && (last == int(kindUnit) || last == int(kindTeen) || last == int(kindTen))
+			case kindScale:
+				ok = last != -1 && last != int(kindScale) && (lastScale == 0 || w.value < lastScale)
+			}
+			if !ok || (k > 0 && w.kind >= kindHundred) {
+				if k > 0 {
+					return number{}, false // a hyphenated word that is not a number
+				}
+				break read
+			}
+			switch w.kind {
+			case kindHundred:
+				current *= 100
+				hundreds = true
+			case kindScale:
+				total += current * w.value
+				current, lastScale, hundreds = 0, w.value, false
+			default:
+				current += w.value
+			}
+			last = int(w.kind)
+			words++
+			if w.ordinal {
+				if k != len(parts)-1 {
+					return number{}, false
+				}
+				n.ordinal = true
+			}
+		}
+		j++
+		if n.ordinal || last == int(kindZero) || fs[j-1].post != "" {
+			break
+		}
+	}
+	if words == 0 {
+		return number{}, false
+	}
+	n.value = total + current
+	n.fields = j - i
+	n.plain = words == 1 && n.value < 10 && !n.negative
+
+	if !n.ordinal && usable(j) && usable(j+1) && fs[j].lower() == "point" && fs[j-1].post == "" {
+		k := j + 1
+		for ; usable(k); k++ {
+			v, ok := digitWord(fs[k].lower())
+			if !ok {
+				break
+			}
+			n.decimals += v
+			if fs[k].post != "" {
+				k++
+				break
+			}
+		}
+		if n.decimals != "" {
+			n.fields = k - i
+			n.plain = false
+		}
+	}
+
+	// "nineteen ninety nine", "twenty twenty four", "nineteen oh five".
+	if years && n.decimals == "" && !n.ordinal && !n.negative && lastScale == 0 && !hundreds && n.value >= 11 && n.value < 100 && usable(j) {
+		if fs[j].lower() == "oh" && usable(j+1) {
+			if v, ok := spokenValues[fs[j+1].lower()]; ok && v.kind == kindUnit && !v.ordinal && unitAt(fs, j+2) == nil {
+				n.value, n.year, n.plain = n.value*100+v.value, true, false
+				n.fields = j + 2 - i
+			}
+		} else if next, ok := readSpokenNumber(fs, j, false); ok && !next.negative && next.decimals == "" && next.value >= 10 && next.value < 100 && unitAt(fs, j+next.fields) == nil {
+			n.value, n.year, n.plain = n.value*100+next.value, true, false
+			n.ordinal = next.ordinal
+			n.fields += next.fields
+		}
+	}
+	return n, true
+}
+
+func digitWord(w string) (string, bool) {
+	if w == "oh" {
+		return "0", true
+	}
+	if v, ok := spokenValues[w]; ok && !v.ordinal && (v.kind == kindUnit || v.kind == kindZero) {
+		return strconv.FormatInt(v.value, 10), true
+	}
+	return "", false
+}
+
+var (
+	digitsRe  = regexp.MustCompile(`^(-?)(\d{1,3}(?:,\d{3})+|\d+)(?:\.(\d+))?$`)
+	ordinalRe = regexp.MustCompile(`^(\d+)(?:st|nd|rd|th)$`)
+)
+
+// readDigits reads the number written in digits in fs[i].
+func readDigits(fs []field, i int) (number, bool) {
+	if i >= len(fs) || fs[i].frozen {
+		return number{}, false
+	}
+	core := fs[i].lower()
+	if m := ordinalRe.FindStringSubmatch(core); m != nil {
+		v, err := strconv.ParseInt(m[1], 10, 64)
+		return number{value: v, ordinal: true, fields: 1}, err == nil
+	}
+	m := digitsRe.FindStringSubmatch(core)
+	if m == nil {
+		return number{}, false
+	}
+	v, err := strconv.ParseInt(strings.ReplaceAll(m[2], ",", ""), 10, 64)
+	if err != nil {
+		return number{}, false
+	}
+	return number{value: v, negative: m[1] != "", decimals: m[3], fields: 1,
+		padded: paddedDigits(m[1], m[2], m[3])}, true
+}
+
+// paddedDigits returns digits when they are a whole number with a leading
+// zero, "" otherwise.
+func paddedDigits(sign, digits, decimals string) string {
+	if sign != "" || decimals != "" || len(digits) < 2 || digits[0] != '0' || strings.Contains(digits, ",") {
+		return ""
+	}
+	return digits
+}
+
+// digitsYear reports whether n, read by readDigits at fs[i], is a year: four
+// digits from 1100 to 2099 in a year context, with no unit after them.
+func digitsYear(fs []field, i int, n number) bool {
+	return n.padded == "" && !n.negative && !n.ordinal && n.decimals == "" && len(fs[i].core) == 4 &&
+		n.value >= 1100 && n.value < 2100 && yearContext(fs, i) && unitAt(fs, i+1) == nil
+}
+
+// readNumber reads the number at fs[i] in either form.
+func readNumber(fs []field, i int) (number, bool) {
+	if n, ok := readDigits(fs, i); ok {
+		return n, true
+	}
+	return readSpoken(fs, i)
+}
+
+// digits writes n in digits: "25", "1,250,000", "3.5", "21st". Numbers
+// from 10,000 up are grouped by thousands, years never.
+func (n number) digits() string {
+	s := strconv.FormatInt(n.value, 10)
+	if n.value >= 10000 && !n.year {
+		var b strings.Builder
+		for k, c := range s {
+			if k > 0 && (len(s)-k)%3 == 0 {
+				b.WriteByte(',')
+			}
+			b.WriteRune(c)
+		}
+		s = b.String()
+	}
+	if n.decimals != "" {
+		s += "." + n.decimals
+	}
+	if n.negative {
+		s = "-" + s
+	}
+	if n.ordinal {
+		s += ordinalSuffix(n.value)
+	}
+	return s
+}
+
+func ordinalSuffix(v int64) string {
+	if v%100 >= 11 && v%100 <= 13 {
+		return "th"
+	}
+	switch v % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	}
+	return "th"
+}
+
+// words writes n in words: "twenty-five", "three point five",
+// "twenty-first", "nineteen ninety-nine". Padded digits are read one by
+// one, "zero zero seven", as a code or zip would be.
+func (n number) words() string {
+	var s string
+	switch {
+	case n.padded != "":
+		digits := make([]string, len(n.padded))
+		for k, c := range n.padded {
+			digits[k] = unitWords[c-'0']
+		}
+		s = strings.Join(digits, " ")
+	case n.year && !n.ordinal:
+		s = yearWords(n.value)
+	case n.ordinal:
+		s = ordinalWords(n.value)
+	default:
+		s = cardinalWords(n.value)
+	}
+	if n.decimals != "" {
+		s += " point"
+		for _, c := range n.decimals {
+			s += " " + unitWords[c-'0']
+		}
+	}
+	if n.negative {
+		s = "minus " + s
+	}
+	return s
+}
+
+func cardinalWords(v int64) string {
+	if v < 20 {
+		return unitWords[v]
+	}
+	if v < 100 {
+		if v%10 == 0 {
+			return tenWords[v/10]
+		}
+		return tenWords[v/10] + "-" + unitWords[v%10]
+	}
+	if v < 1000 {
+		s := unitWords[v/100] + " hundred"
+		if v%100 != 0 {
+			s += " " + cardinalWords(v%100)
+		}
+		return s
+	}
+	for _, sc := range scales {
+		if v >= sc.value {
+			s := cardinalWords(v/sc.value) + " " + sc.word
+			if v%sc.value != 0 {
+				s += " " + cardinalWords(v%sc.value)
+			}
+			return s
+		}
+	}
+	return strconv.FormatInt(v, 10) // past the trillions
+}
+
+func ordinalWords(v int64) string {
+	s := cardinalWords(v)
+	cut := max(strings.LastIndexAny(s, " -"), -1) + 1
+	last := s[cut:]
+	if w, ok := spokenValues[last]; ok {
+		switch w.kind {
+		case kindTen:
+			return s[:cut] + tenNths[w.value/10]
+		case kindHundred, kindScale:
+			return s + "th"
+		default:
+			return s[:cut] + unitNths[w.value]
+		}
+	}
+	return s + ordinalSuffix(v)
+}
+
+// yearWords reads a year the way it is said: "nineteen ninety-nine",
+// "nineteen oh five", "two thousand five", "twenty twenty-four".
+func yearWords(v int64) string {
+	hi, lo := v/100, v%100
+	switch {
+	case v >= 2000 && v < 2010:
+		return cardinalWords(v)
+	case lo == 0:
+		return cardinalWords(hi) + " hundred"
+	case lo < 10:
+		return cardinalWords(hi) + " oh " + unitWords[lo]
+	}
+	return cardinalWords(hi) + " " + cardinalWords(lo)
+}
+
+// formatNumbers rewrites the numbers the earlier rules left. Digits leave
+// single words below ten spelled out ("one of them") unless a unit
+// follows, as style guides write them.
+func formatNumbers(fs []field, style Style) []field {
+	for i := 0; i < len(fs); i++ {
+		if fs[i].frozen {
+			continue
+		}
+		switch style {
+		case Digits:
+			n, ok := readSpoken(fs, i)
+			if !ok || n.plain && unitAt(fs, i+n.fields) == nil {
+				continue
+			}
+			fs = replace(fs, i, n.fields, field{core: n.digits()})
+			if i+1 < len(fs) && fs[i+1].frozen && (fs[i+1].core == "%" || strings.HasPrefix(fs[i+1].core, "°")) {
+				fs[i+1].glue = true
+			}
+		case Words:
+			n, ok := readDigits(fs, i)
+			if !ok {
+				continue
+			}
+			n.year = digitsYear(fs, i, n) // "in 1984", not "1984 apples" or "1500 meters"
+			fs = replace(fs, i, 1, words(n.words())...)
+			i += strings.Count(n.words(), " ")
+			if i+1 < len(fs) && fs[i+1].glue {
+				fs[i+1].glue = false
+				if fs[i+1].core == "%" {
+					fs[i+1].core = "percent"
+				}
+			}
+		}
+	}
+	return fs
+}
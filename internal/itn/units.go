@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package itn
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// unit is a unit written after a number, by its symbol or by name.
+type unit struct {
+	symbol           string
+	singular, plural string
+}
+
+// units lists the SI units, with their common prefixes, and the few others
+// transcripts are full of. Names of two words come before their first word
+// alone.
+var units = []unit{
+	{"%", "percent", "percent"},
+	{"km/h", "kilometer per hour", "kilometers per hour"},
+	{"°C", "degree Celsius", "degrees Celsius"},
+	{"°F", "degree Fahrenheit", "degrees Fahrenheit"},
+	{"kWh", "kilowatt hour", "kilowatt hours"},
+	{"km", "kilometer", "kilometers"},
+	{"m", "meter", "meters"},
+	{"cm", "centimeter", "centimeters"},
+	{"mm", "millimeter", "millimeters"},
+	{"kg", "kilogram", "kilograms"},
+	{"g", "gram", "grams"},
+	{"mg", "milligram", "milligrams"},
+	{"L", "liter", "liters"},
+	{"mL", "milliliter", "milliliters"},
+	{"ms", "millisecond", "milliseconds"},
+	{"Hz", "hertz", "hertz"},
+	{"kHz", "kilohertz", "kilohertz"},
+	{"MHz", "megahertz", "megahertz"},
+	{"GHz", "gigahertz", "gigahertz"},
+	{"W", "watt", "watts"},
+	{"kW", "kilowatt", "kilowatts"},
+	{"V", "volt", "volts"},
+	{"mAh", "milliamp hour", "milliamp hours"},
+	{"KB", "kilobyte", "kilobytes"},
+	{"MB", "megabyte", "megabytes"},
+	{"GB", "gigabyte", "gigabytes"},
+	{"TB", "terabyte", "terabytes"},
+}
+
+// unitNames maps the lower-case names of units, British spellings
+// included, to their entry.
+var unitNames = map[string]*unit{}
+
+func init() {
+	for k := range units {
+		u := &units[k]
+		for _, name := range []string{u.singular, u.plural} {
+			name = strings.ToLower(name)
+			unitNames[name] = u
+			if strings.Contains(name, "meter") || strings.Contains(name, "liter") {
+				unitNames[strings.NewReplacer("meter", "metre", "liter", "litre").Replace(name)] = u
+			}
+		}
+	}
+	unitNames["per cent"] = &units[0]
+}
+
+// unitMatch is a unit found at a field.
+type unitMatch struct {
+	unit   *unit
+	fields int
+	symbol bool
+}
+
+// unitAt reads the unit at fs[i], by name (one or more fields) or by its
+// symbol, matched case-sensitively.
+func unitAt(fs []field, i int) *unitMatch {
+	if i >= len(fs) {
+		return nil
+	}
+	for n := 3; n >= 1; n-- {
+		if i+n > len(fs) {
+			continue
+		}
+		names := make([]string, n)
+		ok := true
+		for k := range n {
+			if k > 0 && (fs[i+k].pre != "" || fs[i+k-1].post != "") {
+				ok = false
+				break
+			}
+			names[k] = fs[i+k].lower()
+		}
+		if !ok {
+			continue
+		}
+		if u, found := unitNames[strings.Join(names, " ")]; found {
+			return &unitMatch{unit: u, fields: n}
+		}
+	}
+	for k := range units {
+		if fs[i].core == units[k].symbol {
+			return &unitMatch{unit: &units[k], fields: 1, symbol: true}
+		}
+	}
+	return nil
+}
+
+// fusedRe is a number written together with its unit: "5km", "20%".
+var fusedRe = regexp.MustCompile(`^(-?[\d,]*\d(?:\.\d+)?)([^\d.,-].*)$`)
+
+// splitFused splits "5km" and "20%" into the number and a glued unit field,
+// so the rules see both; join puts them back together.
+func splitFused(fs []field) []field {
+	for i := 0; i < len(fs); i++ {
+		m := fusedRe.FindStringSubmatch(fs[i].core)
+		if m == nil || ordinalRe.MatchString(fs[i].lower()) {
+			continue
+		}
+		if u := unitAt([]field{{core: m[2]}}, 0); u == nil || !u.symbol {
+			continue
+		}
+		num, sym := fs[i], field{core: m[2], glue: true, post: fs[i].post}
+		num.core, num.post = m[1], ""
+		fs[i] = num
+		fs = slices.Insert(fs, i+1, sym)
+		i++
+	}
+	return fs
+}
+
+// formatUnits rewrites the units that follow a number. A symbol is written
+// after a space, but "%" and the degrees are glued to the number.
+func formatUnits(fs []field, style UnitStyle) []field {
+	for i := 0; i < len(fs); i++ {
+		n, ok := readNumber(fs, i)
+		if !ok || n.ordinal || n.year && n.spoken {
+			continue
+		}
+		j := i + n.fields
+		if fs[j-1].post != "" {
+			continue
+		}
+		u := unitAt(fs, j)
+		if u == nil {
+			continue
+		}
+		var out field
+		switch style {
+		case Abbreviate:
+			out = field{core: u.unit.symbol, glue: !n.spoken && (u.unit.symbol == "%" || strings.HasPrefix(u.unit.symbol, "°"))}
+		case Expand:
+			name := u.unit.plural
+			if n.value == 1 && n.decimals == "" {
+				name = u.unit.singular
+			}
+			fs = replace(fs, j, u.fields, words(name)...)
+			fs[j].glue = false
+			i = j + strings.Count(name, " ")
+			continue
+		}
+		fs = replace(fs, j, u.fields, out)
+		fs[j].glue = out.glue
+		i = j
+	}
+	return fs
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"parakeet/internal/archive"
+	"parakeet/internal/asr"
+)
+
+// Archive job limits: the audio files of one archive, and the size of one
+// of them once unpacked.
+const (
+	maxArchiveFiles     = 1000
+	maxArchiveFileBytes = 200 << 20
+)
+
+// File is the outcome of one audio file of an archive job.
+type File struct {
+	Name   string      // path in the archive
+	Result *asr.Result // set when it was transcribed
+	Error  string      // set when it failed
+}
+
+// runTask transcribes a task's audio with run. A zip or tar archive is
+// transcribed file by file, each with the extension of its name as the
+// Format, and progress is called with the files done so far and how many
+// there are, before the first one and after each; there is no Result then.
+// An archive job fails when the archive cannot be read, has no files or
+// none of them could be transcribed.
+func runTask(ctx context.Context, run RunFunc, audio []byte, opts asr.TranscribeOptions, progress func(files []File, total int)) (*asr.Result, []File, error) {
+	r := bytes.NewReader(audio)
+	if archive.Detect(r, r.Size()) == "" {
+		res, err := run(ctx, audio, opts)
+		return res, nil, err
+	}
+	total, err := archive.Count(r, r.Size(), maxArchiveFiles)
+	switch {
+	case errors.Is(err, archive.ErrTooManyFiles):
+		return nil, nil, fmt.Errorf("the archive has more than the %d files allowed", maxArchiveFiles)
+	case err != nil:
+		return nil, nil, err
+	case total == 0:
+		return nil, nil, errors.New("the archive has no files")
+	}
+
+	files := make([]File, 0, total)
+	progress(files, total)
+	err = archive.Walk(r, r.Size(), func(name string, size int64, content io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f := File{Name: name}
+		if size > maxArchiveFileBytes {
+			f.Error = fmt.Sprintf("file is larger than %d bytes", maxArchiveFileBytes)
+		} else if data, err := io.ReadAll(content); err != nil {
+			f.Error = err.Error()
+		} else {
+			fileOpts := opts
+			fileOpts.Format = strings.ToLower(path.Ext(name))
+			if f.Result, err = run(ctx, data, fileOpts); err != nil {
+				f.Error = err.Error()
+			}
+		}
+		files = append(files, f)
+		progress(files, total)
+		return nil
+	})
+	if err != nil {
+		return nil, files, err
+	}
+	for _, f := range files {
+		if f.Error == "" {
+			return nil, files, nil
+		}
+	}
+	return nil, files, fmt.Errorf("no file of the archive could be transcribed; the first failed with: %s", files[0].Error)
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func zipOf(files ...string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		w, _ := zw.Create(files[i])
+		w.Write([]byte(files[i+1]))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestQueue_Archive(t *testing.T) {
+	release := make(chan struct{})
+	q := NewQueue(Config{}, func(_ context.Context, audio []byte, opts asr.TranscribeOptions) (*asr.Result, error) {
+		if string(audio) == "wait" {
+			<-release
+		}
+		if string(audio) == "bad" {
+			return nil, errors.New("decode failed")
+		}
+		return &asr.Result{Text: string(audio) + opts.Format}, nil
+	})
+	defer q.Close()
+
+	job, _ := q.Submit(Task{Audio: zipOf("calls/1.WAV", "hello", "calls/2.mp3", "wait", "calls/3.ogg", "bad")})
+	// Progress shows the files done while the job runs.
+	for {
+		if got, _ := q.Get(job.ID); len(got.Files) == 1 {
+			if got.FileCount != 3 || got.Status != StatusProcessing {
+				t.Errorf("running job = %+v", got)
+			}
+			break
+		}
+	}
+	close(release)
+	got := waitFor(t, q, job.ID)
+	if got.Status != StatusCompleted || got.Result != nil || len(got.Files) != 3 {
+		t.Fatalf("archive job = %+v", got)
+	}
+	if f := got.Files[0]; f.Name != "calls/1.WAV" || f.Result.Text != "hello.wav" {
+		t.Errorf("first file = %+v", f)
+	}
+	if f := got.Files[2]; f.Name != "calls/3.ogg" || f.Result != nil || f.Error != "decode failed" {
+		t.Errorf("failed file = %+v", f)
+	}
+
+	failed, _ := q.Submit(Task{Audio: zipOf("a.wav", "bad")})
+	if got := waitFor(t, q, failed.ID); got.Status != StatusError || len(got.Files) != 1 {
+		t.Errorf("all files failed: %+v", got)
+	}
+	empty, _ := q.Submit(Task{Audio: zipOf()})
+	if got := waitFor(t, q, empty.ID); got.Status != StatusError || got.Error != "the archive has no files" {
+		t.Errorf("empty archive: %+v", got)
+	}
+}
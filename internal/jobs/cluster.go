@@ -0,0 +1,624 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// Cluster timings.
+const (
+	// heartbeatInterval is how often an instance announces itself.
+	heartbeatInterval = 10 * time.Second
+	// workerTTL is how long a heartbeat is kept: an instance silent for
+	// that long is gone.
+	workerTTL = 3 * heartbeatInterval
+	// claimWait is how long one claim waits for queued jobs.
+	claimWait = 30 * time.Second
+	// claimAckWait is how long a claimed job may go without a progress
+	// report before it is handed to another instance.
+	claimAckWait = 30 * time.Second
+	// progressInterval is how often a running job reports progress.
+	progressInterval = 10 * time.Second
+	// storeTimeout bounds one call to the shared store.
+	storeTimeout = 10 * time.Second
+	// retryMin and retryMax bound the backoff after a failed claim.
+	retryMin = 2 * time.Second
+	retryMax = time.Minute
+)
+
+// ClusterConfig configures a Cluster.
+type ClusterConfig struct {
+	Config
+	// URL is the NATS server, which must have JetStream enabled.
+	URL string
+	// Name identifies this instance in the status; the hostname if empty.
+	Name string
+	// MaxAttempts is how many times a job is started before it fails. A job
+	// whose instance stops reporting progress, because it crashed or lost
+	// its connection, is started again on another one until then.
+	MaxAttempts int
+}
+
+// WorkerInfo is an instance's last heartbeat.
+type WorkerInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Started   time.Time `json:"started"`
+	LastSeen  time.Time `json:"last_seen"`
+	Workers   int       `json:"workers"` // jobs it runs at once
+	Running   []string  `json:"running"` // IDs of the jobs it is running
+	Completed int       `json:"completed"`
+	Failed    int       `json:"failed"`
+}
+
+// ClusterStatus is the combined state of the instances sharing a Cluster.
+type ClusterStatus struct {
+	Workers []WorkerInfo   `json:"workers"`
+	Jobs    map[Status]int `json:"jobs"`
+	// Pending counts the jobs no instance has claimed yet.
+	Pending int `json:"pending"`
+}
+
+func (cfg ClusterConfig) withDefaults() ClusterConfig {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxQueued < 1 {
+		cfg.MaxQueued = 1000
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.Name == "" {
+		cfg.Name, _ = os.Hostname()
+	}
+	return cfg
+}
+
+// claim is a job handed to this instance by the shared queue. Until it is
+// acked it is handed to another instance once progress reports stop.
+type claim struct {
+	id      string
+	attempt int // from 1
+	ack     func() error
+	nak     func() error // hand it back now
+	touch   func() error // report progress
+}
+
+// store is the shared state a Cluster coordinates through.
+type store interface {
+	putRecord(ctx context.Context, id string, data []byte) error
+	getRecord(ctx context.Context, id string) ([]byte, error) // ErrNotFound
+	deleteRecord(ctx context.Context, id string) error
+	listRecords(ctx context.Context) ([]string, error)
+
+	putAudio(ctx context.Context, id string, data []byte) error
+	getAudio(ctx context.Context, id string) ([]byte, error) // ErrNotFound
+	deleteAudio(ctx context.Context, id string) error
+
+	enqueue(ctx context.Context, id string) error // ErrQueueFull
+	claim(ctx context.Context, n int) ([]claim, error)
+	pending(ctx context.Context) (int, error)
+
+	heartbeat(ctx context.Context, w WorkerInfo) error
+	leave(ctx context.Context, id string) error
+	workers(ctx context.Context) ([]WorkerInfo, error)
+
+	close()
+}
+
+// record is a job as kept in the shared store: its snapshot plus what any
+// instance needs to run it.
+type record struct {
+	Job      Job                   `json:"job"`
+	HasAudio bool                  `json:"has_audio,omitempty"` // stored under the job ID
+	AudioURL string                `json:"audio_url,omitempty"`
+	Options  asr.TranscribeOptions `json:"options"`
+	Worker   string                `json:"worker,omitempty"`
+	Attempts int                   `json:"attempts,omitempty"`
+}
+
+// Cluster is a Store shared by every instance pointed at the same NATS
+// server. Any instance accepts and serves jobs; each runs cfg.Workers of
+// them at a time, claimed from a shared queue.
+type Cluster struct {
+	cfg     ClusterConfig
+	run     RunFunc
+	store   store
+	id      string
+	started time.Time
+
+	mu        sync.Mutex
+	running   map[string]bool
+	completed int
+	failed    int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	slots  chan struct{}
+}
+
+var (
+	_ Store = (*Queue)(nil)
+	_ Store = (*Cluster)(nil)
+)
+
+// NewCluster connects to cfg.URL, creates the streams the cluster needs if
+// they are missing and starts cfg.Workers workers.
+func NewCluster(ctx context.Context, cfg ClusterConfig, run RunFunc) (*Cluster, error) {
+	cfg = cfg.withDefaults()
+	st, err := dialNATSStore(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newCluster(cfg, run, st), nil
+}
+
+func newCluster(cfg ClusterConfig, run RunFunc, st store) *Cluster {
+	cfg = cfg.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cluster{
+		cfg:     cfg,
+		run:     run,
+		store:   st,
+		id:      cfg.Name + "-" + newID()[:8],
+		started: time.Now(),
+		running: make(map[string]bool),
+		ctx:     ctx,
+		cancel:  cancel,
+		slots:   make(chan struct{}, cfg.Workers),
+	}
+	c.wg.Add(2)
+	go c.claimLoop()
+	go c.heartbeatLoop()
+	return c
+}
+
+// ID is this instance's worker ID in the status.
+func (c *Cluster) ID() string { return c.id }
+
+// Submit stores a task and queues it for any instance to run.
+func (c *Cluster) Submit(task Task) (Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	rec := &record{
+		Job:      Job{ID: newID(), Status: StatusQueued, Created: time.Now(), Meta: task.Meta},
+		AudioURL: task.AudioURL,
+		Options:  task.Options,
+	}
+	id := rec.Job.ID
+	if len(task.Audio) > 0 {
+		if err := c.store.putAudio(ctx, id, task.Audio); err != nil {
+			return Job{}, fmt.Errorf("store audio: %w", err)
+		}
+		rec.HasAudio = true
+	}
+	err := c.put(ctx, rec)
+	if err == nil {
+		err = c.store.enqueue(ctx, id)
+		if err != nil {
+			c.store.deleteRecord(ctx, id)
+		}
+	}
+	if err != nil {
+		if rec.HasAudio {
+			c.store.deleteAudio(ctx, id)
+		}
+		return Job{}, err
+	}
+	return rec.Job, nil
+}
+
+// Add records work that was done outside the cluster as a completed job.
+func (c *Cluster) Add(result *asr.Result, meta map[string]string) (Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	now := time.Now()
+	rec := &record{Job: Job{
+		ID:        newID(),
+		Status:    StatusCompleted,
+		Created:   now,
+		Started:   now,
+		Completed: now,
+		Result:    result,
+		Meta:      meta,
+	}}
+	if err := c.put(ctx, rec); err != nil {
+		return Job{}, err
+	}
+	return rec.Job, nil
+}
+
+// Get returns a job's current snapshot.
+func (c *Cluster) Get(id string) (Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	rec, err := c.get(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	return rec.Job, nil
+}
+
+// List returns every retained job, newest first. It reads each job from
+// the store, so it is slow with many thousands of them.
+func (c *Cluster) List() ([]Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	ids, err := c.store.listRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Job, 0, len(ids))
+	for _, id := range ids {
+		rec, err := c.get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted or expired meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec.Job)
+	}
+	sortJobs(out)
+	return out, nil
+}
+
+// Delete forgets a job. A queued job is skipped when claimed; a running
+// one finishes but its result is discarded.
+func (c *Cluster) Delete(id string) (Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	rec, err := c.get(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if err := c.store.deleteRecord(ctx, id); err != nil {
+		return Job{}, err
+	}
+	if rec.HasAudio {
+		c.store.deleteAudio(ctx, id)
+	}
+	return rec.Job, nil
+}
+
+// PutAudio stores audio under id for later retrieval by any instance. It
+// expires with the jobs.
+func (c *Cluster) PutAudio(id string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return c.store.putAudio(ctx, id, data)
+}
+
+// Audio returns audio stored with PutAudio, or ErrNotFound.
+func (c *Cluster) Audio(id string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return c.store.getAudio(ctx, id)
+}
+
+// Status returns the live instances, the jobs by status and how many are
+// waiting to be claimed.
+func (c *Cluster) Status() (ClusterStatus, error) {
+	jobs, err := c.List()
+	if err != nil {
+		return ClusterStatus{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	workers, err := c.store.workers(ctx)
+	if err != nil {
+		return ClusterStatus{}, err
+	}
+	pending, err := c.store.pending(ctx)
+	if err != nil {
+		return ClusterStatus{}, err
+	}
+	st := ClusterStatus{Workers: []WorkerInfo{}, Jobs: map[Status]int{}, Pending: pending}
+	cutoff := time.Now().Add(-workerTTL)
+	for _, w := range workers {
+		if w.LastSeen.After(cutoff) {
+			st.Workers = append(st.Workers, w)
+		}
+	}
+	slices.SortFunc(st.Workers, func(a, b WorkerInfo) int { return a.Started.Compare(b.Started) })
+	for _, s := range []Status{StatusQueued, StatusProcessing, StatusCompleted, StatusError} {
+		st.Jobs[s] = 0
+	}
+	for _, job := range jobs {
+		st.Jobs[job.Status]++
+	}
+	return st, nil
+}
+
+// Close stops claiming jobs, hands running ones back to the queue for
+// another instance and leaves the cluster.
+func (c *Cluster) Close() {
+	c.cancel()
+	c.wg.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	c.store.leave(ctx, c.id)
+	c.store.close()
+}
+
+// claimLoop claims as many jobs as there are free workers and runs each on
+// its own goroutine.
+func (c *Cluster) claimLoop() {
+	defer c.wg.Done()
+	backoff := retryMin
+	for {
+		// Wait for one free worker, then take every other free one.
+		select {
+		case c.slots <- struct{}{}:
+		case <-c.ctx.Done():
+			return
+		}
+		n := 1
+	take:
+		for n < cap(c.slots) {
+			select {
+			case c.slots <- struct{}{}:
+				n++
+			default:
+				break take
+			}
+		}
+
+		claims, err := c.store.claim(c.ctx, n)
+		for range n - len(claims) {
+			<-c.slots
+		}
+		for _, cl := range claims {
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				defer func() { <-c.slots }()
+				c.process(cl)
+			}()
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			backoff = retryMin
+			continue
+		}
+		slog.Warn("job cluster claim failed", "error", err, "retry_in", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-c.ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, retryMax)
+	}
+}
+
+// process runs one claimed job. A job claimed more than MaxAttempts times
+// fails without running: every earlier attempt died with its instance.
+func (c *Cluster) process(cl claim) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	rec, err := c.get(ctx, cl.id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		cl.ack() // deleted while queued
+		return
+	case err != nil:
+		slog.Warn("job cluster read failed, job handed back", "id", cl.id, "error", err)
+		cl.nak()
+		return
+	case rec.Job.Status == StatusCompleted || rec.Job.Status == StatusError:
+		cl.ack() // finished by an instance that died before acking
+		return
+	}
+
+	if cl.attempt > c.cfg.MaxAttempts {
+		rec.Job.Status = StatusError
+		rec.Job.Error = fmt.Sprintf("gave up after %d attempts: the instances running it stopped responding", c.cfg.MaxAttempts)
+		rec.Job.Completed = time.Now()
+		c.finish(cl, rec)
+		return
+	}
+	if cl.attempt > 1 {
+		slog.Warn("job retried after its instance stopped responding", "id", cl.id, "previous_worker", rec.Worker, "attempt", cl.attempt)
+	}
+	rec.Job.Status = StatusProcessing
+	rec.Job.Started = time.Now()
+	rec.Worker = c.id
+	rec.Attempts = cl.attempt
+	if err := c.put(ctx, rec); err != nil {
+		slog.Warn("job cluster write failed, job handed back", "id", cl.id, "error", err)
+		cl.nak()
+		return
+	}
+
+	c.setRunning(cl.id, true)
+	defer c.setRunning(cl.id, false)
+	stop := keepClaim(cl)
+	res, files, err := c.execute(rec)
+	stop()
+	if c.ctx.Err() != nil {
+		cl.nak() // shutting down: another instance starts it over
+		return
+	}
+	rec.Job.Completed = time.Now()
+	rec.Job.Files = files
+	if err != nil {
+		rec.Job.Status = StatusError
+		rec.Job.Error = err.Error()
+		slog.Warn("job failed", "id", cl.id, "error", err)
+	} else {
+		rec.Job.Status = StatusCompleted
+		rec.Job.Result = res
+	}
+	c.finish(cl, rec)
+}
+
+// finish stores a job's final record, acks its claim and drops its audio.
+// A job deleted while it ran stays deleted.
+func (c *Cluster) finish(cl claim, rec *record) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if _, err := c.get(ctx, cl.id); errors.Is(err, ErrNotFound) {
+		cl.ack()
+		return
+	}
+	if err := c.put(ctx, rec); err != nil {
+		slog.Warn("job cluster write failed, job will run again", "id", cl.id, "error", err)
+		cl.nak()
+		return
+	}
+	if err := cl.ack(); err != nil {
+		slog.Warn("job cluster ack failed", "id", cl.id, "error", err)
+	}
+	if rec.HasAudio {
+		c.store.deleteAudio(ctx, cl.id)
+	}
+
+	c.mu.Lock()
+	if rec.Job.Status == StatusCompleted {
+		c.completed++
+	} else {
+		c.failed++
+	}
+	c.mu.Unlock()
+	if c.cfg.Done != nil {
+		c.cfg.Done(rec.Job)
+	}
+}
+
+func (c *Cluster) execute(rec *record) (*asr.Result, []File, error) {
+	task := Task{AudioURL: rec.AudioURL, Options: rec.Options}
+	if rec.HasAudio {
+		ctx, cancel := context.WithTimeout(c.ctx, storeTimeout)
+		audio, err := c.store.getAudio(ctx, rec.Job.ID)
+		cancel()
+		if err != nil {
+			return nil, nil, fmt.Errorf("load audio: %w", err)
+		}
+		task.Audio = audio
+	}
+	audio, err := load(c.ctx, c.cfg.Fetch, task)
+	if err != nil {
+		return nil, nil, err
+	}
+	return runTask(c.ctx, c.run, audio, task.Options, func(files []File, total int) {
+		c.progress(rec, files, total)
+	})
+}
+
+// progress stores the files of an archive job done so far, so that every
+// instance reports them. A job deleted meanwhile stays deleted.
+func (c *Cluster) progress(rec *record, files []File, total int) {
+	ctx, cancel := context.WithTimeout(c.ctx, storeTimeout)
+	defer cancel()
+	rec.Job.Files, rec.Job.FileCount = files, total
+	if _, err := c.get(ctx, rec.Job.ID); err != nil {
+		return
+	}
+	if err := c.put(ctx, rec); err != nil {
+		slog.Warn("job cluster progress write failed", "id", rec.Job.ID, "error", err)
+	}
+}
+
+// keepClaim reports progress on a claim until the returned func is called,
+// so long jobs are not handed to another instance.
+func keepClaim(cl claim) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(progressInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				cl.touch()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (c *Cluster) setRunning(id string, running bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if running {
+		c.running[id] = true
+	} else {
+		delete(c.running, id)
+	}
+}
+
+// heartbeatLoop announces this instance until Close.
+func (c *Cluster) heartbeatLoop() {
+	defer c.wg.Done()
+	t := time.NewTicker(heartbeatInterval)
+	defer t.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(c.ctx, storeTimeout)
+		if err := c.store.heartbeat(ctx, c.info()); err != nil && c.ctx.Err() == nil {
+			slog.Warn("job cluster heartbeat failed", "error", err)
+		}
+		cancel()
+		select {
+		case <-t.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cluster) info() WorkerInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := WorkerInfo{
+		ID:        c.id,
+		Name:      c.cfg.Name,
+		Started:   c.started,
+		LastSeen:  time.Now(),
+		Workers:   cap(c.slots),
+		Running:   make([]string, 0, len(c.running)),
+		Completed: c.completed,
+		Failed:    c.failed,
+	}
+	for id := range c.running {
+		w.Running = append(w.Running, id)
+	}
+	slices.Sort(w.Running)
+	return w
+}
+
+func (c *Cluster) get(ctx context.Context, id string) (*record, error) {
+	data, err := c.store.getRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("job %s: invalid record: %w", id, err)
+	}
+	return &rec, nil
+}
+
+func (c *Cluster) put(ctx context.Context, rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return c.store.putRecord(ctx, rec.Job.ID, data)
+}
@@ -0,0 +1,234 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// memStore is a store shared by the Clusters of one test, standing in for
+// NATS.
+type memStore struct {
+	mu      sync.Mutex
+	records map[string][]byte
+	audio   map[string][]byte
+	beats   map[string]WorkerInfo
+	queue   chan claimed
+}
+
+type claimed struct {
+	id      string
+	attempt int
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		records: map[string][]byte{},
+		audio:   map[string][]byte{},
+		beats:   map[string]WorkerInfo{},
+		queue:   make(chan claimed, 16),
+	}
+}
+
+func (m *memStore) lookup(table map[string][]byte, id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := table[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (m *memStore) set(table map[string][]byte, id string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if data == nil {
+		delete(table, id)
+	} else {
+		table[id] = data
+	}
+	return nil
+}
+
+func (m *memStore) putRecord(_ context.Context, id string, data []byte) error {
+	return m.set(m.records, id, data)
+}
+func (m *memStore) getRecord(_ context.Context, id string) ([]byte, error) {
+	return m.lookup(m.records, id)
+}
+func (m *memStore) deleteRecord(_ context.Context, id string) error { return m.set(m.records, id, nil) }
+func (m *memStore) putAudio(_ context.Context, id string, data []byte) error {
+	return m.set(m.audio, id, data)
+}
+func (m *memStore) getAudio(_ context.Context, id string) ([]byte, error) {
+	return m.lookup(m.audio, id)
+}
+func (m *memStore) deleteAudio(_ context.Context, id string) error { return m.set(m.audio, id, nil) }
+
+func (m *memStore) listRecords(context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id := range m.records {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *memStore) enqueue(_ context.Context, id string) error {
+	select {
+	case m.queue <- claimed{id: id, attempt: 1}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// claim hands out queued jobs; a nak queues the job again as the next
+// attempt, as JetStream redelivers it.
+func (m *memStore) claim(ctx context.Context, n int) ([]claim, error) {
+	var out []claim
+	timeout := time.After(20 * time.Millisecond)
+	for len(out) < n {
+		select {
+		case q := <-m.queue:
+			out = append(out, claim{
+				id:      q.id,
+				attempt: q.attempt,
+				ack:     func() error { return nil },
+				nak:     func() error { m.queue <- claimed{q.id, q.attempt + 1}; return nil },
+				touch:   func() error { return nil },
+			})
+		case <-timeout:
+			return out, nil
+		case <-ctx.Done():
+			return out, ctx.Err()
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) pending(context.Context) (int, error) { return len(m.queue), nil }
+
+func (m *memStore) heartbeat(_ context.Context, w WorkerInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.beats[w.ID] = w
+	return nil
+}
+
+func (m *memStore) leave(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.beats, id)
+	return nil
+}
+
+func (m *memStore) workers(context.Context) ([]WorkerInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []WorkerInfo
+	for _, w := range m.beats {
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+func (m *memStore) close() {}
+
+func echoRun(_ context.Context, audio []byte, opts asr.TranscribeOptions) (*asr.Result, error) {
+	return &asr.Result{Text: string(audio) + "/" + opts.Language}, nil
+}
+
+func TestCluster_SharedJobs(t *testing.T) {
+	st := newMemStore()
+	done := make(chan Job, 2)
+	cfg := ClusterConfig{Config: Config{
+		Fetch: func(context.Context, string) ([]byte, error) { return []byte("fetched"), nil },
+		Done:  func(j Job) { done <- j },
+	}}
+	a := newCluster(cfg, echoRun, st)
+	defer a.Close()
+	cfg.Name = "b"
+	b := newCluster(cfg, echoRun, st)
+	defer b.Close()
+
+	// Submitted on one instance, visible and served on the other.
+	uploaded, err := a.Submit(Task{Audio: []byte("hello"), Options: asr.TranscribeOptions{Language: "en"}, Meta: map[string]string{"k": "v"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := b.Submit(Task{AudioURL: "https://example.com/a.wav", Options: asr.TranscribeOptions{Language: "de"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := waitFor(t, b, uploaded.ID); got.Status != StatusCompleted || got.Result.Text != "hello/en" || got.Meta["k"] != "v" {
+		t.Errorf("uploaded job = %+v", got)
+	}
+	if got := waitFor(t, a, fetched.ID); got.Status != StatusCompleted || got.Result.Text != "fetched/de" {
+		t.Errorf("fetched job = %+v", got)
+	}
+	<-done
+	<-done
+	if _, err := st.getAudio(context.Background(), uploaded.ID); !errors.Is(err, ErrNotFound) {
+		t.Error("audio kept after the job finished")
+	}
+
+	status, err := a.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Workers) != 2 || status.Jobs[StatusCompleted] != 2 || status.Jobs[StatusQueued] != 0 {
+		t.Errorf("status = %+v", status)
+	}
+	if list, _ := b.List(); len(list) != 2 || list[0].ID != fetched.ID {
+		t.Errorf("List = %+v", list)
+	}
+	if _, err := b.Delete(uploaded.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Get(uploaded.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+}
+
+func TestCluster_Retry(t *testing.T) {
+	st := newMemStore()
+	c := newCluster(ClusterConfig{MaxAttempts: 2}, echoRun, st)
+	defer c.Close()
+	ctx := context.Background()
+
+	// Two jobs left processing by an instance that died: the queue hands
+	// them out again as later attempts.
+	orphan := func(attempt int) string {
+		rec := &record{Job: Job{ID: newID(), Status: StatusProcessing, Created: time.Now()}, HasAudio: true,
+			Worker: "dead-1", Attempts: attempt - 1}
+		st.putAudio(ctx, rec.Job.ID, []byte("again"))
+		if err := c.put(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		st.queue <- claimed{rec.Job.ID, attempt}
+		return rec.Job.ID
+	}
+	retried, exhausted := orphan(2), orphan(3)
+
+	if got := waitFor(t, c, retried); got.Status != StatusCompleted || got.Result.Text != "again/" {
+		t.Errorf("retried job = %+v", got)
+	}
+	if got := waitFor(t, c, exhausted); got.Status != StatusError || !strings.Contains(got.Error, "gave up after 2 attempts") {
+		t.Errorf("exhausted job = %+v", got)
+	}
+	rec, _ := c.get(ctx, retried)
+	if rec.Worker != c.ID() || rec.Attempts != 2 {
+		t.Errorf("retried record = worker %s, attempts %d", rec.Worker, rec.Attempts)
+	}
+}
@@ -0,0 +1,340 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package jobs runs transcriptions asynchronously: callers submit work, get
+// an ID back at once and poll for the result. It backs the async
+// compatibility APIs. A Queue keeps jobs in memory, where they are lost on
+// restart; a Cluster shares them between instances through NATS JetStream.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusQueued     Status = "queued"
+	StatusProcessing Status = "processing"
+	StatusCompleted  Status = "completed"
+	StatusError      Status = "error"
+)
+
+// ErrQueueFull is returned by Submit when MaxQueued jobs are already
+// waiting.
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrNotFound is returned for unknown or expired job IDs.
+var ErrNotFound = errors.New("job not found")
+
+// FetchFunc downloads a job's audio when the job starts, so slow downloads
+// happen on a worker instead of in the submitting request.
+type FetchFunc func(ctx context.Context, url string) ([]byte, error)
+
+// RunFunc transcribes one job's audio.
+type RunFunc func(ctx context.Context, audio []byte, opts asr.TranscribeOptions) (*asr.Result, error)
+
+// Task is the work submitted for one job. It is plain data so a Cluster
+// can hand it to another instance. Audio that is a zip or tar archive is
+// transcribed file by file (see Job.Files).
+type Task struct {
+	// Audio is the audio itself. When empty, AudioURL is downloaded with
+	// Config.Fetch when the job starts.
+	Audio    []byte
+	AudioURL string
+	Options  asr.TranscribeOptions
+	// Meta is opaque caller data stored with the job (the source URL, a
+	// webhook, ...), returned unchanged in every snapshot.
+	Meta map[string]string
+}
+
+// Job is a snapshot of one job.
+type Job struct {
+	ID        string
+	Status    Status
+	Created   time.Time
+	Started   time.Time
+	Completed time.Time
+	Result    *asr.Result // set when Status is StatusCompleted
+	Error     string      // set when Status is StatusError
+	Meta      map[string]string
+
+	// Files are the outcome of each audio file of a job whose audio is a
+	// zip or tar archive, in archive order, filled in as they finish;
+	// FileCount is how many the archive has. Such jobs have no Result.
+	Files     []File
+	FileCount int
+}
+
+// Config sizes a Queue.
+type Config struct {
+	// Workers is how many jobs run at once.
+	Workers int
+	// MaxQueued bounds the jobs waiting to start.
+	MaxQueued int
+	// Retention is how long finished jobs stay retrievable.
+	Retention time.Duration
+	// Fetch downloads the audio of tasks submitted with an AudioURL.
+	Fetch FetchFunc
+	// Done, when set, is called once a job has finished, with its final
+	// snapshot, on the instance that ran it. It runs on the worker
+	// goroutine.
+	Done func(Job)
+}
+
+// Store is the job API the servers use, implemented by Queue and Cluster.
+type Store interface {
+	// Submit queues a task and returns the new job's snapshot.
+	Submit(Task) (Job, error)
+	// Add records work that was done outside the store as a completed job.
+	Add(result *asr.Result, meta map[string]string) (Job, error)
+	// Get returns a job's current snapshot.
+	Get(id string) (Job, error)
+	// List returns every retained job, newest first.
+	List() ([]Job, error)
+	// Delete forgets a job.
+	Delete(id string) (Job, error)
+	// Close stops the workers, cancelling running jobs.
+	Close()
+}
+
+// Queue holds jobs and the workers that run them.
+type Queue struct {
+	cfg Config
+	run RunFunc
+
+	mu    sync.Mutex
+	jobs  map[string]*entry
+	queue chan *entry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type entry struct {
+	job  Job
+	task Task
+}
+
+// NewQueue starts cfg.Workers workers that run jobs with run.
+func NewQueue(cfg Config, run RunFunc) *Queue {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxQueued < 1 {
+		cfg.MaxQueued = 1000
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		cfg:    cfg,
+		run:    run,
+		jobs:   make(map[string]*entry),
+		queue:  make(chan *entry, cfg.MaxQueued),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// Submit queues a task and returns the new job's snapshot.
+func (q *Queue) Submit(task Task) (Job, error) {
+	e := &entry{
+		job: Job{
+			ID:      newID(),
+			Status:  StatusQueued,
+			Created: time.Now(),
+			Meta:    task.Meta,
+		},
+		task: task,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pruneLocked()
+	select {
+	case q.queue <- e:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	q.jobs[e.job.ID] = e
+	return e.job, nil
+}
+
+// Add records work that was done outside the queue as a completed job, so
+// it can be listed and retrieved like the others.
+func (q *Queue) Add(result *asr.Result, meta map[string]string) (Job, error) {
+	now := time.Now()
+	e := &entry{job: Job{
+		ID:        newID(),
+		Status:    StatusCompleted,
+		Created:   now,
+		Started:   now,
+		Completed: now,
+		Result:    result,
+		Meta:      meta,
+	}}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pruneLocked()
+	q.jobs[e.job.ID] = e
+	return e.job, nil
+}
+
+// Get returns a job's current snapshot.
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return e.job, nil
+}
+
+// List returns every retained job, newest first.
+func (q *Queue) List() ([]Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pruneLocked()
+	out := make([]Job, 0, len(q.jobs))
+	for _, e := range q.jobs {
+		out = append(out, e.job)
+	}
+	sortJobs(out)
+	return out, nil
+}
+
+// Delete forgets a job. A queued job is skipped when its turn comes; a
+// running one finishes but its result is discarded.
+func (q *Queue) Delete(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	delete(q.jobs, id)
+	return e.job, nil
+}
+
+// Close stops the workers, cancelling running jobs, and waits for them.
+func (q *Queue) Close() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case e := <-q.queue:
+			q.process(e)
+		}
+	}
+}
+
+func (q *Queue) process(e *entry) {
+	q.mu.Lock()
+	if _, ok := q.jobs[e.job.ID]; !ok {
+		q.mu.Unlock()
+		return // deleted while queued
+	}
+	e.job.Status = StatusProcessing
+	e.job.Started = time.Now()
+	q.mu.Unlock()
+
+	res, files, err := q.execute(e)
+
+	q.mu.Lock()
+	e.job.Completed = time.Now()
+	e.job.Files = files
+	if err != nil {
+		e.job.Status = StatusError
+		e.job.Error = err.Error()
+	} else {
+		e.job.Status = StatusCompleted
+		e.job.Result = res
+	}
+	e.task.Audio = nil // release the audio
+	snapshot := e.job
+	q.mu.Unlock()
+
+	if err != nil {
+		slog.Warn("job failed", "id", snapshot.ID, "error", err)
+	}
+	if q.cfg.Done != nil {
+		q.cfg.Done(snapshot)
+	}
+}
+
+func (q *Queue) execute(e *entry) (*asr.Result, []File, error) {
+	audio, err := load(q.ctx, q.cfg.Fetch, e.task)
+	if err != nil {
+		return nil, nil, err
+	}
+	return runTask(q.ctx, q.run, audio, e.task.Options, func(files []File, total int) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		e.job.Files, e.job.FileCount = slices.Clone(files), total
+	})
+}
+
+// load returns a task's audio, downloading it if the task only has a URL.
+func load(ctx context.Context, fetch FetchFunc, task Task) ([]byte, error) {
+	if len(task.Audio) > 0 {
+		return task.Audio, nil
+	}
+	if task.AudioURL == "" || fetch == nil {
+		return nil, errors.New("load audio: task has no audio")
+	}
+	audio, err := fetch(ctx, task.AudioURL)
+	if err != nil {
+		return nil, fmt.Errorf("load audio: %w", err)
+	}
+	return audio, nil
+}
+
+// sortJobs orders jobs newest first.
+func sortJobs(jobs []Job) {
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.After(jobs[j].Created) })
+}
+
+// pruneLocked drops finished jobs older than the retention.
+func (q *Queue) pruneLocked() {
+	if q.cfg.Retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-q.cfg.Retention)
+	for id, e := range q.jobs {
+		if !e.job.Completed.IsZero() && e.job.Completed.Before(cutoff) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// newID returns a random 128-bit job ID in hex.
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
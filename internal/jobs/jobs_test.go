@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// waitFor polls a job until it has finished.
+func waitFor(t *testing.T, q Store, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == StatusCompleted || job.Status == StatusError {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestQueue_Lifecycle(t *testing.T) {
+	done := make(chan Job, 3)
+	fetch := func(_ context.Context, url string) ([]byte, error) {
+		if url == "https://example.com/gone.wav" {
+			return nil, errors.New("404")
+		}
+		return []byte("fetched"), nil
+	}
+	q := NewQueue(Config{Workers: 2, Fetch: fetch, Done: func(j Job) { done <- j }}, func(_ context.Context, audio []byte, opts asr.TranscribeOptions) (*asr.Result, error) {
+		if string(audio) == "bad" {
+			return nil, errors.New("decode failed")
+		}
+		return &asr.Result{Text: string(audio) + "/" + opts.Language}, nil
+	})
+	defer q.Close()
+
+	ok, err := q.Submit(Task{Audio: []byte("hello"), Options: asr.TranscribeOptions{Language: "en"}, Meta: map[string]string{"k": "v"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok.Status != StatusQueued || ok.ID == "" {
+		t.Fatalf("submitted job = %+v", ok)
+	}
+	failed, _ := q.Submit(Task{Audio: []byte("bad")})
+	unloadable, _ := q.Submit(Task{AudioURL: "https://example.com/gone.wav"})
+	fetched, _ := q.Submit(Task{AudioURL: "https://example.com/a.wav", Options: asr.TranscribeOptions{Language: "de"}})
+
+	if got := waitFor(t, q, ok.ID); got.Status != StatusCompleted || got.Result.Text != "hello/en" || got.Meta["k"] != "v" {
+		t.Errorf("completed job = %+v", got)
+	}
+	if got := waitFor(t, q, failed.ID); got.Status != StatusError || got.Error != "decode failed" {
+		t.Errorf("failed job = %+v", got)
+	}
+	if got := waitFor(t, q, unloadable.ID); got.Status != StatusError || got.Error != "load audio: 404" {
+		t.Errorf("unloadable job = %+v", got)
+	}
+	if got := waitFor(t, q, fetched.ID); got.Status != StatusCompleted || got.Result.Text != "fetched/de" {
+		t.Errorf("fetched job = %+v", got)
+	}
+	finished := map[string]bool{}
+	for range 4 {
+		finished[(<-done).ID] = true
+	}
+	if !finished[ok.ID] || !finished[unloadable.ID] {
+		t.Errorf("Done called for %v", finished)
+	}
+
+	if list, _ := q.List(); len(list) != 4 {
+		t.Errorf("List returned %d jobs, want 4", len(list))
+	}
+	if _, err := q.Delete(ok.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Get(ok.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+}
+
+func TestQueue_Add(t *testing.T) {
+	q := NewQueue(Config{}, nil)
+	defer q.Close()
+
+	added, _ := q.Add(&asr.Result{Text: "archived"}, map[string]string{"stream": "cam1"})
+	got, err := q.Get(added.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusCompleted || got.Result.Text != "archived" || got.Meta["stream"] != "cam1" {
+		t.Errorf("added job = %+v", got)
+	}
+}
+
+func TestQueue_Full(t *testing.T) {
+	release := make(chan struct{})
+	q := NewQueue(Config{Workers: 1, MaxQueued: 1}, func(context.Context, []byte, asr.TranscribeOptions) (*asr.Result, error) {
+		<-release
+		return &asr.Result{}, nil
+	})
+	defer q.Close()
+	defer close(release)
+
+	first, _ := q.Submit(Task{Audio: []byte("a")})
+	// Wait for the worker to take the first job so the next one waits.
+	for {
+		if job, _ := q.Get(first.ID); job.Status == StatusProcessing {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := q.Submit(Task{Audio: []byte("b")}); err != nil {
+		t.Fatalf("second submit: %v", err)
+	}
+	if _, err := q.Submit(Task{Audio: []byte("c")}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("third submit: %v, want ErrQueueFull", err)
+	}
+}
+
+func TestQueue_Retention(t *testing.T) {
+	q := NewQueue(Config{Retention: time.Millisecond}, func(context.Context, []byte, asr.TranscribeOptions) (*asr.Result, error) {
+		return &asr.Result{}, nil
+	})
+	defer q.Close()
+
+	job, _ := q.Submit(Task{Audio: []byte("a")})
+	waitFor(t, q, job.ID)
+	time.Sleep(5 * time.Millisecond)
+	if list, _ := q.List(); len(list) != 0 {
+		t.Errorf("List returned %d jobs after retention, want 0", len(list))
+	}
+}
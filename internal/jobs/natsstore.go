@@ -0,0 +1,317 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"parakeet/internal/nats"
+)
+
+// JetStream streams behind a Cluster. Records and heartbeats keep only the
+// latest message per subject, so each subject works as a key.
+const (
+	jobsStream    = "PARAKEET_JOBS"    // parakeet.jobs.<id>: each job's record
+	workStream    = "PARAKEET_WORK"    // parakeet.work: IDs of jobs to run, a work queue
+	audioStream   = "PARAKEET_AUDIO"   // parakeet.audio.<id>.<n>: audio in chunks
+	workersStream = "PARAKEET_WORKERS" // parakeet.workers.<id>: heartbeats
+	workConsumer  = "workers"
+
+	jobsPrefix    = "parakeet.jobs."
+	workSubject   = "parakeet.work"
+	audioPrefix   = "parakeet.audio."
+	workersPrefix = "parakeet.workers."
+)
+
+// errCodeMaxMsgs is the JetStream error for a publish refused because the
+// stream is full.
+const errCodeMaxMsgs = 10077
+
+// natsStore keeps a Cluster's state in JetStream, redialing when the
+// connection drops.
+type natsStore struct {
+	cfg ClusterConfig
+
+	mu sync.Mutex
+	c  *nats.Conn
+}
+
+func dialNATSStore(ctx context.Context, cfg ClusterConfig) (*natsStore, error) {
+	if err := nats.CheckURL(cfg.URL); err != nil {
+		return nil, err
+	}
+	st := &natsStore{cfg: cfg}
+	if _, err := st.conn(ctx); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// conn returns the live connection, dialing and setting up the streams
+// again if it was lost.
+func (st *natsStore) conn(ctx context.Context) (*nats.Conn, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.c != nil {
+		select {
+		case <-st.c.Done():
+			st.c = nil
+		default:
+			return st.c, nil
+		}
+	}
+	c, err := nats.Dial(ctx, nats.Options{URL: st.cfg.URL, Name: "parakeet-jobs"})
+	if err != nil {
+		return nil, err
+	}
+	if err := st.setup(ctx, c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	st.c = c
+	return c, nil
+}
+
+// setup creates the streams and the work consumer, or updates them to this
+// instance's settings.
+func (st *natsStore) setup(ctx context.Context, c *nats.Conn) error {
+	streams := []nats.StreamConfig{
+		{Name: jobsStream, Subjects: []string{jobsPrefix + "*"}, MaxMsgsPerSubject: 1, MaxAge: st.cfg.Retention, Storage: "file"},
+		{Name: workStream, Subjects: []string{workSubject}, Retention: "workqueue", MaxMsgs: int64(st.cfg.MaxQueued),
+			Discard: "new", MaxAge: st.cfg.Retention, Storage: "file"},
+		{Name: audioStream, Subjects: []string{audioPrefix + "*.*"}, MaxAge: st.cfg.Retention, Storage: "file"},
+		{Name: workersStream, Subjects: []string{workersPrefix + "*"}, MaxMsgsPerSubject: 1, MaxAge: workerTTL, Storage: "memory"},
+	}
+	for _, cfg := range streams {
+		if err := c.CreateStream(ctx, cfg); err != nil {
+			return fmt.Errorf("stream %s: %w", cfg.Name, err)
+		}
+	}
+	// Redelivery is unlimited: Cluster.process counts the attempts itself,
+	// so a job whose last attempt died is still marked as failed.
+	err := c.CreateConsumer(ctx, workStream, nats.ConsumerConfig{
+		Durable:    workConsumer,
+		AckPolicy:  "explicit",
+		AckWait:    claimAckWait,
+		MaxDeliver: -1,
+	})
+	if err != nil {
+		return fmt.Errorf("consumer %s/%s: %w", workStream, workConsumer, err)
+	}
+	return nil
+}
+
+func (st *natsStore) putRecord(ctx context.Context, id string, data []byte) error {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.PublishJS(ctx, jobsPrefix+id, nil, data)
+	return err
+}
+
+func (st *natsStore) getRecord(ctx context.Context, id string) ([]byte, error) {
+	return st.last(ctx, jobsStream, jobsPrefix+id)
+}
+
+func (st *natsStore) deleteRecord(ctx context.Context, id string) error {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Purge(ctx, jobsStream, jobsPrefix+id)
+}
+
+func (st *natsStore) listRecords(ctx context.Context) ([]string, error) {
+	return st.keys(ctx, jobsStream, jobsPrefix)
+}
+
+// putAudio splits audio into chunks that fit the server's max_payload, with
+// room left for the base64 they are read back in.
+func (st *natsStore) putAudio(ctx context.Context, id string, data []byte) error {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return err
+	}
+	size := int(c.MaxPayload() / 2)
+	for n := 0; len(data) > 0; n++ {
+		chunk := data[:min(size, len(data))]
+		data = data[len(chunk):]
+		if _, err := c.PublishJS(ctx, audioPrefix+id+"."+strconv.Itoa(n), nil, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (st *natsStore) getAudio(ctx context.Context, id string) ([]byte, error) {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subjects, err := c.StreamSubjects(ctx, audioStream, audioPrefix+id+".*")
+	if err != nil {
+		return nil, err
+	}
+	if len(subjects) == 0 {
+		return nil, ErrNotFound
+	}
+	var audio []byte
+	for n := range len(subjects) {
+		chunk, err := c.LastMsg(ctx, audioStream, audioPrefix+id+"."+strconv.Itoa(n))
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			return nil, ErrNotFound // expired while being read
+		}
+		if err != nil {
+			return nil, err
+		}
+		audio = append(audio, chunk...)
+	}
+	return audio, nil
+}
+
+func (st *natsStore) deleteAudio(ctx context.Context, id string) error {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Purge(ctx, audioStream, audioPrefix+id+".*")
+}
+
+func (st *natsStore) enqueue(ctx context.Context, id string) error {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.PublishJS(ctx, workSubject, textproto.MIMEHeader{"Nats-Msg-Id": {id}}, []byte(id))
+	var apiErr *nats.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrCode == errCodeMaxMsgs {
+		return ErrQueueFull
+	}
+	return err
+}
+
+func (st *natsStore) claim(ctx context.Context, n int) ([]claim, error) {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := c.Fetch(ctx, workStream, workConsumer, n, claimWait)
+	claims := make([]claim, 0, len(msgs))
+	for _, msg := range msgs {
+		md, mdErr := msg.Metadata()
+		if mdErr != nil {
+			continue
+		}
+		claims = append(claims, claim{
+			id:      string(msg.Data),
+			attempt: int(md.Delivered),
+			ack:     func() error { return c.Ack(msg) },
+			nak:     func() error { return c.Nak(msg) },
+			touch:   func() error { return c.InProgress(msg) },
+		})
+	}
+	return claims, err
+}
+
+func (st *natsStore) pending(ctx context.Context) (int, error) {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	info, err := c.ConsumerInfo(ctx, workStream, workConsumer)
+	if err != nil {
+		return 0, err
+	}
+	return int(info.NumPending), nil
+}
+
+func (st *natsStore) heartbeat(ctx context.Context, w WorkerInfo) error {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return err
+	}
+	data, _ := json.Marshal(w)
+	_, err = c.PublishJS(ctx, workersPrefix+w.ID, nil, data)
+	return err
+}
+
+func (st *natsStore) leave(ctx context.Context, id string) error {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return err
+	}
+	return c.Purge(ctx, workersStream, workersPrefix+id)
+}
+
+func (st *natsStore) workers(ctx context.Context) ([]WorkerInfo, error) {
+	ids, err := st.keys(ctx, workersStream, workersPrefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WorkerInfo, 0, len(ids))
+	for _, id := range ids {
+		data, err := st.last(ctx, workersStream, workersPrefix+id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var w WorkerInfo
+		if json.Unmarshal(data, &w) == nil {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (st *natsStore) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.c != nil {
+		st.c.Close()
+		st.c = nil
+	}
+}
+
+// last returns the latest message on subject, or ErrNotFound.
+func (st *natsStore) last(ctx context.Context, stream, subject string) ([]byte, error) {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.LastMsg(ctx, stream, subject)
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// keys returns the last token of every subject under prefix that the
+// stream holds messages on.
+func (st *natsStore) keys(ctx context.Context, stream, prefix string) ([]string, error) {
+	c, err := st.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subjects, err := c.StreamSubjects(ctx, stream, prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(subjects))
+	for subject := range subjects {
+		keys = append(keys, strings.TrimPrefix(subject, prefix))
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,492 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mqtt is a minimal MQTT 3.1.1 client: enough to subscribe to
+// audio topics and publish results. It supports plain TCP and TLS, a clean
+// session, a will message, QoS 0 publishing and QoS 0/1 delivery. QoS 2,
+// persistent sessions and MQTT 5 are not implemented.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Control packet types (MQTT 3.1.1 section 2.2.1).
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetSubscribe  = 8
+	packetSuback     = 9
+	packetPingreq    = 12
+	packetPingresp   = 13
+	packetDisconnect = 14
+)
+
+const (
+	defaultKeepAlive  = 30 * time.Second
+	defaultMaxPacket  = 64 << 20
+	handshakeTimeout  = 10 * time.Second
+	subscribeTimeout  = 10 * time.Second
+	writeTimeout      = 10 * time.Second
+	messageBufferSize = 16
+)
+
+// ErrClosed is returned by operations on a closed client.
+var ErrClosed = errors.New("mqtt: client closed")
+
+// Message is one application message.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options configures Dial.
+type Options struct {
+	// Broker is the broker URL: mqtt:// or tcp:// (port 1883 by default),
+	// mqtts:// or ssl:// (TLS, port 8883 by default). User info in the URL
+	// is used as the username and password.
+	Broker   string
+	ClientID string
+	// KeepAlive is the ping interval; 30 s when zero.
+	KeepAlive time.Duration
+	// Will, when set, is published retained by the broker if the
+	// connection drops without a DISCONNECT.
+	Will *Message
+	// MaxPacket bounds incoming packets; larger ones end the connection.
+	// 64 MiB when zero.
+	MaxPacket int
+	// TLS overrides the TLS configuration for mqtts:// brokers.
+	TLS *tls.Config
+}
+
+// Client is one connection to a broker. Incoming messages arrive on
+// Messages until the connection ends; Done is closed then and Err tells
+// why.
+type Client struct {
+	conn      net.Conn
+	keepAlive time.Duration
+	maxPacket int
+
+	writeMu sync.Mutex
+	w       *bufio.Writer
+
+	mu       sync.Mutex
+	nextID   uint16
+	subacks  map[uint16]chan []byte
+	err      error
+	closed   bool
+	messages chan Message
+	quit     chan struct{} // closed by fail
+	done     chan struct{} // closed when the read loop has exited
+}
+
+// Dial connects to the broker and completes the MQTT handshake.
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	u, addr, useTLS, err := parseBroker(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: handshakeTimeout}
+	var conn net.Conn
+	if useTLS {
+		cfg := opts.TLS
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: u.Hostname()}
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: cfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: %w", err)
+	}
+
+	c := &Client{
+		conn:      conn,
+		keepAlive: opts.KeepAlive,
+		maxPacket: opts.MaxPacket,
+		w:         bufio.NewWriter(conn),
+		subacks:   make(map[uint16]chan []byte),
+		messages:  make(chan Message, messageBufferSize),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if c.keepAlive <= 0 {
+		c.keepAlive = defaultKeepAlive
+	}
+	if c.maxPacket <= 0 {
+		c.maxPacket = defaultMaxPacket
+	}
+	username := u.User.Username()
+	password, _ := u.User.Password()
+
+	r := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := c.write(packetConnect<<4, connectBody(opts.ClientID, username, password, c.keepAlive, opts.Will)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: connect: %w", err)
+	}
+	typ, body, err := readPacket(r, c.maxPacket)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: connect: %w", err)
+	}
+	if typ>>4 != packetConnack || len(body) != 2 {
+		conn.Close()
+		return nil, errors.New("mqtt: connect: unexpected reply")
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: connection refused: %s", connackReason(body[1]))
+	}
+	conn.SetDeadline(time.Time{})
+
+	go c.readLoop(r)
+	go c.pingLoop()
+	return c, nil
+}
+
+// CheckBroker reports whether raw is a usable broker URL.
+func CheckBroker(raw string) error {
+	_, _, _, err := parseBroker(raw)
+	return err
+}
+
+// parseBroker returns the broker URL, its host:port and whether it uses TLS.
+func parseBroker(raw string) (u *url.URL, addr string, useTLS bool, err error) {
+	u, err = url.Parse(raw)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("mqtt: invalid broker URL: %w", err)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "mqtt", "tcp":
+	case "mqtts", "ssl", "tls":
+		useTLS, port = true, "8883"
+	default:
+		return nil, "", false, fmt.Errorf("mqtt: unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, "", false, errors.New("mqtt: broker URL has no host")
+	}
+	addr = u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, addr, useTLS, nil
+}
+
+// Messages delivers incoming messages. It is closed when the connection
+// ends.
+func (c *Client) Messages() <-chan Message { return c.messages }
+
+// Done is closed when the connection has ended.
+func (c *Client) Done() <-chan struct{} { return c.done }
+
+// Err returns why the connection ended, or nil while it is up.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Subscribe subscribes to topic filters at QoS 1 and waits for the broker
+// to acknowledge them.
+func (c *Client) Subscribe(filters ...string) error {
+	if len(filters) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	id := c.nextID
+	ack := make(chan []byte, 1)
+	c.subacks[id] = ack
+	c.mu.Unlock()
+
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, f := range filters {
+		body = appendString(body, f)
+		body = append(body, 1) // requested QoS
+	}
+	if err := c.write(packetSubscribe<<4|0x02, body); err != nil {
+		return err
+	}
+	select {
+	case codes := <-ack:
+		for i, code := range codes {
+			if code == 0x80 && i < len(filters) {
+				return fmt.Errorf("mqtt: subscription to %q refused", filters[i])
+			}
+		}
+		return nil
+	case <-c.done:
+		if err := c.Err(); err != nil {
+			return err
+		}
+		return ErrClosed
+	case <-time.After(subscribeTimeout):
+		return errors.New("mqtt: subscribe timed out")
+	}
+}
+
+// Publish sends a message at QoS 0.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	flags := byte(0)
+	if retain {
+		flags = 0x01
+	}
+	body := appendString(nil, topic)
+	return c.write(packetPublish<<4|flags, append(body, payload...))
+}
+
+// Close sends DISCONNECT, so the broker discards the will message, and
+// closes the connection.
+func (c *Client) Close() error {
+	c.write(packetDisconnect<<4, nil)
+	c.fail(ErrClosed)
+	<-c.done
+	return nil
+}
+
+// fail ends the connection with err unless it has already ended.
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.err = err
+	close(c.quit)
+	c.conn.Close()
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer func() {
+		close(c.messages)
+		close(c.done)
+	}()
+	for {
+		// The broker answers every ping, so a silent connection is dead.
+		c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		typ, body, err := readPacket(r, c.maxPacket)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		switch typ >> 4 {
+		case packetPublish:
+			msg, id, qos, err := parsePublish(typ, body)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			if qos > 0 {
+				if err := c.write(packetPuback<<4, binary.BigEndian.AppendUint16(nil, id)); err != nil {
+					c.fail(err)
+					return
+				}
+			}
+			select {
+			case c.messages <- msg:
+			case <-c.quit:
+				return
+			}
+		case packetSuback:
+			if len(body) < 2 {
+				c.fail(errors.New("mqtt: malformed SUBACK"))
+				return
+			}
+			id := binary.BigEndian.Uint16(body)
+			c.mu.Lock()
+			ack := c.subacks[id]
+			delete(c.subacks, id)
+			c.mu.Unlock()
+			if ack != nil {
+				ack <- body[2:]
+			}
+		case packetPingresp, packetPuback:
+		default:
+			c.fail(fmt.Errorf("mqtt: unexpected packet type %d", typ>>4))
+			return
+		}
+	}
+}
+
+func (c *Client) pingLoop() {
+	t := time.NewTicker(c.keepAlive / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-t.C:
+			if err := c.write(packetPingreq<<4, nil); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// write sends one packet.
+func (c *Client) write(header byte, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.w.WriteByte(header)
+	c.w.Write(appendLength(nil, len(body)))
+	c.w.Write(body)
+	return c.w.Flush()
+}
+
+// connectBody builds the variable header and payload of CONNECT.
+func connectBody(clientID, username, password string, keepAlive time.Duration, will *Message) []byte {
+	flags := byte(0x02) // clean session
+	if will != nil {
+		flags |= 0x04 | 0x20 // will flag, will retain, QoS 0
+	}
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	b := appendString(nil, "MQTT")
+	b = append(b, 4, flags) // protocol level 4 = 3.1.1
+	b = binary.BigEndian.AppendUint16(b, uint16(min(keepAlive/time.Second, 65535)))
+	b = appendString(b, clientID)
+	if will != nil {
+		b = appendString(b, will.Topic)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(will.Payload)))
+		b = append(b, will.Payload...)
+	}
+	if username != "" {
+		b = appendString(b, username)
+		if password != "" {
+			b = appendString(b, password)
+		}
+	}
+	return b
+}
+
+// parsePublish decodes a PUBLISH packet.
+func parsePublish(header byte, body []byte) (msg Message, id uint16, qos byte, err error) {
+	qos = header >> 1 & 0x03
+	topic, rest, ok := cutString(body)
+	if !ok || qos > 2 {
+		return Message{}, 0, 0, errors.New("mqtt: malformed PUBLISH")
+	}
+	if qos > 0 {
+		if len(rest) < 2 {
+			return Message{}, 0, 0, errors.New("mqtt: malformed PUBLISH")
+		}
+		id, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	return Message{Topic: topic, Payload: rest, Retain: header&0x01 != 0}, id, qos, nil
+}
+
+// readPacket reads one packet: its fixed-header first byte and its body.
+func readPacket(r *bufio.Reader, maxPacket int) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		mult *= 128
+	}
+	if n > maxPacket {
+		return 0, nil, fmt.Errorf("mqtt: %d-byte packet exceeds the %d-byte limit", n, maxPacket)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// appendLength appends the variable-length "remaining length" encoding.
+func appendLength(b []byte, n int) []byte {
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func cutString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
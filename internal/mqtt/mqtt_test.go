@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts one client and hands its packets to the test.
+type fakeBroker struct {
+	ln      net.Listener
+	conn    net.Conn
+	r       *bufio.Reader
+	connack byte
+}
+
+func newFakeBroker(t *testing.T, connack byte) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return &fakeBroker{ln: ln, connack: connack}
+}
+
+func (b *fakeBroker) url(userinfo string) string {
+	return "mqtt://" + userinfo + b.ln.Addr().String()
+}
+
+// accept takes the client's CONNECT and answers it.
+func (b *fakeBroker) accept(t *testing.T) []byte {
+	conn, err := b.ln.Accept()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	b.conn, b.r = conn, bufio.NewReader(conn)
+	typ, body, err := readPacket(b.r, defaultMaxPacket)
+	if err != nil || typ>>4 != packetConnect {
+		t.Errorf("CONNECT: type %d, %v", typ>>4, err)
+	}
+	b.send(packetConnack<<4, []byte{0, b.connack})
+	return body
+}
+
+func (b *fakeBroker) send(header byte, body []byte) {
+	b.conn.Write(append(appendLength([]byte{header}, len(body)), body...))
+}
+
+func (b *fakeBroker) next(t *testing.T) (byte, []byte) {
+	t.Helper()
+	b.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	typ, body, err := readPacket(b.r, defaultMaxPacket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return typ, body
+}
+
+func TestClient_SubscribePublish(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	connect := make(chan []byte, 1)
+	go func() { connect <- b.accept(t) }()
+
+	c, err := Dial(context.Background(), Options{
+		Broker:   b.url("user:secret@"),
+		ClientID: "parakeet-test",
+		Will:     &Message{Topic: "parakeet/status", Payload: []byte("offline")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	body := <-connect
+	for _, want := range []string{"MQTT", "parakeet-test", "parakeet/status", "offline", "user", "secret"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("CONNECT lacks %q", want)
+		}
+	}
+	if flags := body[7]; flags != 0x02|0x04|0x20|0x80|0x40 {
+		t.Errorf("CONNECT flags = %#x", flags)
+	}
+
+	subscribed := make(chan error, 1)
+	go func() { subscribed <- c.Subscribe("parakeet/+/audio") }()
+	typ, sub := b.next(t)
+	if typ != packetSubscribe<<4|0x02 || !bytes.Contains(sub, []byte("parakeet/+/audio")) {
+		t.Fatalf("SUBSCRIBE = %#x %q", typ, sub)
+	}
+	b.send(packetSuback<<4, append(bytes.Clone(sub[:2]), 1))
+	if err := <-subscribed; err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// A QoS 1 delivery is acknowledged with its packet ID.
+	pub := binary.BigEndian.AppendUint16(appendString(nil, "parakeet/kitchen/audio"), 7)
+	b.send(packetPublish<<4|0x02, append(pub, "RIFF"...))
+	select {
+	case msg := <-c.Messages():
+		if msg.Topic != "parakeet/kitchen/audio" || string(msg.Payload) != "RIFF" {
+			t.Errorf("message = %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message")
+	}
+	if typ, ack := b.next(t); typ != packetPuback<<4 || binary.BigEndian.Uint16(ack) != 7 {
+		t.Errorf("PUBACK = %#x %v", typ, ack)
+	}
+
+	if err := c.Publish("parakeet/kitchen/transcript", []byte(`{"text":"hi"}`), true); err != nil {
+		t.Fatal(err)
+	}
+	typ, body = b.next(t)
+	msg, _, _, err := parsePublish(typ, body)
+	if err != nil || typ>>4 != packetPublish || !msg.Retain || msg.Topic != "parakeet/kitchen/transcript" || string(msg.Payload) != `{"text":"hi"}` {
+		t.Errorf("PUBLISH = %+v, %v", msg, err)
+	}
+
+	c.Close()
+	if typ, _ := b.next(t); typ != packetDisconnect<<4 {
+		t.Errorf("got packet %#x, want DISCONNECT", typ)
+	}
+	if _, ok := <-c.Messages(); ok {
+		t.Error("Messages still open after Close")
+	}
+}
+
+func TestClient_Refused(t *testing.T) {
+	b := newFakeBroker(t, 5)
+	go b.accept(t)
+	_, err := Dial(context.Background(), Options{Broker: b.url(""), ClientID: "x"})
+	if err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Errorf("Dial: %v, want not authorized", err)
+	}
+}
+
+func TestClient_BrokerGone(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	go b.accept(t)
+	c, err := Dial(context.Background(), Options{Broker: b.url(""), ClientID: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.conn.Close()
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed after the broker hung up")
+	}
+	if c.Err() == nil {
+		t.Error("Err() = nil after the broker hung up")
+	}
+	if err := c.Publish("t", nil, false); err == nil {
+		t.Error("Publish after disconnect succeeded")
+	}
+}
+
+func TestRemainingLength(t *testing.T) {
+	for _, tc := range []struct{ n, bytes int }{{0, 1}, {127, 1}, {128, 2}, {16383, 2}, {16384, 3}, {2097152, 4}, {268435455, 4}} {
+		if got := len(appendLength(nil, tc.n)); got != tc.bytes {
+			t.Errorf("%d encodes in %d bytes, want %d", tc.n, got, tc.bytes)
+		}
+	}
+	for _, n := range []int{0, 127, 128, 16384} {
+		packet := append(appendLength([]byte{0x30}, n), make([]byte, n)...)
+		if typ, body, err := readPacket(bufio.NewReader(bytes.NewReader(packet)), n); err != nil || typ != 0x30 || len(body) != n {
+			t.Errorf("%d: read %d bytes, %v", n, len(body), err)
+		}
+	}
+	if _, _, err := readPacket(bufio.NewReader(bytes.NewReader([]byte{0x30, 0xff, 0xff, 0xff, 0xff, 0x01})), 1<<28); err == nil {
+		t.Error("5-byte length accepted")
+	}
+	if _, _, err := readPacket(bufio.NewReader(bytes.NewReader([]byte{0x30, 0x02, 0, 0})), 1); err == nil {
+		t.Error("oversized packet accepted")
+	}
+}
@@ -0,0 +1,297 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fetchGrace is how long Fetch waits past its expiry for the server's
+// timeout status before giving up on its own.
+const fetchGrace = 5 * time.Second
+
+// ErrMsgNotFound is returned by LastMsg when the stream holds nothing on the
+// subject.
+var ErrMsgNotFound = errors.New("jetstream: no message found")
+
+// JetStream API error codes checked by this package.
+const (
+	errCodeStreamNameInUse = 10058
+	errCodeNoMessageFound  = 10037
+)
+
+// APIError is an error returned by the JetStream API.
+type APIError struct {
+	Code        int    `json:"code"`
+	ErrCode     int    `json:"err_code"`
+	Description string `json:"description"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("jetstream: %s (%d)", e.Description, e.ErrCode)
+}
+
+// ConsumerInfo is the part of a JetStream consumer's state used here.
+type ConsumerInfo struct {
+	Stream string `json:"stream_name"`
+	Name   string `json:"name"`
+	Config struct {
+		AckPolicy      string        `json:"ack_policy"`
+		AckWait        time.Duration `json:"ack_wait"` // nanoseconds on the wire
+		MaxDeliver     int           `json:"max_deliver"`
+		FilterSubject  string        `json:"filter_subject"`
+		DeliverSubject string        `json:"deliver_subject"`
+	} `json:"config"`
+	NumPending    uint64 `json:"num_pending"`
+	NumAckPending int    `json:"num_ack_pending"`
+}
+
+// StreamConfig is the part of a stream's configuration parakeet sets. Zero
+// values leave the server's defaults in place.
+type StreamConfig struct {
+	Name              string        `json:"name"`
+	Subjects          []string      `json:"subjects"`
+	Retention         string        `json:"retention,omitempty"` // limits, interest or workqueue
+	MaxMsgs           int64         `json:"max_msgs,omitempty"`
+	MaxMsgsPerSubject int64         `json:"max_msgs_per_subject,omitempty"`
+	MaxAge            time.Duration `json:"max_age,omitempty"` // nanoseconds on the wire
+	Discard           string        `json:"discard,omitempty"` // old or new
+	Storage           string        `json:"storage,omitempty"` // file or memory
+	Duplicates        time.Duration `json:"duplicate_window,omitempty"`
+}
+
+// ConsumerConfig is the part of a durable pull consumer's configuration
+// parakeet sets.
+type ConsumerConfig struct {
+	Durable       string        `json:"durable_name"`
+	AckPolicy     string        `json:"ack_policy"`
+	AckWait       time.Duration `json:"ack_wait,omitempty"`
+	MaxDeliver    int           `json:"max_deliver,omitempty"`
+	MaxAckPending int           `json:"max_ack_pending,omitempty"`
+}
+
+// Metadata is what a JetStream message's reply subject says about it.
+type Metadata struct {
+	Stream    string
+	Consumer  string
+	Delivered uint64 // delivery attempt, from 1
+	StreamSeq uint64
+}
+
+// Metadata parses the reply subject of a message delivered by a consumer:
+// $JS.ACK.<stream>.<consumer>.<delivered>.<stream seq>.<consumer seq>.<time>.<pending>,
+// or the newer form with a domain and account hash after $JS.ACK.
+func (m Msg) Metadata() (Metadata, error) {
+	t := strings.Split(m.Reply, ".")
+	if len(t) < 9 || t[0] != "$JS" || t[1] != "ACK" {
+		return Metadata{}, errors.New("nats: not a JetStream message")
+	}
+	if len(t) >= 12 {
+		t = t[2:] // the domain and account hash now stand where $JS.ACK was
+	}
+	md := Metadata{Stream: t[2], Consumer: t[3]}
+	var err1, err2 error
+	md.Delivered, err1 = strconv.ParseUint(t[4], 10, 64)
+	md.StreamSeq, err2 = strconv.ParseUint(t[5], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Metadata{}, errors.New("nats: malformed JetStream reply subject")
+	}
+	return md, nil
+}
+
+// ConsumerInfo looks up a durable consumer.
+func (c *Conn) ConsumerInfo(ctx context.Context, stream, consumer string) (*ConsumerInfo, error) {
+	var resp struct {
+		ConsumerInfo
+		Error *APIError `json:"error"`
+	}
+	if err := c.apiRequest(ctx, "$JS.API.CONSUMER.INFO."+stream+"."+consumer, nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return &resp.ConsumerInfo, nil
+}
+
+// CreateStream creates a stream, or updates it when one with the same name
+// but a different configuration exists.
+func (c *Conn) CreateStream(ctx context.Context, cfg StreamConfig) error {
+	req, _ := json.Marshal(cfg)
+	err := c.apiCall(ctx, "$JS.API.STREAM.CREATE."+cfg.Name, req, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.ErrCode == errCodeStreamNameInUse {
+		err = c.apiCall(ctx, "$JS.API.STREAM.UPDATE."+cfg.Name, req, nil)
+	}
+	return err
+}
+
+// CreateConsumer creates or updates a durable consumer on a stream.
+func (c *Conn) CreateConsumer(ctx context.Context, stream string, cfg ConsumerConfig) error {
+	req, _ := json.Marshal(map[string]any{"stream_name": stream, "config": cfg})
+	return c.apiCall(ctx, "$JS.API.CONSUMER.CREATE."+stream+"."+cfg.Durable, req, nil)
+}
+
+// LastMsg returns the payload of the newest message a stream holds on
+// subject, or ErrMsgNotFound.
+func (c *Conn) LastMsg(ctx context.Context, stream, subject string) ([]byte, error) {
+	req, _ := json.Marshal(map[string]string{"last_by_subj": subject})
+	var resp struct {
+		Message struct {
+			Data []byte `json:"data"` // base64 on the wire
+		} `json:"message"`
+	}
+	err := c.apiCall(ctx, "$JS.API.STREAM.MSG.GET."+stream, req, &resp)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.ErrCode == errCodeNoMessageFound {
+		return nil, ErrMsgNotFound
+	}
+	return resp.Message.Data, err
+}
+
+// Purge removes every message a stream holds on subject, which may contain
+// wildcards.
+func (c *Conn) Purge(ctx context.Context, stream, subject string) error {
+	req, _ := json.Marshal(map[string]string{"filter": subject})
+	return c.apiCall(ctx, "$JS.API.STREAM.PURGE."+stream, req, nil)
+}
+
+// StreamSubjects returns how many messages a stream holds on each subject
+// matching filter.
+func (c *Conn) StreamSubjects(ctx context.Context, stream, filter string) (map[string]uint64, error) {
+	req, _ := json.Marshal(map[string]string{"subjects_filter": filter})
+	var resp struct {
+		State struct {
+			Subjects map[string]uint64 `json:"subjects"`
+		} `json:"state"`
+	}
+	if err := c.apiCall(ctx, "$JS.API.STREAM.INFO."+stream, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.State.Subjects, nil
+}
+
+// Fetch pulls up to batch messages from a pull consumer, waiting up to
+// expires for them. It returns what arrived, possibly nothing.
+func (c *Conn) Fetch(ctx context.Context, stream, consumer string, batch int, expires time.Duration) ([]Msg, error) {
+	inbox := NewInbox()
+	sid, ch, err := c.Subscribe(inbox, batch+1)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Unsubscribe(sid)
+
+	req, _ := json.Marshal(map[string]any{"batch": batch, "expires": expires.Nanoseconds()})
+	if err := c.Publish("$JS.API.CONSUMER.MSG.NEXT."+stream+"."+consumer, inbox, nil, req); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(expires + fetchGrace)
+	defer timer.Stop()
+	var msgs []Msg
+	for len(msgs) < batch {
+		select {
+		case msg := <-ch:
+			switch msg.Status {
+			case 0:
+				msgs = append(msgs, msg)
+			case 404, 408:
+				// No messages, or the request expired.
+				return msgs, nil
+			case 409:
+				// Limits reached or the consumer changed; report it only
+				// when nothing was delivered.
+				if len(msgs) > 0 {
+					return msgs, nil
+				}
+				return nil, fmt.Errorf("jetstream: pull request ended: %s", msg.Header.Get("Description"))
+			default:
+				if len(msgs) > 0 {
+					return msgs, nil
+				}
+				return nil, fmt.Errorf("jetstream: pull request status %d", msg.Status)
+			}
+		case <-timer.C:
+			return msgs, nil
+		case <-ctx.Done():
+			return msgs, ctx.Err()
+		case <-c.quit:
+			return msgs, c.closedErr()
+		}
+	}
+	return msgs, nil
+}
+
+// PublishJS publishes to a subject captured by a stream and waits for the
+// stream to store it. A Nats-Msg-Id header lets the stream drop
+// duplicates of a message published again after a failure.
+func (c *Conn) PublishJS(ctx context.Context, subject string, header textproto.MIMEHeader, data []byte) (uint64, error) {
+	msg, err := c.Request(ctx, subject, header, data)
+	if err != nil {
+		if errors.Is(err, ErrNoResponders) {
+			return 0, fmt.Errorf("jetstream: no stream captures %q", subject)
+		}
+		return 0, err
+	}
+	var ack struct {
+		Seq   uint64    `json:"seq"`
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(msg.Data, &ack); err != nil {
+		return 0, fmt.Errorf("jetstream: invalid publish ack: %w", err)
+	}
+	if ack.Error != nil {
+		return 0, ack.Error
+	}
+	return ack.Seq, nil
+}
+
+// Ack acknowledges a JetStream message: it will not be redelivered.
+func (c *Conn) Ack(m Msg) error { return c.Publish(m.Reply, "", nil, []byte("+ACK")) }
+
+// Nak asks for a JetStream message to be redelivered.
+func (c *Conn) Nak(m Msg) error { return c.Publish(m.Reply, "", nil, []byte("-NAK")) }
+
+// InProgress resets a JetStream message's ack timer while it is worked on.
+func (c *Conn) InProgress(m Msg) error { return c.Publish(m.Reply, "", nil, []byte("+WPI")) }
+
+// apiRequest calls the JetStream API and decodes its JSON reply into v.
+func (c *Conn) apiRequest(ctx context.Context, subject string, req []byte, v any) error {
+	msg, err := c.Request(ctx, subject, nil, req)
+	if errors.Is(err, ErrNoResponders) {
+		return errors.New("jetstream: not enabled on the server")
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(msg.Data, v)
+}
+
+// apiCall is apiRequest for replies that either carry an error or the
+// fields decoded into v, which may be nil.
+func (c *Conn) apiCall(ctx context.Context, subject string, req []byte, v any) error {
+	var raw json.RawMessage
+	if err := c.apiRequest(ctx, subject, req, &raw); err != nil {
+		return err
+	}
+	var resp struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("jetstream: invalid API reply: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,555 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nats is a minimal NATS client with the JetStream calls a queue
+// worker needs: pulling from a durable consumer, acknowledging messages and
+// publishing with a stream acknowledgement. It speaks the core text
+// protocol over TCP or TLS, with headers and no-responders support.
+// Reconnection, clustering and key/value or object stores are not
+// implemented.
+package nats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPort      = "4222"
+	handshakeTimeout = 10 * time.Second
+	writeTimeout     = 10 * time.Second
+	pingInterval     = 30 * time.Second
+	// maxControlLine bounds a protocol line (INFO can be a few KiB).
+	maxControlLine = 64 << 10
+)
+
+// ErrClosed is returned by operations on a closed connection.
+var ErrClosed = errors.New("nats: connection closed")
+
+// ErrNoResponders is returned by Request when nobody listens on the
+// subject (for JetStream, when no stream captures it).
+var ErrNoResponders = errors.New("nats: no responders")
+
+// Msg is one delivered message. Status is set for server status messages
+// (404 no messages, 408 request timeout, 503 no responders, ...).
+type Msg struct {
+	Subject string
+	Reply   string
+	Header  textproto.MIMEHeader
+	Status  int
+	Data    []byte
+}
+
+// Options configures Dial.
+type Options struct {
+	// URL is the server URL: nats:// (port 4222 by default) or tls://.
+	// User info is sent as user and password, or as the token when there
+	// is no password.
+	URL string
+	// Name identifies the connection in server monitoring.
+	Name string
+	// TLS overrides the TLS configuration.
+	TLS *tls.Config
+}
+
+// serverInfo is the part of the server's INFO used here.
+type serverInfo struct {
+	Headers     bool  `json:"headers"`
+	TLSRequired bool  `json:"tls_required"`
+	MaxPayload  int64 `json:"max_payload"`
+}
+
+// Conn is one connection to a server.
+type Conn struct {
+	conn       net.Conn
+	maxPayload int64
+
+	writeMu sync.Mutex
+	w       *bufio.Writer
+
+	mu      sync.Mutex
+	nextSID int
+	subs    map[int]chan Msg
+	err     error
+	closed  bool
+	quit    chan struct{} // closed by fail
+	done    chan struct{} // closed when the read loop has exited
+
+	// Responses to Request arrive on one wildcard inbox subscription.
+	respPrefix string
+	respMu     sync.Mutex
+	respSID    int
+	resps      map[string]chan Msg
+}
+
+// CheckURL reports whether raw is a usable server URL.
+func CheckURL(raw string) error {
+	_, _, _, err := parseURL(raw)
+	return err
+}
+
+// parseURL returns the server URL, its host:port and whether TLS is
+// required by the scheme.
+func parseURL(raw string) (u *url.URL, addr string, useTLS bool, err error) {
+	u, err = url.Parse(raw)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("nats: invalid URL: %w", err)
+	}
+	switch u.Scheme {
+	case "nats":
+	case "tls":
+		useTLS = true
+	default:
+		return nil, "", false, fmt.Errorf("nats: unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, "", false, errors.New("nats: URL has no host")
+	}
+	addr = u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return u, addr, useTLS, nil
+}
+
+// Dial connects and completes the handshake.
+func Dial(ctx context.Context, opts Options) (*Conn, error) {
+	u, addr, useTLS, err := parseURL(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := (&net.Dialer{Timeout: handshakeTimeout}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	r := bufio.NewReader(conn)
+
+	line, err := readLine(r)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: read INFO: %w", err)
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("nats: expected INFO, got %q", line)
+	}
+	var info serverInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: parse INFO: %w", err)
+	}
+	if useTLS || info.TLSRequired {
+		cfg := opts.TLS
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: u.Hostname()}
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("nats: tls: %w", err)
+		}
+		conn, r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	connect := map[string]any{
+		"verbose":       false,
+		"pedantic":      false,
+		"lang":          "go",
+		"version":       "parakeet",
+		"protocol":      1,
+		"name":          opts.Name,
+		"headers":       info.Headers,
+		"no_responders": info.Headers,
+	}
+	if user := u.User.Username(); user != "" {
+		if pass, ok := u.User.Password(); ok {
+			connect["user"], connect["pass"] = user, pass
+		} else {
+			connect["auth_token"] = user
+		}
+	}
+	payload, _ := json.Marshal(connect)
+	if _, err := conn.Write([]byte("CONNECT " + string(payload) + "\r\nPING\r\n")); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats: connect: %w", err)
+	}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("nats: connect: %w", err)
+		}
+		if line == "PONG" {
+			break
+		}
+		if msg, ok := strings.CutPrefix(line, "-ERR "); ok {
+			conn.Close()
+			return nil, fmt.Errorf("nats: connect: %s", strings.Trim(msg, "'"))
+		}
+		// INFO updates and +OK may precede the PONG.
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Conn{
+		conn:       conn,
+		maxPayload: info.MaxPayload,
+		w:          bufio.NewWriter(conn),
+		subs:       make(map[int]chan Msg),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+		respPrefix: NewInbox() + ".",
+		resps:      make(map[string]chan Msg),
+	}
+	go c.readLoop(r)
+	go c.pingLoop()
+	return c, nil
+}
+
+// NewInbox returns a unique subject for replies.
+func NewInbox() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "_INBOX." + hex.EncodeToString(b[:])
+}
+
+// MaxPayload is the largest message the server accepts, in bytes.
+func (c *Conn) MaxPayload() int64 { return c.maxPayload }
+
+// Done is closed when the connection has ended.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// Err returns why the connection ended, or nil while it is up.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Subscribe delivers messages on subject to a channel with room for
+// buffer messages. A full channel holds up the whole connection, so
+// buffer must cover what the caller asked the server to send.
+func (c *Conn) Subscribe(subject string, buffer int) (int, <-chan Msg, error) {
+	ch := make(chan Msg, buffer)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, nil, ErrClosed
+	}
+	c.nextSID++
+	sid := c.nextSID
+	c.subs[sid] = ch
+	c.mu.Unlock()
+	if err := c.writeLine(fmt.Sprintf("SUB %s %d", subject, sid), nil); err != nil {
+		return 0, nil, err
+	}
+	return sid, ch, nil
+}
+
+// Unsubscribe stops a subscription. Its channel is not closed.
+func (c *Conn) Unsubscribe(sid int) error {
+	c.mu.Lock()
+	delete(c.subs, sid)
+	c.mu.Unlock()
+	return c.writeLine(fmt.Sprintf("UNSUB %d", sid), nil)
+}
+
+// Publish sends a message, with headers when header is not empty.
+func (c *Conn) Publish(subject, reply string, header textproto.MIMEHeader, data []byte) error {
+	if c.maxPayload > 0 && int64(len(data)) > c.maxPayload {
+		return fmt.Errorf("nats: %d-byte message exceeds the server's %d-byte limit", len(data), c.maxPayload)
+	}
+	if data == nil {
+		data = []byte{} // an empty payload still ends with CRLF
+	}
+	target := subject
+	if reply != "" {
+		target += " " + reply
+	}
+	if len(header) == 0 {
+		return c.writeLine(fmt.Sprintf("PUB %s %d", target, len(data)), data)
+	}
+	var h bytes.Buffer
+	h.WriteString("NATS/1.0\r\n")
+	for k, vs := range header {
+		for _, v := range vs {
+			fmt.Fprintf(&h, "%s: %s\r\n", k, v)
+		}
+	}
+	h.WriteString("\r\n")
+	return c.writeLine(fmt.Sprintf("HPUB %s %d %d", target, h.Len(), h.Len()+len(data)), append(h.Bytes(), data...))
+}
+
+// Request publishes a message and waits for the first reply.
+func (c *Conn) Request(ctx context.Context, subject string, header textproto.MIMEHeader, data []byte) (Msg, error) {
+	c.respMu.Lock()
+	if c.respSID == 0 {
+		sid, ch, err := c.Subscribe(c.respPrefix+"*", 64)
+		if err != nil {
+			c.respMu.Unlock()
+			return Msg{}, err
+		}
+		c.respSID = sid
+		go c.routeResponses(ch)
+	}
+	var tok [8]byte
+	_, _ = rand.Read(tok[:])
+	token := hex.EncodeToString(tok[:])
+	reply := make(chan Msg, 1)
+	c.resps[token] = reply
+	c.respMu.Unlock()
+	defer func() {
+		c.respMu.Lock()
+		delete(c.resps, token)
+		c.respMu.Unlock()
+	}()
+
+	if err := c.Publish(subject, c.respPrefix+token, header, data); err != nil {
+		return Msg{}, err
+	}
+	select {
+	case msg := <-reply:
+		if msg.Status == 503 {
+			return Msg{}, ErrNoResponders
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return Msg{}, ctx.Err()
+	case <-c.quit:
+		return Msg{}, c.closedErr()
+	}
+}
+
+func (c *Conn) routeResponses(ch <-chan Msg) {
+	for {
+		select {
+		case msg := <-ch:
+			token := strings.TrimPrefix(msg.Subject, c.respPrefix)
+			c.respMu.Lock()
+			reply := c.resps[token]
+			c.respMu.Unlock()
+			if reply != nil {
+				select {
+				case reply <- msg:
+				default:
+				}
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// Close drains nothing: it closes the connection at once.
+func (c *Conn) Close() error {
+	c.fail(ErrClosed)
+	<-c.done
+	return nil
+}
+
+func (c *Conn) closedErr() error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	return ErrClosed
+}
+
+// fail ends the connection with err unless it has already ended.
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.err = err
+	close(c.quit)
+	c.conn.Close()
+}
+
+func (c *Conn) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		// The server answers every ping, so a silent connection is dead.
+		c.conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+		line, err := readLine(r)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		verb, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "MSG", "HMSG":
+			msg, sid, err := readMsg(r, strings.ToUpper(verb) == "HMSG", args)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			c.mu.Lock()
+			ch := c.subs[sid]
+			c.mu.Unlock()
+			if ch == nil {
+				continue
+			}
+			select {
+			case ch <- msg:
+			case <-c.quit:
+				return
+			}
+		case "PING":
+			if err := c.writeLine("PONG", nil); err != nil {
+				c.fail(err)
+				return
+			}
+		case "PONG", "+OK", "INFO":
+		case "-ERR":
+			// Permission violations keep the connection open; anything
+			// else is followed by the server closing it.
+			if strings.Contains(strings.ToLower(args), "permissions violation") {
+				continue
+			}
+			c.fail(fmt.Errorf("nats: server error: %s", strings.Trim(args, "'")))
+			return
+		default:
+			c.fail(fmt.Errorf("nats: unexpected %q", line))
+			return
+		}
+	}
+}
+
+func (c *Conn) pingLoop() {
+	t := time.NewTicker(pingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-t.C:
+			if err := c.writeLine("PING", nil); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// writeLine sends a protocol line, followed by a payload when data is not
+// nil.
+func (c *Conn) writeLine(line string, data []byte) error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return c.closedErr()
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.w.WriteString(line)
+	c.w.WriteString("\r\n")
+	if data != nil {
+		c.w.Write(data)
+		c.w.WriteString("\r\n")
+	}
+	if err := c.w.Flush(); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+// readMsg reads the payload of a MSG or HMSG whose arguments are args:
+// "<subject> <sid> [reply] [<header bytes>] <total bytes>".
+func readMsg(r *bufio.Reader, withHeader bool, args string) (Msg, int, error) {
+	f := strings.Fields(args)
+	want := 3
+	if withHeader {
+		want = 4
+	}
+	if len(f) != want && len(f) != want+1 {
+		return Msg{}, 0, fmt.Errorf("nats: malformed message line %q", args)
+	}
+	msg := Msg{Subject: f[0]}
+	sid, err := strconv.Atoi(f[1])
+	if err != nil {
+		return Msg{}, 0, fmt.Errorf("nats: malformed message line %q", args)
+	}
+	if len(f) == want+1 {
+		msg.Reply = f[2]
+	}
+	total, err := strconv.Atoi(f[len(f)-1])
+	if err != nil || total < 0 {
+		return Msg{}, 0, fmt.Errorf("nats: malformed message line %q", args)
+	}
+	hdrLen := 0
+	if withHeader {
+		hdrLen, err = strconv.Atoi(f[len(f)-2])
+		if err != nil || hdrLen < 0 || hdrLen > total {
+			return Msg{}, 0, fmt.Errorf("nats: malformed message line %q", args)
+		}
+	}
+	buf := make([]byte, total+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return Msg{}, 0, err
+	}
+	if withHeader {
+		msg.Header, msg.Status, err = parseHeader(buf[:hdrLen])
+		if err != nil {
+			return Msg{}, 0, err
+		}
+	}
+	msg.Data = buf[hdrLen:total]
+	return msg, sid, nil
+}
+
+// parseHeader parses a "NATS/1.0[ <status>[ <description>]]" header block.
+func parseHeader(b []byte) (textproto.MIMEHeader, int, error) {
+	tr := textproto.NewReader(bufio.NewReader(bytes.NewReader(b)))
+	first, err := tr.ReadLine()
+	if err != nil || !strings.HasPrefix(first, "NATS/1.0") {
+		return nil, 0, errors.New("nats: malformed message header")
+	}
+	status := 0
+	if f := strings.Fields(first); len(f) > 1 {
+		status, _ = strconv.Atoi(f[1])
+	}
+	h, err := tr.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, fmt.Errorf("nats: malformed message header: %w", err)
+	}
+	return h, status, nil
+}
+
+// readLine reads one CRLF-terminated protocol line.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxControlLine {
+			return "", errors.New("nats: protocol line too long")
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
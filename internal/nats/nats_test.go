@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package nats
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer accepts one client and lets the test script the conversation.
+type fakeServer struct {
+	t    *testing.T
+	ln   net.Listener
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return &fakeServer{t: t, ln: ln}
+}
+
+// accept greets the client and returns its CONNECT line.
+func (f *fakeServer) accept() string {
+	conn, err := f.ln.Accept()
+	if err != nil {
+		f.t.Error(err)
+		return ""
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	f.conn, f.r = conn, bufio.NewReader(conn)
+	f.write(`INFO {"headers":true,"max_payload":1048576}` + "\r\n")
+	connect := f.line()
+	if ping := f.line(); ping != "PING" {
+		f.t.Errorf("got %q after CONNECT, want PING", ping)
+	}
+	f.write("PONG\r\n")
+	return connect
+}
+
+func (f *fakeServer) write(s string) { f.conn.Write([]byte(s)) }
+
+func (f *fakeServer) line() string {
+	line, err := f.r.ReadString('\n')
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// next reads the next client operation, skipping pings; for PUB and HPUB it
+// also returns the payload (headers included).
+func (f *fakeServer) next() (string, string) {
+	for {
+		line := f.line()
+		if line == "PING" {
+			f.write("PONG\r\n")
+			continue
+		}
+		if !strings.HasPrefix(line, "PUB ") && !strings.HasPrefix(line, "HPUB ") {
+			return line, ""
+		}
+		fields := strings.Fields(line)
+		n, _ := strconv.Atoi(fields[len(fields)-1])
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(f.r, buf); err != nil {
+			f.t.Fatal(err)
+		}
+		return line, string(buf[:n])
+	}
+}
+
+func (f *fakeServer) url() string { return "nats://parakeet:secret@" + f.ln.Addr().String() }
+
+func dialFake(t *testing.T) (*Conn, *fakeServer) {
+	f := newFakeServer(t)
+	connect := make(chan string, 1)
+	go func() { connect <- f.accept() }()
+	c, err := Dial(context.Background(), Options{URL: f.url(), Name: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if line := <-connect; !strings.Contains(line, `"user":"parakeet"`) || !strings.Contains(line, `"headers":true`) {
+		t.Errorf("CONNECT = %s", line)
+	}
+	return c, f
+}
+
+func TestConn_PubSub(t *testing.T) {
+	c, f := dialFake(t)
+
+	sid, msgs, err := c.Subscribe("audio.>", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op, _ := f.next(); op != fmt.Sprintf("SUB audio.> %d", sid) {
+		t.Fatalf("got %q", op)
+	}
+	f.write(fmt.Sprintf("MSG audio.kitchen %d 5\r\nhello\r\n", sid))
+	hdr := "NATS/1.0\r\nNats-Msg-Id: abc\r\n\r\n"
+	f.write(fmt.Sprintf("HMSG audio.office %d reply.to %d %d\r\n%sworld\r\n", sid, len(hdr), len(hdr)+5, hdr))
+	for _, want := range []Msg{{Subject: "audio.kitchen", Data: []byte("hello")}, {Subject: "audio.office", Reply: "reply.to", Data: []byte("world")}} {
+		select {
+		case msg := <-msgs:
+			if msg.Subject != want.Subject || msg.Reply != want.Reply || string(msg.Data) != string(want.Data) {
+				t.Errorf("got %+v, want %+v", msg, want)
+			}
+			if msg.Subject == "audio.office" && msg.Header.Get("Nats-Msg-Id") != "abc" {
+				t.Errorf("header = %v", msg.Header)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message")
+		}
+	}
+
+	if err := c.Publish("out", "", textproto.MIMEHeader{"Nats-Msg-Id": {"x1"}}, []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if op, payload := f.next(); !strings.HasPrefix(op, "HPUB out ") || !strings.Contains(payload, "Nats-Msg-Id: x1\r\n\r\ndata") {
+		t.Errorf("got %q %q", op, payload)
+	}
+	if err := c.Publish("empty", "", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if op, payload := f.next(); op != "PUB empty 0" || payload != "" {
+		t.Errorf("got %q %q", op, payload)
+	}
+	if err := c.Publish("out", "", nil, make([]byte, 2<<20)); err == nil {
+		t.Error("message over max_payload accepted")
+	}
+}
+
+func TestConn_RequestNoResponders(t *testing.T) {
+	c, f := dialFake(t)
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Request(context.Background(), "nobody", nil, []byte("?"))
+		done <- err
+	}()
+	sub, _ := f.next()
+	sid := strings.Fields(sub)[2]
+	op, _ := f.next()
+	reply := strings.Fields(op)[2]
+	hdr := "NATS/1.0 503\r\n\r\n"
+	f.write(fmt.Sprintf("HMSG %s %s %d %d\r\n%s\r\n", reply, sid, len(hdr), len(hdr), hdr))
+	if err := <-done; !errors.Is(err, ErrNoResponders) {
+		t.Errorf("Request: %v, want ErrNoResponders", err)
+	}
+}
+
+func TestConn_Fetch(t *testing.T) {
+	c, f := dialFake(t)
+	got := make(chan []Msg, 1)
+	go func() {
+		msgs, err := c.Fetch(context.Background(), "AUDIO", "parakeet", 3, time.Second)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- msgs
+	}()
+	sub, _ := f.next()
+	inbox, sid := strings.Fields(sub)[1], strings.Fields(sub)[2]
+	op, payload := f.next()
+	if !strings.HasPrefix(op, "PUB $JS.API.CONSUMER.MSG.NEXT.AUDIO.parakeet "+inbox) || !strings.Contains(payload, `"batch":3`) {
+		t.Fatalf("pull request = %q %q", op, payload)
+	}
+	f.write(fmt.Sprintf("MSG audio.in %s $JS.ACK.AUDIO.parakeet.2.41.7.1700000000000000000.0 3\r\nwav\r\n", sid))
+	// Fewer messages than asked for: the server ends the request with 404.
+	hdr := "NATS/1.0 404 No Messages\r\n\r\n"
+	f.write(fmt.Sprintf("HMSG %s %s %d %d\r\n%s\r\n", inbox, sid, len(hdr), len(hdr), hdr))
+
+	msgs := <-got
+	if len(msgs) != 1 || string(msgs[0].Data) != "wav" {
+		t.Fatalf("Fetch = %+v", msgs)
+	}
+	md, err := msgs[0].Metadata()
+	if err != nil || md.Stream != "AUDIO" || md.Consumer != "parakeet" || md.Delivered != 2 || md.StreamSeq != 41 {
+		t.Errorf("Metadata = %+v, %v", md, err)
+	}
+	if op, _ := f.next(); op != "UNSUB "+sid {
+		t.Errorf("got %q, want UNSUB", op)
+	}
+
+	if err := c.Ack(msgs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if op, payload := f.next(); !strings.HasPrefix(op, "PUB $JS.ACK.AUDIO.parakeet.2.41.") || payload != "+ACK" {
+		t.Errorf("ack = %q %q", op, payload)
+	}
+}
+
+func TestConn_StreamAPI(t *testing.T) {
+	c, f := dialFake(t)
+	// reply answers the next API request, checking its subject.
+	reply := func(subject, resp string) string {
+		t.Helper()
+		op, payload := f.next()
+		if strings.HasPrefix(op, "SUB ") {
+			op, payload = f.next()
+		}
+		fields := strings.Fields(op)
+		if fields[1] != subject {
+			t.Fatalf("request to %s, want %s", fields[1], subject)
+		}
+		f.write(fmt.Sprintf("MSG %s 1 %d\r\n%s\r\n", fields[2], len(resp), resp))
+		return payload
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.CreateStream(context.Background(), StreamConfig{Name: "JOBS", Subjects: []string{"jobs.*"}, MaxMsgsPerSubject: 1})
+	}()
+	// An existing stream with another configuration is updated instead.
+	payload := reply("$JS.API.STREAM.CREATE.JOBS", `{"error":{"code":400,"err_code":10058,"description":"stream name already in use"}}`)
+	if !strings.Contains(payload, `"max_msgs_per_subject":1`) {
+		t.Errorf("stream config = %s", payload)
+	}
+	reply("$JS.API.STREAM.UPDATE.JOBS", `{"config":{"name":"JOBS"}}`)
+	if err := <-done; err != nil {
+		t.Fatalf("CreateStream: %v", err)
+	}
+
+	got := make(chan []byte, 1)
+	go func() {
+		data, err := c.LastMsg(context.Background(), "JOBS", "jobs.a")
+		if err != nil {
+			t.Error(err)
+		}
+		got <- data
+	}()
+	if payload := reply("$JS.API.STREAM.MSG.GET.JOBS", `{"message":{"subject":"jobs.a","seq":3,"data":"aGVsbG8="}}`); payload != `{"last_by_subj":"jobs.a"}` {
+		t.Errorf("get request = %s", payload)
+	}
+	if data := <-got; string(data) != "hello" {
+		t.Errorf("LastMsg = %q", data)
+	}
+
+	go func() {
+		_, err := c.LastMsg(context.Background(), "JOBS", "jobs.b")
+		done <- err
+	}()
+	reply("$JS.API.STREAM.MSG.GET.JOBS", `{"error":{"code":404,"err_code":10037,"description":"no message found"}}`)
+	if err := <-done; !errors.Is(err, ErrMsgNotFound) {
+		t.Errorf("LastMsg of empty subject: %v, want ErrMsgNotFound", err)
+	}
+}
+
+func TestMsgMetadata_DomainForm(t *testing.T) {
+	msg := Msg{Reply: "$JS.ACK.hub.ACCHASH.AUDIO.parakeet.1.99.5.1700000000000000000.3.token"}
+	md, err := msg.Metadata()
+	if err != nil || md.Stream != "AUDIO" || md.Consumer != "parakeet" || md.Delivered != 1 || md.StreamSeq != 99 {
+		t.Errorf("Metadata = %+v, %v", md, err)
+	}
+	if _, err := (Msg{Reply: "_INBOX.x"}).Metadata(); err == nil {
+		t.Error("plain reply subject parsed as JetStream metadata")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"nats://localhost":        true,
+		"tls://nats.example:4443": true,
+		"http://localhost":        false,
+		"nats://":                 false,
+	} {
+		if err := CheckURL(raw); (err == nil) != ok {
+			t.Errorf("%s: %v", raw, err)
+		}
+	}
+}
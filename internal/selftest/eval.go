@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadTranscripts reads the transcript of every case from dir, one
+// <name>.txt per case: what a reference implementation (NeMo, or an earlier
+// build saved with SaveTranscripts) made of the same clips. A case without
+// one is an error, so a baseline always covers the whole corpus.
+func LoadTranscripts(dir string, cases []Case) (map[string]string, error) {
+	transcripts := make(map[string]string, len(cases))
+	var missing []string
+	for _, c := range cases {
+		data, err := os.ReadFile(filepath.Join(dir, c.Name+".txt"))
+		if os.IsNotExist(err) {
+			missing = append(missing, c.Name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		transcripts[c.Name] = strings.TrimSpace(string(data))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no transcript in %s for %s", dir, strings.Join(missing, ", "))
+	}
+	return transcripts, nil
+}
+
+// SaveTranscripts writes the transcript of every case of r that did not
+// fail to dir, as LoadTranscripts reads them.
+func SaveTranscripts(dir string, r Report) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, res := range r.Results {
+		if res.Err != nil {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, res.Name+".txt"), []byte(res.Hypothesis+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Regression is a case that scores worse than in the baseline.
+type Regression struct {
+	Name        string
+	WER         float64
+	BaselineWER float64
+}
+
+// Regressions lists the cases of r whose WER is more than tolerance above
+// that of the same case in base. Cases that failed to transcribe in either
+// are left to Passed.
+func (r Report) Regressions(base Report, tolerance float64) []Regression {
+	baseWER := make(map[string]float64, len(base.Results))
+	for _, res := range base.Results {
+		if res.Err == nil {
+			baseWER[res.Name] = res.Score.WER()
+		}
+	}
+	var out []Regression
+	for _, res := range r.Results {
+		b, ok := baseWER[res.Name]
+		if !ok || res.Err != nil {
+			continue
+		}
+		if wer := res.Score.WER(); wer > b+tolerance {
+			out = append(out, Regression{Name: res.Name, WER: wer, BaselineWER: b})
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranscriptsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	report := Report{Results: []CaseResult{
+		{Name: "a", Hypothesis: "first clip"},
+		{Name: "b", Err: errors.New("decode failed")},
+	}}
+	if err := SaveTranscripts(dir, report); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("failed case saved: %v", err)
+	}
+	got, err := LoadTranscripts(dir, []Case{{Name: "a"}})
+	if err != nil || got["a"] != "first clip" {
+		t.Fatalf("LoadTranscripts = %v, %v", got, err)
+	}
+	if _, err := LoadTranscripts(dir, []Case{{Name: "a"}, {Name: "b"}}); err == nil {
+		t.Error("LoadTranscripts accepted a baseline without b")
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	cases := []Case{{Name: "same", Reference: "one two three four"}, {Name: "worse", Reference: "one two three four"}}
+	run := func(worse string) Report {
+		return RunFunc(context.Background(), cases, 0, func(_ context.Context, c Case) (string, error) {
+			if c.Name == "worse" {
+				return worse, nil
+			}
+			return "one two three four", nil
+		})
+	}
+	report, base := run("one two tree four"), run("one two three four")
+	got := report.Regressions(base, 0)
+	if len(got) != 1 || got[0].Name != "worse" || got[0].WER != 0.25 || got[0].BaselineWER != 0 {
+		t.Fatalf("Regressions = %+v", got)
+	}
+	if got := report.Regressions(base, 0.25); len(got) != 0 {
+		t.Errorf("Regressions within tolerance = %+v", got)
+	}
+	if wer, words := report.CorpusWER(); wer != 0.125 || words != 8 {
+		t.Errorf("CorpusWER = %v, %d", wer, words)
+	}
+}
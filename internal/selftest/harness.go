@@ -0,0 +1,372 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package selftest is an end-to-end acceptance harness for a running parakeet
+// server. It builds a small corpus of audio with known transcripts (digital
+// silence, clips from a directory, and optionally synthetic speech from a
+// local TTS), sends every case through the real HTTP API exactly like a
+// client would, and scores the transcripts by word error rate against a
+// threshold.
+//
+// The same harness backs `parakeet selftest` (operators validating a fresh
+// deployment) and the integration-tagged test in this package (developers
+// validating a pipeline change).
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxWER is the pass threshold applied to cases that do not set their
+// own. Synthetic TTS speech is clean but robotic, so it is deliberately looser
+// than what the model scores on natural read speech.
+const DefaultMaxWER = 0.25
+
+// ttsSentences are Harvard sentences (IEEE 1969, public domain): short,
+// phonetically balanced and unambiguous to spell, which keeps WER about
+// recognition rather than about how a number or name was written.
+var ttsSentences = []string{
+	"The birch canoe slid on the smooth planks.",
+	"Glue the sheet to the dark blue background.",
+	"It's easy to tell the depth of a well.",
+	"These days a chicken leg is a rare dish.",
+	"Rice is often served in round bowls.",
+	"The juice of lemons makes fine punch.",
+	"The box was thrown beside the parked truck.",
+	"Four hours of steady work faced us.",
+}
+
+// Options configures corpus construction and the HTTP client side of a run.
+type Options struct {
+	// BaseURL is the server root, e.g. http://127.0.0.1:5092.
+	BaseURL string
+
+	// APIKey is sent as a Bearer token when non-empty.
+	APIKey string
+
+	// Full adds the synthetic TTS sentences to the corpus. Without it only the
+	// cheap cases run (silence plus any ClipsDir clips).
+	Full bool
+
+	// ClipsDir optionally points at a directory of audio files, each with a
+	// sibling .txt holding its reference transcript (clip.wav + clip.txt).
+	ClipsDir string
+
+	// TTSBinary and TTSVoice select the local synthesizer. Empty probes
+	// espeak-ng then espeak with an en-us voice.
+	TTSBinary string
+	TTSVoice  string
+
+	// MaxWER overrides DefaultMaxWER for cases without their own threshold.
+	MaxWER float64
+
+	// Timeout bounds each transcription request. Zero means two minutes.
+	Timeout time.Duration
+}
+
+// Case is one audio clip with its expected transcript.
+type Case struct {
+	Name      string
+	Filename  string
+	Audio     []byte
+	Reference string
+	// MaxWER is the pass threshold for this case; zero uses Options.MaxWER.
+	MaxWER float64
+}
+
+// CaseResult is the outcome of a single case.
+type CaseResult struct {
+	Name       string
+	Reference  string
+	Hypothesis string
+	Score      Score
+	MaxWER     float64
+	Elapsed    time.Duration
+	Err        error
+}
+
+// Passed reports whether the request succeeded and scored within threshold.
+func (r CaseResult) Passed() bool {
+	return r.Err == nil && r.Score.WER() <= r.MaxWER
+}
+
+// Report aggregates every case result of a run.
+type Report struct {
+	Results []CaseResult
+}
+
+// Passed reports whether every case passed. An empty report does not pass:
+// a harness that ran nothing has verified nothing.
+func (r Report) Passed() bool {
+	if len(r.Results) == 0 {
+		return false
+	}
+	for _, res := range r.Results {
+		if !res.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints a human-readable summary, one line per case plus the
+// corpus-level WER (total edits over total reference words).
+func (r Report) Write(w io.Writer) {
+	for _, res := range r.Results {
+		status := "PASS"
+		if !res.Passed() {
+			status = "FAIL"
+		}
+		if res.Err != nil {
+			fmt.Fprintf(w, "%s  %-28s error: %v\n", status, res.Name, res.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s  %-28s wer=%.3f (max %.2f) %6dms  %q\n",
+			status, res.Name, res.Score.WER(), res.MaxWER, res.Elapsed.Milliseconds(), res.Hypothesis)
+	}
+	if wer, words := r.CorpusWER(); words > 0 {
+		fmt.Fprintf(w, "corpus WER: %.3f over %d reference words\n", wer, words)
+	}
+}
+
+// CorpusWER returns the word error rate over the whole corpus, total edits
+// over total reference words, and that total. Cases that failed to
+// transcribe are left out.
+func (r Report) CorpusWER() (float64, int) {
+	var edits, words int
+	for _, res := range r.Results {
+		if res.Err != nil {
+			continue
+		}
+		edits += res.Score.Errors()
+		words += res.Score.ReferenceWords
+	}
+	if words == 0 {
+		return 0, 0
+	}
+	return float64(edits) / float64(words), words
+}
+
+// BuildCases assembles the corpus for opts: two seconds of digital silence
+// (which must transcribe to nothing), every clip in ClipsDir and, with Full,
+// one synthetic case per built-in sentence when a TTS binary is available.
+func BuildCases(ctx context.Context, opts Options) ([]Case, error) {
+	cases := []Case{silenceCase()}
+
+	if opts.ClipsDir != "" {
+		clips, err := LoadClips(opts.ClipsDir)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, clips...)
+	}
+
+	if opts.Full {
+		if tts := newSynthesizer(opts.TTSBinary, opts.TTSVoice); tts != nil {
+			for i, sentence := range ttsSentences {
+				audio, err := tts.Synthesize(ctx, sentence)
+				if err != nil {
+					return nil, fmt.Errorf("synthesize case %d: %w", i, err)
+				}
+				cases = append(cases, Case{
+					Name:      fmt.Sprintf("tts-%02d", i),
+					Filename:  fmt.Sprintf("tts-%02d.wav", i),
+					Audio:     audio,
+					Reference: sentence,
+				})
+			}
+		}
+	}
+	return cases, nil
+}
+
+// LoadClips reads every audio file in dir that has a sibling .txt reference.
+// Files without a reference are skipped; they cannot be scored.
+func LoadClips(dir string) ([]Case, error) {
+	cases, err := readClips(os.DirFS(dir))
+	if err != nil {
+		return nil, fmt.Errorf("read clips dir: %w", err)
+	}
+	return cases, nil
+}
+
+// readClips is LoadClips over any file system, the embedded reference
+// clips included.
+func readClips(fsys fs.FS) ([]Case, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	for _, e := range entries {
+		if e.IsDir() || strings.EqualFold(path.Ext(e.Name()), ".txt") {
+			continue
+		}
+		base := strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+		ref, err := fs.ReadFile(fsys, base+".txt")
+		if err != nil {
+			continue
+		}
+		audio, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read clip %s: %w", e.Name(), err)
+		}
+		cases = append(cases, Case{
+			Name:      base,
+			Filename:  e.Name(),
+			Audio:     audio,
+			Reference: strings.TrimSpace(string(ref)),
+		})
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// Run sends every case to the server's transcription endpoint sequentially
+// and scores it. Sequential on purpose: the per-case latency it reports is
+// only meaningful when cases do not compete for workers.
+func Run(ctx context.Context, opts Options, cases []Case) Report {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	client := &http.Client{Timeout: timeout}
+	return RunFunc(ctx, cases, opts.MaxWER, func(ctx context.Context, c Case) (string, error) {
+		return transcribe(ctx, client, opts, c)
+	})
+}
+
+// RunFunc is Run with the transcription left to transcribe, so a server can
+// score its own pipeline without going through HTTP. maxWER applies to the
+// cases without their own threshold; zero means DefaultMaxWER.
+func RunFunc(ctx context.Context, cases []Case, maxWER float64, transcribe func(context.Context, Case) (string, error)) Report {
+	if maxWER <= 0 {
+		maxWER = DefaultMaxWER
+	}
+	var report Report
+	for _, c := range cases {
+		res := CaseResult{Name: c.Name, Reference: c.Reference, MaxWER: c.MaxWER}
+		if res.MaxWER <= 0 {
+			res.MaxWER = maxWER
+		}
+		start := time.Now()
+		res.Hypothesis, res.Err = transcribe(ctx, c)
+		res.Elapsed = time.Since(start)
+		res.Score = Compare(c.Reference, res.Hypothesis)
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+// WaitHealthy polls the server's /health endpoint until it answers 200 or ctx
+// expires. Model loading takes seconds, so callers that just started a server
+// use this before Run.
+func WaitHealthy(ctx context.Context, baseURL string) error {
+	url := strings.TrimRight(baseURL, "/") + "/health"
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("server at %s not healthy: %w", baseURL, ctx.Err())
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// transcribe uploads one case as an OpenAI-style multipart request and
+// returns the transcript text.
+func transcribe(ctx context.Context, client *http.Client, opts Options, c Case) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", c.Filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(c.Audio); err != nil {
+		return "", err
+	}
+	_ = mw.WriteField("response_format", "json")
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	url := strings.TrimRight(opts.BaseURL, "/") + "/v1/audio/transcriptions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(payload)))
+	}
+
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(payload, &out); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	return out.Text, nil
+}
+
+// EncodeWAV renders mono float32 samples in [-1, 1] as a 16-bit PCM WAV.
+func EncodeWAV(samples []float32, sampleRate int) []byte {
+	var buf bytes.Buffer
+	dataSize := uint32(len(samples) * 2)
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // mono
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, dataSize)
+	for _, s := range samples {
+		if s > 1 {
+			s = 1
+		} else if s < -1 {
+			s = -1
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, int16(s*32767))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers transcription requests with a canned transcript per
+// uploaded filename, so Run can be exercised without models or ONNX Runtime.
+func fakeServer(t *testing.T, apiKey string, transcripts map[string]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey != "" && r.Header.Get("Authorization") != "Bearer "+apiKey {
+			http.Error(w, `{"error":{"message":"Invalid API key"}}`, http.StatusUnauthorized)
+			return
+		}
+		_, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got := r.FormValue("response_format"); got != "json" {
+			t.Errorf("response_format = %q, want json", got)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": transcripts[header.Filename]})
+	}))
+}
+
+func TestRunScoresEachCase(t *testing.T) {
+	srv := fakeServer(t, "secret", map[string]string{
+		"good.wav": "The birch canoe slid on the smooth planks",
+		"bad.wav":  "completely different words here",
+	})
+	defer srv.Close()
+
+	cases := []Case{
+		{Name: "silence", Filename: "silence.wav", Audio: EncodeWAV(make([]float32, 1600), 16000)},
+		{Name: "good", Filename: "good.wav", Audio: []byte("x"), Reference: "The birch canoe slid on the smooth planks."},
+		{Name: "bad", Filename: "bad.wav", Audio: []byte("x"), Reference: "Glue the sheet to the dark blue background."},
+	}
+	report := Run(context.Background(), Options{BaseURL: srv.URL, APIKey: "secret"}, cases)
+
+	if len(report.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(report.Results))
+	}
+	for _, res := range report.Results[:2] {
+		if !res.Passed() {
+			t.Errorf("case %s should pass: err=%v wer=%v", res.Name, res.Err, res.Score.WER())
+		}
+	}
+	if report.Results[2].Passed() {
+		t.Error("case bad should fail the WER threshold")
+	}
+	if report.Passed() {
+		t.Error("report with a failing case must not pass")
+	}
+
+	var out strings.Builder
+	report.Write(&out)
+	if !strings.Contains(out.String(), "FAIL  bad") || !strings.Contains(out.String(), "corpus WER") {
+		t.Errorf("summary missing expected lines:\n%s", out.String())
+	}
+}
+
+func TestRunReportsHTTPErrors(t *testing.T) {
+	srv := fakeServer(t, "secret", nil)
+	defer srv.Close()
+
+	report := Run(context.Background(), Options{BaseURL: srv.URL, APIKey: "wrong"},
+		[]Case{{Name: "x", Filename: "x.wav", Audio: []byte("x")}})
+	if report.Results[0].Err == nil || !strings.Contains(report.Results[0].Err.Error(), "401") {
+		t.Fatalf("expected a 401 error, got %v", report.Results[0].Err)
+	}
+	if report.Passed() {
+		t.Fatal("report with an errored case must not pass")
+	}
+}
+
+func TestEmptyReportDoesNotPass(t *testing.T) {
+	if (Report{}).Passed() {
+		t.Fatal("an empty report verified nothing and must not pass")
+	}
+}
+
+func TestBuildCasesLoadsClipsWithReferences(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("b.wav", "audio-b")
+	write("b.txt", " second clip \n")
+	write("a.mp3", "audio-a")
+	write("a.txt", "first clip")
+	write("orphan.wav", "no reference")
+
+	cases, err := BuildCases(context.Background(), Options{ClipsDir: dir})
+	if err != nil {
+		t.Fatalf("BuildCases: %v", err)
+	}
+	var names []string
+	for _, c := range cases {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "silence-2s,a,b" {
+		t.Fatalf("cases = %s, want silence-2s,a,b", got)
+	}
+	if cases[2].Reference != "second clip" {
+		t.Errorf("reference not trimmed: %q", cases[2].Reference)
+	}
+}
+
+func TestStartupCases(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "extra.wav"), []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "extra.txt"), []byte("an extra clip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := StartupCases(dir)
+	if err != nil {
+		t.Fatalf("StartupCases: %v", err)
+	}
+	if len(cases) < 2 || cases[0].Name != "silence-2s" || cases[len(cases)-1].Name != "extra" {
+		t.Fatalf("cases = %+v, want silence first and the clips dir last", cases)
+	}
+	for _, c := range cases[1 : len(cases)-1] {
+		if c.Reference == "" || len(c.Audio) == 0 {
+			t.Errorf("embedded clip %s has no reference or audio", c.Name)
+		}
+	}
+}
+
+func TestRunFuncScoresTranscripts(t *testing.T) {
+	cases := []Case{
+		{Name: "silence"},
+		{Name: "clip", Reference: "The birch canoe slid on the smooth planks."},
+	}
+	report := RunFunc(context.Background(), cases, 0, func(_ context.Context, c Case) (string, error) {
+		if c.Name == "silence" {
+			return "thank you", nil
+		}
+		return "the birch canoe slid on the smooth planks", nil
+	})
+	if report.Passed() || report.Results[0].Passed() || !report.Results[1].Passed() {
+		t.Fatalf("report = %+v, want only the silence case to fail", report.Results)
+	}
+	if report.Results[1].MaxWER != DefaultMaxWER {
+		t.Errorf("MaxWER = %v, want the default", report.Results[1].MaxWER)
+	}
+}
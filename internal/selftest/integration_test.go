@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build integration
+
+// End-to-end integration test. Build-tag gated so it never runs in the normal
+// test suite: it needs ONNX Runtime and the models, and it boots the real HTTP
+// server. It runs the same corpus as `parakeet selftest --full` (synthetic TTS
+// cases are skipped automatically when no espeak-ng/espeak is installed).
+//
+// Usage:
+//
+//	PARAKEET_MODELS=./models \
+//	PARAKEET_SELFTEST_CLIPS=./my-clips \
+//	go test -tags=integration -run TestEndToEnd -v ./internal/selftest/
+package selftest_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"parakeet/internal/selftest"
+	"parakeet/internal/server"
+)
+
+func TestEndToEnd(t *testing.T) {
+	modelsDir := os.Getenv("PARAKEET_MODELS")
+	if modelsDir == "" {
+		modelsDir = "../../models"
+	}
+	if _, err := os.Stat(modelsDir + "/config.json"); err != nil {
+		t.Skipf("models not found (%v); nothing to test", err)
+	}
+
+	port := freePort(t)
+	srv, err := server.New(server.Config{
+		Port:          port,
+		ModelsDir:     modelsDir,
+		LogLevel:      "info",
+		Workers:       1,
+		FFmpegEnabled: true,
+		FFmpegTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("server.New: %v", err)
+	}
+	go srv.Run()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		srv.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	opts := selftest.Options{
+		BaseURL:  fmt.Sprintf("http://127.0.0.1:%d", port),
+		Full:     true,
+		ClipsDir: os.Getenv("PARAKEET_SELFTEST_CLIPS"),
+	}
+	if err := selftest.WaitHealthy(ctx, opts.BaseURL); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := selftest.BuildCases(ctx, opts)
+	if err != nil {
+		t.Fatalf("BuildCases: %v", err)
+	}
+	report := selftest.Run(ctx, opts, cases)
+	report.Write(testWriter{t})
+	if !report.Passed() {
+		t.Fatal("end-to-end selftest failed")
+	}
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("pick free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// testWriter routes the report through t.Log so it shows up with -v.
+type testWriter struct{ t *testing.T }
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(string(p))
+	return len(p), nil
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// reference holds the clips the server checks itself with at startup; see
+// reference/README.md.
+//
+//go:embed reference
+var reference embed.FS
+
+// silenceCase is two seconds of digital silence, which must transcribe to
+// nothing.
+func silenceCase() Case {
+	return Case{
+		Name:     "silence-2s",
+		Filename: "silence.wav",
+		Audio:    EncodeWAV(make([]float32, 2*16000), 16000),
+	}
+}
+
+// StartupCases is the corpus of the server's startup self-test: the
+// silence case, the reference clips embedded in the binary and, when
+// clipsDir is set, the clips in it. Unlike BuildCases it never needs a TTS,
+// so it is cheap enough to run on every start.
+func StartupCases(clipsDir string) ([]Case, error) {
+	sub, err := fs.Sub(reference, "reference")
+	if err != nil {
+		return nil, err
+	}
+	embedded, err := readClips(sub)
+	if err != nil {
+		return nil, err
+	}
+	cases := append([]Case{silenceCase()}, embedded...)
+	if clipsDir != "" {
+		clips, err := LoadClips(clipsDir)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, clips...)
+	}
+	return cases, nil
+}
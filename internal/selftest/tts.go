@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultTTSBinaries are the local speech synthesizers probed, in order, when
+// no binary is configured. Both accept the same flags we use and write a WAV
+// to stdout, so the rest of the harness does not care which one it got.
+var defaultTTSBinaries = []string{"espeak-ng", "espeak"}
+
+// synthesizer turns sentences into WAV audio with a local TTS binary.
+//
+// TTS is an optional dependency, exactly like ffmpeg for the server: when no
+// binary resolves, newSynthesizer returns nil and the harness skips the
+// synthetic cases instead of failing. Each call runs its own process and
+// captures stdout in memory, so the synthesizer is safe for concurrent use.
+type synthesizer struct {
+	binaryPath string
+	voice      string
+	timeout    time.Duration
+}
+
+// newSynthesizer resolves the TTS binary once. An empty bin probes
+// defaultTTSBinaries. It returns nil (logging why) when nothing resolves.
+func newSynthesizer(bin, voice string) *synthesizer {
+	candidates := defaultTTSBinaries
+	if bin != "" {
+		candidates = []string{bin}
+	}
+	for _, c := range candidates {
+		resolved, err := exec.LookPath(c)
+		if err != nil {
+			continue
+		}
+		if voice == "" {
+			voice = "en-us"
+		}
+		return &synthesizer{binaryPath: resolved, voice: voice, timeout: 30 * time.Second}
+	}
+	slog.Warn("no local TTS binary found, synthetic speech cases will be skipped",
+		"candidates", strings.Join(candidates, ","))
+	return nil
+}
+
+// Synthesize renders text to WAV bytes. The synthesizer's native sample rate
+// (22.05 kHz for espeak-ng) is kept on purpose: it exercises the server's
+// resampling path just like a real upload would.
+func (s *synthesizer) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// -q: no audio device output. --stdout: write the WAV to stdout.
+	// -s 150: a slightly slower-than-default speaking rate, closer to
+	// conversational speech and easier on the recognizer.
+	cmd := exec.CommandContext(ctx, s.binaryPath, "-q", "--stdout", "-s", "150", "-v", s.voice, text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tts: %s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("tts: %s produced no audio", s.binaryPath)
+	}
+	return stdout.Bytes(), nil
+}
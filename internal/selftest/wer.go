@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Score is the word-level alignment of a hypothesis against a reference.
+// Substitutions, Deletions and Insertions come from a minimum edit-distance
+// alignment over normalized words; WER divides their sum by the reference
+// length, so it can exceed 1.0 when the hypothesis hallucinates extra words.
+type Score struct {
+	ReferenceWords int
+	Substitutions  int
+	Deletions      int
+	Insertions     int
+}
+
+// Errors returns the total number of word edits.
+func (s Score) Errors() int {
+	return s.Substitutions + s.Deletions + s.Insertions
+}
+
+// WER returns the word error rate. An empty reference scores 0 when the
+// hypothesis is empty too and 1 otherwise, so "expected silence, got words"
+// still fails a threshold check.
+func (s Score) WER() float64 {
+	if s.ReferenceWords == 0 {
+		if s.Insertions == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(s.Errors()) / float64(s.ReferenceWords)
+}
+
+// Compare aligns hypothesis against reference after normalizing both with
+// NormalizeWords.
+func Compare(reference, hypothesis string) Score {
+	return compareWords(NormalizeWords(reference), NormalizeWords(hypothesis))
+}
+
+// WER is a shorthand for Compare(reference, hypothesis).WER().
+func WER(reference, hypothesis string) float64 {
+	return Compare(reference, hypothesis).WER()
+}
+
+// NormalizeWords lowercases text, drops punctuation (keeping in-word
+// apostrophes such as "don't") and splits on whitespace. Scoring is about
+// recognition, not formatting, so "Hello, world." and "hello world" match.
+func NormalizeWords(text string) []string {
+	var b strings.Builder
+	runes := []rune(strings.ToLower(text))
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+			// Keep apostrophes only between letters ("don't"), not quotes.
+			if i > 0 && i < len(runes)-1 && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1]) {
+				b.WriteRune('\'')
+			} else {
+				b.WriteRune(' ')
+			}
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// Edit is one step of a word alignment: "equal" and "substitute" pair a
+// reference word with a hypothesis word, "delete" is a reference word the
+// hypothesis misses and "insert" a hypothesis word the reference lacks.
+type Edit struct {
+	Op         string `json:"op"`
+	Reference  string `json:"reference,omitempty"`
+	Hypothesis string `json:"hypothesis,omitempty"`
+}
+
+// Diff aligns hypothesis against reference like Compare and returns the
+// alignment in order, over normalized words, with runs of the same edit
+// merged into one: {"equal", "turn on the", "turn on the"}.
+func Diff(reference, hypothesis string) []Edit {
+	var diff []Edit
+	for _, e := range alignWords(NormalizeWords(reference), NormalizeWords(hypothesis)) {
+		if n := len(diff); n > 0 && diff[n-1].Op == e.Op {
+			diff[n-1].Reference = strings.TrimSpace(diff[n-1].Reference + " " + e.Reference)
+			diff[n-1].Hypothesis = strings.TrimSpace(diff[n-1].Hypothesis + " " + e.Hypothesis)
+			continue
+		}
+		diff = append(diff, e)
+	}
+	return diff
+}
+
+// compareWords counts the edits of the alignment of two word sequences.
+func compareWords(ref, hyp []string) Score {
+	s := Score{ReferenceWords: len(ref)}
+	for _, e := range alignWords(ref, hyp) {
+		switch e.Op {
+		case "substitute":
+			s.Substitutions++
+		case "delete":
+			s.Deletions++
+		case "insert":
+			s.Insertions++
+		}
+	}
+	return s
+}
+
+// alignWords runs a Levenshtein alignment over two word sequences and
+// backtracks once to recover the edits, one per word, in order.
+func alignWords(ref, hyp []string) []Edit {
+	n, m := len(ref), len(hyp)
+	dist := make([][]int, n+1)
+	for i := range dist {
+		dist[i] = make([]int, m+1)
+		dist[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		dist[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			dist[i][j] = min(dist[i-1][j-1]+cost, dist[i-1][j]+1, dist[i][j-1]+1)
+		}
+	}
+
+	var edits []Edit
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && ref[i-1] == hyp[j-1] && dist[i][j] == dist[i-1][j-1]:
+			edits = append(edits, Edit{Op: "equal", Reference: ref[i-1], Hypothesis: hyp[j-1]})
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && dist[i][j] == dist[i-1][j-1]+1:
+			edits = append(edits, Edit{Op: "substitute", Reference: ref[i-1], Hypothesis: hyp[j-1]})
+			i, j = i-1, j-1
+		case i > 0 && dist[i][j] == dist[i-1][j]+1:
+			edits = append(edits, Edit{Op: "delete", Reference: ref[i-1]})
+			i--
+		default:
+			edits = append(edits, Edit{Op: "insert", Hypothesis: hyp[j-1]})
+			j--
+		}
+	}
+	slices.Reverse(edits)
+	return edits
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeWords(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello, world.", []string{"hello", "world"}},
+		{"It's easy to tell", []string{"it's", "easy", "to", "tell"}},
+		{"'quoted' words", []string{"quoted", "words"}},
+		{"  multiple   spaces\n", []string{"multiple", "spaces"}},
+		{"", []string{}},
+	}
+	for _, tc := range cases {
+		got := NormalizeWords(tc.in)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("NormalizeWords(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		name     string
+		ref, hyp string
+		want     Score
+		wantWER  float64
+	}{
+		{"identical ignoring case and punctuation", "The birch canoe.", "the birch canoe", Score{ReferenceWords: 3}, 0},
+		{"one substitution", "a b c d", "a x c d", Score{ReferenceWords: 4, Substitutions: 1}, 0.25},
+		{"one deletion", "a b c d", "a b d", Score{ReferenceWords: 4, Deletions: 1}, 0.25},
+		{"one insertion", "a b c d", "a b c c d", Score{ReferenceWords: 4, Insertions: 1}, 0.25},
+		{"empty reference and hypothesis", "", "", Score{}, 0},
+		{"words on expected silence", "", "thank you", Score{Insertions: 2}, 1},
+		{"nothing recognized", "a b", "", Score{ReferenceWords: 2, Deletions: 2}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Compare(tc.ref, tc.hyp)
+			if got != tc.want {
+				t.Fatalf("Compare(%q, %q) = %+v, want %+v", tc.ref, tc.hyp, got, tc.want)
+			}
+			if math.Abs(got.WER()-tc.wantWER) > 1e-9 {
+				t.Fatalf("WER = %v, want %v", got.WER(), tc.wantWER)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("Turn on the kitchen lights, please.", "turn on the chicken lights now please")
+	want := []Edit{
+		{Op: "equal", Reference: "turn on the", Hypothesis: "turn on the"},
+		{Op: "substitute", Reference: "kitchen", Hypothesis: "chicken"},
+		{Op: "equal", Reference: "lights", Hypothesis: "lights"},
+		{Op: "insert", Hypothesis: "now"},
+		{Op: "equal", Reference: "please", Hypothesis: "please"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %+v, want %+v", got, want)
+	}
+	if got := Diff("a b", ""); !reflect.DeepEqual(got, []Edit{{Op: "delete", Reference: "a b"}}) {
+		t.Errorf("Diff with nothing recognized = %+v", got)
+	}
+}
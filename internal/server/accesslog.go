@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// accessLogger writes one line per HTTP request to -access-log, apart from
+// the application log: JSON, or Common Log Format with the duration, audio
+// seconds, real-time factor and request ID appended.
+type accessLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer // nil for stdout
+	clf    bool
+}
+
+// newAccessLogger opens target ("-" for stdout, else a file appended to)
+// for format "json" or "clf".
+func newAccessLogger(target, format string) (*accessLogger, error) {
+	l := &accessLogger{}
+	switch format {
+	case "json":
+	case "clf":
+		l.clf = true
+	default:
+		return nil, fmt.Errorf("invalid -access-log-format %q (available: json, clf)", format)
+	}
+	if target == "-" {
+		l.w = os.Stdout
+		return l, nil
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("open -access-log: %w", err)
+	}
+	l.w, l.closer = f, f
+	return l, nil
+}
+
+// accessEntry is one access log record.
+type accessEntry struct {
+	Time         time.Time `json:"time"`
+	RequestID    string    `json:"request_id,omitempty"`
+	Remote       string    `json:"remote"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Proto        string    `json:"proto"`
+	Status       int       `json:"status"`
+	Bytes        int64     `json:"bytes"`
+	DurationMs   float64   `json:"duration_ms"`
+	AudioSeconds float64   `json:"audio_seconds,omitempty"`
+	RTF          float64   `json:"rtf,omitempty"` // duration / audio, below 1 is faster than real time
+}
+
+// log writes e as one line.
+func (l *accessLogger) log(e accessEntry) {
+	var line []byte
+	if l.clf {
+		line = fmt.Appendf(nil, "%s - - [%s] %q %d %d %.1f %.3f %.4f %q\n",
+			e.Remote, e.Time.Format("02/Jan/2006:15:04:05 -0700"), e.Method+" "+e.Path+" "+e.Proto,
+			e.Status, e.Bytes, e.DurationMs, e.AudioSeconds, e.RTF, e.RequestID)
+	} else {
+		line, _ = json.Marshal(e)
+		line = append(line, '\n')
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(line)
+}
+
+// Close closes the log file.
+func (l *accessLogger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// logAccess records every request in the access log once it is answered;
+// without -access-log it passes requests through. It runs inside
+// timeResponses, whose requestMetrics carry the audio duration.
+func (s *Server) logAccess(next http.Handler) http.Handler {
+	if s.access == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		var audioSeconds float64
+		if m := metricsFrom(r.Context()); m != nil {
+			audioSeconds = m.audioSeconds
+		}
+
+		elapsed := time.Since(start)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		remote, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remote = r.RemoteAddr
+		}
+		e := accessEntry{
+			Time:         start,
+			RequestID:    requestIDFrom(r.Context()),
+			Remote:       remote,
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Proto:        r.Proto,
+			Status:       rec.status,
+			Bytes:        rec.bytes,
+			DurationMs:   float64(elapsed.Microseconds()) / 1000,
+			AudioSeconds: audioSeconds,
+		}
+		if audioSeconds > 0 {
+			e.RTF = elapsed.Seconds() / audioSeconds
+		}
+		s.access.log(e)
+	})
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestLogAccess(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noteAudio(r.Context(), 4)
+		io.WriteString(w, "hello")
+	})
+	serve := func(format string) string {
+		var buf bytes.Buffer
+		s := &Server{access: &accessLogger{w: &buf, clf: format == "clf"}}
+		r := httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)
+		r.RemoteAddr = "192.0.2.7:5000"
+		r.Header.Set(requestIDHeader, "req-1")
+		chain(handler, assignRequestID, timeResponses, s.logAccess).ServeHTTP(httptest.NewRecorder(), r)
+		return buf.String()
+	}
+
+	var e accessEntry
+	if err := json.Unmarshal([]byte(serve("json")), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Method != "POST" || e.Path != "/v1/audio/transcriptions" || e.Status != 200 || e.Bytes != 5 ||
+		e.Remote != "192.0.2.7" || e.RequestID != "req-1" || e.AudioSeconds != 4 || e.RTF <= 0 {
+		t.Fatalf("entry = %+v", e)
+	}
+
+	clf := regexp.MustCompile(`^192\.0\.2\.7 - - \[[^\]]+\] "POST /v1/audio/transcriptions HTTP/1\.1" 200 5 [0-9.]+ 4\.000 [0-9.]+ "req-1"\n$`)
+	if line := serve("clf"); !clf.MatchString(line) {
+		t.Fatalf("clf line = %q", line)
+	}
+}
+
+func TestNewAccessLogger(t *testing.T) {
+	if _, err := newAccessLogger("-", "xml"); err == nil {
+		t.Fatal("want error for an unknown format")
+	}
+	path := filepath.Join(t.TempDir(), "access.log")
+	for range 2 {
+		l, err := newAccessLogger(path, "json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.log(accessEntry{Method: "GET", Path: "/health", Status: 200})
+		l.Close()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n != 2 {
+		t.Fatalf("%d lines, want 2 appended", n)
+	}
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// modelAlias is one entry of -model-aliases: a further model name the API
+// answers to, for clients with a hard-coded model such as whisper-1.
+type modelAlias struct {
+	name   string
+	target string // "" is the loaded model, whatever its name
+}
+
+// parseModelAliases parses -model-aliases: comma-separated "alias" (the
+// loaded model) or "alias=model" entries.
+func parseModelAliases(v string) ([]modelAlias, error) {
+	var aliases []modelAlias
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, target, _ := strings.Cut(entry, "=")
+		name, target = strings.TrimSpace(name), strings.TrimSpace(target)
+		if name == "" {
+			return nil, fmt.Errorf("%q: want alias or alias=model", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("alias %q listed twice", name)
+		}
+		seen[name] = true
+		aliases = append(aliases, modelAlias{name: name, target: target})
+	}
+	return aliases, nil
+}
+
+// checkModelAliases verifies that every alias names the loaded model and
+// none shadows it.
+func checkModelAliases(aliases []modelAlias, loaded string) error {
+	for _, a := range aliases {
+		if a.name == loaded {
+			return fmt.Errorf("alias %q is the loaded model's own name", a.name)
+		}
+		if a.target != "" && a.target != loaded {
+			return fmt.Errorf("alias %q: model %q is not loaded (loaded: %s)", a.name, a.target, loaded)
+		}
+	}
+	return nil
+}
+
+// resolveModel returns the loaded model name that model, as sent by a
+// client, stands for, and whether model is an alias.
+func (s *Server) resolveModel(model string) (string, bool) {
+	for _, a := range s.aliases {
+		if a.name == model {
+			return s.transcriber().Info().Name, true
+		}
+	}
+	return model, false
+}
+
+// noteModel logs the model a request asked for when it is an alias, so
+// the clients still on hard-coded names can be found.
+func (s *Server) noteModel(ctx context.Context, model string) {
+	if target, ok := s.resolveModel(model); ok {
+		slog.InfoContext(ctx, "model alias used", "alias", model, "model", target)
+	}
+}
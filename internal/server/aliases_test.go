@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestParseModelAliases(t *testing.T) {
+	aliases, err := parseModelAliases(" whisper-1 , gpt-4o-transcribe=parakeet-tdt-0.6b-v3,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(aliases); got != "[{whisper-1 } {gpt-4o-transcribe parakeet-tdt-0.6b-v3}]" {
+		t.Errorf("aliases = %s", got)
+	}
+	for _, bad := range []string{"=parakeet", "whisper-1,whisper-1"} {
+		if _, err := parseModelAliases(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestCheckModelAliases(t *testing.T) {
+	const loaded = "parakeet-tdt-0.6b-v3"
+	for _, tc := range []struct {
+		aliases string
+		ok      bool
+	}{
+		{"whisper-1", true},
+		{"whisper-1=" + loaded, true},
+		{"whisper-1=parakeet-tdt-1.1b", false},
+		{loaded, false},
+	} {
+		aliases, err := parseModelAliases(tc.aliases)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkModelAliases(aliases, loaded); (err == nil) != tc.ok {
+			t.Errorf("%q: err = %v", tc.aliases, err)
+		}
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	s := &Server{aliases: []modelAlias{{name: "whisper-1"}}}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+	if model, ok := s.resolveModel("whisper-1"); !ok || model != asr.DefaultModelName {
+		t.Errorf("whisper-1 = %s, %v", model, ok)
+	}
+	if model, ok := s.resolveModel("other"); ok || model != "other" {
+		t.Errorf("other = %s, %v", model, ok)
+	}
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// defaultKeyName is the name PARAKEET_API_KEY is accounted under.
+const defaultKeyName = "default"
+
+// loadAPIKeys reads a -api-keys-file: one "name:key" per line, with blank
+// lines and lines starting with # ignored. It returns the keys mapped to
+// their names. Several keys may share a name, which then adds up their
+// usage; a key listed twice is an error.
+func loadAPIKeys(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open API keys file: %w", err)
+	}
+	defer f.Close()
+
+	keys := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, key, ok := strings.Cut(line, ":")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("%s:%d: want name:key", path, n)
+		}
+		if strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%s:%d: key name %q contains whitespace", path, n, name)
+		}
+		if other, dup := keys[key]; dup {
+			return nil, fmt.Errorf("%s:%d: key of %q is also listed for %q", path, n, name, other)
+		}
+		keys[key] = name
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read API keys file: %w", err)
+	}
+	return keys, nil
+}
+
+// authEnabled reports whether API requests must carry a key.
+func (s *Server) authEnabled() bool {
+	return s.apiKey != "" || len(s.apiKeys) > 0 || s.oidc != nil
+}
+
+// apiKeyName returns the name token is accounted under, and whether it is
+// accepted: PARAKEET_API_KEY, one of the -api-keys-file, or a JWT of the
+// -oidc-issuer, named by its subject.
+func (s *Server) apiKeyName(token string) (string, bool) {
+	if s.apiKey != "" && token == s.apiKey {
+		return defaultKeyName, true
+	}
+	if name, ok := s.apiKeys[token]; ok {
+		return name, true
+	}
+	if s.oidc != nil && looksLikeJWT(token) {
+		subject, err := s.oidc.verify(token)
+		if err != nil {
+			slog.Debug("bearer token rejected", "error", err)
+			return "", false
+		}
+		return subject, true
+	}
+	return "", false
+}
+
+// isAdmin reports whether token may use /admin/*; see requireAdmin. It is
+// apart from apiKeyName: being a valid API key or JWT does not make a
+// caller an admin.
+func (s *Server) isAdmin(token string) bool {
+	if s.adminKey != "" && token == s.adminKey {
+		return true
+	}
+	if s.oidc != nil && looksLikeJWT(token) {
+		return s.oidc.isAdmin(token)
+	}
+	return s.adminKey == "" && len(s.apiKeys) == 0 && s.oidc == nil && token == s.apiKey
+}
+
+type apiKeyNameKey struct{}
+
+// withAPIKeyName records in ctx the name of the key the request
+// authenticated with.
+func withAPIKeyName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, apiKeyNameKey{}, name)
+}
+
+// apiKeyNameFrom returns the key name of ctx, empty when authentication is
+// off.
+func apiKeyNameFrom(ctx context.Context) string {
+	name, _ := ctx.Value(apiKeyNameKey{}).(string)
+	return name
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAPIKeys(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "keys")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	keys, err := loadAPIKeys(write("# teams\nsearch: sk-search\n\nsupport:sk-support-1\nsupport:sk-support-2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"sk-search": "search", "sk-support-1": "support", "sk-support-2": "support"}
+	if len(keys) != len(want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	for key, name := range want {
+		if keys[key] != name {
+			t.Errorf("keys[%q] = %q, want %q", key, keys[key], name)
+		}
+	}
+
+	for content, wantErr := range map[string]string{
+		"sk-nameless\n":           "want name:key",
+		"search:\n":               "want name:key",
+		"two words:sk-1\n":        "whitespace",
+		"search:sk-1\nads:sk-1\n": "also listed",
+	} {
+		if _, err := loadAPIKeys(write(content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("loadAPIKeys(%q) = %v, want an error with %q", content, err, wantErr)
+		}
+	}
+}
+
+func TestRequireAuth_NamedKeys(t *testing.T) {
+	s := &Server{apiKey: "env-key", apiKeys: map[string]string{"sk-search": "search"}}
+	var name string
+	h := s.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name = apiKeyNameFrom(r.Context())
+	}))
+	for token, want := range map[string]string{"env-key": defaultKeyName, "sk-search": "search", "sk-other": ""} {
+		name = ""
+		req := httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if name != want {
+			t.Errorf("token %q: key name %q, want %q", token, name, want)
+		}
+		if want == "" && rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, rec.Code)
+		}
+	}
+}
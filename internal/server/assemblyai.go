@@ -0,0 +1,765 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/jobs"
+)
+
+// AssemblyAI compatibility limits.
+const (
+	// assemblyAIMaxAudioBytes bounds an upload or a downloaded audio_url.
+	// Async jobs are meant for long recordings, so the limit is well above
+	// the 25 MB of the synchronous endpoints.
+	assemblyAIMaxAudioBytes = 200 << 20
+	// assemblyAIMaxStoredBytes bounds all uploads held at once.
+	assemblyAIMaxStoredBytes = 1 << 30
+	// assemblyAIUploadTTL is how long an upload_url stays usable.
+	assemblyAIUploadTTL = time.Hour
+	// assemblyAIRetention is how long finished transcripts can be polled; in
+	// a job cluster, uploads are kept as long.
+	assemblyAIRetention = 24 * time.Hour
+	// assemblyAIFetchTimeout bounds the download of a remote audio_url.
+	assemblyAIFetchTimeout = 5 * time.Minute
+	// assemblyAIWebhookTimeout bounds one webhook delivery.
+	assemblyAIWebhookTimeout = 10 * time.Second
+)
+
+// AssemblyAI wire types. Only the fields parakeet can fill are present.
+
+type assemblyAIError struct {
+	Error string `json:"error"`
+}
+
+type assemblyAIUploadResponse struct {
+	UploadURL string `json:"upload_url"`
+}
+
+type assemblyAITranscriptRequest struct {
+	AudioURL               string `json:"audio_url"`
+	LanguageCode           string `json:"language_code"`
+	Multichannel           bool   `json:"multichannel"`
+	WebhookURL             string `json:"webhook_url"`
+	WebhookAuthHeaderName  string `json:"webhook_auth_header_name"`
+	WebhookAuthHeaderValue string `json:"webhook_auth_header_value"`
+}
+
+type assemblyAIWord struct {
+	Text       string  `json:"text"`
+	Start      int64   `json:"start"` // milliseconds
+	End        int64   `json:"end"`   // milliseconds
+	Confidence float64 `json:"confidence"`
+	Channel    string  `json:"channel,omitempty"` // 1-based, multichannel only
+}
+
+// assemblyAITranscript is the transcript resource. Fields that are not known
+// until the job completes are pointers so they encode as null meanwhile.
+type assemblyAITranscript struct {
+	ID            string           `json:"id"`
+	Status        jobs.Status      `json:"status"`
+	AudioURL      string           `json:"audio_url"`
+	Text          *string          `json:"text"`
+	Words         []assemblyAIWord `json:"words"`
+	Confidence    *float64         `json:"confidence"`
+	AudioDuration *float64         `json:"audio_duration"`
+	LanguageCode  string           `json:"language_code"`
+	Multichannel  bool             `json:"multichannel"`
+	AudioChannels int              `json:"audio_channels,omitempty"`
+	WebhookURL    *string          `json:"webhook_url"`
+	Error         string           `json:"error,omitempty"`
+
+	// Files, FilesDone and FilesTotal report an archive of audio files
+	// file by file; the transcript's text is then their texts one per line.
+	// They are parakeet's own.
+	Files      []assemblyAIFile `json:"files,omitempty"`
+	FilesDone  int              `json:"files_done,omitempty"`
+	FilesTotal int              `json:"files_total,omitempty"`
+}
+
+// assemblyAIFile is one audio file of an archive transcript.
+type assemblyAIFile struct {
+	Filename      string   `json:"filename"`
+	Text          *string  `json:"text"`
+	AudioDuration *float64 `json:"audio_duration"`
+	Error         string   `json:"error,omitempty"`
+}
+
+type assemblyAIListItem struct {
+	ID          string      `json:"id"`
+	ResourceURL string      `json:"resource_url"`
+	Status      jobs.Status `json:"status"`
+	Created     string      `json:"created"`
+	Completed   *string     `json:"completed"`
+	AudioURL    string      `json:"audio_url"`
+	Error       *string     `json:"error"`
+}
+
+type assemblyAIList struct {
+	PageDetails struct {
+		Limit       int     `json:"limit"`
+		ResultCount int     `json:"result_count"`
+		CurrentURL  string  `json:"current_url"`
+		PrevURL     *string `json:"prev_url"`
+		NextURL     *string `json:"next_url"`
+	} `json:"page_details"`
+	Transcripts []assemblyAIListItem `json:"transcripts"`
+}
+
+type assemblyAIWebhook struct {
+	TranscriptID string      `json:"transcript_id"`
+	Status       jobs.Status `json:"status"`
+}
+
+func sendAssemblyAIError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(assemblyAIError{Error: message})
+}
+
+// errUploadsFull is returned when the upload store holds
+// assemblyAIMaxStoredBytes already.
+var errUploadsFull = errors.New("upload storage is full")
+
+// uploadStorage keeps the audio posted to /v2/upload until a transcript job
+// references it by its upload_url: uploadStore on this instance, or
+// clusterUploads shared by the job cluster. put and store take over the
+// spool when they succeed. Each upload belongs to the API key name that
+// posted it (apiKeyNameFrom), and get finds it only for that owner.
+type uploadStorage interface {
+	put(data *spool, owner string) (string, error)
+	// store keeps data under a given id, which put would have picked.
+	store(id, owner string, data *spool) error
+	get(id, owner string) ([]byte, bool)
+	// close releases what the storage holds on this instance.
+	close()
+}
+
+// uploadStore keeps uploads for assemblyAIUploadTTL, in memory or, past
+// -upload-spool-mb, in temp files.
+type uploadStore struct {
+	mu    sync.Mutex
+	files map[string]storedUpload
+	bytes int64
+}
+
+type storedUpload struct {
+	data    *spool
+	owner   string
+	created time.Time
+}
+
+func newUploadStore() *uploadStore {
+	return &uploadStore{files: make(map[string]storedUpload)}
+}
+
+// put stores data and returns its ID, or errUploadsFull.
+func (u *uploadStore) put(data *spool, owner string) (string, error) {
+	id := newRequestID()
+	return id, u.store(id, owner, data)
+}
+
+func (u *uploadStore) store(id, owner string, data *spool) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	cutoff := time.Now().Add(-assemblyAIUploadTTL)
+	for id, f := range u.files {
+		if f.created.Before(cutoff) {
+			u.bytes -= f.data.Len()
+			f.data.Close()
+			delete(u.files, id)
+		}
+	}
+	if u.bytes+data.Len() > assemblyAIMaxStoredBytes {
+		return errUploadsFull
+	}
+	u.files[id] = storedUpload{data: data, owner: owner, created: time.Now()}
+	u.bytes += data.Len()
+	return nil
+}
+
+func (u *uploadStore) get(id, owner string) ([]byte, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	f, ok := u.files[id]
+	if !ok || f.owner != owner || time.Since(f.created) > assemblyAIUploadTTL {
+		return nil, false
+	}
+	data, err := f.data.Bytes()
+	return data, err == nil
+}
+
+func (u *uploadStore) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for id, f := range u.files {
+		f.data.Close()
+		delete(u.files, id)
+	}
+	u.bytes = 0
+}
+
+// requireAssemblyAIAuth accepts the API key as AssemblyAI SDKs send it, the
+// bare key in the Authorization header, as well as "Bearer <key>".
+func (s *Server) requireAssemblyAIAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		name, ok := s.apiKeyName(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if !ok {
+			sendAssemblyAIError(w, "Authentication error, API token missing/invalid", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withAPIKeyName(r.Context(), name)))
+	})
+}
+
+// requestBaseURL is the scheme and host the client used to reach the
+// server, for URLs handed back in responses.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p == "http" || p == "https" {
+		scheme = p
+	}
+	return scheme + "://" + r.Host
+}
+
+// handleAssemblyAIUpload serves POST /v2/upload: the raw request body is
+// kept and an upload_url for POST /v2/transcript is returned.
+// Requests speaking tus go to handleTusCreate instead.
+func (s *Server) handleAssemblyAIUpload(w http.ResponseWriter, r *http.Request) {
+	if isTusRequest(r) {
+		s.handleTusCreate(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		sendAssemblyAIError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, assemblyAIMaxAudioBytes)
+	data := newSpool(s.spoolBytes())
+	if _, err := io.Copy(data, r.Body); err != nil {
+		data.Close()
+		sendAssemblyAIError(w, "Error reading upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if data.Len() == 0 {
+		sendAssemblyAIError(w, "Upload is empty", http.StatusBadRequest)
+		return
+	}
+	id, err := s.uploads.put(data, apiKeyNameFrom(r.Context()))
+	if err != nil {
+		data.Close()
+	}
+	if errors.Is(err, errUploadsFull) {
+		sendAssemblyAIError(w, "Upload storage is full, try again later", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		sendAssemblyAIError(w, "Error storing upload: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assemblyAIUploadResponse{UploadURL: requestBaseURL(r) + "/v2/upload/" + id})
+}
+
+// handleAssemblyAITranscripts serves /v2/transcript: POST submits a job,
+// GET lists the retained ones.
+func (s *Server) handleAssemblyAITranscripts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.submitAssemblyAITranscript(w, r)
+	case http.MethodGet:
+		s.listAssemblyAITranscripts(w, r)
+	default:
+		sendAssemblyAIError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) submitAssemblyAITranscript(w http.ResponseWriter, r *http.Request) {
+	var req assemblyAITranscriptRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		sendAssemblyAIError(w, "Invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.AudioURL == "" {
+		sendAssemblyAIError(w, "audio_url is required", http.StatusBadRequest)
+		return
+	}
+	audio, err := s.assemblyAIAudio(r, req.AudioURL)
+	if errors.Is(err, errUploadNotFound) {
+		sendAssemblyAIError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendAssemblyAIError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.WebhookURL != "" {
+		if !s.config.AssemblyAIAllowURLs {
+			sendAssemblyAIError(w, "webhook_url is disabled on this server (see -assemblyai-allow-urls)", http.StatusBadRequest)
+			return
+		}
+		if err := checkHTTPURL(req.WebhookURL); err != nil {
+			sendAssemblyAIError(w, "webhook_url: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.LanguageCode == "" {
+		req.LanguageCode = "en"
+	}
+	if err := s.checkLanguage(req.LanguageCode); err != nil {
+		sendAssemblyAIError(w, "language_code: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, _ := url.Parse(req.AudioURL)
+	opts := asr.TranscribeOptions{
+		Format:            strings.ToLower(path.Ext(u.Path)),
+		Language:          req.LanguageCode,
+		Channels:          asr.ChannelMix,
+		Conditioning:      s.conditioning,
+		Segmentation:      s.segmentation,
+		NoSpeechThreshold: s.config.NoSpeechThreshold,
+		Guard:             s.guard,
+		Confidence:        s.confidence,
+		Formatting:        s.formatting,
+		Fallback:          s.fallback,
+		Denoise:           s.config.Denoise,
+		Priority:          asr.PriorityBatch,
+	}
+	if req.Multichannel {
+		opts.Channels = asr.ChannelPerChannel
+	}
+	task := jobs.Task{
+		Audio:    audio,
+		AudioURL: req.AudioURL,
+		Options:  opts,
+		Meta: map[string]string{
+			"audio_url":     req.AudioURL,
+			"language_code": req.LanguageCode,
+			"multichannel":  strconv.FormatBool(req.Multichannel),
+			"webhook_url":   req.WebhookURL,
+			jobOwnerMeta:    apiKeyNameFrom(r.Context()),
+		},
+	}
+	if req.WebhookAuthHeaderName != "" {
+		task.Meta["webhook_auth_header_name"] = req.WebhookAuthHeaderName
+		task.Meta["webhook_auth_header_value"] = req.WebhookAuthHeaderValue
+	}
+
+	job, err := s.jobs.Submit(task)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		sendAssemblyAIError(w, "Too many transcripts queued, try again later", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		sendAssemblyAIError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.Info("queued assemblyai transcript", "id", job.ID, "multichannel", req.Multichannel)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assemblyAITranscriptOf(job))
+}
+
+// errUploadNotFound is returned for an upload_url of this server that the
+// caller's key did not upload, or that expired.
+var errUploadNotFound = errors.New("audio_url: upload not found")
+
+// assemblyAIAudio resolves an audio_url. Upload URLs issued by this server
+// (by any instance, in a job cluster) to the caller's key are resolved to
+// their audio now; others on this host fail with errUploadNotFound, as if
+// they did not exist. Anything else is left for the job to download when it
+// starts, if the operator allowed remote URLs, and nil is returned.
+func (s *Server) assemblyAIAudio(r *http.Request, audioURL string) ([]byte, error) {
+	if err := checkHTTPURL(audioURL); err != nil {
+		return nil, fmt.Errorf("audio_url: %w", err)
+	}
+	u, _ := url.Parse(audioURL)
+	if id, ok := strings.CutPrefix(u.Path, "/v2/upload/"); ok {
+		owner := apiKeyNameFrom(r.Context())
+		if data, ok := s.uploads.get(id, owner); ok {
+			return data, nil
+		}
+		if s.tus.pending(id, owner) {
+			return nil, errors.New("audio_url is a resumable upload that is not complete yet")
+		}
+		if u.Host == r.Host {
+			return nil, errUploadNotFound
+		}
+	}
+	if !s.config.AssemblyAIAllowURLs {
+		return nil, errors.New("audio_url must be an upload_url returned by /v2/upload; remote URLs are disabled on this server (see -assemblyai-allow-urls)")
+	}
+	return nil, nil
+}
+
+// checkHTTPURL rejects anything but absolute http and https URLs.
+func checkHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// fetchAudio downloads a remote audio file, bounded in time and size.
+func fetchAudio(ctx context.Context, audioURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, assemblyAIFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, audioURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, assemblyAIMaxAudioBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > assemblyAIMaxAudioBytes {
+		return nil, fmt.Errorf("audio is larger than %d bytes", assemblyAIMaxAudioBytes)
+	}
+	return data, nil
+}
+
+// assemblyAIJobDone is called by the job store once a job has finished, on
+// the instance that ran it.
+func (s *Server) assemblyAIJobDone(job jobs.Job) {
+	if job.Meta["webhook_url"] != "" {
+		go s.deliverAssemblyAIWebhook(job)
+	}
+}
+
+// deliverAssemblyAIWebhook notifies the client that a job finished. As with
+// AssemblyAI, the body only carries the ID and status; the client fetches
+// the transcript itself.
+func (s *Server) deliverAssemblyAIWebhook(job jobs.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), assemblyAIWebhookTimeout)
+	defer cancel()
+	body, _ := json.Marshal(assemblyAIWebhook{TranscriptID: job.ID, Status: job.Status})
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Meta["webhook_url"], bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("assemblyai webhook failed", "id", job.ID, "error", err)
+		return
+	}
+	hr.Header.Set("Content-Type", "application/json")
+	if name := job.Meta["webhook_auth_header_name"]; name != "" {
+		hr.Header.Set(name, job.Meta["webhook_auth_header_value"])
+	}
+	resp, err := http.DefaultClient.Do(hr)
+	if err != nil {
+		slog.Warn("assemblyai webhook failed", "id", job.ID, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("assemblyai webhook rejected", "id", job.ID, "status", resp.StatusCode)
+	}
+}
+
+func (s *Server) listAssemblyAITranscripts(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			sendAssemblyAIError(w, "limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	status := jobs.Status(r.URL.Query().Get("status"))
+
+	all, err := s.jobs.List()
+	if err != nil {
+		sendAssemblyAIError(w, "Error listing transcripts: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	base := requestBaseURL(r)
+	var list assemblyAIList
+	list.Transcripts = []assemblyAIListItem{}
+	for _, job := range all {
+		if status != "" && job.Status != status || !ownsJob(r, job) {
+			continue
+		}
+		if len(list.Transcripts) == limit {
+			break
+		}
+		item := assemblyAIListItem{
+			ID:          job.ID,
+			ResourceURL: base + "/v2/transcript/" + job.ID,
+			Status:      job.Status,
+			Created:     job.Created.UTC().Format(time.RFC3339Nano),
+			AudioURL:    job.Meta["audio_url"],
+		}
+		if !job.Completed.IsZero() {
+			completed := job.Completed.UTC().Format(time.RFC3339Nano)
+			item.Completed = &completed
+		}
+		if job.Error != "" {
+			item.Error = &job.Error
+		}
+		list.Transcripts = append(list.Transcripts, item)
+	}
+	list.PageDetails.Limit = limit
+	list.PageDetails.ResultCount = len(list.Transcripts)
+	list.PageDetails.CurrentURL = base + r.URL.RequestURI()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleAssemblyAITranscript serves /v2/transcript/{id}: GET polls a job,
+// DELETE removes it.
+func (s *Server) handleAssemblyAITranscript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		sendAssemblyAIError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	job, err := s.assemblyAIJob(r)
+	if err == nil && r.Method == http.MethodDelete {
+		job, err = s.jobs.Delete(job.ID)
+	}
+	if errors.Is(err, jobs.ErrNotFound) {
+		sendAssemblyAIError(w, "Transcript not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendAssemblyAIError(w, "Error reading transcript: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assemblyAITranscriptOf(job))
+}
+
+// jobOwnerMeta is the job Meta key holding the name of the API key that
+// submitted it, empty without authentication. Jobs without it, the
+// captions archived by -streams, are shared by every key.
+const jobOwnerMeta = "owner"
+
+// ownsJob reports whether the key of r may read job.
+func ownsJob(r *http.Request, job jobs.Job) bool {
+	owner, ok := job.Meta[jobOwnerMeta]
+	return !ok || owner == apiKeyNameFrom(r.Context())
+}
+
+// assemblyAIJob returns the job of the request's {id}; a job of another
+// key is not found.
+func (s *Server) assemblyAIJob(r *http.Request) (jobs.Job, error) {
+	job, err := s.jobs.Get(r.PathValue("id"))
+	if err == nil && !ownsJob(r, job) {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	return job, err
+}
+
+// handleAssemblyAISubtitles serves GET /v2/transcript/{id}/srt and /vtt,
+// with AssemblyAI's optional chars_per_caption.
+func (s *Server) handleAssemblyAISubtitles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendAssemblyAIError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := r.PathValue("format")
+	if format != "srt" && format != "vtt" {
+		sendAssemblyAIError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	job, err := s.assemblyAIJob(r)
+	if errors.Is(err, jobs.ErrNotFound) {
+		sendAssemblyAIError(w, "Transcript not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendAssemblyAIError(w, "Error reading transcript: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if job.Status != jobs.StatusCompleted {
+		sendAssemblyAIError(w, "Transcript is not completed (status: "+string(job.Status)+")", http.StatusBadRequest)
+		return
+	}
+	if job.Result == nil {
+		sendAssemblyAIError(w, "Transcript of an archive has no single "+format+" file; get /v2/transcript/"+job.ID+"/archive", http.StatusBadRequest)
+		return
+	}
+	layout, err := assemblyAICaptionLayout(r)
+	if err != nil {
+		sendAssemblyAIError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeTranscription(w, job.Result, format, job.Meta["language_code"], layout)
+}
+
+// assemblyAICaptionLayout reads chars_per_caption, which bounds whole
+// captions, so they keep to one line.
+func assemblyAICaptionLayout(r *http.Request) (cueLayout, error) {
+	v := r.URL.Query().Get("chars_per_caption")
+	if v == "" {
+		return cueLayout{}, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return cueLayout{}, errors.New("chars_per_caption must be a positive integer")
+	}
+	return cueLayout{MaxLineChars: n, MaxLines: 1}, nil
+}
+
+// handleAssemblyAIArchive serves GET /v2/transcript/{id}/archive for the
+// transcript of an archive: a zip archive with the transcript of each file
+// in format (any response_format, srt by default), and chars_per_caption
+// as for the subtitles.
+func (s *Server) handleAssemblyAIArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendAssemblyAIError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := cmp.Or(r.URL.Query().Get("format"), "srt")
+	if _, err := parseResponseFormat(format); err != nil {
+		sendAssemblyAIError(w, "format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	layout, err := assemblyAICaptionLayout(r)
+	if err != nil {
+		sendAssemblyAIError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	job, err := s.assemblyAIJob(r)
+	if errors.Is(err, jobs.ErrNotFound) {
+		sendAssemblyAIError(w, "Transcript not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendAssemblyAIError(w, "Error reading transcript: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if job.Status != jobs.StatusCompleted {
+		sendAssemblyAIError(w, "Transcript is not completed (status: "+string(job.Status)+")", http.StatusBadRequest)
+		return
+	}
+	if job.FileCount == 0 {
+		sendAssemblyAIError(w, "Transcript is not of an archive", http.StatusBadRequest)
+		return
+	}
+	var buf bytes.Buffer
+	if err := writeResultsArchive(&buf, job.Files, format, job.Meta["language_code"], layout); err != nil {
+		sendAssemblyAIError(w, "Error writing archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+job.ID+`.zip"`)
+	w.Write(buf.Bytes())
+}
+
+// assemblyAITranscriptOf renders a job as AssemblyAI's transcript resource.
+// Times are in milliseconds; the transcript confidence is the mean word
+// confidence.
+func assemblyAITranscriptOf(job jobs.Job) assemblyAITranscript {
+	t := assemblyAITranscript{
+		ID:           job.ID,
+		Status:       job.Status,
+		AudioURL:     job.Meta["audio_url"],
+		LanguageCode: job.Meta["language_code"],
+		Multichannel: job.Meta["multichannel"] == "true",
+		Error:        job.Error,
+	}
+	if v := job.Meta["webhook_url"]; v != "" {
+		t.WebhookURL = &v
+	}
+	if job.FileCount > 0 {
+		return assemblyAIArchiveTranscript(t, job)
+	}
+	res := job.Result
+	if job.Status != jobs.StatusCompleted || res == nil {
+		return t
+	}
+
+	text := res.Text
+	duration := res.Duration
+	var confidence float64
+	t.Text = &text
+	t.AudioDuration = &duration
+	t.Words = make([]assemblyAIWord, 0, len(res.Words))
+	for _, w := range res.Words {
+		word := assemblyAIWord{
+			Text:       w.Text,
+			Start:      int64(math.Round(w.Start * 1000)),
+			End:        int64(math.Round(w.End * 1000)),
+			Confidence: w.Confidence,
+		}
+		if t.Multichannel {
+			word.Channel = strconv.Itoa(w.Channel + 1)
+		}
+		t.Words = append(t.Words, word)
+		confidence += w.Confidence
+	}
+	if len(res.Words) > 0 {
+		confidence /= float64(len(res.Words))
+	}
+	t.Confidence = &confidence
+	if t.Multichannel {
+		t.AudioChannels = res.Channels
+	}
+	return t
+}
+
+// assemblyAIArchiveTranscript fills the transcript t of an archive job: the
+// files done so far and, once completed, the text and duration of them all.
+func assemblyAIArchiveTranscript(t assemblyAITranscript, job jobs.Job) assemblyAITranscript {
+	t.FilesDone, t.FilesTotal = len(job.Files), job.FileCount
+	t.Files = make([]assemblyAIFile, 0, len(job.Files))
+	var lines []string
+	var duration float64
+	for _, f := range job.Files {
+		file := assemblyAIFile{Filename: f.Name, Error: f.Error}
+		if f.Result != nil {
+			text, d := f.Result.Text, f.Result.Duration
+			file.Text, file.AudioDuration = &text, &d
+			lines = append(lines, text)
+			duration += d
+		}
+		t.Files = append(t.Files, file)
+	}
+	if job.Status == jobs.StatusCompleted {
+		text := strings.Join(lines, "\n")
+		t.Text, t.AudioDuration = &text, &duration
+		t.Words = []assemblyAIWord{}
+	}
+	return t
+}
+
+// transcribeJob runs one async job through the cache like any request.
+func (s *Server) transcribeJob(ctx context.Context, audio []byte, opts asr.TranscribeOptions) (*asr.Result, error) {
+	res, _, err := s.transcribe(ctx, audio, opts)
+	return res, err
+}
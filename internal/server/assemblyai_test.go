@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/jobs"
+)
+
+// newAssemblyAITestServer returns a server with the AssemblyAI routes and a
+// memory cache, so jobs for pre-cached audio complete without a model.
+func newAssemblyAITestServer(t *testing.T) *Server {
+	t.Helper()
+	s := &Server{
+		mux:      http.NewServeMux(),
+		cache:    newMemoryCache(10),
+		stats:    newServerStats(),
+		inflight: newInflightGroup(),
+		uploads:  newUploadStore(),
+		tus:      newTusUploads(),
+		apiKey:   "secret",
+	}
+	s.jobs = jobs.NewQueue(jobs.Config{Workers: 1, Retention: assemblyAIRetention, Done: s.assemblyAIJobDone}, s.transcribeJob)
+	t.Cleanup(s.jobs.Close)
+	s.setupRoutes()
+	return s
+}
+
+func assemblyAIRequest(s *Server, method, target string, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+	r.Header.Set("Authorization", "secret")
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestAssemblyAI_UploadAndPoll(t *testing.T) {
+	s := newAssemblyAITestServer(t)
+	audio := []byte("RIFF-not-really-audio")
+	s.cache.Put(s.cacheKey(audio, asr.TranscribeOptions{Language: "en", Channels: asr.ChannelMix}), &asr.Result{
+		Text:     "Hello, world.",
+		Duration: 1.5,
+		Channels: 1,
+		Words: []asr.Word{
+			{Start: 0.08, End: 0.4, Text: "Hello,", Confidence: 0.9},
+			{Start: 0.48, End: 0.96, Text: "world.", Confidence: 0.7},
+		},
+		Segments: []asr.Segment{{Start: 0, End: 1.5, Text: "Hello, world."}},
+	})
+
+	rec := assemblyAIRequest(s, "POST", "/v2/upload", audio)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", rec.Code, rec.Body)
+	}
+	var up assemblyAIUploadResponse
+	json.Unmarshal(rec.Body.Bytes(), &up)
+	if !strings.HasPrefix(up.UploadURL, "http://example.com/v2/upload/") {
+		t.Fatalf("upload_url = %q", up.UploadURL)
+	}
+
+	body, _ := json.Marshal(assemblyAITranscriptRequest{AudioURL: up.UploadURL})
+	rec = assemblyAIRequest(s, "POST", "/v2/transcript", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("submit: %d %s", rec.Code, rec.Body)
+	}
+	var submitted assemblyAITranscript
+	json.Unmarshal(rec.Body.Bytes(), &submitted)
+	if submitted.ID == "" || submitted.Status != jobs.StatusQueued || submitted.Text != nil {
+		t.Fatalf("submitted = %+v", submitted)
+	}
+
+	var got assemblyAITranscript
+	deadline := time.Now().Add(5 * time.Second)
+	for got.Status != jobs.StatusCompleted && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+submitted.ID, nil)
+		json.Unmarshal(rec.Body.Bytes(), &got)
+	}
+	if got.Status != jobs.StatusCompleted || got.Text == nil || *got.Text != "Hello, world." {
+		t.Fatalf("transcript = %+v", got)
+	}
+	if len(got.Words) != 2 || got.Words[1] != (assemblyAIWord{Text: "world.", Start: 480, End: 960, Confidence: 0.7}) {
+		t.Errorf("words = %+v", got.Words)
+	}
+	if *got.Confidence != 0.8 || *got.AudioDuration != 1.5 || got.LanguageCode != "en" {
+		t.Errorf("confidence %v, duration %v, language %q", *got.Confidence, *got.AudioDuration, got.LanguageCode)
+	}
+
+	rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+submitted.ID+"/srt", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "00:00:00,000 --> 00:00:01,500") {
+		t.Errorf("srt: %d %q", rec.Code, rec.Body)
+	}
+
+	rec = assemblyAIRequest(s, "GET", "/v2/transcript?limit=5", nil)
+	var list assemblyAIList
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if len(list.Transcripts) != 1 || list.Transcripts[0].ResourceURL != "http://example.com/v2/transcript/"+submitted.ID {
+		t.Errorf("list = %+v", list)
+	}
+
+	if rec = assemblyAIRequest(s, "DELETE", "/v2/transcript/"+submitted.ID, nil); rec.Code != http.StatusOK {
+		t.Errorf("delete: %d", rec.Code)
+	}
+	if rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+submitted.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete: %d, want 404", rec.Code)
+	}
+}
+
+func TestAssemblyAI_OwnKeyOnly(t *testing.T) {
+	s := newAssemblyAITestServer(t)
+	s.apiKeys = map[string]string{"sk-search": "search", "sk-support": "support"}
+	call := func(key, method, target string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		r.Header.Set("Authorization", key)
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, r)
+		return rec
+	}
+	audio := []byte("RIFF-tenant-audio")
+	s.cache.Put(s.cacheKey(audio, asr.TranscribeOptions{Language: "en", Channels: asr.ChannelMix}), &asr.Result{Text: "Mine.", Duration: 1, Channels: 1})
+	var up assemblyAIUploadResponse
+	json.Unmarshal(call("sk-search", "POST", "/v2/upload", audio).Body.Bytes(), &up)
+	body, _ := json.Marshal(assemblyAITranscriptRequest{AudioURL: up.UploadURL})
+	if rec := call("sk-support", "POST", "/v2/transcript", body); rec.Code != http.StatusNotFound {
+		t.Errorf("other key submits the upload: %d, want 404", rec.Code)
+	}
+	var job assemblyAITranscript
+	json.Unmarshal(call("sk-search", "POST", "/v2/transcript", body).Body.Bytes(), &job)
+	if job.ID == "" {
+		t.Fatal("submit failed")
+	}
+
+	for _, target := range []string{"/v2/transcript/" + job.ID, "/v2/transcript/" + job.ID + "/srt", "/v2/transcript/" + job.ID + "/archive"} {
+		if rec := call("sk-support", "GET", target, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("other key GET %s: %d, want 404", target, rec.Code)
+		}
+	}
+	if rec := call("sk-support", "DELETE", "/v2/transcript/"+job.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("other key DELETE: %d, want 404", rec.Code)
+	}
+	var list assemblyAIList
+	json.Unmarshal(call("sk-support", "GET", "/v2/transcript", nil).Body.Bytes(), &list)
+	if len(list.Transcripts) != 0 {
+		t.Errorf("other key lists %+v", list.Transcripts)
+	}
+	json.Unmarshal(call("sk-search", "GET", "/v2/transcript", nil).Body.Bytes(), &list)
+	if len(list.Transcripts) != 1 {
+		t.Errorf("owner lists %+v", list.Transcripts)
+	}
+	if rec := call("sk-search", "GET", "/v2/transcript/"+job.ID, nil); rec.Code != http.StatusOK {
+		t.Errorf("owner GET: %d, want 200", rec.Code)
+	}
+}
+
+func TestAssemblyAI_Archive(t *testing.T) {
+	s := newAssemblyAITestServer(t)
+	for name, text := range map[string]string{"one": "Hello.", "two": "Bye."} {
+		s.cache.Put(s.cacheKey([]byte(name), asr.TranscribeOptions{Format: ".wav", Language: "en", Channels: asr.ChannelMix}),
+			&asr.Result{Text: text, Duration: 1, Channels: 1, Segments: []asr.Segment{{Start: 0, End: 1, Text: text}}})
+	}
+	var tarball bytes.Buffer
+	gz := gzip.NewWriter(&tarball)
+	tw := tar.NewWriter(gz)
+	for _, f := range [][2]string{{"calls/one.wav", "one"}, {"calls/two.wav", "two"}} {
+		tw.WriteHeader(&tar.Header{Name: f[0], Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(f[1]))})
+		tw.Write([]byte(f[1]))
+	}
+	tw.Close()
+	gz.Close()
+
+	rec := assemblyAIRequest(s, "POST", "/v2/upload", tarball.Bytes())
+	var up assemblyAIUploadResponse
+	json.Unmarshal(rec.Body.Bytes(), &up)
+	body, _ := json.Marshal(assemblyAITranscriptRequest{AudioURL: up.UploadURL})
+	rec = assemblyAIRequest(s, "POST", "/v2/transcript", body)
+	var submitted assemblyAITranscript
+	json.Unmarshal(rec.Body.Bytes(), &submitted)
+
+	var got assemblyAITranscript
+	deadline := time.Now().Add(5 * time.Second)
+	for got.Status != jobs.StatusCompleted && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+submitted.ID, nil)
+		json.Unmarshal(rec.Body.Bytes(), &got)
+	}
+	if got.Status != jobs.StatusCompleted || got.Text == nil || *got.Text != "Hello.\nBye." || *got.AudioDuration != 2 {
+		t.Fatalf("transcript = %+v", got)
+	}
+	if got.FilesDone != 2 || got.FilesTotal != 2 || len(got.Files) != 2 || got.Files[1].Filename != "calls/two.wav" || *got.Files[1].Text != "Bye." {
+		t.Errorf("files = %+v", got)
+	}
+
+	if rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+submitted.ID+"/srt", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("srt of an archive: %d, want 400", rec.Code)
+	}
+	rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+submitted.ID+"/archive?format=text", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("archive: %d %s", rec.Code, rec.Body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if want := []string{"calls/one.txt", "calls/two.txt"}; !slices.Equal(names, want) {
+		t.Errorf("archive files = %v, want %v", names, want)
+	}
+	if rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+submitted.ID+"/archive?format=mp3", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("archive in an unknown format: %d, want 400", rec.Code)
+	}
+}
+
+func TestAssemblyAI_SubmitValidation(t *testing.T) {
+	s := newAssemblyAITestServer(t)
+	for _, tc := range []struct {
+		name string
+		body string
+	}{
+		{"missing audio_url", `{}`},
+		{"not http", `{"audio_url": "file:///etc/passwd"}`},
+		{"remote url disabled", `{"audio_url": "https://example.org/a.mp3"}`},
+		{"webhook disabled", `{"audio_url": "https://example.org/a.mp3", "webhook_url": "https://example.org/hook"}`},
+		{"bad json", `{`},
+	} {
+		rec := assemblyAIRequest(s, "POST", "/v2/transcript", []byte(tc.body))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", tc.name, rec.Code)
+		}
+	}
+
+	// An upload_url of this server that the key does not own is not found.
+	if rec := assemblyAIRequest(s, "POST", "/v2/transcript", []byte(`{"audio_url": "http://example.com/v2/upload/nope"}`)); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown upload: %d, want 404", rec.Code)
+	}
+
+	r := httptest.NewRequest("GET", "/v2/transcript", nil)
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no key: %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// auditDay names an audit file: audit-2006-01-02.jsonl, one per UTC day.
+const auditDay = "2006-01-02"
+
+// auditEntry is one audit record: a request to an endpoint that takes
+// audio, who sent it and what it transcribed.
+type auditEntry struct {
+	Time      time.Time   `json:"time"`
+	RequestID string      `json:"request_id,omitempty"`
+	Key       string      `json:"key,omitempty"` // name of the API key; empty without authentication
+	Remote    string      `json:"remote"`
+	Method    string      `json:"method"`
+	Path      string      `json:"path"`
+	Status    int         `json:"status"`
+	Files     []auditFile `json:"files,omitempty"`
+}
+
+// auditFile is one transcription of an audited request; a batch has one
+// per file.
+type auditFile struct {
+	Filename    string  `json:"filename,omitempty"`
+	AudioSHA256 string  `json:"audio_sha256"`
+	AudioBytes  int     `json:"audio_bytes"`
+	Duration    float64 `json:"duration"` // seconds of audio
+	// ResultSHA256 is the hash of the transcript text as decoded, before
+	// any post-processing.
+	ResultSHA256 string `json:"result_sha256"`
+	Cached       bool   `json:"cached"`
+}
+
+// auditLog appends audit entries to one JSON Lines file per UTC day in
+// dir, and removes the files of the days past retention (zero keeps them)
+// as it moves on to the next one.
+type auditLog struct {
+	dir       string
+	retention time.Duration
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+func newAuditLog(dir string, retention time.Duration) (*auditLog, error) {
+	if retention < 0 {
+		return nil, fmt.Errorf("-audit-retention must not be negative, got %s", retention)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	a := &auditLog{dir: dir, retention: retention}
+	a.prune(time.Now())
+	return a, nil
+}
+
+func (a *auditLog) path(day string) string {
+	return filepath.Join(a.dir, "audit-"+day+".jsonl")
+}
+
+// log appends e to the file of its day. Failures are logged, never
+// returned: they must not fail the request e describes, but they are
+// errors, as the audit trail now has a gap.
+func (a *auditLog) log(e auditEntry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	day := e.Time.UTC().Format(auditDay)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil || day != a.day {
+		if a.file != nil {
+			a.file.Close()
+			a.file = nil
+		}
+		f, err := os.OpenFile(a.path(day), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+		if err != nil {
+			slog.Error("audit log write failed", "error", err, "request_id", e.RequestID)
+			return
+		}
+		a.file, a.day = f, day
+		go a.prune(e.Time)
+	}
+	if _, err := a.file.Write(line); err != nil {
+		slog.Error("audit log write failed", "error", err, "request_id", e.RequestID)
+	}
+}
+
+// days returns the days that have an audit file, oldest first.
+func (a *auditLog) days() ([]string, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(e.Name(), "audit-"), ".jsonl")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(auditDay, name); err == nil {
+			days = append(days, name)
+		}
+	}
+	return days, nil // ReadDir sorts by name, which is by day
+}
+
+// prune removes the files of the days that ended more than retention
+// before now.
+func (a *auditLog) prune(now time.Time) {
+	if a.retention == 0 {
+		return
+	}
+	days, err := a.days()
+	if err != nil {
+		slog.Warn("audit log prune failed", "error", err)
+		return
+	}
+	cutoff := now.Add(-a.retention).UTC()
+	for _, day := range days {
+		start, _ := time.Parse(auditDay, day)
+		if !start.AddDate(0, 0, 1).Before(cutoff) {
+			break
+		}
+		if err := os.Remove(a.path(day)); err == nil {
+			slog.Info("pruned audit log", "day", day)
+		}
+	}
+}
+
+// export writes the entries from since to until (inclusive, zero for no
+// bound), of key when it is not empty, to w as JSON Lines.
+func (a *auditLog) export(w *bufio.Writer, since, until time.Time, key string) error {
+	days, err := a.days()
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		start, _ := time.Parse(auditDay, day)
+		if !since.IsZero() && start.AddDate(0, 0, 1).Before(since) || !until.IsZero() && start.After(until) {
+			continue
+		}
+		if err := a.exportFile(w, a.path(day), since, until, key); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (a *auditLog) exportFile(w *bufio.Writer, path string, since, until time.Time, key string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil // pruned meanwhile
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		var e struct {
+			Time time.Time `json:"time"`
+			Key  string    `json:"key"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue // a line cut short by a crash
+		}
+		if !since.IsZero() && e.Time.Before(since) || !until.IsZero() && e.Time.After(until) || key != "" && e.Key != key {
+			continue
+		}
+		w.Write(sc.Bytes())
+		w.WriteByte('\n')
+	}
+	return sc.Err()
+}
+
+// Close closes the file being written.
+func (a *auditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// noteUpload records the file name of the audio a request uploaded, for
+// the audit log.
+func noteUpload(ctx context.Context, filename string) {
+	if m := metricsFrom(ctx); m != nil {
+		m.filename = filename
+	}
+}
+
+// noteTranscript records a finished transcription of the request in its
+// audit record.
+func noteTranscript(ctx context.Context, audioSHA256 string, audioBytes int, res *asr.Result, cached bool) {
+	m := metricsFrom(ctx)
+	if m == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(res.Text))
+	m.transcripts = append(m.transcripts, auditFile{
+		Filename:     m.filename,
+		AudioSHA256:  audioSHA256,
+		AudioBytes:   audioBytes,
+		Duration:     res.Duration,
+		ResultSHA256: hex.EncodeToString(sum[:]),
+		Cached:       cached,
+	})
+}
+
+// auditRequests writes an audit entry for every request once it is
+// answered; without -audit-dir it passes requests through. It goes after
+// the auth middleware, for the key name.
+func (s *Server) auditRequests(next http.Handler) http.Handler {
+	if s.audit == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		remote, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remote = r.RemoteAddr
+		}
+		e := auditEntry{
+			Time:      start.UTC(),
+			RequestID: requestIDFrom(r.Context()),
+			Key:       apiKeyNameFrom(r.Context()),
+			Remote:    remote,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    rec.status,
+		}
+		if m := metricsFrom(r.Context()); m != nil {
+			e.Files = m.transcripts
+		}
+		s.audit.log(e)
+	})
+}
+
+// handleAudit serves GET /admin/audit: the audit entries as JSON Lines,
+// oldest first, filtered by since, until (RFC 3339 times or dates) and
+// key.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	since, err := parseAuditTime(q.Get("since"), false)
+	if err != nil {
+		sendRequestError(w, withParam("since", err))
+		return
+	}
+	until, err := parseAuditTime(q.Get("until"), true)
+	if err != nil {
+		sendRequestError(w, withParam("until", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if err := s.audit.export(bufio.NewWriter(w), since, until, q.Get("key")); err != nil {
+		slog.ErrorContext(r.Context(), "audit export failed", "error", err)
+	}
+}
+
+// parseAuditTime reads an RFC 3339 time or a date, which stands for the
+// start of its UTC day, or with end for its last instant. Empty is zero.
+func parseAuditTime(v string, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(auditDay, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339 (2026-01-02T15:04:05Z) or a date (2026-01-02)", v)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestAuditRequests(t *testing.T) {
+	audit, err := newAuditLog(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+	s := &Server{audit: audit}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noteUpload(r.Context(), "call.wav")
+		s.recordTranscript(r.Context(), []byte("audio"), asr.TranscribeOptions{}, &asr.Result{Text: "hello", Duration: 2}, false, time.Second)
+	})
+	r := httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)
+	r.RemoteAddr = "192.0.2.7:5000"
+	r = r.WithContext(withAPIKeyName(r.Context(), "office"))
+	chain(handler, timeResponses, s.auditRequests).ServeHTTP(httptest.NewRecorder(), r)
+
+	data, err := os.ReadFile(audit.path(time.Now().UTC().Format(auditDay)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e auditEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Key != "office" || e.Remote != "192.0.2.7" || e.Path != "/v1/audio/transcriptions" || e.Status != 200 || len(e.Files) != 1 {
+		t.Fatalf("entry = %+v", e)
+	}
+	f := e.Files[0]
+	if f.Filename != "call.wav" || f.AudioBytes != 5 || f.Duration != 2 || f.Cached ||
+		f.AudioSHA256 != "6ed8919ce20490a5e3ad8630a4fab69475297abd07db73918dd5f36fcfaeb11b" ||
+		f.ResultSHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("file = %+v", f)
+	}
+}
+
+func TestAuditExport(t *testing.T) {
+	dir := t.TempDir()
+	audit, err := newAuditLog(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day1 := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	audit.log(auditEntry{Time: day1, Key: "a", RequestID: "1"})
+	audit.log(auditEntry{Time: day2, Key: "b", RequestID: "2"})
+	audit.log(auditEntry{Time: day2.Add(time.Minute), Key: "a", RequestID: "3"})
+	audit.Close()
+
+	export := func(since, until, key string) string {
+		t.Helper()
+		from, err := parseAuditTime(since, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		to, err := parseAuditTime(until, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := audit.export(bufio.NewWriter(&buf), from, to, key); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var e auditEntry
+			if line != "" && json.Unmarshal([]byte(line), &e) == nil {
+				ids = append(ids, e.RequestID)
+			}
+		}
+		return strings.Join(ids, ",")
+	}
+	tests := []struct{ since, until, key, want string }{
+		{"", "", "", "1,2,3"},
+		{"", "", "a", "1,3"},
+		{"2026-03-02", "", "", "2,3"},
+		{"", "2026-03-01", "", "1"},
+		{"2026-03-02T01:00:30Z", "", "", "3"},
+	}
+	for _, tt := range tests {
+		if got := export(tt.since, tt.until, tt.key); got != tt.want {
+			t.Errorf("export(%q, %q, %q) = %s, want %s", tt.since, tt.until, tt.key, got, tt.want)
+		}
+	}
+	if _, err := parseAuditTime("yesterday", false); err == nil {
+		t.Error("parseAuditTime accepted yesterday")
+	}
+}
+
+func TestAuditRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	old := filepath.Join(dir, "audit-"+now.AddDate(0, 0, -10).Format(auditDay)+".jsonl")
+	recent := filepath.Join(dir, "audit-"+now.AddDate(0, 0, -2).Format(auditDay)+".jsonl")
+	for _, path := range []string{old, recent} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := newAuditLog(dir, 7*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("file past the retention kept: %v", err)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("file within the retention removed: %v", err)
+	}
+	if _, err := newAuditLog(dir, -time.Hour); err == nil {
+		t.Error("negative retention accepted")
+	}
+}
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/archive"
+)
+
+// Limits of /v1/audio/transcriptions/batch. The whole body may be larger
+// than one upload, but every file in it, archived or not, is held to
+// maxUploadBytes like a single transcription.
+const (
+	maxBatchUploadBytes = 200 << 20
+	maxBatchFiles       = 1000
+)
+
+// handleBatchTranscription serves /v1/audio/transcriptions/batch: several
+// "file" parts, or zip or tar archives of them, transcribed with the same
+// form parameters as /v1/audio/transcriptions. The answer is one JSON
+// array entry per file, in the order sent, holding the body the single
+// endpoint would have answered with or the error it would have failed
+// with. A file failing does not fail the others.
+func (s *Server) handleBatchTranscription(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchUploadBytes)
+	if err := r.ParseMultipartForm(s.multipartMemory()); err != nil {
+		sendBodyError(w, err)
+		return
+	}
+	parts := r.MultipartForm.File["file"]
+	total, err := countBatchFiles(parts)
+	if err != nil {
+		sendRequestError(w, err)
+		return
+	}
+
+	s.noteModel(r.Context(), r.FormValue("model"))
+	req, err := s.parseTranscriptionRequest(r)
+	if err != nil {
+		sendRequestError(w, err)
+		return
+	}
+	if req.stream {
+		sendRequestError(w, invalidParam("stream", "stream=true is not supported by batch transcription"))
+		return
+	}
+
+	slog.InfoContext(r.Context(), "transcribing batch",
+		"files", total,
+		"language", req.language,
+		"format", req.responseFormat,
+	)
+
+	// Files are read one at a time and decoded as many at a time as there
+	// are workers; the next one waits its turn here rather than in the
+	// decoder queue, so only the files being decoded are held in memory.
+	results := make([]BatchTranscriptionResult, total)
+	audio := make([]requestMetrics, total)
+	slots := make(chan struct{}, max(s.config.Workers, 1))
+	var wg sync.WaitGroup
+	n := 0
+	err = walkBatch(parts, func(name string, size int64, content io.Reader) error {
+		if n == total {
+			return errors.New("archive changed while read")
+		}
+		i := n
+		n++
+		results[i].Filename = name
+		audio[i].filename = name
+		if size > maxUploadBytes {
+			code := "file_too_large"
+			results[i].Error = &ErrorDetail{
+				Message: fmt.Sprintf("Maximum content size limit (%d) exceeded", maxUploadBytes),
+				Type:    "invalid_request_error",
+				Code:    &code,
+			}
+			return nil
+		}
+		data, err := io.ReadAll(io.LimitReader(content, maxUploadBytes))
+		if err != nil {
+			results[i].Error = &ErrorDetail{Message: "Failed to read audio file: " + err.Error(), Type: "invalid_request_error"}
+			return nil
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			// Each file notes its own audio; the request is metered on the
+			// total.
+			ctx := context.WithValue(r.Context(), requestMetricsKey{}, &audio[i])
+			results[i] = s.batchTranscribe(ctx, name, data, req)
+		}()
+		return nil
+	})
+	wg.Wait()
+	if err != nil {
+		sendRequestError(w, withParam("file", err))
+		return
+	}
+
+	var seconds float64
+	parent := metricsFrom(r.Context())
+	for _, m := range audio {
+		seconds += m.audioSeconds
+		if parent != nil {
+			parent.transcripts = append(parent.transcripts, m.transcripts...)
+		}
+	}
+	noteAudio(r.Context(), seconds)
+	writeRendered(w, "application/json", BatchTranscriptionResponse{Results: results})
+}
+
+// batchTranscribe transcribes the audio of one file of a batch for req.
+func (s *Server) batchTranscribe(ctx context.Context, name string, audio []byte, req transcriptionRequest) BatchTranscriptionResult {
+	res := BatchTranscriptionResult{Filename: name}
+	opts := req.opts
+	opts.Format = strings.ToLower(path.Ext(name))
+	result, cached, err := s.transcribe(ctx, audio, opts)
+	if err != nil {
+		_, detail := transcribeError(err)
+		res.Error = &detail
+		return res
+	}
+	ppStart := time.Now()
+	if result, err = s.postprocess(ctx, req.pp, result, req.language); err != nil {
+		res.Error = &ErrorDetail{Message: "Post-processing failed: " + err.Error(), Type: "server_error"}
+		return res
+	}
+	_, res.Result = req.render(result, cached, time.Since(ppStart))
+	return res
+}
+
+// countBatchFiles counts the files of a batch, checking that there are some
+// and not too many: each part, or the files of the parts that are
+// archives.
+func countBatchFiles(parts []*multipart.FileHeader) (int, error) {
+	if len(parts) == 0 {
+		return 0, &paramError{param: "file", code: "missing_required_parameter", err: errors.New("Missing required parameter: 'file'")}
+	}
+	total := 0
+	for _, part := range parts {
+		n, err := countPartFiles(part, maxBatchFiles-total)
+		if errors.Is(err, archive.ErrTooManyFiles) {
+			n = maxBatchFiles + 1
+		} else if err != nil {
+			return 0, withParam("file", fmt.Errorf("%s: %w", part.Filename, err))
+		}
+		if total += n; total > maxBatchFiles {
+			return 0, invalidParam("file", "more than the %d files allowed in a batch", maxBatchFiles)
+		}
+	}
+	return total, nil
+}
+
+// countPartFiles counts the files of one part of a batch: 1, or the files
+// in it if it is an archive, giving up with archive.ErrTooManyFiles past
+// limit.
+func countPartFiles(part *multipart.FileHeader, limit int) (int, error) {
+	f, err := part.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if archive.Detect(f, part.Size) == "" {
+		return 1, nil
+	}
+	return archive.Count(f, part.Size, limit)
+}
+
+// walkBatch calls fn with each file of a batch, in order, like
+// archive.Walk: the parts, and the files of those that are archives.
+func walkBatch(parts []*multipart.FileHeader, fn func(name string, size int64, content io.Reader) error) error {
+	for _, part := range parts {
+		if err := walkPart(part, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkPart(part *multipart.FileHeader, fn func(string, int64, io.Reader) error) error {
+	f, err := part.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", part.Filename, err)
+	}
+	defer f.Close()
+	if archive.Detect(f, part.Size) == "" {
+		return fn(part.Filename, part.Size, f)
+	}
+	return archive.Walk(f, part.Size, fn)
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestHandleBatchTranscription(t *testing.T) {
+	s := &Server{cache: newMemoryCache(8), stats: newServerStats(), inflight: newInflightGroup()}
+	for name, text := range map[string]string{"one": "hello", "two": "world"} {
+		s.cache.Put(s.cacheKey([]byte(name), asr.TranscribeOptions{Format: ".wav", Language: "en", Channels: asr.ChannelMix}),
+			&asr.Result{Text: text, Duration: 1, Channels: 1, Segments: []asr.Segment{{Start: 0.5, End: 1, Text: text}}})
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	zw.Create("voicemail/")
+	fw, _ := zw.Create("voicemail/two.wav")
+	fw.Write([]byte("two"))
+	zw.Close()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ = mw.CreateFormFile("file", "one.wav")
+	fw.Write([]byte("one"))
+	fw, _ = mw.CreateFormFile("file", "clips.zip")
+	fw.Write(archive.Bytes())
+	mw.WriteField("response_format", "verbose_json")
+	mw.WriteField("offset_seconds", "10")
+	mw.Close()
+	r := httptest.NewRequest("POST", "/v1/audio/transcriptions/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s.handleBatchTranscription(rec, r)
+
+	var got struct {
+		Results []struct {
+			Filename string                        `json:"filename"`
+			Result   *VerboseTranscriptionResponse `json:"result"`
+			Error    *ErrorDetail                  `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(got.Results) != 2 {
+		t.Fatalf("status %d, %+v", rec.Code, got)
+	}
+	for i, want := range []struct{ filename, text string }{{"one.wav", "hello"}, {"voicemail/two.wav", "world"}} {
+		res := got.Results[i]
+		if res.Filename != want.filename || res.Error != nil || res.Result == nil || res.Result.Text != want.text {
+			t.Errorf("result %d = %+v; want %s: %q", i, res, want.filename, want.text)
+			continue
+		}
+		if seg := res.Result.Segments[0]; seg.Start != 10.5 || seg.End != 11 {
+			t.Errorf("%s: segment %v-%v, want 10.5-11", want.filename, seg.Start, seg.End)
+		}
+	}
+
+	body.Reset()
+	mw = multipart.NewWriter(&body)
+	mw.WriteField("response_format", "json")
+	mw.Close()
+	r = httptest.NewRequest("POST", "/v1/audio/transcriptions/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	s.handleBatchTranscription(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("no files: status %d, want 400", rec.Code)
+	}
+
+	archive.Reset()
+	zw = zip.NewWriter(&archive)
+	for i := range maxBatchFiles {
+		fw, _ = zw.Create(fmt.Sprintf("clip%d.wav", i))
+		fw.Write([]byte("x"))
+	}
+	zw.Close()
+	body.Reset()
+	mw = multipart.NewWriter(&body)
+	fw, _ = mw.CreateFormFile("file", "one.wav")
+	fw.Write([]byte("one"))
+	fw, _ = mw.CreateFormFile("file", "clips.zip")
+	fw.Write(archive.Bytes())
+	mw.Close()
+	r = httptest.NewRequest("POST", "/v1/audio/transcriptions/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	s.handleBatchTranscription(rec, r)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "more than the 1000 files") {
+		t.Errorf("%d files: status %d, %s; want 400", maxBatchFiles+1, rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,339 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// Cache backends selectable with -cache.
+const (
+	cacheOff    = "off"
+	cacheMemory = "memory"
+	cacheDisk   = "disk"
+)
+
+// resultCache stores finished transcriptions by request key. Implementations
+// are safe for concurrent use and bounded to a fixed number of entries,
+// evicting the least recently used one first.
+type resultCache interface {
+	Get(key string) (*asr.Result, bool)
+	Put(key string, res *asr.Result)
+}
+
+// newResultCache builds the backend named by kind. "off" (or empty) returns
+// nil, which callers treat as "no caching".
+func newResultCache(kind string, size int, dir string) (resultCache, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", cacheOff:
+		return nil, nil
+	case cacheMemory:
+		if size < 1 {
+			return nil, fmt.Errorf("-cache-size must be at least 1, got %d", size)
+		}
+		return newMemoryCache(size), nil
+	case cacheDisk:
+		if size < 1 {
+			return nil, fmt.Errorf("-cache-size must be at least 1, got %d", size)
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("-cache=disk requires -cache-dir")
+		}
+		return newDiskCache(dir, size)
+	default:
+		return nil, fmt.Errorf("unsupported cache %q (supported: off, memory, disk)", kind)
+	}
+}
+
+// cacheKey identifies a request by the SHA-256 of its audio bytes and every
+// parameter that changes the transcript. salt carries the server-side
+// settings that do the same (models directory, resampler), so a disk cache
+// reused after a reconfiguration does not serve stale transcripts. The
+// client's file extension is deliberately left out: format detection is by
+// content, so the same bytes uploaded as .wav and .WAV share an entry.
+func cacheKey(salt string, audio []byte, opts asr.TranscribeOptions) string {
+	h := sha256.New()
+	h.Write(audio)
+	c, d := opts.Conditioning, opts.Decoding
+	fmt.Fprintf(h, "\x00salt=%q lang=%q channels=%q denoise=%t dc=%t gain=%q trim=%t",
+		salt, opts.Language, opts.Channels, opts.Denoise, c.RemoveDC, c.Gain, c.TrimSilence)
+	if d != (asr.DecodingOptions{}) {
+		// Only non-default decoding extends the key, so existing disk cache
+		// entries stay valid.
+		fmt.Fprintf(h, " maxsym=%d blank=%g beam=%d", d.MaxTokensPerStep, d.BlankPenalty, d.BeamSize)
+	}
+	if seg := opts.Segmentation; seg != (asr.Segmentation{}) {
+		fmt.Fprintf(h, " pause=%g sentences=%t maxseg=%g", seg.Pause, seg.Sentences, seg.MaxDuration)
+	}
+	if opts.NoSpeechThreshold > 0 {
+		fmt.Fprintf(h, " nospeech=%g", opts.NoSpeechThreshold)
+	}
+	if c := opts.Confidence; c.Min > 0 {
+		fmt.Fprintf(h, " confidence=%g placeholder=%q", c.Min, c.Placeholder)
+	}
+	if f := opts.Formatting; f.Enabled() {
+		fmt.Fprintf(h, " formatting=%s", f)
+	}
+	if g := opts.Guard; g.Mode != "" && g.Mode != asr.GuardOff {
+		fmt.Fprintf(h, " guard=%s voiced=%g rate=%g repeats=%d", g.Mode, g.MinVoicedRatio, g.MaxTokensPerSecond, g.MaxRepeats)
+	}
+	if f := opts.Fallback; f.CompressionRatio > 0 || f.Logprob < 0 {
+		fmt.Fprintf(h, " fallback=%g,%g,%g", f.CompressionRatio, f.Logprob, f.TemperatureStep)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// memoryCache is an in-process LRU.
+type memoryCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front = most recently used; values are *memoryEntry
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key string
+	res *asr.Result
+}
+
+func newMemoryCache(size int) *memoryCache {
+	return &memoryCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *memoryCache) Get(key string) (*asr.Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*memoryEntry).res, true
+}
+
+func (c *memoryCache) Put(key string, res *asr.Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*memoryEntry).res = res
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, res: res})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// diskCache keeps one JSON file per entry in dir, so results survive
+// restarts. Recency is the file's modification time, refreshed on every hit;
+// eviction removes the oldest files once more than size are present.
+type diskCache struct {
+	mu   sync.Mutex
+	dir  string
+	size int
+}
+
+func newDiskCache(dir string, size int) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	return &diskCache{dir: dir, size: size}, nil
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *diskCache) Get(key string) (*asr.Result, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var res asr.Result
+	if err := json.Unmarshal(data, &res); err != nil {
+		slog.Warn("discarding unreadable cache entry", "key", key, "error", err)
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+	return &res, true
+}
+
+func (c *diskCache) Put(key string, res *asr.Result) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	// Write to a unique temp file and rename so concurrent readers never see
+	// a partial entry.
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		slog.Warn("cache write failed", "error", err)
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		slog.Warn("cache write failed", "error", errors.Join(werr, cerr))
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		slog.Warn("cache write failed", "error", err)
+		return
+	}
+	c.evict()
+}
+
+// evict removes the least recently used entries beyond the size limit.
+func (c *diskCache) evict() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		name string
+		mod  time.Time
+	}
+	var files []file
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{e.Name(), info.ModTime()})
+	}
+	if len(files) <= c.size {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+	for _, f := range files[:len(files)-c.size] {
+		os.Remove(filepath.Join(c.dir, f.name))
+	}
+}
+
+// inflightKey is the in-flight key of a request whose cache key is key.
+// Requests share a decode only when they also have the same
+// max_processing_ms, since a truncated transcript must not reach a request
+// with another limit, and the same priority: an interactive request that
+// joined a batch decode would wait in the batch queue with it.
+func inflightKey(key string, opts asr.TranscribeOptions) string {
+	if p := opts.Priority; p != "" && p != asr.PriorityNormal {
+		key += "|prio=" + string(p)
+	}
+	if opts.MaxProcessing > 0 {
+		key += "|max=" + opts.MaxProcessing.String()
+	}
+	return key
+}
+
+// transcribe runs one buffered request through the result cache and the
+// in-flight deduplication: a cached result is returned immediately, and
+// identical requests that arrive while one is decoding wait for it instead
+// of decoding again. The second return value reports a cache hit.
+func (s *Server) transcribe(ctx context.Context, audio []byte, opts asr.TranscribeOptions) (*asr.Result, bool, error) {
+	requested := time.Now()
+	if opts.Decoding.Temperature > 0 {
+		// Sampled transcripts differ run to run: each request gets its own,
+		// neither cached nor shared.
+		res, err := s.transcribeAudio(ctx, audio, opts, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		s.stats.decode(res.Duration, time.Since(requested))
+		noteAudio(ctx, res.Duration)
+		s.recordTranscript(ctx, audio, opts, res, false, time.Since(requested))
+		return res, false, nil
+	}
+	key := s.cacheKey(audio, opts)
+	if s.cache != nil {
+		if res, ok := s.cache.Get(key); ok {
+			s.stats.cacheHit()
+			noteAudio(ctx, res.Duration)
+			s.recordTranscript(ctx, audio, opts, res, true, time.Since(requested))
+			return res, true, nil
+		}
+	}
+	res, shared, err := s.inflight.do(ctx, inflightKey(key, opts), func(ctx context.Context) (*asr.Result, error) {
+		start := time.Now()
+		res, err := s.transcribeAudio(ctx, audio, opts, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.stats.decode(res.Duration, time.Since(start))
+		if s.cache != nil && !res.Truncated && !s.canary.decoded(res) {
+			s.cache.Put(key, res)
+		}
+		return res, nil
+	})
+	if shared && err == nil {
+		s.stats.sharedInflight()
+		slog.DebugContext(ctx, "joined in-flight transcription", "key", key[:12])
+	}
+	if err == nil {
+		noteAudio(ctx, res.Duration)
+		s.recordTranscript(ctx, audio, opts, res, false, time.Since(requested))
+	}
+	return res, false, err
+}
+
+// cacheKey is cacheKey salted with this server's configuration and the
+// loaded model files, so results of replaced models are not served after a
+// reload. Text profiles change the text, so their file is in it too.
+func (s *Server) cacheKey(audio []byte, opts asr.TranscribeOptions) string {
+	salt := s.config.ResampleQuality
+	if s.config.MaxTokensPerStep > 0 {
+		salt += fmt.Sprintf("|maxsym=%d", s.config.MaxTokensPerStep)
+	}
+	if m := s.models.Load(); m != nil {
+		salt = m.fingerprint + "|" + salt
+	}
+	if s.profiles != nil {
+		salt += "|" + s.profiles.digest
+	}
+	return cacheKey(salt, audio, opts)
+}
+
+// setCacheHeader tells the client whether a response was served from the
+// cache. Nothing is sent when caching is off.
+func (s *Server) setCacheHeader(w http.ResponseWriter, hit bool) {
+	if s.cache == nil {
+		return
+	}
+	if hit {
+		w.Header().Set("X-Cache", "hit")
+	} else {
+		w.Header().Set("X-Cache", "miss")
+	}
+}
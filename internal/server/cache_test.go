@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestCacheKey(t *testing.T) {
+	audio := []byte("RIFF....WAVE")
+	base := asr.TranscribeOptions{Format: ".wav", Language: "en"}
+	key := cacheKey("models", audio, base)
+
+	if got := cacheKey("models", audio, asr.TranscribeOptions{Format: ".WAV", Language: "en"}); got != key {
+		t.Error("file extension must not change the key")
+	}
+	variants := map[string]string{
+		"audio":    cacheKey("models", []byte("RIFF....WAVF"), base),
+		"salt":     cacheKey("other", audio, base),
+		"channel":  cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Channels: asr.ChannelLeft}),
+		"denoise":  cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Denoise: true}),
+		"gain":     cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Conditioning: asr.Conditioning{Gain: asr.GainPeak}}),
+		"beam":     cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Decoding: asr.DecodingOptions{BeamSize: 4}}),
+		"blank":    cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Decoding: asr.DecodingOptions{BlankPenalty: 1.5}}),
+		"nospeech": cacheKey("models", audio, asr.TranscribeOptions{Language: "en", NoSpeechThreshold: 0.6}),
+		"fallback": cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Fallback: asr.Fallback{CompressionRatio: 2.4}}),
+		"guard":    cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Guard: asr.HallucinationGuard{Mode: asr.GuardFlag}}),
+		"segment":  cacheKey("models", audio, asr.TranscribeOptions{Language: "en", Segmentation: asr.Segmentation{Sentences: true}}),
+	}
+	for name, v := range variants {
+		if v == key {
+			t.Errorf("changing %s did not change the key", name)
+		}
+	}
+}
+
+func TestInflightKey(t *testing.T) {
+	if got := inflightKey("k", asr.TranscribeOptions{Priority: asr.PriorityNormal}); got != inflightKey("k", asr.TranscribeOptions{}) {
+		t.Errorf("normal and default priority differ: %q", got)
+	}
+	keys := map[string]bool{}
+	for _, opts := range []asr.TranscribeOptions{
+		{},
+		{Priority: asr.PriorityInteractive},
+		{Priority: asr.PriorityBatch},
+		{MaxProcessing: time.Second},
+		{Priority: asr.PriorityBatch, MaxProcessing: time.Second},
+	} {
+		key := inflightKey("k", opts)
+		if keys[key] {
+			t.Errorf("%+v shares the key %q", opts, key)
+		}
+		keys[key] = true
+	}
+}
+
+func TestNewResultCache(t *testing.T) {
+	if c, err := newResultCache("off", 10, ""); c != nil || err != nil {
+		t.Fatalf("off: got %v, %v", c, err)
+	}
+	for _, tc := range []struct{ kind, dir string }{
+		{"redis", ""},
+		{"disk", ""},
+	} {
+		if _, err := newResultCache(tc.kind, 10, tc.dir); err == nil {
+			t.Errorf("newResultCache(%q, dir=%q): expected an error", tc.kind, tc.dir)
+		}
+	}
+	if _, err := newResultCache("memory", 0, ""); err == nil {
+		t.Error("expected an error for size 0")
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newMemoryCache(2)
+	c.Put("a", &asr.Result{Text: "a"})
+	c.Put("b", &asr.Result{Text: "b"})
+	c.Get("a") // b is now the least recently used
+	c.Put("c", &asr.Result{Text: "c"})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if res, ok := c.Get(k); !ok || res.Text != k {
+			t.Errorf("Get(%q) = %v, %v", k, res, ok)
+		}
+	}
+}
+
+func TestDiskCache_RoundTripAndEviction(t *testing.T) {
+	dir := t.TempDir()
+	c, err := newDiskCache(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &asr.Result{Text: "hello", Duration: 1.5, Channels: 1, Segments: []asr.Segment{{End: 1.5, Text: "hello"}}}
+	c.Put("a", want)
+	got, ok := c.Get("a")
+	if !ok || got.Text != want.Text || got.Duration != want.Duration || len(got.Segments) != 1 {
+		t.Fatalf("Get(a) = %+v, %v", got, ok)
+	}
+
+	// Age "a" so it is the eviction candidate, then overflow the cache.
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "a.json"), old, old)
+	c.Put("b", &asr.Result{Text: "b"})
+	c.Put("c", &asr.Result{Text: "c"})
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have been evicted")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 2 {
+		t.Errorf("cache dir holds %d files, want 2: %v", len(files), files)
+	}
+
+	// A corrupt entry is a miss and is removed.
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{"), 0o644)
+	if _, ok := c.Get("bad"); ok {
+		t.Error("corrupt entry returned as a hit")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.json")); !os.IsNotExist(err) {
+		t.Error("corrupt entry was not removed")
+	}
+}
@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"parakeet/internal/asr"
+)
+
+// canaryModels is the -canary-models-dir model: a second model that decodes
+// a share of the transcriptions, so a new version can be compared with the
+// loaded one on live traffic before it replaces it. It is nil when off.
+//
+// The canary stays loaded for the life of the server: reloads and idle
+// unloading only concern the loaded model.
+type canaryModels struct {
+	models *loadedModels
+	weight float64
+}
+
+// checkCanaryWeight accepts a share of the traffic strictly between 0 and 1.
+func checkCanaryWeight(w float64) error {
+	if w <= 0 || w >= 1 {
+		return fmt.Errorf("%v is not between 0 and 1", w)
+	}
+	return nil
+}
+
+// check verifies that the canary is told apart from the loaded model by
+// name, which is what the per-model stats and Result.Model go by.
+func (c *canaryModels) check(loaded string) error {
+	if c == nil {
+		return nil
+	}
+	if name := c.name(); name == loaded {
+		return fmt.Errorf("the canary model is named %q like the loaded one; give it another config.json name", name)
+	}
+	return nil
+}
+
+// pick draws whether one transcription goes to the canary.
+func (c *canaryModels) pick() bool {
+	return c != nil && rand.Float64() < c.weight
+}
+
+// canaryTranscriber returns the canary's Transcriber and a no-op done func
+// when pick chooses it for one decode, else nil.
+func (s *Server) canaryTranscriber() (*asr.Transcriber, func()) {
+	if !s.canary.pick() {
+		return nil, nil
+	}
+	return s.canary.models.transcriber, func() {}
+}
+
+func (c *canaryModels) name() string {
+	return c.models.transcriber.Info().Name
+}
+
+// decoded reports whether res came from the canary. Its results are not
+// cached, so requests routed to the loaded model never get them.
+func (c *canaryModels) decoded(res *asr.Result) bool {
+	return c != nil && res.Model == c.name()
+}
+
+func (c *canaryModels) close() {
+	if c != nil {
+		c.models.close()
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestCheckCanaryWeight(t *testing.T) {
+	for _, w := range []float64{0.01, 0.5, 0.99} {
+		if err := checkCanaryWeight(w); err != nil {
+			t.Errorf("%v: %v", w, err)
+		}
+	}
+	for _, w := range []float64{0, 1, -0.1, 2} {
+		if err := checkCanaryWeight(w); err == nil {
+			t.Errorf("%v: want error", w)
+		}
+	}
+}
+
+func TestCanaryModels(t *testing.T) {
+	var off *canaryModels
+	if off.pick() || off.decoded(&asr.Result{Model: asr.DefaultModelName}) || off.check("any") != nil {
+		t.Error("a nil canary must never be picked")
+	}
+
+	c := &canaryModels{models: &loadedModels{transcriber: &asr.Transcriber{}}, weight: 0.25}
+	if err := c.check(asr.DefaultModelName); err == nil {
+		t.Error("a canary named like the loaded model must be refused")
+	}
+	if err := c.check("parakeet-tdt-0.6b-v2"); err != nil {
+		t.Error(err)
+	}
+	if !c.decoded(&asr.Result{Model: asr.DefaultModelName}) || c.decoded(&asr.Result{Model: "parakeet-tdt-0.6b-v2"}) {
+		t.Error("decoded must go by the model name")
+	}
+
+	picked := 0
+	for range 10000 {
+		if c.pick() {
+			picked++
+		}
+	}
+	if picked < 2000 || picked > 3000 {
+		t.Errorf("picked %d of 10000 at weight 0.25", picked)
+	}
+}
+
+func TestStats_ModelDecodes(t *testing.T) {
+	s := &Server{stats: newServerStats(), canary: &canaryModels{models: &loadedModels{transcriber: &asr.Transcriber{}}, weight: 0.1}}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+	s.stats.modelDecode("v2", &asr.Result{Duration: 60}, 3*time.Second, nil)
+	s.stats.modelDecode("v2", nil, time.Second, errors.New("boom"))
+	s.stats.modelDecode("v2", nil, time.Second, context.Canceled)
+	s.stats.modelDecode(asr.DefaultModelName, &asr.Result{Duration: 10}, time.Second, nil)
+
+	rec := httptest.NewRecorder()
+	s.handleStats(rec, httptest.NewRequest("GET", "/admin/stats", nil))
+	var got StatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []ModelDecodes{
+		{Model: asr.DefaultModelName, Canary: true, Decodes: 1, AudioSeconds: 10, DecodeSeconds: 1, AverageRTF: 0.1},
+		{Model: "v2", Decodes: 1, Errors: 1, AudioSeconds: 60, DecodeSeconds: 3, AverageRTF: 0.05},
+	}
+	if len(got.ModelDecodes) != len(want) || got.ModelDecodes[0] != want[0] || got.ModelDecodes[1] != want[1] {
+		t.Errorf("model_decodes = %+v, want %+v", got.ModelDecodes, want)
+	}
+}
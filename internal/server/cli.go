@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"parakeet/internal/archive"
+	"parakeet/internal/asr"
+	"parakeet/internal/jobs"
+)
+
+// FileOptions are the per-file settings of TranscribeFile: the
+// response_format, language, max_line_chars, max_lines_per_cue and
+// max_cue_duration (seconds) of an HTTP request. Zero values mean the
+// request defaults.
+type FileOptions struct {
+	ResponseFormat string
+	Language       string
+	MaxLineChars   int
+	MaxLinesPerCue int
+	MaxCueDuration float64
+}
+
+func (opts FileOptions) language() string {
+	if opts.Language == "" {
+		return "en"
+	}
+	return opts.Language
+}
+
+func (opts FileOptions) layout() cueLayout {
+	return cueLayout{MaxLineChars: opts.MaxLineChars, MaxLines: opts.MaxLinesPerCue, MaxDuration: opts.MaxCueDuration}
+}
+
+// formatExtensions maps each response format to the extension of a file
+// holding it.
+var formatExtensions = map[string]string{
+	"srt":          ".srt",
+	"vtt":          ".vtt",
+	"srt_words":    ".srt",
+	"vtt_words":    ".vtt",
+	"ass":          ".ass",
+	"ttml":         ".ttml",
+	"text":         ".txt",
+	"tsv":          ".tsv",
+	"csv":          ".csv",
+	"jsonl":        ".jsonl",
+	"json":         ".json",
+	"verbose_json": ".json",
+}
+
+// FormatExtension returns the file extension of a response format, and
+// whether the format exists.
+func FormatExtension(format string) (string, bool) {
+	ext, ok := formatExtensions[format]
+	return ext, ok
+}
+
+// TranscribeFile transcribes an audio or video file with the server
+// defaults and renders it as a request with opts would. It backs
+// `parakeet transcribe`, which uses a Server without ever calling Run.
+func (s *Server) TranscribeFile(ctx context.Context, path string, opts FileOptions) ([]byte, error) {
+	audio, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.transcribeFile(ctx, path, audio, opts)
+	if err != nil {
+		return nil, err
+	}
+	return renderFile(res, opts.ResponseFormat, opts.language(), opts.layout())
+}
+
+// TranscribeText transcribes audio, the contents of the file name, with the
+// server defaults and returns the transcript's text. It backs `parakeet
+// eval`.
+func (s *Server) TranscribeText(ctx context.Context, name string, audio []byte, opts FileOptions) (string, error) {
+	res, err := s.transcribeFile(ctx, name, audio, opts)
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
+}
+
+// TranscribeArchive transcribes every file of a zip or tar archive like
+// TranscribeFile and returns a zip archive of the transcripts (see
+// writeResultsArchive). progress is called after each file with its path in
+// the archive, its error if it failed, and the files done out of total.
+func (s *Server) TranscribeArchive(ctx context.Context, path string, opts FileOptions, progress func(name string, err error, done, total int)) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	total, err := archive.Count(f, info.Size(), math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	var files []jobs.File
+	err = archive.Walk(f, info.Size(), func(name string, _ int64, content io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		file := jobs.File{Name: name}
+		audio, err := io.ReadAll(content)
+		if err == nil {
+			file.Result, err = s.transcribeFile(ctx, name, audio, opts)
+		}
+		if err != nil {
+			file.Error = err.Error()
+		}
+		files = append(files, file)
+		progress(name, err, len(files), total)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = writeResultsArchive(&buf, files, opts.ResponseFormat, opts.language(), opts.layout())
+	return buf.Bytes(), err
+}
+
+// IsArchive reports whether the file at path is a zip or tar archive, to
+// go to TranscribeArchive rather than TranscribeFile.
+func IsArchive(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	return archive.Detect(f, info.Size()) != "", nil
+}
+
+// transcribeFile transcribes the audio of the file name with the server
+// defaults.
+func (s *Server) transcribeFile(ctx context.Context, name string, audio []byte, opts FileOptions) (*asr.Result, error) {
+	res, _, err := s.transcribe(ctx, audio, s.fileTranscribeOptions(name, opts))
+	return res, err
+}
+
+// fileTranscribeOptions are the server defaults for the file name.
+func (s *Server) fileTranscribeOptions(name string, opts FileOptions) asr.TranscribeOptions {
+	return asr.TranscribeOptions{
+		Format:            strings.ToLower(filepath.Ext(name)),
+		Language:          opts.language(),
+		Channels:          asr.ChannelMix,
+		Conditioning:      s.conditioning,
+		Segmentation:      s.segmentation,
+		NoSpeechThreshold: s.config.NoSpeechThreshold,
+		Guard:             s.guard,
+		Confidence:        s.confidence,
+		Formatting:        s.formatting,
+		Fallback:          s.fallback,
+		Denoise:           s.config.Denoise,
+	}
+}
+
+// renderFile renders a result in a response format as the contents of a
+// file: JSON bodies indented.
+func renderFile(res *asr.Result, format, language string, layout cueLayout) ([]byte, error) {
+	_, body := renderTranscription(res, format, language, layout)
+	if text, ok := body.(string); ok {
+		return []byte(text), nil
+	}
+	data, err := json.MarshalIndent(body, "", "  ")
+	return append(data, '\n'), err
+}
+
+// writeResultsArchive writes a zip archive with the transcript of each file
+// of an archive in format: at the file's path with the format's extension
+// (calls/0001.wav -> calls/0001.srt), or with .error.txt appended and the
+// error in it for the files that failed.
+func writeResultsArchive(w io.Writer, files []jobs.File, format, language string, layout cueLayout) error {
+	zw := zip.NewWriter(w)
+	taken := make(map[string]bool)
+	for _, f := range files {
+		name, data := f.Name+".error.txt", []byte(f.Error+"\n")
+		if f.Result != nil {
+			var err error
+			if data, err = renderFile(f.Result, format, language, layout); err != nil {
+				return err
+			}
+			// calls/a.wav and calls/a.mp3 keep their extension rather
+			// than both becoming calls/a.srt.
+			name = strings.TrimSuffix(f.Name, path.Ext(f.Name)) + formatExtensions[format]
+			if taken[name] {
+				name = f.Name + formatExtensions[format]
+			}
+		}
+		taken[name] = true
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ipSet is a list of networks from a comma-separated flag of CIDRs and
+// bare addresses (a single host).
+type ipSet []netip.Prefix
+
+func parseIPSet(s string) (ipSet, error) {
+	var set ipSet
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR", field)
+			}
+			addr = addr.Unmap()
+			set = append(set, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR", field)
+		}
+		set = append(set, prefix.Masked())
+	}
+	return set, nil
+}
+
+func (set ipSet) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range set {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteAddr is the address of r's peer, without the port.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// clientAddr is the client behind the trusted proxies: X-Forwarded-For is
+// read right to left, each proxy having appended the address it saw, and
+// the first address that is not a trusted proxy is the client. Entries
+// further left were written by the client itself and are not believed.
+// X-Real-IP is used when a trusted proxy sends no X-Forwarded-For.
+func clientAddr(r *http.Request, trusted ipSet) (netip.Addr, bool) {
+	peer, ok := remoteAddr(r)
+	if !ok || !trusted.contains(peer) {
+		return peer, ok
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	if len(hops) == 0 {
+		if real, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return real.Unmap(), true
+		}
+		return peer, true
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A garbled hop ends the chain: what lies beyond it cannot be
+			// attributed to a trusted proxy.
+			break
+		}
+		client = addr.Unmap()
+		if !trusted.contains(client) {
+			break
+		}
+	}
+	return client, true
+}
+
+// resolveClientIP replaces the RemoteAddr of requests relayed by a
+// -trusted-proxies address with the client's, so the IP rules, the request
+// log and the access log see the client instead of the proxy. Without
+// trusted proxies it passes requests through and forwarding headers are
+// ignored.
+func (s *Server) resolveClientIP(next http.Handler) http.Handler {
+	if len(s.trustedProxies) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if addr, ok := clientAddr(r, s.trustedProxies); ok {
+			r.RemoteAddr = addr.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// filterClients refuses requests from -deny-cidrs, and from outside
+// -allow-cidrs when it is set, with 403. Deny wins over allow. /health is
+// exempt so orchestrator probes keep working from wherever they run.
+func (s *Server) filterClients(next http.Handler) http.Handler {
+	if len(s.allowIPs) == 0 && len(s.denyIPs) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" && !s.clientAllowed(r) {
+			sendError(w, "Access from this address is not allowed", "permission_error", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) clientAllowed(r *http.Request) bool {
+	addr, ok := remoteAddr(r)
+	if !ok {
+		// Not an IP peer (e.g. a unix socket or an in-process caller):
+		// only an allowlist turns it away.
+		return len(s.allowIPs) == 0
+	}
+	if s.denyIPs.contains(addr) {
+		return false
+	}
+	return len(s.allowIPs) == 0 || s.allowIPs.contains(addr)
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func mustIPSet(t *testing.T, s string) ipSet {
+	t.Helper()
+	set, err := parseIPSet(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func TestParseIPSet(t *testing.T) {
+	set := mustIPSet(t, " 10.0.0.0/8, 192.168.1.7 ,,2001:db8::/32")
+	if len(set) != 3 {
+		t.Fatalf("parsed %v, want 3 networks", set)
+	}
+	for addr, want := range map[string]bool{"10.2.3.4": true, "192.168.1.7": true, "192.168.1.8": false, "2001:db8::1": true, "::ffff:10.0.0.1": true} {
+		req := &http.Request{RemoteAddr: addr}
+		a, ok := remoteAddr(req)
+		if !ok || set.contains(a) != want {
+			t.Errorf("contains(%s) = %v, want %v", addr, set.contains(a), want)
+		}
+	}
+	for _, bad := range []string{"10.0.0.0/33", "example.com", "10.0.0"} {
+		if _, err := parseIPSet(bad); err == nil {
+			t.Errorf("parseIPSet(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	trusted := mustIPSet(t, "10.0.0.0/8")
+	for _, tc := range []struct {
+		name, remote, xff, realIP, want string
+	}{
+		{"direct client", "203.0.113.9:5000", "", "", "203.0.113.9"},
+		{"untrusted peer cannot spoof", "203.0.113.9:5000", "1.2.3.4", "", "203.0.113.9"},
+		{"one proxy", "10.0.0.2:5000", "198.51.100.4", "", "198.51.100.4"},
+		{"proxy chain", "10.0.0.2:5000", "198.51.100.4, 10.1.1.1", "", "198.51.100.4"},
+		{"spoofed entry left of client", "10.0.0.2:5000", "1.2.3.4, 198.51.100.4", "", "198.51.100.4"},
+		{"X-Real-IP", "10.0.0.2:5000", "", "198.51.100.5", "198.51.100.5"},
+		{"garbled hop", "10.0.0.2:5000", "1.2.3.4, junk", "", "10.0.0.2"},
+		{"only proxies", "10.0.0.2:5000", "10.3.3.3", "", "10.3.3.3"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.xff != "" {
+			req.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		if tc.realIP != "" {
+			req.Header.Set("X-Real-IP", tc.realIP)
+		}
+		if got, ok := clientAddr(req, trusted); !ok || got.String() != tc.want {
+			t.Errorf("%s: client %v, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFilterClients(t *testing.T) {
+	s := &Server{
+		trustedProxies: mustIPSet(t, "10.0.0.1"),
+		allowIPs:       mustIPSet(t, "198.51.100.0/24"),
+		denyIPs:        mustIPSet(t, "198.51.100.66"),
+	}
+	h := s.resolveClientIP(s.filterClients(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	call := func(remote, xff, path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remote
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, tc := range []struct {
+		remote, xff, path string
+		want              int
+	}{
+		{"198.51.100.4:1", "", "/v1/models", http.StatusOK},
+		{"203.0.113.9:1", "", "/v1/models", http.StatusForbidden},
+		{"198.51.100.66:1", "", "/v1/models", http.StatusForbidden}, // deny wins
+		{"10.0.0.1:1", "198.51.100.4", "/v1/models", http.StatusOK},
+		{"10.0.0.1:1", "203.0.113.9", "/v1/models", http.StatusForbidden},
+		{"203.0.113.9:1", "198.51.100.4", "/v1/models", http.StatusForbidden}, // untrusted XFF
+		{"203.0.113.9:1", "", "/health", http.StatusOK},
+	} {
+		if got := call(tc.remote, tc.xff, tc.path); got != tc.want {
+			t.Errorf("%s (XFF %q) %s: status %d, want %d", tc.remote, tc.xff, tc.path, got, tc.want)
+		}
+	}
+}
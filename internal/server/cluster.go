@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"parakeet/internal/jobs"
+)
+
+// clusterConnectTimeout bounds joining the job cluster at startup.
+const clusterConnectTimeout = 30 * time.Second
+
+// clusterStatusResponse is the body of GET /admin/cluster.
+type clusterStatusResponse struct {
+	// Worker is the ID of the instance that answered.
+	Worker string `json:"worker"`
+	jobs.ClusterStatus
+}
+
+// clusterUploads keeps /v2/upload files in the job cluster, so a transcript
+// can be submitted to any instance.
+type clusterUploads struct {
+	cluster *jobs.Cluster
+}
+
+// uploadObject names an upload in the cluster's audio store. The owner is
+// part of the name, so another key asking for the same id finds nothing.
+func uploadObject(id, owner string) string {
+	if owner == "" {
+		return id
+	}
+	sum := sha256.Sum256([]byte(owner))
+	return id + "." + hex.EncodeToString(sum[:8])
+}
+
+func (u clusterUploads) put(data *spool, owner string) (string, error) {
+	id := newRequestID()
+	return id, u.store(id, owner, data)
+}
+
+func (u clusterUploads) store(id, owner string, data *spool) error {
+	audio, err := data.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := u.cluster.PutAudio(uploadObject(id, owner), audio); err != nil {
+		return err
+	}
+	data.Close()
+	return nil
+}
+
+func (u clusterUploads) get(id, owner string) ([]byte, bool) {
+	data, err := u.cluster.Audio(uploadObject(id, owner))
+	return data, err == nil
+}
+
+func (clusterUploads) close() {}
+
+// handleCluster serves GET /admin/cluster: the instances sharing the job
+// cluster, with what each is running, and the jobs by status.
+func (s *Server) handleCluster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	status, err := s.cluster.Status()
+	if err != nil {
+		sendError(w, "Job cluster unavailable: "+err.Error(), "server_error", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clusterStatusResponse{Worker: s.cluster.ID(), ClusterStatus: status})
+}
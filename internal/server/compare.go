@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/selftest"
+)
+
+// Comparison is the same audio transcribed by two models, to choose between
+// them (int8 or fp32, v2 or v3): B's word error rate taking A's transcript
+// as the reference, and the word-level diff between the two.
+type Comparison struct {
+	A ComparedTranscript `json:"a"`
+	B ComparedTranscript `json:"b"`
+
+	WER            float64         `json:"wer"`
+	ReferenceWords int             `json:"reference_words"`
+	Substitutions  int             `json:"substitutions"`
+	Deletions      int             `json:"deletions"`
+	Insertions     int             `json:"insertions"`
+	Diff           []selftest.Edit `json:"diff"`
+}
+
+// ComparedTranscript is one side of a Comparison.
+type ComparedTranscript struct {
+	// Role is the model's place in the server: loaded, compare, canary or
+	// shadow.
+	Role    string  `json:"role"`
+	Model   string  `json:"model"`
+	Text    string  `json:"text"`
+	Seconds float64 `json:"seconds"`
+}
+
+// comparedModel is a model a comparison can run on.
+type comparedModel struct {
+	role, name string
+	use        func() (*asr.Transcriber, func(), error)
+}
+
+// comparableModels lists the loaded model and, after it, the
+// -compare-models-dir, canary and shadow models that are configured.
+func (s *Server) comparableModels() []comparedModel {
+	models := []comparedModel{{role: "loaded", name: s.transcriber().Info().Name, use: s.useTranscriber}}
+	add := func(role string, m *loadedModels) {
+		t := m.transcriber
+		models = append(models, comparedModel{role: role, name: t.Info().Name, use: func() (*asr.Transcriber, func(), error) {
+			return t, func() {}, nil
+		}})
+	}
+	if s.compareModels != nil {
+		add("compare", s.compareModels)
+	}
+	if s.canary != nil {
+		add("canary", s.canary.models)
+	}
+	if s.shadow != nil {
+		add("shadow", s.shadow.models)
+	}
+	return models
+}
+
+// pickComparedModels resolves the model_a and model_b parameters, read with
+// get, each a role or a model name. A defaults to the loaded model and B to
+// the first other one configured.
+func (s *Server) pickComparedModels(get func(string) string) (a, b comparedModel, err error) {
+	models := s.comparableModels()
+	if len(models) < 2 {
+		return a, b, errors.New("only one model is loaded: set -compare-models-dir, -canary-models-dir or -shadow-models-dir to compare with a second one")
+	}
+	pick := func(param string, def comparedModel) (comparedModel, error) {
+		v := get(param)
+		if v == "" {
+			return def, nil
+		}
+		var known []string
+		for _, m := range models {
+			if v == m.role || v == m.name {
+				return m, nil
+			}
+			known = append(known, m.role+" ("+m.name+")")
+		}
+		return def, invalidParam(param, "unknown %s %q (configured: %s)", param, v, strings.Join(known, ", "))
+	}
+	if a, err = pick("model_a", models[0]); err != nil {
+		return a, b, err
+	}
+	def := models[1]
+	if a.role != models[0].role {
+		def = models[0]
+	}
+	b, err = pick("model_b", def)
+	return a, b, err
+}
+
+// compareAudio transcribes audio with both models at once and compares the
+// transcripts, after the -postprocess-profiles profile of the request's
+// language. Comparisons are neither cached nor kept in the history.
+func (s *Server) compareAudio(ctx context.Context, audio []byte, opts asr.TranscribeOptions, a, b comparedModel) (*Comparison, error) {
+	cmp := &Comparison{A: ComparedTranscript{Role: a.role, Model: a.name}, B: ComparedTranscript{Role: b.role, Model: b.name}}
+	opts.Progress = nil
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for k, side := range []*ComparedTranscript{&cmp.A, &cmp.B} {
+		m := []comparedModel{a, b}[k]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t, done, err := m.use()
+			if err != nil {
+				errs[k] = err
+				return
+			}
+			defer done()
+			start := time.Now()
+			res, err := t.TranscribeWithOptions(ctx, audio, opts, nil)
+			s.stats.modelDecode(m.name, res, time.Since(start), err)
+			if err != nil {
+				errs[k] = err
+				return
+			}
+			s.profiles.apply(opts.Language, res)
+			side.Text, side.Seconds = res.Text, time.Since(start).Seconds()
+			if k == 0 {
+				noteAudio(ctx, res.Duration)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	score := selftest.Compare(cmp.A.Text, cmp.B.Text)
+	cmp.WER, cmp.ReferenceWords = score.WER(), score.ReferenceWords
+	cmp.Substitutions, cmp.Deletions, cmp.Insertions = score.Substitutions, score.Deletions, score.Insertions
+	cmp.Diff = selftest.Diff(cmp.A.Text, cmp.B.Text)
+	if cmp.Diff == nil {
+		cmp.Diff = []selftest.Edit{}
+	}
+	return cmp, nil
+}
+
+// CompareFile transcribes an audio or video file with the loaded model and
+// the -compare-models-dir (or canary, or shadow) model, with the server
+// defaults. It backs `parakeet compare`, which uses a Server without ever
+// calling Run.
+func (s *Server) CompareFile(ctx context.Context, path string, opts FileOptions) (*Comparison, error) {
+	audio, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a, b, err := s.pickComparedModels(func(string) string { return "" })
+	if err != nil {
+		return nil, err
+	}
+	return s.compareAudio(ctx, audio, s.fileTranscribeOptions(path, opts), a, b)
+}
+
+// handleCompare serves /v1/audio/compare: a multipart transcription request
+// whose file is transcribed by model_a and model_b and answered with their
+// Comparison. It takes the decoding parameters of /v1/audio/transcriptions.
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.MultipartForm == nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	}
+	if err := r.ParseMultipartForm(s.multipartMemory()); err != nil {
+		sendBodyError(w, err)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		sendRequestError(w, &paramError{param: "file", code: "missing_required_parameter", err: errors.New("Missing required parameter: 'file'")})
+		return
+	}
+	defer file.Close()
+	audioData, err := io.ReadAll(file)
+	if err != nil {
+		sendError(w, "Failed to read audio file: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
+		return
+	}
+
+	req, err := s.parseTranscriptionRequest(r)
+	if err != nil {
+		sendRequestError(w, err)
+		return
+	}
+	a, b, err := s.pickComparedModels(r.FormValue)
+	if err != nil {
+		sendRequestError(w, err)
+		return
+	}
+	opts := req.opts
+	opts.Format = strings.ToLower(filepath.Ext(header.Filename))
+
+	slog.InfoContext(r.Context(), "comparing models",
+		"file", header.Filename,
+		"bytes", len(audioData),
+		"model_a", a.name,
+		"model_b", b.name,
+	)
+	cmp, err := s.compareAudio(r.Context(), audioData, opts, a, b)
+	if err != nil {
+		s.writeTranscribeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cmp)
+}
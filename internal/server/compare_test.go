@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/url"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestPickComparedModels(t *testing.T) {
+	s := &Server{}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+	if _, _, err := s.pickComparedModels(url.Values{}.Get); err == nil {
+		t.Error("a comparison with a single model was accepted")
+	}
+
+	s.compareModels = &loadedModels{transcriber: &asr.Transcriber{}}
+	s.shadow = &shadowModels{models: &loadedModels{transcriber: &asr.Transcriber{}}}
+	for _, tt := range []struct {
+		a, b         string
+		wantA, wantB string
+	}{
+		{"", "", "loaded", "compare"},
+		{"", "shadow", "loaded", "shadow"},
+		{"compare", "", "compare", "loaded"},
+		{"shadow", "compare", "shadow", "compare"},
+		{asr.DefaultModelName, "", "loaded", "compare"},
+	} {
+		a, b, err := s.pickComparedModels(url.Values{"model_a": {tt.a}, "model_b": {tt.b}}.Get)
+		if err != nil || a.role != tt.wantA || b.role != tt.wantB {
+			t.Errorf("model_a=%q model_b=%q: %s, %s, %v; want %s, %s", tt.a, tt.b, a.role, b.role, err, tt.wantA, tt.wantB)
+		}
+	}
+	if _, _, err := s.pickComparedModels(url.Values{"model_b": {"canary"}}.Get); err == nil {
+		t.Error("model_b=canary accepted without a canary")
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"expvar"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// debugStatsServer is the server whose counters the "parakeet" expvar
+// reports. expvar names are process-global and may only be published once,
+// so the variable is registered on first use and reads through this pointer.
+var (
+	debugStatsServer  atomic.Pointer[Server]
+	publishDebugStats sync.Once
+)
+
+// newDebugMux serves net/http/pprof under /debug/pprof/ and expvar under
+// /debug/vars. It is mounted on its own listener (-debug-addr), never on the
+// public API port: profiles expose memory contents and are expensive to take.
+func (s *Server) newDebugMux() *http.ServeMux {
+	debugStatsServer.Store(s)
+	publishDebugStats.Do(func() {
+		expvar.Publish("parakeet", expvar.Func(func() any {
+			if srv := debugStatsServer.Load(); srv != nil {
+				return srv.statsSnapshot()
+			}
+			return nil
+		}))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// startDebugServer starts the opt-in pprof/expvar listener in the
+// background. A failure to listen is logged, not fatal: diagnostics must
+// never take the API down.
+func (s *Server) startDebugServer() {
+	if s.config.DebugAddr == "" {
+		return
+	}
+	s.debugServer = &http.Server{
+		Addr:              s.config.DebugAddr,
+		Handler:           s.newDebugMux(),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	srv := s.debugServer
+	go func() {
+		slog.Warn("debug endpoints enabled (pprof, expvar); keep this address private",
+			"addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("debug server failed", "addr", srv.Addr, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestDebugMux(t *testing.T) {
+	s := &Server{stats: newServerStats()}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+	s.stats.decode(10, 0)
+	mux := s.newDebugMux()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+	var vars struct {
+		Parakeet StatsResponse `json:"parakeet"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Parakeet.Decodes != 1 || vars.Parakeet.AudioSeconds != 10 {
+		t.Errorf("parakeet expvar = %+v", vars.Parakeet)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	if rec.Code != 200 {
+		t.Errorf("/debug/pprof/ status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,571 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"parakeet/internal/asr"
+)
+
+// deepgramModel is the model name reported in Deepgram responses.
+const deepgramModel = "parakeet-tdt-0.6b"
+
+// deepgramIdleTimeout closes a live connection that sends neither audio nor
+// KeepAlive for this long, as Deepgram does.
+const deepgramIdleTimeout = 10 * time.Second
+
+// Deepgram wire types. Only the fields parakeet can fill are present.
+
+type deepgramWord struct {
+	Word           string  `json:"word"`
+	Start          float64 `json:"start"`
+	End            float64 `json:"end"`
+	Confidence     float64 `json:"confidence"`
+	PunctuatedWord string  `json:"punctuated_word"`
+}
+
+type deepgramAlternative struct {
+	Transcript string         `json:"transcript"`
+	Confidence float64        `json:"confidence"`
+	Words      []deepgramWord `json:"words"`
+}
+
+type deepgramChannel struct {
+	Alternatives []deepgramAlternative `json:"alternatives"`
+}
+
+type deepgramModelInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+}
+
+type deepgramMetadata struct {
+	TransactionKey string                       `json:"transaction_key"`
+	RequestID      string                       `json:"request_id"`
+	SHA256         string                       `json:"sha256"`
+	Created        string                       `json:"created"`
+	Duration       float64                      `json:"duration"`
+	Channels       int                          `json:"channels"`
+	Models         []string                     `json:"models"`
+	ModelInfo      map[string]deepgramModelInfo `json:"model_info"`
+}
+
+// deepgramResponse is the body of a pre-recorded /v1/listen request.
+type deepgramResponse struct {
+	Metadata deepgramMetadata `json:"metadata"`
+	Results  struct {
+		Channels []deepgramChannel `json:"channels"`
+	} `json:"results"`
+}
+
+// deepgramResults is a live "Results" message.
+type deepgramResults struct {
+	Type         string          `json:"type"` // always "Results"
+	ChannelIndex []int           `json:"channel_index"`
+	Duration     float64         `json:"duration"`
+	Start        float64         `json:"start"`
+	IsFinal      bool            `json:"is_final"`
+	SpeechFinal  bool            `json:"speech_final"`
+	FromFinalize bool            `json:"from_finalize"`
+	Channel      deepgramChannel `json:"channel"`
+	// Lag is the audio, in seconds, received but not transcribed yet when
+	// the result is sent: how far behind the stream it is. It is not part
+	// of Deepgram's API.
+	Lag      float64 `json:"lag"`
+	Metadata struct {
+		RequestID string            `json:"request_id"`
+		ModelInfo deepgramModelInfo `json:"model_info"`
+		ModelUUID string            `json:"model_uuid"`
+	} `json:"metadata"`
+}
+
+// deepgramLiveMetadata is the live "Metadata" message sent when the stream
+// closes.
+type deepgramLiveMetadata struct {
+	Type string `json:"type"` // always "Metadata"
+	deepgramMetadata
+}
+
+// deepgramError is Deepgram's error body.
+type deepgramError struct {
+	ErrCode   string `json:"err_code"`
+	ErrMsg    string `json:"err_msg"`
+	RequestID string `json:"request_id"`
+}
+
+func sendDeepgramError(w http.ResponseWriter, code, message, requestID string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(deepgramError{ErrCode: code, ErrMsg: message, RequestID: requestID})
+}
+
+// requireDeepgramAuth accepts the API key the ways Deepgram SDKs send it:
+// "Authorization: Token <key>", "Authorization: Bearer <key>", or, for
+// browser WebSockets that cannot set headers, the subprotocol pair
+// "token, <key>".
+func (s *Server) requireDeepgramAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		var tokens []string
+		if token, ok := strings.CutPrefix(auth, "Token "); ok {
+			tokens = append(tokens, token)
+		}
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			tokens = append(tokens, token)
+		}
+		if key, ok := deepgramSubprotocolToken(r); ok {
+			tokens = append(tokens, key)
+		}
+		for _, token := range tokens {
+			if name, ok := s.apiKeyName(token); ok {
+				next.ServeHTTP(w, r.WithContext(withAPIKeyName(r.Context(), name)))
+				return
+			}
+		}
+		sendDeepgramError(w, "INVALID_AUTH", "Invalid credentials.", "", http.StatusUnauthorized)
+	})
+}
+
+// deepgramSubprotocolToken extracts the key from "Sec-WebSocket-Protocol:
+// token, <key>".
+func deepgramSubprotocolToken(r *http.Request) (string, bool) {
+	var protocols []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			protocols = append(protocols, strings.TrimSpace(p))
+		}
+	}
+	if len(protocols) == 2 && strings.EqualFold(protocols[0], "token") {
+		return protocols[1], true
+	}
+	return "", false
+}
+
+// deepgramParams are the /v1/listen query parameters parakeet honours.
+// Formatting options (punctuate, smart_format, ...) are accepted and
+// ignored: parakeet's transcripts are always cased and punctuated.
+type deepgramParams struct {
+	language    string
+	format      *asr.PCMFormat // set for headerless audio (encoding=...)
+	multichan   bool
+	interim     bool
+	endpointing time.Duration
+}
+
+func (s *Server) parseDeepgramParams(r *http.Request) (deepgramParams, error) {
+	q := r.URL.Query()
+	p := deepgramParams{
+		language:    q.Get("language"),
+		multichan:   parseBool(q.Get("multichannel")),
+		interim:     parseBool(q.Get("interim_results")),
+		endpointing: s.liveEndpointing(),
+	}
+	if p.language == "" {
+		p.language = "en"
+	}
+	if err := s.checkLanguage(p.language); err != nil {
+		return p, err
+	}
+	if enc := q.Get("encoding"); enc != "" {
+		rate, err := strconv.Atoi(q.Get("sample_rate"))
+		if err != nil {
+			return p, fmt.Errorf("encoding=%s requires a numeric sample_rate", enc)
+		}
+		channels := 0
+		if v := q.Get("channels"); v != "" {
+			if channels, err = strconv.Atoi(v); err != nil {
+				return p, fmt.Errorf("invalid channels %q", v)
+			}
+		}
+		f, err := asr.ParsePCMFormat(enc, rate, channels)
+		if err != nil {
+			return p, err
+		}
+		p.format = &f
+	}
+	switch v := q.Get("endpointing"); {
+	case v == "":
+	case strings.EqualFold(v, "false"):
+		p.endpointing = 0
+	default:
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			return p, fmt.Errorf("invalid endpointing %q (milliseconds or false)", v)
+		}
+		p.endpointing = time.Duration(ms) * time.Millisecond
+	}
+	return p, nil
+}
+
+// handleListen serves Deepgram's /v1/listen: a WebSocket upgrade starts a
+// live session, a POST transcribes a pre-recorded file.
+func (s *Server) handleListen(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if isWebSocketUpgrade(r) {
+		s.handleListenLive(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		sendDeepgramError(w, "INVALID_REQUEST", "Method not allowed", "", http.StatusMethodNotAllowed)
+		return
+	}
+	s.handleListenPrerecorded(w, r)
+}
+
+func (s *Server) handleListenPrerecorded(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDOf(r)
+	params, err := s.parseDeepgramParams(r)
+	if err != nil {
+		sendDeepgramError(w, "INVALID_QUERY_PARAMETER", err.Error(), requestID, http.StatusBadRequest)
+		return
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		// {"url": ...} would make the server fetch arbitrary URLs on the
+		// client's behalf; only uploads are supported.
+		sendDeepgramError(w, "INVALID_REQUEST", "Remote URLs are not supported; send the audio in the request body", requestID, http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 25<<20)
+	audio, err := io.ReadAll(r.Body)
+	if err != nil {
+		sendDeepgramError(w, "INVALID_REQUEST", "Error reading body: "+err.Error(), requestID, http.StatusBadRequest)
+		return
+	}
+	if len(audio) == 0 {
+		sendDeepgramError(w, "INVALID_REQUEST", "Empty request body", requestID, http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256(audio)
+	if params.format != nil {
+		audio = params.format.WAV(audio)
+	}
+
+	opts := asr.TranscribeOptions{
+		Format:            ".wav",
+		Language:          params.language,
+		Channels:          asr.ChannelMix,
+		Conditioning:      s.conditioning,
+		Segmentation:      s.segmentation,
+		NoSpeechThreshold: s.config.NoSpeechThreshold,
+		Guard:             s.guard,
+		Confidence:        s.confidence,
+		Formatting:        s.formatting,
+		Fallback:          s.fallback,
+		Denoise:           s.config.Denoise,
+	}
+	if params.multichan {
+		opts.Channels = asr.ChannelPerChannel
+	}
+
+	slog.Info("transcribing deepgram request", "bytes", len(audio), "multichannel", params.multichan, "request_id", requestID)
+	result, cached, err := s.transcribe(r.Context(), audio, opts)
+	if err != nil {
+		if errors.Is(err, asr.ErrUnsupportedAudio) {
+			sendDeepgramError(w, "Bad Request", "Bad Request: failed to process audio: corrupt or unsupported data", requestID, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, asr.ErrOverMemoryBudget) {
+			sendDeepgramError(w, "Bad Request", "Bad Request: audio too long for this server's memory", requestID, http.StatusRequestEntityTooLarge)
+			return
+		}
+		if errors.Is(err, asr.ErrQueueFull) {
+			setRetryAfter(w)
+			sendDeepgramError(w, "SERVICE_UNAVAILABLE", "Too many requests queued, retry later", requestID, http.StatusServiceUnavailable)
+			return
+		}
+		sendDeepgramError(w, "INTERNAL_SERVER_ERROR", "Transcription failed: "+err.Error(), requestID, http.StatusInternalServerError)
+		return
+	}
+	s.setCacheHeader(w, cached)
+
+	resp := deepgramResponse{Metadata: s.deepgramMetadata(requestID, hex.EncodeToString(sum[:]), result.Duration, result.Channels)}
+	resp.Results.Channels = make([]deepgramChannel, result.Channels)
+	for ch := range resp.Results.Channels {
+		var words []asr.Word
+		for _, w := range result.Words {
+			if w.Channel == ch {
+				words = append(words, w)
+			}
+		}
+		transcript := result.Text
+		if result.Channels > 1 {
+			transcript = channelTranscript(result, ch)
+		}
+		resp.Results.Channels[ch] = deepgramChannelOf(transcript, words)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// channelTranscript joins one channel's segments of a per-channel result.
+func channelTranscript(result *asr.Result, ch int) string {
+	var parts []string
+	for _, seg := range result.Segments {
+		if seg.Channel == ch {
+			parts = append(parts, seg.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *Server) deepgramModelInfo() deepgramModelInfo {
+	return deepgramModelInfo{Name: deepgramModel, Version: s.config.Build.Version, Arch: "tdt"}
+}
+
+func (s *Server) deepgramMetadata(requestID, sha string, duration float64, channels int) deepgramMetadata {
+	return deepgramMetadata{
+		TransactionKey: "deprecated",
+		RequestID:      requestID,
+		SHA256:         sha,
+		Created:        time.Now().UTC().Format(time.RFC3339Nano),
+		Duration:       duration,
+		Channels:       channels,
+		Models:         []string{deepgramModel},
+		ModelInfo:      map[string]deepgramModelInfo{deepgramModel: s.deepgramModelInfo()},
+	}
+}
+
+// deepgramChannelOf renders a transcript and its words as Deepgram's single
+// alternative. The transcript confidence is the mean word confidence.
+func deepgramChannelOf(transcript string, words []asr.Word) deepgramChannel {
+	alt := deepgramAlternative{Transcript: transcript, Words: make([]deepgramWord, 0, len(words))}
+	for _, w := range words {
+		alt.Words = append(alt.Words, deepgramWord{
+			Word:           deepgramBareWord(w.Text),
+			Start:          w.Start,
+			End:            w.End,
+			Confidence:     w.Confidence,
+			PunctuatedWord: w.Text,
+		})
+		alt.Confidence += w.Confidence
+	}
+	if len(words) > 0 {
+		alt.Confidence /= float64(len(words))
+	}
+	return deepgramChannel{Alternatives: []deepgramAlternative{alt}}
+}
+
+// deepgramBareWord is Deepgram's "word" field: lower case without the
+// surrounding punctuation, which stays in "punctuated_word".
+func deepgramBareWord(text string) string {
+	return strings.ToLower(strings.TrimFunc(text, unicode.IsPunct))
+}
+
+// handleListenLive runs a Deepgram live session over a WebSocket. The
+// client sends binary audio packets in the encoding given by the query
+// string and control messages as JSON text (KeepAlive, Finalize,
+// CloseStream); the server answers with Results messages and a closing
+// Metadata message.
+func (s *Server) handleListenLive(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDOf(r)
+	params, err := s.parseDeepgramParams(r)
+	if err != nil {
+		sendDeepgramError(w, "INVALID_QUERY_PARAMETER", err.Error(), requestID, http.StatusBadRequest)
+		return
+	}
+	if params.format == nil {
+		// Containers (WebM/Opus, MP3, ...) cannot be decoded packet by
+		// packet without a streaming demuxer.
+		sendDeepgramError(w, "INVALID_QUERY_PARAMETER", "live streaming requires encoding (linear16, linear32, float32, mulaw or alaw) and sample_rate", requestID, http.StatusBadRequest)
+		return
+	}
+
+	protocol := ""
+	if _, ok := deepgramSubprotocolToken(r); ok {
+		protocol = "token"
+	}
+	conn, err := upgradeWebSocket(w, r, protocol)
+	if err != nil {
+		slog.Debug("deepgram upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx := r.Context()
+	session := s.newLiveSession(*params.format, asr.TranscribeOptions{
+		Format:            ".wav",
+		Language:          params.language,
+		Channels:          asr.ChannelMix,
+		Conditioning:      s.conditioning,
+		Segmentation:      s.segmentation,
+		NoSpeechThreshold: s.config.NoSpeechThreshold,
+		Guard:             s.guard,
+		Confidence:        s.confidence,
+		Formatting:        s.formatting,
+		Fallback:          s.fallback,
+		Denoise:           s.config.Denoise,
+		Priority:          asr.PriorityInteractive,
+	}, params.interim, params.endpointing)
+	// The session's audio is metered when the handler returns.
+	defer func() { noteAudio(ctx, session.receivedSeconds()) }()
+	queue := newLiveQueue(int(s.liveMaxLag().Seconds()*float64(params.format.SampleRate))*params.format.FrameBytes(), s.liveLagPolicy(), params.format.Seconds)
+	session.queue = queue
+	defer s.liveSessions.add(session, "deepgram", r.RemoteAddr)()
+	slog.Info("deepgram live session started", "request_id", requestID, "encoding", params.format.Encoding,
+		"sample_rate", params.format.SampleRate, "channels", params.format.Channels)
+
+	sendJSON := func(v any) bool {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return false
+		}
+		return conn.writeText(payload) == nil
+	}
+	sendResult := func(res liveResult) bool {
+		msg := deepgramResults{
+			Type:         "Results",
+			ChannelIndex: []int{0, 1},
+			Duration:     res.Duration,
+			Start:        res.Start,
+			IsFinal:      res.IsFinal,
+			SpeechFinal:  res.SpeechFinal,
+			FromFinalize: res.FromFinalize,
+			Channel:      deepgramChannelOf(res.Text, res.Words),
+		}
+		msg.Lag, _ = queue.lag()
+		msg.Metadata.RequestID = requestID
+		msg.Metadata.ModelInfo = s.deepgramModelInfo()
+		msg.Metadata.ModelUUID = deepgramModel
+		return sendJSON(msg)
+	}
+	// finish finalizes what is pending, reports the session and closes.
+	finish := func() {
+		if res, err := session.finalize(ctx, false, true); err != nil {
+			conn.writeClose(wsCloseInternal, "transcription failed")
+			return
+		} else if res != nil {
+			sendResult(*res)
+		}
+		sendJSON(deepgramLiveMetadata{
+			Type:             "Metadata",
+			deepgramMetadata: s.deepgramMetadata(requestID, "", session.receivedSeconds(), 1),
+		})
+		conn.writeClose(wsCloseNormal, "")
+	}
+
+	// Messages are read ahead into the queue, so a burst is taken in at
+	// once and its backlog can be measured and bounded.
+	go func() {
+		for {
+			_ = conn.setReadDeadline(s.liveReadDeadline(session.started, deepgramIdleTimeout))
+			op, data, err := conn.readMessage()
+			if err != nil {
+				queue.close(err)
+				return
+			}
+			if !queue.put(op, data) {
+				return
+			}
+		}
+	}()
+	defer queue.stop()
+
+	for {
+		msg, err := queue.get()
+		op, data := msg.op, msg.data
+		if err != nil && s.liveExpired(session.started) {
+			finish()
+			slog.Info("deepgram live session reached its maximum duration", "request_id", requestID, "seconds", session.receivedSeconds())
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, errWSClosed) && !errors.Is(err, io.EOF) {
+				conn.writeClose(wsClosePolicy, "did not receive audio data or a text message within the timeout window")
+			}
+			slog.Info("deepgram live session ended", "request_id", requestID, "seconds", session.receivedSeconds())
+			return
+		}
+
+		if op == wsText {
+			var ctrl struct {
+				Type string `json:"type"`
+				liveSettings
+			}
+			if err := json.Unmarshal(data, &ctrl); err != nil {
+				conn.writeClose(wsCloseUnsupported, "invalid control message")
+				return
+			}
+			switch ctrl.Type {
+			case "KeepAlive":
+			case "Configure":
+				if err := s.checkLiveSettings(ctrl.liveSettings); err != nil {
+					conn.writeClose(wsCloseUnsupported, err.Error())
+					return
+				}
+				session.configure(ctrl.liveSettings)
+			case "Finalize":
+				res, err := session.finalize(ctx, false, true)
+				if err != nil {
+					conn.writeClose(wsCloseInternal, "transcription failed")
+					return
+				}
+				if res == nil {
+					res = &liveResult{Start: session.pendingStart, IsFinal: true, FromFinalize: true}
+				}
+				if !sendResult(*res) {
+					return
+				}
+			case "CloseStream":
+				finish()
+				return
+			default:
+				conn.writeClose(wsCloseUnsupported, "unknown message type "+strconv.Quote(ctrl.Type))
+				return
+			}
+			continue
+		}
+
+		if msg.dropped > 0 {
+			res, err := session.skip(ctx, msg.dropped)
+			if err != nil {
+				slog.Error("deepgram live transcription failed", "request_id", requestID, "error", err)
+				conn.writeClose(wsCloseInternal, "transcription failed")
+				return
+			}
+			slog.Warn("deepgram live session falling behind, audio dropped", "request_id", requestID, "seconds", params.format.Seconds(msg.dropped))
+			if res != nil && !sendResult(*res) {
+				return
+			}
+			continue
+		}
+		// An empty binary message is the legacy way to close the stream.
+		if len(data) == 0 {
+			finish()
+			return
+		}
+		results, err := session.push(ctx, data)
+		if err != nil {
+			slog.Error("deepgram live transcription failed", "request_id", requestID, "error", err)
+			conn.writeClose(wsCloseInternal, "transcription failed")
+			return
+		}
+		for _, res := range results {
+			if !sendResult(res) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestParseDeepgramParams(t *testing.T) {
+	s := &Server{}
+	r := httptest.NewRequest("GET", "/v1/listen?encoding=mulaw&sample_rate=8000&interim_results=true&endpointing=300", nil)
+	p, err := s.parseDeepgramParams(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.format == nil || p.format.Encoding != asr.PCMMuLaw || p.format.SampleRate != 8000 || p.format.Channels != 1 {
+		t.Errorf("format = %+v", p.format)
+	}
+	if !p.interim || p.endpointing != 300*time.Millisecond || p.language != "en" {
+		t.Errorf("params = %+v", p)
+	}
+
+	for _, q := range []string{"encoding=linear16", "encoding=opus&sample_rate=48000", "endpointing=soon"} {
+		if _, err := s.parseDeepgramParams(httptest.NewRequest("GET", "/v1/listen?"+q, nil)); err == nil {
+			t.Errorf("%s: want error", q)
+		}
+	}
+	p, _ = s.parseDeepgramParams(httptest.NewRequest("GET", "/v1/listen?endpointing=false", nil))
+	if p.endpointing != 0 {
+		t.Errorf("endpointing=false gave %v", p.endpointing)
+	}
+	// Without the parameter, -live-endpointing applies.
+	for cfg, want := range map[time.Duration]time.Duration{0: liveDefaultEndpointing, 800 * time.Millisecond: 800 * time.Millisecond} {
+		s := &Server{config: Config{LiveEndpointing: cfg}}
+		p, _ = s.parseDeepgramParams(httptest.NewRequest("GET", "/v1/listen", nil))
+		if p.endpointing != want {
+			t.Errorf("-live-endpointing %v gave %v, want %v", cfg, p.endpointing, want)
+		}
+	}
+}
+
+func TestDeepgramBareWord(t *testing.T) {
+	for in, want := range map[string]string{"Hello,": "hello", "world.": "world", "don't": "don't", "¿Qué?": "qué"} {
+		if got := deepgramBareWord(in); got != want {
+			t.Errorf("deepgramBareWord(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRequireDeepgramAuth(t *testing.T) {
+	s := &Server{apiKey: "secret"}
+	h := s.requireDeepgramAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tc := range []struct {
+		header, value string
+		want          int
+	}{
+		{"Authorization", "Token secret", http.StatusOK},
+		{"Authorization", "Bearer secret", http.StatusOK},
+		{"Sec-WebSocket-Protocol", "token, secret", http.StatusOK},
+		{"Authorization", "Token wrong", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest("GET", "/v1/listen", nil)
+		if tc.header != "" {
+			r.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("%s: %q -> %d, want %d", tc.header, tc.value, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestListenPrerecorded(t *testing.T) {
+	s := &Server{cache: newMemoryCache(1), stats: newServerStats(), inflight: newInflightGroup()}
+	raw := make([]byte, 3200)
+	format := asr.PCMFormat{Encoding: asr.PCMLinear16, SampleRate: 16000, Channels: 1}
+	s.cache.Put(s.cacheKey(format.WAV(raw), asr.TranscribeOptions{Format: ".wav", Language: "en", Channels: asr.ChannelMix}), &asr.Result{
+		Text:     "Hello, world.",
+		Duration: 0.1,
+		Channels: 1,
+		Words: []asr.Word{
+			{Start: 0, End: 0.04, Text: "Hello,", Confidence: 0.9},
+			{Start: 0.04, End: 0.08, Text: "world.", Confidence: 0.7},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	s.handleListen(rec, httptest.NewRequest("POST", "/v1/listen?encoding=linear16&sample_rate=16000", bytes.NewReader(raw)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp deepgramResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results.Channels) != 1 {
+		t.Fatalf("channels = %+v", resp.Results.Channels)
+	}
+	alt := resp.Results.Channels[0].Alternatives[0]
+	if alt.Transcript != "Hello, world." || len(alt.Words) != 2 || alt.Words[1].Word != "world" || alt.Words[1].PunctuatedWord != "world." {
+		t.Errorf("alternative = %+v", alt)
+	}
+	if alt.Confidence < 0.79 || alt.Confidence > 0.81 {
+		t.Errorf("confidence = %v, want 0.8", alt.Confidence)
+	}
+	if resp.Metadata.RequestID == "" || resp.Metadata.Channels != 1 || len(resp.Metadata.SHA256) != 64 {
+		t.Errorf("metadata = %+v", resp.Metadata)
+	}
+}
+
+func TestListenLive_SilenceThenCloseStream(t *testing.T) {
+	s := &Server{stats: newServerStats()}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleListen))
+	defer srv.Close()
+
+	c := dialWS(t, srv, "/v1/listen?encoding=linear16&sample_rate=16000", nil)
+	// Two seconds of silence never reach the model.
+	for i := 0; i < 20; i++ {
+		c.send(true, wsBinary, make([]byte, 3200))
+	}
+	c.send(true, wsText, []byte(`{"type":"KeepAlive"}`))
+	c.send(true, wsText, []byte(`{"type":"CloseStream"}`))
+
+	op, data := c.read()
+	if op != wsText {
+		t.Fatalf("got op %#x, want the Metadata message", op)
+	}
+	var meta deepgramLiveMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Type != "Metadata" || meta.Duration != 2 {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if op, _ := c.read(); op != wsClose {
+		t.Errorf("got op %#x, want close", op)
+	}
+}
+
+func TestListenLive_RequiresEncoding(t *testing.T) {
+	s := &Server{}
+	r := httptest.NewRequest("GET", "/v1/listen", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	rec := httptest.NewRecorder()
+	s.handleListen(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxUploadBytes caps request bodies carrying audio, OpenAI's 25 MB.
+const maxUploadBytes = 25 << 20
+
+// responseFormats are the response_format values of the transcription
+// endpoints.
+var responseFormats = []string{"json", "text", "srt", "verbose_json", "vtt", "ass", "ttml", "tsv", "csv", "jsonl", "srt_words", "vtt_words"}
+
+// languageCode matches an ISO-639-1 language code.
+var languageCode = regexp.MustCompile(`^[a-z]{2}$`)
+
+// paramError is a request error caused by one parameter. sendRequestError
+// reports it with OpenAI's param and code fields, which SDKs use to tell
+// a bad request (never retried) from a server failure.
+type paramError struct {
+	param string
+	code  string
+	err   error
+}
+
+func (e *paramError) Error() string { return e.err.Error() }
+func (e *paramError) Unwrap() error { return e.err }
+
+// invalidParam returns a paramError with code invalid_value.
+func invalidParam(param, format string, args ...any) error {
+	return &paramError{param: param, code: "invalid_value", err: fmt.Errorf(format, args...)}
+}
+
+// withParam attributes err, if any, to param.
+func withParam(param string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pe *paramError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &paramError{param: param, code: "invalid_value", err: err}
+}
+
+// sendError writes an OpenAI-compatible error with no param or code.
+func sendError(w http.ResponseWriter, message, errType string, status int) {
+	writeError(w, status, ErrorDetail{Message: message, Type: errType})
+}
+
+// sendRequestError writes err as a 400 invalid_request_error, with the
+// param and code of a paramError.
+func sendRequestError(w http.ResponseWriter, err error) {
+	writeError(w, http.StatusBadRequest, requestError(err))
+}
+
+// requestError is the invalid_request_error body of err.
+func requestError(err error) ErrorDetail {
+	detail := ErrorDetail{Message: err.Error(), Type: "invalid_request_error"}
+	var pe *paramError
+	if errors.As(err, &pe) {
+		detail.Param, detail.Code = &pe.param, &pe.code
+	}
+	return detail
+}
+
+// sendBodyError reports a failure to read or parse the request body: 413
+// past maxUploadBytes, 415 when a form was expected and the body is not
+// multipart, 400 otherwise.
+func sendBodyError(w http.ResponseWriter, err error) {
+	param := "file"
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		code := "file_too_large"
+		writeError(w, http.StatusRequestEntityTooLarge, ErrorDetail{
+			Message: fmt.Sprintf("Maximum content size limit (%d) exceeded", tooLarge.Limit),
+			Type:    "invalid_request_error",
+			Param:   &param,
+			Code:    &code,
+		})
+	case errors.Is(err, http.ErrNotMultipart):
+		sendUnsupportedMediaType(w, "Content-Type must be multipart/form-data")
+	default:
+		sendError(w, "Failed to parse form: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
+	}
+}
+
+// sendUnsupportedMediaType writes a 415 for a request body of the wrong
+// Content-Type.
+func sendUnsupportedMediaType(w http.ResponseWriter, message string) {
+	code := "unsupported_media_type"
+	writeError(w, http.StatusUnsupportedMediaType, ErrorDetail{Message: message, Type: "invalid_request_error", Code: &code})
+}
+
+func writeError(w http.ResponseWriter, status int, detail ErrorDetail) {
+	detail.RequestID = w.Header().Get(requestIDHeader)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: detail})
+}
+
+// parseResponseFormat checks response_format; empty means json.
+func parseResponseFormat(v string) (string, error) {
+	if v == "" {
+		return "json", nil
+	}
+	for _, f := range responseFormats {
+		if v == f {
+			return v, nil
+		}
+	}
+	return "", invalidParam("response_format", "unsupported response_format %q (available: %s)", v, strings.Join(responseFormats, ", "))
+}
+
+// parseLanguage checks language as an ISO-639-1 code, case-insensitively;
+// empty means en.
+func parseLanguage(v string) (string, error) {
+	if v == "" {
+		return "en", nil
+	}
+	lang := strings.ToLower(v)
+	if !languageCode.MatchString(lang) {
+		return "", &paramError{
+			param: "language",
+			code:  "invalid_language_format",
+			err:   fmt.Errorf("invalid language %q: it must be an ISO-639-1 code such as en", v),
+		}
+	}
+	return lang, nil
+}
+
+// languageFor parses language like parseLanguage and rejects the codes the
+// loaded model does not transcribe, instead of decoding them as whatever
+// the model hears.
+func (s *Server) languageFor(v string) (string, error) {
+	lang, err := parseLanguage(v)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkLanguage(lang); err != nil {
+		return "", &paramError{param: "language", code: "unsupported_language", err: err}
+	}
+	return lang, nil
+}
+
+// checkLanguage fails for a language tag the loaded model does not
+// transcribe, naming the ones it does. Only the primary subtag counts.
+func (s *Server) checkLanguage(tag string) error {
+	t := s.transcriber()
+	if t == nil || t.SupportsLanguage(tag) {
+		return nil
+	}
+	return fmt.Errorf("unsupported language %q (the model transcribes: %s)", tag, strings.Join(t.Languages(), ", "))
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+// decodeError returns the error object of an OpenAI-style error response,
+// with param and code as raw JSON so a null can be told from a value.
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) map[string]json.RawMessage {
+	t.Helper()
+	var resp struct {
+		Error map[string]json.RawMessage `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Error
+}
+
+func TestTranscriptionValidation(t *testing.T) {
+	s := &Server{}
+	for _, tc := range []struct {
+		fields map[string]string
+		param  string
+		code   string
+	}{
+		{map[string]string{"response_format": "docx"}, `"response_format"`, `"invalid_value"`},
+		{map[string]string{"language": "english"}, `"language"`, `"invalid_language_format"`},
+		{map[string]string{"temperature": "2"}, `"temperature"`, `"invalid_value"`},
+		{map[string]string{"temperature": "0.5", "beam_size": "4"}, `"temperature"`, `"invalid_value"`},
+		{map[string]string{"beam_size": "99"}, `"beam_size"`, `"invalid_value"`},
+		{map[string]string{"channel_mode": "mono"}, `"channel_mode"`, `"invalid_value"`},
+		{map[string]string{"priority": "urgent"}, `"priority"`, `"invalid_value"`},
+		{map[string]string{"include[]": "words"}, `"include[]"`, `"invalid_value"`},
+	} {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "a.wav")
+		fw.Write([]byte("RIFF"))
+		for k, v := range tc.fields {
+			mw.WriteField(k, v)
+		}
+		mw.Close()
+		r := httptest.NewRequest("POST", "/v1/audio/transcriptions", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		s.handleTranscription(rec, r)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status %d, want 400", tc.fields, rec.Code)
+			continue
+		}
+		e := decodeError(t, rec)
+		if string(e["type"]) != `"invalid_request_error"` || string(e["param"]) != tc.param || string(e["code"]) != tc.code {
+			t.Errorf("%v: error = type %s param %s code %s", tc.fields, e["type"], e["param"], e["code"])
+		}
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	s := &Server{}
+
+	// Oversized multipart upload.
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "a.wav")
+	fw.Write(make([]byte, maxUploadBytes+1))
+	mw.Close()
+	r := httptest.NewRequest("POST", "/v1/audio/transcriptions", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s.handleTranscription(rec, r)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized multipart: status %d, want 413", rec.Code)
+	}
+	if e := decodeError(t, rec); string(e["param"]) != `"file"` || string(e["code"]) != `"file_too_large"` {
+		t.Errorf("oversized multipart: error = %v", e)
+	}
+
+	// Oversized raw body.
+	r = httptest.NewRequest("POST", "/v1/audio/transcriptions", bytes.NewReader(make([]byte, maxUploadBytes+1)))
+	r.Header.Set("Content-Type", "audio/wav")
+	rec = httptest.NewRecorder()
+	s.handleTranscription(rec, r)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized raw body: status %d, want 413", rec.Code)
+	}
+
+	// A body that is neither a form nor audio.
+	r = httptest.NewRequest("POST", "/v1/audio/transcriptions", strings.NewReader(`{"file": "a.wav"}`))
+	r.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	s.handleTranscription(rec, r)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("json body: status %d, want 415", rec.Code)
+	}
+	if e := decodeError(t, rec); string(e["param"]) != "null" || string(e["code"]) != `"unsupported_media_type"` {
+		t.Errorf("json body: error = %v", e)
+	}
+
+	// /v1/audio/vad only takes forms.
+	r = httptest.NewRequest("POST", "/v1/audio/vad", strings.NewReader("RIFF"))
+	r.Header.Set("Content-Type", "audio/wav")
+	rec = httptest.NewRecorder()
+	s.handleVAD(rec, r)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("vad raw body: status %d, want 415", rec.Code)
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	for in, want := range map[string]string{"": "en", "es": "es", "DE": "de"} {
+		if got, err := parseLanguage(in); err != nil || got != want {
+			t.Errorf("parseLanguage(%q) = %q, %v", in, got, err)
+		}
+	}
+	for _, bad := range []string{"english", "en-US", "e", "12"} {
+		if _, err := parseLanguage(bad); err == nil {
+			t.Errorf("parseLanguage(%q) accepted", bad)
+		}
+	}
+}
+
+func TestLanguageFor(t *testing.T) {
+	s := &Server{}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+	for in, want := range map[string]string{"": "en", "DE": "de", "uk": "uk"} {
+		if got, err := s.languageFor(in); err != nil || got != want {
+			t.Errorf("languageFor(%q) = %q, %v", in, got, err)
+		}
+	}
+	_, err := s.languageFor("ja")
+	var pe *paramError
+	if !errors.As(err, &pe) || pe.code != "unsupported_language" || !strings.Contains(err.Error(), "bg, cs, da") {
+		t.Errorf("languageFor(ja) = %v", err)
+	}
+	if err := s.checkLanguage("de-AT"); err != nil {
+		t.Errorf("de-AT: %v", err)
+	}
+	if err := s.checkLanguage("zh_cn"); err == nil {
+		t.Error("zh_cn accepted")
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleModels(rec, httptest.NewRequest("GET", "/v1/models", nil))
+	var models ModelsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&models); err != nil {
+		t.Fatal(err)
+	}
+	if got := models.Data[0].Languages; len(got) != 25 || got[0] != "bg" {
+		t.Errorf("/v1/models languages = %v", got)
+	}
+}
+
+func TestRawAudioContentType(t *testing.T) {
+	for ct, want := range map[string]bool{
+		"":                                  true,
+		"audio/wav":                         true,
+		"audio/ogg; codecs=opus":            true,
+		"video/mp4":                         true,
+		"application/octet-stream":          true,
+		"application/json":                  false,
+		"application/x-www-form-urlencoded": false,
+		"text/plain":                        false,
+	} {
+		if got := rawAudioContentType(ct); got != want {
+			t.Errorf("rawAudioContentType(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
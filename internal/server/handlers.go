@@ -4,27 +4,53 @@
 package server
 
 import (
-	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"parakeet/internal/asr"
+	"parakeet/internal/itn"
 )
 
-// handleHealth returns the server health status
+// handleHealth returns the server health status along with the version and
+// execution provider, enough to spot a mismatched instance from a health check
+// without calling /version.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	info := s.transcriber().Info()
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(HealthResponse{
+		Status:             "ok",
+		Version:            s.config.Build.Version,
+		Commit:             s.config.Build.Commit,
+		ONNXRuntimeVersion: info.ONNXRuntimeVersion,
+		Provider:           string(info.Provider),
+	})
+}
+
+// modelIDs are the model names the API answers to: the loaded model's name
+// (config.json "name") and the -model-aliases.
+func (s *Server) modelIDs() []string {
+	ids := []string{s.transcriber().Info().Name}
+	for _, a := range s.aliases {
+		ids = append(ids, a.name)
+	}
+	return ids
 }
 
-// handleModels returns the list of available models
+// handleModels returns the list of available models, described from the
+// loaded model: its name, the modification time of its files as the
+// creation time, its parameter count, precision, languages and
+// capabilities.
 func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 	setCORSHeaders(w)
 	if r.Method == "OPTIONS" {
@@ -33,32 +59,41 @@ func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	ids := s.modelIDs()
 	resp := ModelsResponse{
 		Object: "list",
-		Data: []ModelInfo{
-			{
-				ID:      "parakeet-tdt-0.6b",
-				Object:  "model",
-				Created: 1700000000,
-				OwnedBy: "nvidia",
-			},
-			{
-				ID:      "whisper-1", // Alias for compatibility
-				Object:  "model",
-				Created: 1700000000,
-				OwnedBy: "nvidia",
-			},
-		},
+		Data:   make([]ModelInfo, 0, len(ids)),
+	}
+	info := s.transcriber().Info()
+	var created int64
+	if !info.Modified.IsZero() {
+		created = info.Modified.Unix()
+	}
+	capabilities := s.modelCapabilities()
+	var precision *ModelPrecision
+	if p := info.Precision; p != (asr.PrecisionConfig{}) {
+		precision = &ModelPrecision{Encoder: string(p.Encoder), Decoder: string(p.Decoder)}
+	}
+	for i, id := range ids {
+		var root string
+		if i > 0 {
+			root = info.Name
+		}
+		resp.Data = append(resp.Data, ModelInfo{
+			ID:           id,
+			Root:         root,
+			Object:       "model",
+			Created:      created,
+			OwnedBy:      "nvidia",
+			Parameters:   info.Parameters,
+			Precision:    precision,
+			Languages:    info.Languages,
+			Capabilities: capabilities,
+		})
 	}
 	json.NewEncoder(w).Encode(resp)
 }
 
-// handleTranslation handles translation requests (delegates to transcription for now)
-func (s *Server) handleTranslation(w http.ResponseWriter, r *http.Request) {
-	// Translation endpoint - for now just transcribe (Parakeet is English-focused)
-	s.handleTranscription(w, r)
-}
-
 // handleTranscription routes to either multipart or streaming handler based on Content-Type
 func (s *Server) handleTranscription(w http.ResponseWriter, r *http.Request) {
 	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
@@ -83,15 +118,18 @@ func (s *Server) handleMultipartTranscription(w http.ResponseWriter, r *http.Req
 	}
 
 	// Parse multipart form (25MB max like OpenAI)
-	if err := r.ParseMultipartForm(25 << 20); err != nil {
-		sendError(w, "Failed to parse form: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
+	if r.MultipartForm == nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	}
+	if err := r.ParseMultipartForm(s.multipartMemory()); err != nil {
+		sendBodyError(w, err)
 		return
 	}
 
 	// Get audio file (required)
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		sendError(w, "Missing required parameter: 'file'", "invalid_request_error", http.StatusBadRequest)
+		sendRequestError(w, &paramError{param: "file", code: "missing_required_parameter", err: errors.New("Missing required parameter: 'file'")})
 		return
 	}
 	defer file.Close()
@@ -103,113 +141,557 @@ func (s *Server) handleMultipartTranscription(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// OpenAI parameters
-	model := r.FormValue("model")                    // ignored - we only have one model
-	language := r.FormValue("language")              // ISO-639-1 code
-	prompt := r.FormValue("prompt")                  // ignored for now
-	responseFormat := r.FormValue("response_format") // json, text, srt, verbose_json, vtt
-	temperature := r.FormValue("temperature")        // ignored
-	streamRequested := parseBool(r.FormValue("stream"))
-
-	_ = model       // Accept but ignore
-	_ = prompt      // Accept but ignore
-	_ = temperature // Accept but ignore
-
-	// Default response format
-	if responseFormat == "" {
-		responseFormat = "json"
-	}
-
-	// Default language
-	if language == "" {
-		language = "en"
+	s.noteModel(r.Context(), r.FormValue("model"))
+	noteUpload(r.Context(), header.Filename)
+	req, err := s.parseTranscriptionRequest(r)
+	if err != nil {
+		sendRequestError(w, err)
+		return
 	}
 
-	slog.Info("transcribing",
+	slog.InfoContext(r.Context(), "transcribing",
 		"file", header.Filename,
 		"bytes", len(audioData),
-		"language", language,
-		"format", responseFormat,
+		"language", req.language,
+		"format", req.responseFormat,
+		"channel_mode", req.opts.Channels,
 	)
 
 	// Determine audio format from extension
-	ext := strings.ToLower(filepath.Ext(header.Filename))
+	opts := req.opts
+	opts.Format = strings.ToLower(filepath.Ext(header.Filename))
 
 	// Streaming path: emit SSE transcript.text.delta events as the decoder
 	// produces text, then a final transcript.text.done. Only json/text
 	// formats are streamable; others fall through to the buffered path.
-	if streamRequested && (responseFormat == "json" || responseFormat == "text") {
-		s.streamTranscription(w, r, audioData, ext, language)
+	if req.stream && (req.responseFormat == "json" || req.responseFormat == "text") {
+		if opts.Channels == asr.ChannelPerChannel {
+			sendRequestError(w, invalidParam("channel_mode", "channel_mode=per_channel cannot be combined with stream=true"))
+			return
+		}
+		s.streamTranscription(w, r, audioData, opts, req.include)
+		return
+	}
+
+	// Progress path: Accept: text/event-stream without stream=true reports
+	// the percentage decoded and the text so far, then the full response in
+	// any format. Meant for progress bars on long uploads.
+	if wantsEventStream(r) {
+		s.progressTranscription(w, r, audioData, opts, req.responseFormat, req.language, req.layout, req.offset)
 		return
 	}
 
 	// Transcribe
-	text, err := s.transcriber.Transcribe(r.Context(), audioData, ext, language)
+	result, cached, err := s.transcribe(r.Context(), audioData, opts)
 	if err != nil {
 		// Unsupported or malformed audio is a client error: the request
 		// body we received cannot be decoded. Everything else is treated
 		// as an internal failure.
-		if errors.Is(err, asr.ErrUnsupportedAudio) {
-			sendError(w, "Unsupported or malformed audio: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
-			return
-		}
-		sendError(w, "Transcription failed: "+err.Error(), "server_error", http.StatusInternalServerError)
+		s.writeTranscribeError(w, err)
 		return
 	}
 
 	if asr.DebugMode {
-		slog.Debug("transcription result", "text", text)
+		slog.DebugContext(r.Context(), "transcription result", "text", result.Text, "cached", cached)
 	}
+	ppStart := time.Now()
+	if result, err = s.postprocess(r.Context(), req.pp, result, req.language); err != nil {
+		sendError(w, "Post-processing failed: "+err.Error(), "server_error", http.StatusBadGateway)
+		return
+	}
+	s.setCacheHeader(w, cached)
+	setTruncatedHeader(w, result)
+	setSuspectHeader(w, result)
+	contentType, body := req.render(result, cached, time.Since(ppStart))
+	writeRendered(w, contentType, body)
+}
 
-	// Calculate approximate duration (16kHz, 16-bit mono)
-	duration := float64(len(audioData)) / (16000.0 * 2)
+// transcriptionRequest is the form parameters of a transcription request,
+// everything but the file.
+type transcriptionRequest struct {
+	// opts has all the decoding options but Format, which goes by the
+	// file.
+	opts           asr.TranscribeOptions
+	responseFormat string
+	language       string
+	stream         bool
+	layout         cueLayout
+	pp             postprocessRequest
+	include        includeRequest
+	offset         float64
+}
 
-	// Send response based on format
+// parseTranscriptionRequest reads the parameters of a multipart
+// transcription request, whose form is already parsed.
+func (s *Server) parseTranscriptionRequest(r *http.Request) (transcriptionRequest, error) {
+	req := transcriptionRequest{stream: parseBool(r.FormValue("stream"))}
+	_ = r.FormValue("prompt") // Accept but ignore
+	var err error
+	if req.responseFormat, err = parseResponseFormat(r.FormValue("response_format")); err != nil {
+		return req, err
+	}
+	if req.language, err = s.languageFor(r.FormValue("language")); err != nil {
+		return req, err
+	}
+	opts := asr.TranscribeOptions{Language: req.language, Denoise: s.config.Denoise}
+	if opts.Channels, err = asr.ParseChannelMode(r.FormValue("channel_mode")); err != nil {
+		return req, withParam("channel_mode", err)
+	}
+	if opts.Priority, err = asr.ParsePriority(r.FormValue("priority")); err != nil {
+		return req, withParam("priority", err)
+	}
+	if opts.MaxProcessing, err = s.maxProcessingFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.Conditioning, err = s.conditioningFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.Segmentation, err = s.segmentationFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.NoSpeechThreshold, err = s.noSpeechThresholdFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.Guard, err = s.guardFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.Confidence, err = s.confidenceFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.Formatting, err = s.formattingFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.Fallback, err = s.fallbackFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if req.offset, err = offsetFor(r.FormValue); err != nil {
+		return req, err
+	}
+	if v := r.FormValue("denoise"); v != "" {
+		opts.Denoise = parseBool(v)
+	}
+	if req.layout, err = parseCueLayout(r.FormValue); err != nil {
+		return req, err
+	}
+	if opts.Decoding, err = parseDecoding(r.FormValue); err != nil {
+		return req, err
+	}
+	req.opts = opts
+
+	if req.pp, err = s.parsePostprocess(r.FormValue, req.responseFormat); err != nil {
+		return req, err
+	}
+	if req.pp.llm && (req.stream || wantsEventStream(r)) {
+		return req, invalidParam("postprocess", "postprocess=llm cannot be combined with stream=true or progress events")
+	}
+	if req.include, err = parseInclude(r.MultipartForm, req.responseFormat); err != nil {
+		return req, err
+	}
+	if req.include.logprobs && !req.stream && wantsEventStream(r) {
+		return req, invalidParam("include[]", "include[]=logprobs cannot be combined with progress events")
+	}
+	if req.include.timings && wantsEventStream(r) {
+		return req, invalidParam("include[]", "include[]=timings cannot be combined with progress events")
+	}
+	return req, nil
+}
+
+// render formats a post-processed result for req: shifted by
+// offset_seconds, with the logprobs and timings include[] asks for.
+// postprocess is the time the server's own post-processing took.
+func (req transcriptionRequest) render(result *asr.Result, cached bool, postprocess time.Duration) (contentType string, body any) {
+	// The offset is applied to this response only: the cache and the
+	// transcript history keep the times of the audio as sent.
+	result = result.Shifted(req.offset)
+	contentType, body = renderTranscription(result, req.responseFormat, req.language, req.layout)
+	body = withLogprobs(body, result, req.include)
+	if req.include.timings && !cached {
+		// A cached result was decoded by another request; it has no
+		// timings of this one.
+		body = withTimings(body, result, postprocess)
+	}
+	return contentType, body
+}
+
+// renderTranscription formats a result in one of the OpenAI response
+// formats, the further subtitle formats (ass, ttml, and srt_words and
+// vtt_words with word timings) or whisper's segment tables (tsv, csv,
+// jsonl). body is a string for all but json and
+// verbose_json, which return a response struct to be JSON-encoded.
+// Subtitle cues follow layout.
+func renderTranscription(result *asr.Result, responseFormat, language string, layout cueLayout) (contentType string, body any) {
+	text := result.Text
+	if subtitleFormats[responseFormat] {
+		result = layout.apply(result)
+	}
 	switch responseFormat {
 	case "text":
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte(text))
+		return "text/plain", text
 
 	case "srt":
-		w.Header().Set("Content-Type", "text/plain")
-		// Simple SRT format
-		srt := fmt.Sprintf("1\n00:00:00,000 --> %s\n%s\n", formatSRTTime(duration), text)
-		w.Write([]byte(srt))
+		var sb strings.Builder
+		for i, seg := range result.Segments {
+			fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, formatSRTTime(seg.Start), formatSRTTime(seg.End), cueText(result, seg))
+		}
+		return "text/plain", sb.String()
 
 	case "vtt":
-		w.Header().Set("Content-Type", "text/vtt")
-		// Simple WebVTT format
-		vtt := fmt.Sprintf("WEBVTT\n\n00:00:00.000 --> %s\n%s\n", formatVTTTime(duration), text)
-		w.Write([]byte(vtt))
+		var sb strings.Builder
+		sb.WriteString("WEBVTT\n\n")
+		for _, seg := range result.Segments {
+			fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", formatVTTTime(seg.Start), formatVTTTime(seg.End), cueText(result, seg))
+		}
+		return "text/vtt", sb.String()
+
+	case "srt_words":
+		var sb strings.Builder
+		n := 0
+		for _, seg := range result.Segments {
+			for _, w := range segmentWords(result, seg) {
+				n++
+				fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", n, formatSRTTime(w.start), formatSRTTime(w.end), cueText(result, asr.Segment{Channel: seg.Channel, Text: w.text}))
+			}
+		}
+		return "text/plain", sb.String()
+
+	case "vtt_words":
+		var sb strings.Builder
+		sb.WriteString("WEBVTT\n\n")
+		for _, c := range layout.cues(result) {
+			fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", formatVTTTime(c.words[0].start), formatVTTTime(c.words[len(c.words)-1].end), layout.karaoke(result, c))
+		}
+		return "text/vtt", sb.String()
+
+	case "ass":
+		var sb strings.Builder
+		sb.WriteString(assHeader)
+		for _, seg := range result.Segments {
+			fmt.Fprintf(&sb, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", formatASSTime(seg.Start), formatASSTime(seg.End), assText(cueText(result, seg)))
+		}
+		return "text/x-ssa", sb.String()
+
+	case "ttml":
+		var sb strings.Builder
+		fmt.Fprintf(&sb, ttmlHeader, ttmlAttr(language))
+		for _, seg := range result.Segments {
+			fmt.Fprintf(&sb, "      <p begin=\"%s\" end=\"%s\">%s</p>\n", formatVTTTime(seg.Start), formatVTTTime(seg.End), ttmlText(cueText(result, seg)))
+		}
+		sb.WriteString(ttmlFooter)
+		return "application/ttml+xml", sb.String()
+
+	case "tsv":
+		// whisper's layout: integer milliseconds, tabs in the text replaced.
+		var sb strings.Builder
+		sb.WriteString("start\tend\ttext\n")
+		for _, seg := range result.Segments {
+			fmt.Fprintf(&sb, "%d\t%d\t%s\n", millis(seg.Start), millis(seg.End), strings.ReplaceAll(cueText(result, seg), "\t", " "))
+		}
+		return "text/tab-separated-values", sb.String()
+
+	case "csv":
+		var sb strings.Builder
+		cw := csv.NewWriter(&sb)
+		cw.Write([]string{"start", "end", "text"})
+		for _, seg := range result.Segments {
+			cw.Write([]string{strconv.Itoa(millis(seg.Start)), strconv.Itoa(millis(seg.End)), cueText(result, seg)})
+		}
+		cw.Flush()
+		return "text/csv", sb.String()
+
+	case "jsonl":
+		var sb strings.Builder
+		enc := json.NewEncoder(&sb)
+		for _, seg := range result.Segments {
+			line := SegmentLine{Start: seg.Start, End: seg.End, Text: seg.Text}
+			if result.Channels > 1 {
+				ch := seg.Channel
+				line.Channel = &ch
+			}
+			enc.Encode(line)
+		}
+		return "application/jsonl", sb.String()
 
 	case "verbose_json":
-		w.Header().Set("Content-Type", "application/json")
 		resp := VerboseTranscriptionResponse{
-			Task:     "transcribe",
-			Language: language,
-			Duration: duration,
-			Text:     text,
-			Segments: []Segment{
-				{
-					ID:               0,
-					Seek:             0,
-					Start:            0,
-					End:              duration,
-					Text:             text,
-					Tokens:           []int{},
-					Temperature:      0,
-					AvgLogprob:       -0.5,
-					CompressionRatio: 1.0,
-					NoSpeechProb:     0.0,
-				},
-			},
+			Task:      "transcribe",
+			Language:  language,
+			Duration:  result.Duration,
+			Text:      text,
+			Segments:  make([]Segment, 0, len(result.Segments)),
+			Truncated: result.Truncated,
 		}
-		json.NewEncoder(w).Encode(resp)
+		for i, seg := range result.Segments {
+			out := Segment{
+				ID:               i,
+				Seek:             0,
+				Start:            seg.Start,
+				End:              seg.End,
+				Text:             seg.Text,
+				Tokens:           []int{},
+				Temperature:      seg.Temperature,
+				CompressionRatio: asr.CompressionRatio(seg.Text),
+				NoSpeechProb:     seg.NoSpeechProb,
+				Suspect:          seg.Suspect,
+			}
+			if avg, ok := result.AvgLogprob(seg); ok {
+				out.AvgLogprob = &avg
+			}
+			if result.Channels > 1 {
+				ch := seg.Channel
+				out.Channel = &ch
+			}
+			resp.Segments = append(resp.Segments, out)
+		}
+		return "application/json", resp
 
 	default: // "json"
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(TranscriptionResponse{Text: text})
+		return "application/json", TranscriptionResponse{Text: text, Truncated: result.Truncated}
+	}
+}
+
+// writeTranscription writes a result as the HTTP response body in the
+// requested format.
+func writeTranscription(w http.ResponseWriter, result *asr.Result, responseFormat, language string, layout cueLayout) {
+	contentType, body := renderTranscription(result, responseFormat, language, layout)
+	writeRendered(w, contentType, body)
+}
+
+// writeRendered writes a body from renderTranscription.
+func writeRendered(w http.ResponseWriter, contentType string, body any) {
+	w.Header().Set("Content-Type", contentType)
+	if text, ok := body.(string); ok {
+		w.Write([]byte(text))
+		return
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// cueText is the subtitle text of one segment: prefixed with its channel
+// label when the result merges several channels.
+func cueText(result *asr.Result, seg asr.Segment) string {
+	if result.Channels > 1 {
+		return asr.ChannelLabel(seg.Channel) + " " + seg.Text
+	}
+	return seg.Text
+}
+
+// conditioningFor overlays the per-request remove_dc, normalize_gain and
+// trim_silence parameters on the server defaults. get reads one parameter
+// (form field or query string); parameters that are absent keep the default.
+func (s *Server) conditioningFor(get func(string) string) (asr.Conditioning, error) {
+	c := s.conditioning
+	if v := get("remove_dc"); v != "" {
+		c.RemoveDC = parseBool(v)
+	}
+	if v := get("normalize_gain"); v != "" {
+		gain, err := asr.ParseGainMode(v)
+		if err != nil {
+			return c, withParam("normalize_gain", err)
+		}
+		c.Gain = gain
+	}
+	if v := get("trim_silence"); v != "" {
+		c.TrimSilence = parseBool(v)
+	}
+	return c, nil
+}
+
+// segmentationFor overlays the per-request segment_pause,
+// segment_sentences and segment_max_duration (seconds) parameters on the
+// server defaults. get reads one parameter; absent ones keep the default.
+func (s *Server) segmentationFor(get func(string) string) (asr.Segmentation, error) {
+	seg := s.segmentation
+	for _, p := range []struct {
+		name string
+		to   *float64
+	}{{"segment_pause", &seg.Pause}, {"segment_max_duration", &seg.MaxDuration}} {
+		v := get(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(d >= 0) || math.IsInf(d, 1) {
+			return seg, invalidParam(p.name, "invalid %s %q (seconds, 0 to disable)", p.name, v)
+		}
+		*p.to = d
+	}
+	if v := get("segment_sentences"); v != "" {
+		seg.Sentences = parseBool(v)
+	}
+	return seg, nil
+}
+
+// noSpeechThresholdFor reads no_speech_threshold with get, falling back to
+// -no-speech-threshold. Zero keeps every segment.
+func (s *Server) noSpeechThresholdFor(get func(string) string) (float64, error) {
+	v := get("no_speech_threshold")
+	if v == "" {
+		return s.config.NoSpeechThreshold, nil
+	}
+	p, err := strconv.ParseFloat(v, 64)
+	if err != nil || !(p >= 0 && p <= 1) {
+		return 0, invalidParam("no_speech_threshold", "invalid no_speech_threshold %q (a probability from 0 to 1, 0 to keep every segment)", v)
+	}
+	return p, nil
+}
+
+// fallbackFor overlays compression_ratio_threshold and logprob_threshold,
+// read with get, on the server's decoding fallback.
+func (s *Server) fallbackFor(get func(string) string) (asr.Fallback, error) {
+	f := s.fallback
+	if v := get("compression_ratio_threshold"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(r >= 0) {
+			return f, invalidParam("compression_ratio_threshold", "invalid compression_ratio_threshold %q (a ratio such as 2.4, 0 for off)", v)
+		}
+		f.CompressionRatio = r
+	}
+	if v := get("logprob_threshold"); v != "" {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(p <= 0) {
+			return f, invalidParam("logprob_threshold", "invalid logprob_threshold %q (a negative log-probability such as -1, 0 for off)", v)
+		}
+		f.Logprob = p
 	}
+	return f, nil
+}
+
+// guardFor overlays the hallucination_guard mode read with get on the
+// server's guard.
+func (s *Server) guardFor(get func(string) string) (asr.HallucinationGuard, error) {
+	g := s.guard
+	if v := get("hallucination_guard"); v != "" {
+		mode, err := asr.ParseGuardMode(v)
+		if err != nil {
+			return g, withParam("hallucination_guard", err)
+		}
+		g.Mode = mode
+	}
+	return g, nil
+}
+
+// confidenceFor overlays min_confidence and confidence_placeholder, read
+// with get, on -min-confidence and -confidence-placeholder.
+func (s *Server) confidenceFor(get func(string) string) (asr.ConfidenceFilter, error) {
+	f := s.confidence
+	if v := get("min_confidence"); v != "" {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(p >= 0 && p <= 1) {
+			return f, invalidParam("min_confidence", "invalid min_confidence %q (a probability from 0 to 1, 0 to keep every word)", v)
+		}
+		f.Min = p
+	}
+	if v := get("confidence_placeholder"); v != "" {
+		f.Placeholder = v
+	}
+	return f, nil
+}
+
+// formattingFor overlays the formatting parameter, read with get, on
+// -formatting: the kinds it names replace the default's, the others keep it.
+func (s *Server) formattingFor(get func(string) string) (itn.Options, error) {
+	v := get("formatting")
+	if v == "" {
+		return s.formatting, nil
+	}
+	over, err := itn.Parse(v)
+	if err != nil {
+		return s.formatting, invalidParam("formatting", "invalid formatting %q: %v", v, err)
+	}
+	return s.formatting.Merge(over), nil
+}
+
+// maxProcessingFor reads the max_processing_ms parameter with get, falling
+// back to -max-processing. Zero lifts the limit.
+func (s *Server) maxProcessingFor(get func(string) string) (time.Duration, error) {
+	v := get("max_processing_ms")
+	if v == "" {
+		return s.config.MaxProcessing, nil
+	}
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms < 0 {
+		return 0, invalidParam("max_processing_ms", "invalid max_processing_ms %q (milliseconds, 0 for no limit)", v)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// offsetFor reads the offset_seconds parameter with get: where the audio
+// starts in a longer recording the client cut it from, added to every
+// timestamp of the response. It is 0 when absent.
+func offsetFor(get func(string) string) (float64, error) {
+	v := get("offset_seconds")
+	if v == "" {
+		return 0, nil
+	}
+	offset, err := strconv.ParseFloat(v, 64)
+	if err != nil || offset < 0 || math.IsInf(offset, 0) || math.IsNaN(offset) {
+		return 0, invalidParam("offset_seconds", "invalid offset_seconds %q (seconds, 0 or more)", v)
+	}
+	return offset, nil
+}
+
+// setTruncatedHeader flags a transcript cut short by max_processing_ms, for
+// response formats that have no field to say so.
+func setTruncatedHeader(w http.ResponseWriter, result *asr.Result) {
+	if result.Truncated {
+		w.Header().Set("X-Transcript-Truncated", "true")
+	}
+}
+
+// setSuspectHeader lists the reasons the hallucination guard found for
+// suspect segments, for response formats that cannot carry them.
+func setSuspectHeader(w http.ResponseWriter, result *asr.Result) {
+	var reasons []string
+	for _, seg := range result.Segments {
+		for _, reason := range seg.Suspect {
+			if !slices.Contains(reasons, reason) {
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	if len(reasons) > 0 {
+		w.Header().Set("X-Transcript-Suspect", strings.Join(reasons, ","))
+	}
+}
+
+// parseDecoding reads the decoding overrides max_tokens_per_step,
+// blank_penalty, beam_size and temperature with get and checks them against
+// the asr bounds. Errors name the parameter at fault.
+func parseDecoding(get func(string) string) (asr.DecodingOptions, error) {
+	var d asr.DecodingOptions
+	var err error
+	if v := get("max_tokens_per_step"); v != "" {
+		if d.MaxTokensPerStep, err = strconv.Atoi(v); err != nil || d.MaxTokensPerStep < 1 {
+			return d, invalidParam("max_tokens_per_step", "invalid max_tokens_per_step %q (between 1 and %d)", v, asr.MaxTokensPerStepLimit)
+		}
+		if err := (asr.DecodingOptions{MaxTokensPerStep: d.MaxTokensPerStep}).Validate(); err != nil {
+			return d, withParam("max_tokens_per_step", err)
+		}
+	}
+	if v := get("blank_penalty"); v != "" {
+		if d.BlankPenalty, err = strconv.ParseFloat(v, 64); err != nil {
+			return d, invalidParam("blank_penalty", "invalid blank_penalty %q (a number)", v)
+		}
+		if err := (asr.DecodingOptions{BlankPenalty: d.BlankPenalty}).Validate(); err != nil {
+			return d, withParam("blank_penalty", err)
+		}
+	}
+	if v := get("beam_size"); v != "" {
+		if d.BeamSize, err = strconv.Atoi(v); err != nil || d.BeamSize < 1 {
+			return d, invalidParam("beam_size", "invalid beam_size %q (between 1 and %d)", v, asr.MaxBeamSize)
+		}
+		if err := (asr.DecodingOptions{BeamSize: d.BeamSize}).Validate(); err != nil {
+			return d, withParam("beam_size", err)
+		}
+	}
+	if v := get("temperature"); v != "" {
+		if d.Temperature, err = strconv.ParseFloat(v, 64); err != nil {
+			return d, invalidParam("temperature", "invalid temperature %q (a number)", v)
+		}
+	}
+	// Only temperature is left unchecked: its range, and the beam_size it
+	// cannot be combined with.
+	return d, withParam("temperature", d.Validate())
 }
 
 // parseBool interprets common truthy form values ("true", "1", "yes", "on").
@@ -225,120 +707,117 @@ func parseBool(v string) bool {
 // client as Server-Sent Events, following OpenAI's streaming transcription
 // protocol: a series of transcript.text.delta events followed by a single
 // transcript.text.done event carrying the full transcript.
-func (s *Server) streamTranscription(w http.ResponseWriter, r *http.Request, audioData []byte, ext, language string) {
-	flusher, ok := w.(http.Flusher)
-	if !ok {
+func (s *Server) streamTranscription(w http.ResponseWriter, r *http.Request, audioData []byte, opts asr.TranscribeOptions, include includeRequest) {
+	if _, ok := w.(http.Flusher); !ok {
 		// The ResponseWriter cannot stream; degrade gracefully to a buffered
 		// JSON response so the client still gets a valid result.
-		text, err := s.transcriber.Transcribe(r.Context(), audioData, ext, language)
+		result, cached, err := s.transcribe(r.Context(), audioData, opts)
 		if err != nil {
 			s.writeTranscribeError(w, err)
 			return
 		}
+		s.setCacheHeader(w, cached)
+		setTruncatedHeader(w, result)
+		setSuspectHeader(w, result)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(TranscriptionResponse{Text: text})
+		json.NewEncoder(w).Encode(withLogprobs(TranscriptionResponse{Text: result.Text, Truncated: result.Truncated}, result, include))
 		return
 	}
 
-	// SSE headers must be set before the first write / WriteHeader.
-	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	// Disable proxy buffering (nginx) so events reach the client immediately.
-	w.Header().Set("X-Accel-Buffering", "no")
-	w.WriteHeader(http.StatusOK)
-	flusher.Flush()
-
-	// ResponseController lets us set a per-write deadline. This is what makes
-	// a slow/stalled reader recoverable: if the client stops draining its TCP
-	// receive window, the write below fails instead of blocking forever inside
-	// the decoder goroutine (which holds a worker). On failure we cancel the
-	// context so the decoder stops and releases its worker. We deliberately do
-	// NOT use a global http.Server WriteTimeout, which would kill healthy long
-	// streams; the deadline is reset before every event instead.
-	rc := http.NewResponseController(w)
-	const writeDeadline = 30 * time.Second
-
-	// Derive a cancelable context: if a write to the client fails (disconnect,
-	// broken pipe, stalled reader past the deadline), we cancel so the decoder
-	// stops promptly and releases its worker instead of computing into the void.
-	ctx, cancel := context.WithCancel(r.Context())
+	// A cached transcript is replayed as a single delta, so clients see the
+	// same event sequence as for a live decode.
+	var key string
+	var cached *asr.Result
+	if s.cache != nil {
+		key = s.cacheKey(audioData, opts)
+		cached, _ = s.cache.Get(key)
+	}
+	s.setCacheHeader(w, cached != nil)
+
+	stream, ctx, cancel := startSSE(w, r)
 	defer cancel()
 
-	// writeEvent serializes one SSE frame: "event: <type>\ndata: <json>\n\n".
-	// Each event is marshaled independently so a mid-write failure can never
-	// corrupt a subsequent frame. Returns false on write failure.
-	writeEvent := func(eventType string, v interface{}) bool {
-		payload, err := json.Marshal(v)
-		if err != nil {
-			return false
+	if cached != nil {
+		s.stats.cacheHit()
+		noteAudio(r.Context(), cached.Duration)
+		s.recordTranscript(r.Context(), audioData, opts, cached, true, 0)
+		if cached.Text != "" {
+			stream.send("transcript.text.delta", StreamDeltaEvent{Type: "transcript.text.delta", Delta: cached.Text})
 		}
-		// Bound how long a single event may take to flush to the client. A
-		// reader that has stalled will trip this deadline and the write fails,
-		// freeing the worker (slow-reader DoS mitigation).
-		_ = rc.SetWriteDeadline(time.Now().Add(writeDeadline))
-		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, payload); err != nil {
-			cancel()
-			return false
-		}
-		if err := rc.Flush(); err != nil {
-			cancel()
-			return false
-		}
-		return true
+		stream.send("transcript.text.done", doneEvent(cached, include))
+		return
 	}
 
-	text, err := s.transcriber.TranscribeStream(ctx, audioData, ext, language, func(delta string) {
-		writeEvent("transcript.text.delta", StreamDeltaEvent{Type: "transcript.text.delta", Delta: delta})
+	start := time.Now()
+	result, err := s.transcribeAudio(ctx, audioData, opts, func(delta string) {
+		stream.send("transcript.text.delta", StreamDeltaEvent{Type: "transcript.text.delta", Delta: delta})
 	})
 	if err != nil {
-		// Headers (200 OK) are already sent, so we cannot switch to an HTTP
-		// error status. Client cancellation needs no payload (nobody is
-		// listening); any other failure is surfaced as a terminal SSE error.
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return
-		}
-		msg := "Transcription failed: " + err.Error()
-		errType := "server_error"
-		if errors.Is(err, asr.ErrUnsupportedAudio) {
-			msg = "Unsupported or malformed audio: " + err.Error()
-			errType = "invalid_request_error"
-		}
-		writeEvent("error", ErrorResponse{Error: ErrorDetail{Message: msg, Type: errType}})
+		stream.sendError(err)
 		return
 	}
 
-	writeEvent("transcript.text.done", StreamDoneEvent{Type: "transcript.text.done", Text: text})
+	s.stats.decode(result.Duration, time.Since(start))
+	if s.cache != nil && !result.Truncated && !s.canary.decoded(result) {
+		s.cache.Put(key, result)
+	}
+	noteAudio(r.Context(), result.Duration)
+	s.recordTranscript(r.Context(), audioData, opts, result, false, time.Since(start))
+	stream.send("transcript.text.done", doneEvent(result, include))
+}
+
+// doneEvent is the transcript.text.done event closing a stream of result.
+func doneEvent(result *asr.Result, include includeRequest) StreamDoneEvent {
+	ev := StreamDoneEvent{Type: "transcript.text.done", Text: result.Text, Truncated: result.Truncated}
+	if include.logprobs {
+		ev.Logprobs = tokenLogprobs(result)
+	}
+	return ev
 }
 
 // writeTranscribeError maps a transcription error to an OpenAI-compatible HTTP
 // error response. Only safe to call before any body has been written.
 func (s *Server) writeTranscribeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, asr.ErrQueueFull) {
+		setRetryAfter(w)
+	}
+	status, detail := transcribeError(err)
+	writeError(w, status, detail)
+}
+
+// transcribeError is the status and error body a transcription error is
+// answered with: 400 for audio the request got wrong, 503 when the server
+// is shutting down or overloaded, 413 for audio too long for the
+// -memory-budget, 500 otherwise.
+func transcribeError(err error) (int, ErrorDetail) {
 	if errors.Is(err, asr.ErrUnsupportedAudio) {
-		sendError(w, "Unsupported or malformed audio: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
-		return
+		return http.StatusBadRequest, requestError(withParam("file", fmt.Errorf("Unsupported or malformed audio: %w", err)))
+	}
+	if errors.Is(err, asr.ErrOverMemoryBudget) {
+		return http.StatusRequestEntityTooLarge, requestError(withParam("file", fmt.Errorf("Audio too long for this server's memory: %w", err)))
+	}
+	if errors.Is(err, asr.ErrDenoiseUnavailable) {
+		return http.StatusBadRequest, requestError(withParam("denoise", err))
 	}
-	sendError(w, "Transcription failed: "+err.Error(), "server_error", http.StatusInternalServerError)
+	if errors.Is(err, asr.ErrClosed) {
+		return http.StatusServiceUnavailable, ErrorDetail{Message: "The server is shutting down", Type: "server_error"}
+	}
+	if errors.Is(err, asr.ErrQueueFull) {
+		return http.StatusServiceUnavailable, ErrorDetail{Message: "Too many transcriptions queued, retry later", Type: "server_error"}
+	}
+	var pe *panicError
+	if errors.As(err, &pe) {
+		return http.StatusInternalServerError, ErrorDetail{Message: "The server had an error while processing your request", Type: "server_error"}
+	}
+	return http.StatusInternalServerError, ErrorDetail{Message: "Transcription failed: " + err.Error(), Type: "server_error"}
 }
 
 // setCORSHeaders sets CORS headers for cross-origin requests
 func setCORSHeaders(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-}
-
-// sendError sends an OpenAI-compatible error response
-func sendError(w http.ResponseWriter, message, errType string, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	resp := ErrorResponse{
-		Error: ErrorDetail{
-			Message: message,
-			Type:    errType,
-		},
-	}
-	json.NewEncoder(w).Encode(resp)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Encoding, Authorization, X-Requested-With, X-Request-ID")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Processing-Time-Ms, X-Audio-Duration-Ms, X-Realtime-Factor, X-Transcript-Truncated, X-Transcript-Suspect, X-Usage-Key, X-Usage-Audio-Seconds, Retry-After")
 }
 
 // formatSRTTime formats duration as SRT timestamp
@@ -350,6 +829,44 @@ func formatSRTTime(seconds float64) string {
 	return fmt.Sprintf("%02d:%02d:%02d,%03d", hours, minutes, secs, millis)
 }
 
+// assHeader starts an ASS (Advanced SubStation Alpha) file with one
+// "Default" style: white text with a black outline, centred at the bottom.
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,64,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,3,1,2,60,60,50,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+// millis converts seconds to whole milliseconds, the unit of the tsv and
+// csv formats.
+func millis(seconds float64) int {
+	return int(math.Round(seconds * 1000))
+}
+
+// formatASSTime formats duration as an ASS timestamp (H:MM:SS.cc).
+func formatASSTime(seconds float64) string {
+	hours := int(seconds) / 3600
+	minutes := (int(seconds) % 3600) / 60
+	secs := int(seconds) % 60
+	centis := int((seconds - float64(int(seconds))) * 100)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", hours, minutes, secs, centis)
+}
+
+// assText escapes cue text for an ASS Dialogue line: line breaks become
+// \N and braces, which open override blocks, become parentheses.
+func assText(text string) string {
+	return strings.NewReplacer("\r\n", "\\N", "\n", "\\N", "{", "(", "}", ")").Replace(text)
+}
+
 // formatVTTTime formats duration as WebVTT timestamp
 func formatVTTTime(seconds float64) string {
 	hours := int(seconds) / 3600
@@ -0,0 +1,468 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/itn"
+)
+
+func TestRenderTranscription_ASS(t *testing.T) {
+	res := &asr.Result{
+		Text:     "Hello {there}. Line two",
+		Channels: 1,
+		Segments: []asr.Segment{
+			{Start: 0.5, End: 2.25, Text: "Hello {there}."},
+			{Start: 3661.5, End: 3662, Text: "Line\ntwo"},
+		},
+	}
+	contentType, body := renderTranscription(res, "ass", "en", cueLayout{})
+	out := body.(string)
+	if contentType != "text/x-ssa" || !strings.HasPrefix(out, "[Script Info]") || !strings.Contains(out, "\n[Events]\n") {
+		t.Fatalf("unexpected header (%s):\n%s", contentType, out)
+	}
+	for _, want := range []string{
+		"Dialogue: 0,0:00:00.50,0:00:02.25,Default,,0,0,0,,Hello (there).\n",
+		"Dialogue: 0,1:01:01.50,1:01:02.00,Default,,0,0,0,,Line\\Ntwo\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestCueLayout(t *testing.T) {
+	res := &asr.Result{
+		Text:     "one two three four five six",
+		Channels: 1,
+		Segments: []asr.Segment{{Start: 0, End: 6, Text: "one two three four five six"}},
+		Words: []asr.Word{
+			{Start: 0.1, End: 0.9, Text: "one"}, {Start: 1, End: 1.9, Text: "two"},
+			{Start: 2, End: 2.9, Text: "three"}, {Start: 3, End: 3.9, Text: "four"},
+			{Start: 4, End: 4.9, Text: "five"}, {Start: 5, End: 5.9, Text: "six"},
+		},
+	}
+
+	// Nine characters per line, two lines per cue.
+	got := cueLayout{MaxLineChars: 9}.apply(res).Segments
+	if len(got) != 2 || got[0].Text != "one two\nthree" || got[1].Text != "four five\nsix" {
+		t.Fatalf("wrapped cues = %+v", got)
+	}
+	if got[0].Start != 0 || got[0].End != 2.9 || got[1].Start != 3 || got[1].End != 6 {
+		t.Errorf("wrapped cue times = %+v", got)
+	}
+
+	// Cues of at most 2.5 s; without word timings, words are spread by length.
+	res.Words = nil
+	got = cueLayout{MaxDuration: 2.5}.apply(res).Segments
+	if len(got) != 3 || got[0].Text != "one two" || got[2].Text != "five six" || got[2].End != 6 {
+		t.Errorf("timed cues = %+v", got)
+	}
+	if n := len(cueLayout{}.apply(res).Segments); n != 1 {
+		t.Errorf("zero layout made %d cues", n)
+	}
+
+	_, body := renderTranscription(res, "ttml", "en", cueLayout{MaxLineChars: 9})
+	out := body.(string)
+	for _, want := range []string{`xml:lang="en"`, `tts:origin="10% 80%"`, `<p begin="00:00:00.000" end="`, "one two<br/>three</p>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if _, err := parseCueLayout(url.Values{"max_line_chars": {"0"}}.Get); err == nil {
+		t.Error("max_line_chars=0 accepted")
+	}
+}
+
+func TestRenderTranscription_Words(t *testing.T) {
+	res := &asr.Result{
+		Text:     "Tom & Jerry run",
+		Channels: 1,
+		Segments: []asr.Segment{{Start: 0, End: 2, Text: "Tom & Jerry run"}},
+		Words: []asr.Word{
+			{Start: 0.25, End: 0.5, Text: "Tom"}, {Start: 0.625, End: 0.75, Text: "&"},
+			{Start: 0.75, End: 1.25, Text: "Jerry"}, {Start: 1.5, End: 1.875, Text: "run"},
+		},
+	}
+	contentType, body := renderTranscription(res, "srt_words", "en", cueLayout{})
+	if want := "1\n00:00:00,250 --> 00:00:00,500\nTom\n\n"; contentType != "text/plain" || !strings.HasPrefix(body.(string), want) {
+		t.Errorf("srt_words (%s):\n%s", contentType, body)
+	}
+	if want := "4\n00:00:01,500 --> 00:00:01,875\nrun\n\n"; !strings.HasSuffix(body.(string), want) {
+		t.Errorf("srt_words:\n%s", body)
+	}
+
+	contentType, body = renderTranscription(res, "vtt_words", "en", cueLayout{})
+	want := "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\n" +
+		"<c>Tom</c> <00:00:00.625><c>&amp;</c> <00:00:00.750><c>Jerry</c> <00:00:01.500><c>run</c>\n\n"
+	if contentType != "text/vtt" || body != want {
+		t.Errorf("vtt_words (%s):\n%s\nwant\n%s", contentType, body, want)
+	}
+
+	// Lines wrap like vtt; a cue split off starts at its first word.
+	_, body = renderTranscription(res, "vtt_words", "en", cueLayout{MaxLineChars: 7, MaxLines: 1})
+	out := body.(string)
+	for _, want := range []string{
+		"00:00:00.000 --> 00:00:00.750\n<c>Tom</c> <00:00:00.625><c>&amp;</c>\n",
+		"00:00:00.750 --> 00:00:01.250\n<c>Jerry</c>\n",
+		"00:00:01.500 --> 00:00:02.000\n<c>run</c>\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	_, body = renderTranscription(res, "vtt_words", "en", cueLayout{MaxLineChars: 9})
+	if !strings.Contains(body.(string), "<c>Tom</c> <00:00:00.625><c>&amp;</c>\n<00:00:00.750><c>Jerry</c> <00:00:01.500><c>run</c>") {
+		t.Errorf("wrapped vtt_words:\n%s", body)
+	}
+
+	// Timestamps never go back or leave the cue, even with odd word times.
+	res.Words[1].Start = 1
+	res.Words[3].Start = 2.5
+	_, body = renderTranscription(res, "vtt_words", "en", cueLayout{})
+	if !strings.Contains(body.(string), "<c>Tom</c> <00:00:01.000><c>&amp;</c> <00:00:01.000><c>Jerry</c> <00:00:02.000><c>run</c>") {
+		t.Errorf("clamped vtt_words:\n%s", body)
+	}
+
+	res.Channels = 2
+	res.Segments[0].Channel, res.Words = 1, nil
+	_, body = renderTranscription(res, "srt_words", "en", cueLayout{})
+	if !strings.Contains(body.(string), "\n[channel 1] Jerry\n") {
+		t.Errorf("multi-channel srt_words:\n%s", body)
+	}
+	_, body = renderTranscription(res, "vtt_words", "en", cueLayout{})
+	if !strings.Contains(body.(string), "\n[channel 1] <c>Tom</c> <") {
+		t.Errorf("multi-channel vtt_words:\n%s", body)
+	}
+}
+
+func TestRenderTranscription_SegmentTables(t *testing.T) {
+	res := &asr.Result{
+		Text:     `Hi "there". Bye`,
+		Channels: 1,
+		Segments: []asr.Segment{
+			{Start: 0, End: 1.2345, Text: "Hi \"there\",\tfriend."},
+			{Start: 1.5, End: 2, Text: "Bye"},
+		},
+	}
+	for _, tc := range []struct{ format, contentType, want string }{
+		{"tsv", "text/tab-separated-values", "start\tend\ttext\n0\t1235\tHi \"there\", friend.\n1500\t2000\tBye\n"},
+		{"csv", "text/csv", "start,end,text\n0,1235,\"Hi \"\"there\"\",\tfriend.\"\n1500,2000,Bye\n"},
+		{"jsonl", "application/jsonl", `{"start":0,"end":1.2345,"text":"Hi \"there\",\tfriend."}` + "\n" + `{"start":1.5,"end":2,"text":"Bye"}` + "\n"},
+	} {
+		contentType, body := renderTranscription(res, tc.format, "en", cueLayout{})
+		if contentType != tc.contentType || body != tc.want {
+			t.Errorf("%s: %s\n%q\nwant %q", tc.format, contentType, body, tc.want)
+		}
+	}
+
+	res.Channels = 2
+	res.Segments[1].Channel = 1
+	_, body := renderTranscription(res, "jsonl", "en", cueLayout{})
+	if !strings.HasSuffix(body.(string), `{"start":1.5,"end":2,"text":"Bye","channel":1}`+"\n") {
+		t.Errorf("multi-channel jsonl = %q", body)
+	}
+}
+
+func TestCueLayout_Merge(t *testing.T) {
+	res := &asr.Result{
+		Channels: 1,
+		Segments: []asr.Segment{
+			{Start: 0, End: 1, Text: "Yes."},
+			{Start: 1.2, End: 2, Text: "Of course."},
+			{Start: 2.1, End: 4, Text: "Come in and sit down."},
+			{Start: 6, End: 7, Text: "Later."}, // after a long pause
+		},
+	}
+	got := cueLayout{MaxLineChars: 16}.apply(res).Segments
+	if len(got) != 3 || got[0].Text != "Yes. Of course." || got[0].End != 2 ||
+		got[1].Text != "Come in and sit\ndown." || got[2].Text != "Later." {
+		t.Fatalf("merged cues = %+v", got)
+	}
+
+	// One line per cue: the third segment no longer fits with the first two
+	// and is split on its own.
+	got = cueLayout{MaxLineChars: 16, MaxLines: 1}.apply(res).Segments
+	var texts []string
+	for _, seg := range got {
+		texts = append(texts, seg.Text)
+	}
+	if strings.Join(texts, "|") != "Yes. Of course.|Come in and sit|down.|Later." {
+		t.Errorf("single-line cues = %q", texts)
+	}
+}
+
+func TestParseDecoding(t *testing.T) {
+	d, err := parseDecoding(url.Values{}.Get)
+	if err != nil || d != (asr.DecodingOptions{}) {
+		t.Fatalf("defaults = %+v, %v", d, err)
+	}
+	d, err = parseDecoding(url.Values{"max_tokens_per_step": {"4"}, "blank_penalty": {"-1.5"}, "beam_size": {"4"}}.Get)
+	want := asr.DecodingOptions{MaxTokensPerStep: 4, BlankPenalty: -1.5, BeamSize: 4}
+	if err != nil || d != want {
+		t.Fatalf("got %+v, %v; want %+v", d, err, want)
+	}
+	for _, bad := range []url.Values{
+		{"max_tokens_per_step": {"0"}},
+		{"max_tokens_per_step": {"100"}},
+		{"blank_penalty": {"11"}},
+		{"blank_penalty": {"NaN"}},
+		{"beam_size": {"-1"}},
+		{"beam_size": {"9"}},
+		{"temperature": {"1.5"}},
+		{"temperature": {"0.5"}, "beam_size": {"2"}},
+	} {
+		if _, err := parseDecoding(bad.Get); err == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+}
+
+func TestSegmentationFor(t *testing.T) {
+	s := &Server{segmentation: asr.Segmentation{Pause: 0.8, Sentences: true}}
+	got, err := s.segmentationFor(url.Values{}.Get)
+	if err != nil || got != s.segmentation {
+		t.Fatalf("defaults = %+v, %v", got, err)
+	}
+	got, err = s.segmentationFor(url.Values{"segment_pause": {"0"}, "segment_sentences": {"false"}, "segment_max_duration": {"7.5"}}.Get)
+	if want := (asr.Segmentation{MaxDuration: 7.5}); err != nil || got != want {
+		t.Errorf("got %+v, %v; want %+v", got, err, want)
+	}
+	for _, bad := range []url.Values{{"segment_pause": {"-1"}}, {"segment_max_duration": {"5s"}}, {"segment_max_duration": {"+Inf"}}, {"segment_pause": {"NaN"}}} {
+		if _, err := s.segmentationFor(bad.Get); err == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+}
+
+func TestConfidenceFor(t *testing.T) {
+	s := &Server{confidence: asr.ConfidenceFilter{Min: 0.4, Placeholder: "[?]"}}
+	got, err := s.confidenceFor(url.Values{"min_confidence": {"0.7"}}.Get)
+	if err != nil || got != (asr.ConfidenceFilter{Min: 0.7, Placeholder: "[?]"}) {
+		t.Errorf("min_confidence=0.7: %+v, %v", got, err)
+	}
+	got, err = s.confidenceFor(url.Values{"confidence_placeholder": {"<unk>"}}.Get)
+	if err != nil || got != (asr.ConfidenceFilter{Min: 0.4, Placeholder: "<unk>"}) {
+		t.Errorf("confidence_placeholder: %+v, %v", got, err)
+	}
+	for _, bad := range []string{"-1", "2", "NaN", "low"} {
+		if _, err := s.confidenceFor(url.Values{"min_confidence": {bad}}.Get); err == nil {
+			t.Errorf("min_confidence=%q accepted", bad)
+		}
+	}
+	opts := asr.TranscribeOptions{Language: "en"}
+	masked := opts
+	masked.Confidence = asr.ConfidenceFilter{Min: 0.5, Placeholder: "[?]"}
+	if cacheKey("", []byte("a"), opts) == cacheKey("", []byte("a"), masked) {
+		t.Error("min_confidence does not change the cache key")
+	}
+}
+
+func TestFormattingFor(t *testing.T) {
+	s := &Server{formatting: itn.Options{Numbers: itn.Digits, Units: itn.Abbreviate}}
+	got, err := s.formattingFor(url.Values{}.Get)
+	if err != nil || got != s.formatting {
+		t.Errorf("no formatting: %+v, %v", got, err)
+	}
+	got, err = s.formattingFor(url.Values{"formatting": {"numbers=words,times=digits"}}.Get)
+	if want := (itn.Options{Numbers: itn.Words, Times: itn.Digits, Units: itn.Abbreviate}); err != nil || got != want {
+		t.Errorf("formatting=numbers=words,times=digits: %+v, %v; want %+v", got, err, want)
+	}
+	if _, err := s.formattingFor(url.Values{"formatting": {"numbers=roman"}}.Get); err == nil {
+		t.Error("formatting=numbers=roman accepted")
+	}
+	opts := asr.TranscribeOptions{Language: "en"}
+	formatted := opts
+	formatted.Formatting = got
+	if cacheKey("", []byte("a"), opts) == cacheKey("", []byte("a"), formatted) {
+		t.Error("formatting does not change the cache key")
+	}
+}
+
+func TestNoSpeechThreshold(t *testing.T) {
+	s := &Server{config: Config{NoSpeechThreshold: 0.6}}
+	for v, want := range map[string]float64{"": 0.6, "0": 0, "0.8": 0.8, "1": 1} {
+		if got, err := s.noSpeechThresholdFor(url.Values{"no_speech_threshold": {v}}.Get); err != nil || got != want {
+			t.Errorf("no_speech_threshold=%q: %v, %v; want %v", v, got, err, want)
+		}
+	}
+	for _, bad := range []string{"-0.1", "1.5", "NaN", "high"} {
+		if _, err := s.noSpeechThresholdFor(url.Values{"no_speech_threshold": {bad}}.Get); err == nil {
+			t.Errorf("no_speech_threshold=%q accepted", bad)
+		}
+	}
+
+	res := &asr.Result{Text: "hi", Channels: 1, Segments: []asr.Segment{{Start: 0, End: 1, Text: "hi", NoSpeechProb: 0.25}}}
+	_, body := renderTranscription(res, "verbose_json", "en", cueLayout{})
+	if got := body.(VerboseTranscriptionResponse).Segments[0].NoSpeechProb; got != 0.25 {
+		t.Errorf("no_speech_prob = %g", got)
+	}
+}
+
+func TestVerboseCompressionRatio(t *testing.T) {
+	res := &asr.Result{Channels: 1, Segments: []asr.Segment{
+		{Start: 0, End: 2, Text: "We shipped the release on Friday."},
+		{Start: 2, End: 9, Text: strings.Repeat("Thank you. ", 10)},
+		{Start: 9, End: 10},
+	}}
+	_, body := renderTranscription(res, "verbose_json", "en", cueLayout{})
+	segs := body.(VerboseTranscriptionResponse).Segments
+	if !(segs[0].CompressionRatio > 0 && segs[0].CompressionRatio < 2.4) {
+		t.Errorf("speech compression_ratio = %g", segs[0].CompressionRatio)
+	}
+	if segs[1].CompressionRatio <= 2.4 {
+		t.Errorf("loop compression_ratio = %g, want above 2.4", segs[1].CompressionRatio)
+	}
+	if segs[2].CompressionRatio != 0 {
+		t.Errorf("empty compression_ratio = %g", segs[2].CompressionRatio)
+	}
+}
+
+func TestFallbackFor(t *testing.T) {
+	s := &Server{fallback: asr.Fallback{CompressionRatio: 2.4, TemperatureStep: 0.25}}
+	f, err := s.fallbackFor(url.Values{"logprob_threshold": {"-1"}}.Get)
+	if err != nil || f != (asr.Fallback{CompressionRatio: 2.4, Logprob: -1, TemperatureStep: 0.25}) {
+		t.Errorf("logprob_threshold=-1: %+v, %v", f, err)
+	}
+	if f, err := s.fallbackFor(url.Values{"compression_ratio_threshold": {"0"}}.Get); err != nil || f.CompressionRatio != 0 {
+		t.Errorf("compression_ratio_threshold=0: %+v, %v", f, err)
+	}
+	for name, bad := range map[string]string{"compression_ratio_threshold": "-2", "logprob_threshold": "0.5"} {
+		if _, err := s.fallbackFor(url.Values{name: {bad}}.Get); err == nil {
+			t.Errorf("%s=%s accepted", name, bad)
+		}
+	}
+	for _, bad := range []string{"NaN", "high"} {
+		if _, err := s.fallbackFor(url.Values{"logprob_threshold": {bad}}.Get); err == nil {
+			t.Errorf("logprob_threshold=%s accepted", bad)
+		}
+	}
+
+	res := &asr.Result{Text: "hi", Channels: 1, Segments: []asr.Segment{{Start: 0, End: 1, Text: "hi", Temperature: 0.4}}}
+	_, body := renderTranscription(res, "verbose_json", "en", cueLayout{})
+	if got := body.(VerboseTranscriptionResponse).Segments[0].Temperature; got != 0.4 {
+		t.Errorf("temperature = %g", got)
+	}
+}
+
+func TestGuardFor(t *testing.T) {
+	s := &Server{guard: asr.HallucinationGuard{Mode: asr.GuardFlag, MaxRepeats: 3}}
+	for v, want := range map[string]asr.GuardMode{"": asr.GuardFlag, "off": asr.GuardOff, "blank": asr.GuardBlank} {
+		g, err := s.guardFor(url.Values{"hallucination_guard": {v}}.Get)
+		if err != nil || g.Mode != want || g.MaxRepeats != 3 {
+			t.Errorf("hallucination_guard=%q: %+v, %v; want %q", v, g, err, want)
+		}
+	}
+	if _, err := s.guardFor(url.Values{"hallucination_guard": {"drop"}}.Get); err == nil {
+		t.Error("hallucination_guard=drop accepted")
+	}
+
+	res := &asr.Result{Text: "la la", Channels: 1, Segments: []asr.Segment{
+		{Start: 0, End: 1, Text: "la", Suspect: []string{asr.SuspectUnvoiced, asr.SuspectRepetition}},
+		{Start: 1, End: 2, Text: "la", Suspect: []string{asr.SuspectRepetition}},
+	}}
+	rec := httptest.NewRecorder()
+	setSuspectHeader(rec, res)
+	if got := rec.Header().Get("X-Transcript-Suspect"); got != "unvoiced,repetition" {
+		t.Errorf("X-Transcript-Suspect = %q", got)
+	}
+	_, body := renderTranscription(res, "verbose_json", "en", cueLayout{})
+	if got := body.(VerboseTranscriptionResponse).Segments[1].Suspect; len(got) != 1 || got[0] != asr.SuspectRepetition {
+		t.Errorf("suspect = %v", got)
+	}
+}
+
+func TestMaxProcessing(t *testing.T) {
+	s := &Server{config: Config{MaxProcessing: 2 * time.Second}}
+	for v, want := range map[string]time.Duration{"": 2 * time.Second, "0": 0, "1500": 1500 * time.Millisecond} {
+		if got, err := s.maxProcessingFor(url.Values{"max_processing_ms": {v}}.Get); err != nil || got != want {
+			t.Errorf("max_processing_ms=%q: %v, %v; want %v", v, got, err, want)
+		}
+	}
+	for _, bad := range []string{"-1", "1.5s"} {
+		if _, err := s.maxProcessingFor(url.Values{"max_processing_ms": {bad}}.Get); err == nil {
+			t.Errorf("max_processing_ms=%q accepted", bad)
+		}
+	}
+
+	res := &asr.Result{Text: "hello", Channels: 1, Truncated: true}
+	for _, format := range []string{"json", "verbose_json"} {
+		_, body := renderTranscription(res, format, "en", cueLayout{})
+		out, _ := json.Marshal(body)
+		if !strings.Contains(string(out), `"truncated":true`) {
+			t.Errorf("%s: truncated missing from %s", format, out)
+		}
+	}
+	rec := httptest.NewRecorder()
+	setTruncatedHeader(rec, res)
+	if rec.Header().Get("X-Transcript-Truncated") != "true" {
+		t.Error("X-Transcript-Truncated not set")
+	}
+}
+
+func TestOffset(t *testing.T) {
+	for v, want := range map[string]float64{"": 0, "0": 0, "600": 600, "12.5": 12.5} {
+		if got, err := offsetFor(url.Values{"offset_seconds": {v}}.Get); err != nil || got != want {
+			t.Errorf("offset_seconds=%q: %v, %v; want %v", v, got, err, want)
+		}
+	}
+	for _, bad := range []string{"-1", "10s", "NaN", "Inf"} {
+		if _, err := offsetFor(url.Values{"offset_seconds": {bad}}.Get); err == nil {
+			t.Errorf("offset_seconds=%q accepted", bad)
+		}
+	}
+
+	res := &asr.Result{
+		Text:     "hello world",
+		Channels: 1,
+		Duration: 2,
+		Segments: []asr.Segment{{Start: 0.5, End: 1.5, Text: "hello world"}},
+		Words:    []asr.Word{{Start: 0.5, End: 0.875, Text: "hello"}, {Start: 1, End: 1.5, Text: "world"}},
+		Tokens:   []asr.Token{{Start: 0.5, Text: " hello"}, {Start: 1, Text: " world"}},
+	}
+	shifted := res.Shifted(3600)
+	if res.Segments[0].Start != 0.5 || res.Words[0].Start != 0.5 || res.Tokens[0].Start != 0.5 {
+		t.Errorf("Shifted changed the original: %+v", res)
+	}
+	if shifted.Duration != 2 || shifted.Words[1].End != 3601.5 || shifted.Tokens[1].Start != 3601 {
+		t.Errorf("shifted = %+v", shifted)
+	}
+	_, body := renderTranscription(shifted, "srt", "en", cueLayout{})
+	if want := "1\n01:00:00,500 --> 01:00:01,500\nhello world\n\n"; body != want {
+		t.Errorf("shifted srt = %q, want %q", body, want)
+	}
+}
+
+func TestVerboseAvgLogprob(t *testing.T) {
+	res := &asr.Result{Channels: 2,
+		Segments: []asr.Segment{
+			{Start: 0, End: 1, Text: "hello there"},
+			{Channel: 1, Start: 0.5, End: 1.5, Text: "hi"},
+			{Start: 1, End: 2},
+		},
+		Tokens: []asr.Token{
+			{Start: 0.1, Text: "hello", Logprob: -0.2},
+			{Start: 0.6, Text: " there", Logprob: -0.4},
+			{Channel: 1, Start: 0.7, Text: "hi", Logprob: -1},
+		},
+	}
+	_, body := renderTranscription(res, "verbose_json", "en", cueLayout{})
+	segs := body.(VerboseTranscriptionResponse).Segments
+	for i, want := range []float64{-0.3, -1} {
+		if got := segs[i].AvgLogprob; got == nil || math.Abs(*got-want) > 1e-9 {
+			t.Errorf("segment %d avg_logprob = %v, want %g", i, got, want)
+		}
+	}
+	if segs[2].AvgLogprob != nil {
+		t.Errorf("tokenless segment avg_logprob = %g, want it left out", *segs[2].AvgLogprob)
+	}
+}
@@ -0,0 +1,403 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+const (
+	// historyPruneInterval is how often writes also enforce the retention.
+	historyPruneInterval = time.Minute
+	// historyIDTime is the time part of a record ID, so IDs and file names
+	// sort by creation.
+	historyIDTime = "20060102T150405.000000000"
+	// historyListMax bounds the limit of one /v1/transcripts page.
+	historyListMax = 1000
+)
+
+// errHistoryNotFound is returned for unknown, expired or malformed IDs.
+var errHistoryNotFound = errors.New("transcript not found")
+
+// historyParams are the request parameters that shaped a transcript.
+type historyParams struct {
+	Format      string `json:"format,omitempty"` // the client's file extension
+	Language    string `json:"language,omitempty"`
+	ChannelMode string `json:"channel_mode"`
+	Denoise     bool   `json:"denoise"`
+	RemoveDC    bool   `json:"remove_dc"`
+	Gain        string `json:"normalize_gain,omitempty"`
+	TrimSilence bool   `json:"trim_silence"`
+
+	MaxTokensPerStep int     `json:"max_tokens_per_step,omitempty"`
+	BlankPenalty     float64 `json:"blank_penalty,omitempty"`
+	BeamSize         int     `json:"beam_size,omitempty"`
+	Temperature      float64 `json:"temperature,omitempty"`
+
+	SegmentPause       float64 `json:"segment_pause,omitempty"`
+	SegmentSentences   bool    `json:"segment_sentences,omitempty"`
+	SegmentMaxDuration float64 `json:"segment_max_duration,omitempty"`
+	NoSpeechThreshold  float64 `json:"no_speech_threshold,omitempty"`
+	HallucinationGuard string  `json:"hallucination_guard,omitempty"`
+
+	MinConfidence         float64 `json:"min_confidence,omitempty"`
+	ConfidencePlaceholder string  `json:"confidence_placeholder,omitempty"`
+	Formatting            string  `json:"formatting,omitempty"`
+
+	CompressionRatioThreshold float64 `json:"compression_ratio_threshold,omitempty"`
+	LogprobThreshold          float64 `json:"logprob_threshold,omitempty"`
+}
+
+// historyEntry describes one recorded transcription; it is the list item
+// of /v1/transcripts.
+type historyEntry struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	// Key names the API key of the request, empty without authentication;
+	// only requests with a key of that name can read the record.
+	Key         string        `json:"key,omitempty"`
+	AudioSHA256 string        `json:"audio_sha256"`
+	AudioBytes  int           `json:"audio_bytes"`
+	Params      historyParams `json:"params"`
+	Cached      bool          `json:"cached"`
+	// ElapsedSeconds is the wall time of the request's transcription,
+	// waiting for a decoder included; near zero for cache hits.
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Duration       float64 `json:"duration"` // seconds of audio
+	Text           string  `json:"text"`
+}
+
+// historyRecord is what is stored per transcription.
+type historyRecord struct {
+	historyEntry
+	Result *asr.Result `json:"result"`
+}
+
+// historyTranscript is the body of GET /v1/transcripts/{id}.
+type historyTranscript struct {
+	historyEntry
+	Transcript VerboseTranscriptionResponse `json:"transcript"`
+}
+
+type historyList struct {
+	Object  string         `json:"object"` // "list"
+	Data    []historyEntry `json:"data"`
+	HasMore bool           `json:"has_more"`
+	LastID  string         `json:"last_id,omitempty"` // pass as before= for the next page
+}
+
+// historyStore keeps one JSON file per transcription in dir, named by its
+// ID. Records older than retention, and the oldest beyond max, are removed
+// as new ones are written.
+type historyStore struct {
+	dir       string
+	retention time.Duration
+	max       int
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+func newHistoryStore(dir string, retention time.Duration, maxRecords int) (*historyStore, error) {
+	if maxRecords < 1 {
+		return nil, fmt.Errorf("-history-max must be at least 1, got %d", maxRecords)
+	}
+	// Records hold transcripts: only the server's user may read them.
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+	h := &historyStore{dir: dir, retention: retention, max: maxRecords}
+	h.prune()
+	return h, nil
+}
+
+// newHistoryID returns a time-ordered ID: the UTC creation time and a
+// random suffix.
+func newHistoryID(t time.Time) string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return t.UTC().Format(historyIDTime) + "-" + hex.EncodeToString(b[:])
+}
+
+// validHistoryID rejects anything that is not an ID, so a request cannot
+// name a file outside the directory.
+func validHistoryID(id string) bool {
+	ts, suffix, ok := strings.Cut(id, "-")
+	if !ok || len(suffix) != 8 {
+		return false
+	}
+	if _, err := hex.DecodeString(suffix); err != nil {
+		return false
+	}
+	_, err := time.Parse(historyIDTime, ts)
+	return err == nil
+}
+
+func (h *historyStore) path(id string) string {
+	return filepath.Join(h.dir, id+".json")
+}
+
+// put writes a record; failures are logged, never returned, so the history
+// cannot fail the request it describes.
+func (h *historyStore) put(rec *historyRecord) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	// CreateTemp makes the file 0o600, which the rename keeps.
+	tmp, err := os.CreateTemp(h.dir, rec.ID+".*.tmp")
+	if err != nil {
+		slog.Warn("history write failed", "error", err)
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		slog.Warn("history write failed", "error", errors.Join(werr, cerr))
+		return
+	}
+	if err := os.Rename(tmp.Name(), h.path(rec.ID)); err != nil {
+		os.Remove(tmp.Name())
+		slog.Warn("history write failed", "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	due := time.Since(h.lastPruned) >= historyPruneInterval
+	h.mu.Unlock()
+	if due {
+		h.prune()
+	}
+}
+
+func (h *historyStore) get(id string) (*historyRecord, error) {
+	if !validHistoryID(id) {
+		return nil, errHistoryNotFound
+	}
+	data, err := os.ReadFile(h.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errHistoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec historyRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("history record %s: %w", id, err)
+	}
+	return &rec, nil
+}
+
+// list returns up to limit entries of key older than the before ID (all
+// when empty), newest first, and whether more remain.
+func (h *historyStore) list(before string, limit int, key string) ([]historyEntry, bool, error) {
+	ids, err := h.ids()
+	if err != nil {
+		return nil, false, err
+	}
+	slices.Reverse(ids)
+	out := []historyEntry{}
+	for _, id := range ids {
+		if before != "" && id >= before {
+			continue
+		}
+		rec, err := h.get(id)
+		if errors.Is(err, errHistoryNotFound) {
+			continue // pruned meanwhile
+		}
+		if err != nil {
+			slog.Warn("skipping unreadable history record", "id", id, "error", err)
+			continue
+		}
+		if rec.Key != key {
+			continue
+		}
+		if len(out) == limit {
+			return out, true, nil
+		}
+		out = append(out, rec.historyEntry)
+	}
+	return out, false, nil
+}
+
+// ids returns every stored record ID, oldest first.
+func (h *historyStore) ids() ([]string, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() && validHistoryID(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil // ReadDir sorts by name, which is creation order
+}
+
+// prune removes records past the retention and the oldest beyond max.
+func (h *historyStore) prune() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastPruned = time.Now()
+	ids, err := h.ids()
+	if err != nil {
+		slog.Warn("history prune failed", "error", err)
+		return
+	}
+	drop := max(len(ids)-h.max, 0)
+	if h.retention > 0 {
+		cutoff := time.Now().Add(-h.retention).UTC().Format(historyIDTime)
+		for drop < len(ids) && ids[drop] < cutoff {
+			drop++
+		}
+	}
+	for _, id := range ids[:drop] {
+		os.Remove(h.path(id))
+	}
+	if drop > 0 {
+		slog.Debug("pruned transcript history", "removed", drop)
+	}
+}
+
+// recordTranscript adds a finished transcription to the history, if it is
+// enabled, and to the audit record of the request of ctx.
+func (s *Server) recordTranscript(ctx context.Context, audio []byte, opts asr.TranscribeOptions, res *asr.Result, cached bool, elapsed time.Duration) {
+	if s.history == nil && s.audit == nil || res == nil {
+		return
+	}
+	sum := sha256.Sum256(audio)
+	noteTranscript(ctx, hex.EncodeToString(sum[:]), len(audio), res, cached)
+	if s.history == nil {
+		return
+	}
+	now := time.Now()
+	s.history.put(&historyRecord{
+		historyEntry: historyEntry{
+			ID:          newHistoryID(now),
+			Created:     now.UTC(),
+			Key:         apiKeyNameFrom(ctx),
+			AudioSHA256: hex.EncodeToString(sum[:]),
+			AudioBytes:  len(audio),
+			Params: historyParams{
+				Format:      opts.Format,
+				Language:    opts.Language,
+				ChannelMode: string(opts.Channels),
+				Denoise:     opts.Denoise,
+				RemoveDC:    opts.Conditioning.RemoveDC,
+				Gain:        string(opts.Conditioning.Gain),
+				TrimSilence: opts.Conditioning.TrimSilence,
+
+				MaxTokensPerStep: opts.Decoding.MaxTokensPerStep,
+				BlankPenalty:     opts.Decoding.BlankPenalty,
+				BeamSize:         opts.Decoding.BeamSize,
+				Temperature:      opts.Decoding.Temperature,
+
+				SegmentPause:       opts.Segmentation.Pause,
+				SegmentSentences:   opts.Segmentation.Sentences,
+				SegmentMaxDuration: opts.Segmentation.MaxDuration,
+				NoSpeechThreshold:  opts.NoSpeechThreshold,
+				HallucinationGuard: string(opts.Guard.Mode),
+
+				MinConfidence:         opts.Confidence.Min,
+				ConfidencePlaceholder: opts.Confidence.Placeholder,
+				Formatting:            opts.Formatting.String(),
+
+				CompressionRatioThreshold: opts.Fallback.CompressionRatio,
+				LogprobThreshold:          opts.Fallback.Logprob,
+			},
+			Cached:         cached,
+			ElapsedSeconds: elapsed.Seconds(),
+			Duration:       res.Duration,
+			Text:           res.Text,
+		},
+		Result: res,
+	})
+}
+
+// handleTranscripts serves GET /v1/transcripts: the transcriptions recorded
+// for the caller's key, newest first, paged with limit and before.
+func (s *Server) handleTranscripts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > historyListMax {
+			sendRequestError(w, invalidParam("limit", "limit must be between 1 and %d", historyListMax))
+			return
+		}
+		limit = n
+	}
+	entries, more, err := s.history.list(r.URL.Query().Get("before"), limit, apiKeyNameFrom(r.Context()))
+	if err != nil {
+		sendError(w, "Error reading history: "+err.Error(), "server_error", http.StatusInternalServerError)
+		return
+	}
+	resp := historyList{Object: "list", Data: entries, HasMore: more}
+	if more {
+		resp.LastID = entries[len(entries)-1].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleTranscript serves GET /v1/transcripts/{id}: the record with its
+// verbose transcript, or with response_format (and for subtitles the cue
+// parameters) the transcript alone, as the transcription endpoint would
+// have returned it. Records of another key are not found.
+func (s *Server) handleTranscript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	rec, err := s.history.get(r.PathValue("id"))
+	if err == nil && rec.Key != apiKeyNameFrom(r.Context()) {
+		err = errHistoryNotFound
+	}
+	if errors.Is(err, errHistoryNotFound) {
+		sendError(w, "Transcript not found", "invalid_request_error", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendError(w, "Error reading history: "+err.Error(), "server_error", http.StatusInternalServerError)
+		return
+	}
+	if format := r.URL.Query().Get("response_format"); format != "" {
+		if _, err := parseResponseFormat(format); err != nil {
+			sendRequestError(w, err)
+			return
+		}
+		layout, err := parseCueLayout(r.URL.Query().Get)
+		if err != nil {
+			sendRequestError(w, err)
+			return
+		}
+		writeTranscription(w, rec.Result, format, rec.Params.Language, layout)
+		return
+	}
+	_, body := renderTranscription(rec.Result, "verbose_json", rec.Params.Language, cueLayout{})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(historyTranscript{historyEntry: rec.historyEntry, Transcript: body.(VerboseTranscriptionResponse)})
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestHistory_RecordAndServe(t *testing.T) {
+	history, err := newHistoryStore(t.TempDir(), time.Hour, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		mux:      http.NewServeMux(),
+		cache:    newMemoryCache(4),
+		history:  history,
+		stats:    newServerStats(),
+		inflight: newInflightGroup(),
+	}
+	s.setupRoutes()
+
+	audio := []byte("RIFF....WAVE")
+	opts := asr.TranscribeOptions{Format: ".wav", Language: "en", Channels: asr.ChannelMix}
+	s.cache.Put(s.cacheKey(audio, opts), &asr.Result{
+		Text:     "Hello, world.",
+		Duration: 1.5,
+		Channels: 1,
+		Segments: []asr.Segment{{Start: 0, End: 1.5, Text: "Hello, world."}},
+	})
+	// Two requests, one recorded per call.
+	for range 2 {
+		if _, _, err := s.transcribe(context.Background(), audio, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	var page historyList
+	json.NewDecoder(get("/v1/transcripts?limit=1").Body).Decode(&page)
+	if len(page.Data) != 1 || !page.HasMore || page.LastID == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	first := page.Data[0]
+	if !first.Cached || first.Text != "Hello, world." || first.AudioBytes != len(audio) ||
+		first.Params.Language != "en" || len(first.AudioSHA256) != 64 {
+		t.Errorf("entry = %+v", first)
+	}
+	json.NewDecoder(get("/v1/transcripts?before=" + page.LastID).Body).Decode(&page)
+	if len(page.Data) != 1 || page.HasMore || page.Data[0].ID >= first.ID {
+		t.Fatalf("second page = %+v", page)
+	}
+
+	var detail historyTranscript
+	json.NewDecoder(get("/v1/transcripts/" + first.ID).Body).Decode(&detail)
+	if detail.ID != first.ID || detail.Transcript.Text != "Hello, world." || len(detail.Transcript.Segments) != 1 {
+		t.Errorf("detail = %+v", detail)
+	}
+	if body := get("/v1/transcripts/" + first.ID + "?response_format=srt").Body.String(); !strings.Contains(body, "00:00:00,000 --> 00:00:01,500") {
+		t.Errorf("srt re-export = %q", body)
+	}
+	for _, id := range []string{"nope", "..%2F..%2Fetc%2Fpasswd", "20260101T000000.000000000-00000000"} {
+		if code := get("/v1/transcripts/" + id).Code; code != http.StatusNotFound {
+			t.Errorf("GET %s: %d, want 404", id, code)
+		}
+	}
+}
+
+func TestHistory_OwnKeyOnly(t *testing.T) {
+	history, err := newHistoryStore(t.TempDir(), time.Hour, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{history: history}
+	res := &asr.Result{Text: "hello"}
+	s.recordTranscript(withAPIKeyName(context.Background(), "search"), []byte("a"), asr.TranscribeOptions{}, res, false, 0)
+	s.recordTranscript(withAPIKeyName(context.Background(), "support"), []byte("b"), asr.TranscribeOptions{}, res, false, 0)
+
+	call := func(h http.HandlerFunc, key, target, id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.SetPathValue("id", id)
+		r = r.WithContext(withAPIKeyName(r.Context(), key))
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		return rec
+	}
+	var page historyList
+	json.NewDecoder(call(s.handleTranscripts, "search", "/v1/transcripts?limit=1", "").Body).Decode(&page)
+	if len(page.Data) != 1 || page.HasMore || page.Data[0].Key != "search" {
+		t.Fatalf("search's page = %+v", page)
+	}
+	id := page.Data[0].ID
+	if code := call(s.handleTranscript, "search", "/v1/transcripts/"+id, id).Code; code != http.StatusOK {
+		t.Errorf("owner GET: %d, want 200", code)
+	}
+	if code := call(s.handleTranscript, "support", "/v1/transcripts/"+id, id).Code; code != http.StatusNotFound {
+		t.Errorf("other key GET: %d, want 404", code)
+	}
+}
+
+func TestHistory_Permissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	history, err := newHistoryStore(dir, time.Hour, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history.put(&historyRecord{historyEntry: historyEntry{ID: newHistoryID(time.Now())}})
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("records = %v", files)
+	}
+	for path, want := range map[string]os.FileMode{dir: 0o700, files[0]: 0o600} {
+		if st, err := os.Stat(path); err != nil {
+			t.Error(err)
+		} else if st.Mode().Perm() != want {
+			t.Errorf("%s: mode %v, want %v", path, st.Mode().Perm(), want)
+		}
+	}
+}
+
+func TestHistory_Prune(t *testing.T) {
+	dir := t.TempDir()
+	h, err := newHistoryStore(dir, 24*time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := &historyRecord{historyEntry: historyEntry{ID: newHistoryID(time.Now().Add(-48 * time.Hour))}, Result: &asr.Result{}}
+	h.put(old)
+	for range 3 {
+		h.put(&historyRecord{historyEntry: historyEntry{ID: newHistoryID(time.Now())}, Result: &asr.Result{}})
+	}
+	h.prune()
+	ids, _ := h.ids()
+	if len(ids) != 2 {
+		t.Errorf("%d records after prune, want 2", len(ids))
+	}
+	if _, err := os.Stat(h.path(old.ID)); !os.IsNotExist(err) {
+		t.Error("expired record kept")
+	}
+
+	if _, err := newHistoryStore(dir, 0, 0); err == nil {
+		t.Error("expected an error for -history-max 0")
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// idleModels unloads the -models model (Server.models) after
+// -models-idle-unload without a decode, and has the next decode load it
+// again. The canary, shadow and compare models are not covered: they stay
+// loaded. It is nil when the option is off.
+type idleModels struct {
+	timeout time.Duration
+	stop    chan struct{}
+
+	// mu guards the fields below. A decode holds it while it loads the
+	// models, so a burst after an idle period loads them once.
+	mu       sync.Mutex
+	active   int
+	lastUsed time.Time
+	// unloaded is the generation whose Transcriber was closed for being
+	// idle. The models are unloaded while it is still Server.models; a
+	// reload in the meantime replaces it with a loaded generation.
+	unloaded *loadedModels
+}
+
+func newIdleModels(timeout time.Duration) *idleModels {
+	return &idleModels{timeout: timeout, stop: make(chan struct{}), lastUsed: time.Now()}
+}
+
+// useTranscriber returns the Transcriber for one decode and the func to call
+// once it is done. While a decode holds it, the models are not unloaded.
+func (s *Server) useTranscriber() (*asr.Transcriber, func(), error) {
+	im := s.idle
+	if im == nil {
+		return s.transcriber(), func() {}, nil
+	}
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if im.unloaded != nil && s.models.Load() == im.unloaded {
+		if err := s.loadUnloadedModels(); err != nil {
+			return nil, nil, err
+		}
+	}
+	im.unloaded = nil
+	im.active++
+	return s.transcriber(), im.done, nil
+}
+
+func (im *idleModels) done() {
+	im.mu.Lock()
+	im.active--
+	im.lastUsed = time.Now()
+	im.mu.Unlock()
+}
+
+// loadUnloadedModels loads the idle-unloaded generation again from the same
+// source. The caller holds idle.mu.
+func (s *Server) loadUnloadedModels() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.modelsClosed {
+		return asr.ErrClosed
+	}
+	cur := s.models.Load()
+	started := time.Now()
+	next, err := loadModels(s.config, s.modelOptions, cur.dir, cur.archivePath)
+	if err != nil {
+		return err
+	}
+	next.generation = cur.generation
+	s.models.Store(next)
+	s.hashModels(next, cur)
+	cur.close()
+	slog.Info("models loaded after being idle", "seconds", time.Since(started).Seconds())
+	return nil
+}
+
+// unloadIdleModels runs until Close, unloading the models once no decode
+// has run for the timeout.
+func (s *Server) unloadIdleModels() {
+	im := s.idle
+	ticker := time.NewTicker(max(im.timeout/4, 10*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-im.stop:
+			return
+		case <-ticker.C:
+		}
+		im.mu.Lock()
+		cur := s.models.Load()
+		if im.active == 0 && im.unloaded != cur && time.Since(im.lastUsed) >= im.timeout {
+			// Close waits for anything still running on the sessions, such
+			// as a reload's warm-up decode.
+			cur.transcriber.Close()
+			im.unloaded = cur
+			slog.Info("models unloaded after being idle", "idle", im.timeout)
+		}
+		im.mu.Unlock()
+	}
+}
+
+// transcribeAudio runs TranscribeWithOptions on the current models, or on
+// the canary for its share of the traffic, and the -postprocess-profiles
+// profile of the request's language over the result. Streamed deltas are
+// the recognised text.
+func (s *Server) transcribeAudio(ctx context.Context, audio []byte, opts asr.TranscribeOptions, emit func(string)) (*asr.Result, error) {
+	t, done := s.canaryTranscriber()
+	if t == nil {
+		var err error
+		if t, done, err = s.useTranscriber(); err != nil {
+			return nil, err
+		}
+	}
+	defer done()
+	start := time.Now()
+	res, err := t.TranscribeWithOptions(ctx, audio, opts, emit)
+	s.stats.modelDecode(t.Info().Name, res, time.Since(start), err)
+	if err == nil && res.Truncated {
+		slog.WarnContext(ctx, "transcription stopped at max processing time", "limit", opts.MaxProcessing, "seconds", res.Duration)
+	}
+	if err == nil {
+		s.profiles.apply(opts.Language, res)
+		s.shadowTranscribe(ctx, audio, opts, res, time.Since(start))
+	}
+	return res, err
+}
+
+// detectSpeech runs DetectSpeech on the current models.
+func (s *Server) detectSpeech(ctx context.Context, audio []byte, opts asr.VADOptions) (*asr.VADResult, error) {
+	t, done, err := s.useTranscriber()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return t.DetectSpeech(ctx, audio, opts)
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestIdleModels(t *testing.T) {
+	s := &Server{retireNow: make(chan struct{}), idle: newIdleModels(20 * time.Millisecond)}
+	loaded := &loadedModels{transcriber: &asr.Transcriber{}, dir: filepath.Join(t.TempDir(), "missing")}
+	s.models.Store(loaded)
+	go s.unloadIdleModels()
+	defer s.Close()
+
+	unloaded := func() bool {
+		s.idle.mu.Lock()
+		defer s.idle.mu.Unlock()
+		return s.idle.unloaded == loaded
+	}
+
+	// A running decode keeps the models loaded past the timeout.
+	tr, done, err := s.useTranscriber()
+	if err != nil || tr != loaded.transcriber {
+		t.Fatalf("useTranscriber = %p, %v", tr, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if unloaded() {
+		t.Fatal("models unloaded during a decode")
+	}
+	done()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !unloaded() {
+		if time.Now().After(deadline) {
+			t.Fatal("models not unloaded after the idle timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The next decode loads them again; here the directory is gone, so it
+	// fails and the unloaded generation stays in place.
+	if _, _, err := s.useTranscriber(); err == nil {
+		t.Fatal("loading missing models succeeded")
+	}
+	if s.models.Load() != loaded || !unloaded() {
+		t.Error("failed load replaced the unloaded models")
+	}
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"math"
+	"mime/multipart"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// includeRequest is the parsed include[] parameter of a request. The zero
+// value adds nothing to the response.
+type includeRequest struct {
+	logprobs bool
+	timings  bool
+}
+
+// parseInclude reads OpenAI's include[] parameter (also accepted as include)
+// from form. logprobs is carried by the json and verbose_json formats and
+// the stream's transcript.text.done event; timings, a parakeet extension,
+// only by verbose_json.
+func parseInclude(form *multipart.Form, responseFormat string) (includeRequest, error) {
+	var req includeRequest
+	if form == nil {
+		return req, nil
+	}
+	values := append(form.Value["include[]"], form.Value["include"]...)
+	for _, v := range values {
+		switch v {
+		case "logprobs":
+			req.logprobs = true
+		case "timings":
+			req.timings = true
+		default:
+			return includeRequest{}, invalidParam("include[]", "invalid include value %q (available: logprobs, timings)", v)
+		}
+	}
+	if req.timings && responseFormat != "verbose_json" {
+		return includeRequest{}, invalidParam("include[]", "include[]=timings works with response_format verbose_json, not %q", responseFormat)
+	}
+	if req.logprobs && responseFormat != "json" && responseFormat != "verbose_json" {
+		return includeRequest{}, invalidParam("include[]", "include[]=logprobs works with response_format json or verbose_json, not %q", responseFormat)
+	}
+	return req, nil
+}
+
+// withTimings adds result's stage timings to a verbose_json body from
+// renderTranscription. postprocess is the server's own post-processing,
+// added to the transcriber's.
+func withTimings(body any, result *asr.Result, postprocess time.Duration) any {
+	resp, ok := body.(VerboseTranscriptionResponse)
+	if !ok {
+		return body
+	}
+	t := result.Timings
+	resp.Timings = &StageTimings{
+		DecodeAudioMs: durationMs(t.DecodeAudio),
+		FeaturesMs:    durationMs(t.Features),
+		EncoderMs:     durationMs(t.Encoder),
+		DecoderMs:     durationMs(t.Decoder),
+		PostprocessMs: durationMs(t.Postprocess + postprocess),
+	}
+	return resp
+}
+
+// durationMs is d in milliseconds, to the microsecond.
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// tokenLogprobs converts the decoded tokens of result to the response's
+// logprobs, in time order. Logprobs are rounded to six decimals.
+func tokenLogprobs(result *asr.Result) []Logprob {
+	out := make([]Logprob, 0, len(result.Tokens))
+	for _, tok := range result.Tokens {
+		b := []byte(tok.Text)
+		bytes := make([]int, len(b))
+		for i, c := range b {
+			bytes[i] = int(c)
+		}
+		out = append(out, Logprob{
+			Token:   tok.Text,
+			Logprob: math.Round(tok.Logprob*1e6) / 1e6,
+			Bytes:   bytes,
+		})
+	}
+	return out
+}
+
+// withLogprobs adds the token logprobs of result to a json or verbose_json
+// body from renderTranscription when inc asks for them. Other bodies are
+// returned unchanged.
+func withLogprobs(body any, result *asr.Result, inc includeRequest) any {
+	if !inc.logprobs {
+		return body
+	}
+	switch resp := body.(type) {
+	case TranscriptionResponse:
+		resp.Logprobs = tokenLogprobs(result)
+		return resp
+	case VerboseTranscriptionResponse:
+		resp.Logprobs = tokenLogprobs(result)
+		return resp
+	}
+	return body
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"mime/multipart"
+	"reflect"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestParseInclude(t *testing.T) {
+	for _, tc := range []struct {
+		values   map[string][]string
+		format   string
+		logprobs bool
+		ok       bool
+	}{
+		{nil, "json", false, true},
+		{map[string][]string{"include[]": {"logprobs"}}, "json", true, true},
+		{map[string][]string{"include": {"logprobs"}}, "verbose_json", true, true},
+		{map[string][]string{"include[]": {"logprobs"}}, "srt", false, false},
+		{map[string][]string{"include[]": {"segments"}}, "json", false, false},
+		{map[string][]string{"include[]": {"logprobs", "words"}}, "json", false, false},
+		{map[string][]string{"include[]": {"timings"}}, "verbose_json", false, true},
+		{map[string][]string{"include[]": {"timings"}}, "json", false, false},
+	} {
+		inc, err := parseInclude(&multipart.Form{Value: tc.values}, tc.format)
+		if (err == nil) != tc.ok || inc.logprobs != tc.logprobs {
+			t.Errorf("%v %s: logprobs=%v err=%v", tc.values, tc.format, inc.logprobs, err)
+		}
+	}
+	if inc, err := parseInclude(nil, "json"); err != nil || inc.logprobs {
+		t.Errorf("nil form: %+v, %v", inc, err)
+	}
+}
+
+func TestWithLogprobs(t *testing.T) {
+	res := &asr.Result{
+		Text:   "hi",
+		Tokens: []asr.Token{{Text: " hi", Logprob: -0.1234567}},
+	}
+	want := []Logprob{{Token: " hi", Logprob: -0.123457, Bytes: []int{32, 104, 105}}}
+
+	_, body := renderTranscription(res, "json", "en", cueLayout{})
+	got := withLogprobs(body, res, includeRequest{logprobs: true}).(TranscriptionResponse)
+	if !reflect.DeepEqual(got.Logprobs, want) {
+		t.Fatalf("json logprobs = %+v", got.Logprobs)
+	}
+	_, body = renderTranscription(res, "verbose_json", "en", cueLayout{})
+	verbose := withLogprobs(body, res, includeRequest{logprobs: true}).(VerboseTranscriptionResponse)
+	if !reflect.DeepEqual(verbose.Logprobs, want) {
+		t.Fatalf("verbose_json logprobs = %+v", verbose.Logprobs)
+	}
+	if plain := withLogprobs(body, res, includeRequest{}).(VerboseTranscriptionResponse); plain.Logprobs != nil {
+		t.Fatalf("logprobs without include: %+v", plain.Logprobs)
+	}
+	if ev := doneEvent(res, includeRequest{logprobs: true}); !reflect.DeepEqual(ev.Logprobs, want) {
+		t.Fatalf("done event = %+v", ev)
+	}
+}
+
+func TestWithTimings(t *testing.T) {
+	res := &asr.Result{Text: "hi", Timings: asr.Timings{
+		DecodeAudio: 1500 * time.Microsecond,
+		Features:    2 * time.Millisecond,
+		Encoder:     30 * time.Millisecond,
+		Decoder:     12 * time.Millisecond,
+		Postprocess: time.Millisecond,
+	}}
+	_, body := renderTranscription(res, "verbose_json", "en", cueLayout{})
+	got := withTimings(body, res, 250*time.Microsecond).(VerboseTranscriptionResponse).Timings
+	want := &StageTimings{DecodeAudioMs: 1.5, FeaturesMs: 2, EncoderMs: 30, DecoderMs: 12, PostprocessMs: 1.25}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("timings = %+v, want %+v", got, want)
+	}
+	if plain := body.(VerboseTranscriptionResponse); plain.Timings != nil {
+		t.Fatalf("timings without include: %+v", plain.Timings)
+	}
+	_, body = renderTranscription(res, "json", "en", cueLayout{})
+	if _, ok := withTimings(body, res, 0).(TranscriptionResponse); !ok {
+		t.Fatal("json body changed")
+	}
+}
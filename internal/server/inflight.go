@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+
+	"parakeet/internal/asr"
+)
+
+// inflightGroup collapses concurrent identical requests into one
+// transcription. Clients that retry after a timeout while the first attempt
+// is still decoding would otherwise double the CPU spent on the same clip.
+//
+// Unlike a plain singleflight, the shared work is not tied to the context of
+// whichever request arrived first: it runs on its own context, which is only
+// cancelled once every request waiting on it has gone away. A retrying
+// client that drops its first connection therefore still gets the result of
+// the decode that connection started.
+type inflightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// inflightCall is one running transcription and the requests waiting on it.
+type inflightCall struct {
+	done    chan struct{}
+	res     *asr.Result
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newInflightGroup() *inflightGroup {
+	return &inflightGroup{calls: make(map[string]*inflightCall)}
+}
+
+// do returns the result of fn for key, running fn only if no identical call
+// is already in flight. shared reports whether the result was produced for
+// another request. If ctx ends first, do returns ctx.Err() and, when it was
+// the last waiter, cancels the shared work.
+func (g *inflightGroup) do(ctx context.Context, key string, fn func(context.Context) (*asr.Result, error)) (res *asr.Result, shared bool, err error) {
+	g.mu.Lock()
+	c, ok := g.calls[key]
+	if ok {
+		c.waiters++
+	} else {
+		workCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &inflightCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = c
+		go func() {
+			defer cancel()
+			c.res, c.err = runRecovered(workCtx, fn)
+			g.mu.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.res, ok, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Nobody wants this result any more: stop the decode and make
+			// sure a later identical request starts afresh instead of
+			// joining a call that is being cancelled.
+			c.cancel()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ok, ctx.Err()
+	}
+}
+
+// runRecovered runs fn, turning a panic into a panicError. The shared decode
+// runs on its own goroutine, where an unrecovered panic would take down the
+// whole server rather than fail the requests waiting on it.
+func runRecovered(ctx context.Context, fn func(context.Context) (*asr.Result, error)) (res *asr.Result, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.ErrorContext(ctx, "panic during transcription", "panic", p, "stack", string(debug.Stack()))
+			res, err = nil, &panicError{value: p}
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestInflightGroup_SharesConcurrentCalls(t *testing.T) {
+	g := newInflightGroup()
+	release := make(chan struct{})
+	var runs atomic.Int32
+	fn := func(context.Context) (*asr.Result, error) {
+		runs.Add(1)
+		<-release
+		return &asr.Result{Text: "once"}, nil
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, shared, err := g.do(context.Background(), "k", fn)
+			if err != nil || res.Text != "once" {
+				t.Errorf("do = %v, %v", res, err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	// Wait until every caller has joined before letting the work finish.
+	for {
+		g.mu.Lock()
+		c := g.calls["k"]
+		joined := c != nil && c.waiters == callers
+		g.mu.Unlock()
+		if joined {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if runs.Load() != 1 {
+		t.Fatalf("fn ran %d times, want 1", runs.Load())
+	}
+	if sharedCount.Load() != callers-1 {
+		t.Fatalf("%d callers saw a shared result, want %d", sharedCount.Load(), callers-1)
+	}
+}
+
+// The work survives the first caller leaving while another still waits, and
+// is cancelled once the last waiter leaves.
+func TestInflightGroup_CancelsOnlyWhenAllWaitersLeave(t *testing.T) {
+	g := newInflightGroup()
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (*asr.Result, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() { _, _, err := g.do(ctx1, "k", fn); errs <- err }()
+	<-started
+	go func() { _, _, err := g.do(ctx2, "k", fn); errs <- err }()
+	for {
+		g.mu.Lock()
+		joined := g.calls["k"].waiters == 2
+		g.mu.Unlock()
+		if joined {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel1()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller: err = %v", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("work cancelled while a caller was still waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel2()
+	<-errs
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("work not cancelled after the last caller left")
+	}
+
+	// A new identical call must start fresh work, not join the cancelled one.
+	res, shared, err := g.do(context.Background(), "k", func(context.Context) (*asr.Result, error) {
+		return &asr.Result{Text: "fresh"}, nil
+	})
+	if err != nil || shared || res.Text != "fresh" {
+		t.Fatalf("after cancellation: %v, shared=%v, %v", res, shared, err)
+	}
+}
+
+func TestInflightGroup_RecoversPanics(t *testing.T) {
+	g := newInflightGroup()
+	_, _, err := g.do(t.Context(), "k", func(context.Context) (*asr.Result, error) {
+		panic("boom")
+	})
+	var pe *panicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v, want a panicError", err)
+	}
+	// The key is free again for the next request.
+	res, _, err := g.do(t.Context(), "k", func(context.Context) (*asr.Result, error) {
+		return &asr.Result{Text: "ok"}, nil
+	})
+	if err != nil || res.Text != "ok" {
+		t.Fatalf("after panic: %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// Live transcription tuning. Parakeet is an offline model: a live session
+// buffers the audio of the current utterance and transcribes it whole,
+// finalizing at pauses so each decode stays short.
+const (
+	// liveSilenceDBFS is the level below which a packet counts as silence
+	// for endpointing, unless -live-vad has the Silero VAD decide.
+	liveSilenceDBFS = -45.0
+	// liveVADThreshold is the Silero speech probability that starts speech
+	// with -live-vad.
+	liveVADThreshold = 0.5
+	// liveMaxUtteranceSeconds forces a final result when nobody pauses.
+	liveMaxUtteranceSeconds = 15.0
+	// liveInterimSeconds is how much new audio triggers an interim result.
+	liveInterimSeconds = 1.0
+	// liveDefaultEndpointing is the pause that ends an utterance when
+	// -live-endpointing is not set.
+	liveDefaultEndpointing = 500 * time.Millisecond
+	// maxLiveInterimStability bounds -live-interim-stability: at one
+	// interim a second, more would hold words back for too long.
+	maxLiveInterimStability = 10
+)
+
+// liveResult is one transcription of the current utterance. Times are
+// seconds from the start of the stream.
+type liveResult struct {
+	Start       float64
+	Duration    float64
+	Text        string
+	Words       []asr.Word
+	IsFinal     bool // the utterance will not be transcribed again
+	SpeechFinal bool // finalized because the speaker paused
+	// FromFinalize is set on the final result produced by an explicit
+	// finalize request from the client.
+	FromFinalize bool
+}
+
+// liveSession turns a stream of headerless audio packets into interim and
+// final results. It is protocol-agnostic: the Deepgram and Twilio WebSocket
+// handlers, the RTP listener and the stream workers feed it and render what
+// it returns. Not safe for concurrent use, except for the fields under mu.
+type liveSession struct {
+	s       *Server
+	format  asr.PCMFormat
+	opts    asr.TranscribeOptions
+	interim bool
+	// endpointing is the trailing silence that finalizes an utterance;
+	// zero disables pause detection (only the length cap and explicit
+	// finalize requests end utterances).
+	endpointing time.Duration
+	// vad finds pauses with the Silero VAD (-live-vad); nil uses the
+	// audio level.
+	vad *asr.SpeechStream
+	// stability, above one, holds interim words back until that many
+	// interim transcriptions in a row agree on them
+	// (-live-interim-stability).
+	stability int
+	interims  [][]asr.Word // the last stability interim words, oldest first
+	shown     []asr.Word   // the words of the last interim result sent
+
+	pending      []byte  // audio of the current utterance
+	pendingStart float64 // stream time where pending begins
+	voiced       bool    // pending contains something above the silence level
+	silence      float64 // trailing silent seconds in pending
+	sinceInterim float64 // seconds added since the last interim result
+	received     int     // total bytes received
+
+	// queue holds the audio waiting for the session when its protocol
+	// reads ahead (Deepgram live); nil otherwise.
+	queue *liveQueue
+
+	// Set when the session is listed on /admin/sessions (see
+	// liveSessionRegistry).
+	id       string
+	protocol string
+	source   string
+	started  time.Time
+
+	// mu guards the fields below: /admin/sessions reads them and queues
+	// settings while the session's own goroutine runs it.
+	mu         sync.Mutex
+	status     liveSessionInfo
+	queued     *liveSettings // applied by the next push or finalize
+	utterances int
+}
+
+func (s *Server) newLiveSession(format asr.PCMFormat, opts asr.TranscribeOptions, interim bool, endpointing time.Duration) *liveSession {
+	ls := &liveSession{s: s, format: format, opts: opts, interim: interim, endpointing: endpointing}
+	if interim {
+		ls.stability = s.config.LiveInterimStability
+	}
+	if s.config.LiveVAD {
+		ls.vad = asr.NewSpeechStream(format, liveVADThreshold)
+	}
+	return ls
+}
+
+// liveEndpointing is the pause that ends an utterance unless the client
+// asks for another: -live-endpointing, else liveDefaultEndpointing.
+func (s *Server) liveEndpointing() time.Duration {
+	return cmp.Or(s.config.LiveEndpointing, liveDefaultEndpointing)
+}
+
+// push adds one packet and returns the results it triggers, if any.
+func (ls *liveSession) push(ctx context.Context, packet []byte) ([]liveResult, error) {
+	if len(packet) == 0 {
+		return nil, nil
+	}
+	seconds := ls.format.Seconds(len(packet))
+	ls.pending = append(ls.pending, packet...)
+	ls.received += len(packet)
+	ls.sync(true)
+	ls.sinceInterim += seconds
+	if !ls.speech(ctx, packet) {
+		ls.silence += seconds
+	} else {
+		ls.voiced = true
+		ls.silence = 0
+	}
+
+	pendingSeconds := ls.format.Seconds(len(ls.pending))
+	switch {
+	case !ls.voiced:
+		// Only silence so far: nothing to transcribe. Drop it once it is
+		// as long as the pause that would have ended an utterance, so the
+		// next utterance starts near its first word.
+		if pendingSeconds >= max(ls.endpointing.Seconds(), liveInterimSeconds) {
+			ls.reset()
+		}
+		return nil, nil
+	case ls.endpointing > 0 && ls.silence >= ls.endpointing.Seconds():
+		res, err := ls.finalize(ctx, true, false)
+		return oneResult(res), err
+	case pendingSeconds >= liveMaxUtteranceSeconds:
+		res, err := ls.finalize(ctx, false, false)
+		return oneResult(res), err
+	case ls.interim && ls.sinceInterim >= liveInterimSeconds && !ls.queue.behind():
+		ls.sinceInterim = 0
+		res, err := ls.transcribe(ctx)
+		if err != nil || ls.stability <= 1 {
+			return oneResult(res), err
+		}
+		return oneResult(ls.stabilize(res)), nil
+	}
+	return nil, nil
+}
+
+// speech reports whether packet holds speech: what the Silero VAD hears
+// with -live-vad, else a level above liveSilenceDBFS. A VAD failure, such
+// as a reloaded model without silero_vad.onnx, falls back to the level.
+func (ls *liveSession) speech(ctx context.Context, packet []byte) bool {
+	if ls.vad != nil {
+		t, done, err := ls.s.useTranscriber()
+		if err == nil {
+			var speech bool
+			speech, err = t.StreamSpeech(ls.vad, packet)
+			done()
+			if err == nil {
+				return speech
+			}
+		}
+		slog.DebugContext(ctx, "live VAD failed, using the audio level", "error", err)
+	}
+	return ls.format.LevelDBFS(packet) >= liveSilenceDBFS
+}
+
+// finalize transcribes what is pending as a final result and starts a new
+// utterance. It returns nil when nothing was said.
+func (ls *liveSession) finalize(ctx context.Context, speechFinal, fromFinalize bool) (*liveResult, error) {
+	ls.sync(false)
+	if !ls.voiced {
+		ls.reset()
+		return nil, nil
+	}
+	res, err := ls.transcribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res.IsFinal = true
+	res.SpeechFinal = speechFinal
+	res.FromFinalize = fromFinalize
+	ls.reset()
+	ls.mu.Lock()
+	ls.utterances++
+	ls.mu.Unlock()
+	return res, nil
+}
+
+// skip accounts for n bytes of audio dropped before reaching the session
+// (-live-lag-policy drop). The utterance under way ends where the audio is
+// missing and is finalized, and the next one starts after the gap, so
+// times stay on the client's clock.
+func (ls *liveSession) skip(ctx context.Context, n int) (*liveResult, error) {
+	res, err := ls.finalize(ctx, false, false)
+	ls.received += n
+	ls.reset()
+	return res, err
+}
+
+// transcribe decodes the pending utterance.
+func (ls *liveSession) transcribe(ctx context.Context) (*liveResult, error) {
+	start := time.Now()
+	result, err := ls.s.transcribeAudio(ctx, ls.format.WAV(ls.pending), ls.opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	ls.s.stats.decode(result.Duration, time.Since(start))
+	words := make([]asr.Word, len(result.Words))
+	for i, w := range result.Words {
+		w.Start += ls.pendingStart
+		w.End += ls.pendingStart
+		words[i] = w
+	}
+	return &liveResult{
+		Start:    ls.pendingStart,
+		Duration: ls.format.Seconds(len(ls.pending)),
+		Text:     result.Text,
+		Words:    words,
+	}, nil
+}
+
+// stabilize cuts an interim result down to the words the last stability
+// interim transcriptions agree on, so captions do not flicker while the
+// end of the utterance is still being revised. The words shown only change
+// once a longer run is agreed on; it returns nil when they do not change.
+// Final results always carry the whole utterance.
+func (ls *liveSession) stabilize(res *liveResult) *liveResult {
+	ls.interims = append(ls.interims, res.Words)
+	if len(ls.interims) > ls.stability {
+		ls.interims = ls.interims[1:]
+	}
+	if len(ls.interims) < ls.stability {
+		return nil
+	}
+	agreed := len(res.Words)
+	for _, words := range ls.interims[:len(ls.interims)-1] {
+		n := 0
+		for n < min(agreed, len(words)) && words[n].Text == res.Words[n].Text {
+			n++
+		}
+		agreed = n
+	}
+	if agreed <= len(ls.shown) {
+		return nil
+	}
+	ls.shown = slices.Clone(res.Words[:agreed])
+	texts := make([]string, agreed)
+	for i, w := range ls.shown {
+		texts[i] = w.Text
+	}
+	res.Words = ls.shown
+	res.Text = strings.Join(texts, " ")
+	return res
+}
+
+// reset starts a new utterance after everything received so far.
+func (ls *liveSession) reset() {
+	ls.pendingStart = ls.receivedSeconds()
+	ls.pending = ls.pending[:0]
+	ls.voiced = false
+	ls.silence = 0
+	ls.sinceInterim = 0
+	ls.interims = nil
+	ls.shown = nil
+}
+
+// receivedSeconds is the stream time: the duration of all audio received.
+func (ls *liveSession) receivedSeconds() float64 {
+	return ls.format.Seconds(ls.received)
+}
+
+func oneResult(res *liveResult) []liveResult {
+	if res == nil {
+		return nil
+	}
+	return []liveResult{*res}
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func liveWords(text string) []asr.Word {
+	var words []asr.Word
+	for _, w := range strings.Fields(text) {
+		words = append(words, asr.Word{Text: w})
+	}
+	return words
+}
+
+func TestLiveStabilize(t *testing.T) {
+	ls := &liveSession{stability: 2}
+	for i, tc := range []struct {
+		interim string
+		want    string // "" when nothing is sent
+	}{
+		{"the cat", ""},        // one interim is not enough
+		{"the cap sat", "the"}, // "the" agreed twice
+		{"the cap sat on", "the cap sat"},
+		{"the cap sat on", "the cap sat on"},
+		{"the cat sat on", ""},                       // a revision does not shrink what is shown
+		{"the cat sat on the", ""},                   // agreed, but no longer than shown
+		{"the cat sat on the", "the cat sat on the"}, // the revision comes with more words
+	} {
+		res := ls.stabilize(&liveResult{Text: tc.interim, Words: liveWords(tc.interim)})
+		got := ""
+		if res != nil {
+			got = res.Text
+		}
+		if got != tc.want {
+			t.Errorf("interim %d %q: sent %q, want %q", i, tc.interim, got, tc.want)
+		}
+	}
+
+	ls.reset()
+	if ls.stabilize(&liveResult{Words: liveWords("new")}) != nil {
+		t.Error("stability history survived reset")
+	}
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Lag policies of -live-lag-policy: what a live session does once the audio
+// waiting to be transcribed reaches -live-max-lag.
+const (
+	// liveLagBlock stops reading from the client until the decoder catches
+	// up, so the connection pushes back on the sender.
+	liveLagBlock = "block"
+	// liveLagDrop drops the oldest waiting audio. The utterance under way
+	// ends where audio is missing, and later times stay on the client's
+	// clock.
+	liveLagDrop = "drop"
+	// liveLagCompress blocks like liveLagBlock, and skips interim results
+	// while any audio is waiting, so the decoder spends its time catching
+	// up rather than on transcriptions that are stale when sent.
+	liveLagCompress = "compress"
+)
+
+// liveDefaultMaxLag bounds the waiting audio when -live-max-lag is not set.
+const liveDefaultMaxLag = 10 * time.Second
+
+func checkLiveLagPolicy(p string) error {
+	switch p {
+	case liveLagBlock, liveLagDrop, liveLagCompress:
+		return nil
+	}
+	return fmt.Errorf("unknown policy %q (block, drop or compress)", p)
+}
+
+// liveMaxLag is -live-max-lag, else liveDefaultMaxLag.
+func (s *Server) liveMaxLag() time.Duration {
+	return cmp.Or(s.config.LiveMaxLag, liveDefaultMaxLag)
+}
+
+// liveLagPolicy is -live-lag-policy, else liveLagBlock.
+func (s *Server) liveLagPolicy() string {
+	return cmp.Or(s.config.LiveLagPolicy, liveLagBlock)
+}
+
+// liveMessage is one WebSocket message waiting in a liveQueue. dropped,
+// when above zero, stands for that many bytes of audio dropped there.
+type liveMessage struct {
+	op      byte
+	data    []byte
+	dropped int
+}
+
+// liveQueue sits between the goroutine reading a live WebSocket and the one
+// transcribing it, so audio that arrives in bursts, or faster than it is
+// decoded, waits in order with the control messages, up to capacity bytes
+// of audio (-live-max-lag). lag and dropped are what the session reports.
+type liveQueue struct {
+	capacity int
+	policy   string
+	seconds  func(n int) float64 // audio bytes to seconds
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	messages []liveMessage
+	audio    int   // audio bytes waiting
+	dropped  int   // audio bytes dropped so far
+	err      error // why the input ended, once it has
+	stopped  bool
+}
+
+func newLiveQueue(capacity int, policy string, seconds func(n int) float64) *liveQueue {
+	q := &liveQueue{capacity: max(capacity, 1), policy: policy, seconds: seconds}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// put queues a message from the reader. Audio that does not fit waits for
+// room, or makes room by dropping the oldest audio with liveLagDrop. It
+// returns false once the queue is stopped.
+func (q *liveQueue) put(op byte, data []byte) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if op == wsBinary {
+		for !q.stopped && q.audio > 0 && q.audio+len(data) > q.capacity {
+			if q.policy == liveLagDrop {
+				q.dropOldest()
+				continue
+			}
+			q.cond.Wait()
+		}
+	}
+	if q.stopped {
+		return false
+	}
+	if op == wsBinary {
+		q.audio += len(data)
+	}
+	q.messages = append(q.messages, liveMessage{op: op, data: data})
+	q.cond.Broadcast()
+	return true
+}
+
+// dropOldest drops the oldest waiting audio message, leaving a marker of
+// how much was dropped in its place. The caller holds mu.
+func (q *liveQueue) dropOldest() {
+	for i, m := range q.messages {
+		if m.op != wsBinary || m.dropped > 0 || len(m.data) == 0 {
+			continue
+		}
+		n := len(m.data)
+		q.audio -= n
+		q.dropped += n
+		if i > 0 && q.messages[i-1].dropped > 0 {
+			q.messages[i-1].dropped += n
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+		} else {
+			q.messages[i] = liveMessage{op: wsBinary, dropped: n}
+		}
+		return
+	}
+}
+
+// close records that the input ended with err, after what is queued.
+func (q *liveQueue) close(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err == nil {
+		q.err = err
+	}
+	q.cond.Broadcast()
+}
+
+// stop releases a reader waiting for room, once nothing will be read.
+func (q *liveQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stopped = true
+	q.cond.Broadcast()
+}
+
+// get returns the next message, waiting for one; once the input ended and
+// everything queued was returned, it returns the input's error.
+func (q *liveQueue) get() (liveMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.messages) == 0 && q.err == nil {
+		q.cond.Wait()
+	}
+	if len(q.messages) == 0 {
+		return liveMessage{}, q.err
+	}
+	m := q.messages[0]
+	q.messages = q.messages[1:]
+	if m.op == wsBinary {
+		q.audio -= len(m.data)
+	}
+	q.cond.Broadcast()
+	return m, nil
+}
+
+// behind reports whether interim results are skipped: liveLagCompress
+// with audio waiting.
+func (q *liveQueue) behind() bool {
+	if q == nil || q.policy != liveLagCompress {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.audio > 0
+}
+
+// lag is the seconds of audio waiting to be transcribed, and dropped the
+// seconds dropped so far.
+func (q *liveQueue) lag() (lag, dropped float64) {
+	if q == nil {
+		return 0, 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.seconds(q.audio), q.seconds(q.dropped)
+}
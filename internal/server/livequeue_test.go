@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func bytesSeconds(n int) float64 { return float64(n) / 100 }
+
+func TestLiveQueueDrop(t *testing.T) {
+	q := newLiveQueue(300, liveLagDrop, bytesSeconds)
+	q.put(wsBinary, make([]byte, 100))
+	q.put(wsText, []byte(`{"type":"KeepAlive"}`))
+	q.put(wsBinary, make([]byte, 100))
+	q.put(wsBinary, make([]byte, 100))
+	// No room: the two oldest audio messages go, each leaving a marker
+	// where it was, on either side of the text message.
+	q.put(wsBinary, make([]byte, 150))
+	q.put(wsBinary, make([]byte, 50))
+	q.close(io.EOF)
+
+	if lag, dropped := q.lag(); lag != 3 || dropped != 2 {
+		t.Errorf("lag %v, dropped %v", lag, dropped)
+	}
+	var got []string
+	for {
+		m, err := q.get()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("err = %v", err)
+			}
+			break
+		}
+		switch {
+		case m.dropped > 0:
+			got = append(got, "dropped")
+		case m.op == wsText:
+			got = append(got, "text")
+		default:
+			got = append(got, "audio")
+		}
+	}
+	if want := "dropped text dropped audio audio audio"; strings.Join(got, " ") != want {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+	if lag, _ := q.lag(); lag != 0 {
+		t.Errorf("lag %v after draining", lag)
+	}
+}
+
+func TestLiveQueueBlock(t *testing.T) {
+	q := newLiveQueue(200, liveLagCompress, bytesSeconds)
+	q.put(wsBinary, make([]byte, 200))
+	if !q.behind() {
+		t.Error("compress with audio waiting is not behind")
+	}
+	put := make(chan bool)
+	go func() { put <- q.put(wsBinary, make([]byte, 100)) }()
+	select {
+	case <-put:
+		t.Fatal("put did not wait for room")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, err := q.get(); err != nil {
+		t.Fatal(err)
+	}
+	if !<-put {
+		t.Error("put failed once there was room")
+	}
+
+	// A reader waiting for room is released when the session stops.
+	q.put(wsBinary, make([]byte, 100))
+	go func() { put <- q.put(wsBinary, make([]byte, 100)) }()
+	q.stop()
+	if <-put {
+		t.Error("put succeeded on a stopped queue")
+	}
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"parakeet/internal/asr"
+)
+
+// Where "auto" looks for the memory limit, besides GOMEMLIMIT: the cgroup
+// v2 and v1 limits of a container, and the machine's memory.
+var (
+	cgroupMemoryFiles = []string{
+		"/sys/fs/cgroup/memory.max",
+		"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+	}
+	meminfoFile = "/proc/meminfo"
+)
+
+// memoryHeadroom is what an "auto" budget leaves out of the limit for the
+// Go runtime, ONNX Runtime's own arenas and the rest of the process: a
+// tenth of it, and at least 256 MiB.
+func memoryHeadroom(limit int64) int64 {
+	return max(limit/10, 256<<20)
+}
+
+// parseMemoryBudget turns -memory-budget into the budget shared by every
+// model the server loads, nil when unset.
+func parseMemoryBudget(v string) (*asr.MemoryBudget, error) {
+	switch v = strings.TrimSpace(v); v {
+	case "":
+		return nil, nil
+	case "auto":
+		limit, source := memoryLimit()
+		if limit == 0 {
+			return nil, fmt.Errorf("auto found no memory limit; set GOMEMLIMIT or a size")
+		}
+		budget := limit - memoryHeadroom(limit)
+		if budget <= 0 {
+			return nil, fmt.Errorf("auto found only %d MiB of memory (%s)", limit>>20, source)
+		}
+		slog.Info("memory budget", "budgetMiB", budget>>20, "limitMiB", limit>>20, "source", source)
+		return asr.NewMemoryBudget(budget), nil
+	}
+	n, err := parseByteSize(v)
+	if err != nil {
+		return nil, err
+	}
+	return asr.NewMemoryBudget(n), nil
+}
+
+// byteUnits are the size suffixes parseByteSize accepts: GOMEMLIMIT's
+// binary ones and their decimal counterparts.
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseByteSize parses a positive size such as "6GiB", "3000MB" or
+// "1.5GB"; a bare number is bytes.
+func parseByteSize(v string) (int64, error) {
+	number, unit := v, int64(1)
+	for _, u := range byteUnits {
+		if n, ok := strings.CutSuffix(v, u.suffix); ok {
+			number, unit = strings.TrimSpace(n), u.size
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f <= 0 || math.IsInf(f, 0) || f*float64(unit) >= math.MaxInt64 {
+		return 0, fmt.Errorf("%q is not auto or a size such as 6GiB or 3000MB", v)
+	}
+	return int64(f * float64(unit)), nil
+}
+
+// memoryLimit is the memory the process may use, and where that came from:
+// GOMEMLIMIT when set, else the tightest of the cgroup limit and the
+// machine's memory. Zero when none could be read.
+func memoryLimit() (int64, string) {
+	if limit := debug.SetMemoryLimit(-1); limit < math.MaxInt64 {
+		return limit, "GOMEMLIMIT"
+	}
+	var limit int64
+	source := ""
+	for _, path := range cgroupMemoryFiles {
+		if n := readCgroupLimit(path); n > 0 && (limit == 0 || n < limit) {
+			limit, source = n, path
+		}
+	}
+	if n := readMemTotal(meminfoFile); n > 0 && (limit == 0 || n < limit) {
+		limit, source = n, meminfoFile
+	}
+	return limit, source
+}
+
+// readCgroupLimit reads a cgroup memory limit in bytes; zero when the file
+// is missing or the cgroup is unlimited ("max" in v2, a huge number in v1).
+func readCgroupLimit(path string) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.ParseInt(string(bytes.TrimSpace(data)), 10, 64)
+	if err != nil || n <= 0 || n >= 1<<60 {
+		return 0
+	}
+	return n
+}
+
+// readMemTotal reads MemTotal from /proc/meminfo, in bytes; zero when it
+// cannot.
+func readMemTotal(path string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb << 10
+		}
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestParseByteSize(t *testing.T) {
+	for in, want := range map[string]int64{
+		"6GiB":    6 << 30,
+		"3000MB":  3e9,
+		"1.5GB":   15e8,
+		"512 MiB": 512 << 20,
+		"4096":    4096,
+	} {
+		if got, err := parseByteSize(in); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "lots", "-1GB", "0", "6XB", "1e30GB"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) succeeded", in)
+		}
+	}
+	if b, err := parseMemoryBudget(""); b != nil || err != nil {
+		t.Errorf("empty budget = %v, %v", b, err)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o644)
+		return path
+	}
+	unlimited := write("v2-max", "max\n")
+	v1 := write("v1", "9223372036854771712\n")
+	limited := write("v2", "2147483648\n")
+	meminfo := write("meminfo", "MemTotal:        8048576 kB\nMemFree:          123456 kB\n")
+
+	if n := readCgroupLimit(unlimited); n != 0 {
+		t.Errorf("unlimited v2 cgroup = %d", n)
+	}
+	if n := readCgroupLimit(v1); n != 0 {
+		t.Errorf("unlimited v1 cgroup = %d", n)
+	}
+	if n := readMemTotal(meminfo); n != 8048576<<10 {
+		t.Errorf("MemTotal = %d", n)
+	}
+
+	oldCgroup, oldMeminfo := cgroupMemoryFiles, meminfoFile
+	t.Cleanup(func() { cgroupMemoryFiles, meminfoFile = oldCgroup, oldMeminfo })
+	cgroupMemoryFiles, meminfoFile = []string{unlimited, limited}, meminfo
+	if n, source := memoryLimit(); n != 2<<30 || source != limited {
+		t.Errorf("memoryLimit = %d from %s, want the cgroup's", n, source)
+	}
+	cgroupMemoryFiles = []string{unlimited}
+	if n, source := memoryLimit(); n != 8048576<<10 || source != meminfo {
+		t.Errorf("memoryLimit = %d from %s, want MemTotal", n, source)
+	}
+}
+
+func TestTranscribeErrorOverMemoryBudget(t *testing.T) {
+	status, detail := transcribeError(fmt.Errorf("%w (needs 3000 MB)", asr.ErrOverMemoryBudget))
+	if status != http.StatusRequestEntityTooLarge || detail.Type != "invalid_request_error" {
+		t.Errorf("transcribeError = %d %+v", status, detail)
+	}
+}
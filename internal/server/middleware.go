@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// middleware wraps a handler with behaviour shared by several routes: auth,
+// request counting, logging, compression.
+type middleware func(http.Handler) http.Handler
+
+// chain wraps h in mws so that the first middleware runs first.
+func chain(h http.Handler, mws ...middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// route registers h on the server mux behind the route's middlewares.
+func (s *Server) route(pattern string, h http.HandlerFunc, mws ...middleware) {
+	s.mux.Handle(pattern, chain(h, mws...))
+}
+
+// Handler returns the server's routes behind the middlewares every request
+// goes through. Run serves it; a Go program can mount it in its own mux
+// instead, e.g. mux.Handle("/asr/", http.StripPrefix("/asr", s.Handler())).
+func (s *Server) Handler() http.Handler {
+	return chain(s.mux, assignRequestID, s.resolveClientIP, timeResponses, s.logAccess, logRequests, s.filterClients, recoverPanics, compress, decompressRequests)
+}
+
+// quietPaths are logged at debug level: probes and scrapers poll them.
+var quietPaths = map[string]bool{"/health": true, "/version": true}
+
+// logRequests logs one line per request once its response is written.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		level := slog.LevelInfo
+		if quietPaths[r.URL.Path] || r.Method == http.MethodOptions {
+			level = slog.LevelDebug
+		}
+		slog.Log(r.Context(), level, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"remote", r.RemoteAddr,
+		)
+	})
+}
+
+// gzipWriters reuses gzip writers across responses.
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// compress gzips text and JSON responses for clients that accept it. Event
+// streams and WebSocket upgrades pass through untouched, so SSE events are
+// not held back in the compressor.
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// compressible reports whether a Content-Type is worth compressing.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") || mediaType == "application/jsonl"
+}
+
+// gzipResponseWriter decides on the first write, from the Content-Type the
+// handler set, whether the response is compressed.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if !g.decided {
+		g.decided = true
+		h := g.Header()
+		if status != http.StatusNoContent && status != http.StatusNotModified && status >= 200 &&
+			h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+			h.Set("Content-Encoding", "gzip")
+			h.Add("Vary", "Accept-Encoding")
+			h.Del("Content-Length")
+			g.gz = gzipWriters.Get().(*gzip.Writer)
+			g.gz.Reset(g.ResponseWriter)
+		}
+	}
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.decided {
+		if g.Header().Get("Content-Type") == "" {
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	return g.ResponseWriter.Write(b)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// close finishes the gzip stream, if any, and returns its writer to the pool.
+func (g *gzipResponseWriter) close() {
+	if g.gz == nil {
+		return
+	}
+	g.gz.Close()
+	g.gz.Reset(nil)
+	gzipWriters.Put(g.gz)
+	g.gz = nil
+}
+
+// decompressRequests decodes request bodies sent with Content-Encoding gzip
+// or deflate (some SDKs compress WAV uploads), so handlers, and their
+// upload limit, see the decompressed body. Other codings get a 415.
+func decompressRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		coding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if coding == "" || coding == "identity" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var body io.ReadCloser
+		switch coding {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				sendError(w, "Failed to decompress request body: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
+				return
+			}
+			body = zr
+		case "deflate":
+			body = newDeflateReader(r.Body)
+		default:
+			sendUnsupportedMediaType(w, fmt.Sprintf("unsupported Content-Encoding %q (available: gzip, deflate)", coding))
+			return
+		}
+		defer body.Close()
+		r.Body = body
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newDeflateReader reads a deflate body: zlib-wrapped as HTTP specifies, or
+// the raw deflate stream some clients send instead.
+func newDeflateReader(r io.Reader) io.ReadCloser {
+	br := bufio.NewReader(r)
+	if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(br)
+}
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { order = append(order, "handler") }), mw("a"), mw("b"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if strings.Join(order, ",") != "a,b,handler" {
+		t.Fatalf("order = %v", order)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"text": "hello world"}`, 100)
+	serve := func(contentType, acceptEncoding string, flushFirst bool) *httptest.ResponseRecorder {
+		h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			if flushFirst {
+				http.NewResponseController(w).Flush()
+			}
+			io.WriteString(w, body)
+		}))
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, flushFirst := range []bool{false, true} {
+		rec := serve("application/json", "br, gzip", flushFirst)
+		if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("flushFirst=%v: headers = %v", flushFirst, rec.Header())
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := io.ReadAll(zr); string(got) != body {
+			t.Fatalf("flushFirst=%v: decompressed %d bytes, want %d", flushFirst, len(got), len(body))
+		}
+	}
+
+	for _, tc := range []struct{ contentType, acceptEncoding string }{
+		{"application/json", ""},
+		{"application/json", "gzip;q=0"},
+		{"text/event-stream", "gzip"},
+		{"audio/wav", "gzip"},
+	} {
+		rec := serve(tc.contentType, tc.acceptEncoding, false)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+			t.Errorf("%s with Accept-Encoding %q was compressed", tc.contentType, tc.acceptEncoding)
+		}
+	}
+}
+
+func TestDecompressRequests(t *testing.T) {
+	const payload = "RIFF....WAVEfmt audio bytes"
+	encode := func(coding string) []byte {
+		var buf bytes.Buffer
+		var zw io.WriteCloser
+		switch coding {
+		case "gzip":
+			zw = gzip.NewWriter(&buf)
+		case "deflate":
+			zw = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			zw, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		default:
+			buf.WriteString(payload)
+			return buf.Bytes()
+		}
+		io.WriteString(zw, payload)
+		zw.Close()
+		return buf.Bytes()
+	}
+	serve := func(header string, body []byte) *httptest.ResponseRecorder {
+		h := decompressRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, err := io.ReadAll(r.Body)
+			if err != nil || r.Header.Get("Content-Encoding") != "" {
+				http.Error(w, "bad body", http.StatusBadRequest)
+				return
+			}
+			w.Write(got)
+		}))
+		r := httptest.NewRequest("POST", "/v1/audio/transcriptions", bytes.NewReader(body))
+		if header != "" {
+			r.Header.Set("Content-Encoding", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, tc := range []struct{ header, coding string }{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"x-gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate", "raw-deflate"},
+	} {
+		rec := serve(tc.header, encode(tc.coding))
+		if rec.Code != http.StatusOK || rec.Body.String() != payload {
+			t.Errorf("%s (%s): status %d, body %q", tc.header, tc.coding, rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := serve("br", []byte(payload)); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("br: status %d, want 415", rec.Code)
+	}
+	if rec := serve("gzip", []byte(payload)); rec.Code != http.StatusBadRequest {
+		t.Errorf("corrupt gzip: status %d, want 400", rec.Code)
+	}
+}
+
+func TestHandlerServesRoutes(t *testing.T) {
+	s := &Server{mux: http.NewServeMux()}
+	s.route("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "pong")
+	})
+	srv := httptest.NewServer(http.StripPrefix("/asr", s.Handler()))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/asr/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	// The client transparently decompresses the gzipped reply.
+	if got, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(got) != "pong" || !resp.Uncompressed {
+		t.Fatalf("status %d, body %q, uncompressed %v", resp.StatusCode, got, resp.Uncompressed)
+	}
+}
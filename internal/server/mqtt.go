@@ -0,0 +1,290 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/mqtt"
+)
+
+const (
+	// mqttStatusTopic carries "online"/"offline" (retained; the broker
+	// publishes "offline" as the will if the connection drops).
+	mqttStatusTopic = "parakeet/status"
+	// mqttDiscoveryPrefix is Home Assistant's default discovery prefix.
+	mqttDiscoveryPrefix = "homeassistant"
+	// mqttResultLevel replaces the last level of an audio topic to form
+	// its result topic.
+	mqttResultLevel = "transcript"
+	// mqttMaxPayload bounds one audio message.
+	mqttMaxPayload = 64 << 20
+	// mqttTranscribeTimeout bounds the decode of one message.
+	mqttTranscribeTimeout = 5 * time.Minute
+	// mqttRetryMin and mqttRetryMax bound the reconnect backoff.
+	mqttRetryMin = 2 * time.Second
+	mqttRetryMax = time.Minute
+)
+
+// mqttPCMFormat is how headerless payloads are read: what ESPHome and
+// Wyoming satellites capture.
+var mqttPCMFormat = asr.PCMFormat{Encoding: asr.PCMLinear16, SampleRate: 16000, Channels: 1}
+
+// mqttTranscript is published on the result topic of every audio message.
+type mqttTranscript struct {
+	Topic    string           `json:"topic"` // the audio topic
+	Text     string           `json:"text"`
+	Duration float64          `json:"duration"`
+	Words    []transcriptWord `json:"words,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// mqttBridge keeps a connection to the -mqtt-broker, transcribes audio
+// published on the -mqtt-topics filters and publishes the results.
+type mqttBridge struct {
+	s         *Server
+	broker    string
+	filters   []string
+	discovery bool
+	clientID  string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// sem bounds the messages being transcribed at once.
+	sem chan struct{}
+}
+
+// parseMQTTTopics parses -mqtt-topics: comma-separated topic filters.
+func parseMQTTTopics(spec string) ([]string, error) {
+	var out []string
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		out = append(out, f)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no topics given")
+	}
+	return out, nil
+}
+
+func newMQTTBridge(s *Server, filters []string) *mqttBridge {
+	ctx, cancel := context.WithCancel(context.Background())
+	var id [4]byte
+	_, _ = rand.Read(id[:])
+	return &mqttBridge{
+		s:         s,
+		broker:    s.config.MQTTBroker,
+		filters:   filters,
+		discovery: s.config.MQTTDiscovery,
+		clientID:  "parakeet-" + hex.EncodeToString(id[:]),
+		ctx:       ctx,
+		cancel:    cancel,
+		sem:       make(chan struct{}, max(s.config.Workers, 1)),
+	}
+}
+
+// startMQTT connects in the background; an unreachable broker is retried,
+// not fatal.
+func (s *Server) startMQTT() {
+	if s.mqtt == nil {
+		return
+	}
+	s.mqtt.wg.Add(1)
+	go s.mqtt.run()
+}
+
+// close disconnects, cancelling transcriptions still running.
+func (b *mqttBridge) close() {
+	b.cancel()
+	b.wg.Wait()
+}
+
+// run keeps the bridge connected, reconnecting with backoff.
+func (b *mqttBridge) run() {
+	defer b.wg.Done()
+	backoff := mqttRetryMin
+	for b.ctx.Err() == nil {
+		started := time.Now()
+		err := b.session()
+		if b.ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > mqttRetryMax {
+			backoff = mqttRetryMin
+		}
+		slog.Warn("MQTT connection lost, reconnecting", "broker", redactURL(b.broker), "error", err, "retry_in", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-b.ctx.Done():
+		}
+		backoff = min(backoff*2, mqttRetryMax)
+	}
+}
+
+// session runs one broker connection until it drops or the bridge closes.
+func (b *mqttBridge) session() error {
+	c, err := mqtt.Dial(b.ctx, mqtt.Options{
+		Broker:    b.broker,
+		ClientID:  b.clientID,
+		Will:      &mqtt.Message{Topic: mqttStatusTopic, Payload: []byte("offline")},
+		MaxPacket: mqttMaxPayload,
+	})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if err := c.Subscribe(b.filters...); err != nil {
+		return err
+	}
+	if err := c.Publish(mqttStatusTopic, []byte("online"), true); err != nil {
+		return err
+	}
+	slog.Info("MQTT connected", "broker", redactURL(b.broker), "topics", b.filters, "discovery", b.discovery)
+
+	// Discovery configs are retained, but are sent again on every
+	// connection in case the broker lost them.
+	announced := make(map[string]bool)
+	for {
+		select {
+		case <-b.ctx.Done():
+			// DISCONNECT suppresses the will, so say goodbye explicitly.
+			c.Publish(mqttStatusTopic, []byte("offline"), true)
+			return nil
+		case msg, ok := <-c.Messages():
+			if !ok {
+				return c.Err()
+			}
+			result, ok := mqttResultTopic(msg.Topic)
+			// Retained audio was already handled when it was published.
+			if !ok || msg.Retain || len(msg.Payload) == 0 {
+				continue
+			}
+			if b.discovery && !announced[result] {
+				topic, config := b.discoveryConfig(result)
+				if err := c.Publish(topic, config, true); err != nil {
+					return err
+				}
+				announced[result] = true
+			}
+			select {
+			case b.sem <- struct{}{}:
+			case <-b.ctx.Done():
+				continue
+			}
+			b.wg.Add(1)
+			go b.handle(c, msg, result)
+		}
+	}
+}
+
+// handle transcribes one audio message and publishes the result.
+func (b *mqttBridge) handle(c *mqtt.Client, msg mqtt.Message, result string) {
+	defer b.wg.Done()
+	defer func() { <-b.sem }()
+
+	ctx, cancel := context.WithTimeout(b.ctx, mqttTranscribeTimeout)
+	defer cancel()
+	t := mqttTranscript{Topic: msg.Topic}
+	res, _, err := b.s.transcribe(ctx, mqttAudio(msg.Payload), asr.TranscribeOptions{
+		Format:            ".wav",
+		Channels:          asr.ChannelMix,
+		Conditioning:      b.s.conditioning,
+		Segmentation:      b.s.segmentation,
+		NoSpeechThreshold: b.s.config.NoSpeechThreshold,
+		Guard:             b.s.guard,
+		Confidence:        b.s.confidence,
+		Formatting:        b.s.formatting,
+		Fallback:          b.s.fallback,
+		Denoise:           b.s.config.Denoise,
+	})
+	if err != nil {
+		if b.ctx.Err() != nil {
+			return
+		}
+		slog.Warn("MQTT transcription failed", "topic", msg.Topic, "error", err)
+		t.Error = err.Error()
+	} else {
+		t.Text, t.Duration = res.Text, res.Duration
+		t.Words = make([]transcriptWord, len(res.Words))
+		for i, w := range res.Words {
+			t.Words[i] = transcriptWord{Text: w.Text, Start: w.Start, End: w.End, Confidence: w.Confidence}
+		}
+	}
+	payload, _ := json.Marshal(t)
+	if err := c.Publish(result, payload, false); err != nil {
+		slog.Warn("MQTT publish failed", "topic", result, "error", err)
+	}
+}
+
+// mqttResultTopic is the topic a message's transcript is published on:
+// the audio topic with its last level replaced by "transcript", so
+// parakeet/kitchen/audio answers on parakeet/kitchen/transcript. Result
+// topics themselves (matched by a broad filter such as #) are not audio.
+func mqttResultTopic(topic string) (string, bool) {
+	prefix, last := "", topic
+	if i := strings.LastIndexByte(topic, '/'); i >= 0 {
+		prefix, last = topic[:i+1], topic[i+1:]
+	}
+	if last == mqttResultLevel || topic == mqttStatusTopic || strings.HasPrefix(topic, mqttDiscoveryPrefix+"/") {
+		return "", false
+	}
+	return prefix + mqttResultLevel, true
+}
+
+// mqttAudio returns a payload as a WAV file: WAV payloads as they are,
+// anything else as raw 16 kHz 16-bit mono PCM.
+func mqttAudio(payload []byte) []byte {
+	if len(payload) >= 12 && string(payload[0:4]) == "RIFF" && string(payload[8:12]) == "WAVE" {
+		return payload
+	}
+	return mqttPCMFormat.WAV(payload)
+}
+
+// discoveryConfig returns the Home Assistant MQTT discovery message that
+// exposes a result topic as a sensor holding the last transcript.
+func (b *mqttBridge) discoveryConfig(result string) (topic string, payload []byte) {
+	satellite := strings.TrimSuffix(result, "/"+mqttResultLevel)
+	if i := strings.LastIndexByte(satellite, '/'); i >= 0 {
+		satellite = satellite[i+1:]
+	}
+	name := "Transcript"
+	if satellite != "" && satellite != mqttResultLevel {
+		name = satellite + " transcript"
+	}
+	id := "parakeet_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSuffix(result, "/"+mqttResultLevel))
+
+	payload, _ = json.Marshal(map[string]any{
+		"name":                  name,
+		"unique_id":             id,
+		"state_topic":           result,
+		"value_template":        "{{ value_json.text[:255] }}", // states are capped at 255 characters
+		"json_attributes_topic": result,
+		"availability_topic":    mqttStatusTopic,
+		"icon":                  "mdi:microphone-message",
+		"device": map[string]any{
+			"identifiers": []string{"parakeet"},
+			"name":        "Parakeet ASR",
+			"sw_version":  b.s.config.Build.Version,
+		},
+	})
+	return mqttDiscoveryPrefix + "/sensor/" + id + "/config", payload
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestMQTTResultTopic(t *testing.T) {
+	for topic, want := range map[string]string{
+		"parakeet/kitchen/audio":          "parakeet/kitchen/transcript",
+		"audio":                           "transcript",
+		"parakeet/kitchen/transcript":     "",
+		"parakeet/status":                 "",
+		"homeassistant/sensor/x/config":   "",
+		"hermes/audioServer/office/audio": "hermes/audioServer/office/transcript",
+	} {
+		got, ok := mqttResultTopic(topic)
+		if got != want || ok != (want != "") {
+			t.Errorf("%s: got %q, %v; want %q", topic, got, ok, want)
+		}
+	}
+}
+
+func TestMQTTAudio(t *testing.T) {
+	wav := mqttPCMFormat.WAV(make([]byte, 320))
+	if got := mqttAudio(wav); !bytes.Equal(got, wav) {
+		t.Error("WAV payload was re-wrapped")
+	}
+	if got := mqttAudio(make([]byte, 320)); !bytes.Equal(got, wav) {
+		t.Error("raw PCM was not wrapped as 16 kHz mono WAV")
+	}
+}
+
+// mqttTestPacket reads one MQTT packet from a fake broker's connection.
+func mqttTestPacket(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	header, err := r.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := binary.ReadUvarint(r) // MQTT's remaining length is a uvarint
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatal(err)
+	}
+	return header, body
+}
+
+func mqttTestString(s string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(s))), s...)
+}
+
+func TestMQTTBridge(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	audio := mqttPCMFormat.WAV(make([]byte, 3200))
+	s := &Server{stats: newServerStats(), cache: newMemoryCache(4), config: Config{
+		MQTTBroker:    "mqtt://" + ln.Addr().String(),
+		MQTTDiscovery: true,
+		Build:         BuildInfo{Version: "v1.2.3"},
+	}}
+	// A cached result stands in for the model.
+	s.cache.Put(s.cacheKey(audio, asr.TranscribeOptions{Format: ".wav", Channels: asr.ChannelMix}),
+		&asr.Result{Text: "turn on the lights", Duration: 0.1})
+	s.mqtt = newMQTTBridge(s, []string{"parakeet/+/audio"})
+	s.startMQTT()
+	defer s.mqtt.close()
+
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	send := func(header byte, body []byte) {
+		conn.Write(append(binary.AppendUvarint([]byte{header}, uint64(len(body))), body...))
+	}
+
+	if typ, body := mqttTestPacket(t, r); typ != 0x10 || !bytes.Contains(body, []byte(mqttStatusTopic)) {
+		t.Fatalf("CONNECT = %#x %q", typ, body)
+	}
+	send(0x20, []byte{0, 0})
+	typ, sub := mqttTestPacket(t, r)
+	if typ != 0x82 || !bytes.Contains(sub, []byte("parakeet/+/audio")) {
+		t.Fatalf("SUBSCRIBE = %#x %q", typ, sub)
+	}
+	send(0x90, append(bytes.Clone(sub[:2]), 1))
+
+	// Reads PUBLISH packets, skipping pings.
+	publish := func() (string, []byte, bool) {
+		for {
+			typ, body := mqttTestPacket(t, r)
+			if typ>>4 != 3 {
+				continue
+			}
+			n := int(binary.BigEndian.Uint16(body))
+			return string(body[2 : 2+n]), body[2+n:], typ&1 != 0
+		}
+	}
+	if topic, payload, retain := publish(); topic != mqttStatusTopic || string(payload) != "online" || !retain {
+		t.Fatalf("first publish = %s %q", topic, payload)
+	}
+
+	send(0x30, append(mqttTestString("parakeet/kitchen/audio"), audio...))
+
+	topic, payload, retain := publish()
+	if topic != "homeassistant/sensor/parakeet_parakeet_kitchen/config" || !retain {
+		t.Fatalf("discovery publish = %s", topic)
+	}
+	var config map[string]any
+	if err := json.Unmarshal(payload, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["state_topic"] != "parakeet/kitchen/transcript" || config["name"] != "kitchen transcript" ||
+		!strings.Contains(string(payload), `"sw_version":"v1.2.3"`) {
+		t.Errorf("discovery config = %s", payload)
+	}
+
+	topic, payload, _ = publish()
+	var got mqttTranscript
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	if topic != "parakeet/kitchen/transcript" || got.Text != "turn on the lights" || got.Topic != "parakeet/kitchen/audio" {
+		t.Errorf("transcript on %s = %+v", topic, got)
+	}
+}
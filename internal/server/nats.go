@@ -0,0 +1,318 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/nats"
+)
+
+const (
+	// natsFetchExpiry is how long one pull request waits for messages.
+	natsFetchExpiry = 30 * time.Second
+	// natsProgressInterval is how often a message being transcribed has its
+	// ack timer reset, so long files are not redelivered mid-decode.
+	natsProgressInterval = 10 * time.Second
+	// natsTranscribeTimeout bounds the work on one message.
+	natsTranscribeTimeout = 30 * time.Minute
+	// natsPublishTimeout bounds the wait for the output stream's ack.
+	natsPublishTimeout = 30 * time.Second
+	// natsRetryMin and natsRetryMax bound the reconnect backoff.
+	natsRetryMin = 2 * time.Second
+	natsRetryMax = time.Minute
+)
+
+// errNATSFetch marks a failed audio_url download, which is retried by
+// redelivery.
+var errNATSFetch = errors.New("fetch audio")
+
+// natsRequest is a JSON input message: a pointer to the audio instead of
+// the audio itself, for files larger than the server's max_payload.
+type natsRequest struct {
+	ID       string `json:"id"`
+	AudioURL string `json:"audio_url"`
+	Language string `json:"language"`
+}
+
+// natsTranscript is published to the output subject for every input
+// message.
+type natsTranscript struct {
+	ID        string           `json:"id,omitempty"` // from the request or its Nats-Msg-Id
+	Subject   string           `json:"subject"`      // the input subject
+	StreamSeq uint64           `json:"stream_seq"`
+	Text      string           `json:"text"`
+	Duration  float64          `json:"duration"`
+	Words     []transcriptWord `json:"words,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// natsWorker consumes audio from a JetStream pull consumer and publishes
+// transcripts, acknowledging each input only once its result is stored.
+type natsWorker struct {
+	s        *Server
+	url      string
+	stream   string
+	consumer string
+	output   string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// slots bounds the messages being worked on at once.
+	slots chan struct{}
+}
+
+func newNATSWorker(s *Server) *natsWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := s.config.NATSConcurrency
+	if n < 1 {
+		n = max(s.config.Workers, 1)
+	}
+	return &natsWorker{
+		s:        s,
+		url:      s.config.NATSURL,
+		stream:   s.config.NATSStream,
+		consumer: s.config.NATSConsumer,
+		output:   s.config.NATSOutputSubject,
+		ctx:      ctx,
+		cancel:   cancel,
+		slots:    make(chan struct{}, n),
+	}
+}
+
+// startNATS connects in the background; an unreachable server is retried,
+// not fatal.
+func (s *Server) startNATS() {
+	if s.nats == nil {
+		return
+	}
+	s.nats.wg.Add(1)
+	go s.nats.run()
+}
+
+// close stops pulling. Messages still being transcribed are cancelled and
+// handed back for redelivery.
+func (nw *natsWorker) close() {
+	nw.cancel()
+	nw.wg.Wait()
+}
+
+// run keeps the worker connected, reconnecting with backoff.
+func (nw *natsWorker) run() {
+	defer nw.wg.Done()
+	backoff := natsRetryMin
+	for nw.ctx.Err() == nil {
+		started := time.Now()
+		err := nw.session()
+		if nw.ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > natsRetryMax {
+			backoff = natsRetryMin
+		}
+		slog.Warn("NATS worker disconnected, reconnecting", "url", redactURL(nw.url), "error", err, "retry_in", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-nw.ctx.Done():
+		}
+		backoff = min(backoff*2, natsRetryMax)
+	}
+}
+
+// session runs one connection: it pulls as many messages as there are free
+// slots and hands each to its own goroutine.
+func (nw *natsWorker) session() error {
+	c, err := nats.Dial(nw.ctx, nats.Options{URL: nw.url, Name: "parakeet"})
+	if err != nil {
+		return err
+	}
+	var handlers sync.WaitGroup
+	defer func() {
+		handlers.Wait()
+		c.Close()
+	}()
+
+	info, err := c.ConsumerInfo(nw.ctx, nw.stream, nw.consumer)
+	if err != nil {
+		return fmt.Errorf("consumer %s/%s: %w", nw.stream, nw.consumer, err)
+	}
+	if info.Config.DeliverSubject != "" {
+		return fmt.Errorf("consumer %s/%s is a push consumer; a pull consumer is required", nw.stream, nw.consumer)
+	}
+	if info.Config.AckPolicy != "explicit" {
+		return fmt.Errorf("consumer %s/%s has ack policy %q; explicit is required", nw.stream, nw.consumer, info.Config.AckPolicy)
+	}
+	slog.Info("NATS worker connected", "url", redactURL(nw.url), "stream", nw.stream, "consumer", nw.consumer,
+		"output", nw.output, "concurrency", cap(nw.slots), "pending", info.NumPending)
+
+	for {
+		// Wait for one free slot, then take every other free one.
+		select {
+		case nw.slots <- struct{}{}:
+		case <-nw.ctx.Done():
+			return nil
+		case <-c.Done():
+			return c.Err()
+		}
+		batch := 1
+	take:
+		for batch < cap(nw.slots) {
+			select {
+			case nw.slots <- struct{}{}:
+				batch++
+			default:
+				break take
+			}
+		}
+
+		msgs, err := c.Fetch(nw.ctx, nw.stream, nw.consumer, batch, natsFetchExpiry)
+		for range batch - len(msgs) {
+			<-nw.slots
+		}
+		for _, msg := range msgs {
+			handlers.Add(1)
+			go func() {
+				defer handlers.Done()
+				defer func() { <-nw.slots }()
+				nw.handle(c, msg)
+			}()
+		}
+		if err != nil {
+			if nw.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// handle transcribes one message, publishes the result and acknowledges
+// the input. Audio that cannot be fetched, a shutdown and a failed publish
+// hand the message back for redelivery; invalid requests and audio that
+// cannot be decoded are answered with an error result, since retrying
+// would not help.
+func (nw *natsWorker) handle(c *nats.Conn, msg nats.Msg) {
+	md, _ := msg.Metadata()
+	out := natsTranscript{ID: msg.Header.Get("Nats-Msg-Id"), Subject: msg.Subject, StreamSeq: md.StreamSeq}
+
+	ctx, cancel := context.WithTimeout(nw.ctx, natsTranscribeTimeout)
+	defer cancel()
+	stop := nw.keepAlive(c, msg)
+	defer stop()
+
+	audio, language, err := nw.load(ctx, msg, &out)
+	switch {
+	case nw.ctx.Err() != nil:
+		c.Nak(msg)
+		return
+	case errors.Is(err, errNATSFetch):
+		slog.Warn("NATS message audio unavailable, will be redelivered", "stream_seq", md.StreamSeq,
+			"delivered", md.Delivered, "error", err)
+		c.Nak(msg)
+		return
+	case err != nil:
+		out.Error = err.Error()
+	default:
+		res, _, err := nw.s.transcribe(ctx, audio, asr.TranscribeOptions{
+			Format:            ".wav",
+			Language:          language,
+			Channels:          asr.ChannelMix,
+			Conditioning:      nw.s.conditioning,
+			Segmentation:      nw.s.segmentation,
+			NoSpeechThreshold: nw.s.config.NoSpeechThreshold,
+			Guard:             nw.s.guard,
+			Confidence:        nw.s.confidence,
+			Formatting:        nw.s.formatting,
+			Fallback:          nw.s.fallback,
+			Denoise:           nw.s.config.Denoise,
+			Priority:          asr.PriorityBatch,
+		})
+		if nw.ctx.Err() != nil {
+			c.Nak(msg)
+			return
+		}
+		if err != nil {
+			out.Error = err.Error()
+			break
+		}
+		out.Text, out.Duration = res.Text, res.Duration
+		out.Words = make([]transcriptWord, len(res.Words))
+		for i, w := range res.Words {
+			out.Words[i] = transcriptWord{Text: w.Text, Start: w.Start, End: w.End, Confidence: w.Confidence}
+		}
+	}
+	if out.Error != "" {
+		slog.Warn("NATS message failed", "stream_seq", md.StreamSeq, "error", out.Error)
+	}
+
+	payload, _ := json.Marshal(out)
+	// The stream drops a result published again after a redelivery.
+	header := textproto.MIMEHeader{"Nats-Msg-Id": {"parakeet-" + nw.stream + "-" + strconv.FormatUint(md.StreamSeq, 10)}}
+	pubCtx, pubCancel := context.WithTimeout(nw.ctx, natsPublishTimeout)
+	defer pubCancel()
+	if _, err := c.PublishJS(pubCtx, nw.output, header, payload); err != nil {
+		slog.Warn("NATS result publish failed, input will be redelivered", "stream_seq", md.StreamSeq, "error", err)
+		c.Nak(msg)
+		return
+	}
+	if err := c.Ack(msg); err != nil {
+		slog.Warn("NATS ack failed, input will be redelivered", "stream_seq", md.StreamSeq, "error", err)
+	}
+}
+
+// load returns a message's audio: the payload itself, or for a JSON
+// request the file at its audio_url.
+func (nw *natsWorker) load(ctx context.Context, msg nats.Msg, out *natsTranscript) ([]byte, string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(msg.Data), []byte("{")) {
+		if len(msg.Data) == 0 {
+			return nil, "", errors.New("empty message")
+		}
+		return msg.Data, "", nil
+	}
+	var req natsRequest
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		return nil, "", fmt.Errorf("invalid JSON request: %w", err)
+	}
+	if req.ID != "" {
+		out.ID = req.ID
+	}
+	if err := checkHTTPURL(req.AudioURL); err != nil {
+		return nil, "", fmt.Errorf("audio_url: %w", err)
+	}
+	audio, err := fetchAudio(ctx, req.AudioURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", errNATSFetch, err)
+	}
+	return audio, req.Language, nil
+}
+
+// keepAlive resets the message's ack timer until the returned func is
+// called.
+func (nw *natsWorker) keepAlive(c *nats.Conn, msg nats.Msg) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(natsProgressInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				c.InProgress(msg)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
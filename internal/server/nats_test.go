@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestNATSWorker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	audio := mqttPCMFormat.WAV(make([]byte, 3200))
+	s := &Server{stats: newServerStats(), cache: newMemoryCache(4), config: Config{
+		NATSURL:           "nats://" + ln.Addr().String(),
+		NATSStream:        "AUDIO",
+		NATSConsumer:      "parakeet",
+		NATSOutputSubject: "transcripts",
+	}}
+	// A cached result stands in for the model.
+	s.cache.Put(s.cacheKey(audio, asr.TranscribeOptions{Format: ".wav", Channels: asr.ChannelMix}),
+		&asr.Result{Text: "quarterly numbers", Duration: 0.1})
+	s.nats = newNATSWorker(s)
+	s.startNATS()
+	defer s.nats.close()
+
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	write := func(format string, args ...any) { fmt.Fprintf(conn, format, args...) }
+	// next returns the client's next operation and, for publishes, its
+	// payload.
+	next := func() ([]string, string) {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := strings.Fields(line)
+			switch f[0] {
+			case "PING":
+				write("PONG\r\n")
+				continue
+			case "PUB", "HPUB":
+				n, _ := strconv.Atoi(f[len(f)-1])
+				buf := make([]byte, n+2)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					t.Fatal(err)
+				}
+				return f, string(buf[:n])
+			}
+			return f, ""
+		}
+	}
+
+	write("INFO {\"headers\":true,\"max_payload\":1048576}\r\n")
+	if op, _ := next(); op[0] != "CONNECT" {
+		t.Fatalf("got %v, want CONNECT", op)
+	}
+	write("PONG\r\n")
+
+	// The worker checks the consumer through the JetStream API.
+	sub, _ := next()
+	respSID := sub[2]
+	op, _ := next()
+	if op[1] != "$JS.API.CONSUMER.INFO.AUDIO.parakeet" {
+		t.Fatalf("got %v", op)
+	}
+	info := `{"stream_name":"AUDIO","name":"parakeet","config":{"ack_policy":"explicit"},"num_pending":1}`
+	write("MSG %s %s %d\r\n%s\r\n", op[2], respSID, len(info), info)
+
+	// It pulls one message (concurrency 1) and gets the audio.
+	sub, _ = next()
+	inbox, pullSID := sub[1], sub[2]
+	if op, payload := next(); op[1] != "$JS.API.CONSUMER.MSG.NEXT.AUDIO.parakeet" || op[2] != inbox || !strings.Contains(payload, `"batch":1`) {
+		t.Fatalf("pull = %v %s", op, payload)
+	}
+	ack := "$JS.ACK.AUDIO.parakeet.1.17.1.1700000000000000000.0"
+	write("MSG audio.in %s %s %d\r\n%s\r\n", pullSID, ack, len(audio), audio)
+
+	// The result goes out with a stable Nats-Msg-Id and is acknowledged by
+	// the output stream before the input is acked.
+	var pub []string
+	var payload string
+	for {
+		if pub, payload = next(); pub[0] == "HPUB" {
+			break
+		}
+	}
+	if pub[1] != "transcripts" || !strings.Contains(payload, "Nats-Msg-Id: parakeet-AUDIO-17") {
+		t.Fatalf("result publish = %v %q", pub, payload)
+	}
+	var got natsTranscript
+	if err := json.Unmarshal([]byte(payload[strings.Index(payload, "\r\n\r\n")+4:]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "quarterly numbers" || got.StreamSeq != 17 || got.Subject != "audio.in" {
+		t.Errorf("result = %+v", got)
+	}
+	pubAck := `{"stream":"RESULTS","seq":1}`
+	write("MSG %s %s %d\r\n%s\r\n", pub[2], respSID, len(pubAck), pubAck)
+
+	for {
+		op, payload := next()
+		if op[0] == "PUB" && op[1] == ack {
+			if payload != "+ACK" {
+				t.Errorf("input acked with %q", payload)
+			}
+			break
+		}
+	}
+}
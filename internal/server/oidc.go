@@ -0,0 +1,437 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // RS256, ES256
+	_ "crypto/sha512" // RS384, RS512, ES384, ES512
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// oidcFetchTimeout bounds one discovery or JWKS request.
+	oidcFetchTimeout = 10 * time.Second
+	// oidcKeysMaxAge is how long fetched signing keys are used before they
+	// are fetched again; a token with an unknown key ID fetches them sooner,
+	// but at most once per oidcRefetchInterval.
+	oidcKeysMaxAge      = time.Hour
+	oidcRefetchInterval = time.Minute
+	oidcClockSkew       = time.Minute
+	oidcDocumentLimit   = 1 << 20
+	oidcDiscoveryPath   = "/.well-known/openid-configuration"
+)
+
+// oidcVerifier validates bearer JWTs issued by an OIDC provider: the
+// signature against the issuer's JWKS, then iss, aud, exp and nbf. It is
+// an alternative to static API keys, so corporate SSO can gate the API.
+type oidcVerifier struct {
+	issuer   string
+	audience string
+
+	// adminClaim and adminValue are the claim (a dotted path into nested
+	// objects) and the value, or one of the values, that make a token an
+	// admin; empty when no token is.
+	adminClaim string
+	adminValue string
+
+	mu      sync.Mutex
+	jwksURI string // from discovery, on first use
+	keys    map[string]crypto.PublicKey
+	fetched time.Time // last JWKS fetch, successful or not
+
+	now func() time.Time // for tests
+}
+
+// newOIDCVerifier checks the -oidc-* settings. adminClaim is
+// -oidc-admin-claim, "claim=value" or empty. Nothing is fetched yet: an
+// unreachable provider fails requests, not startup.
+func newOIDCVerifier(issuer, audience, adminClaim string) (*oidcVerifier, error) {
+	if err := checkHTTPURL(issuer); err != nil {
+		return nil, fmt.Errorf("invalid -oidc-issuer: %w", err)
+	}
+	if audience == "" {
+		return nil, errors.New("-oidc-issuer requires -oidc-audience")
+	}
+	v := &oidcVerifier{issuer: strings.TrimSuffix(issuer, "/"), audience: audience, now: time.Now}
+	if adminClaim != "" {
+		claim, value, ok := strings.Cut(adminClaim, "=")
+		if !ok || claim == "" || value == "" || claim == "sub" {
+			return nil, fmt.Errorf("invalid -oidc-admin-claim %q: want claim=value, e.g. groups=parakeet-admins, on a claim other than sub", adminClaim)
+		}
+		v.adminClaim, v.adminValue = claim, value
+	}
+	return v, nil
+}
+
+// looksLikeJWT tells tokens worth verifying from static keys, which are
+// never three dot-separated parts.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// jwtHeader and jwtClaims are the parts of a token that are checked.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer    string       `json:"iss"`
+	Subject   string       `json:"sub"`
+	Audience  jwtAudience  `json:"aud"`
+	ExpiresAt *json.Number `json:"exp"`
+	NotBefore *json.Number `json:"nbf"`
+}
+
+// jwtAudience is "aud", a string or an array of strings.
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = jwtAudience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+// isAdmin reports whether token is valid and carries -oidc-admin-claim.
+func (v *oidcVerifier) isAdmin(token string) bool {
+	if v.adminClaim == "" {
+		return false
+	}
+	if _, err := v.verify(token); err != nil {
+		slog.Debug("admin bearer token rejected", "error", err)
+		return false
+	}
+	var claims map[string]any
+	if err := decodeJWTPart(strings.Split(token, ".")[1], &claims); err != nil {
+		return false
+	}
+	return claimHas(claims, v.adminClaim, v.adminValue)
+}
+
+// claimHas reports whether the claim at path, a string or an array of
+// strings, is or holds want. Dots in path descend into nested objects, as
+// in Keycloak's realm_access.roles.
+func claimHas(claims map[string]any, path, want string) bool {
+	var v any = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		v = m[key]
+	}
+	switch v := v.(type) {
+	case string:
+		return v == want
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verify checks token and returns its subject, the name its usage is
+// accounted under.
+func (v *oidcVerifier) verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("not a JWT")
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return "", fmt.Errorf("header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("signature: %w", err)
+	}
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return "", err
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("claims: %w", err)
+	}
+	if strings.TrimSuffix(claims.Issuer, "/") != v.issuer {
+		return "", fmt.Errorf("issuer %q not accepted", claims.Issuer)
+	}
+	if !containsString(claims.Audience, v.audience) {
+		return "", fmt.Errorf("audience %v not accepted", []string(claims.Audience))
+	}
+	now := v.now()
+	if claims.ExpiresAt == nil {
+		return "", errors.New("no exp claim")
+	}
+	if exp, err := claims.ExpiresAt.Int64(); err != nil || now.After(time.Unix(exp, 0).Add(oidcClockSkew)) {
+		return "", errors.New("token expired")
+	}
+	if claims.NotBefore != nil {
+		if nbf, err := claims.NotBefore.Int64(); err != nil || now.Add(oidcClockSkew).Before(time.Unix(nbf, 0)) {
+			return "", errors.New("token not valid yet")
+		}
+	}
+	if claims.Subject == "" {
+		return "", errors.New("no sub claim")
+	}
+	return claims.Subject, nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyJWTSignature checks sig over signed with key for alg. Only
+// asymmetric algorithms are accepted: "none" and HMAC would let anyone who
+// knows the public keys mint tokens.
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("algorithm %q not accepted", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg[0] != 'R' {
+			return fmt.Errorf("algorithm %s does not match an RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, sig); err != nil {
+			return errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[0] != 'E' || len(sig) != 2*size {
+			return fmt.Errorf("algorithm %s does not match the EC key", alg)
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.New("unsupported key type")
+	}
+	return nil
+}
+
+// key returns the signing key kid, fetching the JWKS when the keys are
+// stale or do not have it. A token without kid is accepted when the
+// provider publishes a single key.
+func (v *oidcVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	lookup := func() crypto.PublicKey {
+		if kid == "" && len(v.keys) == 1 {
+			for _, k := range v.keys {
+				return k
+			}
+		}
+		return v.keys[kid]
+	}
+	stale := now.Sub(v.fetched) > oidcKeysMaxAge
+	if k := lookup(); k != nil && !stale {
+		return k, nil
+	}
+	if stale || now.Sub(v.fetched) > oidcRefetchInterval {
+		v.fetched = now
+		keys, err := v.fetchKeys()
+		if err != nil {
+			slog.Warn("OIDC signing keys could not be fetched", "issuer", v.issuer, "error", err)
+			// Keep serving with the keys we have, if any.
+			if k := lookup(); k != nil {
+				return k, nil
+			}
+			return nil, fmt.Errorf("fetch signing keys: %w", err)
+		}
+		v.keys = keys
+	}
+	if k := lookup(); k != nil {
+		return k, nil
+	}
+	if v.keys == nil {
+		return nil, errors.New("signing keys unavailable")
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetchKeys reads the provider's signing keys, discovering the JWKS URL
+// first. Called with mu held.
+func (v *oidcVerifier) fetchKeys() (map[string]crypto.PublicKey, error) {
+	if v.jwksURI == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := fetchJSON(v.issuer+oidcDiscoveryPath, &doc); err != nil {
+			return nil, fmt.Errorf("discovery: %w", err)
+		}
+		if strings.TrimSuffix(doc.Issuer, "/") != v.issuer {
+			return nil, fmt.Errorf("discovery document is for issuer %q", doc.Issuer)
+		}
+		if err := checkHTTPURL(doc.JWKSURI); err != nil {
+			return nil, fmt.Errorf("discovery jwks_uri: %w", err)
+		}
+		v.jwksURI = doc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := fetchJSON(v.jwksURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of other types or curves are skipped, not fatal: tokens
+		// signed with them are rejected as unknown.
+		if k, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = k
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no usable signing keys")
+	}
+	return keys, nil
+}
+
+// fetchJSON GETs url into v, bounded in time and size.
+func fetchJSON(url string, v any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcDocumentLimit)).Decode(v)
+}
+
+// jsonWebKey is one RSA or EC key of a JWKS (RFC 7517).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testIssuer is an OIDC provider with one RSA and one EC signing key.
+type testIssuer struct {
+	srv       *httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	jwksFetch atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	iss := &testIssuer{}
+	var err error
+	if iss.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if iss.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": iss.srv.URL, "jwks_uri": iss.srv.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		iss.jwksFetch.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(iss.rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(iss.rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(iss.ecKey.X.FillBytes(make([]byte, 32))), "y": b64(iss.ecKey.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": b64(iss.rsaKey.N.Bytes()), "e": "AQAB"},
+		}})
+	})
+	iss.srv = httptest.NewServer(mux)
+	t.Cleanup(iss.srv.Close)
+	return iss
+}
+
+// token signs claims with alg (RS256 or ES256) under kid.
+func (iss *testIssuer) token(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	enc := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	iss := newTestIssuer(t)
+	v, err := newOIDCVerifier(iss.srv.URL+"/", "parakeet", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	claims := func(edit func(map[string]any)) map[string]any {
+		c := map[string]any{"iss": iss.srv.URL, "sub": "alice", "aud": []string{"other", "parakeet"}, "exp": now.Add(time.Hour).Unix()}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+
+	for _, alg := range []string{"RS256", "ES256"} {
+		kid := map[string]string{"RS256": "rsa-1", "ES256": "ec-1"}[alg]
+		if sub, err := v.verify(iss.token(t, alg, kid, claims(nil))); err != nil || sub != "alice" {
+			t.Errorf("%s: verify = %q, %v; want alice", alg, sub, err)
+		}
+	}
+
+	rsaToken := iss.token(t, "RS256", "rsa-1", claims(nil))
+	for name, tc := range map[string]struct {
+		token string
+		want  string
+	}{
+		"wrong audience":  {iss.token(t, "RS256", "rsa-1", claims(func(c map[string]any) { c["aud"] = "other" })), "audience"},
+		"wrong issuer":    {iss.token(t, "RS256", "rsa-1", claims(func(c map[string]any) { c["iss"] = "https://evil.example" })), "issuer"},
+		"expired":         {iss.token(t, "RS256", "rsa-1", claims(func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() })), "expired"},
+		"no exp":          {iss.token(t, "RS256", "rsa-1", claims(func(c map[string]any) { delete(c, "exp") })), "exp"},
+		"not yet valid":   {iss.token(t, "RS256", "rsa-1", claims(func(c map[string]any) { c["nbf"] = now.Add(time.Hour).Unix() })), "not valid yet"},
+		"no subject":      {iss.token(t, "RS256", "rsa-1", claims(func(c map[string]any) { delete(c, "sub") })), "sub"},
+		"tampered claims": {rsaToken[:strings.Index(rsaToken, ".")+1] + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory"}`)) + rsaToken[strings.LastIndex(rsaToken, "."):], "invalid signature"},
+		"alg none":        {iss.token(t, "none", "rsa-1", claims(nil)), "not accepted"},
+		"alg for EC key":  {iss.token(t, "RS256", "ec-1", claims(nil)), "does not match"},
+		"encryption key":  {iss.token(t, "RS256", "enc-1", claims(nil)), "unknown signing key"},
+	} {
+		if _, err := v.verify(tc.token); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: verify error %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestOIDCVerifier_RefetchesKeys(t *testing.T) {
+	iss := newTestIssuer(t)
+	v, err := newOIDCVerifier(iss.srv.URL, "parakeet", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	v.now = func() time.Time { return now }
+	claims := map[string]any{"iss": iss.srv.URL, "sub": "alice", "aud": "parakeet", "exp": now.Add(2 * time.Hour).Unix()}
+
+	if _, err := v.verify(iss.token(t, "RS256", "rsa-1", claims)); err != nil {
+		t.Fatal(err)
+	}
+	// Unknown key IDs fetch the keys again, at most once a minute.
+	v.verify(iss.token(t, "RS256", "rotated", claims))
+	v.verify(iss.token(t, "RS256", "rotated", claims))
+	if n := iss.jwksFetch.Load(); n != 1 {
+		t.Fatalf("JWKS fetched %d times within a minute, want 1", n)
+	}
+	now = now.Add(2 * time.Minute)
+	v.verify(iss.token(t, "RS256", "rotated", claims))
+	if n := iss.jwksFetch.Load(); n != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2 after the refetch interval", n)
+	}
+	// Known keys are fetched again once they are an hour old.
+	now = now.Add(time.Hour + time.Second)
+	if _, err := v.verify(iss.token(t, "RS256", "rsa-1", claims)); err != nil {
+		t.Fatal(err)
+	}
+	if n := iss.jwksFetch.Load(); n != 3 {
+		t.Fatalf("JWKS fetched %d times, want 3 after an hour", n)
+	}
+}
+
+func TestRequireAuth_OIDC(t *testing.T) {
+	iss := newTestIssuer(t)
+	oidc, err := newOIDCVerifier(iss.srv.URL, "parakeet", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{apiKey: "static", oidc: oidc}
+	var name string
+	h := s.requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name = apiKeyNameFrom(r.Context())
+	}))
+	call := func(token string) int {
+		name = ""
+		req := httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	token := iss.token(t, "ES256", "ec-1", map[string]any{"iss": iss.srv.URL, "sub": "bob", "aud": "parakeet", "exp": time.Now().Add(time.Hour).Unix()})
+	if code := call(token); code != http.StatusOK || name != "bob" {
+		t.Errorf("JWT: status %d, name %q; want 200, bob", code, name)
+	}
+	if code := call("static"); code != http.StatusOK || name != defaultKeyName {
+		t.Errorf("static key: status %d, name %q; want 200, %s", code, name, defaultKeyName)
+	}
+	if code := call(token[:len(token)-4] + "AAAA"); code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", code)
+	}
+}
+
+func TestNewOIDCVerifier_RequiresAudience(t *testing.T) {
+	if _, err := newOIDCVerifier("https://login.example.com", "", ""); err == nil {
+		t.Error("issuer without audience accepted")
+	}
+	if _, err := newOIDCVerifier("login.example.com", "parakeet", ""); err == nil {
+		t.Error("issuer that is not a URL accepted")
+	}
+	for _, claim := range []string{"groups", "=admins", "sub=alice"} {
+		if _, err := newOIDCVerifier("https://login.example.com", "parakeet", claim); err == nil {
+			t.Errorf("-oidc-admin-claim %q accepted", claim)
+		}
+	}
+}
+
+func TestRequireAdmin_OIDC(t *testing.T) {
+	iss := newTestIssuer(t)
+	token := func(claims map[string]any) string {
+		c := map[string]any{"iss": iss.srv.URL, "sub": "bob", "aud": "parakeet", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range claims {
+			c[k] = v
+		}
+		return iss.token(t, "RS256", "rsa-1", c)
+	}
+	call := func(s *Server, token string) int {
+		h := s.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("POST", "/admin/models/reload", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	oidc, err := newOIDCVerifier(iss.srv.URL, "parakeet", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := token(nil)
+	if code := call(&Server{oidc: oidc}, user); code != http.StatusUnauthorized {
+		t.Errorf("OIDC user without an admin key: status %d, want 401", code)
+	}
+	if code := call(&Server{oidc: oidc, adminKey: "admin"}, user); code != http.StatusUnauthorized {
+		t.Errorf("OIDC user with an admin key: status %d, want 401", code)
+	}
+
+	oidc, err = newOIDCVerifier(iss.srv.URL, "parakeet", "realm_access.roles=parakeet-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{oidc: oidc}
+	admin := token(map[string]any{"realm_access": map[string]any{"roles": []string{"user", "parakeet-admin"}}})
+	if code := call(s, admin); code != http.StatusOK {
+		t.Errorf("token with the admin claim: status %d, want 200", code)
+	}
+	if code := call(s, user); code != http.StatusUnauthorized {
+		t.Errorf("token without the admin claim: status %d, want 401", code)
+	}
+	other := token(map[string]any{"realm_access": map[string]any{"roles": "user"}})
+	if code := call(s, other); code != http.StatusUnauthorized {
+		t.Errorf("token with another role: status %d, want 401", code)
+	}
+}
@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// llmAPIKeyEnvVar is sent as a bearer token to -llm-url. It is read from the
+// environment only, like the other secrets.
+const llmAPIKeyEnvVar = "PARAKEET_LLM_API_KEY"
+
+// DefaultLLMPrompt is the -llm-prompt default: a light cleanup that keeps the
+// speaker's wording.
+const DefaultLLMPrompt = `Clean up this speech transcript: fix punctuation, capitalization and obvious recognition errors, and remove filler words and false starts. Keep the wording, the meaning and the language ({{.Language}}). Answer with the corrected transcript only, without comments.
+
+{{.Text}}`
+
+// llmReplyLimit caps how much of a chat completion response is read.
+const llmReplyLimit = 4 << 20
+
+// errLLMUnavailable is returned for postprocess=llm when -llm-url is not set.
+var errLLMUnavailable = errors.New("postprocess=llm requires the server to be started with -llm-url")
+
+// llmPostprocessor sends transcripts through an OpenAI-compatible chat
+// completions endpoint (OpenAI, Ollama, llama.cpp, vLLM, ...).
+type llmPostprocessor struct {
+	endpoint string // .../chat/completions
+	model    string
+	apiKey   string
+	prompt   *template.Template
+	timeout  time.Duration
+}
+
+// llmPromptData is what prompt templates see.
+type llmPromptData struct {
+	Text     string
+	Language string
+}
+
+// newLLMPostprocessor checks the -llm-* settings. baseURL is the API root,
+// e.g. http://localhost:11434/v1; an empty prompt means DefaultLLMPrompt.
+func newLLMPostprocessor(baseURL, model, prompt string, timeout time.Duration) (*llmPostprocessor, error) {
+	if err := checkHTTPURL(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid -llm-url: %w", err)
+	}
+	if model == "" {
+		return nil, errors.New("-llm-url requires -llm-model")
+	}
+	if prompt == "" {
+		prompt = DefaultLLMPrompt
+	}
+	tmpl, err := parseLLMPrompt(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -llm-prompt: %w", err)
+	}
+	return &llmPostprocessor{
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/chat/completions",
+		model:    model,
+		prompt:   tmpl,
+		timeout:  timeout,
+	}, nil
+}
+
+// parseLLMPrompt parses a prompt template. A prompt that never uses .Text
+// gets the transcript appended after a blank line.
+func parseLLMPrompt(prompt string) (*template.Template, error) {
+	if !strings.Contains(prompt, ".Text") {
+		prompt += "\n\n{{.Text}}"
+	}
+	return template.New("prompt").Parse(prompt)
+}
+
+// process renders prompt (the server's when nil) over text and returns the
+// model's answer.
+func (p *llmPostprocessor) process(ctx context.Context, prompt *template.Template, text, language string) (string, error) {
+	if prompt == nil {
+		prompt = p.prompt
+	}
+	var content strings.Builder
+	if err := prompt.Execute(&content, llmPromptData{Text: text, Language: language}); err != nil {
+		return "", fmt.Errorf("render prompt: %w", err)
+	}
+	body, err := json.Marshal(map[string]any{
+		"model":    p.model,
+		"messages": []map[string]string{{"role": "user", "content": content.String()}},
+		"stream":   false,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, llmReplyLimit))
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(data, &reply); err != nil && resp.StatusCode < 300 {
+		return "", fmt.Errorf("malformed chat completion: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		if reply.Error != nil && reply.Error.Message != "" {
+			return "", fmt.Errorf("%s answered %s: %s", p.endpoint, resp.Status, reply.Error.Message)
+		}
+		return "", fmt.Errorf("%s answered %s", p.endpoint, resp.Status)
+	}
+	if len(reply.Choices) == 0 {
+		return "", errors.New("chat completion has no choices")
+	}
+	return strings.TrimSpace(reply.Choices[0].Message.Content), nil
+}
+
+// postprocessRequest is the parsed postprocess and postprocess_prompt
+// parameters of a request. The zero value leaves the transcript alone.
+type postprocessRequest struct {
+	llm    bool
+	prompt *template.Template // nil means the server's -llm-prompt
+}
+
+// parsePostprocess reads postprocess and postprocess_prompt with get. Only
+// the json, text and verbose_json formats carry post-processed text.
+func (s *Server) parsePostprocess(get func(string) string, responseFormat string) (postprocessRequest, error) {
+	var req postprocessRequest
+	switch v := get("postprocess"); v {
+	case "", "none":
+		return req, nil
+	case "llm":
+	default:
+		return req, invalidParam("postprocess", "invalid postprocess %q (available: llm)", v)
+	}
+	if s.llm == nil {
+		return req, withParam("postprocess", errLLMUnavailable)
+	}
+	switch responseFormat {
+	case "", "json", "text", "verbose_json":
+	default:
+		return req, invalidParam("postprocess", "postprocess=llm works with response_format json, text or verbose_json, not %q", responseFormat)
+	}
+	req.llm = true
+	if v := get("postprocess_prompt"); v != "" {
+		tmpl, err := parseLLMPrompt(v)
+		if err != nil {
+			return postprocessRequest{}, withParam("postprocess_prompt", fmt.Errorf("invalid postprocess_prompt: %w", err))
+		}
+		req.prompt = tmpl
+	}
+	return req, nil
+}
+
+// postprocess returns a copy of res whose Text is the model's answer, or res
+// itself when pp asks for nothing. Segments and words keep the recognised
+// text and timings. The request's prompt wins over the language profile's,
+// which wins over -llm-prompt.
+func (s *Server) postprocess(ctx context.Context, pp postprocessRequest, res *asr.Result, language string) (*asr.Result, error) {
+	if !pp.llm {
+		return res, nil
+	}
+	prompt := pp.prompt
+	if prof := s.profiles.profile(language); prompt == nil && prof != nil {
+		prompt = prof.llmPrompt
+	}
+	text, err := s.llm.process(ctx, prompt, res.Text, language)
+	if err != nil {
+		return nil, err
+	}
+	out := *res
+	out.Text = text
+	return &out, nil
+}
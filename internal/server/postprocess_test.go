@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestLLMPostprocessor(t *testing.T) {
+	var got struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	var auth string
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		if strings.Contains(got.Messages[0].Content, "fail") {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "slow down"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": " Hello, world. \n"}}]}`))
+	}))
+	defer llm.Close()
+
+	p, err := newLLMPostprocessor(llm.URL+"/v1/", "llama3", "Fix this {{.Language}} text: {{.Text}}", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.apiKey = "sk-test"
+	s := &Server{llm: p}
+
+	res := &asr.Result{Text: "hello world", Segments: []asr.Segment{{Start: 0, End: 1, Text: "hello world"}}}
+	pp, err := s.parsePostprocess(url.Values{"postprocess": {"llm"}}.Get, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := s.postprocess(t.Context(), pp, res, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Text != "Hello, world." || out.Segments[0].Text != "hello world" || res.Text != "hello world" {
+		t.Fatalf("out = %+v, res = %+v", out, res)
+	}
+	if got.Model != "llama3" || got.Messages[0].Role != "user" || got.Messages[0].Content != "Fix this en text: hello world" {
+		t.Fatalf("request = %+v", got)
+	}
+	if auth != "Bearer sk-test" {
+		t.Fatalf("Authorization = %q", auth)
+	}
+
+	// A per-request prompt without .Text gets the transcript appended.
+	pp, err = s.parsePostprocess(url.Values{"postprocess": {"llm"}, "postprocess_prompt": {"Summarize:"}}.Get, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.postprocess(t.Context(), pp, res, "en"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Messages[0].Content != "Summarize:\n\nhello world" {
+		t.Fatalf("content = %q", got.Messages[0].Content)
+	}
+
+	// Upstream errors carry the API's message.
+	_, err = s.postprocess(t.Context(), pp, &asr.Result{Text: "fail"}, "en")
+	if err == nil || !strings.Contains(err.Error(), "slow down") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestParsePostprocess(t *testing.T) {
+	on := &Server{llm: &llmPostprocessor{}}
+	off := &Server{}
+	for _, tc := range []struct {
+		s      *Server
+		params url.Values
+		format string
+		llm    bool
+		ok     bool
+	}{
+		{off, url.Values{}, "json", false, true},
+		{off, url.Values{"postprocess": {"none"}}, "srt", false, true},
+		{off, url.Values{"postprocess": {"llm"}}, "json", false, false},
+		{on, url.Values{"postprocess": {"llm"}}, "verbose_json", true, true},
+		{on, url.Values{"postprocess": {"llm"}}, "srt", false, false},
+		{on, url.Values{"postprocess": {"gpt"}}, "json", false, false},
+		{on, url.Values{"postprocess": {"llm"}, "postprocess_prompt": {"{{.Text"}}, "json", false, false},
+	} {
+		pp, err := tc.s.parsePostprocess(tc.params.Get, tc.format)
+		if (err == nil) != tc.ok || pp.llm != tc.llm {
+			t.Errorf("%v %s: llm=%v err=%v", tc.params, tc.format, pp.llm, err)
+		}
+	}
+
+	for _, bad := range []struct{ url, model string }{
+		{"localhost:11434", "llama3"},
+		{"http://localhost:11434/v1", ""},
+	} {
+		if _, err := newLLMPostprocessor(bad.url, bad.model, "", 0); err == nil {
+			t.Errorf("newLLMPostprocessor(%q, %q) accepted", bad.url, bad.model)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"parakeet/internal/asr"
+)
+
+// anyLanguage keys the profile used for languages without their own.
+const anyLanguage = "*"
+
+// textProfiles are the per-language post-processing chains of a
+// -postprocess-profiles file, applied to every transcript after decoding.
+// A nil *textProfiles changes nothing.
+type textProfiles struct {
+	byLanguage map[string]*textProfile
+	digest     string // of the file, salting the cache key
+}
+
+// textProfile is the chain for one language: word replacements, then
+// rewrite rules (inverse text normalization such as decimal commas), and
+// the prompt postprocess=llm uses for the language.
+type textProfile struct {
+	replacements []wordReplacement
+	rules        []rewriteRule
+	llmPrompt    *template.Template // nil keeps -llm-prompt
+}
+
+// wordReplacement swaps a whole word or phrase, matched case-insensitively.
+type wordReplacement struct {
+	from *regexp.Regexp
+	to   string
+}
+
+// rewriteRule is a regular expression replacement ($1 expands groups).
+type rewriteRule struct {
+	pattern *regexp.Regexp
+	replace string
+}
+
+// profileFile is the JSON layout of -postprocess-profiles: profiles keyed
+// by ISO-639-1 code or "*".
+type profileFile map[string]struct {
+	Replacements []struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"replacements"`
+	Rules []struct {
+		Pattern string `json:"pattern"`
+		Replace string `json:"replace"`
+	} `json:"rules"`
+	LLMPrompt string `json:"llm_prompt"`
+}
+
+// loadTextProfiles reads and checks a -postprocess-profiles file.
+func loadTextProfiles(path string) (*textProfiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read profiles: %w", err)
+	}
+	var file profileFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse profiles: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	p := &textProfiles{byLanguage: make(map[string]*textProfile, len(file)), digest: hex.EncodeToString(sum[:8])}
+	for lang, def := range file {
+		if lang != anyLanguage && !languageCode.MatchString(lang) {
+			return nil, fmt.Errorf("profile %q: want an ISO-639-1 code or %q", lang, anyLanguage)
+		}
+		prof := &textProfile{}
+		for i, r := range def.Replacements {
+			if strings.TrimSpace(r.From) == "" {
+				return nil, fmt.Errorf("profile %q: replacement %d has no from", lang, i+1)
+			}
+			prof.replacements = append(prof.replacements, wordReplacement{
+				from: regexp.MustCompile("(?i)" + regexp.QuoteMeta(strings.TrimSpace(r.From))),
+				to:   r.To,
+			})
+		}
+		for i, r := range def.Rules {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("profile %q: rule %d: %w", lang, i+1, err)
+			}
+			prof.rules = append(prof.rules, rewriteRule{pattern: re, replace: r.Replace})
+		}
+		if def.LLMPrompt != "" {
+			if prof.llmPrompt, err = parseLLMPrompt(def.LLMPrompt); err != nil {
+				return nil, fmt.Errorf("profile %q: llm_prompt: %w", lang, err)
+			}
+		}
+		p.byLanguage[lang] = prof
+	}
+	return p, nil
+}
+
+// profile returns the profile for language, the "*" one without its own,
+// or nil.
+func (p *textProfiles) profile(language string) *textProfile {
+	if p == nil {
+		return nil
+	}
+	if prof, ok := p.byLanguage[language]; ok {
+		return prof
+	}
+	return p.byLanguage[anyLanguage]
+}
+
+// apply runs the profile of language over the transcript text and every
+// segment's text, in place. Words and tokens keep the recognised text, as
+// their timings refer to it.
+func (p *textProfiles) apply(language string, res *asr.Result) {
+	prof := p.profile(language)
+	if prof == nil {
+		return
+	}
+	res.Text = prof.rewrite(res.Text)
+	for i := range res.Segments {
+		res.Segments[i].Text = prof.rewrite(res.Segments[i].Text)
+	}
+}
+
+// rewrite runs the chain over text.
+func (prof *textProfile) rewrite(text string) string {
+	for _, r := range prof.replacements {
+		text = replaceWords(text, r.from, r.to)
+	}
+	for _, r := range prof.rules {
+		text = r.pattern.ReplaceAllString(text, r.replace)
+	}
+	return text
+}
+
+// replaceWords replaces the matches of re that are whole words: not
+// preceded or followed by a letter or digit, in any script.
+func replaceWords(text string, re *regexp.Regexp, to string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:m[0]])
+		after, _ := utf8.DecodeRuneInString(text[m[1]:])
+		if isWordRune(before) || isWordRune(after) {
+			continue
+		}
+		b.WriteString(text[last:m[0]])
+		b.WriteString(to)
+		last = m[1]
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
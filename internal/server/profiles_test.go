@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func writeProfiles(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTextProfiles(t *testing.T) {
+	p, err := loadTextProfiles(writeProfiles(t, `{
+		"de": {
+			"replacements": [{"from": "prozent", "to": "%"}],
+			"rules": [{"pattern": "(\\d)\\.(\\d)", "replace": "$1,$2"}],
+			"llm_prompt": "Korrigiere: {{.Text}}"
+		},
+		"*": {"replacements": [{"from": "gonna", "to": "going to"}]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.digest == "" || p.profile("de").llmPrompt == nil || p.profile("fr") != p.profile("*") {
+		t.Fatalf("profiles = %+v", p)
+	}
+
+	for content, wantErr := range map[string]string{
+		`{"german": {}}`:                          "ISO-639-1",
+		`{"de": {"rules": [{"pattern": "("}]}}`:   "rule 1",
+		`{"de": {"replacements": [{"to": "x"}]}}`: "replacement 1 has no from",
+		`{"de": {"llm_prompt": "{{.Text"}}`:       "llm_prompt",
+		`[]`:                                      "parse profiles",
+	} {
+		if _, err := loadTextProfiles(writeProfiles(t, content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: err = %v, want %q", content, err, wantErr)
+		}
+	}
+	if _, err := loadTextProfiles(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing file was accepted")
+	}
+}
+
+func TestTextProfilesApply(t *testing.T) {
+	p, err := loadTextProfiles(writeProfiles(t, `{
+		"de": {"rules": [{"pattern": "(\\d)\\.(\\d)", "replace": "$1,$2"}]},
+		"en": {"replacements": [{"from": "k8s", "to": "Kubernetes"}]},
+		"*": {"replacements": [{"from": "uh", "to": ""}]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ language, text, want string }{
+		{"de", "Es kostet 3.5 Euro.", "Es kostet 3,5 Euro."},
+		{"en", "It costs 3.5 dollars on K8S.", "It costs 3.5 dollars on Kubernetes."},
+		{"fr", "uh bonjour", " bonjour"},
+		{"", "uh hello", " hello"},
+	} {
+		res := &asr.Result{Text: tc.text, Segments: []asr.Segment{{Text: tc.text}}, Words: []asr.Word{{Text: "raw"}}}
+		p.apply(tc.language, res)
+		if res.Text != tc.want || res.Segments[0].Text != tc.want || res.Words[0].Text != "raw" {
+			t.Errorf("%s %q = %+v, want %q", tc.language, tc.text, res, tc.want)
+		}
+	}
+
+	// No profiles change nothing.
+	var none *textProfiles
+	res := &asr.Result{Text: "uh"}
+	none.apply("en", res)
+	if res.Text != "uh" {
+		t.Errorf("text = %q", res.Text)
+	}
+}
+
+func TestReplaceWords(t *testing.T) {
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta("straße"))
+	for text, want := range map[string]string{
+		"Die Straße ist lang":   "Die Str. ist lang",
+		"Hauptstraße 5":         "Hauptstraße 5",
+		"straßen und Straße.":   "straßen und Str..",
+		"STRASSE bleibt stehen": "STRASSE bleibt stehen",
+	} {
+		if got := replaceWords(text, re, "Str."); got != want {
+			t.Errorf("%q = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestProfilePrompt(t *testing.T) {
+	var content string
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		content = req.Messages[0].Content
+		w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer llm.Close()
+
+	pp, err := newLLMPostprocessor(llm.URL, "llama3", "Fix: {{.Text}}", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := loadTextProfiles(writeProfiles(t, `{"de": {"llm_prompt": "Korrigiere: {{.Text}}"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{llm: pp, profiles: profiles}
+	res := &asr.Result{Text: "hallo"}
+
+	for _, tc := range []struct {
+		query    url.Values
+		language string
+		want     string
+	}{
+		{url.Values{"postprocess": {"llm"}}, "de", "Korrigiere: hallo"},
+		{url.Values{"postprocess": {"llm"}}, "en", "Fix: hallo"},
+		{url.Values{"postprocess": {"llm"}, "postprocess_prompt": {"Mine: {{.Text}}"}}, "de", "Mine: hallo"},
+	} {
+		req, err := s.parsePostprocess(tc.query.Get, "json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.postprocess(t.Context(), req, res, tc.language); err != nil {
+			t.Fatal(err)
+		}
+		if content != tc.want {
+			t.Errorf("%v in %s: prompt = %q, want %q", tc.query, tc.language, content, tc.want)
+		}
+	}
+}
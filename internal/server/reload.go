@@ -0,0 +1,289 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// retireGrace is how long a replaced Transcriber stays open after a reload,
+// so requests that picked it up just before the swap still run on it.
+const retireGrace = 5 * time.Second
+
+// warmupTimeout bounds the test decode that warms up reloaded models.
+const warmupTimeout = time.Minute
+
+// errReloadRunning is returned while another reload is loading models.
+var errReloadRunning = errors.New("a model reload is already running")
+
+// errReloadPath is returned for a ModelsReload path a reload may not load;
+// see reloadPathAllowed.
+var errReloadPath = errors.New("models path not allowed")
+
+// loadedModels is one generation of loaded models: the Transcriber serving
+// requests and where its files came from. A reload builds the next
+// generation and swaps it into Server.models.
+type loadedModels struct {
+	transcriber *asr.Transcriber
+	// archive holds the -models-archive open for /version checksums; nil
+	// when the models come from a directory.
+	archive asr.ModelArchive
+
+	dir         string
+	archivePath string
+	generation  int
+
+	// fingerprint identifies the model files (names, sizes, modification
+	// times) in the result cache key.
+	fingerprint string
+
+	// checksums holds the model file hashes reported by /version, filled
+	// in the background by Server.hashModels.
+	checksums modelChecksums
+}
+
+// loadModels loads the models from archivePath when it is set, else from
+// dir. Everything else comes from opts.
+func loadModels(cfg Config, opts asr.Options, dir, archivePath string) (*loadedModels, error) {
+	m := &loadedModels{dir: dir, archivePath: archivePath}
+	if archivePath != "" {
+		archive, err := asr.OpenModelArchive(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		m.archive = archive
+		opts.Models = archive
+	}
+	t, err := asr.NewTranscriber(dir, cfg.Workers, opts)
+	if err != nil {
+		m.close()
+		return nil, fmt.Errorf("failed to initialize transcriber: %w", err)
+	}
+	m.transcriber = t
+	if cfg.Denoise && !t.CanDenoise() {
+		m.close()
+		return nil, fmt.Errorf("-denoise is set but no denoise model was loaded (see -denoise-model-path)")
+	}
+	files := t.Info().ModelFiles
+	if archivePath != "" {
+		files = []string{archivePath}
+	}
+	m.fingerprint = fingerprintFiles(files)
+	return m, nil
+}
+
+func (m *loadedModels) close() {
+	if m.transcriber != nil {
+		m.transcriber.Close()
+	}
+	if m.archive != nil {
+		m.archive.Close()
+	}
+}
+
+// fingerprintFiles hashes the name, size and modification time of each file:
+// cheap next to hashing the weights, and enough to tell a replaced file.
+func fingerprintFiles(paths []string) string {
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s", filepath.Base(p))
+		if st, err := os.Stat(p); err == nil {
+			fmt.Fprintf(h, " %d %d", st.Size(), st.ModTime().UnixNano())
+		}
+		io.WriteString(h, "\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// transcriber returns the Transcriber of the current model generation.
+func (s *Server) transcriber() *asr.Transcriber {
+	if m := s.models.Load(); m != nil {
+		return m.transcriber
+	}
+	return nil
+}
+
+// ModelsReload selects what ReloadModels loads. Empty fields keep the
+// current source; ModelsArchive takes precedence over ModelsDir. Either must
+// be the startup -models or -models-archive, or lie under
+// -models-reload-root.
+type ModelsReload struct {
+	ModelsDir     string `json:"models_dir,omitempty"`
+	ModelsArchive string `json:"models_archive,omitempty"`
+}
+
+// ModelsReloadResponse is the body of POST /admin/models/reload.
+type ModelsReloadResponse struct {
+	Generation    int      `json:"generation"`
+	ModelsDir     string   `json:"models_dir,omitempty"`
+	ModelsArchive string   `json:"models_archive,omitempty"`
+	ModelType     string   `json:"model_type"`
+	ModelFiles    []string `json:"model_files"`
+	LoadSeconds   float64  `json:"load_seconds"`
+}
+
+// ReloadModels loads the models again, from req or else from where the
+// current ones came from, warms them up with a test decode and then switches
+// new requests to them. The replaced models are closed in the background
+// once the requests running on them finish. On error the current models
+// keep serving. Only one reload runs at a time; another one fails with
+// errReloadRunning.
+func (s *Server) ReloadModels(req ModelsReload) (ModelsReloadResponse, error) {
+	if !s.reloadMu.TryLock() {
+		return ModelsReloadResponse{}, errReloadRunning
+	}
+	defer s.reloadMu.Unlock()
+	if s.modelsClosed {
+		return ModelsReloadResponse{}, asr.ErrClosed
+	}
+
+	old := s.models.Load()
+	dir, archivePath := old.dir, old.archivePath
+	switch {
+	case req.ModelsArchive != "":
+		if !s.reloadPathAllowed(req.ModelsArchive) {
+			return ModelsReloadResponse{}, fmt.Errorf("%w: %s is not -models-archive or under -models-reload-root", errReloadPath, req.ModelsArchive)
+		}
+		archivePath = req.ModelsArchive
+	case req.ModelsDir != "":
+		if !s.reloadPathAllowed(req.ModelsDir) {
+			return ModelsReloadResponse{}, fmt.Errorf("%w: %s is not -models or under -models-reload-root", errReloadPath, req.ModelsDir)
+		}
+		dir, archivePath = req.ModelsDir, ""
+	}
+
+	slog.Info("reloading models", "dir", dir, "archive", archivePath)
+	started := time.Now()
+	next, err := loadModels(s.config, s.modelOptions, dir, archivePath)
+	if err != nil {
+		return ModelsReloadResponse{}, err
+	}
+	if err := checkModelAliases(s.aliases, next.transcriber.Info().Name); err != nil {
+		next.close()
+		return ModelsReloadResponse{}, fmt.Errorf("-model-aliases: %w", err)
+	}
+	if err := s.canary.check(next.transcriber.Info().Name); err != nil {
+		next.close()
+		return ModelsReloadResponse{}, fmt.Errorf("-canary-models-dir: %w", err)
+	}
+	if err := warmUp(next.transcriber); err != nil {
+		next.close()
+		return ModelsReloadResponse{}, fmt.Errorf("warm-up decode failed: %w", err)
+	}
+	next.generation = old.generation + 1
+	s.models.Store(next)
+	s.hashModels(next, old)
+	elapsed := time.Since(started)
+	slog.Info("models reloaded", "generation", next.generation, "seconds", elapsed.Seconds())
+
+	s.retiring.Add(1)
+	go func() {
+		defer s.retiring.Done()
+		select {
+		case <-time.After(retireGrace):
+		case <-s.retireNow:
+		}
+		old.close()
+		slog.Info("previous models unloaded", "generation", old.generation)
+	}()
+
+	info := next.transcriber.Info()
+	return ModelsReloadResponse{
+		Generation:    next.generation,
+		ModelsDir:     dir,
+		ModelsArchive: archivePath,
+		ModelType:     info.ModelType,
+		ModelFiles:    info.ModelFiles,
+		LoadSeconds:   elapsed.Seconds(),
+	}, nil
+}
+
+// reloadPathAllowed reports whether a reload request may load path: the
+// -models or -models-archive the server started with, or anything under
+// -models-reload-root. Symlinks are resolved first, so a link under the root
+// cannot point out of it.
+func (s *Server) reloadPathAllowed(path string) bool {
+	path = resolvePath(path)
+	for _, configured := range []string{s.config.ModelsDir, s.config.ModelsArchive} {
+		if configured != "" && path == resolvePath(configured) {
+			return true
+		}
+	}
+	if s.config.ModelsReloadRoot == "" {
+		return false
+	}
+	rel, err := filepath.Rel(resolvePath(s.config.ModelsReloadRoot), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePath returns path absolute and clean, with symlinks resolved when
+// it exists.
+func resolvePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return filepath.Clean(path)
+}
+
+// warmUp decodes a second of silence, so ORT allocates its buffers before
+// the Transcriber takes traffic and a model that cannot run is refused.
+func warmUp(t *asr.Transcriber) error {
+	format, err := asr.ParsePCMFormat("pcm_s16le", 16000, 1)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+	defer cancel()
+	_, err = t.TranscribeWithOptions(ctx, format.WAV(make([]byte, 2*16000)), asr.TranscribeOptions{}, nil)
+	return err
+}
+
+// handleModelsReload serves POST /admin/models/reload. The body is optional;
+// see ModelsReload.
+func (s *Server) handleModelsReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	var req ModelsReload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		sendError(w, "Invalid JSON body: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
+		return
+	}
+	resp, err := s.ReloadModels(req)
+	switch {
+	case errors.Is(err, errReloadPath):
+		sendError(w, err.Error(), "invalid_request_error", http.StatusBadRequest)
+		return
+	case errors.Is(err, errReloadRunning):
+		sendError(w, "A model reload is already running", "invalid_request_error", http.StatusConflict)
+		return
+	case errors.Is(err, asr.ErrClosed):
+		sendError(w, "The server is shutting down", "server_error", http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		slog.Error("model reload failed, keeping the current models", "error", err)
+		sendError(w, "Model reload failed: "+err.Error(), "server_error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
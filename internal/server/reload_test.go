@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestHandleModelsReload_Errors(t *testing.T) {
+	root := t.TempDir()
+	s := &Server{retireNow: make(chan struct{}), config: Config{ModelsReloadRoot: root}}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}, dir: t.TempDir()})
+
+	rec := httptest.NewRecorder()
+	s.handleModelsReload(rec, httptest.NewRequest("GET", "/admin/models/reload", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want 405", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleModelsReload(rec, httptest.NewRequest("POST", "/admin/models/reload", strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad JSON: status %d, want 400", rec.Code)
+	}
+
+	// A second reload while one is loading is refused.
+	s.reloadMu.Lock()
+	rec = httptest.NewRecorder()
+	s.handleModelsReload(rec, httptest.NewRequest("POST", "/admin/models/reload", nil))
+	s.reloadMu.Unlock()
+	if rec.Code != http.StatusConflict {
+		t.Errorf("concurrent reload: status %d, want 409", rec.Code)
+	}
+
+	// A failed load keeps the current models.
+	current := s.models.Load()
+	rec = httptest.NewRecorder()
+	body := `{"models_dir": "` + filepath.Join(root, "missing") + `"}`
+	s.handleModelsReload(rec, httptest.NewRequest("POST", "/admin/models/reload", strings.NewReader(body)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("missing models: status %d, want 500", rec.Code)
+	}
+	if s.models.Load() != current {
+		t.Error("failed reload replaced the current models")
+	}
+
+	// Paths outside -models-reload-root are refused before anything loads.
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(root, "link"))
+	for _, body := range []string{
+		`{"models_dir": "` + outside + `"}`,
+		`{"models_archive": "/etc/passwd"}`,
+		`{"models_dir": "` + filepath.Join(root, "..", filepath.Base(outside)) + `"}`,
+		`{"models_dir": "` + filepath.Join(root, "link") + `"}`,
+	} {
+		rec = httptest.NewRecorder()
+		s.handleModelsReload(rec, httptest.NewRequest("POST", "/admin/models/reload", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, rec.Code)
+		}
+	}
+
+	// No reloads once the server is closing.
+	s.Close()
+	rec = httptest.NewRecorder()
+	s.handleModelsReload(rec, httptest.NewRequest("POST", "/admin/models/reload", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("after Close: status %d, want 503", rec.Code)
+	}
+}
+
+func TestReloadPathAllowed(t *testing.T) {
+	models, root := t.TempDir(), t.TempDir()
+	s := &Server{config: Config{ModelsDir: models, ModelsArchive: "/srv/models.tar"}}
+	for path, want := range map[string]bool{
+		models:                         true,
+		models + "/":                   true,
+		"/srv/models.tar":              true,
+		filepath.Join(root, "v2"):      false,
+		filepath.Join(models, "other"): false,
+	} {
+		if got := s.reloadPathAllowed(path); got != want {
+			t.Errorf("no root: %s allowed = %v, want %v", path, got, want)
+		}
+	}
+	s.config.ModelsReloadRoot = root
+	for path, want := range map[string]bool{
+		root:                                  true,
+		filepath.Join(root, "v2"):             true,
+		filepath.Join(root, "v2", "..", ".."): false,
+		root + "-other":                       false,
+	} {
+		if got := s.reloadPathAllowed(path); got != want {
+			t.Errorf("root %s: %s allowed = %v, want %v", root, path, got, want)
+		}
+	}
+}
+
+func TestFingerprintFiles(t *testing.T) {
+	dir := t.TempDir()
+	model := filepath.Join(dir, "encoder-model.int8.onnx")
+	os.WriteFile(model, []byte("weights"), 0o644)
+
+	first := fingerprintFiles([]string{model})
+	if again := fingerprintFiles([]string{model}); again != first {
+		t.Errorf("fingerprint not stable: %s, %s", first, again)
+	}
+	os.WriteFile(model, []byte("new weights"), 0o644)
+	os.Chtimes(model, time.Now(), time.Now().Add(time.Hour))
+	if replaced := fingerprintFiles([]string{model}); replaced == first {
+		t.Error("fingerprint unchanged after the file was replaced")
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// requestIDHeader carries the request ID both ways: a client or proxy may
+// send one, and every response has one.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds an accepted X-Request-ID; longer ones are replaced.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// newRequestID returns a random UUID (version 4). It names requests,
+// Deepgram request IDs and AssemblyAI jobs.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// validRequestID accepts IDs a log line can carry verbatim: printable ASCII
+// without spaces or quotes, up to maxRequestIDLen.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c > '~' || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// assignRequestID gives every request an ID: the client's X-Request-ID when
+// it is usable, else a new UUID. The ID is echoed in the response header,
+// put in error bodies, and added to every log line logged with the
+// request's context (see LogHandler).
+func assignRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestIDFrom returns the request ID carried by ctx, or "".
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDOf returns the ID of r, or a new one for a request that did not
+// go through Handler (tests calling a handler directly).
+func requestIDOf(r *http.Request) string {
+	if id := requestIDFrom(r.Context()); id != "" {
+		return id
+	}
+	return newRequestID()
+}
+
+// LogHandler wraps h so that records logged with a request's context (the
+// slog ...Context functions) carry its request_id, unless they set one.
+func LogHandler(h slog.Handler) slog.Handler {
+	return requestIDLogHandler{h}
+}
+
+type requestIDLogHandler struct {
+	slog.Handler
+}
+
+func (h requestIDLogHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := requestIDFrom(ctx); id != "" {
+		has := false
+		rec.Attrs(func(a slog.Attr) bool {
+			has = a.Key == "request_id"
+			return !has
+		})
+		if !has {
+			rec.AddAttrs(slog.String("request_id", id))
+		}
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h requestIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDLogHandler) WithGroup(name string) slog.Handler {
+	return requestIDLogHandler{h.Handler.WithGroup(name)}
+}
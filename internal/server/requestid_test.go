@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAssignRequestID(t *testing.T) {
+	var seen string
+	s := &Server{mux: http.NewServeMux()}
+	s.route("/fail", func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFrom(r.Context())
+		sendError(w, "nope", "invalid_request_error", http.StatusBadRequest)
+	})
+	h := s.Handler()
+
+	for _, tc := range []struct {
+		sent string
+		keep bool
+	}{
+		{"", false},
+		{"trace-1234:abc", true},
+		{"has space", false},
+		{strings.Repeat("x", maxRequestIDLen+1), false},
+	} {
+		r := httptest.NewRequest("GET", "/fail", nil)
+		if tc.sent != "" {
+			r.Header.Set(requestIDHeader, tc.sent)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		got := rec.Header().Get(requestIDHeader)
+		if got == "" || got != seen {
+			t.Fatalf("sent %q: header %q, handler saw %q", tc.sent, got, seen)
+		}
+		if (got == tc.sent) != tc.keep {
+			t.Errorf("sent %q: got %q, keep=%v", tc.sent, got, tc.keep)
+		}
+		if e := decodeError(t, rec); string(e["request_id"]) != `"`+got+`"` {
+			t.Errorf("sent %q: error body request_id %s, want %q", tc.sent, e["request_id"], got)
+		}
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(LogHandler(slog.NewTextHandler(&buf, nil)))
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := r.Context()
+	assignRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { ctx = r.Context() })).
+		ServeHTTP(httptest.NewRecorder(), r)
+	id := requestIDFrom(ctx)
+
+	log.InfoContext(ctx, "with context")
+	log.InfoContext(ctx, "explicit", "request_id", "other")
+	log.Info("without context")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "request_id="+id) {
+		t.Errorf("line = %q, want request_id=%s", lines[0], id)
+	}
+	if strings.Count(lines[1], "request_id=") != 1 || !strings.Contains(lines[1], "request_id=other") {
+		t.Errorf("explicit request_id overridden: %q", lines[1])
+	}
+	if strings.Contains(lines[2], "request_id") {
+		t.Errorf("line without context has a request_id: %q", lines[2])
+	}
+}
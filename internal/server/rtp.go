@@ -0,0 +1,410 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+const (
+	// rtpIdleTimeout ends a stream that stops sending packets. RTP has no
+	// in-band end of stream (BYE travels over RTCP or SIP), so silence on
+	// the wire is the signal.
+	rtpIdleTimeout = 5 * time.Second
+	// rtpMaxGapSamples bounds the silence inserted for lost packets or a
+	// timestamp jump; larger jumps are treated as a new talk spurt.
+	rtpMaxGapSamples = 8000
+	// rtpStreamQueue is how many packets may wait for a stream's decoder
+	// before new ones are dropped.
+	rtpStreamQueue = 500
+	// rtpFinalizeTimeout bounds the last decode of a stream at shutdown.
+	rtpFinalizeTimeout = 30 * time.Second
+)
+
+// rtpPayloadFormats maps the static RTP payload types parakeet can decode
+// (RFC 3551) to their audio format. Everything else, Opus and other dynamic
+// payload types included, is ignored.
+var rtpPayloadFormats = map[byte]asr.PCMFormat{
+	0: {Encoding: asr.PCMMuLaw, SampleRate: 8000, Channels: 1}, // PCMU
+	8: {Encoding: asr.PCMALaw, SampleRate: 8000, Channels: 1},  // PCMA
+}
+
+// rtpPacket is the part of an RTP packet (RFC 3550 section 5.1) used here.
+type rtpPacket struct {
+	payloadType byte
+	seq         uint16
+	timestamp   uint32
+	ssrc        uint32
+	payload     []byte
+}
+
+// parseRTP decodes an RTP packet, skipping CSRCs, the header extension and
+// padding.
+func parseRTP(b []byte) (rtpPacket, error) {
+	if len(b) < 12 {
+		return rtpPacket{}, errors.New("rtp: packet too short")
+	}
+	if b[0]>>6 != 2 {
+		return rtpPacket{}, fmt.Errorf("rtp: unsupported version %d", b[0]>>6)
+	}
+	if b[1] >= 200 && b[1] <= 204 {
+		return rtpPacket{}, errors.New("rtp: RTCP packet")
+	}
+	p := rtpPacket{
+		payloadType: b[1] & 0x7f,
+		seq:         binary.BigEndian.Uint16(b[2:]),
+		timestamp:   binary.BigEndian.Uint32(b[4:]),
+		ssrc:        binary.BigEndian.Uint32(b[8:]),
+	}
+	offset := 12 + 4*int(b[0]&0x0f)
+	if b[0]&0x10 != 0 {
+		if len(b) < offset+4 {
+			return rtpPacket{}, errors.New("rtp: truncated header extension")
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(b[offset+2:]))
+	}
+	end := len(b)
+	if b[0]&0x20 != 0 {
+		end -= int(b[len(b)-1])
+	}
+	if offset > end {
+		return rtpPacket{}, errors.New("rtp: truncated packet")
+	}
+	p.payload = b[offset:end]
+	return p, nil
+}
+
+// rtpTranscript is one final utterance of an RTP stream, as POSTed to the
+// callback URL and sent to /rtp/transcripts subscribers. Times are seconds
+// from the stream's first packet.
+type rtpTranscript struct {
+	Source      string           `json:"source"` // sender address
+	SSRC        uint32           `json:"ssrc"`
+	Encoding    string           `json:"encoding"`
+	Start       float64          `json:"start"`
+	End         float64          `json:"end"`
+	Text        string           `json:"text"`
+	SpeechFinal bool             `json:"speech_final"`
+	Words       []transcriptWord `json:"words"`
+}
+
+// rtpIngest listens for RTP on UDP and transcribes each stream, identified
+// by sender address and SSRC, with its own live session.
+type rtpIngest struct {
+	s        *Server
+	conns    []net.PacketConn
+	callback *callbackSender // nil without -rtp-callback-url
+	feed     *transcriptFeed
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	mu      sync.Mutex
+	streams map[string]*rtpStream
+}
+
+type rtpStream struct {
+	key     string
+	source  string
+	ssrc    uint32
+	packets chan rtpPacket
+}
+
+// startRTP binds every -rtp-addr address and starts reading. A bind
+// failure is returned: the operator asked for the listener.
+func (s *Server) startRTP() error {
+	if s.rtp == nil {
+		return nil
+	}
+	for _, addr := range strings.Split(s.config.RTPAddr, ",") {
+		conn, err := net.ListenPacket("udp", strings.TrimSpace(addr))
+		if err != nil {
+			s.rtp.close()
+			return fmt.Errorf("rtp listener: %w", err)
+		}
+		s.rtp.conns = append(s.rtp.conns, conn)
+		slog.Info("RTP listener started", "addr", conn.LocalAddr().String())
+	}
+	for _, conn := range s.rtp.conns {
+		s.rtp.wg.Add(1)
+		go s.rtp.read(conn)
+	}
+	return nil
+}
+
+func newRTPIngest(s *Server) *rtpIngest {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := &rtpIngest{
+		s:       s,
+		feed:    newTranscriptFeed(),
+		ctx:     ctx,
+		cancel:  cancel,
+		streams: make(map[string]*rtpStream),
+	}
+	if s.config.RTPCallbackURL != "" {
+		in.callback = newCallbackSender(s.config.RTPCallbackURL)
+	}
+	return in
+}
+
+// close stops the listeners and waits for every stream to deliver its last
+// utterance.
+func (in *rtpIngest) close() {
+	in.closeOnce.Do(func() {
+		in.cancel()
+		for _, conn := range in.conns {
+			conn.Close()
+		}
+		in.wg.Wait()
+		if in.callback != nil {
+			in.callback.close()
+		}
+	})
+}
+
+func (in *rtpIngest) read(conn net.PacketConn) {
+	defer in.wg.Done()
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if in.ctx.Err() == nil {
+				slog.Error("rtp read failed", "addr", conn.LocalAddr().String(), "error", err)
+			}
+			return
+		}
+		pkt, err := parseRTP(buf[:n])
+		if err != nil {
+			continue
+		}
+		if _, ok := rtpPayloadFormats[pkt.payloadType]; !ok {
+			continue
+		}
+		pkt.payload = bytes.Clone(pkt.payload)
+		in.dispatch(addr.String(), pkt)
+	}
+}
+
+// dispatch hands a packet to its stream, starting the stream on its first
+// packet.
+func (in *rtpIngest) dispatch(source string, pkt rtpPacket) {
+	key := fmt.Sprintf("%s/%08x", source, pkt.ssrc)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	st, ok := in.streams[key]
+	if !ok {
+		if in.ctx.Err() != nil {
+			return
+		}
+		st = &rtpStream{key: key, source: source, ssrc: pkt.ssrc, packets: make(chan rtpPacket, rtpStreamQueue)}
+		in.streams[key] = st
+		in.wg.Add(1)
+		go in.run(st, rtpPayloadFormats[pkt.payloadType], pkt.payloadType)
+		slog.Info("rtp stream started", "source", source, "ssrc", pkt.ssrc, "payload_type", pkt.payloadType)
+	}
+	select {
+	case st.packets <- pkt:
+	default:
+		slog.Warn("rtp stream falling behind, dropping packet", "source", source, "ssrc", pkt.ssrc)
+	}
+}
+
+// run transcribes one stream until it goes idle or the listener closes.
+// Lost packets and timestamp jumps up to a second are filled with silence
+// so word times stay aligned with the call.
+func (in *rtpIngest) run(st *rtpStream, format asr.PCMFormat, payloadType byte) {
+	defer in.wg.Done()
+	session := in.s.newLiveSession(format, asr.TranscribeOptions{
+		Format:            ".wav",
+		Language:          "en",
+		Channels:          asr.ChannelMix,
+		Conditioning:      in.s.conditioning,
+		Segmentation:      in.s.segmentation,
+		NoSpeechThreshold: in.s.config.NoSpeechThreshold,
+		Guard:             in.s.guard,
+		Confidence:        in.s.confidence,
+		Formatting:        in.s.formatting,
+		Fallback:          in.s.fallback,
+		Denoise:           in.s.config.Denoise,
+		Priority:          asr.PriorityInteractive,
+	}, false, in.s.liveEndpointing())
+	defer in.s.liveSessions.add(session, "rtp", st.key)()
+	silence := byte(0xff) // µ-law zero
+	if format.Encoding == asr.PCMALaw {
+		silence = 0xd5
+	}
+
+	publish := func(res liveResult) {
+		if strings.TrimSpace(res.Text) == "" {
+			return
+		}
+		t := rtpTranscript{
+			Source:      st.source,
+			SSRC:        st.ssrc,
+			Encoding:    string(format.Encoding),
+			Start:       res.Start,
+			End:         res.Start + res.Duration,
+			Text:        res.Text,
+			SpeechFinal: res.SpeechFinal,
+			Words:       make([]transcriptWord, len(res.Words)),
+		}
+		for i, w := range res.Words {
+			t.Words[i] = transcriptWord{Text: w.Text, Start: w.Start, End: w.End, Confidence: w.Confidence}
+		}
+		if in.callback != nil {
+			in.callback.send(t)
+		}
+		in.feed.publish(t)
+	}
+
+	var started bool
+	var lastSeq uint16
+	var nextTS uint32
+	idle := time.NewTimer(rtpIdleTimeout)
+	defer idle.Stop()
+loop:
+	for {
+		select {
+		case pkt := <-st.packets:
+			idle.Reset(rtpIdleTimeout)
+			if pkt.payloadType != payloadType {
+				continue
+			}
+			if started {
+				if int16(pkt.seq-lastSeq) <= 0 {
+					continue // duplicate or late
+				}
+				if gap := pkt.timestamp - nextTS; gap > 0 && gap <= rtpMaxGapSamples {
+					if _, err := session.push(in.ctx, bytes.Repeat([]byte{silence}, int(gap))); err != nil {
+						slog.Error("rtp transcription failed", "stream", st.key, "error", err)
+					}
+				}
+			}
+			started = true
+			lastSeq = pkt.seq
+			nextTS = pkt.timestamp + uint32(len(pkt.payload))
+			results, err := session.push(in.ctx, pkt.payload)
+			if err != nil {
+				if in.ctx.Err() == nil {
+					slog.Error("rtp transcription failed", "stream", st.key, "error", err)
+				}
+				continue
+			}
+			for _, res := range results {
+				publish(res)
+			}
+		case <-idle.C:
+			break loop
+		case <-in.ctx.Done():
+			break loop
+		}
+	}
+
+	in.mu.Lock()
+	delete(in.streams, st.key)
+	in.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), rtpFinalizeTimeout)
+	defer cancel()
+	if res, err := session.finalize(ctx, false, false); err != nil {
+		slog.Error("rtp transcription failed", "stream", st.key, "error", err)
+	} else if res != nil {
+		publish(*res)
+	}
+	slog.Info("rtp stream ended", "source", st.source, "ssrc", st.ssrc, "seconds", session.receivedSeconds())
+}
+
+// transcriptFeed fans transcripts out to WebSocket subscribers. A
+// subscriber that cannot keep up misses transcripts rather than slowing
+// the streams down.
+type transcriptFeed struct {
+	mu   sync.Mutex
+	subs map[chan []byte]struct{}
+}
+
+func newTranscriptFeed() *transcriptFeed {
+	return &transcriptFeed{subs: make(map[chan []byte]struct{})}
+}
+
+func (f *transcriptFeed) subscribe() chan []byte {
+	ch := make(chan []byte, callbackQueueSize)
+	f.mu.Lock()
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch
+}
+
+func (f *transcriptFeed) unsubscribe(ch chan []byte) {
+	f.mu.Lock()
+	delete(f.subs, ch)
+	f.mu.Unlock()
+}
+
+func (f *transcriptFeed) publish(v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+}
+
+// handleRTPTranscripts serves /rtp/transcripts, a WebSocket that receives
+// every final RTP transcript as a JSON text message.
+func (s *Server) handleRTPTranscripts(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r, "")
+	if err != nil {
+		slog.Debug("rtp transcripts upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := s.rtp.feed.subscribe()
+	defer s.rtp.feed.unsubscribe(ch)
+
+	// The reader answers pings and notices the client leaving.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.readMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case payload := <-ch:
+			if err := conn.writeText(payload); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		case <-s.rtp.ctx.Done():
+			conn.writeClose(wsCloseNormal, "server shutting down")
+			return
+		}
+	}
+}
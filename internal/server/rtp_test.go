@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// rtpHeader builds a 12-byte RTP header.
+func rtpHeader(flags, pt byte, seq uint16, ts, ssrc uint32) []byte {
+	b := []byte{0x80 | flags, pt}
+	b = binary.BigEndian.AppendUint16(b, seq)
+	b = binary.BigEndian.AppendUint32(b, ts)
+	return binary.BigEndian.AppendUint32(b, ssrc)
+}
+
+func TestParseRTP(t *testing.T) {
+	payload := []byte{1, 2, 3, 4}
+	withCSRC := append(rtpHeader(0x01, 0, 7, 160, 42), 0, 0, 0, 9)
+	withExt := append(rtpHeader(0x10, 8, 7, 160, 42), 0xbe, 0xde, 0, 1, 0xaa, 0xbb, 0xcc, 0xdd)
+	withPadding := append(append(rtpHeader(0x20, 0, 7, 160, 42), payload...), 0, 0, 3)
+
+	for _, tc := range []struct {
+		name    string
+		packet  []byte
+		pt      byte
+		wantErr bool
+	}{
+		{name: "plain", packet: append(rtpHeader(0, 0x80|0, 7, 160, 42), payload...), pt: 0},
+		{name: "csrc", packet: append(withCSRC, payload...), pt: 0},
+		{name: "extension", packet: append(withExt, payload...), pt: 8},
+		{name: "padding", packet: withPadding, pt: 0},
+		{name: "short", packet: []byte{0x80, 0}, wantErr: true},
+		{name: "version 1", packet: append([]byte{0x40}, rtpHeader(0, 0, 7, 160, 42)[1:]...), wantErr: true},
+		{name: "rtcp", packet: rtpHeader(0, 200, 0, 0, 42), wantErr: true},
+		{name: "truncated extension", packet: rtpHeader(0x10, 0, 7, 160, 42), wantErr: true},
+	} {
+		p, err := parseRTP(tc.packet)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if p.payloadType != tc.pt || p.seq != 7 || p.timestamp != 160 || p.ssrc != 42 || !bytes.Equal(p.payload, payload) {
+			t.Errorf("%s: got %+v", tc.name, p)
+		}
+	}
+}
+
+func TestRTPIngest_SilentStream(t *testing.T) {
+	s := &Server{stats: newServerStats(), config: Config{RTPAddr: "127.0.0.1:0"}}
+	s.rtp = newRTPIngest(s)
+	if err := s.startRTP(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.rtp.close()
+
+	conn, err := net.Dial("udp", s.rtp.conns[0].LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// Half a second of µ-law silence in 20 ms packets, plus one Opus-style
+	// dynamic payload type that must be ignored.
+	for i := 0; i < 25; i++ {
+		pkt := append(rtpHeader(0, 0, uint16(i), uint32(i*160), 1234), bytes.Repeat([]byte{0xff}, 160)...)
+		conn.Write(pkt)
+	}
+	conn.Write(append(rtpHeader(0, 111, 0, 0, 5678), 1, 2, 3))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.rtp.mu.Lock()
+		n := len(s.rtp.streams)
+		s.rtp.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d streams, want 1", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Closing finalizes the stream; silence reaches neither the model nor
+	// the subscribers.
+	feed := s.rtp.feed.subscribe()
+	s.rtp.close()
+	if len(s.rtp.streams) != 0 {
+		t.Errorf("%d streams left after close", len(s.rtp.streams))
+	}
+	select {
+	case msg := <-feed:
+		t.Errorf("unexpected transcript %s", msg)
+	default:
+	}
+}
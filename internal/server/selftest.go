@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"parakeet/internal/selftest"
+)
+
+// selftestTimeout bounds a whole self-test run.
+const selftestTimeout = 2 * time.Minute
+
+// SelftestResponse is the body of /admin/selftest.
+type SelftestResponse struct {
+	Passed bool           `json:"passed"`
+	Model  string         `json:"model"`
+	Cases  []SelftestCase `json:"cases"`
+}
+
+// SelftestCase is one clip of a self-test and what the model made of it.
+type SelftestCase struct {
+	Name      string  `json:"name"`
+	Passed    bool    `json:"passed"`
+	Reference string  `json:"reference"`
+	Text      string  `json:"text"`
+	WER       float64 `json:"wer"`
+	MaxWER    float64 `json:"max_wer"`
+	Seconds   float64 `json:"seconds"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// runSelftest transcribes the self-test clips (see selftest.StartupCases)
+// with the loaded model and the server's default options, as `parakeet
+// transcribe` would, and scores each against its reference transcript. A
+// broken model file or a regression in the audio preprocessing shows up as
+// words in the silence or a wrong transcript of a reference clip.
+func (s *Server) runSelftest(ctx context.Context) (SelftestResponse, error) {
+	cases, err := selftest.StartupCases(s.config.SelftestClipsDir)
+	if err != nil {
+		return SelftestResponse{}, fmt.Errorf("self-test clips: %w", err)
+	}
+	t, done, err := s.useTranscriber()
+	if err != nil {
+		return SelftestResponse{}, err
+	}
+	defer done()
+	report := selftest.RunFunc(ctx, cases, 0, func(ctx context.Context, c selftest.Case) (string, error) {
+		res, err := t.TranscribeWithOptions(ctx, c.Audio, s.fileTranscribeOptions(c.Filename, FileOptions{}), nil)
+		if err != nil {
+			return "", err
+		}
+		return res.Text, nil
+	})
+	return selftestResponse(t.Info().Name, report), nil
+}
+
+// selftestResponse renders a self-test report.
+func selftestResponse(model string, report selftest.Report) SelftestResponse {
+	resp := SelftestResponse{Passed: report.Passed(), Model: model, Cases: []SelftestCase{}}
+	for _, r := range report.Results {
+		c := SelftestCase{
+			Name:      r.Name,
+			Passed:    r.Passed(),
+			Reference: r.Reference,
+			Text:      r.Hypothesis,
+			WER:       r.Score.WER(),
+			MaxWER:    r.MaxWER,
+			Seconds:   r.Elapsed.Seconds(),
+		}
+		if r.Err != nil {
+			c.Error = r.Err.Error()
+		}
+		resp.Cases = append(resp.Cases, c)
+	}
+	return resp
+}
+
+// startupSelftest runs the self-test once the models are loaded, logging
+// every case that fails as an error. It returns an error naming them, for
+// -startup-selftest=error to refuse to start on.
+func (s *Server) startupSelftest() error {
+	ctx, cancel := context.WithTimeout(context.Background(), selftestTimeout)
+	defer cancel()
+	started := time.Now()
+	resp, err := s.runSelftest(ctx)
+	if err != nil {
+		slog.Error("startup self-test could not run", "error", err)
+		return fmt.Errorf("startup self-test: %w", err)
+	}
+	if resp.Passed {
+		slog.Info("startup self-test passed", "model", resp.Model, "cases", len(resp.Cases), "seconds", time.Since(started).Seconds())
+		return nil
+	}
+	var failed []string
+	for _, c := range resp.Cases {
+		if c.Passed {
+			continue
+		}
+		failed = append(failed, c.Name)
+		slog.Error("startup self-test case failed: the model or the audio pipeline is broken",
+			"model", resp.Model,
+			"case", c.Name,
+			"reference", c.Reference,
+			"text", c.Text,
+			"wer", c.WER,
+			"max_wer", c.MaxWER,
+			"error", c.Error,
+		)
+	}
+	return fmt.Errorf("startup self-test failed: %s", strings.Join(failed, ", "))
+}
+
+// handleSelftest serves /admin/selftest: the startup self-test run again on
+// the current models, answered with 200 when every case passes and 503
+// otherwise, so it can back a deeper health check after a reload.
+func (s *Server) handleSelftest(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), selftestTimeout)
+	defer cancel()
+	resp, err := s.runSelftest(ctx)
+	if err != nil {
+		s.writeTranscribeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Passed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"parakeet/internal/selftest"
+)
+
+func TestSelftestResponse(t *testing.T) {
+	report := selftest.Report{Results: []selftest.CaseResult{
+		{Name: "silence-2s", MaxWER: 0.25},
+		{Name: "clip", Reference: "the birch canoe", Hypothesis: "the bird canoe", Score: selftest.Compare("the birch canoe", "the bird canoe"), MaxWER: 0.25},
+		{Name: "broken", Reference: "a clip", MaxWER: 0.25, Err: errors.New("decode failed")},
+	}}
+	resp := selftestResponse("parakeet-tdt-0.6b-v3", report)
+	if resp.Passed || resp.Model != "parakeet-tdt-0.6b-v3" || len(resp.Cases) != 3 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if c := resp.Cases[0]; !c.Passed || c.Error != "" {
+		t.Errorf("silence case = %+v, want passed", c)
+	}
+	if c := resp.Cases[1]; c.Passed || c.Text != "the bird canoe" || c.WER < 0.3 || c.WER > 0.34 {
+		t.Errorf("clip case = %+v, want failed with a WER of 1/3", c)
+	}
+	if c := resp.Cases[2]; c.Passed || c.Error != "decode failed" {
+		t.Errorf("broken case = %+v, want failed with its error", c)
+	}
+}
+
+func TestNewRejectsUnknownStartupSelftest(t *testing.T) {
+	_, err := New(Config{StartupSelftest: "strict"})
+	if err == nil || !strings.Contains(err.Error(), "-startup-selftest") {
+		t.Fatalf("New = %v, want -startup-selftest rejected", err)
+	}
+}
@@ -9,10 +9,17 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/exec"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"parakeet/internal/asr"
+	"parakeet/internal/itn"
+	"parakeet/internal/jobs"
+	"parakeet/internal/mqtt"
+	"parakeet/internal/nats"
 )
 
 const apiKeyEnvVar = "PARAKEET_API_KEY"
@@ -25,6 +32,103 @@ type Config struct {
 	LogFormat string
 	Workers   int
 
+	// ModelsArchive, when set, loads the models from this .zip or .tar
+	// instead of ModelsDir, so a deployment can ship them as one file.
+	ModelsArchive string
+
+	// ModelsReloadRoot lets POST /admin/models/reload load models_dir and
+	// models_archive paths under this directory. Empty: only ModelsDir and
+	// ModelsArchive.
+	ModelsReloadRoot string
+
+	// ONNXRuntimeDownload fetches ONNX Runtime into ONNXRuntimeCacheDir
+	// (empty: the user cache directory) from ONNXRuntimeURL (empty: the
+	// GitHub releases) when no installed library is found. The archive
+	// must match the pinned digest, or ONNXRuntimeSHA256 when set, and
+	// ONNXRuntimeURL must be https unless ONNXRuntimeAllowHTTP.
+	ONNXRuntimeDownload  bool
+	ONNXRuntimeCacheDir  string
+	ONNXRuntimeURL       string
+	ONNXRuntimeAllowHTTP bool
+	ONNXRuntimeSHA256    string
+
+	// CanaryModelsDir loads a second model from this directory and sends
+	// CanaryWeight (0..1) of the transcriptions to it instead of the loaded
+	// model, to validate a new version on live traffic before replacing the
+	// old one. /admin/stats reports the decodes, errors and real-time factor
+	// of each model. The canary's results are not cached, and its
+	// config.json name must differ from the loaded model's. Empty, the
+	// default, disables it.
+	CanaryModelsDir string
+	CanaryWeight    float64
+
+	// ShadowModelsDir loads a second model from this directory that
+	// transcribes ShadowSample (0..1) of the requests again in the
+	// background, once the client has its answer, and compares the two
+	// transcripts: /admin/stats sums up their divergence and ShadowLog,
+	// when set, gets one JSON line per comparison with both texts. The
+	// shadow never delays or changes a response; sampled requests are
+	// skipped while all its workers are busy. Empty, the default, disables
+	// it.
+	ShadowModelsDir string
+	ShadowSample    float64
+	ShadowLog       string
+
+	// CompareModelsDir loads a second model from this directory that only
+	// /v1/audio/compare and `parakeet compare` use, to compare it with the
+	// loaded model on chosen audio. Empty, the default, leaves them the
+	// canary and shadow models.
+	CompareModelsDir string
+
+	// VerifyModels is what happens when the model files do not match the
+	// models.lock next to them: "error" (refuse to start), "warn" or "off".
+	VerifyModels string
+
+	// StartupSelftest is what happens when the model, right after loading,
+	// does not transcribe the self-test clips (silence, the reference clips
+	// built into the binary and SelftestClipsDir) as expected: "error"
+	// (refuse to start), "warn" (log it and serve anyway) or "off" (skip
+	// it). Empty is off. /admin/selftest runs the same check on demand.
+	StartupSelftest string
+
+	// SelftestClipsDir adds its audio files, each with a sibling .txt
+	// reference transcript, to the self-test clips.
+	SelftestClipsDir string
+
+	// QueueLimit* cap the transcriptions waiting for a decoder worker per
+	// priority class (interactive, normal, batch); zero is unlimited. A
+	// request over its class's limit gets 503 with Retry-After.
+	QueueLimitInteractive int
+	QueueLimitNormal      int
+	QueueLimitBatch       int
+
+	// ShedLatencyBudget, when positive, answers transcription requests with
+	// 503 and Retry-After while the p99 latency of the last 30 s exceeds it.
+	ShedLatencyBudget time.Duration
+
+	// MaxProcessing is the default of the max_processing_ms parameter: when
+	// positive, a transcription that runs longer stops and returns the
+	// transcript so far, flagged as truncated.
+	MaxProcessing time.Duration
+
+	// MaxTokensPerStep is the default of the max_tokens_per_step
+	// parameter, overriding the model's config.json; zero keeps the
+	// model's (10 unless it sets one).
+	MaxTokensPerStep int
+
+	// DecoderBatch, above 1, runs the decoder steps of concurrent decodes
+	// together, up to this many per ONNX call; DecoderBatchWait bounds how
+	// long a step waits for the others (asr.DefaultDecoderBatchWait when
+	// zero).
+	DecoderBatch     int
+	DecoderBatchWait time.Duration
+
+	// ModelsIdleUnload, when positive, unloads the -models model after
+	// this long without a decode; the next request loads it again. It is
+	// still loaded at startup, and the canary, shadow and compare models
+	// are never unloaded.
+	ModelsIdleUnload time.Duration
+
 	// FFmpegEnabled toggles the ffmpeg-backed fallback for non-WAV audio.
 	// When true, unknown input formats are transcoded to 16 kHz mono WAV
 	// before transcription. When false, only WAV input is accepted.
@@ -44,6 +148,23 @@ type Config struct {
 	// GPUDeviceID selects the GPU device index for GPU providers.
 	GPUDeviceID int
 
+	// EncoderPrecision and DecoderPrecision pick the model exports to load:
+	// "auto" (int8, else fp32), "int8", "fp16" or "fp32". An explicit
+	// precision whose file is missing fails at startup.
+	EncoderPrecision string
+	DecoderPrecision string
+
+	// ONNXThreads is the threads each ONNX Runtime session runs an operator
+	// on; zero lets ONNX Runtime use every core. DisableONNXSpinning makes
+	// its idle threads sleep rather than busy-wait, which saves CPU and
+	// power on small boards.
+	ONNXThreads         int
+	DisableONNXSpinning bool
+
+	// MelPrecision is "float64" (the default when empty) or "float32", the
+	// precision log-mel features are computed in.
+	MelPrecision string
+
 	// ChunkSeconds is the sliding-window size for long audio, in seconds.
 	// ChunkOverlapSeconds is how much consecutive windows share so words at
 	// the seams keep their context. LongAudio enables the windowing; when off,
@@ -60,15 +181,420 @@ type Config struct {
 	DisableVADBasedChunking bool
 	DisableMelBasedChunking bool
 	VADModelPath            string
+
+	// ResampleQuality selects the resampler for WAV input that is not 16 kHz:
+	// "linear", "medium" (default) or "high". An unknown value fails fast at
+	// startup.
+	ResampleQuality string
+
+	// RemoveDC, GainNormalization and TrimSilence are the server-wide
+	// defaults of the audio conditioning chain (DC offset removal, "none" /
+	// "peak" / "loudness" level normalization, leading/trailing silence trim).
+	// Requests can override each one with remove_dc, normalize_gain and
+	// trim_silence. An unknown gain mode fails fast at startup.
+	RemoveDC          bool
+	GainNormalization string
+	TrimSilence       bool
+
+	// SegmentPause, SegmentSentences and SegmentMaxDuration are the
+	// server-wide defaults of transcript segmentation: split at silences of
+	// at least SegmentPause, after sentence-final punctuation, and segments
+	// longer than SegmentMaxDuration. Zero values keep one segment per
+	// transcript. Requests override each one with segment_pause,
+	// segment_sentences and segment_max_duration.
+	SegmentPause       time.Duration
+	SegmentSentences   bool
+	SegmentMaxDuration time.Duration
+
+	// NoSpeechThreshold drops transcript segments whose no-speech
+	// probability is above it (0..1), suppressing text made up on silence
+	// or noise. Zero, the default, keeps every segment. Requests override it
+	// with no_speech_threshold.
+	NoSpeechThreshold float64
+
+	// MinConfidence filters the words decoded with a confidence below it
+	// (0..1): ConfidencePlaceholder replaces their text, or when empty they
+	// are dropped. Zero, the default, keeps every word. Requests override
+	// them with min_confidence and confidence_placeholder.
+	MinConfidence         float64
+	ConfidencePlaceholder string
+
+	// Formatting is how numbers, dates, times and units are written in
+	// English transcripts, as a spec like "numbers=digits,units=abbreviate"
+	// (see itn.Parse). Empty, the default, leaves them as recognised.
+	// Requests override it, kind by kind, with formatting.
+	Formatting string
+
+	// ModelAliases are further model names the API answers to, for
+	// clients with a hard-coded model: comma-separated "alias" or
+	// "alias=model" entries, where model must be the loaded model's name.
+	// Requests using an alias are logged.
+	ModelAliases string
+
+	// Translation is what /v1/audio/translations does with a model that
+	// only transcribes: "transcribe" answers with the transcript, "reject"
+	// answers 501, and "auto" runs models whose config.json declares the
+	// translate task and answers 501 otherwise. "reject" refuses every
+	// translation.
+	Translation string
+
+	// HallucinationGuard is the default hallucination guard: "off",
+	// "flag" (mark suspect segments) or "blank" (remove them and collapse
+	// repetition loops). Requests override it with hallucination_guard. The
+	// Guard* limits tune its checks; zero takes the built-in defaults. An
+	// unknown mode fails fast at startup.
+	HallucinationGuard      string
+	GuardMinVoicedRatio     float64
+	GuardMaxTokensPerSecond float64
+	GuardMaxRepeats         int
+
+	// FallbackCompressionRatio and FallbackLogprob are the default
+	// compression_ratio_threshold and logprob_threshold: a chunk whose text
+	// compresses better than the ratio, or whose mean token log-probability
+	// is below the logprob, is decoded again with temperature sampling,
+	// FallbackTemperatureStep higher each time up to 1. Zero thresholds,
+	// the default, never retry.
+	FallbackCompressionRatio float64
+	FallbackLogprob          float64
+	FallbackTemperatureStep  float64
+
+	// Denoise runs every request through the noise-suppression model unless
+	// the request sets denoise=false. DenoiseModelPath overrides where the
+	// model is loaded from; empty means denoise.onnx inside the models
+	// directory. Without a model, denoise requests are rejected with 400.
+	Denoise          bool
+	DenoiseModelPath string
+
+	// Cache selects where finished transcriptions are kept for reuse:
+	// "off" (default), "memory" or "disk". Entries are keyed by the SHA-256
+	// of the audio plus every parameter that affects the transcript, and the
+	// least recently used entry is evicted beyond CacheSize entries. CacheDir
+	// is the directory used by the disk backend.
+	Cache     string
+	CacheSize int
+	CacheDir  string
+
+	// EncoderCacheTTL keeps the encoder output of each window for this long,
+	// up to EncoderCacheMB, so that follow-up requests on the same audio
+	// with other response formats, timestamps or decoding options skip the
+	// encoder. Zero, the default, disables it.
+	EncoderCacheTTL time.Duration
+	EncoderCacheMB  int
+
+	// HistoryDir records every buffered transcription (audio hash,
+	// parameters, timings and result) as a JSON file in this directory and
+	// serves them on /v1/transcripts. Records older than HistoryRetention
+	// (zero keeps them) and the oldest beyond HistoryMax are removed. Empty,
+	// the default, disables the history.
+	HistoryDir       string
+	HistoryRetention time.Duration
+	HistoryMax       int
+
+	// AuditDir records who transcribed what, one JSON line per request to
+	// an endpoint that takes audio (API key name, client address, file
+	// name, audio and transcript hashes, duration), in a file per UTC day
+	// in this directory, exported on /admin/audit. Files of days older than
+	// AuditRetention (zero keeps them) are removed. Empty, the default,
+	// disables the audit log.
+	AuditDir       string
+	AuditRetention time.Duration
+
+	// Build is the binary's version metadata, reported by /version and
+	// /health.
+	Build BuildInfo
+
+	// UI serves the embedded web page at / for uploading files, recording
+	// the microphone and live captions. The page holds no secrets; it asks
+	// for the API key and calls the public endpoints like any client.
+	UI bool
+
+	// LLMURL is the base URL of an OpenAI-compatible chat API (for Ollama,
+	// http://localhost:11434/v1). Requests with postprocess=llm send the
+	// transcript to its /chat/completions with LLMModel and LLMPrompt, a
+	// text/template over .Text and .Language (DefaultLLMPrompt when empty),
+	// and get the answer back as their text. LLMTimeout bounds each call.
+	// Empty disables post-processing.
+	LLMURL     string
+	LLMModel   string
+	LLMPrompt  string
+	LLMTimeout time.Duration
+
+	// PostprocessProfiles is a JSON file of per-language text profiles:
+	// word replacements and rewrite rules applied to every transcript in
+	// its language, and the prompt postprocess=llm uses for it. "*" keys
+	// the profile of languages without their own.
+	PostprocessProfiles string
+
+	// APIKeysFile lists further API keys, one "name:key" per line, accepted
+	// like PARAKEET_API_KEY. Audio transcribed with each key is accounted
+	// under its name on /admin/usage; PARAKEET_API_KEY counts as "default".
+	// UsageHeaders also reports the key's total on each response.
+	APIKeysFile  string
+	UsageHeaders bool
+
+	// OIDCIssuer accepts bearer JWTs signed by this OpenID Connect
+	// provider, as well as the API keys. Tokens are checked against the
+	// issuer's JWKS and must carry OIDCAudience in aud; their sub is the
+	// name usage is accounted under. Empty disables it.
+	OIDCIssuer   string
+	OIDCAudience string
+	// OIDCAdminClaim, "claim=value", makes the JWTs whose claim is, or is
+	// an array holding, value admins of /admin/*. Other JWTs never are.
+	OIDCAdminClaim string
+
+	// TrustedProxies, AllowCIDRs and DenyCIDRs are comma-separated CIDRs or
+	// addresses. Requests from a trusted proxy are attributed to the client
+	// in its X-Forwarded-For (or X-Real-IP). Clients in DenyCIDRs, or
+	// outside a non-empty AllowCIDRs, get 403 on everything but /health.
+	TrustedProxies string
+	AllowCIDRs     string
+	DenyCIDRs      string
+
+	// AccessLog writes one line per HTTP request (method, path, status,
+	// duration, audio seconds, real-time factor) to this file, or to stdout
+	// for "-", in AccessLogFormat: "json" or "clf" (Common Log Format with
+	// those fields appended). Empty, the default, disables it.
+	AccessLog       string
+	AccessLogFormat string
+
+	// DebugAddr enables net/http/pprof and expvar on a separate listener
+	// (e.g. "127.0.0.1:6060"). Empty, the default, disables them.
+	DebugAddr string
+
+	// UploadSpoolMB is how much of an upload is held in memory: multipart
+	// bodies past it are parsed to temp files, and AssemblyAI uploads,
+	// resumable ones included, are kept in one until they expire. Zero,
+	// the default, keeps uploads in memory.
+	UploadSpoolMB int
+
+	// MemoryBudget bounds the memory of the model weights and the
+	// transcriptions running at once: "auto" for GOMEMLIMIT, else the
+	// cgroup limit, else the machine's memory, less some headroom; or a
+	// size such as "6GiB" or "3000MB". Transcriptions wait for memory to
+	// free up, audio that would never fit is rejected with 413, and
+	// long-audio windows shrink for Workers of them to fit. Empty, the
+	// default, sets no budget.
+	MemoryBudget string
+
+	// AssemblyAI enables the AssemblyAI-compatible async API (/v2/upload,
+	// /v2/transcript). AssemblyAIAllowURLs additionally lets clients submit
+	// remote audio_url and webhook_url values, which makes the server send
+	// requests to addresses of the client's choosing; without it only
+	// upload URLs issued by this server are accepted.
+	AssemblyAI          bool
+	AssemblyAIAllowURLs bool
+
+	// JobsNATSURL shares the async API's jobs and uploads through NATS
+	// JetStream with every instance pointed at the same server: any of them
+	// accepts, serves and runs jobs, and a job whose instance dies is
+	// started again on another. Requires AssemblyAI. Empty, the default,
+	// keeps jobs in memory.
+	JobsNATSURL string
+
+	// TwilioCallbackURL enables the Twilio Media Streams endpoint
+	// (/twilio/stream): each final utterance of a call is POSTed there as
+	// JSON. Empty, the default, disables the endpoint.
+	TwilioCallbackURL string
+
+	// RTPAddr lists UDP addresses (comma-separated) to receive G.711 RTP
+	// streams on. Each stream is transcribed live and every final utterance
+	// is sent to /rtp/transcripts WebSocket subscribers and, when
+	// RTPCallbackURL is set, POSTed there. Empty disables RTP ingestion.
+	RTPAddr        string
+	RTPCallbackURL string
+
+	// LiveEndpointing is the trailing silence that finalizes an utterance
+	// of a live session (Deepgram live, Twilio, RTP, streams); zero means
+	// 500ms. Deepgram clients override it with endpointing. LiveVAD has the
+	// Silero VAD tell the silence instead of the audio level, so steady
+	// background noise does not hold an utterance open; it requires
+	// silero_vad.onnx.
+	LiveEndpointing time.Duration
+	LiveVAD         bool
+
+	// LiveIdleTimeout closes a WebSocket live session (Deepgram live,
+	// Twilio) that sends nothing for that long; zero keeps each protocol's
+	// default. LiveMaxDuration finalizes and closes one once it has run
+	// that long; zero is unlimited.
+	LiveIdleTimeout time.Duration
+	LiveMaxDuration time.Duration
+
+	// LiveMaxLag bounds the audio a Deepgram live session has received
+	// and not transcribed yet; zero means 10s. LiveLagPolicy is what
+	// happens beyond it: "block" (the default) stops reading until the
+	// decoder catches up, "drop" drops the oldest audio, "compress" blocks
+	// and skips interim results while audio is waiting.
+	LiveMaxLag    time.Duration
+	LiveLagPolicy string
+
+	// LiveInterimStability, above one, holds the words of interim live
+	// results back until that many interim transcriptions in a row agree
+	// on them, so captions stop flickering; the words shown only grow.
+	LiveInterimStability int
+
+	// Streams lists live sources to caption continuously, as comma-separated
+	// name=url pairs (RTSP, RTMP, HLS or anything else ffmpeg can open).
+	// Requires ffmpeg. Empty disables the stream workers.
+	Streams string
+
+	// MQTTBroker connects to an MQTT broker (mqtt:// or mqtts://, with
+	// credentials in the URL) and transcribes WAV or raw PCM published on
+	// MQTTTopics, answering on each topic's "transcript" sibling.
+	// MQTTDiscovery announces the result topics to Home Assistant. Empty
+	// disables MQTT.
+	MQTTBroker    string
+	MQTTTopics    string
+	MQTTDiscovery bool
+
+	// NATSURL runs a JetStream queue worker: messages pulled from the
+	// durable pull consumer NATSConsumer of NATSStream (audio, or JSON with
+	// an audio_url) are transcribed and the results published to
+	// NATSOutputSubject, NATSConcurrency at a time (Workers when zero).
+	// Inputs are acknowledged once their result is stored. Empty disables
+	// the worker.
+	NATSURL           string
+	NATSStream        string
+	NATSConsumer      string
+	NATSOutputSubject string
+	NATSConcurrency   int
 }
 
 // Server represents the HTTP server for the ASR service
 type Server struct {
 	config      Config
-	transcriber *asr.Transcriber
 	httpServer  *http.Server
+	debugServer *http.Server
 	mux         *http.ServeMux
 	apiKey      string
+
+	// apiKeys are the -api-keys-file keys, mapped to the name their usage
+	// is accounted under; usage holds that accounting.
+	apiKeys map[string]string
+	usage   *usageMeter
+
+	// oidc accepts JWTs of -oidc-issuer as API credentials; nil when off.
+	oidc *oidcVerifier
+
+	// trustedProxies may report the client in X-Forwarded-For; allowIPs
+	// and denyIPs restrict who may call the server. All empty when off.
+	trustedProxies ipSet
+	allowIPs       ipSet
+	denyIPs        ipSet
+
+	// conditioning is the default audio conditioning chain, parsed once
+	// from Config; see conditioningFor for the per-request overlay.
+	conditioning asr.Conditioning
+
+	// segmentation is the default transcript segmentation; see
+	// segmentationFor for the per-request overlay.
+	segmentation asr.Segmentation
+
+	// guard is the default hallucination guard; the mode can be overridden
+	// per request with hallucination_guard.
+	guard asr.HallucinationGuard
+
+	// confidence is the default low-confidence word filter; see
+	// confidenceFor for the per-request overlay.
+	confidence asr.ConfidenceFilter
+
+	// formatting is the default number, date, time and unit formatting;
+	// see formattingFor for the per-request overlay.
+	formatting itn.Options
+
+	// fallback is the default decoding fallback; see fallbackFor for the
+	// per-request overlay.
+	fallback asr.Fallback
+
+	// cache holds finished transcriptions; nil when caching is off.
+	cache resultCache
+
+	// history records transcriptions for /v1/transcripts; nil when
+	// -history-dir is empty.
+	history *historyStore
+
+	// audit writes the -audit-dir; nil when it is not set.
+	audit *auditLog
+
+	// inflight deduplicates identical buffered requests that overlap in time.
+	inflight *inflightGroup
+
+	// adminKey guards /admin/*; empty falls back to apiKey, unless named
+	// keys or OIDC are on, which leaves /admin/* closed (see requireAdmin).
+	adminKey string
+	stats    *serverStats
+
+	// jobs and uploads back the AssemblyAI-compatible async API, and tus
+	// holds the resumable uploads still being sent; nil when it is
+	// disabled.
+	jobs    jobs.Store
+	uploads uploadStorage
+	tus     *tusUploads
+
+	// cluster is jobs when they are shared through -jobs-nats-url.
+	cluster *jobs.Cluster
+
+	// twilioAuthToken verifies X-Twilio-Signature on /twilio/stream.
+	twilioAuthToken string
+
+	// rtp receives RTP streams; nil when -rtp-addr is empty.
+	rtp *rtpIngest
+
+	// streams captions the -streams sources; nil when there are none.
+	streams *streamWorkers
+
+	// liveSessions lists the live sessions running, for /admin/sessions.
+	liveSessions liveSessionRegistry
+
+	// mqtt bridges the -mqtt-broker; nil when it is not set.
+	mqtt *mqttBridge
+
+	// nats consumes the -nats-url queue; nil when it is not set.
+	nats *natsWorker
+
+	// llm post-processes transcripts for postprocess=llm; nil when
+	// -llm-url is empty.
+	llm *llmPostprocessor
+
+	// profiles are the -postprocess-profiles; nil when it is not set.
+	profiles *textProfiles
+
+	// aliases are the -model-aliases.
+	aliases []modelAlias
+
+	// canary decodes -canary-weight of the transcriptions; nil when
+	// -canary-models-dir is not set.
+	canary *canaryModels
+
+	// shadow transcribes -shadow-sample of the requests again for
+	// comparison; nil when -shadow-models-dir is not set.
+	shadow *shadowModels
+
+	// compareModels is the -compare-models-dir model, for comparisons
+	// only; nil when it is not set.
+	compareModels *loadedModels
+
+	// access writes the -access-log; nil when it is not set.
+	access *accessLogger
+
+	// models is the current model generation, swapped by ReloadModels.
+	// modelOptions is how every generation is loaded, apart from where the
+	// files come from.
+	models       atomic.Pointer[loadedModels]
+	modelOptions asr.Options
+
+	// reloadMu serializes reloads with each other and with Close, which
+	// sets modelsClosed. retiring tracks replaced generations waiting to be
+	// closed; closing retireNow closes them without the grace period.
+	reloadMu     sync.Mutex
+	modelsClosed bool
+	retiring     sync.WaitGroup
+	retireNow    chan struct{}
+
+	// idle unloads unused models; nil unless -models-idle-unload is set.
+	idle *idleModels
+
+	// latency tracks recent transcription latencies for shedLoad.
+	latency latencyTracker
 }
 
 // New creates a new Server instance with the given configuration
@@ -81,8 +607,254 @@ func New(cfg Config) (*Server, error) {
 		return nil, err
 	}
 
-	// Initialize transcriber
-	transcriber, err := asr.NewTranscriber(cfg.ModelsDir, cfg.Workers, asr.Options{
+	resampleQuality, err := asr.ParseResampleQuality(cfg.ResampleQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	verify, err := asr.ParseVerifyMode(cfg.VerifyModels)
+	if err != nil {
+		return nil, err
+	}
+
+	encoderPrecision, err := asr.ParsePrecision(cfg.EncoderPrecision)
+	if err != nil {
+		return nil, fmt.Errorf("encoder: %w", err)
+	}
+	decoderPrecision, err := asr.ParsePrecision(cfg.DecoderPrecision)
+	if err != nil {
+		return nil, fmt.Errorf("decoder: %w", err)
+	}
+
+	if cfg.ONNXThreads < 0 {
+		return nil, fmt.Errorf("invalid -onnx-threads: must not be negative")
+	}
+	if cfg.DecoderBatch < 0 || cfg.DecoderBatchWait < 0 {
+		return nil, fmt.Errorf("invalid -decoder-batch or -decoder-batch-wait: must not be negative")
+	}
+	switch cfg.MelPrecision {
+	case "", "float64", "float32":
+	default:
+		return nil, fmt.Errorf("invalid -mel-precision %q (supported: float64, float32)", cfg.MelPrecision)
+	}
+	switch cfg.StartupSelftest {
+	case "", "off", "warn", "error":
+	default:
+		return nil, fmt.Errorf("invalid -startup-selftest %q (supported: error, warn, off)", cfg.StartupSelftest)
+	}
+
+	gain, err := asr.ParseGainMode(cfg.GainNormalization)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := parseModelAliases(cfg.ModelAliases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -model-aliases: %w", err)
+	}
+
+	if cfg.CanaryModelsDir != "" {
+		if err := checkCanaryWeight(cfg.CanaryWeight); err != nil {
+			return nil, fmt.Errorf("invalid -canary-weight: %w", err)
+		}
+	}
+
+	if cfg.ShadowModelsDir != "" {
+		if err := checkShadowSample(cfg.ShadowSample); err != nil {
+			return nil, fmt.Errorf("invalid -shadow-sample: %w", err)
+		}
+	}
+
+	if cfg.Translation == "" {
+		cfg.Translation = translationTranscribe
+	}
+	if err := checkTranslationMode(cfg.Translation); err != nil {
+		return nil, fmt.Errorf("invalid -translation: %w", err)
+	}
+
+	runtimeCfg := asr.RuntimeConfig{BaseURL: cfg.ONNXRuntimeURL, AllowHTTP: cfg.ONNXRuntimeAllowHTTP, SHA256: cfg.ONNXRuntimeSHA256}
+	if err := runtimeCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid -onnxruntime-url or -onnxruntime-sha256: %w", err)
+	}
+
+	confidence := asr.ConfidenceFilter{Min: cfg.MinConfidence, Placeholder: cfg.ConfidencePlaceholder}
+	if err := confidence.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid -min-confidence: %w", err)
+	}
+	formatting, err := itn.Parse(cfg.Formatting)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -formatting: %w", err)
+	}
+
+	guardMode, err := asr.ParseGuardMode(cfg.HallucinationGuard)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -hallucination-guard: %w", err)
+	}
+	fallback := asr.Fallback{
+		CompressionRatio: cfg.FallbackCompressionRatio,
+		Logprob:          cfg.FallbackLogprob,
+		TemperatureStep:  cfg.FallbackTemperatureStep,
+	}
+	if err := fallback.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid decoding fallback: %w", err)
+	}
+
+	if cfg.TwilioCallbackURL != "" {
+		if err := checkHTTPURL(cfg.TwilioCallbackURL); err != nil {
+			return nil, fmt.Errorf("invalid -twilio-callback-url: %w", err)
+		}
+	}
+
+	if cfg.UploadSpoolMB < 0 {
+		return nil, fmt.Errorf("invalid -upload-spool-mb: must not be negative")
+	}
+	if cfg.EncoderCacheTTL < 0 || cfg.EncoderCacheMB < 0 {
+		return nil, fmt.Errorf("invalid -encoder-cache-ttl or -encoder-cache-mb: must not be negative")
+	}
+	memoryBudget, err := parseMemoryBudget(cfg.MemoryBudget)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -memory-budget: %w", err)
+	}
+
+	if cfg.LiveEndpointing < 0 {
+		return nil, fmt.Errorf("invalid -live-endpointing: must not be negative")
+	}
+	if cfg.LiveIdleTimeout < 0 || cfg.LiveMaxDuration < 0 {
+		return nil, fmt.Errorf("invalid -live-idle-timeout or -live-max-duration: must not be negative")
+	}
+	if cfg.LiveMaxLag < 0 {
+		return nil, fmt.Errorf("invalid -live-max-lag: must not be negative")
+	}
+	if cfg.LiveLagPolicy != "" {
+		if err := checkLiveLagPolicy(cfg.LiveLagPolicy); err != nil {
+			return nil, fmt.Errorf("invalid -live-lag-policy: %w", err)
+		}
+	}
+	if cfg.LiveInterimStability < 0 || cfg.LiveInterimStability > maxLiveInterimStability {
+		return nil, fmt.Errorf("invalid -live-interim-stability: must be between 0 and %d", maxLiveInterimStability)
+	}
+
+	if cfg.RTPCallbackURL != "" {
+		if err := checkHTTPURL(cfg.RTPCallbackURL); err != nil {
+			return nil, fmt.Errorf("invalid -rtp-callback-url: %w", err)
+		}
+	}
+
+	var streamSources []*streamSource
+	var ffmpegBin string
+	if cfg.Streams != "" {
+		if streamSources, err = parseStreamSources(cfg.Streams); err != nil {
+			return nil, fmt.Errorf("invalid -streams: %w", err)
+		}
+		if !cfg.FFmpegEnabled {
+			return nil, fmt.Errorf("-streams requires ffmpeg (-ffmpeg=true)")
+		}
+		bin := cfg.FFmpegPath
+		if bin == "" {
+			bin = "ffmpeg"
+		}
+		if ffmpegBin, err = exec.LookPath(bin); err != nil {
+			return nil, fmt.Errorf("-streams requires ffmpeg: %w", err)
+		}
+	}
+
+	var mqttTopics []string
+	if cfg.MQTTBroker != "" {
+		if err := mqtt.CheckBroker(cfg.MQTTBroker); err != nil {
+			return nil, fmt.Errorf("invalid -mqtt-broker: %w", err)
+		}
+		if mqttTopics, err = parseMQTTTopics(cfg.MQTTTopics); err != nil {
+			return nil, fmt.Errorf("invalid -mqtt-topics: %w", err)
+		}
+	}
+
+	if cfg.NATSURL != "" {
+		if err := nats.CheckURL(cfg.NATSURL); err != nil {
+			return nil, fmt.Errorf("invalid -nats-url: %w", err)
+		}
+		if cfg.NATSStream == "" || cfg.NATSConsumer == "" || cfg.NATSOutputSubject == "" {
+			return nil, fmt.Errorf("-nats-url requires -nats-stream, -nats-consumer and -nats-output")
+		}
+	}
+
+	if cfg.JobsNATSURL != "" {
+		if !cfg.AssemblyAI {
+			return nil, fmt.Errorf("-jobs-nats-url requires -assemblyai")
+		}
+		if err := nats.CheckURL(cfg.JobsNATSURL); err != nil {
+			return nil, fmt.Errorf("invalid -jobs-nats-url: %w", err)
+		}
+	}
+
+	var llm *llmPostprocessor
+	if cfg.LLMURL != "" {
+		if llm, err = newLLMPostprocessor(cfg.LLMURL, cfg.LLMModel, cfg.LLMPrompt, cfg.LLMTimeout); err != nil {
+			return nil, err
+		}
+		llm.apiKey = os.Getenv(llmAPIKeyEnvVar)
+	}
+
+	var profiles *textProfiles
+	if cfg.PostprocessProfiles != "" {
+		if profiles, err = loadTextProfiles(cfg.PostprocessProfiles); err != nil {
+			return nil, fmt.Errorf("invalid -postprocess-profiles: %w", err)
+		}
+	}
+
+	var apiKeys map[string]string
+	if cfg.APIKeysFile != "" {
+		if apiKeys, err = loadAPIKeys(cfg.APIKeysFile); err != nil {
+			return nil, err
+		}
+	}
+
+	trustedProxies, err := parseIPSet(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -trusted-proxies: %w", err)
+	}
+	allowIPs, err := parseIPSet(cfg.AllowCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -allow-cidrs: %w", err)
+	}
+	denyIPs, err := parseIPSet(cfg.DenyCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -deny-cidrs: %w", err)
+	}
+
+	var oidc *oidcVerifier
+	if cfg.OIDCIssuer != "" {
+		if oidc, err = newOIDCVerifier(cfg.OIDCIssuer, cfg.OIDCAudience, cfg.OIDCAdminClaim); err != nil {
+			return nil, err
+		}
+	}
+
+	var access *accessLogger
+	if cfg.AccessLog != "" {
+		if access, err = newAccessLogger(cfg.AccessLog, cfg.AccessLogFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	cache, err := newResultCache(cfg.Cache, cfg.CacheSize, cfg.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var history *historyStore
+	if cfg.HistoryDir != "" {
+		if history, err = newHistoryStore(cfg.HistoryDir, cfg.HistoryRetention, cfg.HistoryMax); err != nil {
+			return nil, err
+		}
+	}
+
+	var audit *auditLog
+	if cfg.AuditDir != "" {
+		if audit, err = newAuditLog(cfg.AuditDir, cfg.AuditRetention); err != nil {
+			return nil, err
+		}
+	}
+
+	modelOptions := asr.Options{
 		FFmpeg: asr.FFmpegConfig{
 			Enabled:    cfg.FFmpegEnabled,
 			BinaryPath: cfg.FFmpegPath,
@@ -102,20 +874,239 @@ func New(cfg Config) (*Server, error) {
 			DisableMel:   cfg.DisableMelBasedChunking,
 			VADModelPath: cfg.VADModelPath,
 		},
-	})
+		Resample: resampleQuality,
+		Denoise: asr.DenoiseConfig{
+			ModelPath: cfg.DenoiseModelPath,
+		},
+		Verify: verify,
+		Precision: asr.PrecisionConfig{
+			Encoder: encoderPrecision,
+			Decoder: decoderPrecision,
+		},
+		Threads: asr.ThreadConfig{
+			IntraOp: cfg.ONNXThreads,
+			NoSpin:  cfg.DisableONNXSpinning,
+		},
+		MelFloat32: cfg.MelPrecision == "float32",
+		EncoderCache: asr.EncoderCacheConfig{
+			TTL:      cfg.EncoderCacheTTL,
+			MaxBytes: int64(cfg.EncoderCacheMB) << 20,
+		},
+		MemoryBudget: memoryBudget,
+		QueueLimits: asr.QueueLimits{
+			Interactive: cfg.QueueLimitInteractive,
+			Normal:      cfg.QueueLimitNormal,
+			Batch:       cfg.QueueLimitBatch,
+		},
+		MaxTokensPerStep: cfg.MaxTokensPerStep,
+		DecoderBatch: asr.DecoderBatchConfig{
+			Size: cfg.DecoderBatch,
+			Wait: cfg.DecoderBatchWait,
+		},
+		Runtime: asr.RuntimeConfig{
+			Download:  cfg.ONNXRuntimeDownload,
+			CacheDir:  cfg.ONNXRuntimeCacheDir,
+			BaseURL:   cfg.ONNXRuntimeURL,
+			AllowHTTP: cfg.ONNXRuntimeAllowHTTP,
+			SHA256:    cfg.ONNXRuntimeSHA256,
+		},
+	}
+	models, err := loadModels(cfg, modelOptions, cfg.ModelsDir, cfg.ModelsArchive)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize transcriber: %w", err)
+		return nil, err
+	}
+	if err := checkModelAliases(aliases, models.transcriber.Info().Name); err != nil {
+		models.close()
+		return nil, fmt.Errorf("invalid -model-aliases: %w", err)
+	}
+	if cfg.LiveVAD && !models.transcriber.CanDetectSpeech() {
+		models.close()
+		return nil, fmt.Errorf("-live-vad requires silero_vad.onnx in the models directory")
+	}
+
+	var canary *canaryModels
+	if cfg.CanaryModelsDir != "" {
+		cm, err := loadModels(cfg, modelOptions, cfg.CanaryModelsDir, "")
+		if err != nil {
+			models.close()
+			return nil, fmt.Errorf("canary model: %w", err)
+		}
+		canary = &canaryModels{models: cm, weight: cfg.CanaryWeight}
+		if err := canary.check(models.transcriber.Info().Name); err != nil {
+			canary.close()
+			models.close()
+			return nil, fmt.Errorf("invalid -canary-models-dir: %w", err)
+		}
+	}
+
+	var shadow *shadowModels
+	if cfg.ShadowModelsDir != "" {
+		sm, err := loadModels(cfg, modelOptions, cfg.ShadowModelsDir, "")
+		if err != nil {
+			canary.close()
+			models.close()
+			return nil, fmt.Errorf("shadow model: %w", err)
+		}
+		if shadow, err = newShadowModels(sm, cfg.ShadowSample, cfg.Workers, cfg.ShadowLog); err != nil {
+			sm.close()
+			canary.close()
+			models.close()
+			return nil, err
+		}
+	}
+
+	var compare *loadedModels
+	if cfg.CompareModelsDir != "" {
+		if compare, err = loadModels(cfg, modelOptions, cfg.CompareModelsDir, ""); err != nil {
+			shadow.close()
+			canary.close()
+			models.close()
+			return nil, fmt.Errorf("compare model: %w", err)
+		}
 	}
 
 	s := &Server{
-		config:      cfg,
-		transcriber: transcriber,
-		mux:         http.NewServeMux(),
-		apiKey:      os.Getenv(apiKeyEnvVar),
+		config:  cfg,
+		mux:     http.NewServeMux(),
+		apiKey:  os.Getenv(apiKeyEnvVar),
+		apiKeys: apiKeys,
+		usage:   newUsageMeter(),
+		oidc:    oidc,
+
+		trustedProxies: trustedProxies,
+		allowIPs:       allowIPs,
+		denyIPs:        denyIPs,
+		conditioning: asr.Conditioning{
+			RemoveDC:    cfg.RemoveDC,
+			Gain:        gain,
+			TrimSilence: cfg.TrimSilence,
+		},
+		segmentation: asr.Segmentation{
+			Pause:       cfg.SegmentPause.Seconds(),
+			Sentences:   cfg.SegmentSentences,
+			MaxDuration: cfg.SegmentMaxDuration.Seconds(),
+		},
+		confidence: confidence,
+		formatting: formatting,
+		guard: asr.HallucinationGuard{
+			Mode:               guardMode,
+			MinVoicedRatio:     cfg.GuardMinVoicedRatio,
+			MaxTokensPerSecond: cfg.GuardMaxTokensPerSecond,
+			MaxRepeats:         cfg.GuardMaxRepeats,
+		},
+		fallback: fallback,
+		cache:    cache,
+		history:  history,
+		audit:    audit,
+		inflight: newInflightGroup(),
+		adminKey: os.Getenv(adminKeyEnvVar),
+		stats:    newServerStats(),
+		llm:      llm,
+		profiles: profiles,
+		aliases:  aliases,
+		canary:   canary,
+		shadow:   shadow,
+		access:   access,
+
+		compareModels: compare,
+
+		modelOptions: modelOptions,
+		retireNow:    make(chan struct{}),
+
+		twilioAuthToken: os.Getenv(twilioAuthTokenEnvVar),
+	}
+	s.models.Store(models)
+	s.hashModels(models, nil)
+
+	if cfg.AssemblyAI {
+		jobsCfg := jobs.Config{
+			Workers:   cfg.Workers,
+			Retention: assemblyAIRetention,
+			Fetch:     fetchAudio,
+			Done:      s.assemblyAIJobDone,
+		}
+		if cfg.JobsNATSURL != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), clusterConnectTimeout)
+			cluster, err := jobs.NewCluster(ctx, jobs.ClusterConfig{Config: jobsCfg, URL: cfg.JobsNATSURL}, s.transcribeJob)
+			cancel()
+			if err != nil {
+				if compare != nil {
+					compare.close()
+				}
+				shadow.close()
+				canary.close()
+				models.close()
+				return nil, fmt.Errorf("job cluster at %s: %w", redactURL(cfg.JobsNATSURL), err)
+			}
+			s.jobs, s.cluster, s.uploads = cluster, cluster, clusterUploads{cluster}
+			slog.Info("job cluster joined", "url", redactURL(cfg.JobsNATSURL), "worker", cluster.ID())
+		} else {
+			s.jobs = jobs.NewQueue(jobsCfg, s.transcribeJob)
+			s.uploads = newUploadStore()
+		}
+		s.tus = newTusUploads()
+		slog.Info("AssemblyAI-compatible API enabled", "remote_urls", cfg.AssemblyAIAllowURLs)
+	}
+
+	if cfg.RTPAddr != "" {
+		s.rtp = newRTPIngest(s)
+	}
+	if streamSources != nil {
+		s.streams = newStreamWorkers(streamSources, ffmpegBin)
+	}
+	if mqttTopics != nil {
+		s.mqtt = newMQTTBridge(s, mqttTopics)
+	}
+	if cfg.NATSURL != "" {
+		s.nats = newNATSWorker(s)
+	}
+
+	if cfg.TwilioCallbackURL != "" {
+		slog.Info("Twilio Media Streams enabled", "callback", cfg.TwilioCallbackURL,
+			"signature_check", s.twilioAuthToken != "")
+	}
+
+	if s.authEnabled() {
+		slog.Info("API key authentication enabled", "named_keys", len(s.apiKeys))
+	}
+	if oidc != nil {
+		slog.Info("OIDC authentication enabled", "issuer", oidc.issuer, "audience", oidc.audience, "admin_claim", cfg.OIDCAdminClaim)
+	}
+	if s.adminKey == "" && (len(s.apiKeys) > 0 || oidc != nil) && cfg.OIDCAdminClaim == "" {
+		slog.Warn("admin endpoints are closed: set " + adminKeyEnvVar + " to use /admin/* with named keys or OIDC")
+	}
+	if len(allowIPs) > 0 || len(denyIPs) > 0 || len(trustedProxies) > 0 {
+		slog.Info("client IP rules enabled", "allow", len(allowIPs), "deny", len(denyIPs), "trusted_proxies", len(trustedProxies))
+	}
+	if cache != nil {
+		slog.Info("result cache enabled", "backend", cfg.Cache, "size", cfg.CacheSize)
+	}
+	if llm != nil {
+		slog.Info("LLM post-processing enabled", "url", redactURL(cfg.LLMURL), "model", cfg.LLMModel)
+	}
+	if canary != nil {
+		slog.Info("canary model enabled", "model", canary.name(), "dir", cfg.CanaryModelsDir, "weight", cfg.CanaryWeight)
+	}
+	if shadow != nil {
+		slog.Info("shadow model enabled", "model", shadow.name(), "dir", cfg.ShadowModelsDir, "sample", cfg.ShadowSample, "log", cfg.ShadowLog)
+	}
+	if history != nil {
+		slog.Info("transcript history enabled", "dir", cfg.HistoryDir, "retention", cfg.HistoryRetention, "max", cfg.HistoryMax)
+	}
+	if audit != nil {
+		slog.Info("audit log enabled", "dir", cfg.AuditDir, "retention", cfg.AuditRetention)
+	}
+	if cfg.ModelsIdleUnload > 0 {
+		s.idle = newIdleModels(cfg.ModelsIdleUnload)
+		go s.unloadIdleModels()
+		slog.Info("idle model unloading enabled", "after", cfg.ModelsIdleUnload)
 	}
 
-	if s.apiKey != "" {
-		slog.Info("API key authentication enabled")
+	if cfg.StartupSelftest == "warn" || cfg.StartupSelftest == "error" {
+		if err := s.startupSelftest(); err != nil && cfg.StartupSelftest == "error" {
+			s.Close()
+			return nil, err
+		}
 	}
 
 	s.setupRoutes()
@@ -124,30 +1115,76 @@ func New(cfg Config) (*Server, error) {
 
 // setupRoutes configures the HTTP routes
 func (s *Server) setupRoutes() {
-	s.mux.HandleFunc("/v1/audio/transcriptions", s.requireAuth(s.handleTranscription))
-	s.mux.HandleFunc("/v1/audio/translations", s.requireAuth(s.handleTranslation))
-	s.mux.HandleFunc("/v1/models", s.requireAuth(s.handleModels))
-	s.mux.HandleFunc("/health", s.handleHealth)
+	s.route("/v1/audio/transcriptions", s.handleTranscription, s.countRequests, s.requireAuth, s.auditRequests, s.meterUsage, s.shedLoad)
+	s.route("/v1/audio/transcriptions/batch", s.handleBatchTranscription, s.countRequests, s.requireAuth, s.auditRequests, s.meterUsage, s.shedLoad)
+	s.route("/v1/audio/translations", s.handleTranslation, s.countRequests, s.requireAuth, s.auditRequests, s.meterUsage, s.shedLoad)
+	s.route("/inference", s.handleInference, s.countRequests, s.requireAuth, s.auditRequests, s.meterUsage, s.shedLoad)
+	s.route("/v1/audio/vad", s.handleVAD, s.countRequests, s.requireAuth)
+	if s.compareModels != nil || s.canary != nil || s.shadow != nil {
+		s.route("/v1/audio/compare", s.handleCompare, s.countRequests, s.requireAuth, s.auditRequests, s.meterUsage, s.shedLoad)
+	}
+	s.route("/v1/listen", s.handleListen, s.countRequests, s.requireDeepgramAuth, s.auditRequests, s.meterUsage, s.shedLoad)
+	s.route("/v1/models", s.handleModels, s.requireAuth)
+	s.route("/health", s.handleHealth)
+	s.route("/version", s.handleVersion)
+	s.route("/admin/stats", s.handleStats, s.requireAdmin)
+	s.route("/admin/models/reload", s.handleModelsReload, s.requireAdmin)
+	s.route("/admin/selftest", s.handleSelftest, s.requireAdmin)
+	s.route("/admin/usage", s.handleUsage, s.requireAdmin)
+	s.route("/admin/sessions", s.handleSessions, s.requireAdmin)
+	s.route("/admin/sessions/{id}", s.handleSession, s.requireAdmin)
+	if s.audit != nil {
+		s.route("/admin/audit", s.handleAudit, s.requireAdmin)
+	}
+	if s.config.UI {
+		s.route("/{$}", s.handleUI)
+	}
+	if s.history != nil {
+		s.route("/v1/transcripts", s.handleTranscripts, s.requireAuth, s.auditRequests)
+		s.route("/v1/transcripts/{id}", s.handleTranscript, s.requireAuth, s.auditRequests)
+	}
+	if s.cluster != nil {
+		s.route("/admin/cluster", s.handleCluster, s.requireAdmin)
+	}
+
+	if s.jobs != nil {
+		s.route("/v2/upload", s.handleAssemblyAIUpload, s.countRequests, s.requireAssemblyAIAuth, s.auditRequests)
+		s.route("/v2/upload/{id}", s.handleTusUpload, s.countRequests, s.requireAssemblyAIAuth, s.auditRequests)
+		s.route("/v2/transcript", s.handleAssemblyAITranscripts, s.countRequests, s.requireAssemblyAIAuth, s.auditRequests)
+		s.route("/v2/transcript/{id}", s.handleAssemblyAITranscript, s.countRequests, s.requireAssemblyAIAuth, s.auditRequests)
+		s.route("/v2/transcript/{id}/archive", s.handleAssemblyAIArchive, s.countRequests, s.requireAssemblyAIAuth, s.auditRequests)
+		s.route("/v2/transcript/{id}/{format}", s.handleAssemblyAISubtitles, s.countRequests, s.requireAssemblyAIAuth, s.auditRequests)
+	}
+	if s.config.TwilioCallbackURL != "" {
+		s.route("/twilio/stream", s.handleTwilioStream, s.countRequests)
+	}
+	if s.rtp != nil {
+		s.route("/rtp/transcripts", s.handleRTPTranscripts, s.requireAuth)
+	}
+	if s.streams != nil {
+		s.route("/streams", s.handleStreams, s.requireAuth)
+		s.route("/streams/{name}/captions", s.handleStreamCaptions, s.requireAuth)
+	}
 }
 
 // requireAuth wraps a handler with API key authentication.
 // If no API key is configured, requests pass through without checks.
-func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if s.apiKey == "" {
-			next(w, r)
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authEnabled() {
+			next.ServeHTTP(w, r)
 			return
 		}
 
 		auth := r.Header.Get("Authorization")
-		token := strings.TrimPrefix(auth, "Bearer ")
-		if auth == "" || token != s.apiKey {
+		name, ok := s.apiKeyName(strings.TrimPrefix(auth, "Bearer "))
+		if auth == "" || !ok {
 			sendError(w, "Invalid API key", "authentication_error", http.StatusUnauthorized)
 			return
 		}
 
-		next(w, r)
-	}
+		next.ServeHTTP(w, r.WithContext(withAPIKeyName(r.Context(), name)))
+	})
 }
 
 // Run starts the HTTP server. It blocks until the server is shut down.
@@ -156,18 +1193,26 @@ func (s *Server) Run() error {
 	addr := fmt.Sprintf(":%d", s.config.Port)
 	s.httpServer = &http.Server{
 		Addr:    addr,
-		Handler: s.mux,
+		Handler: s.Handler(),
 		// ReadHeaderTimeout bounds the time to read request headers, defending
 		// against Slowloris without capping the body upload or the response.
 		// We intentionally do NOT set WriteTimeout: streaming (SSE) responses
 		// are long-lived and a global write deadline would cut them off.
 		ReadHeaderTimeout: 30 * time.Second,
 	}
-	slog.Info("Parakeet ASR server started", "addr", addr)
+	slog.Info("Parakeet ASR server started", "addr", addr,
+		"version", s.config.Build.Version, "commit", s.config.Build.Commit)
 	slog.Info("endpoints registered",
 		"transcriptions", "POST /v1/audio/transcriptions",
 		"models", "GET /v1/models",
 	)
+	s.startDebugServer()
+	if err := s.startRTP(); err != nil {
+		return err
+	}
+	s.startStreams()
+	s.startMQTT()
+	s.startNATS()
 	err := s.httpServer.ListenAndServe()
 	if err == http.ErrServerClosed {
 		return nil
@@ -179,17 +1224,69 @@ func (s *Server) Run() error {
 // to complete before returning. After Shutdown returns, all request handlers
 // have finished and it is safe to call Close.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.debugServer != nil {
+		s.debugServer.Close()
+	}
+	// RTP streams deliver their last utterance before the decoders close.
+	if s.rtp != nil {
+		s.rtp.close()
+	}
+	// Stopping the stream workers also ends caption subscriptions, which
+	// would otherwise hold Shutdown open.
+	if s.streams != nil {
+		s.streams.close()
+	}
+	if s.mqtt != nil {
+		s.mqtt.close()
+	}
+	if s.nats != nil {
+		s.nats.close()
+	}
 	if s.httpServer != nil {
 		slog.Info("shutting down HTTP server, waiting for in-flight requests...")
-		return s.httpServer.Shutdown(ctx)
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			// Past the deadline, drop the remaining connections: it cancels
+			// their requests, so Close does not wait on their decodes.
+			s.httpServer.Close()
+			return err
+		}
 	}
 	return nil
 }
 
-// Close releases server resources. Must be called after Shutdown.
+// Close releases server resources. Must be called after Shutdown. It waits
+// for transcriptions still running to stop (asr.Transcriber.Close).
 func (s *Server) Close() error {
-	if s.transcriber != nil {
-		s.transcriber.Close()
+	// Running async jobs are cancelled before the decoders go away; in a
+	// job cluster they are handed to another instance.
+	if s.jobs != nil {
+		s.jobs.Close()
+		s.uploads.close()
+		s.tus.close()
+	}
+	if s.idle != nil {
+		close(s.idle.stop)
+	}
+	s.reloadMu.Lock()
+	s.modelsClosed = true
+	s.reloadMu.Unlock()
+	if s.retireNow != nil {
+		close(s.retireNow)
+	}
+	s.retiring.Wait()
+	if m := s.models.Load(); m != nil {
+		m.close()
+	}
+	s.canary.close()
+	s.shadow.close()
+	if s.compareModels != nil {
+		s.compareModels.close()
+	}
+	if s.access != nil {
+		s.access.Close()
+	}
+	if s.audit != nil {
+		s.audit.Close()
 	}
 	return nil
 }
@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// liveSessionRegistry lists the live sessions running, Deepgram live and
+// Twilio WebSockets, RTP streams and -streams sources, for
+// /admin/sessions.
+type liveSessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*liveSession
+}
+
+// add lists ls under a new ID, protocol being what feeds it and source
+// whom (a client address, a call, a stream name). It returns the func that
+// removes it once the session is over.
+func (reg *liveSessionRegistry) add(ls *liveSession, protocol, source string) func() {
+	ls.id = newRequestID()
+	ls.protocol, ls.source = protocol, source
+	ls.started = time.Now()
+	ls.sync(false)
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.sessions == nil {
+		reg.sessions = make(map[string]*liveSession)
+	}
+	reg.sessions[ls.id] = ls
+	return func() {
+		reg.mu.Lock()
+		defer reg.mu.Unlock()
+		delete(reg.sessions, ls.id)
+	}
+}
+
+func (reg *liveSessionRegistry) get(id string) (*liveSession, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	ls, ok := reg.sessions[id]
+	return ls, ok
+}
+
+// list returns the sessions running, oldest first.
+func (reg *liveSessionRegistry) list() []liveSessionInfo {
+	reg.mu.Lock()
+	out := make([]liveSessionInfo, 0, len(reg.sessions))
+	for _, ls := range reg.sessions {
+		out = append(out, ls.info())
+	}
+	reg.mu.Unlock()
+	slices.SortFunc(out, func(a, b liveSessionInfo) int {
+		return cmp.Or(a.Started.Compare(b.Started), cmp.Compare(a.ID, b.ID))
+	})
+	return out
+}
+
+// liveSessionInfo is one entry of GET /admin/sessions.
+type liveSessionInfo struct {
+	ID            string    `json:"id"`
+	Protocol      string    `json:"protocol"`
+	Source        string    `json:"source,omitempty"`
+	Started       time.Time `json:"started"`
+	LastAudio     time.Time `json:"last_audio,omitzero"`
+	AudioSeconds  float64   `json:"audio_seconds"`
+	Utterances    int       `json:"utterances"`
+	Language      string    `json:"language"`
+	EndpointingMS int64     `json:"endpointing_ms"`
+	Interim       bool      `json:"interim_results"`
+	// LagSeconds is the audio received and waiting to be transcribed;
+	// DroppedSeconds the audio dropped by -live-lag-policy drop.
+	LagSeconds     float64 `json:"lag_seconds"`
+	DroppedSeconds float64 `json:"dropped_seconds"`
+}
+
+// liveSettings are the settings of a session that can change while it
+// runs, from /admin/sessions/{id} or a Deepgram Configure message. Unset
+// fields are kept.
+type liveSettings struct {
+	Language *string `json:"language"`
+	// EndpointingMS is the pause that ends an utterance; 0 disables pause
+	// detection.
+	EndpointingMS *int64 `json:"endpointing_ms"`
+}
+
+// checkLiveSettings validates settings before they are queued.
+func (s *Server) checkLiveSettings(set liveSettings) error {
+	if set.Language != nil {
+		if err := s.checkLanguage(*set.Language); err != nil {
+			return err
+		}
+	}
+	if set.EndpointingMS != nil && *set.EndpointingMS < 0 {
+		return errors.New("endpointing_ms must not be negative")
+	}
+	return nil
+}
+
+// configure queues settings for the session's goroutine, which applies
+// them before the next packet or finalize; the utterance under way is
+// decoded with the new language.
+func (ls *liveSession) configure(set liveSettings) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.queued == nil {
+		ls.queued = &liveSettings{}
+	}
+	if set.Language != nil {
+		ls.queued.Language = set.Language
+	}
+	if set.EndpointingMS != nil {
+		ls.queued.EndpointingMS = set.EndpointingMS
+	}
+}
+
+// sync applies the queued settings and refreshes the status
+// /admin/sessions reports, audio telling whether a packet just arrived.
+// Only the session's goroutine calls it.
+func (ls *liveSession) sync(audio bool) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if q := ls.queued; q != nil {
+		if q.Language != nil {
+			ls.opts.Language = *q.Language
+		}
+		if q.EndpointingMS != nil {
+			ls.endpointing = time.Duration(*q.EndpointingMS) * time.Millisecond
+		}
+		ls.queued = nil
+	}
+	if audio {
+		ls.status.LastAudio = time.Now()
+	}
+	ls.status.AudioSeconds = ls.receivedSeconds()
+	ls.status.Language = ls.opts.Language
+	ls.status.EndpointingMS = ls.endpointing.Milliseconds()
+}
+
+func (ls *liveSession) info() liveSessionInfo {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	info := ls.status
+	info.ID, info.Protocol, info.Source, info.Started = ls.id, ls.protocol, ls.source, ls.started
+	info.Utterances = ls.utterances
+	info.Interim = ls.interim
+	info.LagSeconds, info.DroppedSeconds = ls.queue.lag()
+	return info
+}
+
+// liveIdleTimeout is how long a WebSocket live session waits for a message:
+// -live-idle-timeout, else the protocol's own default.
+func (s *Server) liveIdleTimeout(protocolDefault time.Duration) time.Duration {
+	return cmp.Or(s.config.LiveIdleTimeout, protocolDefault)
+}
+
+// liveReadDeadline is when a WebSocket live session that started at
+// started stops waiting for the next message: after the idle timeout, or at
+// -live-max-duration when that comes first.
+func (s *Server) liveReadDeadline(started time.Time, idle time.Duration) time.Time {
+	deadline := time.Now().Add(s.liveIdleTimeout(idle))
+	if end := started.Add(s.config.LiveMaxDuration); s.config.LiveMaxDuration > 0 && end.Before(deadline) {
+		deadline = end
+	}
+	return deadline
+}
+
+// liveExpired reports whether a session that started at started has run
+// for -live-max-duration.
+func (s *Server) liveExpired(started time.Time) bool {
+	return s.config.LiveMaxDuration > 0 && time.Since(started) >= s.config.LiveMaxDuration
+}
+
+// handleSessions serves GET /admin/sessions: the live sessions running.
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.liveSessions.list())
+}
+
+// handleSession serves /admin/sessions/{id}: GET reports one session, PATCH
+// changes its settings (see liveSettings) and reports it.
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	ls, ok := s.liveSessions.get(r.PathValue("id"))
+	if !ok {
+		sendError(w, "Unknown session", "invalid_request_error", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		var set liveSettings
+		if err := json.NewDecoder(r.Body).Decode(&set); err != nil && err != io.EOF {
+			sendError(w, "Invalid JSON body: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
+			return
+		}
+		if err := s.checkLiveSettings(set); err != nil {
+			sendRequestError(w, err)
+			return
+		}
+		ls.configure(set)
+	default:
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ls.info())
+}
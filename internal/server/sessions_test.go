@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestLiveSessionRegistry(t *testing.T) {
+	s := &Server{}
+	format, _ := asr.ParsePCMFormat("linear16", 16000, 1)
+	ls := s.newLiveSession(format, asr.TranscribeOptions{Language: "en"}, true, 500*time.Millisecond)
+	remove := s.liveSessions.add(ls, "deepgram", "10.0.0.7:51544")
+	if _, err := ls.push(t.Context(), make([]byte, 3200)); err != nil {
+		t.Fatal(err)
+	}
+
+	list := s.liveSessions.list()
+	if len(list) != 1 {
+		t.Fatalf("listed %d sessions, want 1", len(list))
+	}
+	got := list[0]
+	if got.ID != ls.id || got.Protocol != "deepgram" || got.Language != "en" || got.EndpointingMS != 500 || !got.Interim || got.AudioSeconds != 0.1 || got.LastAudio.IsZero() {
+		t.Errorf("session = %+v", got)
+	}
+
+	remove()
+	if n := len(s.liveSessions.list()); n != 0 {
+		t.Errorf("%d sessions after removal", n)
+	}
+}
+
+func TestHandleSessionPatch(t *testing.T) {
+	s := &Server{}
+	format, _ := asr.ParsePCMFormat("linear16", 16000, 1)
+	ls := s.newLiveSession(format, asr.TranscribeOptions{Language: "en"}, false, 500*time.Millisecond)
+	defer s.liveSessions.add(ls, "rtp", "10.0.0.9:4000")()
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, "/admin/sessions/"+id, strings.NewReader(body))
+		r.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		s.handleSession(w, r)
+		return w
+	}
+	if w := patch("nope", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown session: status %d", w.Code)
+	}
+	if w := patch(ls.id, `{"endpointing_ms": -1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("negative endpointing: status %d", w.Code)
+	}
+	if w := patch(ls.id, `{"language": "es", "endpointing_ms": 0}`); w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	// The settings wait for the session's own goroutine.
+	if ls.opts.Language != "en" {
+		t.Error("settings applied outside the session's goroutine")
+	}
+	ls.sync(false)
+	if ls.opts.Language != "es" || ls.endpointing != 0 {
+		t.Errorf("language %q, endpointing %v after sync", ls.opts.Language, ls.endpointing)
+	}
+	var info liveSessionInfo
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/admin/sessions/"+ls.id, nil)
+	r.SetPathValue("id", ls.id)
+	s.handleSession(w, r)
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil || info.Language != "es" || info.EndpointingMS != 0 {
+		t.Errorf("GET = %+v, %v", info, err)
+	}
+}
+
+func TestLiveReadDeadline(t *testing.T) {
+	started := time.Now()
+	s := &Server{}
+	if d := time.Until(s.liveReadDeadline(started, 10*time.Second)); d < 9*time.Second || d > 10*time.Second {
+		t.Errorf("default idle deadline in %v", d)
+	}
+	s.config.LiveIdleTimeout = time.Minute
+	s.config.LiveMaxDuration = 30 * time.Second
+	if got := s.liveReadDeadline(started, 10*time.Second); !got.Equal(started.Add(30 * time.Second)) {
+		t.Errorf("deadline %v, want the maximum duration", got.Sub(started))
+	}
+	if s.liveExpired(started) || !s.liveExpired(started.Add(-time.Minute)) {
+		t.Error("liveExpired disagrees with -live-max-duration")
+	}
+}
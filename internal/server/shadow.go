@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/selftest"
+)
+
+// shadowModels is the -shadow-models-dir model: a second model that
+// transcribes a sample of the requests again in the background, after the
+// client has its answer, so two models can be compared on real audio
+// without either one affecting the responses. It is nil when off.
+type shadowModels struct {
+	models *loadedModels
+	sample float64
+
+	// slots bounds the shadow decodes running at once to the workers of
+	// the shadow model; a sampled request finding them all busy is
+	// skipped rather than queued, so a backlog never builds up.
+	slots chan struct{}
+
+	// log appends one JSON line per comparison to -shadow-log; nil when
+	// it is not set.
+	mu  sync.Mutex
+	log io.WriteCloser
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// newShadowModels starts the shadow over models, writing comparisons to
+// logPath when it is not empty.
+func newShadowModels(models *loadedModels, sample float64, workers int, logPath string) (*shadowModels, error) {
+	sh := &shadowModels{models: models, sample: sample, slots: make(chan struct{}, max(workers, 1))}
+	if logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("open -shadow-log: %w", err)
+		}
+		sh.log = f
+	}
+	sh.ctx, sh.cancel = context.WithCancel(context.Background())
+	return sh, nil
+}
+
+// checkShadowSample accepts a sampled share of the requests above 0 and up
+// to 1 (every request).
+func checkShadowSample(v float64) error {
+	if v <= 0 || v > 1 {
+		return fmt.Errorf("%v is not above 0 and at most 1", v)
+	}
+	return nil
+}
+
+func (sh *shadowModels) name() string {
+	return sh.models.transcriber.Info().Name
+}
+
+// shadowComparison is one line of -shadow-log: the same audio transcribed
+// by the model that answered the client and by the shadow.
+type shadowComparison struct {
+	Time          time.Time `json:"time"`
+	RequestID     string    `json:"request_id,omitempty"`
+	AudioSeconds  float64   `json:"audio_seconds"`
+	Model         string    `json:"model"`
+	Text          string    `json:"text"`
+	Seconds       float64   `json:"seconds"`
+	ShadowModel   string    `json:"shadow_model"`
+	ShadowText    string    `json:"shadow_text"`
+	ShadowSeconds float64   `json:"shadow_seconds"`
+	Error         string    `json:"error,omitempty"`
+
+	// WER is the shadow's word error rate taking the answered transcript
+	// as the reference, split into its edits.
+	WER           float64 `json:"wer"`
+	Substitutions int     `json:"substitutions"`
+	Deletions     int     `json:"deletions"`
+	Insertions    int     `json:"insertions"`
+}
+
+// shadowTranscribe transcribes audio with the shadow model in the
+// background when the request is sampled, and compares the transcript with
+// res, which took elapsed on the model that answered. It returns at once.
+// Truncated transcripts are not compared.
+func (s *Server) shadowTranscribe(ctx context.Context, audio []byte, opts asr.TranscribeOptions, res *asr.Result, elapsed time.Duration) {
+	sh := s.shadow
+	if sh == nil || res.Truncated || rand.Float64() >= sh.sample {
+		return
+	}
+	select {
+	case sh.slots <- struct{}{}:
+	default:
+		s.stats.shadowSkipped()
+		slog.DebugContext(ctx, "shadow transcription skipped, shadow model busy")
+		return
+	}
+	cmp := shadowComparison{
+		Time:         time.Now(),
+		RequestID:    requestIDFrom(ctx),
+		AudioSeconds: res.Duration,
+		Model:        res.Model,
+		Text:         res.Text,
+		Seconds:      elapsed.Seconds(),
+		ShadowModel:  sh.name(),
+	}
+	// The shadow only runs on what is left over: no progress, no deltas,
+	// no time limit, and behind every request in the decoder queues.
+	opts.Progress = nil
+	opts.MaxProcessing = 0
+	opts.Priority = asr.PriorityBatch
+	sh.running.Add(1)
+	go func() {
+		defer sh.running.Done()
+		defer func() { <-sh.slots }()
+		start := time.Now()
+		shadowRes, err := sh.models.transcriber.TranscribeWithOptions(sh.ctx, audio, opts, nil)
+		cmp.ShadowSeconds = time.Since(start).Seconds()
+		if err != nil {
+			cmp.Error = err.Error()
+		} else {
+			s.profiles.apply(opts.Language, shadowRes)
+			cmp.ShadowText = shadowRes.Text
+			score := selftest.Compare(cmp.Text, cmp.ShadowText)
+			cmp.WER = score.WER()
+			cmp.Substitutions, cmp.Deletions, cmp.Insertions = score.Substitutions, score.Deletions, score.Insertions
+		}
+		s.stats.shadowCompared(cmp)
+		sh.write(cmp)
+		slog.InfoContext(ctx, "shadow transcription compared", "model", cmp.Model, "shadow_model", cmp.ShadowModel,
+			"wer", cmp.WER, "seconds", cmp.Seconds, "shadow_seconds", cmp.ShadowSeconds, "error", cmp.Error)
+	}()
+}
+
+// write appends cmp to -shadow-log.
+func (sh *shadowModels) write(cmp shadowComparison) {
+	if sh.log == nil {
+		return
+	}
+	line, _ := json.Marshal(cmp)
+	line = append(line, '\n')
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.log.Write(line)
+}
+
+// close cancels the shadow decodes still running, waits for them and
+// unloads the shadow model.
+func (sh *shadowModels) close() {
+	if sh == nil {
+		return
+	}
+	sh.cancel()
+	sh.running.Wait()
+	sh.models.close()
+	if sh.log != nil {
+		sh.log.Close()
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestCheckShadowSample(t *testing.T) {
+	for _, v := range []float64{0.01, 1} {
+		if err := checkShadowSample(v); err != nil {
+			t.Errorf("%v: %v", v, err)
+		}
+	}
+	for _, v := range []float64{0, -1, 1.5} {
+		if err := checkShadowSample(v); err == nil {
+			t.Errorf("%v: want error", v)
+		}
+	}
+}
+
+func TestStats_ShadowComparisons(t *testing.T) {
+	st := newServerStats()
+	st.shadowCompared(shadowComparison{WER: 0, Seconds: 1, ShadowSeconds: 2})
+	st.shadowCompared(shadowComparison{WER: 0.5, Substitutions: 1, Seconds: 1, ShadowSeconds: 2})
+	st.shadowCompared(shadowComparison{Error: "boom"})
+	st.shadowSkipped()
+	want := ShadowStats{Compared: 2, Errors: 1, Skipped: 1, MeanWER: 0.25, Identical: 1, Seconds: 2, ShadowSeconds: 4}
+	if st.shadow != want {
+		t.Errorf("shadow = %+v, want %+v", st.shadow, want)
+	}
+}
+
+func TestShadowTranscribe_LogsComparison(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "shadow.jsonl")
+	sh, err := newShadowModels(&loadedModels{transcriber: &asr.Transcriber{}}, 1, 1, logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{stats: newServerStats(), shadow: sh}
+	// The shadow fails on audio it cannot decode; the comparison is still
+	// logged, with the error.
+	s.shadowTranscribe(context.WithValue(context.Background(), requestIDKey{}, "req-1"), []byte("not audio"), asr.TranscribeOptions{},
+		&asr.Result{Model: "v2", Text: "hello world", Duration: 1}, time.Second)
+	sh.running.Wait()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cmp shadowComparison
+	if err := json.Unmarshal(data, &cmp); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	if cmp.RequestID != "req-1" || cmp.Model != "v2" || cmp.Text != "hello world" || cmp.ShadowModel != asr.DefaultModelName || cmp.Error == "" {
+		t.Errorf("comparison = %+v", cmp)
+	}
+	if s.stats.shadow.Errors != 1 {
+		t.Errorf("shadow stats = %+v", s.stats.shadow)
+	}
+
+	// Truncated transcripts are never compared.
+	s.shadowTranscribe(context.Background(), nil, asr.TranscribeOptions{}, &asr.Result{Truncated: true}, time.Second)
+	sh.running.Wait()
+	if s.stats.shadow.Errors != 1 || s.stats.shadow.Compared != 0 {
+		t.Errorf("shadow stats = %+v", s.stats.shadow)
+	}
+}
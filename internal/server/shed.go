@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// shedWindow is how far back the p99 latency looks. Requests shed
+	// meanwhile add no samples, so the window empties and traffic is let
+	// through again once it passes.
+	shedWindow = 30 * time.Second
+	// shedMinSamples keeps a handful of slow requests on an idle server
+	// from tripping the budget.
+	shedMinSamples = 20
+	// shedRetryAfter is the Retry-After sent with a shed request.
+	shedRetryAfter = 5 * time.Second
+)
+
+// latencyTracker keeps the latencies of the requests that finished within
+// shedWindow.
+type latencyTracker struct {
+	mu      sync.Mutex
+	samples []latencySample // oldest first
+}
+
+type latencySample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+func (lt *latencyTracker) add(now time.Time, latency time.Duration) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.expire(now)
+	lt.samples = append(lt.samples, latencySample{at: now, latency: latency})
+}
+
+// p99 returns the 99th percentile latency within the window, and false when
+// it has fewer than shedMinSamples requests.
+func (lt *latencyTracker) p99(now time.Time) (time.Duration, bool) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.expire(now)
+	if len(lt.samples) < shedMinSamples {
+		return 0, false
+	}
+	latencies := make([]time.Duration, len(lt.samples))
+	for i, s := range lt.samples {
+		latencies[i] = s.latency
+	}
+	slices.Sort(latencies)
+	return latencies[int(math.Ceil(0.99*float64(len(latencies))))-1], true
+}
+
+func (lt *latencyTracker) expire(now time.Time) {
+	i := 0
+	for i < len(lt.samples) && now.Sub(lt.samples[i].at) > shedWindow {
+		i++
+	}
+	lt.samples = lt.samples[i:]
+}
+
+// shedLoad answers 503 with Retry-After while the p99 latency of the
+// buffered transcription routes exceeds -shed-latency-budget, instead of
+// queueing more work behind requests that are already late. WebSocket
+// sessions are neither shed nor measured: their length is the call's.
+func (s *Server) shedLoad(next http.Handler) http.Handler {
+	budget := s.config.ShedLatencyBudget
+	if budget <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || isWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if p99, ok := s.latency.p99(time.Now()); ok && p99 > budget {
+			s.stats.shed()
+			sendOverloaded(w, "The server is overloaded (p99 latency "+p99.Round(time.Millisecond).String()+" over the "+budget.String()+" budget), retry later")
+			return
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status < 400 {
+			s.latency.add(time.Now(), time.Since(start))
+		}
+	})
+}
+
+// sendOverloaded answers 503 with Retry-After, for requests refused because
+// the server is too busy to serve them in time.
+func sendOverloaded(w http.ResponseWriter, message string) {
+	setRetryAfter(w)
+	sendError(w, message, "server_error", http.StatusServiceUnavailable)
+}
+
+func setRetryAfter(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(shedRetryAfter.Seconds())))
+}
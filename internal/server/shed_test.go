@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	var lt latencyTracker
+	now := time.Now()
+	for i := 1; i < shedMinSamples; i++ {
+		lt.add(now, time.Duration(i)*time.Millisecond)
+	}
+	if _, ok := lt.p99(now); ok {
+		t.Fatal("p99 reported below shedMinSamples")
+	}
+	for i := 0; i < 100-shedMinSamples; i++ {
+		lt.add(now, time.Millisecond)
+	}
+	lt.add(now, time.Second)
+	if p99, ok := lt.p99(now); !ok || p99 != 19*time.Millisecond {
+		t.Errorf("p99 = %v, %t; want 19ms", p99, ok)
+	}
+	if _, ok := lt.p99(now.Add(shedWindow + time.Second)); ok {
+		t.Error("samples older than the window still counted")
+	}
+}
+
+func TestShedLoad(t *testing.T) {
+	s := &Server{config: Config{ShedLatencyBudget: 100 * time.Millisecond}, stats: newServerStats()}
+	h := s.shedLoad(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if rec := serve(httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("under budget: status %d", rec.Code)
+	}
+
+	now := time.Now()
+	for i := 0; i < shedMinSamples; i++ {
+		s.latency.add(now, time.Second)
+	}
+	rec := serve(httptest.NewRequest("POST", "/v1/audio/transcriptions", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "5" {
+		t.Fatalf("over budget: status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if e := decodeError(t, rec); string(e["type"]) != `"server_error"` {
+		t.Errorf("error type = %s", e["type"])
+	}
+	if got := s.stats.shedRequests; got != 1 {
+		t.Errorf("shed_requests = %d, want 1", got)
+	}
+
+	// Live sessions are not shed.
+	ws := httptest.NewRequest("GET", "/v1/listen", nil)
+	ws.Header.Set("Connection", "Upgrade")
+	ws.Header.Set("Upgrade", "websocket")
+	if rec := serve(ws); rec.Code != http.StatusOK {
+		t.Errorf("WebSocket upgrade: status %d", rec.Code)
+	}
+
+	// Off without a budget.
+	s.config.ShedLatencyBudget = 0
+	h = s.shedLoad(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if rec := serve(httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)); rec.Code != http.StatusOK {
+		t.Errorf("no budget: status %d", rec.Code)
+	}
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"io"
+	"os"
+)
+
+// spool holds audio being received: in memory up to its threshold, and past
+// it in a temp file, so that large uploads kept around (AssemblyAI uploads,
+// resumable uploads) do not sit in RAM. A threshold of zero or less keeps
+// everything in memory. Close removes the temp file; a spool is not safe for
+// concurrent use.
+type spool struct {
+	threshold int64
+	buf       []byte
+	file      *os.File
+	size      int64
+}
+
+func newSpool(threshold int64) *spool {
+	return &spool{threshold: threshold}
+}
+
+// spoolBytes is the -upload-spool-mb threshold.
+func (s *Server) spoolBytes() int64 {
+	return int64(s.config.UploadSpoolMB) << 20
+}
+
+// multipartMemory is how much of a multipart body ParseMultipartForm may
+// keep in memory before it writes the files to disk.
+func (s *Server) multipartMemory() int64 {
+	if n := s.spoolBytes(); n > 0 {
+		return n
+	}
+	return maxUploadBytes
+}
+
+// Write appends p, moving what was received to a temp file once it grows
+// past the threshold.
+func (sp *spool) Write(p []byte) (int, error) {
+	if sp.file == nil && sp.threshold > 0 && sp.size+int64(len(p)) > sp.threshold {
+		f, err := os.CreateTemp("", "parakeet-upload-*")
+		if err != nil {
+			return 0, err
+		}
+		if _, err := f.Write(sp.buf); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return 0, err
+		}
+		sp.file, sp.buf = f, nil
+	}
+	if sp.file == nil {
+		sp.buf = append(sp.buf, p...)
+		sp.size += int64(len(p))
+		return len(p), nil
+	}
+	n, err := sp.file.Write(p)
+	sp.size += int64(n)
+	return n, err
+}
+
+// Len is the number of bytes received.
+func (sp *spool) Len() int64 {
+	return sp.size
+}
+
+// Bytes returns everything received, read back from disk if spooled.
+func (sp *spool) Bytes() ([]byte, error) {
+	if sp.file == nil {
+		return sp.buf, nil
+	}
+	data := make([]byte, sp.size)
+	_, err := io.ReadFull(io.NewSectionReader(sp.file, 0, sp.size), data)
+	return data, err
+}
+
+// Truncate drops what was received past the first n bytes.
+func (sp *spool) Truncate(n int64) error {
+	if n >= sp.size {
+		return nil
+	}
+	if sp.file == nil {
+		sp.buf = sp.buf[:n]
+	} else {
+		if err := sp.file.Truncate(n); err != nil {
+			return err
+		}
+		if _, err := sp.file.Seek(n, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	sp.size = n
+	return nil
+}
+
+// Close releases the spool, removing its temp file.
+func (sp *spool) Close() error {
+	sp.buf = nil
+	if sp.file == nil {
+		return nil
+	}
+	f := sp.file
+	sp.file = nil
+	f.Close()
+	return os.Remove(f.Name())
+}
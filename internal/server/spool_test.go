@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSpool(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	sp := newSpool(4)
+	sp.Write([]byte("abc"))
+	if sp.file != nil {
+		t.Fatal("spooled below the threshold")
+	}
+	sp.Write([]byte("defgh"))
+	if sp.file == nil || sp.buf != nil {
+		t.Fatal("not spooled past the threshold")
+	}
+	if data, err := sp.Bytes(); string(data) != "abcdefgh" || err != nil || sp.Len() != 8 {
+		t.Errorf("Bytes = %q, %v; Len = %d", data, err, sp.Len())
+	}
+	sp.Truncate(5)
+	sp.Write([]byte("X"))
+	if data, _ := sp.Bytes(); string(data) != "abcdeX" {
+		t.Errorf("after Truncate: %q", data)
+	}
+
+	name := sp.file.Name()
+	sp.Close()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("temp file left after Close: %v", err)
+	}
+
+	mem := newSpool(0)
+	mem.Write(make([]byte, 1<<20))
+	if mem.file != nil || mem.Len() != 1<<20 {
+		t.Error("a zero threshold spooled to disk")
+	}
+}
+
+func TestUploadStoreSpooled(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	u := newUploadStore()
+	sp := newSpool(2)
+	sp.Write([]byte("audio"))
+	id, err := u.put(sp, "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := u.get(id, "search"); !ok || string(data) != "audio" {
+		t.Errorf("get = %q, %v", data, ok)
+	}
+	if _, ok := u.get(id, "support"); ok {
+		t.Error("another owner got the upload")
+	}
+	u.close()
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("%d temp files left after close", len(entries))
+	}
+}
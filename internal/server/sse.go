@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+// sseWriteDeadline bounds how long a single event may take to reach the
+// client.
+const sseWriteDeadline = 30 * time.Second
+
+// sseStream writes Server-Sent Events to one response.
+//
+// ResponseController lets us set a per-write deadline. This is what makes
+// a slow/stalled reader recoverable: if the client stops draining its TCP
+// receive window, the write fails instead of blocking forever inside the
+// decoder goroutine (which holds a worker). On failure the stream's context
+// is cancelled so the decoder stops and releases its worker. We deliberately
+// do NOT use a global http.Server WriteTimeout, which would kill healthy long
+// streams; the deadline is reset before every event instead.
+type sseStream struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	cancel context.CancelFunc
+}
+
+// startSSE sends the SSE response headers and returns the stream with a
+// context derived from the request's, cancelled as soon as a write to the
+// client fails. Response headers set by the caller (X-Cache) must be set
+// before. The caller must call cancel when done.
+func startSSE(w http.ResponseWriter, r *http.Request) (*sseStream, context.Context, context.CancelFunc) {
+	// SSE headers must be set before the first write / WriteHeader.
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Disable proxy buffering (nginx) so events reach the client immediately.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	// Derive a cancelable context: if a write to the client fails (disconnect,
+	// broken pipe, stalled reader past the deadline), we cancel so the decoder
+	// stops promptly and releases its worker instead of computing into the void.
+	ctx, cancel := context.WithCancel(r.Context())
+	return &sseStream{w: w, rc: http.NewResponseController(w), cancel: cancel}, ctx, cancel
+}
+
+// send serializes one SSE frame: "event: <type>\ndata: <json>\n\n". Each
+// event is marshaled independently so a mid-write failure can never corrupt
+// a subsequent frame. Returns false on write failure.
+func (e *sseStream) send(eventType string, v interface{}) bool {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	// A reader that has stalled will trip this deadline and the write fails,
+	// freeing the worker (slow-reader DoS mitigation).
+	_ = e.rc.SetWriteDeadline(time.Now().Add(sseWriteDeadline))
+	if _, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", eventType, payload); err != nil {
+		e.cancel()
+		return false
+	}
+	if err := e.rc.Flush(); err != nil {
+		e.cancel()
+		return false
+	}
+	return true
+}
+
+// sendError reports a transcription failure as a terminal "error" event.
+// Headers (200 OK) are already sent, so we cannot switch to an HTTP error
+// status. Client cancellation needs no payload (nobody is listening).
+func (e *sseStream) sendError(err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	msg := "Transcription failed: " + err.Error()
+	errType := "server_error"
+	if errors.Is(err, asr.ErrUnsupportedAudio) {
+		msg = "Unsupported or malformed audio: " + err.Error()
+		errType = "invalid_request_error"
+	} else if errors.Is(err, asr.ErrOverMemoryBudget) {
+		msg = "Audio too long for this server's memory: " + err.Error()
+		errType = "invalid_request_error"
+	} else if errors.Is(err, asr.ErrDenoiseUnavailable) {
+		msg = err.Error()
+		errType = "invalid_request_error"
+	}
+	e.send("error", ErrorResponse{Error: ErrorDetail{Message: msg, Type: errType}})
+}
+
+// wantsEventStream reports whether the client asked for an SSE response with
+// its Accept header.
+func wantsEventStream(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(v, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "text/event-stream") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// progressTranscription transcribes audioData and reports its progress as
+// Server-Sent Events, for clients that send Accept: text/event-stream without
+// stream=true: transcript.progress events with the percentage of audio
+// decoded and the text so far, then one transcript.result event carrying the
+// response in the requested format. Progress is sent each time the
+// percentage grows by at least one point. The result's times are moved by
+// offset seconds (offset_seconds).
+func (s *Server) progressTranscription(w http.ResponseWriter, r *http.Request, audioData []byte, opts asr.TranscribeOptions, responseFormat, language string, layout cueLayout, offset float64) {
+	if _, ok := w.(http.Flusher); !ok {
+		// The ResponseWriter cannot stream; answer as if no SSE was asked for.
+		result, cached, err := s.transcribe(r.Context(), audioData, opts)
+		if err != nil {
+			s.writeTranscribeError(w, err)
+			return
+		}
+		s.setCacheHeader(w, cached)
+		writeTranscription(w, result.Shifted(offset), responseFormat, language, layout)
+		return
+	}
+
+	var key string
+	var cached *asr.Result
+	if s.cache != nil {
+		key = s.cacheKey(audioData, opts)
+		cached, _ = s.cache.Get(key)
+	}
+	s.setCacheHeader(w, cached != nil)
+
+	stream, ctx, cancel := startSSE(w, r)
+	defer cancel()
+
+	sendResult := func(result *asr.Result) {
+		_, body := renderTranscription(result.Shifted(offset), responseFormat, language, layout)
+		stream.send("transcript.result", StreamResultEvent{
+			Type:           "transcript.result",
+			ResponseFormat: responseFormat,
+			Result:         body,
+		})
+	}
+
+	if cached != nil {
+		s.stats.cacheHit()
+		noteAudio(r.Context(), cached.Duration)
+		s.recordTranscript(r.Context(), audioData, opts, cached, true, 0)
+		stream.send("transcript.progress", StreamProgressEvent{
+			Type:             "transcript.progress",
+			Percent:          100,
+			ProcessedSeconds: cached.Duration,
+			TotalSeconds:     cached.Duration,
+			Text:             cached.Text,
+		})
+		sendResult(cached)
+		return
+	}
+
+	// Per-channel results are merged after decoding, so there is no partial
+	// text to show; progress is still reported.
+	var partial strings.Builder
+	var emit func(delta string)
+	if opts.Channels != asr.ChannelPerChannel {
+		emit = func(delta string) { partial.WriteString(delta) }
+	}
+	lastPercent := -1
+	opts.Progress = func(processed, total float64) {
+		if total <= 0 {
+			return
+		}
+		percent := int(math.Floor(100 * processed / total))
+		if percent <= lastPercent {
+			return
+		}
+		lastPercent = percent
+		stream.send("transcript.progress", StreamProgressEvent{
+			Type:             "transcript.progress",
+			Percent:          percent,
+			ProcessedSeconds: processed,
+			TotalSeconds:     total,
+			Text:             strings.Join(strings.Fields(partial.String()), " "),
+		})
+	}
+
+	start := time.Now()
+	result, err := s.transcribeAudio(ctx, audioData, opts, emit)
+	if err != nil {
+		stream.sendError(err)
+		return
+	}
+	s.stats.decode(result.Duration, time.Since(start))
+	if s.cache != nil && !result.Truncated && !s.canary.decoded(result) {
+		s.cache.Put(key, result)
+	}
+	noteAudio(r.Context(), result.Duration)
+	s.recordTranscript(r.Context(), audioData, opts, result, false, time.Since(start))
+	sendResult(result)
+}
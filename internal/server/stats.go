@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+const adminKeyEnvVar = "PARAKEET_ADMIN_KEY"
+
+// serverStats are the runtime counters behind /admin/stats. They are kept in
+// process and reset on restart; they are for operators without a metrics
+// stack, not a replacement for one.
+type serverStats struct {
+	started time.Time
+
+	mu              sync.Mutex
+	requests        int64
+	clientErrors    int64 // 4xx responses
+	serverErrors    int64 // 5xx responses
+	decodes         int64
+	audioSeconds    float64
+	decodeSeconds   float64
+	modelRequests   map[string]int64
+	modelDecodes    map[string]*ModelDecodes
+	cacheHits       int64
+	sharedInflights int64
+	shedRequests    int64
+	shadow          ShadowStats
+}
+
+func newServerStats() *serverStats {
+	return &serverStats{
+		started:       time.Now(),
+		modelRequests: make(map[string]int64),
+		modelDecodes:  make(map[string]*ModelDecodes),
+	}
+}
+
+// request records one finished API request with its response status.
+func (st *serverStats) request(model string, status int) {
+	if model == "" {
+		model = "default"
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.requests++
+	st.modelRequests[model]++
+	switch {
+	case status >= 500:
+		st.serverErrors++
+	case status >= 400:
+		st.clientErrors++
+	}
+}
+
+// decode records one transcription that actually ran the model.
+func (st *serverStats) decode(audioSeconds float64, elapsed time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.decodes++
+	st.audioSeconds += audioSeconds
+	st.decodeSeconds += elapsed.Seconds()
+}
+
+// modelDecode records one run of the named model, which produced res or
+// failed with err. Cancelled requests are not counted as errors.
+func (st *serverStats) modelDecode(model string, res *asr.Result, elapsed time.Duration, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m := st.modelDecodes[model]
+	if m == nil {
+		m = &ModelDecodes{Model: model}
+		st.modelDecodes[model] = m
+	}
+	switch {
+	case err == nil:
+		m.Decodes++
+		m.AudioSeconds += res.Duration
+		m.DecodeSeconds += elapsed.Seconds()
+	case !errors.Is(err, context.Canceled):
+		m.Errors++
+	}
+}
+
+// shadowCompared records one shadow transcription; shadowSkipped one
+// sampled request the busy shadow model left out.
+func (st *serverStats) shadowCompared(cmp shadowComparison) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if cmp.Error != "" {
+		st.shadow.Errors++
+		return
+	}
+	st.shadow.Compared++
+	st.shadow.MeanWER += (cmp.WER - st.shadow.MeanWER) / float64(st.shadow.Compared)
+	if cmp.Substitutions+cmp.Deletions+cmp.Insertions == 0 {
+		st.shadow.Identical++
+	}
+	st.shadow.Seconds += cmp.Seconds
+	st.shadow.ShadowSeconds += cmp.ShadowSeconds
+}
+
+func (st *serverStats) shadowSkipped() {
+	st.mu.Lock()
+	st.shadow.Skipped++
+	st.mu.Unlock()
+}
+
+// cacheHit and sharedInflight count requests answered without a decode of
+// their own.
+func (st *serverStats) cacheHit() {
+	st.mu.Lock()
+	st.cacheHits++
+	st.mu.Unlock()
+}
+
+func (st *serverStats) sharedInflight() {
+	st.mu.Lock()
+	st.sharedInflights++
+	st.mu.Unlock()
+}
+
+// shed counts a request refused by shedLoad.
+func (st *serverStats) shed() {
+	st.mu.Lock()
+	st.shedRequests++
+	st.mu.Unlock()
+}
+
+// statusRecorder captures the status code and body size written by a
+// handler. It forwards Flush and exposes Unwrap so SSE responses and
+// http.ResponseController keep working through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// countRequests wraps an API handler so every request is counted by model
+// and outcome. The model is read after the handler ran, when the multipart
+// form (or the query string of the raw endpoint) has been parsed.
+func (s *Server) countRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		model := r.URL.Query().Get("model")
+		if r.MultipartForm != nil {
+			if v := r.MultipartForm.Value["model"]; len(v) > 0 {
+				model = v[0]
+			}
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.stats.request(model, status)
+	})
+}
+
+// requireAdmin guards the admin endpoints with PARAKEET_ADMIN_KEY. Without
+// one, a lone PARAKEET_API_KEY stands in for it, and with no authentication
+// at all the endpoints are open like the rest of the API. Named keys and
+// OIDC tokens are tenants, never admins, except a JWT carrying
+// -oidc-admin-claim: with -api-keys-file or -oidc-issuer and neither,
+// /admin/* answers 401 to everyone.
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminKey == "" && !s.authEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if auth == "" || !s.isAdmin(token) {
+			sendError(w, "Invalid admin key", "authentication_error", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleStats serves /admin/stats.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.statsSnapshot())
+}
+
+// statsSnapshot collects the counters and the decoder pool occupancy. It is
+// also published as the "parakeet" expvar on the debug listener.
+func (s *Server) statsSnapshot() StatsResponse {
+	pool := s.transcriber().PoolStatus()
+
+	st := s.stats
+	st.mu.Lock()
+	resp := StatsResponse{
+		UptimeSeconds:   time.Since(st.started).Seconds(),
+		Requests:        st.requests,
+		ClientErrors:    st.clientErrors,
+		ServerErrors:    st.serverErrors,
+		Decodes:         st.decodes,
+		CacheHits:       st.cacheHits,
+		SharedInflights: st.sharedInflights,
+		ShedRequests:    st.shedRequests,
+		AudioSeconds:    st.audioSeconds,
+		DecodeSeconds:   st.decodeSeconds,
+		Workers:         pool.Workers,
+		BusyWorkers:     pool.Busy,
+		QueueDepth:      pool.Waiting,
+		Models:          make([]ModelUsage, 0, len(st.modelRequests)),
+		ModelDecodes:    make([]ModelDecodes, 0, len(st.modelDecodes)),
+	}
+	if len(pool.WaitingByPriority) > 0 {
+		resp.QueueByPriority = make(map[string]int, len(pool.WaitingByPriority))
+		for p, n := range pool.WaitingByPriority {
+			resp.QueueByPriority[string(p)] = n
+		}
+	}
+	if pool.BatchRuns > 0 {
+		resp.DecoderBatching = &BatchStats{
+			Runs:        pool.BatchRuns,
+			Steps:       pool.BatchSteps,
+			AverageSize: float64(pool.BatchSteps) / float64(pool.BatchRuns),
+		}
+	}
+	if st.audioSeconds > 0 {
+		resp.AverageRTF = st.decodeSeconds / st.audioSeconds
+	}
+	for model, n := range st.modelRequests {
+		resp.Models = append(resp.Models, ModelUsage{Model: model, Requests: n})
+	}
+	if budget := s.modelOptions.MemoryBudget; budget != nil {
+		mem := budget.Status()
+		resp.Memory = &MemoryStats{Budget: mem.Total, Weights: mem.Weights, Reserved: mem.Reserved, Waiting: mem.Waiting}
+	}
+	if s.shadow != nil {
+		shadow := st.shadow
+		shadow.Model = s.shadow.name()
+		resp.Shadow = &shadow
+	}
+	for _, m := range st.modelDecodes {
+		md := *m
+		if md.AudioSeconds > 0 {
+			md.AverageRTF = md.DecodeSeconds / md.AudioSeconds
+		}
+		md.Canary = s.canary != nil && md.Model == s.canary.name()
+		resp.ModelDecodes = append(resp.ModelDecodes, md)
+	}
+	st.mu.Unlock()
+
+	sort.Slice(resp.Models, func(i, j int) bool { return resp.Models[i].Model < resp.Models[j].Model })
+	sort.Slice(resp.ModelDecodes, func(i, j int) bool { return resp.ModelDecodes[i].Model < resp.ModelDecodes[j].Model })
+	return resp
+}
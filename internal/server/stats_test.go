@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+func TestStats_CountsRequestsAndDecodes(t *testing.T) {
+	s := &Server{stats: newServerStats()}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+
+	respond := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+			}
+			w.Write([]byte("{}"))
+		}
+	}
+	for _, tc := range []struct {
+		query  string
+		status int
+	}{
+		{"?model=whisper-1", http.StatusOK},
+		{"?model=whisper-1", http.StatusBadRequest},
+		{"", http.StatusInternalServerError},
+	} {
+		s.countRequests(respond(tc.status)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/audio/transcriptions"+tc.query, nil))
+	}
+	s.stats.decode(60, 3*time.Second)
+	s.stats.cacheHit()
+
+	rec := httptest.NewRecorder()
+	s.handleStats(rec, httptest.NewRequest("GET", "/admin/stats", nil))
+	var got StatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Requests != 3 || got.ClientErrors != 1 || got.ServerErrors != 1 {
+		t.Errorf("requests/errors = %d/%d/%d, want 3/1/1", got.Requests, got.ClientErrors, got.ServerErrors)
+	}
+	if got.Decodes != 1 || got.AudioSeconds != 60 || got.AverageRTF != 0.05 || got.CacheHits != 1 {
+		t.Errorf("decode stats = %+v", got)
+	}
+	want := []ModelUsage{{"default", 1}, {"whisper-1", 2}}
+	if len(got.Models) != len(want) || got.Models[0] != want[0] || got.Models[1] != want[1] {
+		t.Errorf("models = %+v, want %+v", got.Models, want)
+	}
+}
+
+func TestStatusRecorder_KeepsFlusher(t *testing.T) {
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	if _, ok := interface{}(rec).(http.Flusher); !ok {
+		t.Fatal("statusRecorder must implement http.Flusher for SSE responses")
+	}
+	if err := http.NewResponseController(rec).Flush(); err != nil {
+		t.Fatalf("ResponseController.Flush: %v", err)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	call := func(s *Server, token string) int {
+		req := httptest.NewRequest("GET", "/admin/stats", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.requireAdmin(ok).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if got := call(&Server{}, ""); got != http.StatusNoContent {
+		t.Errorf("no keys configured: status %d, want open", got)
+	}
+	apiOnly := &Server{apiKey: "api"}
+	if call(apiOnly, "api") != http.StatusNoContent || call(apiOnly, "") != http.StatusUnauthorized {
+		t.Error("API key must guard admin endpoints when no admin key is set")
+	}
+	both := &Server{apiKey: "api", adminKey: "admin"}
+	if call(both, "admin") != http.StatusNoContent || call(both, "api") != http.StatusUnauthorized {
+		t.Error("admin key must take precedence over the API key")
+	}
+	named := &Server{apiKey: "api", apiKeys: map[string]string{"sk-team": "team"}}
+	if call(named, "sk-team") != http.StatusUnauthorized || call(named, "api") != http.StatusUnauthorized {
+		t.Error("with named keys and no admin key, /admin/* must be closed")
+	}
+	named.adminKey = "admin"
+	if call(named, "admin") != http.StatusNoContent || call(named, "sk-team") != http.StatusUnauthorized {
+		t.Error("a named key must not be an admin")
+	}
+}
@@ -9,15 +9,31 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"strings"
 
 	"parakeet/internal/asr"
 )
 
+// rawAudioContentType reports whether a raw-body transcription request may
+// carry contentType: audio, video, octet-stream or none at all. Anything else
+// (JSON, form-encoded, text) is a client mistake answered with 415.
+func rawAudioContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/") || mediaType == "application/octet-stream"
+}
+
 // handleStreamingTranscription accepts a request whose body is the raw audio
 // bytes (non-multipart), e.g. Content-Type: audio/wav or a chunked upload.
-// It buffers the body (capped at 25MB) and returns a single JSON transcript.
+// It buffers the body (capped at 25MB) and returns a single JSON transcript,
+// or progress events when the client sends Accept: text/event-stream.
 // For an SSE delta stream, clients send a multipart request with stream=true
 // (handled by streamTranscription in handlers.go).
 func (s *Server) handleStreamingTranscription(w http.ResponseWriter, r *http.Request) {
@@ -34,7 +50,12 @@ func (s *Server) handleStreamingTranscription(w http.ResponseWriter, r *http.Req
 	}
 
 	// 1. Prevent infinite buffer DOS
-	r.Body = http.MaxBytesReader(w, r.Body, 25<<20)
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+
+	if !rawAudioContentType(r.Header.Get("Content-Type")) {
+		sendUnsupportedMediaType(w, "Unsupported Content-Type "+r.Header.Get("Content-Type")+": send multipart/form-data or the raw audio (audio/*, video/*, application/octet-stream)")
+		return
+	}
 
 	// Determine format
 	format := r.URL.Query().Get("format")
@@ -59,43 +80,94 @@ func (s *Server) handleStreamingTranscription(w http.ResponseWriter, r *http.Req
 		format = "." + format
 	}
 
-	language := r.URL.Query().Get("language")
-	if language == "" {
-		language = "en" // default
+	language, err := s.languageFor(r.URL.Query().Get("language"))
+	if err != nil {
+		sendRequestError(w, err)
+		return
+	}
+
+	channelMode, err := asr.ParseChannelMode(r.URL.Query().Get("channel_mode"))
+	if err != nil {
+		sendRequestError(w, withParam("channel_mode", err))
+		return
+	}
+	conditioning, err := s.conditioningFor(r.URL.Query().Get)
+	if err != nil {
+		sendRequestError(w, err)
+		return
+	}
+	denoise := s.config.Denoise
+	if v := r.URL.Query().Get("denoise"); v != "" {
+		denoise = parseBool(v)
+	}
+	priority, err := asr.ParsePriority(r.URL.Query().Get("priority"))
+	if err != nil {
+		sendRequestError(w, withParam("priority", err))
+		return
+	}
+	maxProcessing, err := s.maxProcessingFor(r.URL.Query().Get)
+	if err != nil {
+		sendRequestError(w, err)
+		return
 	}
 
 	// Accumulate chunks
 	audioData, err := io.ReadAll(r.Body)
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendBodyError(w, err)
+			return
+		}
 		sendError(w, "Error reading stream: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
 		return
 	}
 
-	slog.Info("transcribing streaming audio",
+	slog.InfoContext(r.Context(), "transcribing streaming audio",
 		"bytes", len(audioData),
 		"language", language,
 		"format", format,
+		"channel_mode", channelMode,
 	)
 
+	opts := asr.TranscribeOptions{
+		Format:            format,
+		Language:          language,
+		Channels:          channelMode,
+		Conditioning:      conditioning,
+		Denoise:           denoise,
+		Priority:          priority,
+		MaxProcessing:     maxProcessing,
+		NoSpeechThreshold: s.config.NoSpeechThreshold,
+		Guard:             s.guard,
+		Confidence:        s.confidence,
+		Formatting:        s.formatting,
+		Fallback:          s.fallback,
+	}
+	if wantsEventStream(r) {
+		s.progressTranscription(w, r, audioData, opts, "json", language, cueLayout{}, 0)
+		return
+	}
+
 	// 2 & 4. Goroutine leak and deadlock avoided by passing context down to Transcribe
-	text, err := s.transcriber.Transcribe(r.Context(), audioData, format, language)
+	result, cached, err := s.transcribe(r.Context(), audioData, opts)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return // Context cancelled, ignore
 		}
-		if errors.Is(err, asr.ErrUnsupportedAudio) {
-			sendError(w, "Unsupported or malformed audio: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
-			return
-		}
-		sendError(w, "Transcription failed: "+err.Error(), "server_error", http.StatusInternalServerError)
+		s.writeTranscribeError(w, err)
 		return
 	}
 
+	text := result.Text
 	if asr.DebugMode {
-		slog.Debug("transcription result", "text", text)
+		slog.DebugContext(r.Context(), "transcription result", "text", text, "cached", cached)
 	}
+	s.setCacheHeader(w, cached)
+	setTruncatedHeader(w, result)
+	setSuspectHeader(w, result)
 
 	// 3. JSON Injection fixed by using proper encoding
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(TranscriptionResponse{Text: text})
+	json.NewEncoder(w).Encode(TranscriptionResponse{Text: text, Truncated: result.Truncated})
 }
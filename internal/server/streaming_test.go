@@ -11,6 +11,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"parakeet/internal/asr"
 )
 
 func TestStreamParseBool(t *testing.T) {
@@ -153,3 +155,65 @@ func TestStreamSSEParserHelper(t *testing.T) {
 		t.Errorf("expected concatenated text 'Hello world!', got %q", fullText)
 	}
 }
+
+func TestWantsEventStream(t *testing.T) {
+	for _, tc := range []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"text/event-stream", true},
+		{"application/json, Text/Event-Stream;q=0.9", true},
+	} {
+		r := httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept", tc.accept)
+		}
+		if got := wantsEventStream(r); got != tc.want {
+			t.Errorf("wantsEventStream(%q) = %v, want %v", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestProgressTranscription_CachedResult(t *testing.T) {
+	s := &Server{cache: newMemoryCache(1), stats: newServerStats()}
+	audio := []byte("audio")
+	opts := asr.TranscribeOptions{Language: "en"}
+	s.cache.Put(s.cacheKey(audio, opts), &asr.Result{
+		Text:     "hello world",
+		Duration: 2,
+		Channels: 1,
+		Segments: []asr.Segment{{Start: 0, End: 2, Text: "hello world"}},
+	})
+
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)
+	s.progressTranscription(rec, r, audio, opts, "srt", "en", cueLayout{}, 0)
+
+	events := parseSSEEvents(t, rec.Body.String())
+	if len(events) != 2 || events[0].Event != "transcript.progress" || events[1].Event != "transcript.result" {
+		t.Fatalf("events = %+v", events)
+	}
+	var progress StreamProgressEvent
+	if err := json.Unmarshal([]byte(events[0].Data), &progress); err != nil {
+		t.Fatal(err)
+	}
+	if progress.Percent != 100 || progress.Text != "hello world" {
+		t.Errorf("progress = %+v", progress)
+	}
+	var result struct {
+		ResponseFormat string `json:"response_format"`
+		Result         string `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(events[1].Data), &result); err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n00:00:00,000 --> 00:00:02,000\nhello world\n\n"
+	if result.ResponseFormat != "srt" || result.Result != want {
+		t.Errorf("result = %+v, want srt %q", result, want)
+	}
+	if rec.Header().Get("X-Cache") != "hit" {
+		t.Errorf("X-Cache = %q, want hit", rec.Header().Get("X-Cache"))
+	}
+}
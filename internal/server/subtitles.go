@@ -0,0 +1,298 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"parakeet/internal/asr"
+)
+
+const (
+	// cueMaxLines is how many lines a subtitle cue holds by default when
+	// lines are wrapped, the usual limit for broadcast and fansub work.
+	cueMaxLines = 2
+	// cueMergeGap is the longest pause between two segments that still
+	// lets them share a cue.
+	cueMergeGap = 0.5
+)
+
+// cueLayout shapes the cues of the subtitle formats (srt, vtt, ass, ttml).
+// The zero value keeps one cue per segment on one line.
+type cueLayout struct {
+	// MaxLineChars wraps cue text at word boundaries to lines of at most
+	// this many characters, and splits cues that would need more than
+	// MaxLines lines. Short segments that fit together, with a pause of at
+	// most cueMergeGap between them, are merged into one cue. Zero disables
+	// wrapping and merging.
+	MaxLineChars int
+	MaxLines     int // zero means cueMaxLines
+	// MaxDuration splits cues longer than this many seconds. Zero keeps
+	// segment-long cues.
+	MaxDuration float64
+}
+
+// subtitleFormats are the response formats whose segments a cueLayout
+// re-cuts. vtt_words lays out its cues itself, to keep their words' timings;
+// srt_words has one cue per word and no layout.
+var subtitleFormats = map[string]bool{"srt": true, "vtt": true, "ass": true, "ttml": true}
+
+// parseCueLayout reads max_line_chars, max_lines_per_cue and
+// max_cue_duration (seconds) with get, a form field or query string lookup.
+func parseCueLayout(get func(string) string) (cueLayout, error) {
+	var l cueLayout
+	if v := get("max_line_chars"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return l, invalidParam("max_line_chars", "invalid max_line_chars %q (a positive number of characters)", v)
+		}
+		l.MaxLineChars = n
+	}
+	if v := get("max_lines_per_cue"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return l, invalidParam("max_lines_per_cue", "invalid max_lines_per_cue %q (a positive number of lines)", v)
+		}
+		l.MaxLines = n
+	}
+	if v := get("max_cue_duration"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || d <= 0 {
+			return l, invalidParam("max_cue_duration", "invalid max_cue_duration %q (seconds, greater than zero)", v)
+		}
+		l.MaxDuration = d
+	}
+	return l, nil
+}
+
+// cueWord is one word of a segment being laid out.
+type cueWord struct {
+	text       string
+	start, end float64
+}
+
+// cue is one laid-out subtitle cue: the words of one channel it shows.
+type cue struct {
+	channel int
+	words   []cueWord
+}
+
+// apply returns a copy of res whose segments are the laid-out cues, with
+// line breaks in their text. Cue times come from the word timings; a
+// segment without them spreads its words over its span by length.
+func (l cueLayout) apply(res *asr.Result) *asr.Result {
+	if l.MaxLineChars == 0 && l.MaxDuration == 0 {
+		return res
+	}
+	out := *res
+	out.Segments = nil
+	for _, c := range l.cues(res) {
+		out.Segments = append(out.Segments, asr.Segment{
+			Channel: c.channel,
+			Start:   c.words[0].start,
+			End:     c.words[len(c.words)-1].end,
+			Text:    strings.Join(l.wrap(c.words), "\n"),
+		})
+	}
+	return &out
+}
+
+// cues lays out the segments of res: one cue per segment for the zero
+// layout, otherwise segments split and merged to the limits. Each cue keeps
+// the span of the segments it covers.
+func (l cueLayout) cues(res *asr.Result) []cue {
+	if l.MaxLines == 0 {
+		l.MaxLines = cueMaxLines
+	}
+	var cues []cue
+	var open []cueWord
+	channel := 0
+	flush := func() {
+		if len(open) > 0 {
+			cues = append(cues, cue{channel, open})
+			open = nil
+		}
+	}
+	for _, seg := range res.Segments {
+		words := segmentWords(res, seg)
+		if len(words) > 0 {
+			// The cue keeps the segment's span rather than the first
+			// word's start and the last word's end.
+			words[0].start, words[len(words)-1].end = seg.Start, seg.End
+		}
+		// A segment joins the open cue only whole, so cues still break
+		// where the model ended a segment.
+		if len(open) == 0 || !l.canMerge(open, channel, seg, words) {
+			flush()
+		}
+		channel = seg.Channel
+		for _, w := range words {
+			if len(open) > 0 && !l.fits(append(open[:len(open):len(open)], w)) {
+				flush()
+			}
+			open = append(open, w)
+		}
+	}
+	flush()
+	return cues
+}
+
+// fits reports whether cue stays within the line and duration limits.
+func (l cueLayout) fits(cue []cueWord) bool {
+	if l.MaxDuration > 0 && cue[len(cue)-1].end-cue[0].start > l.MaxDuration {
+		return false
+	}
+	return len(l.wrap(cue)) <= l.MaxLines
+}
+
+// canMerge reports whether the words of seg can be added to the open cue.
+func (l cueLayout) canMerge(cue []cueWord, channel int, seg asr.Segment, words []cueWord) bool {
+	if l.MaxLineChars == 0 || len(words) == 0 || seg.Channel != channel ||
+		seg.Start-cue[len(cue)-1].end > cueMergeGap {
+		return false
+	}
+	return l.fits(append(cue[:len(cue):len(cue)], words...))
+}
+
+// wrap fills lines greedily with the words of a cue.
+func (l cueLayout) wrap(words []cueWord) []string {
+	var lines []string
+	for _, line := range l.wrapWords(words) {
+		texts := make([]string, len(line))
+		for i, w := range line {
+			texts[i] = w.text
+		}
+		lines = append(lines, strings.Join(texts, " "))
+	}
+	return lines
+}
+
+// wrapWords splits the words of a cue into lines of at most MaxLineChars
+// characters; a word longer than that gets a line of its own.
+func (l cueLayout) wrapWords(words []cueWord) [][]cueWord {
+	var lines [][]cueWord
+	var line []cueWord
+	n := 0
+	for _, w := range words {
+		chars := utf8.RuneCountInString(w.text)
+		if len(line) > 0 && l.MaxLineChars > 0 && n+1+chars > l.MaxLineChars {
+			lines = append(lines, line)
+			line, n = nil, 0
+		}
+		if len(line) > 0 {
+			n++
+		}
+		line = append(line, w)
+		n += chars
+	}
+	return append(lines, line)
+}
+
+// segmentWords returns the timed words of seg, or its text split into
+// words timed in proportion to their length when the result has no word
+// timings for it.
+func segmentWords(res *asr.Result, seg asr.Segment) []cueWord {
+	const slack = 0.01 // word and segment times are rounded independently
+	var words []cueWord
+	for _, w := range res.Words {
+		if w.Channel == seg.Channel && w.Start >= seg.Start-slack && w.End <= seg.End+slack {
+			if text := strings.TrimSpace(w.Text); text != "" {
+				words = append(words, cueWord{text, w.Start, w.End})
+			}
+		}
+	}
+	if len(words) > 0 {
+		return words
+	}
+
+	fields := strings.Fields(seg.Text)
+	total := 0
+	for _, f := range fields {
+		total += utf8.RuneCountInString(f)
+	}
+	at := seg.Start
+	for _, f := range fields {
+		end := at + (seg.End-seg.Start)*float64(utf8.RuneCountInString(f))/float64(total)
+		words = append(words, cueWord{f, at, end})
+		at = end
+	}
+	return words
+}
+
+// vttEscaper escapes cue text for WebVTT, where & and < start markup.
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// karaoke is the text of a vtt_words cue: each word in a <c> class span,
+// preceded by an inline timestamp of when it is spoken, so renderers can
+// highlight words as they are said. The first word starts with the cue and
+// has none; the others are kept in order and within the cue's span. Lines
+// break like the cue's text does in vtt.
+func (l cueLayout) karaoke(res *asr.Result, c cue) string {
+	start, end := c.words[0].start, c.words[len(c.words)-1].end
+	var sb strings.Builder
+	if res.Channels > 1 {
+		sb.WriteString(vttEscaper.Replace(asr.ChannelLabel(c.channel)) + " ")
+	}
+	at := start
+	for i, line := range l.wrapWords(c.words) {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		for j, w := range line {
+			if j > 0 {
+				sb.WriteByte(' ')
+			}
+			if i > 0 || j > 0 {
+				at = min(max(w.start, at), end)
+				sb.WriteString("<" + formatVTTTime(at) + ">")
+			}
+			sb.WriteString("<c>" + vttEscaper.Replace(w.text) + "</c>")
+		}
+	}
+	return sb.String()
+}
+
+// ttmlHeader opens a TTML document: one style (white, centred) and one
+// region along the bottom of the frame. It is a format string taking the
+// language.
+const ttmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xml:lang="%s">
+  <head>
+    <styling>
+      <style xml:id="default" tts:color="white" tts:fontFamily="proportionalSansSerif" tts:textAlign="center"/>
+    </styling>
+    <layout>
+      <region xml:id="bottom" tts:origin="10%% 80%%" tts:extent="80%% 15%%" tts:displayAlign="after"/>
+    </layout>
+  </head>
+  <body style="default" region="bottom">
+    <div>
+`
+
+const ttmlFooter = `    </div>
+  </body>
+</tt>
+`
+
+// ttmlText escapes cue text for a TTML paragraph, with <br/> for line breaks.
+func ttmlText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		var b bytes.Buffer
+		xml.EscapeText(&b, []byte(line))
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "<br/>")
+}
+
+// ttmlAttr escapes a value for an XML attribute.
+func ttmlAttr(v string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(v))
+	return b.String()
+}
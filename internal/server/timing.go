@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Performance headers of a transcription response: time from the request
+// being received to the response, audio duration, and their ratio (below 1
+// is faster than real time).
+const (
+	processingTimeHeader = "X-Processing-Time-Ms"
+	audioDurationHeader  = "X-Audio-Duration-Ms"
+	realtimeFactorHeader = "X-Realtime-Factor"
+)
+
+// requestMetrics is what a request's handler reports back to the
+// middlewares: the performance headers, the access log and the audit log.
+type requestMetrics struct {
+	start        time.Time
+	audioSeconds float64
+
+	// filename is the name of the uploaded audio and transcripts the
+	// transcriptions finished for the request, for the audit log.
+	filename    string
+	transcripts []auditFile
+}
+
+type requestMetricsKey struct{}
+
+// metricsFrom returns the requestMetrics of ctx, nil outside timeResponses.
+func metricsFrom(ctx context.Context) *requestMetrics {
+	m, _ := ctx.Value(requestMetricsKey{}).(*requestMetrics)
+	return m
+}
+
+// noteAudio records that the request transcribed (or analysed) seconds of
+// audio. Called from the request goroutine before the response is written;
+// a no-op for contexts not derived from an HTTP request.
+func noteAudio(ctx context.Context, seconds float64) {
+	if m := metricsFrom(ctx); m != nil {
+		m.audioSeconds = seconds
+	}
+}
+
+// timeResponses puts a requestMetrics in every request's context and, for
+// responses whose handler called noteAudio before writing, adds the
+// performance headers. Streamed responses start before the audio is
+// decoded and go without them.
+func timeResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := &requestMetrics{start: time.Now()}
+		next.ServeHTTP(&timingWriter{ResponseWriter: w, m: m},
+			r.WithContext(context.WithValue(r.Context(), requestMetricsKey{}, m)))
+	})
+}
+
+// timingWriter sets the performance headers on the first write.
+type timingWriter struct {
+	http.ResponseWriter
+	m           *requestMetrics
+	wroteHeader bool
+}
+
+func (w *timingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.m.audioSeconds > 0 {
+			elapsed := time.Since(w.m.start)
+			h := w.Header()
+			h.Set(processingTimeHeader, strconv.FormatInt(elapsed.Milliseconds(), 10))
+			h.Set(audioDurationHeader, strconv.FormatInt(int64(w.m.audioSeconds*1000+0.5), 10))
+			h.Set(realtimeFactorHeader, strconv.FormatFloat(elapsed.Seconds()/w.m.audioSeconds, 'f', 4, 64))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestTimeResponses(t *testing.T) {
+	serve := func(audioSeconds float64) http.Header {
+		h := timeResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			noteAudio(r.Context(), audioSeconds)
+			io.WriteString(w, "{}")
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/audio/transcriptions", nil))
+		return rec.Header()
+	}
+
+	h := serve(2.5)
+	if h.Get(audioDurationHeader) != "2500" {
+		t.Fatalf("%s = %q", audioDurationHeader, h.Get(audioDurationHeader))
+	}
+	if _, err := strconv.ParseInt(h.Get(processingTimeHeader), 10, 64); err != nil {
+		t.Fatalf("%s = %q", processingTimeHeader, h.Get(processingTimeHeader))
+	}
+	if rtf, err := strconv.ParseFloat(h.Get(realtimeFactorHeader), 64); err != nil || rtf < 0 {
+		t.Fatalf("%s = %q", realtimeFactorHeader, h.Get(realtimeFactorHeader))
+	}
+
+	h = serve(0)
+	for _, name := range []string{processingTimeHeader, audioDurationHeader, realtimeFactorHeader} {
+		if h.Get(name) != "" {
+			t.Fatalf("%s set without audio: %q", name, h.Get(name))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Values of -translation: what /v1/audio/translations (and whisper.cpp's
+// translate=true) does.
+const (
+	// translationTranscribe answers with the transcript in the spoken
+	// language, whatever the model is, as parakeet always did.
+	translationTranscribe = "transcribe"
+	// translationReject answers 501.
+	translationReject = "reject"
+	// translationAuto runs models that declare the translate task and
+	// answers 501 for the others.
+	translationAuto = "auto"
+)
+
+// checkTranslationMode validates -translation.
+func checkTranslationMode(mode string) error {
+	switch mode {
+	case translationTranscribe, translationReject, translationAuto:
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q (want %s, %s or %s)", mode, translationTranscribe, translationReject, translationAuto)
+}
+
+// translationUnsupported is the reason a translation request is refused,
+// or "" when it may run.
+func (s *Server) translationUnsupported() string {
+	switch s.config.Translation {
+	case translationReject:
+		return "translation is disabled on this server (-translation=reject); use /v1/audio/transcriptions"
+	case translationAuto:
+		if !s.translates() {
+			return "the loaded model only transcribes; use /v1/audio/transcriptions"
+		}
+	}
+	return ""
+}
+
+// translates reports whether the loaded model's output is an English
+// translation. A server without models translates nothing.
+func (s *Server) translates() bool {
+	t := s.transcriber()
+	return t != nil && t.Translates()
+}
+
+// handleTranslation serves /v1/audio/translations. Models that translate
+// run as on /v1/audio/transcriptions; for the others -translation decides
+// between the transcript and 501.
+func (s *Server) handleTranslation(w http.ResponseWriter, r *http.Request) {
+	if reason := s.translationUnsupported(); reason != "" {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		code := "translation_unsupported"
+		writeError(w, http.StatusNotImplemented, ErrorDetail{Message: reason, Type: "invalid_request_error", Code: &code})
+		return
+	}
+	s.handleTranscription(w, r)
+}
+
+// modelCapabilities lists what the loaded models and the server can do,
+// for /v1/models: "translation" only when translations are real.
+func (s *Server) modelCapabilities() []string {
+	caps := []string{"transcription"}
+	if s.translates() && s.config.Translation != translationReject {
+		caps = append(caps, "translation")
+	}
+	caps = append(caps, "streaming", "word_timestamps")
+	if t := s.transcriber(); t != nil {
+		if t.CanDetectSpeech() {
+			caps = append(caps, "vad")
+		}
+		if t.CanDenoise() {
+			caps = append(caps, "denoise")
+		}
+	}
+	return caps
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestCheckTranslationMode(t *testing.T) {
+	for _, ok := range []string{"transcribe", "reject", "auto"} {
+		if err := checkTranslationMode(ok); err != nil {
+			t.Errorf("%s: %v", ok, err)
+		}
+	}
+	if err := checkTranslationMode("translate"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestHandleTranslation(t *testing.T) {
+	for mode, want := range map[string]string{
+		translationReject: "-translation=reject",
+		translationAuto:   "only transcribes",
+	} {
+		s := &Server{config: Config{Translation: mode}}
+		s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+
+		rec := httptest.NewRecorder()
+		s.handleTranslation(rec, inferenceRequest(t, []byte("audio"), nil))
+		var got ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&got)
+		if rec.Code != http.StatusNotImplemented || got.Error.Code == nil || *got.Error.Code != "translation_unsupported" || !strings.Contains(got.Error.Message, want) {
+			t.Errorf("%s: status %d, error %+v", mode, rec.Code, got.Error)
+		}
+
+		// whisper.cpp's translate=true follows the same switch.
+		rec = httptest.NewRecorder()
+		s.handleInference(rec, inferenceRequest(t, []byte("audio"), map[string]string{"translate": "true"}))
+		if rec.Code != http.StatusNotImplemented || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s /inference: status %d, body %s", mode, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestTranslationTranscribes(t *testing.T) {
+	s := &Server{cache: newMemoryCache(1), stats: newServerStats(), inflight: newInflightGroup()}
+	audio := []byte("audio")
+	s.cache.Put(s.cacheKey(audio, asr.TranscribeOptions{Format: ".wav", Language: "en", Channels: asr.ChannelMix}),
+		&asr.Result{Text: "hello world", Duration: 1, Channels: 1})
+
+	rec := httptest.NewRecorder()
+	r := inferenceRequest(t, audio, nil)
+	r.URL.Path = "/v1/audio/translations"
+	s.handleTranslation(rec, r)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "hello world") {
+		t.Errorf("status %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestModelCapabilities(t *testing.T) {
+	s := &Server{config: Config{Translation: translationTranscribe}, aliases: []modelAlias{{name: "whisper-1"}}}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+	if got := fmt.Sprint(s.modelCapabilities()); got != "[transcription streaming word_timestamps]" {
+		t.Errorf("capabilities = %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleModels(rec, httptest.NewRequest("GET", "/v1/models", nil))
+	var models ModelsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&models); err != nil {
+		t.Fatal(err)
+	}
+	if len(models.Data) != 2 || models.Data[0].ID != asr.DefaultModelName || models.Data[1].ID != "whisper-1" || models.Data[1].Root != asr.DefaultModelName {
+		t.Fatalf("models = %+v", models.Data)
+	}
+	for _, m := range models.Data {
+		if len(m.Capabilities) == 0 || m.Capabilities[0] != "transcription" {
+			t.Errorf("%s capabilities = %v", m.ID, m.Capabilities)
+		}
+	}
+}
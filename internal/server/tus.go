@@ -0,0 +1,346 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tusVersion is the tus resumable upload protocol (https://tus.io) served
+// on /v2/upload, with its creation, termination and expiration extensions.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+)
+
+// tusChunkBytes is how much of a PATCH body is read before it counts as
+// received, so that a dropped connection keeps what arrived before it.
+const tusChunkBytes = 1 << 20
+
+// tusUploads holds the resumable uploads still being sent. A finished one
+// moves to the upload storage under the same id, so its URL is also its
+// upload_url; until then it lives on the instance that created it.
+type tusUploads struct {
+	mu      sync.Mutex
+	uploads map[string]*tusUpload
+	bytes   int64 // declared lengths of the uploads held
+}
+
+type tusUpload struct {
+	owner    string // apiKeyNameFrom of the request that created it
+	length   int64
+	data     *spool // written only by the PATCH holding busy
+	received int64
+	expires  time.Time
+	busy     bool // a PATCH is writing to it
+}
+
+func newTusUploads() *tusUploads {
+	return &tusUploads{uploads: make(map[string]*tusUpload)}
+}
+
+// create reserves an upload of length bytes for owner, spooled to disk past
+// spoolBytes, or returns errUploadsFull.
+func (t *tusUploads) create(id, owner string, length, spoolBytes int64) (time.Time, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for id, u := range t.uploads {
+		if !u.busy && now.After(u.expires) {
+			u.data.Close()
+			t.remove(id)
+		}
+	}
+	if t.bytes+length > assemblyAIMaxStoredBytes {
+		return time.Time{}, errUploadsFull
+	}
+	u := &tusUpload{owner: owner, length: length, data: newSpool(spoolBytes), expires: now.Add(assemblyAIUploadTTL)}
+	t.uploads[id] = u
+	t.bytes += length
+	return u.expires, nil
+}
+
+// lookup returns the upload id, unless it does not exist, expired or
+// belongs to another owner.
+func (t *tusUploads) lookup(id, owner string) (*tusUpload, bool) {
+	u, ok := t.uploads[id]
+	if !ok || u.owner != owner || (!u.busy && time.Now().After(u.expires)) {
+		return nil, false
+	}
+	return u, true
+}
+
+// remove forgets the upload id; its spool is the caller's.
+func (t *tusUploads) remove(id string) {
+	if u, ok := t.uploads[id]; ok {
+		t.bytes -= u.length
+		delete(t.uploads, id)
+	}
+}
+
+// close drops the uploads still being sent.
+func (t *tusUploads) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, u := range t.uploads {
+		if !u.busy {
+			u.data.Close()
+		}
+		t.remove(id)
+	}
+}
+
+// pending reports whether id is an upload of owner still being sent.
+func (t *tusUploads) pending(id, owner string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.lookup(id, owner)
+	return ok
+}
+
+// isTusRequest reports whether a request to /v2/upload speaks tus rather
+// than posting the whole file: it carries Tus-Resumable, or it is the
+// OPTIONS a tus client discovers the server with.
+func isTusRequest(r *http.Request) bool {
+	return r.Header.Get("Tus-Resumable") != "" || r.Method == http.MethodOptions
+}
+
+// checkTusVersion answers 412 to a tus request of another protocol version.
+func checkTusVersion(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodOptions || r.Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+	w.Header().Set("Tus-Version", tusVersion)
+	sendAssemblyAIError(w, "Unsupported tus version, this server speaks "+tusVersion, http.StatusPreconditionFailed)
+	return false
+}
+
+// handleTusCreate serves the tus requests to /v2/upload: OPTIONS describes
+// what the server supports and POST creates an upload of Upload-Length
+// bytes at the URL in Location.
+func (s *Server) handleTusCreate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if !checkTusVersion(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", tusExtensions)
+		w.Header().Set("Tus-Max-Size", strconv.Itoa(assemblyAIMaxAudioBytes))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPost:
+	default:
+		sendAssemblyAIError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		sendAssemblyAIError(w, "Upload-Defer-Length is not supported, send Upload-Length", http.StatusBadRequest)
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	switch {
+	case err != nil || length < 0:
+		sendAssemblyAIError(w, "Upload-Length must be a number of bytes", http.StatusBadRequest)
+		return
+	case length == 0:
+		sendAssemblyAIError(w, "Upload is empty", http.StatusBadRequest)
+		return
+	case length > assemblyAIMaxAudioBytes:
+		sendAssemblyAIError(w, "Upload-Length exceeds Tus-Max-Size "+strconv.Itoa(assemblyAIMaxAudioBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+	id := newRequestID()
+	expires, err := s.tus.create(id, apiKeyNameFrom(r.Context()), length, s.spoolBytes())
+	if err != nil {
+		sendAssemblyAIError(w, "Upload storage is full, try again later", http.StatusTooManyRequests)
+		return
+	}
+	w.Header().Set("Location", requestBaseURL(r)+"/v2/upload/"+id)
+	w.Header().Set("Upload-Expires", expires.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleTusUpload serves /v2/upload/{id}, an upload created by
+// handleTusCreate: HEAD tells how much of it the server has, PATCH appends
+// to it from Upload-Offset, and DELETE abandons it. The PATCH that
+// completes it stores it as an upload, which HEAD then reports as whole
+// from any instance. Uploads of another key answer 404.
+func (s *Server) handleTusUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if !checkTusVersion(w, r) {
+		return
+	}
+	id, owner := r.PathValue("id"), apiKeyNameFrom(r.Context())
+	switch r.Method {
+	case http.MethodOptions:
+		s.handleTusCreate(w, r)
+	case http.MethodHead:
+		s.tusHead(w, id, owner)
+	case http.MethodPatch:
+		s.tusPatch(w, r, id, owner)
+	case http.MethodDelete:
+		s.tus.mu.Lock()
+		u, ok := s.tus.lookup(id, owner)
+		busy := ok && u.busy
+		if ok && !busy {
+			u.data.Close()
+			s.tus.remove(id)
+		}
+		s.tus.mu.Unlock()
+		switch {
+		case !ok:
+			sendAssemblyAIError(w, "Upload not found", http.StatusNotFound)
+		case busy:
+			sendAssemblyAIError(w, "Upload is being written by another request", http.StatusLocked)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		sendAssemblyAIError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) tusHead(w http.ResponseWriter, id, owner string) {
+	w.Header().Set("Cache-Control", "no-store")
+	s.tus.mu.Lock()
+	u, ok := s.tus.lookup(id, owner)
+	var offset, length int64
+	if ok {
+		offset, length = u.received, u.length
+		w.Header().Set("Upload-Expires", u.expires.UTC().Format(http.TimeFormat))
+	}
+	s.tus.mu.Unlock()
+	if !ok {
+		data, stored := s.uploads.get(id, owner)
+		if !stored {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		offset, length = int64(len(data)), int64(len(data))
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) tusPatch(w http.ResponseWriter, r *http.Request, id, owner string) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		sendAssemblyAIError(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		sendAssemblyAIError(w, "Upload-Offset must be a number of bytes", http.StatusBadRequest)
+		return
+	}
+
+	s.tus.mu.Lock()
+	u, ok := s.tus.lookup(id, owner)
+	switch {
+	case !ok:
+		s.tus.mu.Unlock()
+		sendAssemblyAIError(w, "Upload not found", http.StatusNotFound)
+		return
+	case u.busy:
+		s.tus.mu.Unlock()
+		sendAssemblyAIError(w, "Upload is being written by another request", http.StatusLocked)
+		return
+	case offset != u.received:
+		have := strconv.FormatInt(u.received, 10)
+		s.tus.mu.Unlock()
+		w.Header().Set("Upload-Offset", have)
+		sendAssemblyAIError(w, "Upload-Offset does not match the "+have+" bytes received", http.StatusConflict)
+		return
+	}
+	u.busy = true
+	remaining := u.length - offset
+	s.tus.mu.Unlock()
+
+	// Read the body a chunk at a time, keeping each as it arrives: when
+	// the client goes away the next PATCH resumes after the last chunk.
+	// The read stops one byte past what the upload still lacks, to tell
+	// a body that is too long; such a body is rejected whole, so the
+	// upload goes back to offset and does not complete.
+	body := io.LimitReader(r.Body, remaining+1)
+	buf := make([]byte, min(remaining+1, tusChunkBytes))
+	var readErr, writeErr error
+	tooLong := false
+	for {
+		n, err := io.ReadFull(body, buf)
+		if u.data.Len()+int64(n) > u.length {
+			tooLong = true
+			u.data.Truncate(offset)
+		} else if _, writeErr = u.data.Write(buf[:n]); writeErr != nil {
+			u.data.Truncate(u.received)
+			break
+		}
+		s.tus.mu.Lock()
+		u.received = u.data.Len()
+		u.expires = time.Now().Add(assemblyAIUploadTTL)
+		s.tus.mu.Unlock()
+		if err == io.EOF || err == io.ErrUnexpectedEOF || tooLong {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+
+	s.tus.mu.Lock()
+	received, expires := u.received, u.expires
+	complete := received == u.length
+	if !complete {
+		u.busy = false
+	}
+	s.tus.mu.Unlock()
+
+	if complete {
+		err := s.uploads.store(id, owner, u.data)
+		s.tus.mu.Lock()
+		if err == nil {
+			s.tus.remove(id)
+		} else {
+			// Leave it one byte short, so that the client retrying the
+			// last PATCH stores it again instead of starting over.
+			u.data.Truncate(received - 1)
+			u.received = u.data.Len()
+			u.busy = false
+		}
+		s.tus.mu.Unlock()
+		if errors.Is(err, errUploadsFull) {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(received-1, 10))
+			sendAssemblyAIError(w, "Upload storage is full, try again later", http.StatusTooManyRequests)
+			return
+		}
+		if err != nil {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(received-1, 10))
+			sendAssemblyAIError(w, "Error storing upload: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(received, 10))
+	switch {
+	case writeErr != nil:
+		sendAssemblyAIError(w, "Error storing upload: "+writeErr.Error(), http.StatusInternalServerError)
+	case readErr != nil:
+		sendAssemblyAIError(w, "Error reading upload: "+readErr.Error(), http.StatusBadRequest)
+	case tooLong:
+		sendAssemblyAIError(w, "Body is longer than Upload-Length", http.StatusRequestEntityTooLarge)
+	default:
+		if !complete {
+			w.Header().Set("Upload-Expires", expires.UTC().Format(http.TimeFormat))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"parakeet/internal/asr"
+	"parakeet/internal/jobs"
+)
+
+func tusRequest(s *Server, method, target string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, body)
+	r.Header.Set("Authorization", "secret")
+	r.Header.Set("Tus-Resumable", tusVersion)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+	return rec
+}
+
+func tusPatchRequest(s *Server, path string, offset int, body io.Reader) *httptest.ResponseRecorder {
+	return tusRequest(s, "PATCH", path, body,
+		"Content-Type", "application/offset+octet-stream",
+		"Upload-Offset", strconv.Itoa(offset))
+}
+
+// droppedBody yields data and then fails, like a connection cut mid-upload.
+type droppedBody struct{ data []byte }
+
+func (d *droppedBody) Read(p []byte) (int, error) {
+	if len(d.data) == 0 {
+		return 0, errors.New("connection reset by peer")
+	}
+	n := copy(p, d.data)
+	d.data = d.data[n:]
+	return n, nil
+}
+
+func TestTus_ResumeAfterDrop(t *testing.T) {
+	s := newAssemblyAITestServer(t)
+	audio := []byte("RIFF-resumed-audio-that-was-cut-in-two")
+	s.cache.Put(s.cacheKey(audio, asr.TranscribeOptions{Language: "en", Channels: asr.ChannelMix}), &asr.Result{
+		Text: "Resumed.", Duration: 1, Channels: 1,
+		Segments: []asr.Segment{{Start: 0, End: 1, Text: "Resumed."}},
+	})
+
+	rec := tusRequest(s, "OPTIONS", "/v2/upload", nil)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Tus-Version") != tusVersion || !strings.Contains(rec.Header().Get("Tus-Extension"), "creation") {
+		t.Fatalf("options: %d %v", rec.Code, rec.Header())
+	}
+
+	rec = tusRequest(s, "POST", "/v2/upload", nil, "Upload-Length", strconv.Itoa(len(audio)))
+	location := rec.Header().Get("Location")
+	if rec.Code != http.StatusCreated || !strings.HasPrefix(location, "http://example.com/v2/upload/") {
+		t.Fatalf("create: %d %q", rec.Code, location)
+	}
+	path := strings.TrimPrefix(location, "http://example.com")
+
+	// The connection drops after 10 bytes: they are kept.
+	tusPatchRequest(s, path, 0, &droppedBody{data: audio[:10]})
+	rec = tusRequest(s, "HEAD", path, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != "10" || rec.Header().Get("Upload-Length") != strconv.Itoa(len(audio)) {
+		t.Fatalf("head after drop: %d %v", rec.Code, rec.Header())
+	}
+
+	body, _ := json.Marshal(assemblyAITranscriptRequest{AudioURL: location})
+	if rec = assemblyAIRequest(s, "POST", "/v2/transcript", body); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "not complete") {
+		t.Errorf("submit before complete: %d %s", rec.Code, rec.Body)
+	}
+	if rec = tusPatchRequest(s, path, 0, bytes.NewReader(audio)); rec.Code != http.StatusConflict || rec.Header().Get("Upload-Offset") != "10" {
+		t.Errorf("patch at a stale offset: %d %v", rec.Code, rec.Header())
+	}
+
+	rec = tusPatchRequest(s, path, 10, bytes.NewReader(audio[10:]))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != strconv.Itoa(len(audio)) {
+		t.Fatalf("resume: %d %v %s", rec.Code, rec.Header(), rec.Body)
+	}
+	if rec = tusRequest(s, "HEAD", path, nil); rec.Header().Get("Upload-Offset") != strconv.Itoa(len(audio)) {
+		t.Errorf("head after complete: %d %v", rec.Code, rec.Header())
+	}
+
+	rec = assemblyAIRequest(s, "POST", "/v2/transcript", body)
+	var got assemblyAITranscript
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	id := got.ID
+	deadline := time.Now().Add(5 * time.Second)
+	for got.Status != jobs.StatusCompleted && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rec = assemblyAIRequest(s, "GET", "/v2/transcript/"+id, nil)
+		json.Unmarshal(rec.Body.Bytes(), &got)
+	}
+	if got.Text == nil || *got.Text != "Resumed." {
+		t.Fatalf("transcript = %+v", got)
+	}
+}
+
+func TestTus_OwnKeyOnly(t *testing.T) {
+	s := newAssemblyAITestServer(t)
+	s.apiKeys = map[string]string{"sk-search": "search", "sk-support": "support"}
+	other := []string{"Authorization", "sk-support"}
+	audio := []byte("RIFF-tenant")
+
+	rec := tusRequest(s, "POST", "/v2/upload", nil, "Authorization", "sk-search", "Upload-Length", strconv.Itoa(len(audio)))
+	location := rec.Header().Get("Location")
+	path := strings.TrimPrefix(location, "http://example.com")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d", rec.Code)
+	}
+	patch := func(key string, offset int, body []byte) *httptest.ResponseRecorder {
+		return tusRequest(s, "PATCH", path, bytes.NewReader(body), "Authorization", key,
+			"Content-Type", "application/offset+octet-stream", "Upload-Offset", strconv.Itoa(offset))
+	}
+	if rec = tusRequest(s, "HEAD", path, nil, other...); rec.Code != http.StatusNotFound {
+		t.Errorf("other key HEAD: %d, want 404", rec.Code)
+	}
+	if rec = patch("sk-support", 0, audio[:4]); rec.Code != http.StatusNotFound {
+		t.Errorf("other key PATCH: %d, want 404", rec.Code)
+	}
+	if rec = tusRequest(s, "DELETE", path, nil, other...); rec.Code != http.StatusNotFound {
+		t.Errorf("other key DELETE: %d, want 404", rec.Code)
+	}
+	if rec = patch("sk-search", 0, audio); rec.Code != http.StatusNoContent {
+		t.Fatalf("owner PATCH: %d %s", rec.Code, rec.Body)
+	}
+
+	// Complete, it is still the owner's only.
+	if rec = tusRequest(s, "HEAD", path, nil, other...); rec.Code != http.StatusNotFound {
+		t.Errorf("other key HEAD after complete: %d, want 404", rec.Code)
+	}
+	if rec = tusRequest(s, "HEAD", path, nil, "Authorization", "sk-search"); rec.Code != http.StatusOK {
+		t.Errorf("owner HEAD after complete: %d", rec.Code)
+	}
+	body, _ := json.Marshal(assemblyAITranscriptRequest{AudioURL: location})
+	submit := func(key string) int {
+		r := httptest.NewRequest("POST", "/v2/transcript", bytes.NewReader(body))
+		r.Header.Set("Authorization", key)
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	if code := submit("sk-support"); code != http.StatusNotFound {
+		t.Errorf("other key submit: %d, want 404", code)
+	}
+	if code := submit("sk-search"); code != http.StatusOK {
+		t.Errorf("owner submit: %d", code)
+	}
+}
+
+func TestTus_Errors(t *testing.T) {
+	s := newAssemblyAITestServer(t)
+
+	if rec := tusRequest(s, "POST", "/v2/upload", nil, "Tus-Resumable", "0.2.2", "Upload-Length", "10"); rec.Code != http.StatusPreconditionFailed || rec.Header().Get("Tus-Version") != tusVersion {
+		t.Errorf("old version: %d", rec.Code)
+	}
+	if rec := tusRequest(s, "POST", "/v2/upload", nil, "Upload-Length", strconv.Itoa(assemblyAIMaxAudioBytes+1)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too large: %d", rec.Code)
+	}
+	if rec := tusRequest(s, "POST", "/v2/upload", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("no Upload-Length: %d", rec.Code)
+	}
+
+	rec := tusRequest(s, "POST", "/v2/upload", nil, "Upload-Length", "4")
+	path := strings.TrimPrefix(rec.Header().Get("Location"), "http://example.com")
+	if rec = tusRequest(s, "PATCH", path, strings.NewReader("abcd"), "Upload-Offset", "0"); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("wrong content type: %d", rec.Code)
+	}
+	if rec = tusPatchRequest(s, path, 0, strings.NewReader("ab")); rec.Code != http.StatusNoContent {
+		t.Errorf("first half: %d", rec.Code)
+	}
+	// A body longer than what is left is dropped whole: the upload stays
+	// at the offset it had, incomplete, and can still be finished.
+	if rec = tusPatchRequest(s, path, 2, strings.NewReader("cdef")); rec.Code != http.StatusRequestEntityTooLarge || rec.Header().Get("Upload-Offset") != "2" {
+		t.Errorf("longer than Upload-Length: %d %v", rec.Code, rec.Header())
+	}
+	if rec = tusRequest(s, "HEAD", path, nil); rec.Header().Get("Upload-Offset") != "2" {
+		t.Errorf("head after 413: %v", rec.Header())
+	}
+	body, _ := json.Marshal(assemblyAITranscriptRequest{AudioURL: "http://example.com" + path})
+	if rec = assemblyAIRequest(s, "POST", "/v2/transcript", body); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "not complete") {
+		t.Errorf("submit after 413: %d %s", rec.Code, rec.Body)
+	}
+	if rec = tusPatchRequest(s, path, 2, strings.NewReader("cd")); rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "4" {
+		t.Errorf("finish after 413: %d %v", rec.Code, rec.Header())
+	}
+
+	rec = tusRequest(s, "POST", "/v2/upload", nil, "Upload-Length", "4")
+	path = strings.TrimPrefix(rec.Header().Get("Location"), "http://example.com")
+	if rec = tusRequest(s, "DELETE", path, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: %d", rec.Code)
+	}
+	if rec = tusRequest(s, "HEAD", path, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("head after delete: %d", rec.Code)
+	}
+	if s.tus.bytes != 0 {
+		t.Errorf("%d bytes still reserved", s.tus.bytes)
+	}
+}
@@ -0,0 +1,354 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"parakeet/internal/asr"
+)
+
+const twilioAuthTokenEnvVar = "PARAKEET_TWILIO_AUTH_TOKEN"
+
+const (
+	// twilioIdleTimeout closes a stream that stops sending media. Twilio
+	// sends a packet every 20 ms for the whole call, silence included.
+	twilioIdleTimeout = 30 * time.Second
+	// callbackTimeout bounds one transcript POST to the callback URL.
+	callbackTimeout = 10 * time.Second
+	// callbackQueueSize is how many transcripts may wait for delivery per
+	// stream before new ones are dropped.
+	callbackQueueSize = 64
+)
+
+// twilioFormat is the only audio Twilio Media Streams send: 8 kHz mono
+// G.711 µ-law.
+var twilioFormat = asr.PCMFormat{Encoding: asr.PCMMuLaw, SampleRate: 8000, Channels: 1}
+
+// twilioMessage is one Media Streams message. Only the events and fields
+// used here are decoded.
+type twilioMessage struct {
+	Event     string `json:"event"`
+	StreamSid string `json:"streamSid"`
+	Start     *struct {
+		AccountSid       string            `json:"accountSid"`
+		CallSid          string            `json:"callSid"`
+		Tracks           []string          `json:"tracks"`
+		CustomParameters map[string]string `json:"customParameters"`
+		MediaFormat      struct {
+			Encoding   string `json:"encoding"`
+			SampleRate int    `json:"sampleRate"`
+			Channels   int    `json:"channels"`
+		} `json:"mediaFormat"`
+	} `json:"start"`
+	Media *struct {
+		Track   string `json:"track"`
+		Payload string `json:"payload"`
+	} `json:"media"`
+}
+
+// twilioTranscript is the body POSTed to the callback URL for each final
+// utterance. Times are seconds from the start of the stream.
+type twilioTranscript struct {
+	StreamSid        string            `json:"stream_sid"`
+	CallSid          string            `json:"call_sid"`
+	AccountSid       string            `json:"account_sid"`
+	Track            string            `json:"track"`
+	Start            float64           `json:"start"`
+	End              float64           `json:"end"`
+	Text             string            `json:"text"`
+	SpeechFinal      bool              `json:"speech_final"`
+	Words            []transcriptWord  `json:"words"`
+	CustomParameters map[string]string `json:"custom_parameters,omitempty"`
+}
+
+// transcriptWord is a word of a callback transcript.
+type transcriptWord struct {
+	Text       string  `json:"text"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence"`
+}
+
+// twilioSignature computes X-Twilio-Signature for a request without POST
+// parameters, such as the WebSocket handshake: the base64 HMAC-SHA1 of the
+// full URL keyed with the account's auth token.
+func twilioSignature(authToken, url string) string {
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(url))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// twilioStreamURL is the URL Twilio signed: the <Stream> URL, with the
+// WebSocket scheme matching the scheme the request arrived on.
+func twilioStreamURL(r *http.Request) string {
+	base := requestBaseURL(r)
+	if rest, ok := strings.CutPrefix(base, "https://"); ok {
+		base = "wss://" + rest
+	} else {
+		base = "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	return base + r.URL.RequestURI()
+}
+
+// handleTwilioStream serves a Twilio Media Streams WebSocket (the target of
+// a TwiML <Stream>). Each track of the call gets its own live session, and
+// every final utterance is POSTed to the configured callback URL.
+//
+// Twilio cannot send an API key. With PARAKEET_TWILIO_AUTH_TOKEN set, the
+// handshake's X-Twilio-Signature is verified instead; otherwise, when an API
+// key is configured, the stream must carry it as the "api_key" <Parameter>.
+func (s *Server) handleTwilioStream(w http.ResponseWriter, r *http.Request) {
+	if s.twilioAuthToken != "" {
+		want := twilioSignature(s.twilioAuthToken, twilioStreamURL(r))
+		if !hmac.Equal([]byte(r.Header.Get("X-Twilio-Signature")), []byte(want)) {
+			http.Error(w, "invalid Twilio signature", http.StatusForbidden)
+			return
+		}
+	}
+	conn, err := upgradeWebSocket(w, r, "")
+	if err != nil {
+		slog.Debug("twilio upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx := r.Context()
+	callback := newCallbackSender(s.config.TwilioCallbackURL)
+	defer callback.close()
+
+	var call twilioTranscript // identifies the call in every callback
+	sessions := make(map[string]*liveSession)
+	started := time.Now()
+	var unlist []func()
+	defer func() {
+		for _, f := range unlist {
+			f()
+		}
+	}()
+	opts := asr.TranscribeOptions{
+		Format:            ".wav",
+		Language:          "en",
+		Channels:          asr.ChannelMix,
+		Conditioning:      s.conditioning,
+		Segmentation:      s.segmentation,
+		NoSpeechThreshold: s.config.NoSpeechThreshold,
+		Guard:             s.guard,
+		Confidence:        s.confidence,
+		Formatting:        s.formatting,
+		Fallback:          s.fallback,
+		Denoise:           s.config.Denoise,
+		Priority:          asr.PriorityInteractive,
+	}
+
+	deliver := func(track string, res liveResult) {
+		if strings.TrimSpace(res.Text) == "" {
+			return
+		}
+		t := call
+		t.Track = track
+		t.Start = res.Start
+		t.End = res.Start + res.Duration
+		t.Text = res.Text
+		t.SpeechFinal = res.SpeechFinal
+		t.Words = make([]transcriptWord, len(res.Words))
+		for i, w := range res.Words {
+			t.Words[i] = transcriptWord{Text: w.Text, Start: w.Start, End: w.End, Confidence: w.Confidence}
+		}
+		callback.send(t)
+	}
+	// flush finalizes every track, in a stable order.
+	flush := func() {
+		tracks := make([]string, 0, len(sessions))
+		for track := range sessions {
+			tracks = append(tracks, track)
+		}
+		sort.Strings(tracks)
+		for _, track := range tracks {
+			res, err := sessions[track].finalize(ctx, false, true)
+			if err != nil {
+				slog.Error("twilio transcription failed", "stream_sid", call.StreamSid, "error", err)
+				continue
+			}
+			if res != nil {
+				deliver(track, *res)
+			}
+		}
+	}
+
+	for {
+		_ = conn.setReadDeadline(s.liveReadDeadline(started, twilioIdleTimeout))
+		op, data, err := conn.readMessage()
+		if err != nil {
+			switch {
+			case s.liveExpired(started):
+				conn.writeClose(wsClosePolicy, "maximum session duration reached")
+			case !errors.Is(err, errWSClosed) && !errors.Is(err, io.EOF):
+				conn.writeClose(wsClosePolicy, "no media received within the timeout window")
+			}
+			flush()
+			slog.Info("twilio stream ended", "stream_sid", call.StreamSid, "reason", err)
+			return
+		}
+		if op != wsText {
+			conn.writeClose(wsCloseUnsupported, "expected JSON text messages")
+			return
+		}
+		var msg twilioMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			conn.writeClose(wsCloseUnsupported, "invalid message")
+			return
+		}
+
+		switch msg.Event {
+		case "start":
+			if msg.Start == nil {
+				conn.writeClose(wsCloseProtocolError, "start event without start")
+				return
+			}
+			params := msg.Start.CustomParameters
+			if _, ok := s.apiKeyName(params["api_key"]); s.twilioAuthToken == "" && s.authEnabled() && !ok {
+				conn.writeClose(wsClosePolicy, "invalid API key")
+				return
+			}
+			mf := msg.Start.MediaFormat
+			if mf.Encoding != "audio/x-mulaw" || mf.SampleRate != twilioFormat.SampleRate || mf.Channels != twilioFormat.Channels {
+				conn.writeClose(wsCloseUnsupported, "unsupported media format "+mf.Encoding)
+				return
+			}
+			if lang := params["language"]; lang != "" {
+				if err := s.checkLanguage(lang); err != nil {
+					conn.writeClose(wsCloseUnsupported, err.Error())
+					return
+				}
+				opts.Language = lang
+			}
+			delete(params, "api_key")
+			call = twilioTranscript{
+				StreamSid:        msg.StreamSid,
+				CallSid:          msg.Start.CallSid,
+				AccountSid:       msg.Start.AccountSid,
+				CustomParameters: params,
+			}
+			slog.Info("twilio stream started", "stream_sid", msg.StreamSid, "call_sid", msg.Start.CallSid, "tracks", msg.Start.Tracks)
+
+		case "media":
+			if call.StreamSid == "" {
+				conn.writeClose(wsCloseProtocolError, "media before start")
+				return
+			}
+			if msg.Media == nil {
+				continue
+			}
+			audio, err := base64.StdEncoding.DecodeString(msg.Media.Payload)
+			if err != nil {
+				conn.writeClose(wsCloseUnsupported, "invalid media payload")
+				return
+			}
+			track := msg.Media.Track
+			if track == "" {
+				track = "inbound"
+			}
+			session, ok := sessions[track]
+			if !ok {
+				session = s.newLiveSession(twilioFormat, opts, false, s.liveEndpointing())
+				sessions[track] = session
+				unlist = append(unlist, s.liveSessions.add(session, "twilio", call.CallSid+"/"+track))
+			}
+			results, err := session.push(ctx, audio)
+			if err != nil {
+				slog.Error("twilio transcription failed", "stream_sid", call.StreamSid, "error", err)
+				conn.writeClose(wsCloseInternal, "transcription failed")
+				return
+			}
+			for _, res := range results {
+				deliver(track, res)
+			}
+
+		case "stop":
+			flush()
+			conn.writeClose(wsCloseNormal, "")
+			slog.Info("twilio stream stopped", "stream_sid", call.StreamSid)
+			return
+
+		default:
+			// connected, mark, dtmf and future events carry no audio.
+		}
+	}
+}
+
+// callbackSender POSTs JSON documents to a URL in order, from its own
+// goroutine, so a slow receiver does not stall the audio read loop. When
+// the receiver falls behind by callbackQueueSize documents, new ones are
+// dropped and logged.
+type callbackSender struct {
+	url  string
+	ch   chan any
+	done chan struct{}
+}
+
+func newCallbackSender(url string) *callbackSender {
+	c := &callbackSender{url: url, ch: make(chan any, callbackQueueSize), done: make(chan struct{})}
+	go c.run()
+	return c
+}
+
+func (c *callbackSender) send(v any) {
+	select {
+	case c.ch <- v:
+	default:
+		slog.Warn("callback queue full, dropping transcript", "url", c.url)
+	}
+}
+
+// close delivers what is queued and stops the sender.
+func (c *callbackSender) close() {
+	close(c.ch)
+	<-c.done
+}
+
+func (c *callbackSender) run() {
+	defer close(c.done)
+	for v := range c.ch {
+		if err := postJSON(c.url, v); err != nil {
+			slog.Warn("callback failed", "url", c.url, "error", err)
+		}
+	}
+}
+
+// postJSON POSTs v as JSON, bounded by callbackTimeout.
+func postJSON(url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callbackTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New("callback answered " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func twilioStart(params map[string]string) []byte {
+	msg := map[string]any{
+		"event":     "start",
+		"streamSid": "MZ123",
+		"start": map[string]any{
+			"accountSid":       "AC123",
+			"callSid":          "CA123",
+			"tracks":           []string{"inbound"},
+			"customParameters": params,
+			"mediaFormat":      map[string]any{"encoding": "audio/x-mulaw", "sampleRate": 8000, "channels": 1},
+		},
+	}
+	b, _ := json.Marshal(msg)
+	return b
+}
+
+func TestTwilioStream_SilenceThenStop(t *testing.T) {
+	var mu sync.Mutex
+	var posted int
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		posted++
+		mu.Unlock()
+	}))
+	defer sink.Close()
+
+	s := &Server{stats: newServerStats(), config: Config{TwilioCallbackURL: sink.URL}}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleTwilioStream))
+	defer srv.Close()
+
+	c := dialWS(t, srv, "/twilio/stream", nil)
+	c.send(true, wsText, []byte(`{"event":"connected","protocol":"Call","version":"1.0.0"}`))
+	c.send(true, wsText, twilioStart(nil))
+	// One second of µ-law silence (0xFF) in 20 ms packets never reaches
+	// the model.
+	payload := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 160))
+	for i := 0; i < 50; i++ {
+		c.send(true, wsText, []byte(`{"event":"media","streamSid":"MZ123","media":{"track":"inbound","payload":"`+payload+`"}}`))
+	}
+	c.send(true, wsText, []byte(`{"event":"stop","streamSid":"MZ123"}`))
+
+	if op, data := c.read(); op != wsClose || binary.BigEndian.Uint16(data) != wsCloseNormal {
+		t.Fatalf("got op %#x %v, want close 1000", op, data)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if posted != 0 {
+		t.Errorf("silence produced %d callbacks", posted)
+	}
+}
+
+func TestTwilioStream_Auth(t *testing.T) {
+	s := &Server{stats: newServerStats(), apiKey: "secret", config: Config{TwilioCallbackURL: "http://127.0.0.1:1"}}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleTwilioStream))
+	defer srv.Close()
+
+	// Without a Twilio auth token the API key travels as a <Parameter>.
+	c := dialWS(t, srv, "/twilio/stream", nil)
+	c.send(true, wsText, twilioStart(map[string]string{"api_key": "wrong"}))
+	if op, data := c.read(); op != wsClose || binary.BigEndian.Uint16(data) != wsClosePolicy {
+		t.Fatalf("got op %#x %v, want close 1008", op, data)
+	}
+	c = dialWS(t, srv, "/twilio/stream", nil)
+	c.send(true, wsText, twilioStart(map[string]string{"api_key": "secret"}))
+	c.send(true, wsText, []byte(`{"event":"stop"}`))
+	if op, data := c.read(); op != wsClose || binary.BigEndian.Uint16(data) != wsCloseNormal {
+		t.Fatalf("got op %#x %v, want close 1000", op, data)
+	}
+
+	// With one, the handshake signature is checked instead.
+	s.twilioAuthToken = "token"
+	r := httptest.NewRequest("GET", "http://parakeet.example/twilio/stream", nil)
+	r.Header.Set("X-Twilio-Signature", "bogus")
+	rec := httptest.NewRecorder()
+	s.handleTwilioStream(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("bad signature: status %d, want 403", rec.Code)
+	}
+	// dialWS sends Host: test.
+	dialWS(t, srv, "/twilio/stream", http.Header{"X-Twilio-Signature": {twilioSignature("token", "ws://test/twilio/stream")}})
+}
+
+func TestCallbackSender_DeliversInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, string(body))
+		mu.Unlock()
+	}))
+	defer sink.Close()
+
+	c := newCallbackSender(sink.URL)
+	for _, text := range []string{"one", "two", "three"} {
+		c.send(map[string]string{"text": text})
+	}
+	c.close()
+
+	want := []string{`{"text":"one"}`, `{"text":"two"}`, `{"text":"three"}`}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("callback %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
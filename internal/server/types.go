@@ -5,16 +5,57 @@ package server
 
 // TranscriptionResponse represents a simple transcription result
 type TranscriptionResponse struct {
-	Text string `json:"text"`
+	Text      string    `json:"text"`
+	Logprobs  []Logprob `json:"logprobs,omitempty"`  // with include[]=logprobs
+	Truncated bool      `json:"truncated,omitempty"` // cut short by max_processing_ms
+}
+
+// BatchTranscriptionResponse is the answer of
+// /v1/audio/transcriptions/batch: one result per file, in the order sent.
+type BatchTranscriptionResponse struct {
+	Results []BatchTranscriptionResult `json:"results"`
+}
+
+// BatchTranscriptionResult is one file of a batch: its response_format body
+// (an object for json and verbose_json, a string for the others), or the
+// error it failed with.
+type BatchTranscriptionResult struct {
+	Filename string       `json:"filename"`
+	Result   any          `json:"result,omitempty"`
+	Error    *ErrorDetail `json:"error,omitempty"`
+}
+
+// Logprob is the log-probability of one decoded token, as in OpenAI's
+// transcription logprobs. Bytes are the UTF-8 bytes of Token.
+type Logprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes"`
 }
 
 // VerboseTranscriptionResponse represents a detailed transcription result
 type VerboseTranscriptionResponse struct {
-	Task     string    `json:"task"`
-	Language string    `json:"language"`
-	Duration float64   `json:"duration"`
-	Text     string    `json:"text"`
-	Segments []Segment `json:"segments,omitempty"`
+	Task      string    `json:"task"`
+	Language  string    `json:"language"`
+	Duration  float64   `json:"duration"`
+	Text      string    `json:"text"`
+	Segments  []Segment `json:"segments,omitempty"`
+	Logprobs  []Logprob `json:"logprobs,omitempty"`  // with include[]=logprobs
+	Truncated bool      `json:"truncated,omitempty"` // cut short by max_processing_ms
+
+	// Timings is where the decode spent its time, with include[]=timings.
+	Timings *StageTimings `json:"timings,omitempty"`
+}
+
+// StageTimings splits a transcription's processing time by pipeline stage,
+// in milliseconds. Encoder and decoder are summed over chunks; decoder
+// excludes the wait for a free worker.
+type StageTimings struct {
+	DecodeAudioMs float64 `json:"decode_audio_ms"`
+	FeaturesMs    float64 `json:"features_ms"`
+	EncoderMs     float64 `json:"encoder_ms"`
+	DecoderMs     float64 `json:"decoder_ms"`
+	PostprocessMs float64 `json:"postprocess_ms"`
 }
 
 // Segment represents a transcription segment with timing information
@@ -26,9 +67,32 @@ type Segment struct {
 	Text             string  `json:"text"`
 	Tokens           []int   `json:"tokens"`
 	Temperature      float64 `json:"temperature"`
-	AvgLogprob       float64 `json:"avg_logprob"`
 	CompressionRatio float64 `json:"compression_ratio"`
 	NoSpeechProb     float64 `json:"no_speech_prob"`
+
+	// AvgLogprob is the mean log-probability of the segment's tokens; left
+	// out when the segment has none.
+	AvgLogprob *float64 `json:"avg_logprob,omitempty"`
+
+	// Suspect lists why hallucination_guard=flag found the segment
+	// improbable: unvoiced, token_rate or repetition.
+	Suspect []string `json:"suspect,omitempty"`
+
+	// Channel is the source channel of the segment; only set for
+	// channel_mode=per_channel on multi-channel audio.
+	Channel *int `json:"channel,omitempty"`
+}
+
+// SegmentLine is one line of response_format=jsonl: a segment with its
+// times in seconds.
+type SegmentLine struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+
+	// Channel is the source channel of the segment; only set for
+	// multi-channel results.
+	Channel *int `json:"channel,omitempty"`
 }
 
 // StreamDeltaEvent is emitted (as SSE) for each chunk of transcript produced
@@ -41,8 +105,29 @@ type StreamDeltaEvent struct {
 // StreamDoneEvent is the final SSE event, carrying the complete transcript.
 // Mirrors OpenAI's transcript.text.done.
 type StreamDoneEvent struct {
-	Type string `json:"type"` // always "transcript.text.done"
-	Text string `json:"text"`
+	Type      string    `json:"type"` // always "transcript.text.done"
+	Text      string    `json:"text"`
+	Logprobs  []Logprob `json:"logprobs,omitempty"`  // with include[]=logprobs
+	Truncated bool      `json:"truncated,omitempty"` // cut short by max_processing_ms
+}
+
+// StreamProgressEvent reports how much of the audio has been decoded, for
+// clients that ask for progress with Accept: text/event-stream.
+type StreamProgressEvent struct {
+	Type             string  `json:"type"` // always "transcript.progress"
+	Percent          int     `json:"percent"`
+	ProcessedSeconds float64 `json:"processed_seconds"`
+	TotalSeconds     float64 `json:"total_seconds"`
+	Text             string  `json:"text"` // transcript so far
+}
+
+// StreamResultEvent is the final progress-stream event. Result is the
+// response the request would have received without SSE: an object for json
+// and verbose_json, a string for text, srt and vtt.
+type StreamResultEvent struct {
+	Type           string `json:"type"` // always "transcript.result"
+	ResponseFormat string `json:"response_format"`
+	Result         any    `json:"result"`
 }
 
 // ErrorResponse represents an OpenAI-compatible error response
@@ -52,9 +137,14 @@ type ErrorResponse struct {
 
 // ErrorDetail contains error information
 type ErrorDetail struct {
-	Message string `json:"message"`
-	Type    string `json:"type"`
-	Code    string `json:"code,omitempty"`
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"` // null unless one parameter is at fault
+	Code    *string `json:"code"`
+
+	// RequestID is the response's X-Request-ID, to quote when reporting
+	// the error.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // ModelInfo represents information about an available model
@@ -63,6 +153,33 @@ type ModelInfo struct {
 	Object  string `json:"object"`
 	Created int64  `json:"created"`
 	OwnedBy string `json:"owned_by"`
+
+	// Root is the model an alias (-model-aliases) stands for; empty for
+	// the model itself.
+	Root string `json:"root,omitempty"`
+
+	// Parameters is the model's parameter count: config.json
+	// "parameters", else estimated from the size of its weights.
+	Parameters int64 `json:"parameters,omitempty"`
+
+	// Precision is the export loaded for each part of the model.
+	Precision *ModelPrecision `json:"precision,omitempty"`
+
+	// Languages are the ISO-639-1 codes the model transcribes; any other
+	// language is rejected.
+	Languages []string `json:"languages"`
+
+	// Capabilities are what the server does with the model:
+	// "transcription", "translation" (only when the model's output is an
+	// English translation), "streaming", "word_timestamps", and "vad" and
+	// "denoise" when their models are loaded.
+	Capabilities []string `json:"capabilities"`
+}
+
+// ModelPrecision reports the encoder and decoder precisions in use.
+type ModelPrecision struct {
+	Encoder string `json:"encoder"`
+	Decoder string `json:"decoder"`
 }
 
 // ModelsResponse represents the list of available models
@@ -70,3 +187,138 @@ type ModelsResponse struct {
 	Object string      `json:"object"`
 	Data   []ModelInfo `json:"data"`
 }
+
+// HealthResponse is returned by /health.
+type HealthResponse struct {
+	Status             string `json:"status"`
+	Version            string `json:"version"`
+	Commit             string `json:"commit"`
+	ONNXRuntimeVersion string `json:"onnxruntime_version"`
+	Provider           string `json:"provider"`
+}
+
+// VersionResponse is returned by /version.
+type VersionResponse struct {
+	BuildInfo
+	GoVersion          string          `json:"go_version"`
+	ONNXRuntimeVersion string          `json:"onnxruntime_version"`
+	Provider           string          `json:"provider"`
+	ModelType          string          `json:"model_type"`
+	Models             []string        `json:"models"`
+	ModelFiles         []ModelFileInfo `json:"model_files"`
+}
+
+// ModelFileInfo identifies one loaded model file by name, size and SHA-256.
+// Size and checksum are omitted when the file could not be read.
+type ModelFileInfo struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// StatsResponse is returned by /admin/stats. Counters start at zero when the
+// process starts. AverageRTF is decode wall time over decoded audio time
+// (lower is faster; 0.05 means a minute of audio takes three seconds).
+type StatsResponse struct {
+	UptimeSeconds   float64        `json:"uptime_seconds"`
+	Requests        int64          `json:"requests"`
+	ClientErrors    int64          `json:"client_errors"`
+	ServerErrors    int64          `json:"server_errors"`
+	Decodes         int64          `json:"decodes"`
+	CacheHits       int64          `json:"cache_hits"`
+	SharedInflights int64          `json:"shared_inflight"`
+	ShedRequests    int64          `json:"shed_requests"`
+	AudioSeconds    float64        `json:"audio_seconds"`
+	DecodeSeconds   float64        `json:"decode_seconds"`
+	AverageRTF      float64        `json:"average_rtf"`
+	Workers         int            `json:"workers"`
+	BusyWorkers     int            `json:"busy_workers"`
+	QueueDepth      int            `json:"queue_depth"`
+	QueueByPriority map[string]int `json:"queue_depth_by_priority,omitempty"`
+	DecoderBatching *BatchStats    `json:"decoder_batching,omitempty"`
+	Models          []ModelUsage   `json:"models"`
+	ModelDecodes    []ModelDecodes `json:"model_decodes"`
+	Shadow          *ShadowStats   `json:"shadow,omitempty"`
+	Memory          *MemoryStats   `json:"memory,omitempty"`
+}
+
+// BatchStats counts the ONNX calls of -decoder-batch and the decoder steps
+// they ran; AverageSize is steps per call.
+type BatchStats struct {
+	Runs        int64   `json:"runs"`
+	Steps       int64   `json:"steps"`
+	AverageSize float64 `json:"average_size"`
+}
+
+// MemoryStats is the use of the -memory-budget, in bytes.
+type MemoryStats struct {
+	Budget   int64 `json:"budget_bytes"`
+	Weights  int64 `json:"weights_bytes"`
+	Reserved int64 `json:"reserved_bytes"`
+	Waiting  int   `json:"waiting"`
+}
+
+// ModelUsage counts requests by the model name the client asked for.
+type ModelUsage struct {
+	Model    string `json:"model"`
+	Requests int64  `json:"requests"`
+}
+
+// ModelDecodes are the decodes of one loaded model, by the name it
+// reports; with -canary-models-dir they compare the canary with the model
+// it may replace. Errors leave out cancelled requests.
+type ModelDecodes struct {
+	Model         string  `json:"model"`
+	Canary        bool    `json:"canary,omitempty"`
+	Decodes       int64   `json:"decodes"`
+	Errors        int64   `json:"errors"`
+	AudioSeconds  float64 `json:"audio_seconds"`
+	DecodeSeconds float64 `json:"decode_seconds"`
+	AverageRTF    float64 `json:"average_rtf"`
+}
+
+// ShadowStats sum up the -shadow-models-dir comparisons: how many sampled
+// requests the shadow transcribed (Compared), failed on or skipped while
+// busy, its mean word error rate against the answered transcripts, how
+// many it matched word for word, and the decode time of both sides.
+type ShadowStats struct {
+	Model         string  `json:"model"`
+	Compared      int64   `json:"compared"`
+	Errors        int64   `json:"errors"`
+	Skipped       int64   `json:"skipped"`
+	MeanWER       float64 `json:"mean_wer"`
+	Identical     int64   `json:"identical"`
+	Seconds       float64 `json:"seconds"`
+	ShadowSeconds float64 `json:"shadow_seconds"`
+}
+
+// UsageResponse is returned by /admin/usage: the audio transcribed with
+// each API key since the process started, UptimeSeconds ago.
+type UsageResponse struct {
+	UptimeSeconds float64    `json:"uptime_seconds"`
+	Keys          []KeyUsage `json:"keys"`
+}
+
+// KeyUsage is the usage of one API key name. AudioMinutes is AudioSeconds
+// in minutes, unrounded.
+type KeyUsage struct {
+	Key          string  `json:"key"`
+	Requests     int64   `json:"requests"`
+	AudioSeconds float64 `json:"audio_seconds"`
+	AudioMinutes float64 `json:"audio_minutes"`
+}
+
+// VADResponse is returned by /v1/audio/vad: the whole audio cut into
+// alternating speech and non-speech segments.
+type VADResponse struct {
+	Duration       float64      `json:"duration"`
+	SpeechDuration float64      `json:"speech_duration"`
+	Segments       []VADSegment `json:"segments"`
+}
+
+// VADSegment is one stretch of speech or non-speech, in seconds.
+type VADSegment struct {
+	Start  float64 `json:"start"`
+	End    float64 `json:"end"`
+	Speech bool    `json:"speech"`
+}
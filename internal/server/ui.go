@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// uiPage is the web UI: one self-contained HTML file with its CSS and
+// JavaScript inline, so it needs no other route.
+//
+//go:embed ui/index.html
+var uiPage []byte
+
+// handleUI serves GET / (only with -ui): a page that uploads files with
+// stream=true, records the microphone with MediaRecorder and uploads the
+// recording, and shows live captions over the Deepgram-compatible
+// WebSocket (/v1/listen) from linear16 PCM captured in the browser.
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	// The page loads nothing from elsewhere; it only talks to this origin.
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline' blob:; style-src 'unsafe-inline'; connect-src 'self' ws: wss:")
+	w.Write(uiPage)
+}
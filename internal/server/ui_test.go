@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUI(t *testing.T) {
+	get := func(s *Server, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	s := &Server{mux: http.NewServeMux(), config: Config{UI: true}, apiKey: "secret"}
+	s.setupRoutes()
+	// The page itself is served without the API key.
+	rec := get(s, "/")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET / = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"/v1/audio/transcriptions", "/v1/listen?", "MediaRecorder"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("page does not reference %q", want)
+		}
+	}
+	if code := get(s, "/nope").Code; code != http.StatusNotFound {
+		t.Errorf("GET /nope = %d, want 404", code)
+	}
+
+	off := &Server{mux: http.NewServeMux()}
+	off.setupRoutes()
+	if code := get(off, "/").Code; code != http.StatusNotFound {
+		t.Errorf("GET / with -ui=false = %d, want 404", code)
+	}
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Usage headers, set with -usage-headers on metered responses: the name of
+// the API key and the audio it has transcribed since the server started,
+// this request included.
+const (
+	usageKeyHeader          = "X-Usage-Key"
+	usageAudioSecondsHeader = "X-Usage-Audio-Seconds"
+)
+
+// usageMeter accounts transcribed audio by API key name, for chargeback in
+// shared deployments. Like serverStats it is kept in process and starts over
+// on restart; billing periods are the difference between two readings.
+type usageMeter struct {
+	started time.Time
+
+	mu   sync.Mutex
+	keys map[string]*keyUsage
+}
+
+type keyUsage struct {
+	requests     int64
+	audioSeconds float64
+}
+
+func newUsageMeter() *usageMeter {
+	return &usageMeter{started: time.Now(), keys: make(map[string]*keyUsage)}
+}
+
+// add records one request of key that transcribed seconds of audio and
+// returns the key's new total.
+func (u *usageMeter) add(key string, seconds float64) float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	k := u.keys[key]
+	if k == nil {
+		k = &keyUsage{}
+		u.keys[key] = k
+	}
+	k.requests++
+	k.audioSeconds += seconds
+	return k.audioSeconds
+}
+
+// snapshot returns the usage of every key, sorted by name.
+func (u *usageMeter) snapshot() UsageResponse {
+	u.mu.Lock()
+	resp := UsageResponse{UptimeSeconds: time.Since(u.started).Seconds(), Keys: make([]KeyUsage, 0, len(u.keys))}
+	for name, k := range u.keys {
+		resp.Keys = append(resp.Keys, KeyUsage{
+			Key:          name,
+			Requests:     k.requests,
+			AudioSeconds: k.audioSeconds,
+			AudioMinutes: k.audioSeconds / 60,
+		})
+	}
+	u.mu.Unlock()
+
+	sort.Slice(resp.Keys, func(i, j int) bool { return resp.Keys[i].Key < resp.Keys[j].Key })
+	return resp
+}
+
+// meterUsage accounts the audio a request transcribed (as reported with
+// noteAudio) to the API key it authenticated with. It goes after the auth
+// middleware; without authentication there is no key and nothing is
+// accounted. The usage is recorded when the response starts, so the usage
+// headers include it, or when the handler returns for responses that
+// stream before the audio is known (SSE, WebSocket sessions).
+func (s *Server) meterUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := apiKeyNameFrom(r.Context())
+		m := metricsFrom(r.Context())
+		if key == "" || m == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		uw := &usageWriter{ResponseWriter: w, s: s, key: key, m: m}
+		next.ServeHTTP(uw, r)
+		if !uw.recorded && m.audioSeconds > 0 {
+			s.usage.add(key, m.audioSeconds)
+		}
+	})
+}
+
+// usageWriter records the usage on the first write, while headers can
+// still be set.
+type usageWriter struct {
+	http.ResponseWriter
+	s           *Server
+	key         string
+	m           *requestMetrics
+	wroteHeader bool
+	recorded    bool
+}
+
+func (w *usageWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		// A failed request, e.g. failed post-processing, is not accounted.
+		w.recorded = status >= 400
+		if !w.recorded && w.m.audioSeconds > 0 {
+			w.recorded = true
+			total := w.s.usage.add(w.key, w.m.audioSeconds)
+			if w.s.config.UsageHeaders {
+				h := w.Header()
+				h.Set(usageKeyHeader, w.key)
+				h.Set(usageAudioSecondsHeader, strconv.FormatFloat(total, 'f', 3, 64))
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *usageWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *usageWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *usageWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// handleUsage serves /admin/usage.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.usage.snapshot())
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMeterUsage(t *testing.T) {
+	s := &Server{apiKeys: map[string]string{"sk-a": "team-a", "sk-b": "team-b"}, usage: newUsageMeter(), config: Config{UsageHeaders: true}}
+	transcribe := func(seconds float64, status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			noteAudio(r.Context(), seconds)
+			w.WriteHeader(status)
+			io.WriteString(w, "{}")
+		}
+	}
+	serve := func(token string, h http.HandlerFunc) http.Header {
+		req := httptest.NewRequest("POST", "/v1/audio/transcriptions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		timeResponses(s.requireAuth(s.meterUsage(h))).ServeHTTP(rec, req)
+		return rec.Header()
+	}
+
+	serve("sk-a", transcribe(30, http.StatusOK))
+	h := serve("sk-a", transcribe(90, http.StatusOK))
+	if h.Get(usageKeyHeader) != "team-a" || h.Get(usageAudioSecondsHeader) != "120.000" {
+		t.Errorf("usage headers = %q, %q; want team-a, 120.000", h.Get(usageKeyHeader), h.Get(usageAudioSecondsHeader))
+	}
+	serve("sk-b", transcribe(10, http.StatusBadGateway)) // failed: not accounted
+	// Audio reported after the response started, as SSE and WebSocket
+	// sessions do, is accounted when the handler returns.
+	serve("sk-b", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "data: ...\n\n")
+		noteAudio(r.Context(), 6)
+	})
+
+	rec := httptest.NewRecorder()
+	s.handleUsage(rec, httptest.NewRequest("GET", "/admin/usage", nil))
+	var got UsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyUsage{
+		{Key: "team-a", Requests: 2, AudioSeconds: 120, AudioMinutes: 2},
+		{Key: "team-b", Requests: 1, AudioSeconds: 6, AudioMinutes: 0.1},
+	}
+	if len(got.Keys) != len(want) || got.Keys[0] != want[0] || got.Keys[1] != want[1] {
+		t.Errorf("usage = %+v, want %+v", got.Keys, want)
+	}
+}
+
+func TestMeterUsage_NoHeadersByDefault(t *testing.T) {
+	s := &Server{apiKey: "k", usage: newUsageMeter()}
+	req := httptest.NewRequest("POST", "/inference", nil)
+	req.Header.Set("Authorization", "Bearer k")
+	rec := httptest.NewRecorder()
+	timeResponses(s.requireAuth(s.meterUsage(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noteAudio(r.Context(), 1)
+		io.WriteString(w, "{}")
+	})))).ServeHTTP(rec, req)
+	if rec.Header().Get(usageKeyHeader) != "" {
+		t.Errorf("%s set without -usage-headers", usageKeyHeader)
+	}
+	if got := s.usage.snapshot().Keys; len(got) != 1 || got[0].Key != defaultKeyName {
+		t.Errorf("usage = %+v, want one %q entry", got, defaultKeyName)
+	}
+}
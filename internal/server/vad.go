@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"parakeet/internal/asr"
+)
+
+// handleVAD runs the Silero VAD over an uploaded file and returns its speech
+// and non-speech segments, without transcribing. Clients use it to
+// pre-segment audio or to find where speech ends.
+func (s *Server) handleVAD(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", "invalid_request_error", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	if err := r.ParseMultipartForm(s.multipartMemory()); err != nil {
+		sendBodyError(w, err)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		sendRequestError(w, &paramError{param: "file", code: "missing_required_parameter", err: errors.New("Missing required parameter: 'file'")})
+		return
+	}
+	defer file.Close()
+	audioData, err := io.ReadAll(file)
+	if err != nil {
+		sendError(w, "Failed to read audio file: "+err.Error(), "invalid_request_error", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseVADOptions(r.FormValue)
+	if err != nil {
+		sendRequestError(w, err)
+		return
+	}
+	opts.Format = strings.ToLower(filepath.Ext(header.Filename))
+
+	slog.InfoContext(r.Context(), "detecting speech", "file", header.Filename, "bytes", len(audioData))
+	res, err := s.detectSpeech(r.Context(), audioData, opts)
+	if err != nil {
+		if errors.Is(err, asr.ErrVADUnavailable) {
+			sendError(w, err.Error(), "server_error", http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, asr.ErrUnsupportedAudio) {
+			sendRequestError(w, withParam("file", fmt.Errorf("Unsupported or malformed audio: %w", err)))
+			return
+		}
+		sendError(w, "Speech detection failed: "+err.Error(), "server_error", http.StatusInternalServerError)
+		return
+	}
+
+	noteAudio(r.Context(), res.Duration)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vadResponse(res))
+}
+
+// parseVADOptions reads threshold, min_speech_duration_ms,
+// min_silence_duration_ms and speech_pad_ms, the parameter names of Silero's
+// get_speech_timestamps, with get. Absent ones keep asr.DefaultVADOptions.
+func parseVADOptions(get func(string) string) (asr.VADOptions, error) {
+	opts := asr.DefaultVADOptions
+	if v := get("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 || t >= 1 {
+			return opts, invalidParam("threshold", "invalid threshold %q (a speech probability between 0 and 1)", v)
+		}
+		opts.Threshold = t
+	}
+	for _, p := range []struct {
+		name string
+		dst  *float64
+	}{
+		{"min_speech_duration_ms", &opts.MinSpeech},
+		{"min_silence_duration_ms", &opts.MinSilence},
+		{"speech_pad_ms", &opts.SpeechPad},
+	} {
+		v := get(p.name)
+		if v == "" {
+			continue
+		}
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			return opts, invalidParam(p.name, "invalid %s %q (milliseconds, zero or more)", p.name, v)
+		}
+		*p.dst = float64(ms) / 1000
+	}
+	return opts, nil
+}
+
+// vadResponse fills the gaps between the speech spans of res with
+// non-speech segments, so the segments cover the whole audio.
+func vadResponse(res *asr.VADResult) VADResponse {
+	round := func(x float64) float64 { return math.Round(x*1000) / 1000 }
+	resp := VADResponse{Duration: round(res.Duration), Segments: []VADSegment{}}
+	at := 0.0
+	for _, sp := range res.Speech {
+		if sp.Start > at {
+			resp.Segments = append(resp.Segments, VADSegment{Start: round(at), End: round(sp.Start)})
+		}
+		resp.Segments = append(resp.Segments, VADSegment{Start: round(sp.Start), End: round(sp.End), Speech: true})
+		resp.SpeechDuration += sp.End - sp.Start
+		at = sp.End
+	}
+	if res.Duration > at {
+		resp.Segments = append(resp.Segments, VADSegment{Start: round(at), End: round(res.Duration)})
+	}
+	resp.SpeechDuration = round(resp.SpeechDuration)
+	return resp
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestParseVADOptions(t *testing.T) {
+	opts, err := parseVADOptions(url.Values{}.Get)
+	if err != nil || opts != asr.DefaultVADOptions {
+		t.Fatalf("defaults = %+v, %v", opts, err)
+	}
+	opts, err = parseVADOptions(url.Values{
+		"threshold":               {"0.7"},
+		"min_speech_duration_ms":  {"500"},
+		"min_silence_duration_ms": {"300"},
+		"speech_pad_ms":           {"0"},
+	}.Get)
+	want := asr.VADOptions{Threshold: 0.7, MinSpeech: 0.5, MinSilence: 0.3}
+	if err != nil || opts != want {
+		t.Fatalf("opts = %+v, %v; want %+v", opts, err, want)
+	}
+	for _, bad := range []url.Values{
+		{"threshold": {"1.5"}},
+		{"threshold": {"x"}},
+		{"min_silence_duration_ms": {"-1"}},
+		{"speech_pad_ms": {"0.5"}},
+	} {
+		if _, err := parseVADOptions(bad.Get); err == nil {
+			t.Errorf("%v accepted", bad)
+		}
+	}
+}
+
+func TestVADResponse(t *testing.T) {
+	resp := vadResponse(&asr.VADResult{
+		Duration: 10,
+		Speech:   []asr.SpeechSpan{{Start: 1, End: 3.5}, {Start: 5, End: 10}},
+	})
+	want := VADResponse{
+		Duration:       10,
+		SpeechDuration: 7.5,
+		Segments: []VADSegment{
+			{Start: 0, End: 1},
+			{Start: 1, End: 3.5, Speech: true},
+			{Start: 3.5, End: 5},
+			{Start: 5, End: 10, Speech: true},
+		},
+	}
+	if !reflect.DeepEqual(resp, want) {
+		t.Fatalf("resp = %+v\nwant %+v", resp, want)
+	}
+
+	silent := vadResponse(&asr.VADResult{Duration: 2})
+	if len(silent.Segments) != 1 || silent.Segments[0].Speech || silent.SpeechDuration != 0 {
+		t.Fatalf("silent = %+v", silent)
+	}
+}
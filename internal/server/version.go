@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+)
+
+// BuildInfo is the binary's build metadata, stamped into package main by the
+// Makefile's -ldflags and handed to the server through Config.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// modelChecksums holds the hashes of a generation's model files. Hashing a
+// full-precision encoder takes seconds, so it runs in the background once
+// the generation is loaded (see hashModels), off both the startup path and
+// the unauthenticated /version route.
+type modelChecksums struct {
+	files atomic.Pointer[[]ModelFileInfo]
+}
+
+func (m *modelChecksums) compute(paths []string, open func(string) (io.ReadCloser, error)) {
+	files := make([]ModelFileInfo, 0, len(paths))
+	for _, p := range paths {
+		info := ModelFileInfo{Name: filepath.Base(p)}
+		sum, size, err := sha256File(open, p)
+		if err != nil {
+			slog.Warn("failed to checksum model file", "path", p, "error", err)
+		} else {
+			info.SHA256 = sum
+			info.Size = size
+		}
+		files = append(files, info)
+	}
+	m.files.Store(&files)
+}
+
+// get returns the checksums, or only the names of paths while they are
+// still being computed.
+func (m *modelChecksums) get(paths []string) []ModelFileInfo {
+	if files := m.files.Load(); files != nil {
+		return *files
+	}
+	files := make([]ModelFileInfo, 0, len(paths))
+	for _, p := range paths {
+		files = append(files, ModelFileInfo{Name: filepath.Base(p)})
+	}
+	return files
+}
+
+// hashModels starts hashing the model files of m, a generation just
+// swapped in. When it was loaded from the same files as prev, as after an
+// idle unload, prev's checksums are kept instead.
+func (s *Server) hashModels(m, prev *loadedModels) {
+	if prev != nil && prev.fingerprint == m.fingerprint {
+		if files := prev.checksums.files.Load(); files != nil {
+			m.checksums.files.Store(files)
+			return
+		}
+	}
+	go m.checksums.compute(m.transcriber.Info().ModelFiles, m.transcriber.OpenModelFile)
+}
+
+func sha256File(open func(string) (io.ReadCloser, error), path string) (string, int64, error) {
+	f, err := open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// handleVersion reports the build, runtime and loaded models, so mixed
+// fleets can be told apart when debugging.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	m := s.models.Load()
+	info := m.transcriber.Info()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(VersionResponse{
+		BuildInfo:          s.config.Build,
+		GoVersion:          runtime.Version(),
+		ONNXRuntimeVersion: info.ONNXRuntimeVersion,
+		Provider:           string(info.Provider),
+		ModelType:          info.ModelType,
+		Models:             s.modelIDs(),
+		ModelFiles:         m.checksums.get(info.ModelFiles),
+	})
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+func TestModelChecksums(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "vocab.txt")
+	os.WriteFile(present, []byte("abc"), 0o644)
+	missing := filepath.Join(dir, "gone.onnx")
+
+	var m modelChecksums
+	// Only the names until the hashes are in.
+	if files := m.get([]string{present, missing}); len(files) != 2 || files[0].Name != "vocab.txt" || files[0].SHA256 != "" {
+		t.Errorf("before compute = %+v", files)
+	}
+	m.compute([]string{present, missing}, openFile)
+	files := m.get([]string{present, missing})
+	if len(files) != 2 {
+		t.Fatalf("got %d entries, want 2", len(files))
+	}
+	// sha256("abc")
+	if files[0].Name != "vocab.txt" || files[0].Size != 3 ||
+		files[0].SHA256 != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("present file = %+v", files[0])
+	}
+	if files[1].Name != "gone.onnx" || files[1].SHA256 != "" {
+		t.Errorf("missing file = %+v", files[1])
+	}
+
+	// get never hashes: reading the files is left to compute.
+	os.WriteFile(present, []byte("changed"), 0o644)
+	if again := m.get([]string{present, missing}); again[0].SHA256 != files[0].SHA256 {
+		t.Errorf("checksums recomputed: %+v", again)
+	}
+
+	// A generation loaded from the same files keeps the hashes.
+	s := &Server{}
+	prev := &loadedModels{fingerprint: "f"}
+	prev.checksums.files.Store(&files)
+	next := &loadedModels{fingerprint: "f"}
+	s.hashModels(next, prev)
+	if got := next.checksums.files.Load(); got == nil || (*got)[0].SHA256 != files[0].SHA256 {
+		t.Errorf("reused checksums = %v", got)
+	}
+}
+
+func openFile(name string) (io.ReadCloser, error) { return os.Open(name) }
+
+func TestHandleVersion(t *testing.T) {
+	s := &Server{
+		config: Config{Build: BuildInfo{Version: "v1.2.3", Commit: "abc1234", BuildDate: "2026-01-01T00:00:00Z"}},
+	}
+	s.models.Store(&loadedModels{transcriber: &asr.Transcriber{}})
+	rec := httptest.NewRecorder()
+	s.handleVersion(rec, httptest.NewRequest("GET", "/version", nil))
+
+	var got VersionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "v1.2.3" || got.Commit != "abc1234" || got.BuildDate != "2026-01-01T00:00:00Z" {
+		t.Errorf("build info = %+v", got.BuildInfo)
+	}
+	if got.GoVersion == "" || len(got.Models) == 0 {
+		t.Errorf("response = %+v", got)
+	}
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWSAcceptKey(t *testing.T) {
+	// The example from RFC 6455 section 1.3.
+	if got := wsAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("wsAcceptKey = %q", got)
+	}
+}
+
+// wsTestClient is just enough of a WebSocket client to drive the server in
+// tests: it sends masked frames and reads unmasked ones.
+type wsTestClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, srv *httptest.Server, path string, header http.Header) *wsTestClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req := "GET " + path + " HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	for k, vs := range header {
+		for _, v := range vs {
+			req += k + ": " + v + "\r\n"
+		}
+	}
+	if _, err := io.WriteString(conn, req+"\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("handshake status %d: %s", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &wsTestClient{t: t, conn: conn, br: br}
+}
+
+// send writes one masked frame.
+func (c *wsTestClient) send(fin bool, opcode byte, payload []byte) {
+	c.t.Helper()
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read returns the next frame from the server.
+func (c *wsTestClient) read() (byte, []byte) {
+	c.t.Helper()
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	n := int(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return hdr[0] & 0x0f, payload
+}
+
+func TestWSConn_EchoFragmentsPingAndClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r, "")
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			op, data, err := conn.readMessage()
+			if err != nil {
+				return
+			}
+			conn.writeFrame(op, data)
+		}
+	}))
+	defer srv.Close()
+
+	c := dialWS(t, srv, "/", nil)
+
+	c.send(false, wsText, []byte("hel"))
+	c.send(true, wsPing, []byte("p"))
+	c.send(true, wsContinuation, []byte("lo"))
+	if op, data := c.read(); op != wsPong || string(data) != "p" {
+		t.Fatalf("got op %#x %q, want pong \"p\"", op, data)
+	}
+	if op, data := c.read(); op != wsText || string(data) != "hello" {
+		t.Fatalf("got op %#x %q, want text \"hello\"", op, data)
+	}
+
+	big := make([]byte, 70000)
+	c.send(true, wsBinary, big)
+	if op, data := c.read(); op != wsBinary || len(data) != len(big) {
+		t.Fatalf("got op %#x with %d bytes, want binary %d", op, len(data), len(big))
+	}
+
+	c.send(true, wsClose, binary.BigEndian.AppendUint16(nil, wsCloseNormal))
+	if op, data := c.read(); op != wsClose || binary.BigEndian.Uint16(data) != wsCloseNormal {
+		t.Fatalf("got op %#x %v, want close 1000", op, data)
+	}
+}
+
+func TestWSConn_RejectsUnmaskedFrames(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r, "")
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.readMessage()
+	}))
+	defer srv.Close()
+
+	c := dialWS(t, srv, "/", nil)
+	c.conn.Write([]byte{0x81, 0x01, 'x'})
+	if op, data := c.read(); op != wsClose || binary.BigEndian.Uint16(data) != wsCloseProtocolError {
+		t.Fatalf("got op %#x %v, want close 1002", op, data)
+	}
+}
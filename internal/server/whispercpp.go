@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// whisperCppErrorResponse is the error body of the whisper.cpp server:
+// a bare message instead of OpenAI's nested error object.
+type whisperCppErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// handleInference serves POST /inference, the transcription endpoint of the
+// whisper.cpp example server, so tools written against it (editor and
+// note-taking plugins, shell scripts) work unchanged.
+//
+// The request is the same multipart upload as /v1/audio/transcriptions with
+// whisper.cpp's field conventions: "file", "response_format" (json, text,
+// srt, vtt, verbose_json) and "language", where "auto" means detect. The
+// whisper.cpp decoding knobs (temperature, temperature_inc, beam_size,
+// best_of, no_timestamps, ...) are accepted and ignored: their values are
+// tuned for Whisper (beam_size=-1, a temperature fallback ladder).
+// translate=true follows -translation like /v1/audio/translations. Everything parakeet adds on top
+// (stream=true, Accept: text/event-stream, channel_mode, denoise, the
+// conditioning switches, max_tokens_per_step, blank_penalty) works as on the
+// OpenAI endpoint.
+func (s *Server) handleInference(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		sendWhisperCppError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The checks whisper.cpp clients can act on are answered in its own
+	// error shape; once the upload is valid the OpenAI handler takes over.
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		sendWhisperCppError(w, "request must be multipart/form-data", http.StatusBadRequest)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	if err := r.ParseMultipartForm(s.multipartMemory()); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendWhisperCppError(w, fmt.Sprintf("request larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		sendWhisperCppError(w, "failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(r.MultipartForm.File["file"]) == 0 {
+		sendWhisperCppError(w, "no 'file' field in the request", http.StatusBadRequest)
+		return
+	}
+
+	if v := r.MultipartForm.Value["translate"]; len(v) > 0 && v[0] == "true" {
+		if reason := s.translationUnsupported(); reason != "" {
+			sendWhisperCppError(w, reason, http.StatusNotImplemented)
+			return
+		}
+	}
+
+	// Parakeet detects the language itself; "auto" is whisper.cpp's way of
+	// asking for that and is reported as the default.
+	if v := r.MultipartForm.Value["language"]; len(v) > 0 && strings.EqualFold(v[0], "auto") {
+		delete(r.MultipartForm.Value, "language")
+		r.Form.Del("language")
+	}
+
+	// The knobs the OpenAI endpoint understands under the same names mean
+	// something else to whisper.cpp clients.
+	for _, knob := range whisperCppIgnoredKnobs {
+		delete(r.MultipartForm.Value, knob)
+		r.Form.Del(knob)
+	}
+
+	s.handleMultipartTranscription(w, r)
+}
+
+// whisperCppIgnoredKnobs are whisper.cpp fields dropped before the request
+// reaches the OpenAI handler.
+var whisperCppIgnoredKnobs = []string{"temperature", "beam_size"}
+
+// sendWhisperCppError writes an error in the whisper.cpp server's shape.
+func sendWhisperCppError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(whisperCppErrorResponse{Error: message})
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"parakeet/internal/asr"
+)
+
+// inferenceRequest builds a whisper.cpp style /inference upload.
+func inferenceRequest(t *testing.T, audio []byte, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if audio != nil {
+		fw, err := mw.CreateFormFile("file", "clip.wav")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(audio)
+	}
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+	r := httptest.NewRequest("POST", "/inference", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestHandleInference(t *testing.T) {
+	s := &Server{cache: newMemoryCache(1), stats: newServerStats(), inflight: newInflightGroup()}
+	audio := []byte("audio")
+	s.cache.Put(s.cacheKey(audio, asr.TranscribeOptions{Format: ".wav", Language: "en", Channels: asr.ChannelMix}),
+		&asr.Result{Text: "hello world", Duration: 1, Channels: 1})
+
+	rec := httptest.NewRecorder()
+	s.handleInference(rec, inferenceRequest(t, audio, map[string]string{
+		"language":        "auto",
+		"temperature":     "0.4",
+		"temperature_inc": "0.2",
+		"beam_size":       "-1",
+		"response_format": "json",
+	}))
+	var got TranscriptionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || got.Text != "hello world" {
+		t.Errorf("status %d, text %q; want 200 %q", rec.Code, got.Text, "hello world")
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleInference(rec, inferenceRequest(t, nil, nil))
+	var errResp whisperCppErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&errResp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || errResp.Error == "" {
+		t.Errorf("missing file: status %d, error %q", rec.Code, errResp.Error)
+	}
+}
@@ -4,9 +4,15 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
+
+	"parakeet/internal/selftest"
+	"parakeet/internal/server"
 )
 
 // newTestFlags builds an isolated FlagSet mirroring the real flags so tests never
@@ -107,3 +113,123 @@ func TestApplyEnvDefaults(t *testing.T) {
 		}
 	})
 }
+
+func TestApplyProfile(t *testing.T) {
+	parse := func(t *testing.T, args ...string) (*server.Config, error) {
+		t.Helper()
+		cfg := &server.Config{}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		registerServerFlags(fs, cfg)
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		applyEnvDefaults(fs)
+		return cfg, applyProfile(fs)
+	}
+
+	t.Run("no profile keeps the defaults", func(t *testing.T) {
+		cfg, err := parse(t)
+		if err != nil || cfg.Workers != 4 || cfg.MelPrecision != "float64" || cfg.ONNXThreads != 0 {
+			t.Fatalf("cfg = %+v, %v", cfg, err)
+		}
+	})
+
+	t.Run("low-power fills the flags left at their defaults", func(t *testing.T) {
+		cfg, err := parse(t, "-profile", "low-power")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Workers != 1 || cfg.EncoderPrecision != "int8" || cfg.DecoderPrecision != "int8" ||
+			cfg.MelPrecision != "float32" || !cfg.DisableONNXSpinning || !cfg.LongAudio ||
+			cfg.ChunkSeconds != 60 || cfg.UploadSpoolMB != 8 || cfg.ONNXThreads != runtime.NumCPU() {
+			t.Errorf("cfg = %+v", cfg)
+		}
+	})
+
+	t.Run("CLI flags and env vars beat the profile", func(t *testing.T) {
+		t.Setenv("PARAKEET_CHUNK_SECONDS", "120")
+		cfg, err := parse(t, "-profile", "low-power", "-workers", "2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Workers != 2 || cfg.ChunkSeconds != 120 || cfg.ONNXThreads != max(1, runtime.NumCPU()/2) {
+			t.Errorf("workers %d, chunk-seconds %d, onnx-threads %d", cfg.Workers, cfg.ChunkSeconds, cfg.ONNXThreads)
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		if _, err := parse(t, "-profile", "turbo"); err == nil || !strings.Contains(err.Error(), "low-power") {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
+
+func TestOutputPath(t *testing.T) {
+	for _, tc := range []struct {
+		file, format string
+		archive      bool
+		want         string
+	}{
+		{"movie.mp4", "srt", false, "movie.srt"},
+		{"/data/show.s01e01.mkv", "ass", false, "/data/show.s01e01.ass"},
+		{"talk.webm", "text", false, "talk.txt"},
+		{"noext", "vtt", false, "noext.vtt"},
+		{"calls.zip", "srt", true, "calls.srt.zip"},
+		{"/data/calls.tar.gz", "json", true, "/data/calls.json.zip"},
+		{"calls.tgz", "text", true, "calls.txt.zip"},
+	} {
+		if got := outputPath(tc.file, tc.format, tc.archive); got != tc.want {
+			t.Errorf("outputPath(%q, %q, %v) = %q, want %q", tc.file, tc.format, tc.archive, got, tc.want)
+		}
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	cmp := &server.Comparison{
+		A:   server.ComparedTranscript{Role: "loaded", Model: "int8", Seconds: 1},
+		B:   server.ComparedTranscript{Role: "compare", Model: "fp32", Seconds: 2},
+		WER: 0.5, ReferenceWords: 4, Substitutions: 1, Insertions: 1,
+		Diff: []selftest.Edit{
+			{Op: "equal", Reference: "turn on the", Hypothesis: "turn on the"},
+			{Op: "substitute", Reference: "kitchen", Hypothesis: "chicken"},
+			{Op: "insert", Hypothesis: "now"},
+		},
+	}
+	var b strings.Builder
+	writeComparison(&b, cmp)
+	if !strings.Contains(b.String(), "WER: 50.00% (1 substitutions, 0 deletions, 1 insertions over 4 words)") ||
+		!strings.HasSuffix(b.String(), "turn on the [-kitchen-] {+chicken+} {+now+}\n") {
+		t.Errorf("writeComparison:\n%s", b.String())
+	}
+}
+
+func TestWriteEval(t *testing.T) {
+	cases := []selftest.Case{
+		{Name: "a", Reference: "the birch canoe slid"},
+		{Name: "b", Reference: "on the smooth planks"},
+	}
+	transcribe := func(texts map[string]string) selftest.Report {
+		return selftest.RunFunc(context.Background(), cases, 0.5, func(_ context.Context, c selftest.Case) (string, error) {
+			return texts[c.Name], nil
+		})
+	}
+	report := transcribe(map[string]string{"a": "the birch canoe slid", "b": "on the smooth blanks"})
+	base := transcribe(map[string]string{"a": "the birch canoe slid", "b": "on the smooth planks"})
+
+	var b strings.Builder
+	if n := writeEval(&b, report, nil, 0, 0); n != 0 {
+		t.Errorf("without bounds: %d failures\n%s", n, b.String())
+	}
+	b.Reset()
+	if n := writeEval(&b, report, nil, 0.1, 0); n != 1 || !strings.Contains(b.String(), "corpus WER 0.125 over -max-corpus-wer 0.100") {
+		t.Errorf("-max-corpus-wer: %d failures\n%s", n, b.String())
+	}
+	b.Reset()
+	if n := writeEval(&b, report, &base, 0, 0); n != 2 || !strings.Contains(b.String(), "REGRESSION  b") {
+		t.Errorf("-baseline: %d failures\n%s", n, b.String())
+	}
+	b.Reset()
+	if n := writeEval(&b, report, &base, 0, 0.25); n != 0 {
+		t.Errorf("-baseline within -tolerance: %d failures\n%s", n, b.String())
+	}
+}
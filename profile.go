@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// profiles are the -profile presets: flag values tuned for a class of
+// hardware, applied to the flags left at their defaults.
+var profiles = map[string]map[string]string{
+	// low-power fits Raspberry Pi 4/5 class boards: one worker with every
+	// core, the int8 exports only (ONNX Runtime's ARM64 kernels run them on
+	// NEON, with the dot-product instructions of a Pi 5), float32 features,
+	// threads that sleep when idle, 60 s windows to bound the encoder's
+	// memory, and uploads spooled to disk past 8 MB.
+	"low-power": {
+		"workers":               "1",
+		"encoder-precision":     "int8",
+		"decoder-precision":     "int8",
+		"mel-precision":         "float32",
+		"disable-onnx-spinning": "true",
+		"long-audio":            "true",
+		"chunk-seconds":         "60",
+		"chunk-overlap-seconds": "5",
+		"upload-spool-mb":       "8",
+	},
+}
+
+// applyProfile sets the flags of the -profile preset that were neither
+// passed on the command line nor through their PARAKEET_* variable, so
+// either still overrides the profile. A profile also splits the cores among
+// the workers with -onnx-threads, unless that was given.
+func applyProfile(fs *flag.FlagSet) error {
+	name := fs.Lookup("profile").Value.String()
+	if name == "" {
+		return nil
+	}
+	preset, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown -profile %q (available: %s)", name, strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	given := func(name string) bool {
+		_, env := os.LookupEnv(envVar(name))
+		return explicit[name] || env
+	}
+	for flagName, value := range preset {
+		if given(flagName) {
+			continue
+		}
+		if err := fs.Set(flagName, value); err != nil {
+			return fmt.Errorf("-profile %s: -%s: %w", name, flagName, err)
+		}
+	}
+	if !given("onnx-threads") {
+		workers, _ := strconv.Atoi(fs.Lookup("workers").Value.String())
+		threads := max(1, runtime.NumCPU()/max(1, workers))
+		fs.Set("onnx-threads", strconv.Itoa(threads))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"parakeet/internal/selftest"
+	"parakeet/internal/server"
+)
+
+// runSelftest implements `parakeet selftest`: an acceptance test that pushes a
+// known corpus through the full HTTP pipeline and fails (exit 1) when any case
+// exceeds its WER threshold. Without -url it boots an in-process server from
+// the usual server flags on a free loopback port, so the same command validates
+// a fresh install; with -url it targets an existing deployment instead.
+func runSelftest(args []string) int {
+	cfg := server.Config{}
+	opts := selftest.Options{}
+	var timeout time.Duration
+
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	registerServerFlags(fs, &cfg)
+	fs.BoolVar(&opts.Full, "full", false, "Also run synthetic speech cases generated with a local TTS (espeak-ng/espeak)")
+	fs.StringVar(&opts.BaseURL, "url", "", "Test an already running server at this base URL instead of starting one")
+	fs.StringVar(&opts.ClipsDir, "clips", "", "Directory of audio clips with sibling .txt reference transcripts")
+	fs.StringVar(&opts.TTSBinary, "tts", "", "Path to the TTS binary (default: espeak-ng, then espeak)")
+	fs.StringVar(&opts.TTSVoice, "tts-voice", "en-us", "TTS voice name")
+	fs.Float64Var(&opts.MaxWER, "max-wer", selftest.DefaultMaxWER, "Maximum word error rate for a case to pass")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "Overall time budget for the selftest run")
+	fs.Parse(args)
+	applyEnvDefaults(fs)
+	if err := applyProfile(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	setupLogger(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	opts.APIKey = os.Getenv("PARAKEET_API_KEY")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if opts.BaseURL == "" {
+		srv, baseURL, err := startLocalServer(cfg)
+		if err != nil {
+			slog.Error("selftest: failed to start server", "error", err)
+			return 1
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+			srv.Close()
+		}()
+		opts.BaseURL = baseURL
+	}
+
+	if err := selftest.WaitHealthy(ctx, opts.BaseURL); err != nil {
+		slog.Error("selftest: server did not become healthy", "error", err)
+		return 1
+	}
+
+	cases, err := selftest.BuildCases(ctx, opts)
+	if err != nil {
+		slog.Error("selftest: failed to build corpus", "error", err)
+		return 1
+	}
+
+	report := selftest.Run(ctx, opts, cases)
+	report.Write(os.Stdout)
+	if !report.Passed() {
+		fmt.Fprintln(os.Stdout, "selftest FAILED")
+		return 1
+	}
+	fmt.Fprintln(os.Stdout, "selftest passed")
+	return 0
+}
+
+// startLocalServer boots a server from cfg on a free loopback port and returns
+// it with its base URL. The port flag is ignored: a selftest must never collide
+// with a production instance already listening on the configured port.
+func startLocalServer(cfg server.Config) (*server.Server, string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", fmt.Errorf("pick free port: %w", err)
+	}
+	cfg.Port = l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	srv, err := server.New(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	go func() {
+		if err := srv.Run(); err != nil {
+			slog.Error("selftest: server error", "error", err)
+		}
+	}()
+	return srv, fmt.Sprintf("http://127.0.0.1:%d", cfg.Port), nil
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2026 Alby Hernández <hola@achetronic.com>
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"parakeet/internal/server"
+)
+
+// runTranscribe implements `parakeet transcribe [flags] FILE...`: each audio
+// or video file is transcribed in-process, without starting the HTTP server,
+// and written next to it with the format's extension (movie.mp4 ->
+// movie.srt), or to -o. A zip or tar archive has each of its files
+// transcribed and gets a zip archive of the transcripts (calls.tar.gz ->
+// calls.srt.zip). It accepts every server flag, so models, ffmpeg,
+// long-audio and conditioning settings match `parakeet serve`.
+func runTranscribe(args []string) int {
+	cfg := server.Config{}
+	var output string
+	opts := server.FileOptions{}
+
+	fs := flag.NewFlagSet("transcribe", flag.ExitOnError)
+	registerServerFlags(fs, &cfg)
+	fs.StringVar(&opts.ResponseFormat, "format", "srt", "Output format: srt, vtt, srt_words, vtt_words, ass, ttml, text, tsv, csv, jsonl, json or verbose_json")
+	fs.StringVar(&opts.Language, "language", "", "Language of the audio (default: en)")
+	fs.IntVar(&opts.MaxLineChars, "max-line-chars", 0, "Wrap subtitle lines at this many characters and merge short segments into one cue (default: no wrapping)")
+	fs.IntVar(&opts.MaxLinesPerCue, "max-lines-per-cue", 2, "Lines per subtitle cue when -max-line-chars wraps them")
+	fs.Float64Var(&opts.MaxCueDuration, "max-cue-duration", 0, "Split subtitle cues longer than this many seconds (default: one cue per segment)")
+	fs.StringVar(&output, "o", "", "Output file, or - for stdout (default: next to each input; only with one input)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: parakeet transcribe [flags] FILE...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	applyEnvDefaults(fs)
+	if err := applyProfile(fs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if _, ok := server.FormatExtension(opts.ResponseFormat); !ok {
+		fmt.Fprintf(os.Stderr, "unknown -format %q (available: srt, vtt, ass, ttml, text, tsv, csv, jsonl, json, verbose_json)\n", opts.ResponseFormat)
+		return 2
+	}
+	if opts.MaxLineChars < 0 || opts.MaxLinesPerCue < 1 || opts.MaxCueDuration < 0 {
+		fmt.Fprintln(os.Stderr, "-max-line-chars and -max-cue-duration cannot be negative, and -max-lines-per-cue must be at least 1")
+		return 2
+	}
+	if output != "" && len(files) > 1 {
+		fmt.Fprintln(os.Stderr, "-o takes a single input file")
+		return 2
+	}
+
+	// Logs go to stderr so that -o - leaves stdout to the transcript.
+	setupLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	// Configured integrations belong to `serve`; a one-off run must not
+	// start the async API or join a job cluster from the environment.
+	cfg.AssemblyAI, cfg.JobsNATSURL = false, ""
+
+	srv, err := server.New(cfg)
+	if err != nil {
+		slog.Error("failed to initialize", "error", err)
+		return 1
+	}
+	defer srv.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := 0
+	for _, file := range files {
+		isArchive, err := server.IsArchive(file)
+		dest := output
+		if dest == "" {
+			dest = outputPath(file, opts.ResponseFormat, isArchive)
+		}
+		var data []byte
+		switch {
+		case err != nil:
+		case isArchive:
+			data, err = srv.TranscribeArchive(ctx, file, opts, func(name string, err error, done, total int) {
+				if err != nil {
+					slog.Error("transcription failed", "archive", file, "file", name, "done", done, "total", total, "error", err)
+				} else {
+					slog.Info("transcribed", "archive", file, "file", name, "done", done, "total", total)
+				}
+			})
+		default:
+			data, err = srv.TranscribeFile(ctx, file, opts)
+		}
+		if err == nil {
+			err = writeOutput(dest, data)
+		}
+		if err != nil {
+			slog.Error("transcription failed", "file", file, "error", err)
+			failed++
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if dest != "-" {
+			slog.Info("transcribed", "file", file, "output", dest)
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// outputPath is where the transcript of file goes by default: the same path
+// with the format's extension, or for an archive, with the format's
+// extension and .zip.
+func outputPath(file, format string, isArchive bool) string {
+	ext, _ := server.FormatExtension(format)
+	if isArchive {
+		base := strings.TrimSuffix(file, ".gz")
+		return strings.TrimSuffix(base, filepath.Ext(base)) + ext + ".zip"
+	}
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ext
+}
+
+func writeOutput(dest string, data []byte) error {
+	if dest == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(dest, data, 0o644)
+}